@@ -9,6 +9,7 @@ import (
 	"github.com/open-horizon/anax/metering"
 	"github.com/open-horizon/anax/persistence"
 	"github.com/open-horizon/anax/policy"
+	"github.com/open-horizon/anax/tracing"
 	"net/http"
 )
 
@@ -30,13 +31,16 @@ type ProtocolMessage interface {
 	Protocol() string
 	Version() int
 	AgreementId() string
+	TraceContext() string
+	SetTraceContext(traceParent string)
 }
 
 type BaseProtocolMessage struct {
-	MsgType   string `json:"type"`
-	AProtocol string `json:"protocol"`
-	AVersion  int    `json:"version"`
-	AgreeId   string `json:"agreementId"`
+	MsgType     string `json:"type"`
+	AProtocol   string `json:"protocol"`
+	AVersion    int    `json:"version"`
+	AgreeId     string `json:"agreementId"`
+	TraceParent string `json:"traceparent,omitempty"` // W3C trace context of the sender's span, used to trace the agreement across the agbot and the node
 }
 
 func (pm *BaseProtocolMessage) IsValid() bool {
@@ -67,6 +71,14 @@ func (pm *BaseProtocolMessage) AgreementId() string {
 	return pm.AgreeId
 }
 
+func (pm *BaseProtocolMessage) TraceContext() string {
+	return pm.TraceParent
+}
+
+func (pm *BaseProtocolMessage) SetTraceContext(traceParent string) {
+	pm.TraceParent = traceParent
+}
+
 // Extract the agreement protocol name from stringified message
 func ExtractProtocol(msg string) (string, error) {
 
@@ -420,6 +432,11 @@ func SendProtocolMessage(messageTarget interface{},
 	msg interface{},
 	sendMessage func(mt interface{}, pay []byte) error) error {
 
+	// Carry the agreement's current span to the other party so that its spans become children of ours.
+	if pm, ok := msg.(ProtocolMessage); ok && pm.TraceContext() == "" {
+		pm.SetTraceContext(tracing.AgreementTraceParent(pm.AgreementId()))
+	}
+
 	pay, err := json.Marshal(msg)
 	if err != nil {
 		return errors.New(fmt.Sprintf("unable to serialize payload %v, error: %v", msg, err))
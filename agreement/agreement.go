@@ -216,6 +216,14 @@ func (w *AgreementWorker) NewEvent(incoming events.Message) {
 			w.Commands <- NewNodePolicyChangedCommand(msg)
 		}
 
+	case *events.NodeDiscoveryMessage:
+		msg, _ := incoming.(*events.NodeDiscoveryMessage)
+		switch msg.Event().Id {
+		case events.NODE_PROPERTIES_DISCOVERED:
+			// The discovered properties are built-in node properties, syncing the node policy puts them in the exchange.
+			w.Commands <- NewNodePolicyChangeCommand()
+		}
+
 	case *events.ExchangeChangeMessage:
 		msg, _ := incoming.(*events.ExchangeChangeMessage)
 		switch msg.Event().Id {
@@ -1021,6 +1029,10 @@ func deleteProducerAgreement(httpClient *http.Client, url string, deviceId strin
 }
 
 func (w *AgreementWorker) deleteMessage(msg *exchange.DeviceMessage) error {
+	if msg.MsgId == exchange.PUSHED_MESSAGE_ID {
+		return nil
+	}
+
 	var resp interface{}
 	resp = new(exchange.PostDeviceResponse)
 	targetURL := w.GetExchangeURL() + "orgs/" + exchange.GetOrg(w.GetExchangeId()) + "/nodes/" + exchange.GetId(w.GetExchangeId()) + "/msgs/" + strconv.Itoa(msg.MsgId)
@@ -1040,6 +1052,11 @@ func (w *AgreementWorker) deleteMessage(msg *exchange.DeviceMessage) error {
 }
 
 func (w *AgreementWorker) messageInExchange(msgId int) (bool, error) {
+	// A pushed message is handled once, there is no copy in the exchange that another worker could have handled.
+	if msgId == exchange.PUSHED_MESSAGE_ID {
+		return true, nil
+	}
+
 	var resp interface{}
 	resp = new(exchange.GetDeviceMessageResponse)
 	targetURL := w.GetExchangeURL() + "orgs/" + exchange.GetOrg(w.GetExchangeId()) + "/nodes/" + exchange.GetId(w.GetExchangeId()) + "/msgs/" + strconv.Itoa(msgId)
@@ -0,0 +1,312 @@
+package agreementbot
+
+import (
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/gorilla/mux"
+	"github.com/open-horizon/anax/agreementbot/persistence"
+	"github.com/open-horizon/anax/cutil"
+	"github.com/open-horizon/anax/events"
+	"github.com/open-horizon/anax/exchange"
+	"github.com/open-horizon/anax/i18n"
+	"github.com/open-horizon/anax/policy"
+	"net/http"
+	"time"
+)
+
+// The admin API lets operators change what the agbot does. It is part of the secure API, and is only served over TLS
+// because it requires the basic auth credentials of an exchange admin user of the org of the target resource, or of
+// the root org.
+
+// Add the admin API routes to the router of the secure API.
+func (a *SecureAPI) addAdminRoutes(router *mux.Router) {
+	router.HandleFunc("/admin/pause", a.pausedPolicies).Methods("GET", "OPTIONS")
+	router.HandleFunc("/admin/pause/{type}/{org}/{name}", a.pausePolicy).Methods("POST", "DELETE", "OPTIONS")
+	router.HandleFunc("/admin/cancel/{type}/{org}/{name}", a.cancelPolicyAgreements).Methods("POST", "OPTIONS")
+	router.HandleFunc("/admin/reevaluate/{org}/{node}", a.reevaluateNode).Methods("POST", "OPTIONS")
+	router.HandleFunc("/admin/drain", a.drain).Methods("POST", "OPTIONS")
+	router.HandleFunc("/admin/messagingkey", a.messagingKey).Methods("POST", "OPTIONS")
+}
+
+// Returns true if the exchange user can administer the resources of the org. An empty org means any org.
+func adminAuthorized(user string, ud *exchange.UserDefinition, org string) bool {
+	userOrg, _ := cutil.SplitOrgSpecUrl(user)
+	return ud != nil && ud.Admin && (org == "" || userOrg == org || userOrg == "root")
+}
+
+// Authenticate the user of the request with the exchange and verify that it is an admin of the org. The fully qualified
+// user id is returned, and false if a response was written.
+func (a *SecureAPI) authorizeAdmin(resource string, org string, w http.ResponseWriter, r *http.Request) (string, bool) {
+	lan := r.Header.Get("Accept-Language")
+	if lan == "" {
+		lan = i18n.DEFAULT_LANGUAGE
+	}
+	msgPrinter := i18n.GetMessagePrinterWithLocale(lan)
+
+	user, userPasswd, ok := r.BasicAuth()
+	if !ok {
+		glog.Errorf(APIlogString(fmt.Sprintf("%v is called without exchange authentication.", resource)))
+		writeResponse(w, msgPrinter.Sprintf("Unauthorized. No exchange user id is supplied."), http.StatusUnauthorized)
+		return "", false
+	}
+
+	userOrg, userId := cutil.SplitOrgSpecUrl(user)
+	if userOrg == "" || userId == "" || userPasswd == "" {
+		writeResponse(w, msgPrinter.Sprintf("Unauthorized. The exchange user must be specified as org/user:password."), http.StatusUnauthorized)
+		return "", false
+	}
+
+	user_ec := exchange.NewCustomExchangeContext(user, userPasswd, a.Config.AgreementBot.ExchangeURL, a.Config.GetAgbotCSSURL(), newHTTPClientFactory())
+	if ud, err := verifyExchangeUser(a.httpClient, user_ec, msgPrinter); err != nil {
+		glog.Errorf(APIlogString(fmt.Sprintf("Failed to authenticate user %v with the Exchange. %v", user, err)))
+		writeResponse(w, msgPrinter.Sprintf("Failed to authenticate the user with the Exchange. %v", err), http.StatusUnauthorized)
+		return "", false
+	} else if !adminAuthorized(user, ud, org) {
+		glog.Errorf(APIlogString(fmt.Sprintf("User %v is not allowed to call %v for org %v.", user, resource, org)))
+		writeResponse(w, msgPrinter.Sprintf("Forbidden. User %v is not an admin of organization %v.", user, org), http.StatusForbidden)
+		return "", false
+	}
+
+	glog.V(3).Infof(APIlogString(fmt.Sprintf("user %v authorized for %v %v", user, r.Method, resource)))
+	return user, true
+}
+
+// Returns the paused policy from the path of the request, or false if an error response was written.
+func pausedPolicyFromPath(w http.ResponseWriter, r *http.Request) (*persistence.PausedPolicy, bool) {
+	pathVars := mux.Vars(r)
+	if !persistence.IsPausedPolicyType(pathVars["type"]) {
+		writeInputErr(w, http.StatusBadRequest, &APIUserInputError{Input: "type", Error: fmt.Sprintf("must be %v or %v", persistence.PAUSED_DEPLOYMENT_POLICY, persistence.PAUSED_PATTERN)})
+		return nil, false
+	}
+	return &persistence.PausedPolicy{Type: pathVars["type"], Name: fmt.Sprintf("%v/%v", pathVars["org"], pathVars["name"])}, true
+}
+
+// List the paused deployment policies and patterns. Admins of the root org see all of them, other admins see the ones
+// of their own org.
+func (a *SecureAPI) pausedPolicies(w http.ResponseWriter, r *http.Request) {
+
+	resource := "admin/pause"
+
+	switch r.Method {
+	case "GET":
+		user, ok := a.authorizeAdmin(resource, "", w, r)
+		if !ok {
+			return
+		}
+		userOrg, _ := cutil.SplitOrgSpecUrl(user)
+
+		if paused, err := a.db.FindPausedPolicies(); err != nil {
+			glog.Error(APIlogString(fmt.Sprintf("error finding paused policies, error: %v", err)))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		} else {
+			res := make([]persistence.PausedPolicy, 0, len(paused))
+			for _, pp := range paused {
+				if polOrg, _ := cutil.SplitOrgSpecUrl(pp.Name); userOrg == "root" || polOrg == userOrg {
+					res = append(res, pp)
+				}
+			}
+			writeResponse(w, res, http.StatusOK)
+		}
+
+	case "OPTIONS":
+		w.Header().Set("Allow", "GET, OPTIONS")
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// Pause (POST) or resume (DELETE) agreement-making for a deployment policy or pattern. The existing agreements are not
+// affected. The pause is kept in the agbot database, so it applies to all the agbots that share the database.
+func (a *SecureAPI) pausePolicy(w http.ResponseWriter, r *http.Request) {
+
+	resource := "admin/pause"
+
+	switch r.Method {
+	case "POST":
+		pp, ok := pausedPolicyFromPath(w, r)
+		if !ok {
+			return
+		}
+		polOrg, _ := cutil.SplitOrgSpecUrl(pp.Name)
+		if pp.PausedBy, ok = a.authorizeAdmin(resource, polOrg, w, r); !ok {
+			return
+		}
+		pp.PausedAt = uint64(time.Now().Unix())
+
+		if err := a.db.PausePolicy(pp); err != nil {
+			glog.Error(APIlogString(fmt.Sprintf("error pausing %v %v, error: %v", pp.Type, pp.Name, err)))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		} else {
+			glog.V(3).Infof(APIlogString(fmt.Sprintf("paused agreement-making for %v", pp)))
+			writeResponse(w, pp, http.StatusOK)
+		}
+
+	case "DELETE":
+		pp, ok := pausedPolicyFromPath(w, r)
+		if !ok {
+			return
+		}
+		polOrg, _ := cutil.SplitOrgSpecUrl(pp.Name)
+		if _, ok = a.authorizeAdmin(resource, polOrg, w, r); !ok {
+			return
+		}
+
+		// Remember when the policy was paused, so that the node search can pick up the nodes that changed since then.
+		var pausedAt uint64
+		if paused, err := a.db.FindPausedPolicies(); err != nil {
+			glog.Error(APIlogString(fmt.Sprintf("error finding paused policies, error: %v", err)))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		} else {
+			for _, p := range paused {
+				if p.Type == pp.Type && p.Name == pp.Name {
+					pausedAt = p.PausedAt
+				}
+			}
+		}
+
+		if resumed, err := a.db.ResumePolicy(pp.Type, pp.Name); err != nil {
+			glog.Error(APIlogString(fmt.Sprintf("error resuming %v %v, error: %v", pp.Type, pp.Name, err)))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		} else if !resumed {
+			writeInputErr(w, http.StatusBadRequest, &APIUserInputError{Input: "name", Error: fmt.Sprintf("%v %v is not paused", pp.Type, pp.Name)})
+		} else {
+			glog.V(3).Infof(APIlogString(fmt.Sprintf("resumed agreement-making for %v %v", pp.Type, pp.Name)))
+			a.Messages() <- events.NewABApiPolicyResumedMessage(events.POLICY_RESUMED, pp.Type, pp.Name, pausedAt)
+			w.WriteHeader(http.StatusNoContent)
+		}
+
+	case "OPTIONS":
+		w.Header().Set("Allow", "POST, DELETE, OPTIONS")
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// Cancel all the agreements made with a deployment policy or pattern, in the same way as the DELETE of a single agreement.
+// The ids of the cancelled agreements are returned. Unless the policy is paused, the agbots make new agreements with the
+// nodes afterwards.
+func (a *SecureAPI) cancelPolicyAgreements(w http.ResponseWriter, r *http.Request) {
+
+	resource := "admin/cancel"
+
+	switch r.Method {
+	case "POST":
+		pp, ok := pausedPolicyFromPath(w, r)
+		if !ok {
+			return
+		}
+		polOrg, _ := cutil.SplitOrgSpecUrl(pp.Name)
+		if _, ok = a.authorizeAdmin(resource, polOrg, w, r); !ok {
+			return
+		}
+
+		policyFilter := func() persistence.AFilter {
+			return func(ag persistence.Agreement) bool { return ag.AgreementTimedout == 0 && pp.MatchesAgreement(&ag) }
+		}
+
+		cancelled := make([]string, 0)
+		for _, agp := range policy.AllAgreementProtocols() {
+			ags, err := a.db.FindAgreements([]persistence.AFilter{persistence.UnarchivedAFilter(), policyFilter()}, agp)
+			if err != nil {
+				glog.Error(APIlogString(fmt.Sprintf("error finding agreements for %v %v, error: %v", pp.Type, pp.Name, err)))
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			for _, ag := range ags {
+				if _, err := a.db.AgreementTimedout(ag.CurrentAgreementId, ag.AgreementProtocol); err != nil {
+					glog.Errorf(APIlogString(fmt.Sprintf("error marking agreement %v terminated: %v", ag.CurrentAgreementId, err)))
+				}
+				a.Messages() <- events.NewABApiAgreementCancelationMessage(events.AGREEMENT_ENDED, ag.AgreementProtocol, ag.CurrentAgreementId)
+				cancelled = append(cancelled, ag.CurrentAgreementId)
+			}
+		}
+
+		glog.V(3).Infof(APIlogString(fmt.Sprintf("cancelled %v agreements for %v %v", len(cancelled), pp.Type, pp.Name)))
+		writeResponse(w, map[string][]string{"agreements": cancelled}, http.StatusOK)
+
+	case "OPTIONS":
+		w.Header().Set("Allow", "POST, OPTIONS")
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// Re-evaluate a node against all the served deployment policies and patterns right away, instead of waiting for the next
+// node search. The re-evaluation runs in the background.
+func (a *SecureAPI) reevaluateNode(w http.ResponseWriter, r *http.Request) {
+
+	resource := "admin/reevaluate"
+
+	switch r.Method {
+	case "POST":
+		pathVars := mux.Vars(r)
+		org := pathVars["org"]
+		if _, ok := a.authorizeAdmin(resource, org, w, r); !ok {
+			return
+		}
+
+		nodeId := fmt.Sprintf("%v/%v", org, pathVars["node"])
+		glog.V(3).Infof(APIlogString(fmt.Sprintf("re-evaluating node %v", nodeId)))
+		a.Messages() <- events.NewABApiNodeReevaluateMessage(events.NODE_REEVALUATE, nodeId)
+		w.WriteHeader(http.StatusAccepted)
+
+	case "OPTIONS":
+		w.Header().Set("Allow", "POST, OPTIONS")
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// Drain this agbot. It stops making new agreements, waits for the agreements in progress to be finalized or to time out,
+// and then gives up its partition and shards to the other agbots and terminates, the same as the DELETE of /node. It is
+// authorized for the admins of the agbot org.
+func (a *SecureAPI) drain(w http.ResponseWriter, r *http.Request) {
+
+	resource := "admin/drain"
+
+	switch r.Method {
+	case "POST":
+		if _, ok := a.authorizeAdmin(resource, exchange.GetOrg(a.Config.AgreementBot.ExchangeId), w, r); !ok {
+			return
+		}
+
+		glog.V(3).Infof(APIlogString("draining the agbot"))
+		a.Messages() <- events.NewNodeShutdownMessage(events.START_AGBOT_QUIESCE, false, false)
+		w.WriteHeader(http.StatusAccepted)
+
+	case "OPTIONS":
+		w.Header().Set("Allow", "POST, OPTIONS")
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// Rotate the messaging key of this agbot. The new public key is published to the exchange, and the old key can still
+// decrypt messages for the configured grace period. It is authorized for the admins of the agbot org.
+func (a *SecureAPI) messagingKey(w http.ResponseWriter, r *http.Request) {
+
+	resource := "admin/messagingkey"
+
+	switch r.Method {
+	case "POST":
+		if _, ok := a.authorizeAdmin(resource, exchange.GetOrg(a.Config.AgreementBot.ExchangeId), w, r); !ok {
+			return
+		}
+
+		glog.V(3).Infof(APIlogString("rotating the messaging key of the agbot"))
+		a.Messages() <- events.NewMessageKeyRotateMessage(events.ROTATE_MESSAGE_KEY)
+		w.WriteHeader(http.StatusAccepted)
+
+	case "OPTIONS":
+		w.Header().Set("Allow", "POST, OPTIONS")
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
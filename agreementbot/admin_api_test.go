@@ -0,0 +1,209 @@
+// +build unit
+
+package agreementbot
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/open-horizon/anax/agreementbot/persistence/memory"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/events"
+	"github.com/open-horizon/anax/exchange"
+	"github.com/open-horizon/anax/policy"
+	"github.com/open-horizon/anax/worker"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_adminAuthorized(t *testing.T) {
+
+	admin := &exchange.UserDefinition{Admin: true}
+	user := &exchange.UserDefinition{Admin: false}
+
+	if !adminAuthorized("myorg/admin", admin, "myorg") {
+		t.Errorf("an admin of myorg should be authorized for myorg")
+	} else if adminAuthorized("myorg/admin", admin, "otherorg") {
+		t.Errorf("an admin of myorg should not be authorized for otherorg")
+	} else if !adminAuthorized("root/root", admin, "otherorg") {
+		t.Errorf("an admin of the root org should be authorized for any org")
+	} else if adminAuthorized("myorg/user", user, "myorg") {
+		t.Errorf("a user that is not an admin should not be authorized")
+	} else if !adminAuthorized("myorg/admin", admin, "") {
+		t.Errorf("an admin should be authorized when no org is required")
+	} else if adminAuthorized("myorg/admin", nil, "myorg") {
+		t.Errorf("an unknown user should not be authorized")
+	}
+}
+
+// An exchange that knows the users myorg/admin and otherorg/admin, which are admins, and myorg/user, which is not. The
+// password of all of them is "password".
+func getTestAdminAPI(t *testing.T) (*SecureAPI, func()) {
+	users := map[string]bool{"myorg/admin": true, "otherorg/admin": true, "myorg/user": false}
+	exch := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/orgs/"), "/users/")
+		user, pw, _ := r.BasicAuth()
+		if admin, ok := users[strings.Join(parts, "/")]; !ok || len(parts) != 2 || user != strings.Join(parts, "/") || pw != "password" {
+			w.WriteHeader(http.StatusUnauthorized)
+		} else {
+			resp := exchange.GetUsersResponse{Users: map[string]exchange.UserDefinition{user: exchange.UserDefinition{Admin: admin}}}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(resp)
+		}
+	}))
+
+	db := new(memory.AgbotMemoryDB)
+	if err := db.Initialize(&config.HorizonConfig{}); err != nil {
+		t.Fatalf("unable to initialize the agbot database, error %v", err)
+	}
+
+	a := &SecureAPI{
+		Manager: worker.Manager{
+			Config:   &config.HorizonConfig{AgreementBot: config.AGConfig{ExchangeURL: exch.URL + "/", ExchangeId: "myorg/agbot1"}},
+			Messages: make(chan events.Message, 10),
+		},
+		httpClient: exch.Client(),
+		name:       "SecureAPI",
+		db:         db,
+	}
+	return a, exch.Close
+}
+
+// Call the admin API as the given user, an empty user sends no credentials.
+func callAdminAPI(a *SecureAPI, method string, path string, user string, pw string) *httptest.ResponseRecorder {
+	router := mux.NewRouter()
+	a.addAdminRoutes(router)
+
+	r := httptest.NewRequest(method, path, nil)
+	if user != "" {
+		r.SetBasicAuth(user, pw)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	return w
+}
+
+// Returns the event of the next message that the admin API sent to the agbot, or nil when there is none.
+func nextAdminMessage(a *SecureAPI) events.Message {
+	select {
+	case msg := <-a.Messages():
+		return msg
+	default:
+		return nil
+	}
+}
+
+func Test_pausePolicy(t *testing.T) {
+	a, stop := getTestAdminAPI(t)
+	defer stop()
+
+	if w := callAdminAPI(a, "POST", "/admin/pause/policy/myorg/pol1", "myorg/admin", "password"); w.Code != http.StatusBadRequest {
+		t.Errorf("a bad policy type should be a bad request, the status is %v", w.Code)
+	} else if w := callAdminAPI(a, "POST", "/admin/pause/deploymentpol/myorg/pol1", "", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("a request without credentials should be unauthorized, the status is %v", w.Code)
+	} else if w := callAdminAPI(a, "POST", "/admin/pause/deploymentpol/myorg/pol1", "myorg/admin", "wrong"); w.Code != http.StatusUnauthorized {
+		t.Errorf("a request with a wrong password should be unauthorized, the status is %v", w.Code)
+	} else if w := callAdminAPI(a, "POST", "/admin/pause/deploymentpol/myorg/pol1", "myorg/user", "password"); w.Code != http.StatusForbidden {
+		t.Errorf("a user that is not an admin should be forbidden, the status is %v", w.Code)
+	} else if w := callAdminAPI(a, "POST", "/admin/pause/deploymentpol/myorg/pol1", "otherorg/admin", "password"); w.Code != http.StatusForbidden {
+		t.Errorf("an admin of another org should be forbidden, the status is %v", w.Code)
+	}
+
+	if paused, _ := a.db.FindPausedPolicies(); len(paused) != 0 {
+		t.Errorf("a request that is not authorized should not pause the policy, the paused policies are %v", paused)
+	}
+
+	if w := callAdminAPI(a, "POST", "/admin/pause/deploymentpol/myorg/pol1", "myorg/admin", "password"); w.Code != http.StatusOK {
+		t.Errorf("an admin of the org should be able to pause the policy, the status is %v", w.Code)
+	} else if paused, _ := a.db.FindPausedPolicies(); len(paused) != 1 || paused[0].Name != "myorg/pol1" || paused[0].PausedBy != "myorg/admin" {
+		t.Errorf("the policy should be paused by myorg/admin, the paused policies are %v", paused)
+	}
+
+	if w := callAdminAPI(a, "GET", "/admin/pause", "otherorg/admin", "password"); w.Code != http.StatusOK || strings.Contains(w.Body.String(), "myorg/pol1") {
+		t.Errorf("an admin of another org should not see the paused policy, the status is %v, the body is %v", w.Code, w.Body.String())
+	} else if w := callAdminAPI(a, "GET", "/admin/pause", "myorg/admin", "password"); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "myorg/pol1") {
+		t.Errorf("an admin of the org should see the paused policy, the status is %v, the body is %v", w.Code, w.Body.String())
+	}
+
+	if w := callAdminAPI(a, "DELETE", "/admin/pause/deploymentpol/myorg/pol1", "otherorg/admin", "password"); w.Code != http.StatusForbidden {
+		t.Errorf("an admin of another org should not be able to resume the policy, the status is %v", w.Code)
+	} else if w := callAdminAPI(a, "DELETE", "/admin/pause/deploymentpol/myorg/pol1", "myorg/admin", "password"); w.Code != http.StatusNoContent {
+		t.Errorf("an admin of the org should be able to resume the policy, the status is %v", w.Code)
+	} else if msg, ok := nextAdminMessage(a).(*events.ABApiPolicyResumedMessage); !ok || msg.PolicyName != "myorg/pol1" {
+		t.Errorf("resuming the policy should send a policy resumed message, it sent %v", msg)
+	} else if w := callAdminAPI(a, "DELETE", "/admin/pause/deploymentpol/myorg/pol1", "myorg/admin", "password"); w.Code != http.StatusBadRequest {
+		t.Errorf("resuming a policy that is not paused should be a bad request, the status is %v", w.Code)
+	}
+}
+
+func Test_cancelPolicyAgreements(t *testing.T) {
+	a, stop := getTestAdminAPI(t)
+	defer stop()
+
+	if err := a.db.AgreementAttempt("ag1", "myorg", "myorg/node1", "device", "myorg/pol1", "", "", "", policy.BasicProtocol, "", []string{"myorg/svc1"}, policy.NodeHealth{}, 0, 0); err != nil {
+		t.Fatalf("unable to save agreement, error %v", err)
+	} else if err := a.db.AgreementAttempt("ag2", "myorg", "myorg/node2", "device", "myorg/pol2", "", "", "", policy.BasicProtocol, "", []string{"myorg/svc1"}, policy.NodeHealth{}, 0, 0); err != nil {
+		t.Fatalf("unable to save agreement, error %v", err)
+	}
+
+	if w := callAdminAPI(a, "POST", "/admin/cancel/policy/myorg/pol1", "myorg/admin", "password"); w.Code != http.StatusBadRequest {
+		t.Errorf("a bad policy type should be a bad request, the status is %v", w.Code)
+	} else if w := callAdminAPI(a, "POST", "/admin/cancel/deploymentpol/myorg/pol1", "", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("a request without credentials should be unauthorized, the status is %v", w.Code)
+	} else if w := callAdminAPI(a, "POST", "/admin/cancel/deploymentpol/myorg/pol1", "otherorg/admin", "password"); w.Code != http.StatusForbidden {
+		t.Errorf("an admin of another org should be forbidden, the status is %v", w.Code)
+	} else if msg := nextAdminMessage(a); msg != nil {
+		t.Errorf("a request that is not authorized should not cancel agreements, it sent %v", msg)
+	}
+
+	w := callAdminAPI(a, "POST", "/admin/cancel/deploymentpol/myorg/pol1", "myorg/admin", "password")
+	var resp map[string][]string
+	if w.Code != http.StatusOK {
+		t.Errorf("an admin of the org should be able to cancel the agreements, the status is %v", w.Code)
+	} else if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || len(resp["agreements"]) != 1 || resp["agreements"][0] != "ag1" {
+		t.Errorf("only agreement ag1 should be cancelled, the response is %v, error %v", w.Body.String(), err)
+	} else if msg, ok := nextAdminMessage(a).(*events.ABApiAgreementCancelationMessage); !ok || msg.AgreementId != "ag1" {
+		t.Errorf("cancelling the agreements should send an agreement cancelation message for ag1, it sent %v", msg)
+	} else if msg := nextAdminMessage(a); msg != nil {
+		t.Errorf("only one agreement should be cancelled, it also sent %v", msg)
+	}
+}
+
+func Test_reevaluateNode(t *testing.T) {
+	a, stop := getTestAdminAPI(t)
+	defer stop()
+
+	if w := callAdminAPI(a, "POST", "/admin/reevaluate/myorg/node1", "", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("a request without credentials should be unauthorized, the status is %v", w.Code)
+	} else if w := callAdminAPI(a, "POST", "/admin/reevaluate/myorg/node1", "otherorg/admin", "password"); w.Code != http.StatusForbidden {
+		t.Errorf("an admin of another org should be forbidden, the status is %v", w.Code)
+	} else if w := callAdminAPI(a, "POST", "/admin/reevaluate/myorg/node1", "myorg/user", "password"); w.Code != http.StatusForbidden {
+		t.Errorf("a user that is not an admin should be forbidden, the status is %v", w.Code)
+	} else if msg := nextAdminMessage(a); msg != nil {
+		t.Errorf("a request that is not authorized should not re-evaluate the node, it sent %v", msg)
+	} else if w := callAdminAPI(a, "POST", "/admin/reevaluate/myorg/node1", "myorg/admin", "password"); w.Code != http.StatusAccepted {
+		t.Errorf("an admin of the org should be able to re-evaluate the node, the status is %v", w.Code)
+	} else if msg, ok := nextAdminMessage(a).(*events.ABApiNodeReevaluateMessage); !ok || msg.NodeId != "myorg/node1" {
+		t.Errorf("re-evaluating the node should send a node re-evaluate message for myorg/node1, it sent %v", msg)
+	}
+}
+
+func Test_drain(t *testing.T) {
+	a, stop := getTestAdminAPI(t)
+	defer stop()
+
+	if w := callAdminAPI(a, "GET", "/admin/drain", "myorg/admin", "password"); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("drain should only accept POST, the status is %v", w.Code)
+	} else if w := callAdminAPI(a, "POST", "/admin/drain", "", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("a request without credentials should be unauthorized, the status is %v", w.Code)
+	} else if w := callAdminAPI(a, "POST", "/admin/drain", "otherorg/admin", "password"); w.Code != http.StatusForbidden {
+		t.Errorf("an admin of another org than the agbot org should be forbidden, the status is %v", w.Code)
+	} else if msg := nextAdminMessage(a); msg != nil {
+		t.Errorf("a request that is not authorized should not drain the agbot, it sent %v", msg)
+	} else if w := callAdminAPI(a, "POST", "/admin/drain", "myorg/admin", "password"); w.Code != http.StatusAccepted {
+		t.Errorf("an admin of the agbot org should be able to drain the agbot, the status is %v", w.Code)
+	} else if msg, ok := nextAdminMessage(a).(*events.NodeShutdownMessage); !ok || msg.Event().Id != events.START_AGBOT_QUIESCE {
+		t.Errorf("draining should send an agbot quiesce message, it sent %v", msg)
+	}
+}
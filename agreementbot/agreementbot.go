@@ -2,11 +2,13 @@ package agreementbot
 
 import (
 	"bytes"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"github.com/golang/glog"
 	"github.com/open-horizon/anax/abstractprotocol"
 	"github.com/open-horizon/anax/agreementbot/persistence"
+	"github.com/open-horizon/anax/agreementbot/webhook"
 	"github.com/open-horizon/anax/config"
 	"github.com/open-horizon/anax/events"
 	"github.com/open-horizon/anax/exchange"
@@ -29,7 +31,10 @@ const GOVERN_ARCHIVED_AGREEMENTS = "AgBotGovernArchivedAgreements"
 //const GOVERN_BC_NEEDS = "AgBotGovernBlockchain"
 const POLICY_WATCHER = "AgBotPolicyWatcher"
 const STALE_PARTITIONS = "AgbotStaleDatabasePartition"
+const SEARCH_SHARDS = "AgbotSearchShards"
 const MESSAGE_KEY_CHECK = "AgbotMessageKeyCheck"
+const MESSAGE_KEY_ROTATION = "AgbotMessageKeyRotation"
+const WEBHOOK_DELIVERY = "AgbotWebhookDelivery"
 
 // Agreement governance timing state. Used in the GovernAgreements subworker.
 type DVState struct {
@@ -40,6 +45,7 @@ type DVState struct {
 //package level variable
 var patternManager *PatternManager
 var businessPolManager *BusinessPolicyManager
+var webhooks *webhook.Notifier // nil when there are no webhook endpoints
 
 // must be safely-constructed!!
 type AgreementBotWorker struct {
@@ -53,8 +59,9 @@ type AgreementBotWorker struct {
 	GovTiming         DVState
 	shutdownStarted   bool
 	MMSObjectPM       *MMSObjectPolicyManager
-	noworkDispatch    int64       // The last time the NoWorkHandler was dispatched.
-	nodeSearch        *NodeSearch // The object that controls node searches and the state of search sessions.
+	noworkDispatch    int64                  // The last time the NoWorkHandler was dispatched.
+	nodeSearch        *NodeSearch            // The object that controls node searches and the state of search sessions.
+	push              exchange.PushTransport // The transport that pushes messages to and from the nodes, nil when no broker is configured.
 }
 
 func NewAgreementBotWorker(name string, cfg *config.HorizonConfig, db persistence.AgbotDatabase) *AgreementBotWorker {
@@ -171,10 +178,29 @@ func (w *AgreementBotWorker) NewEvent(incoming events.Message) {
 			}
 		}
 
+	case *events.ABApiPolicyResumedMessage:
+		if w.ready {
+			msg, _ := incoming.(*events.ABApiPolicyResumedMessage)
+			switch msg.Event().Id {
+			case events.POLICY_RESUMED:
+				w.Commands <- NewPolicyResumedCommand(*msg)
+			}
+		}
+
+	case *events.ABApiNodeReevaluateMessage:
+		if w.ready {
+			msg, _ := incoming.(*events.ABApiNodeReevaluateMessage)
+			switch msg.Event().Id {
+			case events.NODE_REEVALUATE:
+				w.Commands <- NewNodeReevaluateCommand(*msg)
+			}
+		}
+
 	case *events.NodeShutdownCompleteMessage:
 		msg, _ := incoming.(*events.NodeShutdownCompleteMessage)
 		switch msg.Event().Id {
 		case events.UNCONFIGURE_COMPLETE:
+			w.Commands <- NewStopPushTransportCommand()
 			w.Commands <- worker.NewBeginShutdownCommand()
 			w.Commands <- worker.NewTerminateCommand("shutdown")
 		case events.AGBOT_QUIESCE_COMPLETE:
@@ -188,6 +214,13 @@ func (w *AgreementBotWorker) NewEvent(incoming events.Message) {
 			w.Commands <- NewAgbotShutdownCommand(msg)
 		}
 
+	case *events.MessageKeyRotateMessage:
+		msg, _ := incoming.(*events.MessageKeyRotateMessage)
+		switch msg.Event().Id {
+		case events.ROTATE_MESSAGE_KEY:
+			w.Commands <- NewMessageKeyRotateCommand(exchange.MESSAGE_KEY_ROTATION_REQUESTED)
+		}
+
 	case *events.CacheServicePolicyMessage:
 		msg, _ := incoming.(*events.CacheServicePolicyMessage)
 
@@ -296,6 +329,13 @@ func (w *AgreementBotWorker) Initialize() bool {
 	// Start the go thread that heartbeats to the database.
 	w.DispatchSubworker(DATABASE_HEARTBEAT, w.databaseHeartBeat, int(w.BaseWorker.Manager.Config.GetPartitionStale()/3), false)
 
+	// Start delivering webhook notifications, including the ones that were queued before the agbot was restarted.
+	timeoutS := uint(w.Config.Webhooks.TimeoutS)
+	webhooks = webhook.NewNotifier(&w.Config.Webhooks, w.db, w.GetExchangeId(), w.Config.Collaborators.HTTPClientFactory.NewHTTPClient(&timeoutS))
+	if webhooks != nil {
+		w.DispatchSubworker(WEBHOOK_DELIVERY, w.deliverWebhooks, w.Config.Webhooks.DeliveryIntervalS, true)
+	}
+
 	// Give the policy manager a chance to read in all the policies. The agbot worker will not proceed past this point
 	// until it has some policies to work with.
 	businessPolManager = NewBusinessPolicyManager(w.Messages())
@@ -335,6 +375,13 @@ func (w *AgreementBotWorker) Initialize() bool {
 	// Tell the node search component to initialize itself.
 	w.nodeSearch.Init(w.db, w.pm, w.consumerPH, w.Messages(), w, w.Config)
 
+	// Claim the node search shards of this agbot before the first node search, and keep them balanced with the other agbots
+	// in the cluster as agbots join and leave.
+	if w.Config.GetAgbotSearchShards() != 0 {
+		w.balanceSearchShards()
+		w.DispatchSubworker(SEARCH_SHARDS, w.balanceSearchShards, int(w.BaseWorker.Manager.Config.GetPartitionStale()/3), false)
+	}
+
 	// Make sure that our public key is registered in the exchange so that other parties
 	// can send us messages.
 	if err := w.registerPublicKey(); err != nil {
@@ -342,6 +389,15 @@ func (w *AgreementBotWorker) Initialize() bool {
 		return w.fail()
 	}
 
+	// Receive messages pushed through the MQTT broker, when there is one. The msgEndPoint of the agbot is advertised in
+	// the exchange by the message key check, once the transport is connected.
+	if w.Config.MQTT.IsEnabled() {
+		w.push = exchange.NewMQTTTransport(w.Config, w, exchange.AGBOT_MAILBOX, func(msg *exchange.PushedMessage) {
+			w.Commands <- NewPushedMessageCommand(msg)
+		})
+		exchange.SetPushTransport(w.push)
+	}
+
 	// For each agreement protocol in the current list of configured policies, startup a processor
 	// to initiate the protocol.
 	for protocolName, _ := range w.pm.GetAllAgreementProtocols() {
@@ -374,6 +430,9 @@ func (w *AgreementBotWorker) Initialize() bool {
 	w.DispatchSubworker(GOVERN_ARCHIVED_AGREEMENTS, w.GovernArchivedAgreements, 1800, false)
 	//w.DispatchSubworker(GOVERN_BC_NEEDS, w.GovernBlockchainNeeds, 60, false)
 	w.DispatchSubworker(MESSAGE_KEY_CHECK, w.messageKeyCheck, w.BaseWorker.Manager.Config.AgreementBot.MessageKeyCheck, false)
+	if w.Config.MessageKeyRotation.IsScheduled() {
+		w.DispatchSubworker(MESSAGE_KEY_ROTATION, w.messageKeyRotationCheck, MESSAGE_KEY_ROTATION_CHECK_S, false)
+	}
 
 	if w.Config.AgreementBot.CheckUpdatedPolicyS != 0 {
 		// Use custom subworker APIs for the policy watcher because it is stateful and already does its own time management.
@@ -531,6 +590,17 @@ func (w *AgreementBotWorker) CommandHandler(command worker.Command) bool {
 			}
 		}
 
+	case *PolicyResumedCommand:
+		cmd, _ := command.(*PolicyResumedCommand)
+		w.nodeSearch.PolicyResumed(&persistence.PausedPolicy{Type: cmd.Msg.PolicyType, Name: cmd.Msg.PolicyName, PausedAt: cmd.Msg.PausedAt})
+
+	case *NodeReevaluateCommand:
+		cmd, _ := command.(*NodeReevaluateCommand)
+		// New agreements are not made once the agbot is shutting down.
+		if !w.ShutdownStarted() {
+			go w.nodeSearch.ReevaluateNode(cmd.Msg.NodeId)
+		}
+
 	case *AccountFundedCommand:
 		cmd, _ := command.(*AccountFundedCommand)
 		for _, cph := range w.consumerPH.GetAll() {
@@ -564,6 +634,10 @@ func (w *AgreementBotWorker) CommandHandler(command worker.Command) bool {
 	case *MessageCommand:
 		w.processProtocolMessage()
 
+	case *PushedMessageCommand:
+		cmd, _ := command.(*PushedMessageCommand)
+		w.processPushedMessage(&cmd.Msg)
+
 	case *PatternChangeCommand:
 		cmd, _ := command.(*PatternChangeCommand)
 		go w.generatePolicyFromPatterns(&cmd.Msg)
@@ -588,6 +662,13 @@ func (w *AgreementBotWorker) CommandHandler(command worker.Command) bool {
 		w.shutdownStarted = true
 		glog.V(4).Infof("AgreementBotWorker received start shutdown command")
 
+	case *StopPushTransportCommand:
+		w.stopPushTransport()
+
+	case *MessageKeyRotateCommand:
+		cmd, _ := command.(*MessageKeyRotateCommand)
+		w.rotateMessageKey(cmd.Reason)
+
 	default:
 		return false
 	}
@@ -613,49 +694,73 @@ func (w *AgreementBotWorker) processProtocolMessage() {
 	} else {
 		// Loop through all the returned messages and process them.
 		for _, msg := range msgs {
-
 			glog.V(3).Infof(fmt.Sprintf("AgreementBotWorker reading message %v from the exchange", msg.MsgId))
-			// First get my own keys
-			_, myPrivKey, _ := exchange.GetKeys(w.Config.AgreementBot.MessageKeyPath)
-
-			// Deconstruct and decrypt the message. If there is a problem with the message, it will be deleted.
-			deleteMessage := true
-			if protocolMessage, receivedPubKey, err := exchange.DeconstructExchangeMessage(msg.Message, myPrivKey); err != nil {
-				glog.Errorf(fmt.Sprintf("AgreementBotWorker unable to deconstruct exchange message %v, error %v", msg, err))
-			} else if serializedPubKey, err := exchange.MarshalPublicKey(receivedPubKey); err != nil {
-				glog.Errorf(fmt.Sprintf("AgreementBotWorker unable to marshal the key from the encrypted message %v, error %v", receivedPubKey, err))
-			} else if bytes.Compare(msg.DevicePubKey, serializedPubKey) != 0 {
-				glog.Errorf(fmt.Sprintf("AgreementBotWorker sender public key from exchange %x is not the same as the sender public key in the encrypted message %x", msg.DevicePubKey, serializedPubKey))
-			} else if msgProtocol, err := abstractprotocol.ExtractProtocol(string(protocolMessage)); err != nil {
-				glog.Errorf(fmt.Sprintf("AgreementBotWorker unable to extract agreement protocol name from message %v", protocolMessage))
-			} else if !w.consumerPH.Has(msgProtocol) {
-				glog.Infof(fmt.Sprintf("AgreementBotWorker unable to direct exchange message %v to a protocol handler, deleting it.", protocolMessage))
-				deleteMessage = false
-				DeleteMessage(msg.MsgId, w.GetExchangeId(), w.GetExchangeToken(), w.GetExchangeURL(), w.httpClient)
-			} else {
-				// The message seems to be good, so don't delete it yet, the protocol worker that handles the message will delete it.
-				deleteMessage = false
-
-				// Send the message to a protocol worker.
-				cmd := NewNewProtocolMessageCommand(protocolMessage, msg.MsgId, msg.DeviceId, msg.DevicePubKey)
-				if !w.consumerPH.Get(msgProtocol).AcceptCommand(cmd) {
-					glog.Infof(fmt.Sprintf("AgreementBotWorker protocol handler for %v not accepting exchange messages, deleting msg.", msgProtocol))
-					DeleteMessage(msg.MsgId, w.GetExchangeId(), w.GetExchangeToken(), w.GetExchangeURL(), w.httpClient)
-				} else if err := w.consumerPH.Get(msgProtocol).DispatchProtocolMessage(cmd, w.consumerPH.Get(msgProtocol)); err != nil {
-					DeleteMessage(msg.MsgId, w.GetExchangeId(), w.GetExchangeToken(), w.GetExchangeURL(), w.httpClient)
-				}
+			w.processMessage(&msg)
+		}
+	}
+	glog.V(3).Infof(fmt.Sprintf("AgreementBotWorker done processing messages"))
+}
 
-			}
+// Decrypt a message from a node and dispatch it to the protocol handler of its agreement protocol.
+func (w *AgreementBotWorker) processMessage(msg *exchange.AgbotMessage) {
+	// First get my own keys
+	_, myPrivKey, _ := exchange.GetKeys(w.Config.AgreementBot.MessageKeyPath)
+
+	// Deconstruct and decrypt the message. If there is a problem with the message, it will be deleted.
+	deleteMessage := true
+	if protocolMessage, receivedPubKey, err := exchange.DeconstructExchangeMessage(msg.Message, myPrivKey); err != nil {
+		glog.Errorf(fmt.Sprintf("AgreementBotWorker unable to deconstruct exchange message %v, error %v", msg, err))
+	} else if serializedPubKey, err := exchange.MarshalPublicKey(receivedPubKey); err != nil {
+		glog.Errorf(fmt.Sprintf("AgreementBotWorker unable to marshal the key from the encrypted message %v, error %v", receivedPubKey, err))
+	} else if bytes.Compare(msg.DevicePubKey, serializedPubKey) != 0 {
+		glog.Errorf(fmt.Sprintf("AgreementBotWorker sender public key from exchange %x is not the same as the sender public key in the encrypted message %x", msg.DevicePubKey, serializedPubKey))
+	} else if msgProtocol, err := abstractprotocol.ExtractProtocol(string(protocolMessage)); err != nil {
+		glog.Errorf(fmt.Sprintf("AgreementBotWorker unable to extract agreement protocol name from message %v", protocolMessage))
+	} else if !w.consumerPH.Has(msgProtocol) {
+		glog.Infof(fmt.Sprintf("AgreementBotWorker unable to direct exchange message %v to a protocol handler, deleting it.", protocolMessage))
+		deleteMessage = false
+		DeleteMessage(msg.MsgId, w.GetExchangeId(), w.GetExchangeToken(), w.GetExchangeURL(), w.httpClient)
+	} else {
+		// The message seems to be good, so don't delete it yet, the protocol worker that handles the message will delete it.
+		deleteMessage = false
+
+		// Send the message to a protocol worker.
+		cmd := NewNewProtocolMessageCommand(protocolMessage, msg.MsgId, msg.DeviceId, msg.DevicePubKey)
+		if !w.consumerPH.Get(msgProtocol).AcceptCommand(cmd) {
+			glog.Infof(fmt.Sprintf("AgreementBotWorker protocol handler for %v not accepting exchange messages, deleting msg.", msgProtocol))
+			DeleteMessage(msg.MsgId, w.GetExchangeId(), w.GetExchangeToken(), w.GetExchangeURL(), w.httpClient)
+		} else if err := w.consumerPH.Get(msgProtocol).DispatchProtocolMessage(cmd, w.consumerPH.Get(msgProtocol)); err != nil {
+			DeleteMessage(msg.MsgId, w.GetExchangeId(), w.GetExchangeToken(), w.GetExchangeURL(), w.httpClient)
+		}
 
-			// If anything went wrong trying to decrypt the message or verify its origin, etc, just delete it. These errors aren't
-			// expected to be retryable.
-			if deleteMessage {
-				DeleteMessage(msg.MsgId, w.GetExchangeId(), w.GetExchangeToken(), w.GetExchangeURL(), w.httpClient)
-			}
+	}
 
-		}
+	// If anything went wrong trying to decrypt the message or verify its origin, etc, just delete it. These errors aren't
+	// expected to be retryable.
+	if deleteMessage {
+		DeleteMessage(msg.MsgId, w.GetExchangeId(), w.GetExchangeToken(), w.GetExchangeURL(), w.httpClient)
+	}
+}
+
+// A message pushed by a node is checked with the public key that the exchange has for the node, like the messages in the
+// exchange mailbox of the agbot, to which the exchange adds the key of the sender.
+func (w *AgreementBotWorker) processPushedMessage(pushed *exchange.PushedMessage) {
+	glog.V(3).Infof(fmt.Sprintf("AgreementBotWorker reading message pushed by %v", pushed.SenderId))
+
+	if dev, err := exchange.GetExchangeDevice(w.GetHTTPFactory(), pushed.SenderId, w.GetExchangeId(), w.GetExchangeToken(), w.GetExchangeURL()); err != nil {
+		glog.Errorf(fmt.Sprintf("AgreementBotWorker unable to get node %v of pushed message from the exchange, error: %v", pushed.SenderId, err))
+	} else if pubKey, err := base64.StdEncoding.DecodeString(dev.PublicKey); err != nil {
+		glog.Errorf(fmt.Sprintf("AgreementBotWorker unable to decode public key of node %v, error: %v", pushed.SenderId, err))
+	} else {
+		exchange.SetMessageEndPoint(pushed.SenderId, dev.MsgEndPoint)
+		w.processMessage(&exchange.AgbotMessage{
+			MsgId:        exchange.PUSHED_MESSAGE_ID,
+			DeviceId:     pushed.SenderId,
+			DevicePubKey: pubKey,
+			Message:      pushed.Message,
+			TimeSent:     pushed.TimeSent,
+		})
 	}
-	glog.V(3).Infof(fmt.Sprintf("AgreementBotWorker done processing messages"))
 }
 
 func (w *AgreementBotWorker) NoWorkHandler() {
@@ -724,6 +829,14 @@ func (w *AgreementBotWorker) NoWorkHandler() {
 			// Shutdown the subworkers.
 			w.TerminateSubworkers()
 
+			// Release the node search shards so that the other agbots take them over right away.
+			if err := w.db.ReleaseShards(); err != nil {
+				glog.Errorf(AWlogString(fmt.Sprintf("Error releasing node search shards, error: %v", err)))
+			}
+
+			// Stop receiving pushed messages.
+			w.stopPushTransport()
+
 			// Shutdown the database partition.
 			w.db.QuiescePartition()
 
@@ -841,6 +954,10 @@ func DeleteConsumerAgreement(httpClient *http.Client, url string, agbotId string
 }
 
 func DeleteMessage(msgId int, agbotId, agbotToken, exchangeURL string, httpClient *http.Client) error {
+	if msgId == exchange.PUSHED_MESSAGE_ID {
+		return nil
+	}
+
 	var resp interface{}
 	resp = new(exchange.PostDeviceResponse)
 	targetURL := exchangeURL + "orgs/" + exchange.GetOrg(agbotId) + "/agbots/" + exchange.GetId(agbotId) + "/msgs/" + strconv.Itoa(msgId)
@@ -1298,6 +1415,19 @@ func (w *AgreementBotWorker) databaseHeartBeat() int {
 	return 0
 }
 
+// Renew the leases on our node search shards, and give up or claim shards as agbots join or leave the cluster. This
+// function is called by the search shards subworker.
+func (w *AgreementBotWorker) balanceSearchShards() int {
+
+	if owned, err := w.db.BalanceShards(w.Config.GetAgbotSearchShards(), w.Config.GetPartitionStale()); err != nil {
+		glog.Errorf(AWlogString(fmt.Sprintf("Error balancing node search shards, error: %v", err)))
+	} else {
+		w.nodeSearch.SetShards(owned)
+	}
+
+	return 0
+}
+
 // Ask the database to check for stale partitions and move them into our partition if one is found.
 func (w *AgreementBotWorker) stalePartitions() int {
 
@@ -1320,10 +1450,18 @@ func (w *AgreementBotWorker) stalePartitions() int {
 	return 0
 }
 
+// Deliver the webhook notifications that are due.
+func (w *AgreementBotWorker) deliverWebhooks() int {
+	webhooks.Deliver()
+	return 0
+}
+
 // Ensure that the agbot's message key is still in its object in the exchange. If the agbot itself is missing,
 // we will panic (that should not happen). If the key is missing (i.e. the current key is a zero length byte array)
-// we will add our key back. If there is a key but it is just wrong, we will panic. This latter case could occur if
-// multiple agbots are setup without sharing the same messaging key.
+// we will add our key back. If there is a different key, the key might have been rotated by another agbot sharing the
+// key files, so we reload the keys. If the exchange still has our key from before a rotation, the rotated key could
+// not be published, so we publish it again. Otherwise the key is just wrong and we will panic. This latter case could
+// occur if multiple agbots are setup without sharing the same messaging key.
 func (w *AgreementBotWorker) messageKeyCheck() int {
 
 	glog.V(5).Infof(AWlogString(fmt.Sprintf("checking agbot message key")))
@@ -1361,14 +1499,42 @@ func (w *AgreementBotWorker) messageKeyCheck() int {
 
 			} else if !bytes.Equal(key, agbot.PublicKey) {
 
-				// Make sure the message key in the exchange is our key. If not, exit quickly.
-				msg := AWlogString(fmt.Sprintf("agbot message key has changed from %v to %v", key, agbot.PublicKey))
-				glog.Errorf(msg)
-				panic(msg)
+				// The key files might have been rotated by another agbot, or the key might have been rotated by this agbot since
+				// the check started.
+				keyPath := w.Config.AgreementBot.MessageKeyPath
+				if reloaded, err := exchange.ReloadKeys(keyPath, w.Config.MessageKeyRotation.GraceS); err != nil {
+					glog.Errorf(AWlogString(fmt.Sprintf("unable to reload the messaging key, error: %v", err)))
+				} else if reloaded {
+					glog.Infof(AWlogString(fmt.Sprintf("reloaded the messaging key rotated by another agbot")))
+				}
+				key = exchange.CreateAgbotPublicKeyPatch(keyPath).PublicKey
+
+				if bytes.Equal(key, agbot.PublicKey) {
+					glog.V(3).Infof(AWlogString(fmt.Sprintf("agbot message key was rotated")))
+
+				} else if exchange.IsPreviousPublicKey(agbot.PublicKey) {
+
+					// The rotated key is not in the exchange yet, publish it again.
+					glog.Warningf(AWlogString(fmt.Sprintf("agbot message key in the exchange is the key from before the rotation, publishing %v", key)))
+					if err := w.registerPublicKey(); err != nil {
+						msg := AWlogString(fmt.Sprintf("unable to register public key, error: %v", err))
+						glog.Errorf(msg)
+						panic(msg)
+					}
+
+				} else {
+
+					// Make sure the message key in the exchange is our key. If not, exit quickly.
+					msg := AWlogString(fmt.Sprintf("agbot message key has changed from %v to %v", key, agbot.PublicKey))
+					glog.Errorf(msg)
+					panic(msg)
+				}
 
 			} else {
 				glog.V(5).Infof(AWlogString(fmt.Sprintf("agbot message key is present")))
 			}
+
+			w.advertiseMessageEndPoint(ags[w.GetExchangeId()].MsgEndPoint)
 			return 0
 
 		}
@@ -1376,6 +1542,38 @@ func (w *AgreementBotWorker) messageKeyCheck() int {
 
 }
 
+// Advertise the topic of the push transport as the msgEndPoint of the agbot once the transport is connected, so that the
+// nodes do not push messages to the agbot before it has subscribed to its topic. Without a push transport, a msgEndPoint
+// that was advertised before is removed, so that the nodes send their messages through the exchange again.
+func (w *AgreementBotWorker) advertiseMessageEndPoint(current string) {
+	endPoint := ""
+	if w.push != nil {
+		if !w.push.Connected() {
+			return
+		}
+		endPoint = w.push.EndPoint()
+	}
+
+	if endPoint != current {
+		if err := exchange.PatchMessageEndPoint(w, exchange.AGBOT_MAILBOX, endPoint); err != nil {
+			glog.Errorf(AWlogString(fmt.Sprintf("unable to advertise msgEndPoint %v, error: %v", endPoint, err)))
+		}
+	}
+}
+
+// Stop receiving pushed messages. The msgEndPoint is removed from the exchange before the transport is closed, so that
+// the nodes send their messages through the exchange instead of to a topic that might not be read anymore. The other
+// agbots that share the exchange id advertise the topic again when they check their message key.
+func (w *AgreementBotWorker) stopPushTransport() {
+	if w.push != nil && exchange.GetPushTransport() != nil {
+		exchange.SetPushTransport(nil)
+		if err := exchange.PatchMessageEndPoint(w, exchange.AGBOT_MAILBOX, ""); err != nil {
+			glog.Errorf(AWlogString(fmt.Sprintf("unable to remove the msgEndPoint, error: %v", err)))
+		}
+		w.push.Close()
+	}
+}
+
 // ==========================================================================================================
 // Utility functions
 
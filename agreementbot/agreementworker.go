@@ -8,6 +8,7 @@ import (
 	"github.com/golang/glog"
 	"github.com/open-horizon/anax/abstractprotocol"
 	"github.com/open-horizon/anax/agreementbot/persistence"
+	"github.com/open-horizon/anax/agreementbot/webhook"
 	"github.com/open-horizon/anax/compcheck"
 	"github.com/open-horizon/anax/config"
 	"github.com/open-horizon/anax/cutil"
@@ -16,6 +17,7 @@ import (
 	"github.com/open-horizon/anax/externalpolicy"
 	"github.com/open-horizon/anax/i18n"
 	"github.com/open-horizon/anax/policy"
+	"github.com/open-horizon/anax/tracing"
 	"github.com/open-horizon/anax/worker"
 	"math/rand"
 	"net/http"
@@ -46,9 +48,10 @@ type InitiateAgreement struct {
 	Device                 exchange.SearchResultDevice              // the device entry in the exchange
 	ConsumerPolicyName     string                                   // the name of the consumer policy in the exchange
 	ServicePolicies        map[string]externalpolicy.ExternalPolicy // cached service polices, keyed by service id. it is a subset of the service versions in the consumer policy file
+	SearchSpan             tracing.SpanContext                      // the span of the node search that found the device
 }
 
-func NewInitiateAgreement(pPolicy policy.Policy, cPolicy policy.Policy, org string, device exchange.SearchResultDevice, cpName string, sPols map[string]externalpolicy.ExternalPolicy) AgreementWork {
+func NewInitiateAgreement(pPolicy policy.Policy, cPolicy policy.Policy, org string, device exchange.SearchResultDevice, cpName string, sPols map[string]externalpolicy.ExternalPolicy, searchSpan tracing.SpanContext) AgreementWork {
 	return InitiateAgreement{
 		workType:           INITIATE,
 		ProducerPolicy:     pPolicy,
@@ -57,6 +60,7 @@ func NewInitiateAgreement(pPolicy policy.Policy, cPolicy policy.Policy, org stri
 		Device:             device,
 		ConsumerPolicyName: cpName,
 		ServicePolicies:    sPols,
+		SearchSpan:         searchSpan,
 	}
 }
 
@@ -336,6 +340,20 @@ func (b *BaseAgreementWorker) InitiateNewAgreement(cph ConsumerProtocolHandler,
 	}
 	glog.V(5).Infof(BAWlogstring(workerId, fmt.Sprintf("using AgreementId %v", agreementIdString)))
 
+	// Trace the agreement from here on. The span is linked to the search that found the node.
+	proposed := false
+	span := tracing.StartAgreementSpan("agbot.proposal", agreementIdString)
+	span.AddLink(wi.SearchSpan)
+	span.SetAttribute("node.id", wi.Device.Id)
+	span.SetAttribute("policy.name", wi.ConsumerPolicy.Header.Name)
+	defer func() {
+		span.SetAttribute("proposal.sent", proposed)
+		span.End()
+		if !proposed {
+			tracing.ForgetAgreement(agreementIdString)
+		}
+	}()
+
 	bcType, bcName, bcOrg := (&wi.ProducerPolicy).RequiresKnownBC(cph.Name())
 
 	// Use the blockchain name to choose the handler
@@ -706,12 +724,13 @@ func (b *BaseAgreementWorker) InitiateNewAgreement(cph ConsumerProtocolHandler,
 		glog.Errorf(BAWlogstring(workerId, fmt.Sprintf("error decoding device publicKey for node: %s, %v", wi.Device.Id, err)))
 
 		// Create message target for protocol message
-	} else if mt, err := exchange.CreateMessageTarget(wi.Device.Id, nil, publicKeyBytes, ""); err != nil {
+	} else if mt, err := exchange.CreateMessageTarget(wi.Device.Id, nil, publicKeyBytes, wi.Device.MsgEndPoint); err != nil {
 		glog.Errorf(BAWlogstring(workerId, fmt.Sprintf("error creating message target: %v", err)))
 
 		// Initiate the protocol
 	} else if proposal, err := protocolHandler.InitiateAgreement(agreementIdString, &wi.ProducerPolicy, &wi.ConsumerPolicy, wi.Org, cph.GetExchangeId(), mt, workload, b.config.AgreementBot.DefaultWorkloadPW, b.config.AgreementBot.NoDataIntervalS, cph.GetSendMessage()); err != nil {
 		glog.Errorf(BAWlogstring(workerId, fmt.Sprintf("error initiating agreement: %v", err)))
+		span.SetError(err)
 
 		// Remove pending agreement from database
 		if err := b.db.DeleteAgreement(agreementIdString, cph.Name()); err != nil {
@@ -720,9 +739,14 @@ func (b *BaseAgreementWorker) InitiateNewAgreement(cph ConsumerProtocolHandler,
 
 		// TODO: Publish error on the message bus
 
+	} else {
+		proposed = true
+
 		// Update the agreement in the DB with the proposal and policy
-	} else if err := cph.PersistAgreement(wi, proposal, workerId); err != nil {
-		glog.Errorf(err.Error())
+		if err := cph.PersistAgreement(wi, proposal, workerId); err != nil {
+			glog.Errorf(err.Error())
+			span.SetError(err)
+		}
 	}
 
 }
@@ -759,6 +783,11 @@ func (b *BaseAgreementWorker) HandleAgreementReply(cph ConsumerProtocolHandler,
 	reply := wi.Reply
 	protocolHandler := cph.AgreementProtocolHandler("", "", "") // Use the generic protocol handler
 
+	span := tracing.StartRemoteAgreementSpan("agbot.reply", reply.AgreementId(), reply.TraceContext())
+	span.SetAttribute("node.id", wi.SenderId)
+	span.SetAttribute("proposal.accepted", reply.ProposalAccepted())
+	defer span.End()
+
 	// The reply message is usually deleted before recording on the blockchain. For now assume it will be deleted at the end. Early exit from
 	// this function is NOT allowed.
 	deletedMessage := false
@@ -842,6 +871,15 @@ func (b *BaseAgreementWorker) HandleAgreementReply(cph ConsumerProtocolHandler,
 				}
 
 				if !wlUsage.DisableRetry {
+					// A numerically higher priority value is a lower priority, so the node is rolled back to an earlier workload version.
+					if pol.Workloads[0].Priority.PriorityValue > wlUsage.Priority {
+						webhooks.Notify(webhook.NewAgreementEvent(config.WebhookEventWorkloadRollback, agreement, map[string]interface{}{
+							"service":       cutil.FormOrgSpecUrl(pol.Workloads[0].WorkloadURL, pol.Workloads[0].Org),
+							"version":       pol.Workloads[0].Version,
+							"from_priority": wlUsage.Priority,
+							"to_priority":   pol.Workloads[0].Priority.PriorityValue,
+						}))
+					}
 					if pol.Workloads[0].Priority.PriorityValue != wlUsage.Priority {
 						if _, err := b.db.UpdatePriority(wi.SenderId, consumerPolicy.Header.Name, pol.Workloads[0].Priority.PriorityValue, pol.Workloads[0].Priority.RetryDurationS, pol.Workloads[0].Priority.VerifiedDurationS, reply.AgreementId()); err != nil {
 							glog.Errorf(BAWlogstring(workerId, fmt.Sprintf("error updating workload usage prioroty for device %v with policy %v, error: %v", wi.SenderId, consumerPolicy.Header.Name, err)))
@@ -915,10 +953,15 @@ func (b *BaseAgreementWorker) HandleAgreementReply(cph ConsumerProtocolHandler,
 				droppedLock = true
 				lock.Unlock()
 
+				finalizeSpan := tracing.StartAgreementSpan("agbot.finalize", reply.AgreementId())
 				if err := cph.PostReply(reply.AgreementId(), proposal, reply, consumerPolicy, agreement.Org, workerId); err != nil {
 					glog.Errorf(BAWlogstring(workerId, fmt.Sprintf("error trying to record agreement in blockchain, %v", err)))
+					finalizeSpan.SetError(err)
+					finalizeSpan.End()
 					b.CancelAgreementWithLock(cph, reply.AgreementId(), cph.GetTerminationCode(TERM_REASON_CANCEL_BC_WRITE_FAILED), workerId)
 					ackReplyAsValid = false
+				} else {
+					finalizeSpan.End()
 				}
 
 			}
@@ -953,6 +996,7 @@ func (b *BaseAgreementWorker) HandleAgreementReply(cph ConsumerProtocolHandler,
 		}
 	}
 
+	span.SetAttribute("reply.acked", ackReplyAsValid)
 	return ackReplyAsValid
 
 }
@@ -1083,6 +1127,18 @@ func (b *BaseAgreementWorker) CancelAgreement(cph ConsumerProtocolHandler, agree
 		return false
 	}
 
+	span := tracing.StartAgreementSpan("agbot.cancel", agreementId)
+	span.SetAttribute("cancel.reason", cph.GetTerminationReason(reason))
+	defer func() {
+		span.End()
+		tracing.ForgetAgreement(agreementId)
+	}()
+
+	// An agreement that ends before it was finalized failed, unless the user, a policy change or the node ended it.
+	if ag.AgreementFinalizedTime == 0 && !b.expectedTermination(cph, reason) {
+		webhooks.AgreementFailed(ag, cph.GetTerminationReason(reason))
+	}
+
 	// Update state in exchange
 	if err := DeleteConsumerAgreement(b.config.Collaborators.HTTPClientFactory.NewHTTPClient(nil), b.config.AgreementBot.ExchangeURL, cph.GetExchangeId(), cph.GetExchangeToken(), agreementId); err != nil {
 		glog.Errorf(BAWlogstring(workerId, fmt.Sprintf("error deleting agreement %v in exchange: %v", agreementId, err)))
@@ -1136,6 +1192,16 @@ func (b *BaseAgreementWorker) CancelAgreement(cph ConsumerProtocolHandler, agree
 	return true
 }
 
+// Returns true if the termination reason is not a failure of the agreement.
+func (b *BaseAgreementWorker) expectedTermination(cph ConsumerProtocolHandler, reason uint) bool {
+	for _, r := range []string{TERM_REASON_POLICY_CHANGED, TERM_REASON_USER_REQUESTED, TERM_REASON_CANCEL_FORCED_UPGRADE, TERM_REASON_POLICY_EXPIRED} {
+		if reason == cph.GetTerminationCode(r) {
+			return true
+		}
+	}
+	return cph.IsTerminationReasonNodeShutdown(reason)
+}
+
 // This function is only called when the cancel is deferred due to blockchain unavailability.
 func (b *BaseAgreementWorker) ExternalCancel(cph ConsumerProtocolHandler, agreementId string, reason uint, workerId string) {
 
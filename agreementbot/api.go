@@ -767,12 +767,16 @@ func (a *API) partition(w http.ResponseWriter, r *http.Request) {
 		const AGREEMENT_ACTIVE_KEY = "active agreements"
 		const AGREEMENT_ARCHIVED_KEY = "archived agreements"
 		const WORKLOAD_USAGES_KEY = "workload usages"
+		const SHARDS_KEY = "shards"
 
 		output := make(map[string]map[string]interface{}, 0)
 
 		if partitions, err := a.db.FindPartitions(); err != nil {
 			glog.Error(APIlogString(fmt.Sprintf("error finding all partitions, error: %v", err)))
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		} else if shards, err := a.db.FindShards(); err != nil {
+			glog.Error(APIlogString(fmt.Sprintf("error finding all node search shards, error: %v", err)))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
 		} else {
 
 			// For each partition, get a count of records in the partition.
@@ -786,6 +790,15 @@ func (a *API) partition(w http.ResponseWriter, r *http.Request) {
 					return
 				} else {
 					partitionMaps[PARTITION_OWNER] = owner
+
+					// The node search shards are owned by the agbot that owns the partition.
+					ownedShards := make([]int, 0)
+					for _, s := range shards {
+						if s.Owner == owner {
+							ownedShards = append(ownedShards, s.Id)
+						}
+					}
+					partitionMaps[SHARDS_KEY] = ownedShards
 				}
 
 				// Then get the agreement count.
@@ -163,6 +163,8 @@ func (c *BasicProtocolHandler) GetTerminationCode(reason string) uint {
 		return basicprotocol.AB_CANCEL_NODE_HEARTBEAT
 	case TERM_REASON_AG_MISSING:
 		return basicprotocol.AB_CANCEL_AG_MISSING
+	case TERM_REASON_POLICY_EXPIRED:
+		return basicprotocol.AB_CANCEL_POLICY_EXPIRED
 	default:
 		return 999
 	}
@@ -79,6 +79,8 @@ type BusinessPolicyEntry struct {
 	Updated         uint64                         `json:"updatedTime,omitempty"`     // the time when this entry was updated
 	Hash            []byte                         `json:"hash,omitempty"`            // a hash of the business policy to compare for matadata changes in the exchange
 	ServicePolicies map[string]*ServicePolicyEntry `json:"servicePolicies,omitempty"` // map of the service id and service policies
+	ActiveFrom      uint64                         `json:"activeFrom,omitempty"`      // the time when the business policy becomes active, 0 if it is always active
+	ActiveUntil     uint64                         `json:"activeUntil,omitempty"`     // the time when the business policy expires, 0 if it never expires
 }
 
 // return a pointer to a copy of BusinessPolicyEntry
@@ -110,7 +112,7 @@ func (p *BusinessPolicyEntry) DeepCopy() *BusinessPolicyEntry {
 		}
 	}
 
-	copyBusinessPolicyEntry := BusinessPolicyEntry{Policy: newPolicy, Updated: newUpdated, Hash: newHash, ServicePolicies: newServePolicy}
+	copyBusinessPolicyEntry := BusinessPolicyEntry{Policy: newPolicy, Updated: newUpdated, Hash: newHash, ServicePolicies: newServePolicy, ActiveFrom: p.ActiveFrom, ActiveUntil: p.ActiveUntil}
 	return &copyBusinessPolicyEntry
 
 }
@@ -136,6 +138,7 @@ func NewBusinessPolicyEntry(pol *businesspolicy.BusinessPolicy, polId string) (*
 	} else {
 		pBE.Policy = pPolicy
 	}
+	pBE.setActiveWindow(pol)
 
 	return pBE, nil
 }
@@ -145,8 +148,10 @@ func (p *BusinessPolicyEntry) String() string {
 		"Updated: %v "+
 		"Hash: %x "+
 		"Policy: %v"+
-		"ServicePolicies: %v",
-		p.Updated, p.Hash, p.Policy, p.ServicePolicies)
+		"ServicePolicies: %v "+
+		"ActiveFrom: %v "+
+		"ActiveUntil: %v",
+		p.Updated, p.Hash, p.Policy, p.ServicePolicies, p.ActiveFrom, p.ActiveUntil)
 }
 
 func (p *BusinessPolicyEntry) ShortString() string {
@@ -245,10 +250,35 @@ func (p *BusinessPolicyEntry) UpdateEntry(pol *businesspolicy.BusinessPolicy, po
 		return nil, fmt.Errorf("Failed to convert the business policy to internal policy format: %v. %v", *pol, err)
 	} else {
 		p.Policy = pPolicy
+		p.setActiveWindow(pol)
 		return pPolicy, nil
 	}
 }
 
+// Save the time window in which the business policy is active. The policy has already been validated.
+func (p *BusinessPolicyEntry) setActiveWindow(pol *businesspolicy.BusinessPolicy) {
+	p.ActiveFrom = 0
+	p.ActiveUntil = 0
+	if from, until, err := pol.GetActiveWindow(); err == nil {
+		if !from.IsZero() {
+			p.ActiveFrom = uint64(from.Unix())
+		}
+		if !until.IsZero() {
+			p.ActiveUntil = uint64(until.Unix())
+		}
+	}
+}
+
+// Returns true if agreements can be made with the business policy at the given time.
+func (p *BusinessPolicyEntry) IsActive(now uint64) bool {
+	return now >= p.ActiveFrom && !p.IsExpired(now)
+}
+
+// Returns true if the business policy has expired at the given time.
+func (p *BusinessPolicyEntry) IsExpired(now uint64) bool {
+	return p.ActiveUntil != 0 && now >= p.ActiveUntil
+}
+
 type BusinessPolicyManager struct {
 	spMapLock      sync.Mutex                                 // The lock that protects the map of ServedPolicies because it is referenced from another thread.
 	polMapLock     sync.Mutex                                 // The lock that protects the map of BusinessPolicyEntry because it is referenced from another thread.
@@ -392,6 +422,20 @@ func (pm *BusinessPolicyManager) GetBusinessPolicyEntry(org string, pol *policy.
 	return nil
 }
 
+// Returns true if the business policy with the given name (org/name) has expired.
+func (pm *BusinessPolicyManager) IsBusinessPolicyExpired(org string, polName string, now uint64) bool {
+	pm.polMapLock.Lock()
+	defer pm.polMapLock.Unlock()
+
+	if orgMap, ok := pm.OrgPolicies[org]; ok {
+		_, name := cutil.SplitOrgSpecUrl(polName)
+		if pBE, found := orgMap[name]; found {
+			return pBE.IsExpired(now)
+		}
+	}
+	return false
+}
+
 func (pm *BusinessPolicyManager) GetAllPolicyOrgs() []string {
 	pm.spMapLock.Lock()
 	defer pm.spMapLock.Unlock()
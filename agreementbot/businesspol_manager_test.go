@@ -0,0 +1,53 @@
+// +build unit
+
+package agreementbot
+
+import (
+	"github.com/open-horizon/anax/businesspolicy"
+	_ "github.com/open-horizon/anax/externalpolicy/text_language"
+	"testing"
+)
+
+func Test_BusinessPolicyEntry_ActiveWindow(t *testing.T) {
+
+	pol := &businesspolicy.BusinessPolicy{
+		Service: businesspolicy.ServiceRef{
+			Name:            "cpu",
+			Org:             "mycomp",
+			Arch:            "amd64",
+			ServiceVersions: []businesspolicy.WorkloadChoice{businesspolicy.WorkloadChoice{Version: "1.0.0"}},
+		},
+		ActiveFrom:  "2026-01-01T00:00:00Z",
+		ActiveUntil: "2026-02-01T00:00:00Z",
+	}
+
+	pBE, err := NewBusinessPolicyEntry(pol, "myorg/mypolicy")
+	if err != nil {
+		t.Fatalf("NewBusinessPolicyEntry should not have returned error but got: %v", err)
+	} else if pBE.ActiveFrom != 1767225600 || pBE.ActiveUntil != 1769904000 {
+		t.Errorf("wrong active window %v, %v", pBE.ActiveFrom, pBE.ActiveUntil)
+	} else if pBE.IsActive(pBE.ActiveFrom-1) || !pBE.IsActive(pBE.ActiveFrom) || pBE.IsActive(pBE.ActiveUntil) {
+		t.Errorf("wrong active state for window %v, %v", pBE.ActiveFrom, pBE.ActiveUntil)
+	} else if pBE.IsExpired(pBE.ActiveUntil-1) || !pBE.IsExpired(pBE.ActiveUntil) {
+		t.Errorf("wrong expired state for window %v, %v", pBE.ActiveFrom, pBE.ActiveUntil)
+	}
+
+	pm := NewBusinessPolicyManager(nil)
+	pm.OrgPolicies["myorg"] = map[string]*BusinessPolicyEntry{"mypolicy": pBE}
+	if pm.IsBusinessPolicyExpired("myorg", "myorg/mypolicy", pBE.ActiveFrom) {
+		t.Errorf("policy should not be expired at %v", pBE.ActiveFrom)
+	} else if !pm.IsBusinessPolicyExpired("myorg", "myorg/mypolicy", pBE.ActiveUntil) {
+		t.Errorf("policy should be expired at %v", pBE.ActiveUntil)
+	} else if pm.IsBusinessPolicyExpired("myorg", "myorg/other", pBE.ActiveUntil) {
+		t.Errorf("unknown policy should not be expired")
+	}
+
+	// changing the policy removes the window
+	pol.ActiveFrom = ""
+	pol.ActiveUntil = ""
+	if _, err := pBE.UpdateEntry(pol, "myorg/mypolicy", []byte("newhash")); err != nil {
+		t.Errorf("UpdateEntry should not have returned error but got: %v", err)
+	} else if pBE.ActiveFrom != 0 || pBE.ActiveUntil != 0 || !pBE.IsActive(0) {
+		t.Errorf("active window should be unset but got %v, %v", pBE.ActiveFrom, pBE.ActiveUntil)
+	}
+}
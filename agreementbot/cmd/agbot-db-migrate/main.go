@@ -0,0 +1,96 @@
+// The agbot-db-migrate command copies the agreements, workload usages and search sessions of an agbot bolt database
+// into the postgresql database of the agbot. The agbot that owns the bolt database must be stopped. The postgresql
+// database is configured in the AgreementBot.Postgresql section of the agbot config file. The records are written into
+// a new partition, which is released at the end so that the next agbot that starts, or a running agbot, takes it over.
+// The partition is released also when the migration fails, so that it can be run again.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/agreementbot/persistence"
+	_ "github.com/open-horizon/anax/agreementbot/persistence/bolt"
+	"github.com/open-horizon/anax/agreementbot/persistence/migrate"
+	_ "github.com/open-horizon/anax/agreementbot/persistence/postgresql"
+	"github.com/open-horizon/anax/config"
+	"os"
+)
+
+func main() {
+	configFile := flag.String("config", "/etc/horizon/anax.json", "Agbot config file location, the AgreementBot.Postgresql section configures the target database")
+	boltDir := flag.String("bolt-dir", "", "The directory of the bolt database to migrate, the default is AgreementBot.DBPath of the config file")
+	dryRun := flag.Bool("dry-run", false, "Read and count the records of the bolt database without connecting to the postgresql database")
+
+	flag.Parse()
+
+	err := run(*configFile, *boltDir, *dryRun)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	}
+	glog.Flush()
+	if err != nil {
+		os.Exit(1)
+	}
+}
+
+// Run the migration. The databases are closed before it returns, so it must not exit the process.
+func run(configFile string, boltDir string, dryRun bool) error {
+
+	cfg, err := config.Read(configFile)
+	if err != nil {
+		return fmt.Errorf("unable to read config file %v, error: %v", configFile, err)
+	}
+
+	// The bolt database is opened with a copy of the config that only configures bolt.
+	boltCfg := *cfg
+	if boltDir != "" {
+		boltCfg.AgreementBot.DBPath = boltDir
+	}
+	if !boltCfg.IsBoltDBConfigured() {
+		return fmt.Errorf("the bolt database directory is not configured, use -bolt-dir")
+	}
+
+	src := persistence.DatabaseProviders["bolt"]
+	if err := src.Initialize(&boltCfg); err != nil {
+		return fmt.Errorf("unable to open the bolt database, make sure the agbot is stopped, error: %v", err)
+	}
+	defer src.Close()
+
+	dst := persistence.DatabaseProviders["postgresql"]
+	if !dryRun {
+		// The postgresql database is opened with a copy of the config that does not configure bolt.
+		pgCfg := *cfg
+		pgCfg.AgreementBot.DBPath = ""
+		if !pgCfg.IsPostgresqlConfigured() {
+			return fmt.Errorf("the postgresql database is not configured in %v", configFile)
+		}
+		if err := dst.Initialize(&pgCfg); err != nil {
+			return fmt.Errorf("unable to open the postgresql database, error: %v", err)
+		}
+		defer dst.Close()
+	}
+
+	res, err := migrate.Migrate(src, dst, dryRun)
+	if res != nil {
+		if out, jerr := json.MarshalIndent(res, "", "  "); jerr == nil {
+			fmt.Println(string(out))
+		}
+	}
+
+	if !dryRun {
+		// Release the partition so that an agbot takes over the migrated records. This is also done when the migration
+		// failed, the records written so far are found in that partition when the migration is run again.
+		if qerr := dst.QuiescePartition(); qerr != nil && err == nil {
+			return fmt.Errorf("unable to release partition %v, error: %v", res.Partition, qerr)
+		} else if qerr != nil {
+			glog.Errorf("Unable to release partition %v, error: %v", res.Partition, qerr)
+		}
+	}
+
+	if err != nil {
+		return fmt.Errorf("migration failed, error: %v", err)
+	}
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/open-horizon/anax/exchange"
 	"github.com/open-horizon/anax/externalpolicy"
 	"github.com/open-horizon/anax/policy"
+	"github.com/open-horizon/anax/tracing"
 )
 
 // ==============================================================================================================
@@ -108,6 +109,36 @@ func NewWorkloadUpgradeCommand(msg events.ABApiWorkloadUpgradeMessage) *Workload
 	}
 }
 
+// ==============================================================================================================
+type PolicyResumedCommand struct {
+	Msg events.ABApiPolicyResumedMessage
+}
+
+func (e PolicyResumedCommand) ShortString() string {
+	return e.Msg.ShortString()
+}
+
+func NewPolicyResumedCommand(msg events.ABApiPolicyResumedMessage) *PolicyResumedCommand {
+	return &PolicyResumedCommand{
+		Msg: msg,
+	}
+}
+
+// ==============================================================================================================
+type NodeReevaluateCommand struct {
+	Msg events.ABApiNodeReevaluateMessage
+}
+
+func (e NodeReevaluateCommand) ShortString() string {
+	return e.Msg.ShortString()
+}
+
+func NewNodeReevaluateCommand(msg events.ABApiNodeReevaluateMessage) *NodeReevaluateCommand {
+	return &NodeReevaluateCommand{
+		Msg: msg,
+	}
+}
+
 // ==============================================================================================================
 type MakeAgreementCommand struct {
 	ProducerPolicy     policy.Policy                            // the producer policy received from the exchange
@@ -116,6 +147,7 @@ type MakeAgreementCommand struct {
 	ConsumerPolicyName string                                   // the name of the consumer policy in the exchange
 	Device             exchange.SearchResultDevice              // the device entry in the exchange
 	ServicePolicies    map[string]externalpolicy.ExternalPolicy // cached service polices, keyed by service id. it is a subset of the service versions in the consumer policy file
+	SearchSpan         tracing.SpanContext                      // the span of the node search that found the device
 }
 
 func (e MakeAgreementCommand) ShortString() string {
@@ -262,6 +294,21 @@ func NewMMSObjectPolicyEventCommand(msg *events.MMSObjectPolicyMessage) *MMSObje
 	}
 }
 
+// ==============================================================================================================
+type PushedMessageCommand struct {
+	Msg exchange.PushedMessage
+}
+
+func (p PushedMessageCommand) ShortString() string {
+	return p.Msg.String()
+}
+
+func NewPushedMessageCommand(msg *exchange.PushedMessage) *PushedMessageCommand {
+	return &PushedMessageCommand{
+		Msg: *msg,
+	}
+}
+
 // ==============================================================================================================
 type MessageCommand struct {
 	Msg events.ExchangeChangeMessage
@@ -360,3 +407,30 @@ func (e ServedPolicyCommand) ShortString() string {
 func NewServedPolicyCommand() *ServedPolicyCommand {
 	return &ServedPolicyCommand{}
 }
+
+// ==============================================================================================================
+type MessageKeyRotateCommand struct {
+	Reason string
+}
+
+func (e MessageKeyRotateCommand) ShortString() string {
+	return fmt.Sprintf("MessageKeyRotateCommand Reason: %v", e.Reason)
+}
+
+func NewMessageKeyRotateCommand(reason string) *MessageKeyRotateCommand {
+	return &MessageKeyRotateCommand{
+		Reason: reason,
+	}
+}
+
+// ==============================================================================================================
+type StopPushTransportCommand struct {
+}
+
+func (e StopPushTransportCommand) ShortString() string {
+	return "StopPushTransportCommand"
+}
+
+func NewStopPushTransportCommand() *StopPushTransportCommand {
+	return &StopPushTransportCommand{}
+}
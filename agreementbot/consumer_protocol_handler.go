@@ -14,6 +14,7 @@ import (
 	"github.com/open-horizon/anax/exchange"
 	"github.com/open-horizon/anax/metering"
 	"github.com/open-horizon/anax/policy"
+	"github.com/open-horizon/anax/tracing"
 	"github.com/open-horizon/anax/worker"
 	"net/http"
 	"time"
@@ -159,6 +160,7 @@ func (w *BaseConsumerProtocolHandler) sendMessage(mt interface{}, pay []byte) er
 	if err != nil {
 		return fmt.Errorf("Unable to get device from exchange: %v", err)
 	}
+	exchange.SetMessageEndPoint(messageTarget.ReceiverExchangeId, exchDev.MsgEndPoint)
 	maxHb := exchDev.HeartbeatIntv.MaxInterval
 	if maxHb == 0 {
 		exchOrg, err := exchange.GetOrganization(w.GetHTTPFactory(), exchange.GetOrg(messageTarget.ReceiverExchangeId), w.config.AgreementBot.ExchangeURL, w.agbotId, w.token)
@@ -175,26 +177,13 @@ func (w *BaseConsumerProtocolHandler) sendMessage(mt interface{}, pay []byte) er
 		// Marshal it into a byte array
 	} else if msgBody, err := json.Marshal(encryptedMsg); err != nil {
 		return errors.New(fmt.Sprintf("Unable to marshal exchange message, error %v for message %v", err, encryptedMsg))
-		// Send it to the device's message queue
+		// Send it to the device, through the exchange or pushed to the device when it advertises a msgEndPoint
+	} else if err := exchange.DeliverMessage(exchange.NewExchangeTransport(w, w.httpClient), exchange.NODE_MAILBOX, messageTarget, msgBody, exchangeMessageTTL); err != nil {
+		return err
 	} else {
-		pm := exchange.CreatePostMessage(msgBody, exchangeMessageTTL)
-		var resp interface{}
-		resp = new(exchange.PostDeviceResponse)
-		targetURL := w.config.AgreementBot.ExchangeURL + "orgs/" + exchange.GetOrg(messageTarget.ReceiverExchangeId) + "/nodes/" + exchange.GetId(messageTarget.ReceiverExchangeId) + "/msgs"
-		for {
-			if err, tpErr := exchange.InvokeExchange(w.httpClient, "POST", targetURL, w.agbotId, w.token, pm, &resp); err != nil {
-				return err
-			} else if tpErr != nil {
-				glog.Warningf(tpErr.Error())
-				time.Sleep(10 * time.Second)
-				continue
-			} else {
-				glog.V(5).Infof(BCPHlogstring(w.Name(), fmt.Sprintf("sent message for %v to exchange.", messageTarget.ReceiverExchangeId)))
-				return nil
-			}
-		}
+		glog.V(5).Infof(BCPHlogstring(w.Name(), fmt.Sprintf("sent message for %v.", messageTarget.ReceiverExchangeId)))
+		return nil
 	}
-
 }
 
 func (b *BaseConsumerProtocolHandler) DispatchProtocolMessage(cmd *NewProtocolMessageCommand, cph ConsumerProtocolHandler) error {
@@ -219,6 +208,9 @@ func (b *BaseConsumerProtocolHandler) DispatchProtocolMessage(cmd *NewProtocolMe
 		} else if ag.DeviceId != cmd.From {
 			glog.Warningf(BCPHlogstring(b.Name(), fmt.Sprintf("cancel ignored, cancel message for %v came from id %v but agreement is with %v", can.AgreementId(), cmd.From, ag.DeviceId)))
 		} else {
+			// Record the receipt of the cancel so that the agbot's cancel span becomes a child of the node's span.
+			tracing.StartRemoteAgreementSpan("agbot.cancel_received", can.AgreementId(), can.TraceContext()).End()
+
 			agreementWork := NewCancelAgreement(can.AgreementId(), can.Protocol(), can.Reason(), cmd.MessageId)
 			cph.WorkQueue().InboundHigh() <- &agreementWork
 			glog.V(5).Infof(BCPHlogstring(b.Name(), fmt.Sprintf("queued cancel message")))
@@ -409,7 +401,7 @@ func (b *BaseConsumerProtocolHandler) HandleWorkloadUpgrade(cmd *WorkloadUpgrade
 
 func (b *BaseConsumerProtocolHandler) HandleMakeAgreement(cmd *MakeAgreementCommand, cph ConsumerProtocolHandler) {
 	glog.V(5).Infof(BCPHlogstring(b.Name(), fmt.Sprintf("received make agreement command.")))
-	agreementWork := NewInitiateAgreement(cmd.ProducerPolicy, cmd.ConsumerPolicy, cmd.Org, cmd.Device, cmd.ConsumerPolicyName, cmd.ServicePolicies)
+	agreementWork := NewInitiateAgreement(cmd.ProducerPolicy, cmd.ConsumerPolicy, cmd.Org, cmd.Device, cmd.ConsumerPolicyName, cmd.ServicePolicies, cmd.SearchSpan)
 	cph.WorkQueue().InboundLow() <- &agreementWork
 	glog.V(5).Infof(BCPHlogstring(b.Name(), fmt.Sprintf("queued make agreement command.")))
 }
@@ -529,6 +521,7 @@ func (b *BaseConsumerProtocolHandler) GetDeviceMessageEndpoint(deviceId string,
 		return "", nil, errors.New(fmt.Sprintf("Error decoding device publicKey for %s, %v", deviceId, err))
 	} else {
 		glog.V(5).Infof(BCPHlogstring2(workerId, fmt.Sprintf("retrieved device %v msg endpoint from exchange %v", deviceId, dev.MsgEndPoint)))
+		exchange.SetMessageEndPoint(deviceId, dev.MsgEndPoint)
 		return dev.MsgEndPoint, publicKeyBytes, nil
 	}
 
@@ -622,6 +615,7 @@ const TERM_REASON_CANCEL_FORCED_UPGRADE = "ForceUpgrade"
 const TERM_REASON_CANCEL_BC_WRITE_FAILED = "WriteFailed"
 const TERM_REASON_NODE_HEARTBEAT = "NodeHeartbeat"
 const TERM_REASON_AG_MISSING = "AgreementMissing"
+const TERM_REASON_POLICY_EXPIRED = "PolicyExpired"
 
 var BCPHlogstring = func(p string, v interface{}) string {
 	return fmt.Sprintf("Base Consumer Protocol Handler (%v) %v", p, v)
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"github.com/golang/glog"
 	"github.com/open-horizon/anax/agreementbot/persistence"
+	"github.com/open-horizon/anax/agreementbot/webhook"
+	"github.com/open-horizon/anax/config"
 	"github.com/open-horizon/anax/cutil"
 	"github.com/open-horizon/anax/events"
 	"github.com/open-horizon/anax/exchange"
@@ -57,6 +59,13 @@ func (w *AgreementBotWorker) GovernAgreements() int {
 
 			for _, ag := range agreements {
 
+				// Cancel the agreements that were made with a deployment policy that is past its active window.
+				if ag.Pattern == "" && businessPolManager.IsBusinessPolicyExpired(ag.Org, ag.PolicyName, uint64(time.Now().Unix())) {
+					glog.V(3).Infof(logString(fmt.Sprintf("cancelling agreement %v because deployment policy %v has expired", ag.CurrentAgreementId, ag.PolicyName)))
+					w.TerminateAgreement(&ag, protocolHandler.GetTerminationCode(TERM_REASON_POLICY_EXPIRED))
+					continue
+				}
+
 				// Govern agreements that have seen a reply from the device
 				if protocolHandler.AlreadyReceivedReply(&ag) {
 
@@ -414,10 +423,17 @@ func (w *AgreementBotWorker) VerifyNodeHealth(ag *persistence.Agreement, cph Con
 
 	// If this agreement's node is out of policy, cancel the agreement and remove the node from the cache.
 	// If the agreement is missing, cancel it.
+	// The node out of policy event is raised once each time the node misses its heartbeat, not for each of its agreements.
 	if w.NHManager.NodeOutOfPolicy(ag.Pattern, ag.Org, ag.DeviceId, ag.NHMissingHBInterval) {
+		if w.NHManager.ReportNodeOutOfPolicy(ag.Pattern, ag.Org, ag.DeviceId) {
+			webhooks.Notify(webhook.NewAgreementEvent(config.WebhookEventNodeOutOfPolicy, ag, map[string]interface{}{"missing_heartbeat_interval": ag.NHMissingHBInterval}))
+		}
 		w.TerminateAgreement(ag, cph.GetTerminationCode(TERM_REASON_NODE_HEARTBEAT))
-	} else if w.NHManager.AgreementOutOfPolicy(ag.Pattern, ag.Org, ag.DeviceId, ag.CurrentAgreementId, ag.AgreementFinalizedTime, ag.NHCheckAgreementStatus) {
-		w.TerminateAgreement(ag, cph.GetTerminationCode(TERM_REASON_AG_MISSING))
+	} else {
+		w.NHManager.NodeInPolicy(ag.DeviceId)
+		if w.NHManager.AgreementOutOfPolicy(ag.Pattern, ag.Org, ag.DeviceId, ag.CurrentAgreementId, ag.AgreementFinalizedTime, ag.NHCheckAgreementStatus) {
+			w.TerminateAgreement(ag, cph.GetTerminationCode(TERM_REASON_AG_MISSING))
+		}
 	}
 
 	return ag.NHCheckAgreementStatus, nil
@@ -0,0 +1,38 @@
+package agreementbot
+
+import (
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/exchange"
+)
+
+// The number of seconds between checks for a scheduled rotation of the messaging key.
+const MESSAGE_KEY_ROTATION_CHECK_S = 60
+
+// The subworker that asks for a rotation of the messaging key when it is older than the rotation interval. The rotation
+// itself happens on the command thread, like a rotation that is requested through the admin API.
+func (w *AgreementBotWorker) messageKeyRotationCheck() int {
+	if exchange.MessagingKeyRotationDue(w.Config.AgreementBot.MessageKeyPath, w.Config.MessageKeyRotation.IntervalS) {
+		w.Commands <- NewMessageKeyRotateCommand(exchange.MESSAGE_KEY_ROTATION_SCHEDULED)
+	}
+	return 0
+}
+
+// Rotate the messaging key of the agbot and publish the new public key to the exchange. Until the nodes see the new key,
+// they encrypt their messages with the old key, which can still decrypt messages during the grace period. A key that
+// could not be published is published again by the message key check.
+func (w *AgreementBotWorker) rotateMessageKey(reason string) {
+
+	keyPath := w.Config.AgreementBot.MessageKeyPath
+
+	// A scheduled rotation might have been queued twice.
+	if reason == exchange.MESSAGE_KEY_ROTATION_SCHEDULED && !exchange.MessagingKeyRotationDue(keyPath, w.Config.MessageKeyRotation.IntervalS) {
+		return
+	}
+
+	if err := exchange.RotateMessagingKey(w, exchange.AGBOT_MAILBOX, keyPath, w.Config.MessageKeyRotation.GraceS); err != nil {
+		glog.Errorf(AWlogString(fmt.Sprintf("error rotating the messaging key (%v): %v", reason, err)))
+	} else {
+		glog.Infof(AWlogString(fmt.Sprintf("messaging key rotated (%v), the previous key can decrypt messages for %v seconds", reason, w.Config.MessageKeyRotation.GraceS)))
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/open-horizon/anax/exchange"
 	"github.com/open-horizon/anax/externalpolicy"
 	"github.com/open-horizon/anax/policy"
+	"github.com/open-horizon/anax/tracing"
 	"sync"
 	"time"
 )
@@ -30,13 +31,17 @@ type NodeSearch struct {
 	lastSearchComplete   bool
 	lastSearchTime       uint64
 	searchThread         chan bool
-	rescanLock           sync.Mutex // The lock that protects the rescanNeeded flag. The rescanNeeded flag can be checked/changed on different threads.
-	rescanNeeded         bool       // A broad indicator that something policy or pattern related changed, and therefore the agbot needs to rescan all nodes.
-	batchSize            uint64     // The max number of nodes that this object will process in a deployment policy search result.
-	activeDeviceTimeoutS int        // The amount of time a device can go without heartbeating and still be considered active for the purposes of search.
-	retryLookBack        uint64     // The amount of time to look backward for node changes when node retries are happening.
-	policyOrder          bool       // When true, order policies most recently changed to least recently changed.
-	clearExchangeCache   bool       // When true, the exchange cache will be deleted after a seach is made with devices returned.
+	rescanLock           sync.Mutex   // The lock that protects the rescanNeeded flag. The rescanNeeded flag can be checked/changed on different threads.
+	rescanNeeded         bool         // A broad indicator that something policy or pattern related changed, and therefore the agbot needs to rescan all nodes.
+	rescanAt             uint64       // The time when a rescan of all nodes is needed, e.g. when a deployment policy becomes active, zero when none is scheduled.
+	batchSize            uint64       // The max number of nodes that this object will process in a deployment policy search result.
+	activeDeviceTimeoutS int          // The amount of time a device can go without heartbeating and still be considered active for the purposes of search.
+	retryLookBack        uint64       // The amount of time to look backward for node changes when node retries are happening.
+	policyOrder          bool         // When true, order policies most recently changed to least recently changed.
+	clearExchangeCache   bool         // When true, the exchange cache will be deleted after a seach is made with devices returned.
+	shardCount           int          // The number of shards that the policies are split into, zero when sharding is off.
+	shardLock            sync.Mutex   // The lock that protects the owned shards. The shards are changed and checked on different threads.
+	shards               map[int]bool // The shards owned by this agbot, it only searches for nodes for the policies in these shards.
 }
 
 func NewNodeSearch() *NodeSearch {
@@ -48,6 +53,7 @@ func NewNodeSearch() *NodeSearch {
 		searchThread:        make(chan bool, 10),
 		rescanNeeded:        false,
 		clearExchangeCache:  false,
+		shards:              make(map[int]bool),
 	}
 	return ns
 }
@@ -66,6 +72,7 @@ func (n *NodeSearch) Init(db persistence.AgbotDatabase, pm *policy.PolicyManager
 	n.activeDeviceTimeoutS = cfg.AgreementBot.ActiveDeviceTimeoutS
 	n.retryLookBack = cfg.GetAgbotRetryLookBackWindow()
 	n.policyOrder = cfg.GetAgbotPolicyOrder()
+	n.shardCount = cfg.GetAgbotSearchShards()
 
 	// Set the time of the worker restart to 1 minute ago. This time is used to indicate that the node searches need to go backward in time
 	// because this agbot just restarted, and therefore could have lost search results that were in memory but the database was
@@ -101,13 +108,62 @@ func (n *NodeSearch) UnsetRescanNeeded() {
 	n.rescanNeeded = false
 }
 
-// Check if a node rescan is needed. This function is thread safe.
+// Indicate that a rescan of all nodes is needed at the given time. Only the earliest scheduled time is kept, later ones
+// are scheduled again by the scan that starts at the earliest time. This function is thread safe.
+func (n *NodeSearch) SetRescanNeededAt(at uint64) {
+	n.rescanLock.Lock()
+	defer n.rescanLock.Unlock()
+	if n.rescanAt == 0 || at < n.rescanAt {
+		n.rescanAt = at
+	}
+}
+
+// Check if a node rescan is needed, either because it was requested or because the scheduled rescan time has been reached.
+// This function is thread safe.
 func (n *NodeSearch) IsRescanNeeded() bool {
 	n.rescanLock.Lock()
 	defer n.rescanLock.Unlock()
+	if n.rescanAt != 0 && uint64(time.Now().Unix()) >= n.rescanAt {
+		n.rescanNeeded = true
+		n.rescanAt = 0
+	}
 	return n.rescanNeeded
 }
 
+// Set the shards that this agbot owns. When the agbot gains a shard, the policies in that shard have to be searched right
+// away, so a rescan is requested. This function is thread safe.
+func (n *NodeSearch) SetShards(owned []int) {
+	n.shardLock.Lock()
+	defer n.shardLock.Unlock()
+
+	shards := make(map[int]bool)
+	gained := false
+	for _, shard := range owned {
+		shards[shard] = true
+		if !n.shards[shard] {
+			gained = true
+		}
+	}
+	if gained || len(shards) != len(n.shards) {
+		glog.V(3).Infof(AWlogString(fmt.Sprintf("searching for nodes for shards %v of %v", owned, n.shardCount)))
+	}
+	n.shards = shards
+	if gained {
+		n.SetRescanNeeded()
+	}
+}
+
+// Check if this agbot searches for nodes for a policy, because it owns the shard of the policy. All the policies are
+// searched when sharding is off. This function is thread safe.
+func (n *NodeSearch) OwnsPolicy(policyName string) bool {
+	if n.shardCount == 0 {
+		return true
+	}
+	n.shardLock.Lock()
+	defer n.shardLock.Unlock()
+	return n.shards[persistence.ShardOf(policyName, n.shardCount)]
+}
+
 // This is the main driving function in this object. It will initiate a node scan if needed, using an exiting search session or obtain a new one if needed.
 // The actual processing of a node scan for all policies and patterns is actually performed on a sub-thread. This function also also handles updating
 // itself if a previous scan has completed since the last time this method was called.
@@ -156,6 +212,16 @@ func (n *NodeSearch) findAndMakeAgreements() {
 	// time and the same search session.
 	searchError := false
 
+	// Agreement-making can be paused for some of the policies, there is no need to search for nodes for them. If the paused
+	// policies cant be read, try again later rather than make agreements for a paused policy.
+	paused, err := n.db.FindPausedPolicies()
+	if err != nil {
+		glog.Errorf(AWlogString(fmt.Sprintf("unable to read paused policies, error: %v", err)))
+		n.SetRescanNeeded()
+		n.searchThread <- true
+		return
+	}
+
 	// allow clearing the cache for all the exchange resources, the searchNodesAndMakeAgreements
 	// function will clear the cache and set it false after it finds devices to make agreements.
 	n.clearExchangeCache = true
@@ -172,6 +238,18 @@ func (n *NodeSearch) findAndMakeAgreements() {
 
 		for _, consumerPolicy := range availablePolicies {
 
+			// Clustered agbots split the policies between them, the other agbots search for nodes for the policies that
+			// are not in the shards of this agbot.
+			if !n.OwnsPolicy(consumerPolicy.Header.Name) {
+				glog.V(5).Infof(AWlogString(fmt.Sprintf("skipping policy %v, it is in shard %v which is owned by another agbot", consumerPolicy.Header.Name, persistence.ShardOf(consumerPolicy.Header.Name, n.shardCount))))
+				continue
+			}
+
+			if pp := persistence.FindPausedPolicy(paused, &consumerPolicy); pp != nil {
+				glog.V(5).Infof(AWlogString(fmt.Sprintf("skipping policy %v, agreement-making for %v %v was paused by %v", consumerPolicy.Header.Name, pp.Type, pp.Name, pp.PausedBy)))
+				continue
+			}
+
 			// Search for nodes based on the current changedSince timestamp to pick up any newly changed nodes.
 			if consumerPolicy.PatternId != "" {
 				if _, err := n.searchNodesAndMakeAgreements(&consumerPolicy, org, "", 0); err != nil {
@@ -181,7 +259,23 @@ func (n *NodeSearch) findAndMakeAgreements() {
 				}
 			} else if pBE := businessPolManager.GetBusinessPolicyEntry(org, &consumerPolicy); pBE != nil {
 				_, polName := cutil.SplitOrgSpecUrl(consumerPolicy.Header.Name)
-				if lastPage, err := n.searchNodesAndMakeAgreements(&consumerPolicy, org, polName, pBE.Updated); err != nil {
+
+				// Agreements are only made while the deployment policy is active. The first search after the policy becomes
+				// active has to look at all the nodes, as if the policy had just changed, so a rescan is scheduled for then.
+				now := uint64(time.Now().Unix())
+				if !pBE.IsActive(now) {
+					glog.V(5).Infof(AWlogString(fmt.Sprintf("skipping deployment policy %v, it is only active from %v until %v", consumerPolicy.Header.Name, pBE.ActiveFrom, pBE.ActiveUntil)))
+					if now < pBE.ActiveFrom {
+						n.SetRescanNeededAt(pBE.ActiveFrom)
+					}
+					continue
+				}
+				polLastUpdateTime := pBE.Updated
+				if pBE.ActiveFrom > polLastUpdateTime {
+					polLastUpdateTime = pBE.ActiveFrom
+				}
+
+				if lastPage, err := n.searchNodesAndMakeAgreements(&consumerPolicy, org, polName, polLastUpdateTime); err != nil {
 					// Dont move the changed since time forward since there was an error.
 					searchError = true
 					break
@@ -238,37 +332,24 @@ func (n *NodeSearch) searchNodesAndMakeAgreements(consumerPolicy *policy.Policy,
 
 	endOfResults := true
 
+	span := tracing.StartSpan("agbot.search", tracing.SpanContext{})
+	span.SetAttribute("policy.name", polName)
+	span.SetAttribute("policy.org", org)
+	defer span.End()
+
 	if devices, err := n.searchExchange(consumerPolicy, org, polName, polLastUpdateTime); err != nil {
 		glog.Errorf(AWlogString(fmt.Sprintf("received error searching for %v, error: %v", consumerPolicy, err)))
+		span.SetError(err)
 		return endOfResults, err
 
 	} else {
+		span.SetAttribute("nodes.found", len(*devices))
 
 		// Remember whether or not this search returned all the possible nodes.
 		if uint64(len(*devices)) == n.batchSize {
 			endOfResults = false
 		}
 
-		// Get all the agreements for this policy that are still active.
-		pendingAgreementFilter := func() persistence.AFilter {
-			return func(a persistence.Agreement) bool {
-				return a.PolicyName == consumerPolicy.Header.Name && a.AgreementTimedout == 0
-			}
-		}
-
-		ags := make(map[string][]persistence.Agreement)
-
-		// The agreements with this policy could be part of any supported agreement protocol.
-		for _, agp := range policy.AllAgreementProtocols() {
-			// Find all agreements that are in progress. They might be waiting for a reply or not yet finalized.
-			// TODO: To support more than 1 agreement (maxagreements > 1) with this device for this policy, we need to adjust this logic.
-			if agreements, err := n.db.FindAgreements([]persistence.AFilter{persistence.UnarchivedAFilter(), pendingAgreementFilter()}, agp); err != nil {
-				glog.Errorf(AWlogString(fmt.Sprintf("received error trying to find pending agreements for protocol %v: %v", agp, err)))
-			} else {
-				ags[agp] = agreements
-			}
-		}
-
 		// For each Scan(), clear the cache only once when there are devices returned from the search api.
 		if n.clearExchangeCache && len(*devices) != 0 {
 			glog.V(5).Infof("Clearing cache for all resources.")
@@ -276,62 +357,88 @@ func (n *NodeSearch) searchNodesAndMakeAgreements(consumerPolicy *policy.Policy,
 			n.clearExchangeCache = false
 		}
 
-		for _, dev := range *devices {
+		n.makeAgreements(consumerPolicy, org, polName, *devices, span)
+	}
 
-			glog.V(3).Infof(AWlogString(fmt.Sprintf("picked up %v for policy %v.", dev.ShortString(), consumerPolicy.Header.Name)))
-			glog.V(5).Infof(AWlogString(fmt.Sprintf("picked up %v", dev)))
+	return endOfResults, nil
 
-			// Check for agreements already in progress with this device
-			if found := n.alreadyMakingAgreementWith(&dev, consumerPolicy, ags); found {
-				glog.V(5).Infof(AWlogString(fmt.Sprintf("skipping device id %v, agreement attempt already in progress with %v", dev.Id, consumerPolicy.Header.Name)))
-				continue
-			}
+}
 
-			// If the device is not ready to make agreements yet, then skip it.
-			if dev.PublicKey == "" {
-				glog.V(5).Infof(AWlogString(fmt.Sprintf("skipping device id %v, node is not ready to exchange messages", dev.Id)))
-				continue
-			}
+// Queue an agreement attempt for each of the devices that the agbot is not already making an agreement with for the policy.
+func (n *NodeSearch) makeAgreements(consumerPolicy *policy.Policy, org string, polName string, devices []exchange.SearchResultDevice, span *tracing.Span) {
 
-			producerPolicy := policy.Policy_Factory(consumerPolicy.Header.Name)
-
-			// Get the cached service policies from the business policy manager. The returned value
-			// is a map keyed by the service id.
-			// There could be many service versions defined in a business policy.
-			// The policy manager only caches the ones that are used by an old agreement for this business policy.
-			// The cached ones may not be what the new agreement will use. If the new agreement chooses a
-			// new service version, then the new service policy will be put into the cache.
-			svcPolicies := make(map[string]externalpolicy.ExternalPolicy, 0)
-			if consumerPolicy.PatternId == "" {
-				svcPolicies = businessPolManager.GetServicePoliciesForPolicy(org, polName)
-			}
+	// Get all the agreements for this policy that are still active.
+	pendingAgreementFilter := func() persistence.AFilter {
+		return func(a persistence.Agreement) bool {
+			return a.PolicyName == consumerPolicy.Header.Name && a.AgreementTimedout == 0
+		}
+	}
+
+	ags := make(map[string][]persistence.Agreement)
+
+	// The agreements with this policy could be part of any supported agreement protocol.
+	for _, agp := range policy.AllAgreementProtocols() {
+		// Find all agreements that are in progress. They might be waiting for a reply or not yet finalized.
+		// TODO: To support more than 1 agreement (maxagreements > 1) with this device for this policy, we need to adjust this logic.
+		if agreements, err := n.db.FindAgreements([]persistence.AFilter{persistence.UnarchivedAFilter(), pendingAgreementFilter()}, agp); err != nil {
+			glog.Errorf(AWlogString(fmt.Sprintf("received error trying to find pending agreements for protocol %v: %v", agp, err)))
+		} else {
+			ags[agp] = agreements
+		}
+	}
 
-			// Select a worker pool based on the agreement protocol that will be used. This is decided by the
-			// consumer policy.
-			protocol := policy.Select_Protocol(producerPolicy, consumerPolicy)
-			cmd := NewMakeAgreementCommand(*producerPolicy, *consumerPolicy, org, polName, dev, svcPolicies)
+	for _, dev := range devices {
 
-			bcType, bcName, bcOrg := producerPolicy.RequiresKnownBC(protocol)
+		glog.V(3).Infof(AWlogString(fmt.Sprintf("picked up %v for policy %v.", dev.ShortString(), consumerPolicy.Header.Name)))
+		glog.V(5).Infof(AWlogString(fmt.Sprintf("picked up %v", dev)))
 
-			if !n.ph.Has(protocol) {
-				glog.Errorf(AWlogString(fmt.Sprintf("unable to find protocol handler for %v.", protocol)))
-			} else if bcType != "" && !n.ph.Get(protocol).IsBlockchainWritable(bcType, bcName, bcOrg) {
-				// Get that blockchain running if it isn't up.
-				glog.V(5).Infof(AWlogString(fmt.Sprintf("skipping device id %v, requires blockchain %v %v %v that isnt ready yet.", dev.Id, bcType, bcName, bcOrg)))
-				n.msgs <- events.NewNewBCContainerMessage(events.NEW_BC_CLIENT, bcType, bcName, bcOrg, n.ec.GetExchangeURL(), n.ec.GetExchangeId(), n.ec.GetExchangeToken())
-				continue
-			} else if !n.ph.Get(protocol).AcceptCommand(cmd) {
-				glog.Errorf(AWlogString(fmt.Sprintf("protocol handler for %v not accepting new agreement commands.", protocol)))
-			} else {
-				n.ph.Get(protocol).HandleMakeAgreement(cmd, n.ph.Get(protocol))
-				glog.V(5).Infof(AWlogString(fmt.Sprintf("queued agreement attempt for policy %v and node %v using protocol %v", consumerPolicy.Header.Name, dev.Id, protocol)))
-			}
+		// Check for agreements already in progress with this device
+		if found := n.alreadyMakingAgreementWith(&dev, consumerPolicy, ags); found {
+			glog.V(5).Infof(AWlogString(fmt.Sprintf("skipping device id %v, agreement attempt already in progress with %v", dev.Id, consumerPolicy.Header.Name)))
+			continue
 		}
 
-	}
+		// If the device is not ready to make agreements yet, then skip it.
+		if dev.PublicKey == "" {
+			glog.V(5).Infof(AWlogString(fmt.Sprintf("skipping device id %v, node is not ready to exchange messages", dev.Id)))
+			continue
+		}
 
-	return endOfResults, nil
+		producerPolicy := policy.Policy_Factory(consumerPolicy.Header.Name)
+
+		// Get the cached service policies from the business policy manager. The returned value
+		// is a map keyed by the service id.
+		// There could be many service versions defined in a business policy.
+		// The policy manager only caches the ones that are used by an old agreement for this business policy.
+		// The cached ones may not be what the new agreement will use. If the new agreement chooses a
+		// new service version, then the new service policy will be put into the cache.
+		svcPolicies := make(map[string]externalpolicy.ExternalPolicy, 0)
+		if consumerPolicy.PatternId == "" {
+			svcPolicies = businessPolManager.GetServicePoliciesForPolicy(org, polName)
+		}
 
+		// Select a worker pool based on the agreement protocol that will be used. This is decided by the
+		// consumer policy.
+		protocol := policy.Select_Protocol(producerPolicy, consumerPolicy)
+		cmd := NewMakeAgreementCommand(*producerPolicy, *consumerPolicy, org, polName, dev, svcPolicies)
+		cmd.SearchSpan = span.Context()
+
+		bcType, bcName, bcOrg := producerPolicy.RequiresKnownBC(protocol)
+
+		if !n.ph.Has(protocol) {
+			glog.Errorf(AWlogString(fmt.Sprintf("unable to find protocol handler for %v.", protocol)))
+		} else if bcType != "" && !n.ph.Get(protocol).IsBlockchainWritable(bcType, bcName, bcOrg) {
+			// Get that blockchain running if it isn't up.
+			glog.V(5).Infof(AWlogString(fmt.Sprintf("skipping device id %v, requires blockchain %v %v %v that isnt ready yet.", dev.Id, bcType, bcName, bcOrg)))
+			n.msgs <- events.NewNewBCContainerMessage(events.NEW_BC_CLIENT, bcType, bcName, bcOrg, n.ec.GetExchangeURL(), n.ec.GetExchangeId(), n.ec.GetExchangeToken())
+			continue
+		} else if !n.ph.Get(protocol).AcceptCommand(cmd) {
+			glog.Errorf(AWlogString(fmt.Sprintf("protocol handler for %v not accepting new agreement commands.", protocol)))
+		} else {
+			n.ph.Get(protocol).HandleMakeAgreement(cmd, n.ph.Get(protocol))
+			glog.V(5).Infof(AWlogString(fmt.Sprintf("queued agreement attempt for policy %v and node %v using protocol %v", consumerPolicy.Header.Name, dev.Id, protocol)))
+		}
+	}
 }
 
 // Check all agreement protocol buckets to see if there are any agreements with this device.
@@ -491,6 +598,80 @@ func (n *NodeSearch) searchExchange(pol *policy.Policy, polOrg string, polName s
 	}
 }
 
+// Agreement-making for a deployment policy or pattern was resumed. The nodes that changed while it was paused have not been
+// searched for yet, so the search for a deployment policy goes back to the time it was paused.
+func (n *NodeSearch) PolicyResumed(pp *persistence.PausedPolicy) {
+	if pp.Type == persistence.PAUSED_DEPLOYMENT_POLICY && pp.PausedAt > n.retryLookBack {
+		n.AddRetry(pp.Name, pp.PausedAt-n.retryLookBack)
+	} else {
+		n.SetRescanNeeded()
+	}
+}
+
+// Check one node against all the policies and patterns served by this agbot, and make agreements for the ones it is
+// eligible for, without waiting for a node search to find it. The exchange decides whether a node is compatible with a
+// deployment policy when it searches for nodes, so the agreement attempts made here are checked again by the agreement
+// protocol, the same as the ones made by a search. The shards are ignored so that a node can be re-evaluated through any agbot.
+func (n *NodeSearch) ReevaluateNode(nodeId string) {
+
+	span := tracing.StartSpan("agbot.reevaluate", tracing.SpanContext{})
+	span.SetAttribute("node.id", nodeId)
+	defer span.End()
+
+	// Get the current node from the exchange, not from the cache.
+	exchange.DeleteCacheNodeWriteThru(exchange.GetOrg(nodeId), exchange.GetId(nodeId))
+	exchange.DeleteCacheResource(exchange.NODE_POL_TYPE_CACHE, exchange.NodeCacheMapKey(exchange.GetOrg(nodeId), exchange.GetId(nodeId)))
+	node, err := exchange.GetHTTPDeviceHandler(n.ec)(nodeId, "")
+	if err != nil {
+		glog.Errorf(AWlogString(fmt.Sprintf("unable to re-evaluate node %v, error getting the node from the exchange: %v", nodeId, err)))
+		span.SetError(err)
+		return
+	} else if node == nil {
+		glog.Errorf(AWlogString(fmt.Sprintf("unable to re-evaluate node %v, it is not in the exchange", nodeId)))
+		return
+	}
+
+	paused, err := n.db.FindPausedPolicies()
+	if err != nil {
+		glog.Errorf(AWlogString(fmt.Sprintf("unable to re-evaluate node %v, error reading paused policies: %v", nodeId, err)))
+		span.SetError(err)
+		return
+	}
+
+	nodeOrg := exchange.GetOrg(nodeId)
+	dev := exchange.SearchResultDevice{Id: nodeId, NodeType: node.NodeType, PublicKey: node.PublicKey, MsgEndPoint: node.MsgEndPoint}
+	now := uint64(time.Now().Unix())
+	found := 0
+
+	for _, org := range n.pm.GetAllPolicyOrgs() {
+		for _, consumerPolicy := range n.getOrderedPolicies(org) {
+
+			if pp := persistence.FindPausedPolicy(paused, &consumerPolicy); pp != nil {
+				continue
+			}
+
+			polName := ""
+			if consumerPolicy.PatternId != "" {
+				// A node with a pattern only runs that pattern.
+				if node.Pattern != consumerPolicy.PatternId || !cutil.SliceContains(patternManager.GetServedNodeOrgs(org, exchange.GetId(consumerPolicy.PatternId)), nodeOrg) {
+					continue
+				}
+			} else if pBE := businessPolManager.GetBusinessPolicyEntry(org, &consumerPolicy); pBE == nil || !pBE.IsActive(now) || node.Pattern != "" {
+				continue
+			} else if _, polName = cutil.SplitOrgSpecUrl(consumerPolicy.Header.Name); !cutil.SliceContains(businessPolManager.GetServedNodeOrgs(org, polName), nodeOrg) {
+				continue
+			}
+
+			glog.V(3).Infof(AWlogString(fmt.Sprintf("re-evaluating node %v with policy %v", nodeId, consumerPolicy.Header.Name)))
+			found++
+			n.makeAgreements(&consumerPolicy, org, polName, []exchange.SearchResultDevice{dev}, span)
+		}
+	}
+
+	span.SetAttribute("policies.found", found)
+	glog.V(3).Infof(AWlogString(fmt.Sprintf("re-evaluated node %v with %v policies", nodeId, found)))
+}
+
 func (n *NodeSearch) AddRetry(policyName string, changedSince uint64) {
 	n.SetRescanNeeded()
 	if err := n.db.ResetPolicyChangedSince(policyName, changedSince); err != nil {
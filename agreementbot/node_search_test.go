@@ -0,0 +1,73 @@
+// +build unit
+
+package agreementbot
+
+import (
+	"github.com/open-horizon/anax/agreementbot/persistence"
+	"testing"
+	"time"
+)
+
+func Test_NodeSearch_shards(t *testing.T) {
+
+	ns := NewNodeSearch()
+
+	// Without sharding, all the policies are searched.
+	if !ns.OwnsPolicy("myorg/pol1") {
+		t.Errorf("all policies should be searched when sharding is off")
+	}
+
+	ns.shardCount = 4
+	shard := persistence.ShardOf("myorg/pol1", 4)
+	if ns.OwnsPolicy("myorg/pol1") {
+		t.Errorf("no policies should be searched before the agbot owns a shard")
+	}
+
+	// Gaining a shard starts a rescan.
+	ns.SetShards([]int{shard})
+	if !ns.OwnsPolicy("myorg/pol1") {
+		t.Errorf("policy in shard %v should be searched", shard)
+	} else if !ns.IsRescanNeeded() {
+		t.Errorf("a rescan should be needed after gaining shard %v", shard)
+	}
+
+	// Trading a shard for another one starts a rescan for the new shard.
+	ns.UnsetRescanNeeded()
+	ns.SetShards([]int{(shard + 1) % 4})
+	if ns.OwnsPolicy("myorg/pol1") {
+		t.Errorf("policy in shard %v should not be searched", shard)
+	} else if !ns.IsRescanNeeded() {
+		t.Errorf("a rescan should be needed after gaining shard %v", (shard+1)%4)
+	}
+
+	ns.UnsetRescanNeeded()
+	ns.SetShards([]int{})
+	if ns.IsRescanNeeded() {
+		t.Errorf("a rescan should not be needed after losing all shards")
+	}
+}
+
+func Test_NodeSearch_rescan_at(t *testing.T) {
+
+	ns := NewNodeSearch()
+	now := uint64(time.Now().Unix())
+
+	// A rescan scheduled in the future is not needed yet.
+	ns.SetRescanNeededAt(now + 3600)
+	if ns.IsRescanNeeded() {
+		t.Errorf("a rescan should not be needed before %v", now+3600)
+	}
+
+	// The earliest scheduled time wins, and the rescan is needed once it is reached.
+	ns.SetRescanNeededAt(now - 1)
+	ns.SetRescanNeededAt(now + 7200)
+	if !ns.IsRescanNeeded() {
+		t.Errorf("a rescan should be needed at %v", now-1)
+	}
+
+	// The scheduled time is used up by the rescan.
+	ns.UnsetRescanNeeded()
+	if ns.IsRescanNeeded() {
+		t.Errorf("a rescan should not be needed after the scheduled rescan started")
+	}
+}
@@ -45,8 +45,9 @@ func NewNHPatternEntry() *NHPatternEntry {
 }
 
 type NodeHealthManager struct {
-	Patterns map[string]*NHPatternEntry // A map of patterns for which this agbot has agreements
-	NodeOrgs map[string][]string        // a map of node orgs for each pattern used by current active agreements
+	Patterns         map[string]*NHPatternEntry // A map of patterns for which this agbot has agreements
+	NodeOrgs         map[string][]string        // a map of node orgs for each pattern used by current active agreements
+	OutOfPolicyNodes map[string]string          // The last heartbeat of each node that has been reported as out of policy
 }
 
 func (n *NodeHealthManager) String() string {
@@ -56,7 +57,8 @@ func (n *NodeHealthManager) String() string {
 
 func NewNodeHealthManager() *NodeHealthManager {
 	nh := &NodeHealthManager{
-		Patterns: make(map[string]*NHPatternEntry),
+		Patterns:         make(map[string]*NHPatternEntry),
+		OutOfPolicyNodes: make(map[string]string),
 	}
 	return nh
 }
@@ -103,6 +105,30 @@ func (m *NodeHealthManager) NodeOutOfPolicy(pattern string, org string, deviceId
 	return false
 }
 
+// Return true only the first time an out of policy node is reported for its current last heartbeat. A node usually
+// has many agreements, this allows the node to be reported once each time it misses its heartbeat instead of once
+// for each of its agreements.
+func (m *NodeHealthManager) ReportNodeOutOfPolicy(pattern string, org string, deviceId string) bool {
+
+	lastHB := ""
+	if pe, ok := m.Patterns[getKey(pattern, org)]; ok && pe.Nodes != nil {
+		if node, ok := pe.Nodes.Nodes[deviceId]; ok {
+			lastHB = node.LastHeartbeat
+		}
+	}
+
+	if reportedHB, ok := m.OutOfPolicyNodes[deviceId]; ok && reportedHB == lastHB {
+		return false
+	}
+	m.OutOfPolicyNodes[deviceId] = lastHB
+	return true
+}
+
+// Forget that the input node was reported as out of policy, it is heartbeating again.
+func (m *NodeHealthManager) NodeInPolicy(deviceId string) {
+	delete(m.OutOfPolicyNodes, deviceId)
+}
+
 // Determine if the input agreement id is still present in the exchange. Return false (not out of policy)
 // if the agreement is present. If the agreement is not present then give the node NHCheckAgreementStatus + agbot finalized time
 // to get the agreement object into the exchange.
@@ -242,6 +242,45 @@ func Test_NodeHealthStatus_multiupdate(t *testing.T) {
 
 }
 
+func Test_NodeHealthStatus_report_out_of_policy(t *testing.T) {
+
+	nhm := NewNodeHealthManager()
+
+	mypattern := "mypattern"
+	mynode := "org/node1"
+	lastHB := "2006-01-02T15:04:05.999Z[UTC]"
+
+	nhHandler := getVariableStatusHandler(mynode, "ag1", []string{}, lastHB)
+	if err := nhm.SetUpdatedStatus(mypattern, "theorg", nhHandler); err != nil {
+		t.Errorf("unexpected error %v", err)
+	} else if !nhm.ReportNodeOutOfPolicy(mypattern, "theorg", mynode) {
+		t.Errorf("node %v should be reported the first time, %v", mynode, nhm.OutOfPolicyNodes)
+	} else if nhm.ReportNodeOutOfPolicy(mypattern, "theorg", mynode) {
+		t.Errorf("node %v should not be reported again for the same heartbeat, %v", mynode, nhm.OutOfPolicyNodes)
+	} else if !nhm.ReportNodeOutOfPolicy(mypattern, "theorg", "org/node2") {
+		t.Errorf("node org/node2 should be reported, %v", nhm.OutOfPolicyNodes)
+	}
+
+	// The node heartbeats and then misses its heartbeat again.
+	nhm.ResetUpdateStatus()
+	nhHandler = getVariableStatusHandler(mynode, "ag1", []string{}, "2006-01-02T15:14:05.999Z[UTC]")
+	if err := nhm.SetUpdatedStatus(mypattern, "theorg", nhHandler); err != nil {
+		t.Errorf("unexpected error %v", err)
+	} else if !nhm.ReportNodeOutOfPolicy(mypattern, "theorg", mynode) {
+		t.Errorf("node %v should be reported for a new heartbeat, %v", mynode, nhm.OutOfPolicyNodes)
+	} else if nhm.ReportNodeOutOfPolicy(mypattern, "theorg", mynode) {
+		t.Errorf("node %v should not be reported again for the same heartbeat, %v", mynode, nhm.OutOfPolicyNodes)
+	}
+
+	// A node that is back in policy is reported again.
+	nhm.NodeInPolicy(mynode)
+	if _, ok := nhm.OutOfPolicyNodes[mynode]; ok {
+		t.Errorf("node %v should be forgotten, %v", mynode, nhm.OutOfPolicyNodes)
+	} else if !nhm.ReportNodeOutOfPolicy(mypattern, "theorg", mynode) {
+		t.Errorf("node %v should be reported after it was in policy, %v", mynode, nhm.OutOfPolicyNodes)
+	}
+}
+
 func Test_SetNodeOrgs(t *testing.T) {
 	nhm := NewNodeHealthManager()
 	if len(nhm.Patterns) != 0 {
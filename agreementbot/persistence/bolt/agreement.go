@@ -21,7 +21,8 @@ const AGREEMENTS = "agreements" // The bolt DB bucket name for agreement objects
 
 // This is the object that represents the handle to the bolt func (db *AgbotBoltDB)
 type AgbotBoltDB struct {
-	db *bolt.DB
+	db         *bolt.DB
+	shardCount int32 // The number of node search shards, all of them are owned by this agbot.
 }
 
 func (db *AgbotBoltDB) String() string {
@@ -93,6 +94,10 @@ func (db *AgbotBoltDB) AgreementAttempt(agreementid string, org string, deviceid
 	}
 }
 
+func (db *AgbotBoltDB) ImportAgreement(agreement *persistence.Agreement, protocol string) error {
+	return db.persistNew(agreement.CurrentAgreementId, bucketName(protocol), agreement)
+}
+
 func (db *AgbotBoltDB) AgreementUpdate(agreementid string, proposal string, policy string, dvPolicy policy.DataVerification, defaultCheckRate uint64, hash string, sig string, protocol string, agreementProtoVersion int) (*persistence.Agreement, error) {
 	return persistence.AgreementUpdate(db, agreementid, proposal, policy, dvPolicy, defaultCheckRate, hash, sig, protocol, agreementProtoVersion)
 }
@@ -222,7 +227,8 @@ func (db *AgbotBoltDB) DeleteAgreement(pk string, protocol string) error {
 		return db.db.Update(func(tx *bolt.Tx) error {
 			b := tx.Bucket([]byte(bucketName(protocol)))
 			if b == nil {
-				return fmt.Errorf("Unknown bucket: %v", bucketName(protocol))
+				glog.Warningf("Warning: record deletion requested, but there are no %v agreements: %v", protocol, pk)
+				return nil // handle already-deleted agreement as success
 			} else if existing := b.Get([]byte(pk)); existing == nil {
 				glog.Errorf("Warning: record deletion requested, but record does not exist: %v", pk)
 				return nil // handle already-deleted agreement as success
@@ -0,0 +1,31 @@
+// +build unit
+
+package bolt
+
+import (
+	"github.com/open-horizon/anax/agreementbot/persistence"
+	"github.com/open-horizon/anax/agreementbot/persistence/conformance"
+	"github.com/open-horizon/anax/config"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func Test_Conformance(t *testing.T) {
+	dirs := make([]string, 0, 10)
+	defer func() {
+		for _, dir := range dirs {
+			os.RemoveAll(dir)
+		}
+	}()
+
+	conformance.RunSuite(t, func(t *testing.T) persistence.AgbotDatabase {
+		dir, err := ioutil.TempDir("", "agbot-bolt-")
+		assert.Nil(t, err)
+		dirs = append(dirs, dir)
+		db := new(AgbotBoltDB)
+		assert.Nil(t, db.Initialize(&config.HorizonConfig{AgreementBot: config.AGConfig{DBPath: dir}}))
+		return db
+	})
+}
@@ -1,6 +1,9 @@
 package bolt
 
-import ()
+import (
+	"github.com/open-horizon/anax/agreementbot/persistence"
+	"sync/atomic"
+)
 
 // Functions related to partitions in the bolt database. It does not use partitions, or rather has only 1 global partition.
 func (db *AgbotBoltDB) FindPartitions() ([]string, error) {
@@ -32,3 +35,27 @@ func (db *AgbotBoltDB) GetPartitionOwner(id string) (string, error) {
 func (db *AgbotBoltDB) MovePartition(timeout uint64) (bool, error) {
 	return false, nil
 }
+
+// The bolt database is used by a single agbot, so it owns all the shards. There are no leases to keep.
+func (db *AgbotBoltDB) BalanceShards(shardCount int, timeout uint64) ([]int, error) {
+	atomic.StoreInt32(&db.shardCount, int32(shardCount))
+	shards := make([]int, 0, shardCount)
+	for i := 0; i < shardCount; i++ {
+		shards = append(shards, i)
+	}
+	return shards, nil
+}
+
+func (db *AgbotBoltDB) FindShards() ([]persistence.Shard, error) {
+	shardCount := int(atomic.LoadInt32(&db.shardCount))
+	shards := make([]persistence.Shard, 0, shardCount)
+	for i := 0; i < shardCount; i++ {
+		shards = append(shards, persistence.Shard{Id: i, Owner: "global"})
+	}
+	return shards, nil
+}
+
+func (db *AgbotBoltDB) ReleaseShards() error {
+	atomic.StoreInt32(&db.shardCount, 0)
+	return nil
+}
@@ -0,0 +1,65 @@
+package bolt
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/boltdb/bolt"
+	"github.com/open-horizon/anax/agreementbot/persistence"
+)
+
+const PAUSED_POLICY_BUCKET = "paused_policies" // The bolt DB bucket name for paused deployment policies and patterns.
+
+// The paused policies are keyed by type and name, so they are returned in that order.
+func pausedPolicyKey(policyType string, name string) []byte {
+	return []byte(policyType + "/" + name)
+}
+
+func (db *AgbotBoltDB) PausePolicy(pp *persistence.PausedPolicy) error {
+	return db.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(PAUSED_POLICY_BUCKET))
+		if err != nil {
+			return err
+		}
+
+		if serial, err := json.Marshal(pp); err != nil {
+			return fmt.Errorf("Failed to serialize paused policy: %v. Error: %v", *pp, err)
+		} else {
+			return b.Put(pausedPolicyKey(pp.Type, pp.Name), serial)
+		}
+	})
+}
+
+func (db *AgbotBoltDB) ResumePolicy(policyType string, name string) (bool, error) {
+	resumed := false
+	writeErr := db.db.Update(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(PAUSED_POLICY_BUCKET)); b != nil && b.Get(pausedPolicyKey(policyType, name)) != nil {
+			resumed = true
+			return b.Delete(pausedPolicyKey(policyType, name))
+		}
+		return nil
+	})
+	return resumed, writeErr
+}
+
+func (db *AgbotBoltDB) FindPausedPolicies() ([]persistence.PausedPolicy, error) {
+	paused := make([]persistence.PausedPolicy, 0)
+
+	readErr := db.db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(PAUSED_POLICY_BUCKET)); b != nil {
+			return b.ForEach(func(k, v []byte) error {
+				var pp persistence.PausedPolicy
+				if err := json.Unmarshal(v, &pp); err != nil {
+					return fmt.Errorf("Unable to deserialize paused policy record: %v", v)
+				}
+				paused = append(paused, pp)
+				return nil
+			})
+		}
+		return nil // end transaction
+	})
+
+	if readErr != nil {
+		return nil, readErr
+	}
+	return paused, nil
+}
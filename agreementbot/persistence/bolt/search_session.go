@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/boltdb/bolt"
 	"github.com/golang/glog"
+	"github.com/open-horizon/anax/agreementbot/persistence"
 	"strconv"
 	"time"
 )
@@ -105,6 +106,36 @@ func (db *AgbotBoltDB) DumpSearchSessions() error {
 	return nil
 }
 
+// The one and only search session, it is used for all policies so it has no policy name.
+func (db *AgbotBoltDB) FindSearchSessions() ([]persistence.SearchSession, error) {
+	ss, err := db.findSearchSession()
+	if err != nil {
+		return nil, err
+	}
+	return []persistence.SearchSession{{
+		ChangedSince: ss.ChangedSince,
+		SessionToken: ss.SessionToken,
+		SessionEnded: ss.SessionEnded,
+	}}, nil
+}
+
+// The imported search session replaces the one and only search session, unless that has an earlier changedSince. The
+// policy name of the imported session is ignored.
+func (db *AgbotBoltDB) ImportSearchSession(imported *persistence.SearchSession) error {
+	ss, err := db.findSearchSession()
+	if err != nil {
+		return err
+	}
+	if ss.Updated != 0 && ss.ChangedSince < imported.ChangedSince {
+		return nil
+	}
+	ss.ChangedSince = imported.ChangedSince
+	ss.SessionToken = imported.SessionToken
+	ss.SessionEnded = true
+	ss.Updated = uint64(time.Now().Unix())
+	return db.saveSearchSession(ss)
+}
+
 // These are functions used internally by the search session object to provide the search session capability for the agbot database interface.
 
 // Return the one and only SearchSession object in the DB.
@@ -0,0 +1,126 @@
+package bolt
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/boltdb/bolt"
+	"github.com/open-horizon/anax/agreementbot/persistence"
+)
+
+const WEBHOOK_DELIVERY_BUCKET = "webhook_deliveries" // The bolt DB bucket name for queued webhook deliveries.
+
+func putWebhookDelivery(b *bolt.Bucket, d *persistence.WebhookDelivery) error {
+	if serial, err := json.Marshal(d); err != nil {
+		return fmt.Errorf("Failed to serialize webhook delivery: %v. Error: %v", *d, err)
+	} else {
+		return b.Put([]byte(d.Id), serial)
+	}
+}
+
+// Read all the webhook deliveries of the bucket, oldest first.
+func readWebhookDeliveries(b *bolt.Bucket) ([]persistence.WebhookDelivery, error) {
+	deliveries := make([]persistence.WebhookDelivery, 0)
+	err := b.ForEach(func(k, v []byte) error {
+		var d persistence.WebhookDelivery
+		if err := json.Unmarshal(v, &d); err != nil {
+			return fmt.Errorf("Unable to deserialize webhook delivery record: %v", v)
+		}
+		deliveries = append(deliveries, d)
+		return nil
+	})
+	persistence.SortWebhookDeliveries(deliveries)
+	return deliveries, err
+}
+
+func (db *AgbotBoltDB) QueueWebhookDelivery(d *persistence.WebhookDelivery) error {
+	return db.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(WEBHOOK_DELIVERY_BUCKET))
+		if err != nil {
+			return err
+		}
+		return putWebhookDelivery(b, d)
+	})
+}
+
+func (db *AgbotBoltDB) ClaimWebhookDeliveries(now uint64, leaseS uint64, limit int) ([]persistence.WebhookDelivery, error) {
+	claimed := make([]persistence.WebhookDelivery, 0)
+
+	writeErr := db.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(WEBHOOK_DELIVERY_BUCKET))
+		if b == nil {
+			return nil
+		}
+
+		deliveries, err := readWebhookDeliveries(b)
+		if err != nil {
+			return err
+		}
+		for _, d := range deliveries {
+			if len(claimed) == limit {
+				break
+			} else if d.NextAttempt > now {
+				continue
+			}
+			claimed = append(claimed, d)
+			d.NextAttempt = now + leaseS
+			if err := putWebhookDelivery(b, &d); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if writeErr != nil {
+		return nil, writeErr
+	}
+	return claimed, nil
+}
+
+func (db *AgbotBoltDB) RescheduleWebhookDelivery(id string, attempts int, nextAttempt uint64, lastError string) error {
+	return db.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(WEBHOOK_DELIVERY_BUCKET))
+		if b == nil {
+			return nil
+		}
+		v := b.Get([]byte(id))
+		if v == nil {
+			return nil
+		}
+
+		var d persistence.WebhookDelivery
+		if err := json.Unmarshal(v, &d); err != nil {
+			return fmt.Errorf("Unable to deserialize webhook delivery record: %v", v)
+		}
+		d.Attempts = attempts
+		d.NextAttempt = nextAttempt
+		d.LastError = lastError
+		return putWebhookDelivery(b, &d)
+	})
+}
+
+func (db *AgbotBoltDB) DeleteWebhookDelivery(id string) error {
+	return db.db.Update(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(WEBHOOK_DELIVERY_BUCKET)); b != nil {
+			return b.Delete([]byte(id))
+		}
+		return nil
+	})
+}
+
+func (db *AgbotBoltDB) FindWebhookDeliveries() ([]persistence.WebhookDelivery, error) {
+	deliveries := make([]persistence.WebhookDelivery, 0)
+
+	readErr := db.db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(WEBHOOK_DELIVERY_BUCKET)); b != nil {
+			var err error
+			deliveries, err = readWebhookDeliveries(b)
+			return err
+		}
+		return nil // end transaction
+	})
+
+	if readErr != nil {
+		return nil, readErr
+	}
+	return deliveries, nil
+}
@@ -25,6 +25,16 @@ func (db *AgbotBoltDB) NewWorkloadUsage(deviceId string, hapartners []string, po
 	}
 }
 
+// The imported record gets a new primary key from the sequence counter of this database.
+func (db *AgbotBoltDB) ImportWorkloadUsage(wu *persistence.WorkloadUsage) error {
+	if existing, err := db.FindSingleWorkloadUsageByDeviceAndPolicyName(wu.DeviceId, wu.PolicyName); err != nil {
+		return err
+	} else if existing != nil {
+		return fmt.Errorf("Workload usage record for device %v and policy name %v already exists.", wu.DeviceId, wu.PolicyName)
+	}
+	return db.WUPersistNew(wuBucketName(), wu)
+}
+
 func (db *AgbotBoltDB) GetWorkloadUsagesCount(partition string) (int64, error) {
 	if wus, err := db.FindWorkloadUsages([]persistence.WUFilter{}); err != nil {
 		return 0, err
@@ -125,7 +135,8 @@ func (db *AgbotBoltDB) DeleteWorkloadUsage(deviceid string, policyName string) e
 		if wlUsage, err := db.FindSingleWorkloadUsageByDeviceAndPolicyName(deviceid, policyName); err != nil {
 			return err
 		} else if wlUsage == nil {
-			return fmt.Errorf("Unable to locate workload usage for device: %v, and policy: %v", deviceid, policyName)
+			glog.Warningf("Warning: workload usage deletion requested, but record does not exist for device: %v, and policy: %v", deviceid, policyName)
+			return nil // handle already-deleted workload usage as success
 		} else {
 
 			pk := wlUsage.Id
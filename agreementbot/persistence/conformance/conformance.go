@@ -0,0 +1,691 @@
+// Package conformance contains tests that every implementation of the agbot database interface must pass. The
+// tests are run by the unit tests of each implementation, so that any difference in the behavior of the
+// implementations shows up as a test failure.
+package conformance
+
+import (
+	"github.com/open-horizon/anax/agreementbot/persistence"
+	"github.com/open-horizon/anax/policy"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// Returns a newly initialized, empty database. The database is closed by the test that asked for it.
+type NewDatabase func(t *testing.T) persistence.AgbotDatabase
+
+const protocol = policy.BasicProtocol
+
+// Run all the conformance tests against the databases returned by newDB. Each test gets its own database.
+func RunSuite(t *testing.T, newDB NewDatabase) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, db persistence.AgbotDatabase)
+	}{
+		{"Partitions", testPartitions},
+		{"Shards", testShards},
+		{"PausedPolicies", testPausedPolicies},
+		{"WebhookDeliveries", testWebhookDeliveries},
+		{"AgreementAttempt", testAgreementAttempt},
+		{"AgreementStateTransitions", testAgreementStateTransitions},
+		{"AgreementQueries", testAgreementQueries},
+		{"AgreementDelete", testAgreementDelete},
+		{"WorkloadUsage", testWorkloadUsage},
+		{"WorkloadUsageUpdates", testWorkloadUsageUpdates},
+		{"WorkloadUsageQueries", testWorkloadUsageQueries},
+		{"SearchSessions", testSearchSessions},
+		{"Import", testImport},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			db := newDB(t)
+			defer db.Close()
+			test.test(t, db)
+		})
+	}
+}
+
+// The databases without partitions have 1 global partition, so the tests only rely on the partition of the agbot.
+func testPartitions(t *testing.T, db persistence.AgbotDatabase) {
+	partition := primaryPartition(t, db)
+
+	owner, err := db.GetPartitionOwner(partition)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, owner)
+
+	assert.Nil(t, db.HeartbeatPartition())
+	_, err = db.GetHeartbeat()
+	assert.Nil(t, err)
+
+	// There is no stale partition to move into the partition of this agbot.
+	moved, err := db.MovePartition(3600)
+	assert.Nil(t, err)
+	assert.False(t, moved)
+
+	assert.Nil(t, db.QuiescePartition())
+}
+
+// The agbot is the only live agbot of the database, so it owns all the shards.
+func testShards(t *testing.T, db persistence.AgbotDatabase) {
+	owner, err := db.GetPartitionOwner(primaryPartition(t, db))
+	assert.Nil(t, err)
+
+	owned, err := db.BalanceShards(8, 3600)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{0, 1, 2, 3, 4, 5, 6, 7}, owned)
+
+	shards, err := db.FindShards()
+	assert.Nil(t, err)
+	if assert.Len(t, shards, 8) {
+		for i, s := range shards {
+			assert.Equal(t, i, s.Id)
+			assert.Equal(t, owner, s.Owner)
+		}
+	}
+
+	// Balancing again renews the leases and keeps the shards.
+	owned, err = db.BalanceShards(8, 3600)
+	assert.Nil(t, err)
+	assert.Len(t, owned, 8)
+
+	// The shards above a lower shard count are removed.
+	owned, err = db.BalanceShards(4, 3600)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{0, 1, 2, 3}, owned)
+	shards, err = db.FindShards()
+	assert.Nil(t, err)
+	assert.Len(t, shards, 4)
+
+	// Released shards are no longer owned by the agbot, until it balances again.
+	assert.Nil(t, db.ReleaseShards())
+	shards, err = db.FindShards()
+	assert.Nil(t, err)
+	for _, s := range shards {
+		assert.NotEqual(t, owner, s.Owner)
+	}
+	owned, err = db.BalanceShards(4, 3600)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{0, 1, 2, 3}, owned)
+
+	// No shards means that sharding is off.
+	owned, err = db.BalanceShards(0, 3600)
+	assert.Nil(t, err)
+	assert.Empty(t, owned)
+}
+
+func testPausedPolicies(t *testing.T, db persistence.AgbotDatabase) {
+	paused, err := db.FindPausedPolicies()
+	assert.Nil(t, err)
+	assert.Empty(t, paused)
+
+	pattern := persistence.PausedPolicy{Type: persistence.PAUSED_PATTERN, Name: "myorg/pat1", PausedBy: "myorg/admin", PausedAt: 1000}
+	deploymentPol := persistence.PausedPolicy{Type: persistence.PAUSED_DEPLOYMENT_POLICY, Name: "myorg/pol1", PausedBy: "myorg/admin", PausedAt: 2000}
+	assert.Nil(t, db.PausePolicy(&pattern))
+	assert.Nil(t, db.PausePolicy(&deploymentPol))
+
+	paused, err = db.FindPausedPolicies()
+	assert.Nil(t, err)
+	assert.Equal(t, []persistence.PausedPolicy{deploymentPol, pattern}, paused)
+
+	// Pausing a paused policy again replaces it.
+	deploymentPol.PausedBy = "root/root"
+	deploymentPol.PausedAt = 3000
+	assert.Nil(t, db.PausePolicy(&deploymentPol))
+	paused, err = db.FindPausedPolicies()
+	assert.Nil(t, err)
+	assert.Equal(t, []persistence.PausedPolicy{deploymentPol, pattern}, paused)
+
+	// A policy is only resumed for its own type.
+	resumed, err := db.ResumePolicy(persistence.PAUSED_DEPLOYMENT_POLICY, "myorg/pat1")
+	assert.Nil(t, err)
+	assert.False(t, resumed)
+	resumed, err = db.ResumePolicy(persistence.PAUSED_PATTERN, "myorg/pat1")
+	assert.Nil(t, err)
+	assert.True(t, resumed)
+	resumed, err = db.ResumePolicy(persistence.PAUSED_PATTERN, "myorg/pat1")
+	assert.Nil(t, err)
+	assert.False(t, resumed)
+
+	paused, err = db.FindPausedPolicies()
+	assert.Nil(t, err)
+	assert.Equal(t, []persistence.PausedPolicy{deploymentPol}, paused)
+}
+
+func testWebhookDeliveries(t *testing.T, db persistence.AgbotDatabase) {
+	deliveries, err := db.FindWebhookDeliveries()
+	assert.Nil(t, err)
+	assert.Empty(t, deliveries)
+
+	d1 := persistence.WebhookDelivery{Id: "d1", URL: "https://hooks.example.com/a", Event: "node_out_of_policy", Payload: `{"id":"e1"}`, NextAttempt: 1000, CreatedAt: 1000}
+	d2 := persistence.WebhookDelivery{Id: "d2", URL: "https://hooks.example.com/b", Event: "node_out_of_policy", Payload: `{"id":"e1"}`, NextAttempt: 1000, CreatedAt: 1000}
+	d3 := persistence.WebhookDelivery{Id: "d3", URL: "https://hooks.example.com/a", Event: "workload_rollback", Payload: `{"id":"e2"}`, NextAttempt: 2000, CreatedAt: 900}
+	assert.Nil(t, db.QueueWebhookDelivery(&d3))
+	assert.Nil(t, db.QueueWebhookDelivery(&d2))
+	assert.Nil(t, db.QueueWebhookDelivery(&d1))
+
+	deliveries, err = db.FindWebhookDeliveries()
+	assert.Nil(t, err)
+	assert.Equal(t, []persistence.WebhookDelivery{d3, d1, d2}, deliveries)
+
+	// Only the due deliveries are claimed, up to the limit, and they are not due again until the lease is over.
+	claimed, err := db.ClaimWebhookDeliveries(1500, 60, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, []persistence.WebhookDelivery{d1}, claimed)
+	claimed, err = db.ClaimWebhookDeliveries(1500, 60, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, []persistence.WebhookDelivery{d2}, claimed)
+	claimed, err = db.ClaimWebhookDeliveries(1500, 60, 10)
+	assert.Nil(t, err)
+	assert.Empty(t, claimed)
+
+	// A rescheduled delivery keeps its payload.
+	assert.Nil(t, db.RescheduleWebhookDelivery("d1", 1, 1600, "HTTP status 503"))
+	assert.Nil(t, db.DeleteWebhookDelivery("d2"))
+	claimed, err = db.ClaimWebhookDeliveries(2000, 60, 10)
+	assert.Nil(t, err)
+	d1.Attempts = 1
+	d1.NextAttempt = 1600
+	d1.LastError = "HTTP status 503"
+	assert.Equal(t, []persistence.WebhookDelivery{d3, d1}, claimed)
+
+	// Queueing a delivery again replaces it.
+	d3.NextAttempt = 5000
+	assert.Nil(t, db.QueueWebhookDelivery(&d3))
+	assert.Nil(t, db.DeleteWebhookDelivery("d1"))
+	assert.Nil(t, db.DeleteWebhookDelivery("d1"))
+	deliveries, err = db.FindWebhookDeliveries()
+	assert.Nil(t, err)
+	assert.Equal(t, []persistence.WebhookDelivery{d3}, deliveries)
+}
+
+func testAgreementAttempt(t *testing.T, db persistence.AgbotDatabase) {
+	nh := policy.NodeHealth{MissingHBInterval: 120, CheckAgreementStatus: 30}
+	err := db.AgreementAttempt("ag1", "myorg", "myorg/dev1", persistence.DEVICE_TYPE_DEVICE, "myorg/pol1", "", "", "", protocol, "", []string{"myorg/svc1"}, nh, 60, 600)
+	assert.Nil(t, err)
+
+	ag, err := db.FindSingleAgreementByAgreementId("ag1", protocol, []persistence.AFilter{})
+	assert.Nil(t, err)
+	if assert.NotNil(t, ag) {
+		assert.Equal(t, "ag1", ag.CurrentAgreementId)
+		assert.Equal(t, "myorg", ag.Org)
+		assert.Equal(t, "myorg/dev1", ag.DeviceId)
+		assert.Equal(t, persistence.DEVICE_TYPE_DEVICE, ag.DeviceType)
+		assert.Equal(t, "myorg/pol1", ag.PolicyName)
+		assert.Equal(t, protocol, ag.AgreementProtocol)
+		assert.Equal(t, []string{"myorg/svc1"}, ag.ServiceId)
+		assert.Equal(t, 120, ag.NHMissingHBInterval)
+		assert.Equal(t, 30, ag.NHCheckAgreementStatus)
+		assert.Equal(t, uint64(60), ag.ProtocolTimeoutS)
+		assert.Equal(t, uint64(600), ag.AgreementTimeoutS)
+		assert.NotZero(t, ag.AgreementInceptionTime)
+		assert.Zero(t, ag.AgreementCreationTime)
+		assert.False(t, ag.Archived)
+	}
+
+	// Agreement ids are unique, and an agreement needs an id and a protocol.
+	err = db.AgreementAttempt("ag1", "myorg", "myorg/dev2", persistence.DEVICE_TYPE_DEVICE, "myorg/pol1", "", "", "", protocol, "", []string{}, nh, 60, 600)
+	assert.NotNil(t, err)
+	err = db.AgreementAttempt("", "myorg", "myorg/dev2", persistence.DEVICE_TYPE_DEVICE, "myorg/pol1", "", "", "", protocol, "", []string{}, nh, 60, 600)
+	assert.NotNil(t, err)
+	err = db.AgreementAttempt("ag2", "myorg", "myorg/dev2", persistence.DEVICE_TYPE_DEVICE, "myorg/pol1", "", "", "", "", "", []string{}, nh, 60, 600)
+	assert.NotNil(t, err)
+
+	ags, err := db.FindAgreements([]persistence.AFilter{}, protocol)
+	assert.Nil(t, err)
+	assert.Len(t, ags, 1)
+
+	// A missing agreement is not an error.
+	ag, err = db.FindSingleAgreementByAgreementId("ag9", protocol, []persistence.AFilter{})
+	assert.Nil(t, err)
+	assert.Nil(t, ag)
+}
+
+func testAgreementStateTransitions(t *testing.T, db persistence.AgbotDatabase) {
+	createAgreement(t, db, "ag1", "myorg/dev1", "myorg/pol1")
+
+	dvPolicy := policy.DataVerification{Enabled: true, URL: "https://dv.example.com", URLUser: "user", URLPassword: "pw", Interval: 300}
+	ag, err := db.AgreementUpdate("ag1", "proposal", "policy", dvPolicy, 15, "hash", "consumersig", protocol, 2)
+	assert.Nil(t, err)
+	if assert.NotNil(t, ag) {
+		assert.Equal(t, "proposal", ag.Proposal)
+	}
+	ag = findAgreement(t, db, "ag1")
+	assert.NotZero(t, ag.AgreementCreationTime)
+	assert.Equal(t, "proposal", ag.Proposal)
+	assert.Equal(t, "policy", ag.Policy)
+	assert.Equal(t, "hash", ag.ProposalHash)
+	assert.Equal(t, "consumersig", ag.ConsumerProposalSig)
+	assert.Equal(t, 2, ag.AgreementProtocolVersion)
+	assert.Equal(t, "https://dv.example.com", ag.DataVerificationURL)
+	assert.Equal(t, 15, ag.DataVerificationCheckRate)
+	assert.Equal(t, 300, ag.DataVerificationNoDataInterval)
+	assert.False(t, ag.DisableDataVerificationChecks)
+
+	// The proposal can be set only once.
+	_, err = db.AgreementUpdate("ag1", "proposal2", "policy2", dvPolicy, 15, "hash2", "consumersig2", protocol, 3)
+	assert.Nil(t, err)
+	ag = findAgreement(t, db, "ag1")
+	assert.Equal(t, "proposal", ag.Proposal)
+	assert.Equal(t, "policy", ag.Policy)
+	assert.Equal(t, 2, ag.AgreementProtocolVersion)
+
+	_, err = db.AgreementMade("ag1", "counterparty", "proposalsig", protocol, []string{"myorg/dev9"}, "", "", "")
+	assert.Nil(t, err)
+	ag = findAgreement(t, db, "ag1")
+	assert.Equal(t, "counterparty", ag.CounterPartyAddress)
+	assert.Equal(t, "proposalsig", ag.ProposalSig)
+	assert.Equal(t, []string{"myorg/dev9"}, ag.HAPartners)
+
+	_, err = db.AgreementFinalized("ag1", protocol)
+	assert.Nil(t, err)
+	_, err = db.AgreementBlockchainUpdateAck("ag1", protocol)
+	assert.Nil(t, err)
+	_, err = db.AgreementTimedout("ag1", protocol)
+	assert.Nil(t, err)
+	_, err = db.DataVerified("ag1", protocol)
+	assert.Nil(t, err)
+	_, err = db.DataNotification("ag1", protocol)
+	assert.Nil(t, err)
+	ag = findAgreement(t, db, "ag1")
+	assert.NotZero(t, ag.AgreementFinalizedTime)
+	assert.NotZero(t, ag.BCUpdateAckTime)
+	assert.NotZero(t, ag.AgreementTimedout)
+	assert.NotZero(t, ag.DataVerifiedTime)
+	assert.NotZero(t, ag.DataNotificationSent)
+
+	// Counters only move forward.
+	_, err = db.DataNotVerified("ag1", protocol)
+	assert.Nil(t, err)
+	_, err = db.DataNotVerified("ag1", protocol)
+	assert.Nil(t, err)
+	_, err = db.SingleAgreementUpdate("ag1", protocol, func(a persistence.Agreement) *persistence.Agreement {
+		a.DataVerificationMissedCount = 1
+		a.Proposal = ""
+		return &a
+	})
+	assert.Nil(t, err)
+	ag = findAgreement(t, db, "ag1")
+	assert.Equal(t, uint64(2), ag.DataVerificationMissedCount)
+	assert.Equal(t, "proposal", ag.Proposal)
+
+	// The newest metering notification is first.
+	_, err = db.MeteringNotification("ag1", protocol, "mn1")
+	assert.Nil(t, err)
+	_, err = db.MeteringNotification("ag1", protocol, "mn2")
+	assert.Nil(t, err)
+	ag = findAgreement(t, db, "ag1")
+	assert.NotZero(t, ag.MeteringNotificationSent)
+	assert.Equal(t, []string{"mn2", "mn1"}, ag.MeteringNotificationMsgs)
+
+	// An archived agreement stays archived with the first termination reason.
+	ag, err = db.ArchiveAgreement("ag1", protocol, 5, "cancelled")
+	assert.Nil(t, err)
+	if assert.NotNil(t, ag) {
+		assert.True(t, ag.Archived)
+	}
+	_, err = db.ArchiveAgreement("ag1", protocol, 7, "timed out")
+	assert.Nil(t, err)
+	_, err = db.SingleAgreementUpdate("ag1", protocol, func(a persistence.Agreement) *persistence.Agreement {
+		a.Archived = false
+		return &a
+	})
+	assert.Nil(t, err)
+	ag = findAgreement(t, db, "ag1")
+	assert.True(t, ag.Archived)
+	assert.Equal(t, uint(5), ag.TerminatedReason)
+	assert.Equal(t, "cancelled", ag.TerminatedDescription)
+
+	// An agreement that does not exist cannot be updated.
+	_, err = db.AgreementFinalized("ag9", protocol)
+	assert.NotNil(t, err)
+	_, err = db.ArchiveAgreement("ag9", protocol, 5, "cancelled")
+	assert.NotNil(t, err)
+}
+
+func testAgreementQueries(t *testing.T, db persistence.AgbotDatabase) {
+	createAgreement(t, db, "ag1", "myorg/dev1", "myorg/pol1")
+	createAgreement(t, db, "ag2", "myorg/dev2", "myorg/pol1")
+	createAgreement(t, db, "ag3", "myorg/dev1", "myorg/pol2")
+	_, err := db.ArchiveAgreement("ag3", protocol, 5, "cancelled")
+	assert.Nil(t, err)
+
+	assert.ElementsMatch(t, []string{"ag1", "ag2", "ag3"}, findAgreementIds(t, db, []persistence.AFilter{}, protocol))
+	assert.ElementsMatch(t, []string{"ag1", "ag2"}, findAgreementIds(t, db, []persistence.AFilter{persistence.UnarchivedAFilter()}, protocol))
+	assert.ElementsMatch(t, []string{"ag3"}, findAgreementIds(t, db, []persistence.AFilter{persistence.ArchivedAFilter()}, protocol))
+	assert.ElementsMatch(t, []string{"ag2"}, findAgreementIds(t, db, []persistence.AFilter{persistence.IdAFilter("ag2")}, protocol))
+	assert.ElementsMatch(t, []string{"ag3"}, findAgreementIds(t, db, []persistence.AFilter{persistence.DevPolAFilter("myorg/dev1", "myorg/pol2")}, protocol))
+	assert.ElementsMatch(t, []string{}, findAgreementIds(t, db, []persistence.AFilter{persistence.UnarchivedAFilter(), persistence.DevPolAFilter("myorg/dev1", "myorg/pol2")}, protocol))
+	assert.ElementsMatch(t, []string{}, findAgreementIds(t, db, []persistence.AFilter{}, "unknown"))
+
+	// The filters apply to a single agreement too.
+	ag, err := db.FindSingleAgreementByAgreementId("ag3", protocol, []persistence.AFilter{persistence.UnarchivedAFilter()})
+	assert.Nil(t, err)
+	assert.Nil(t, ag)
+	ag, err = db.FindSingleAgreementByAgreementId("ag3", "unknown", []persistence.AFilter{})
+	assert.Nil(t, err)
+	assert.Nil(t, ag)
+
+	ag, err = db.FindSingleAgreementByAgreementIdAllProtocols("ag2", policy.AllAgreementProtocols(), []persistence.AFilter{persistence.UnarchivedAFilter()})
+	assert.Nil(t, err)
+	if assert.NotNil(t, ag) {
+		assert.Equal(t, "myorg/dev2", ag.DeviceId)
+	}
+	ag, err = db.FindSingleAgreementByAgreementIdAllProtocols("ag3", policy.AllAgreementProtocols(), []persistence.AFilter{persistence.UnarchivedAFilter()})
+	assert.Nil(t, err)
+	assert.Nil(t, ag)
+	ag, err = db.FindSingleAgreementByAgreementIdAllProtocols("ag2", []string{"unknown"}, []persistence.AFilter{})
+	assert.Nil(t, err)
+	assert.Nil(t, ag)
+
+	active, archived, err := db.GetAgreementCount(primaryPartition(t, db))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), active)
+	assert.Equal(t, int64(1), archived)
+}
+
+func testAgreementDelete(t *testing.T, db persistence.AgbotDatabase) {
+	createAgreement(t, db, "ag1", "myorg/dev1", "myorg/pol1")
+	createAgreement(t, db, "ag2", "myorg/dev2", "myorg/pol1")
+
+	assert.Nil(t, db.DeleteAgreement("ag1", protocol))
+	assert.ElementsMatch(t, []string{"ag2"}, findAgreementIds(t, db, []persistence.AFilter{}, protocol))
+
+	// Deleting an agreement that was already deleted, or never existed, is not an error.
+	assert.Nil(t, db.DeleteAgreement("ag1", protocol))
+	assert.Nil(t, db.DeleteAgreement("ag2", "unknown"))
+	assert.NotNil(t, db.DeleteAgreement("", protocol))
+
+	active, archived, err := db.GetAgreementCount(primaryPartition(t, db))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), active)
+	assert.Equal(t, int64(0), archived)
+}
+
+func testWorkloadUsage(t *testing.T, db persistence.AgbotDatabase) {
+	err := db.NewWorkloadUsage("myorg/dev1", []string{"myorg/dev9"}, "policy", "myorg/pol1", 1, 60, 120, true, "ag1")
+	assert.Nil(t, err)
+
+	wu := findWorkloadUsage(t, db, "myorg/dev1", "myorg/pol1")
+	assert.Equal(t, "myorg/dev1", wu.DeviceId)
+	assert.Equal(t, []string{"myorg/dev9"}, wu.HAPartners)
+	assert.Equal(t, "policy", wu.Policy)
+	assert.Equal(t, "myorg/pol1", wu.PolicyName)
+	assert.Equal(t, 1, wu.Priority)
+	assert.Equal(t, 0, wu.RetryCount)
+	assert.Equal(t, 60, wu.RetryDurationS)
+	assert.Equal(t, 120, wu.VerifiedDurationS)
+	assert.True(t, wu.ReqsNotMet)
+	assert.Equal(t, "ag1", wu.CurrentAgreementId)
+	assert.NotZero(t, wu.FirstTryTime)
+	assert.False(t, wu.DisableRetry)
+
+	// There is 1 workload usage for a device and policy, and it needs a priority, a retry duration and an agreement.
+	err = db.NewWorkloadUsage("myorg/dev1", []string{}, "policy", "myorg/pol1", 2, 60, 120, false, "ag2")
+	assert.NotNil(t, err)
+	err = db.NewWorkloadUsage("myorg/dev2", []string{}, "policy", "myorg/pol1", 0, 60, 120, false, "ag2")
+	assert.NotNil(t, err)
+	err = db.NewWorkloadUsage("myorg/dev2", []string{}, "policy", "myorg/pol1", 1, 0, 120, false, "ag2")
+	assert.NotNil(t, err)
+	err = db.NewWorkloadUsage("myorg/dev2", []string{}, "policy", "myorg/pol1", 1, 60, 120, false, "")
+	assert.NotNil(t, err)
+
+	wus, err := db.FindWorkloadUsages([]persistence.WUFilter{})
+	assert.Nil(t, err)
+	assert.Len(t, wus, 1)
+
+	// A missing workload usage is not an error.
+	missing, err := db.FindSingleWorkloadUsageByDeviceAndPolicyName("myorg/dev1", "myorg/pol2")
+	assert.Nil(t, err)
+	assert.Nil(t, missing)
+
+	// Deleting a workload usage that was already deleted is not an error.
+	assert.Nil(t, db.DeleteWorkloadUsage("myorg/dev1", "myorg/pol1"))
+	assert.Nil(t, db.DeleteWorkloadUsage("myorg/dev1", "myorg/pol1"))
+	assert.NotNil(t, db.DeleteWorkloadUsage("", "myorg/pol1"))
+	assert.NotNil(t, db.DeleteWorkloadUsage("myorg/dev1", ""))
+	missing, err = db.FindSingleWorkloadUsageByDeviceAndPolicyName("myorg/dev1", "myorg/pol1")
+	assert.Nil(t, err)
+	assert.Nil(t, missing)
+}
+
+func testWorkloadUsageUpdates(t *testing.T, db persistence.AgbotDatabase) {
+	createAgreement(t, db, "ag1", "myorg/dev1", "myorg/pol1")
+	createAgreement(t, db, "ag2", "myorg/dev1", "myorg/pol1")
+	err := db.NewWorkloadUsage("myorg/dev1", []string{}, "", "myorg/pol1", 1, 60, 120, false, "ag1")
+	assert.Nil(t, err)
+
+	_, err = db.UpdateRetryCount("myorg/dev1", "myorg/pol1", 3, "ag1")
+	assert.Nil(t, err)
+	wu := findWorkloadUsage(t, db, "myorg/dev1", "myorg/pol1")
+	assert.Equal(t, 3, wu.RetryCount)
+	assert.NotZero(t, wu.LatestRetryTime)
+
+	// The agreement id changes only when the agreement ends and when the next agreement starts.
+	_, err = db.UpdateWUAgreementId("myorg/dev1", "myorg/pol1", "ag2", protocol)
+	assert.Nil(t, err)
+	assert.Equal(t, "ag1", findWorkloadUsage(t, db, "myorg/dev1", "myorg/pol1").CurrentAgreementId)
+	_, err = db.UpdateWUAgreementId("myorg/dev1", "myorg/pol1", "", protocol)
+	assert.Nil(t, err)
+	assert.Equal(t, "", findWorkloadUsage(t, db, "myorg/dev1", "myorg/pol1").CurrentAgreementId)
+	_, err = db.UpdateWUAgreementId("myorg/dev1", "myorg/pol1", "ag2", protocol)
+	assert.Nil(t, err)
+	assert.Equal(t, "ag2", findWorkloadUsage(t, db, "myorg/dev1", "myorg/pol1").CurrentAgreementId)
+
+	updated, err := db.UpdatePriority("myorg/dev1", "myorg/pol1", 2, 90, 30, "ag2")
+	assert.Nil(t, err)
+	if assert.NotNil(t, updated) {
+		assert.Equal(t, 2, updated.Priority)
+	}
+	wu = findWorkloadUsage(t, db, "myorg/dev1", "myorg/pol1")
+	assert.Equal(t, 2, wu.Priority)
+	assert.Equal(t, 0, wu.RetryCount)
+	assert.Equal(t, 90, wu.RetryDurationS)
+	assert.Equal(t, 30, wu.VerifiedDurationS)
+
+	_, err = db.UpdatePendingUpgrade("myorg/dev1", "myorg/pol1")
+	assert.Nil(t, err)
+	assert.NotZero(t, findWorkloadUsage(t, db, "myorg/dev1", "myorg/pol1").PendingUpgradeTime)
+
+	// The policy can be set only once.
+	_, err = db.UpdatePolicy("myorg/dev1", "myorg/pol1", "policy")
+	assert.Nil(t, err)
+	_, err = db.UpdatePolicy("myorg/dev1", "myorg/pol1", "policy2")
+	assert.Nil(t, err)
+	assert.Equal(t, "policy", findWorkloadUsage(t, db, "myorg/dev1", "myorg/pol1").Policy)
+
+	// Rollback checking cannot be enabled again.
+	_, err = db.UpdateRetryCount("myorg/dev1", "myorg/pol1", 2, "ag2")
+	assert.Nil(t, err)
+	_, err = db.DisableRollbackChecking("myorg/dev1", "myorg/pol1")
+	assert.Nil(t, err)
+	_, err = db.SingleWorkloadUsageUpdate("myorg/dev1", "myorg/pol1", func(w persistence.WorkloadUsage) *persistence.WorkloadUsage {
+		w.DisableRetry = false
+		return &w
+	})
+	assert.Nil(t, err)
+	wu = findWorkloadUsage(t, db, "myorg/dev1", "myorg/pol1")
+	assert.True(t, wu.DisableRetry)
+	assert.Equal(t, 0, wu.RetryCount)
+
+	// A workload usage that does not exist cannot be updated.
+	_, err = db.UpdatePendingUpgrade("myorg/dev2", "myorg/pol1")
+	assert.NotNil(t, err)
+	_, err = db.UpdatePriority("myorg/dev2", "myorg/pol1", 2, 90, 30, "ag2")
+	assert.NotNil(t, err)
+}
+
+func testWorkloadUsageQueries(t *testing.T, db persistence.AgbotDatabase) {
+	assert.Nil(t, db.NewWorkloadUsage("myorg/dev1", []string{}, "", "myorg/pol1", 1, 60, 120, false, "ag1"))
+	assert.Nil(t, db.NewWorkloadUsage("myorg/dev2", []string{}, "", "myorg/pol1", 1, 60, 120, false, "ag2"))
+	assert.Nil(t, db.NewWorkloadUsage("myorg/dev1", []string{}, "", "myorg/pol2", 1, 60, 120, false, "ag3"))
+
+	assert.ElementsMatch(t, []string{"ag1", "ag2", "ag3"}, findWorkloadUsageAgreementIds(t, db, []persistence.WUFilter{}))
+	assert.ElementsMatch(t, []string{"ag1", "ag3"}, findWorkloadUsageAgreementIds(t, db, []persistence.WUFilter{persistence.DWUFilter("myorg/dev1")}))
+	assert.ElementsMatch(t, []string{"ag1", "ag2"}, findWorkloadUsageAgreementIds(t, db, []persistence.WUFilter{persistence.PWUFilter("myorg/pol1")}))
+	assert.ElementsMatch(t, []string{"ag3"}, findWorkloadUsageAgreementIds(t, db, []persistence.WUFilter{persistence.DaPWUFilter("myorg/dev1", "myorg/pol2")}))
+	assert.ElementsMatch(t, []string{}, findWorkloadUsageAgreementIds(t, db, []persistence.WUFilter{persistence.DWUFilter("myorg/dev2"), persistence.PWUFilter("myorg/pol2")}))
+
+	count, err := db.GetWorkloadUsagesCount(primaryPartition(t, db))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), count)
+
+	assert.Nil(t, db.DeleteWorkloadUsage("myorg/dev1", "myorg/pol1"))
+	count, err = db.GetWorkloadUsagesCount(primaryPartition(t, db))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), count)
+}
+
+// The databases keep the search sessions in different ways. The bolt database has one session for all policies, and
+// starts a new session with a full node search every time. These tests only check what the node search relies on:
+// a session is ended once, an ended session is followed by a new session, and the changedSince of a new session is
+// never later than what the previous session or a reset set it to, so that no node changes are missed.
+func testSearchSessions(t *testing.T, db persistence.AgbotDatabase) {
+	pol := "myorg/pol1"
+
+	token1, cs, err := db.ObtainSearchSession(pol)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, token1)
+	assert.Zero(t, cs)
+
+	// A session is ended only once.
+	_, err = db.UpdateSearchSessionChangedSince(cs, 100, pol)
+	assert.Nil(t, err)
+	ended, err := db.UpdateSearchSessionChangedSince(cs, 100, pol)
+	assert.Nil(t, err)
+	assert.True(t, ended)
+
+	token2, cs, err := db.ObtainSearchSession(pol)
+	assert.Nil(t, err)
+	assert.NotEqual(t, token1, token2)
+	assert.True(t, cs <= 100, "changedSince %v", cs)
+
+	// A retry of a policy goes back in time, even when the session was in flight when the retry was added.
+	assert.Nil(t, db.ResetPolicyChangedSince(pol, 50))
+	_, err = db.UpdateSearchSessionChangedSince(cs, 200, pol)
+	assert.Nil(t, err)
+	_, cs, err = db.ObtainSearchSession(pol)
+	assert.Nil(t, err)
+	assert.True(t, cs <= 50, "changedSince %v", cs)
+
+	// A restart of the agbot goes back in time for all the policies.
+	_, err = db.UpdateSearchSessionChangedSince(cs, 300, pol)
+	assert.Nil(t, err)
+	assert.Nil(t, db.ResetAllChangedSince(20))
+	_, cs, err = db.ObtainSearchSession(pol)
+	assert.Nil(t, err)
+	assert.True(t, cs <= 20, "changedSince %v", cs)
+
+	sessions, err := db.FindSearchSessions()
+	assert.Nil(t, err)
+	assert.NotEmpty(t, sessions)
+	assert.Nil(t, db.DumpSearchSessions())
+}
+
+// The imported records are the same as the records that were exported.
+func testImport(t *testing.T, db persistence.AgbotDatabase) {
+	ag := persistence.Agreement{
+		CurrentAgreementId:       "ag1",
+		Org:                      "myorg",
+		DeviceId:                 "myorg/dev1",
+		DeviceType:               persistence.DEVICE_TYPE_DEVICE,
+		HAPartners:               []string{},
+		AgreementProtocol:        protocol,
+		AgreementProtocolVersion: 2,
+		AgreementInceptionTime:   1000,
+		AgreementCreationTime:    1010,
+		Proposal:                 "proposal",
+		PolicyName:               "myorg/pol1",
+		MeteringNotificationMsgs: []string{"", ""},
+		Archived:                 true,
+		TerminatedReason:         5,
+		TerminatedDescription:    "cancelled",
+		ServiceId:                []string{"myorg/svc1"},
+	}
+	assert.Nil(t, db.ImportAgreement(&ag, protocol))
+	assert.NotNil(t, db.ImportAgreement(&ag, protocol))
+	assert.Equal(t, ag, *findAgreement(t, db, "ag1"))
+
+	wu := persistence.WorkloadUsage{
+		DeviceId:           "myorg/dev1",
+		HAPartners:         []string{},
+		PolicyName:         "myorg/pol1",
+		Priority:           2,
+		RetryCount:         1,
+		RetryDurationS:     60,
+		CurrentAgreementId: "ag1",
+		FirstTryTime:       1000,
+		VerifiedDurationS:  120,
+	}
+	assert.Nil(t, db.ImportWorkloadUsage(&wu))
+	assert.NotNil(t, db.ImportWorkloadUsage(&wu))
+	imported := findWorkloadUsage(t, db, "myorg/dev1", "myorg/pol1")
+	imported.Id = wu.Id // The databases assign the primary key in different ways.
+	assert.Equal(t, wu, imported)
+
+	// A search session that already exists keeps the earlier changedSince.
+	assert.Nil(t, db.ImportSearchSession(&persistence.SearchSession{PolicyName: "myorg/pol1", ChangedSince: 100, SessionToken: 5}))
+	_, cs, err := db.ObtainSearchSession("myorg/pol1")
+	assert.Nil(t, err)
+	assert.True(t, cs <= 100, "changedSince %v", cs)
+	assert.Nil(t, db.ImportSearchSession(&persistence.SearchSession{PolicyName: "myorg/pol1", ChangedSince: 200, SessionToken: 7}))
+	_, cs, err = db.ObtainSearchSession("myorg/pol1")
+	assert.Nil(t, err)
+	assert.True(t, cs <= 100, "changedSince %v", cs)
+}
+
+// Utility functions used by the tests.
+
+func primaryPartition(t *testing.T, db persistence.AgbotDatabase) string {
+	partitions, err := db.FindPartitions()
+	assert.Nil(t, err)
+	if assert.Len(t, partitions, 1) {
+		return partitions[0]
+	}
+	return ""
+}
+
+func createAgreement(t *testing.T, db persistence.AgbotDatabase, agreementId string, deviceId string, policyName string) {
+	err := db.AgreementAttempt(agreementId, "myorg", deviceId, persistence.DEVICE_TYPE_DEVICE, policyName, "", "", "", protocol, "", []string{}, policy.NodeHealth{}, 60, 0)
+	assert.Nil(t, err)
+}
+
+// Fails the test when the agreement does not exist.
+func findAgreement(t *testing.T, db persistence.AgbotDatabase, agreementId string) *persistence.Agreement {
+	ag, err := db.FindSingleAgreementByAgreementId(agreementId, protocol, []persistence.AFilter{})
+	assert.Nil(t, err)
+	if !assert.NotNil(t, ag, "agreement %v", agreementId) {
+		t.FailNow()
+	}
+	return ag
+}
+
+func findAgreementIds(t *testing.T, db persistence.AgbotDatabase, filters []persistence.AFilter, protocol string) []string {
+	ags, err := db.FindAgreements(filters, protocol)
+	assert.Nil(t, err)
+	ids := make([]string, 0, len(ags))
+	for _, ag := range ags {
+		ids = append(ids, ag.CurrentAgreementId)
+	}
+	return ids
+}
+
+// Fails the test when the workload usage does not exist.
+func findWorkloadUsage(t *testing.T, db persistence.AgbotDatabase, deviceId string, policyName string) persistence.WorkloadUsage {
+	wu, err := db.FindSingleWorkloadUsageByDeviceAndPolicyName(deviceId, policyName)
+	assert.Nil(t, err)
+	if !assert.NotNil(t, wu, "workload usage of %v and %v", deviceId, policyName) {
+		t.FailNow()
+	}
+	return *wu
+}
+
+func findWorkloadUsageAgreementIds(t *testing.T, db persistence.AgbotDatabase, filters []persistence.WUFilter) []string {
+	wus, err := db.FindWorkloadUsages(filters)
+	assert.Nil(t, err)
+	ids := make([]string, 0, len(wus))
+	for _, wu := range wus {
+		ids = append(ids, wu.CurrentAgreementId)
+	}
+	return ids
+}
@@ -25,6 +25,29 @@ type AgbotDatabase interface {
 	GetPartitionOwner(id string) (string, error)
 	MovePartition(timeout uint64) (bool, error)
 
+	// Node search shard related functions. BalanceShards renews the leases on the shards owned by this agbot, gives up or
+	// claims shards so that the live agbots own about the same number of shards, and returns the shards owned by this agbot.
+	// Shards whose lease has not been renewed within the timeout can be claimed by another agbot.
+	BalanceShards(shardCount int, timeout uint64) ([]int, error)
+	FindShards() ([]Shard, error)
+	ReleaseShards() error
+
+	// Functions related to pausing agreement-making for deployment policies and patterns. PausePolicy replaces an existing
+	// entry for the same policy. ResumePolicy returns false if the policy was not paused.
+	PausePolicy(pp *PausedPolicy) error
+	ResumePolicy(policyType string, name string) (bool, error)
+	FindPausedPolicies() ([]PausedPolicy, error)
+
+	// Webhook notification delivery related functions. QueueWebhookDelivery replaces an existing delivery with the same id.
+	// ClaimWebhookDeliveries returns up to limit deliveries whose next attempt is due, oldest first, and moves their next
+	// attempt leaseS seconds into the future so that no other agbot delivers them at the same time. A claimed delivery that
+	// is neither deleted nor rescheduled is claimed again when the lease is over.
+	QueueWebhookDelivery(d *WebhookDelivery) error
+	ClaimWebhookDeliveries(now uint64, leaseS uint64, limit int) ([]WebhookDelivery, error)
+	RescheduleWebhookDelivery(id string, attempts int, nextAttempt uint64, lastError string) error
+	DeleteWebhookDelivery(id string) error
+	FindWebhookDeliveries() ([]WebhookDelivery, error)
+
 	// Persistent agreement related functions
 	FindAgreements(filters []AFilter, protocol string) ([]Agreement, error)
 	FindSingleAgreementByAgreementId(agreementid string, protocol string, filters []AFilter) (*Agreement, error)
@@ -74,4 +97,12 @@ type AgbotDatabase interface {
 	ResetAllChangedSince(newChangedSince uint64) error
 	ResetPolicyChangedSince(policy string, newChangedSince uint64) error
 	DumpSearchSessions() error
+	FindSearchSessions() ([]SearchSession, error)
+
+	// Functions used to migrate the records of one agbot database to another. The records are written as they are,
+	// into the primary partition. It is an error if the agreement or workload usage already exists. A search session
+	// that already exists keeps the earlier changedSince, so that no node changes are missed.
+	ImportAgreement(agreement *Agreement, protocol string) error
+	ImportWorkloadUsage(wu *WorkloadUsage) error
+	ImportSearchSession(ss *SearchSession) error
 }
@@ -0,0 +1,232 @@
+package memory
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/agreementbot/persistence"
+	"github.com/open-horizon/anax/policy"
+	"sort"
+	"sync"
+)
+
+// An agbot database that keeps all of its records in memory, so they are lost when the agbot process ends. It is
+// intended for agbot unit tests, which need a database that behaves like the real ones but does not need a file or
+// a database server. The records are kept in their serialized form, so that callers can never share a record with
+// the database, which is also the case for the bolt and Postgresql databases.
+type AgbotMemoryDB struct {
+	lock                sync.Mutex
+	agreements          map[string]map[string][]byte // agreements by protocol and agreement id
+	workloadUsages      map[uint64][]byte            // workload usages by record id
+	nextWorkloadUsageId uint64
+	searchSessions      map[string]*searchSession // search sessions by policy name
+	heartbeat           uint64
+	shards              map[int]*persistence.Shard             // node search shards by shard id
+	pausedPolicies      map[string]persistence.PausedPolicy    // paused policies by type and name
+	webhookDeliveries   map[string]persistence.WebhookDelivery // webhook deliveries by id
+}
+
+func (db *AgbotMemoryDB) String() string {
+	return fmt.Sprintf("In-memory DB Handle, agreement protocols: %v, workload usages: %v", len(db.agreements), len(db.workloadUsages))
+}
+
+func (db *AgbotMemoryDB) Close() {
+	glog.V(2).Infof("Closed in-memory database")
+}
+
+func (db *AgbotMemoryDB) GetAgreementCount(partition string) (int64, int64, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	var activeNum, archivedNum int64
+	for _, protocol := range policy.AllAgreementProtocols() {
+		for _, serial := range db.agreements[protocol] {
+			var a persistence.Agreement
+			if err := json.Unmarshal(serial, &a); err != nil {
+				return 0, 0, fmt.Errorf("Unable to deserialize agreement record: %v", string(serial))
+			} else if a.Archived {
+				archivedNum += 1
+			} else {
+				activeNum += 1
+			}
+		}
+	}
+	return activeNum, archivedNum, nil
+}
+
+// The agreements are returned in order of agreement id.
+func (db *AgbotMemoryDB) FindAgreements(filters []persistence.AFilter, protocol string) ([]persistence.Agreement, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	ids := make([]string, 0, len(db.agreements[protocol]))
+	for id := range db.agreements[protocol] {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	agreements := make([]persistence.Agreement, 0)
+	for _, id := range ids {
+		var a persistence.Agreement
+		if err := json.Unmarshal(db.agreements[protocol][id], &a); err != nil {
+			return nil, fmt.Errorf("Unable to deserialize agreement record: %v", string(db.agreements[protocol][id]))
+		} else if persistence.RunFilters(&a, filters) != nil {
+			agreements = append(agreements, a)
+		}
+	}
+	return agreements, nil
+}
+
+func (db *AgbotMemoryDB) AgreementAttempt(agreementid string, org string, deviceid string, deviceType string, policyName string, bcType string, bcName string, bcOrg string, agreementProto string, pattern string, serviceId []string, nhPolicy policy.NodeHealth, protocolTimeout uint64, agreementTimeout uint64) error {
+	if agreement, err := persistence.NewAgreement(agreementid, org, deviceid, deviceType, policyName, bcType, bcName, bcOrg, agreementProto, pattern, serviceId, nhPolicy, protocolTimeout, agreementTimeout); err != nil {
+		return err
+	} else {
+		return db.persistNew(agreement, agreementProto)
+	}
+}
+
+func (db *AgbotMemoryDB) ImportAgreement(agreement *persistence.Agreement, protocol string) error {
+	return db.persistNew(agreement, protocol)
+}
+
+func (db *AgbotMemoryDB) AgreementUpdate(agreementid string, proposal string, policy string, dvPolicy policy.DataVerification, defaultCheckRate uint64, hash string, sig string, protocol string, agreementProtoVersion int) (*persistence.Agreement, error) {
+	return persistence.AgreementUpdate(db, agreementid, proposal, policy, dvPolicy, defaultCheckRate, hash, sig, protocol, agreementProtoVersion)
+}
+
+func (db *AgbotMemoryDB) AgreementMade(agreementId string, counterParty string, signature string, protocol string, hapartners []string, bcType string, bcName string, bcOrg string) (*persistence.Agreement, error) {
+	return persistence.AgreementMade(db, agreementId, counterParty, signature, protocol, hapartners, bcType, bcName, bcOrg)
+}
+
+func (db *AgbotMemoryDB) AgreementBlockchainUpdate(agreementId string, consumerSig string, hash string, counterParty string, signature string, protocol string) (*persistence.Agreement, error) {
+	return persistence.AgreementBlockchainUpdate(db, agreementId, consumerSig, hash, counterParty, signature, protocol)
+}
+
+func (db *AgbotMemoryDB) AgreementBlockchainUpdateAck(agreementId string, protocol string) (*persistence.Agreement, error) {
+	return persistence.AgreementBlockchainUpdateAck(db, agreementId, protocol)
+}
+
+func (db *AgbotMemoryDB) AgreementFinalized(agreementId string, protocol string) (*persistence.Agreement, error) {
+	return persistence.AgreementFinalized(db, agreementId, protocol)
+}
+
+func (db *AgbotMemoryDB) AgreementTimedout(agreementid string, protocol string) (*persistence.Agreement, error) {
+	return persistence.AgreementTimedout(db, agreementid, protocol)
+}
+
+func (db *AgbotMemoryDB) DataVerified(agreementid string, protocol string) (*persistence.Agreement, error) {
+	return persistence.DataVerified(db, agreementid, protocol)
+}
+
+func (db *AgbotMemoryDB) DataNotVerified(agreementid string, protocol string) (*persistence.Agreement, error) {
+	return persistence.DataNotVerified(db, agreementid, protocol)
+}
+
+func (db *AgbotMemoryDB) DataNotification(agreementid string, protocol string) (*persistence.Agreement, error) {
+	return persistence.DataNotification(db, agreementid, protocol)
+}
+
+func (db *AgbotMemoryDB) MeteringNotification(agreementid string, protocol string, mn string) (*persistence.Agreement, error) {
+	return persistence.MeteringNotification(db, agreementid, protocol, mn)
+}
+
+func (db *AgbotMemoryDB) ArchiveAgreement(agreementid string, protocol string, reason uint, desc string) (*persistence.Agreement, error) {
+	return persistence.ArchiveAgreement(db, agreementid, protocol, reason, desc)
+}
+
+// no error on not found, only nil
+func (db *AgbotMemoryDB) FindSingleAgreementByAgreementId(agreementid string, protocol string, filters []persistence.AFilter) (*persistence.Agreement, error) {
+	filters = append(filters, persistence.IdAFilter(agreementid))
+
+	if agreements, err := db.FindAgreements(filters, protocol); err != nil {
+		return nil, err
+	} else if len(agreements) == 0 {
+		return nil, nil
+	} else {
+		return &agreements[0], nil
+	}
+}
+
+// no error on not found, only nil
+func (db *AgbotMemoryDB) FindSingleAgreementByAgreementIdAllProtocols(agreementid string, protocols []string, filters []persistence.AFilter) (*persistence.Agreement, error) {
+	for _, protocol := range protocols {
+		if agreement, err := db.FindSingleAgreementByAgreementId(agreementid, protocol, filters); err != nil {
+			return nil, err
+		} else if agreement != nil {
+			return agreement, nil
+		}
+	}
+	return nil, nil
+}
+
+func (db *AgbotMemoryDB) SingleAgreementUpdate(agreementid string, protocol string, fn func(persistence.Agreement) *persistence.Agreement) (*persistence.Agreement, error) {
+	if agreement, err := db.FindSingleAgreementByAgreementId(agreementid, protocol, []persistence.AFilter{}); err != nil {
+		return nil, err
+	} else if agreement == nil {
+		return nil, fmt.Errorf("Unable to locate agreement id: %v", agreementid)
+	} else {
+		updated := fn(*agreement)
+		return updated, db.persistUpdatedAgreement(agreementid, protocol, updated)
+	}
+}
+
+// The current record is read and updated under the database lock, so the state transitions are checked against the
+// latest record, the same way as in the transaction of the other databases.
+func (db *AgbotMemoryDB) persistUpdatedAgreement(agreementid string, protocol string, update *persistence.Agreement) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	var mod persistence.Agreement
+	if current, ok := db.agreements[protocol][agreementid]; !ok {
+		return fmt.Errorf("No agreement with given id available to update: %v", agreementid)
+	} else if err := json.Unmarshal(current, &mod); err != nil {
+		return fmt.Errorf("Failed to unmarshal agreement DB data: %v", string(current))
+	}
+
+	persistence.ValidateStateTransition(&mod, update)
+
+	if serialized, err := json.Marshal(mod); err != nil {
+		return fmt.Errorf("Failed to serialize agreement record: %v", mod)
+	} else {
+		db.agreements[protocol][agreementid] = serialized
+		glog.V(2).Infof("Succeeded updating agreement record to %v", mod)
+	}
+	return nil
+}
+
+func (db *AgbotMemoryDB) DeleteAgreement(pk string, protocol string) error {
+	if pk == "" {
+		return fmt.Errorf("Missing required arg pk")
+	}
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if _, ok := db.agreements[protocol][pk]; !ok {
+		glog.Warningf("Warning: record deletion requested, but record does not exist: %v", pk)
+		return nil // handle already-deleted agreement as success
+	}
+	delete(db.agreements[protocol], pk)
+	return nil
+}
+
+func (db *AgbotMemoryDB) persistNew(agreement *persistence.Agreement, protocol string) error {
+	if agreement.CurrentAgreementId == "" || protocol == "" {
+		return fmt.Errorf("Missing required args, agreement id and/or protocol")
+	}
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if _, ok := db.agreements[protocol][agreement.CurrentAgreementId]; ok {
+		return fmt.Errorf("Agreement %v already exists.", agreement.CurrentAgreementId)
+	} else if serialized, err := json.Marshal(agreement); err != nil {
+		return fmt.Errorf("Unable to serialize record %v. Error: %v", agreement, err)
+	} else {
+		if _, ok := db.agreements[protocol]; !ok {
+			db.agreements[protocol] = make(map[string][]byte)
+		}
+		db.agreements[protocol][agreement.CurrentAgreementId] = serialized
+		glog.V(2).Infof("Succeeded creating agreement record %v", agreement.CurrentAgreementId)
+	}
+	return nil
+}
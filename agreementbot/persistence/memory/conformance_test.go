@@ -0,0 +1,19 @@
+// +build unit
+
+package memory
+
+import (
+	"github.com/open-horizon/anax/agreementbot/persistence"
+	"github.com/open-horizon/anax/agreementbot/persistence/conformance"
+	"github.com/open-horizon/anax/config"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_Conformance(t *testing.T) {
+	conformance.RunSuite(t, func(t *testing.T) persistence.AgbotDatabase {
+		db := new(AgbotMemoryDB)
+		assert.Nil(t, db.Initialize(&config.HorizonConfig{}))
+		return db
+	})
+}
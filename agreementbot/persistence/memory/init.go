@@ -0,0 +1,23 @@
+package memory
+
+import (
+	"github.com/open-horizon/anax/agreementbot/persistence"
+	"github.com/open-horizon/anax/config"
+)
+
+// Setup everything the in-memory database needs to be able to run an agbot. Nothing is read from the config, and any
+// records from a previous use of the database object are discarded.
+func (db *AgbotMemoryDB) Initialize(cfg *config.HorizonConfig) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	db.agreements = make(map[string]map[string][]byte)
+	db.workloadUsages = make(map[uint64][]byte)
+	db.nextWorkloadUsageId = 0
+	db.searchSessions = make(map[string]*searchSession)
+	db.heartbeat = 0
+	db.shards = make(map[int]*persistence.Shard)
+	db.pausedPolicies = make(map[string]persistence.PausedPolicy)
+	db.webhookDeliveries = make(map[string]persistence.WebhookDelivery)
+	return nil
+}
@@ -0,0 +1,87 @@
+package memory
+
+import (
+	"github.com/open-horizon/anax/agreementbot/persistence"
+	"sort"
+	"time"
+)
+
+// Functions related to partitions in the in-memory database. Like the bolt database, it has only 1 global partition.
+const PARTITION = "global"
+
+func (db *AgbotMemoryDB) FindPartitions() ([]string, error) {
+	return []string{PARTITION}, nil
+}
+
+func (db *AgbotMemoryDB) ClaimPartition(timeout uint64) (string, error) {
+	return PARTITION, nil
+}
+
+func (db *AgbotMemoryDB) HeartbeatPartition() error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+	db.heartbeat = uint64(time.Now().Unix())
+	return nil
+}
+
+func (db *AgbotMemoryDB) GetHeartbeat() (uint64, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+	return db.heartbeat, nil
+}
+
+func (db *AgbotMemoryDB) QuiescePartition() error {
+	return nil
+}
+
+func (db *AgbotMemoryDB) GetPartitionOwner(id string) (string, error) {
+	return PARTITION, nil
+}
+
+func (db *AgbotMemoryDB) MovePartition(timeout uint64) (bool, error) {
+	return false, nil
+}
+
+// The in-memory database is used by a single agbot, so it owns all the shards, but it keeps the leases like the Postgresql
+// database does. A released shard is kept without an owner until the next balance.
+func (db *AgbotMemoryDB) BalanceShards(shardCount int, timeout uint64) ([]int, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	now := uint64(time.Now().Unix())
+	for id := range db.shards {
+		if id >= shardCount {
+			delete(db.shards, id)
+		}
+	}
+	owned := make([]int, 0, shardCount)
+	for id := 0; id < shardCount; id++ {
+		db.shards[id] = &persistence.Shard{Id: id, Owner: PARTITION, Heartbeat: now}
+		owned = append(owned, id)
+	}
+	return owned, nil
+}
+
+// The shards are returned in order of shard id.
+func (db *AgbotMemoryDB) FindShards() ([]persistence.Shard, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	shards := make([]persistence.Shard, 0, len(db.shards))
+	for _, s := range db.shards {
+		shards = append(shards, *s)
+	}
+	sort.Slice(shards, func(i, j int) bool { return shards[i].Id < shards[j].Id })
+	return shards, nil
+}
+
+func (db *AgbotMemoryDB) ReleaseShards() error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	for _, s := range db.shards {
+		s.Owner = ""
+		s.Heartbeat = 0
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package memory
+
+import (
+	"github.com/open-horizon/anax/agreementbot/persistence"
+	"sort"
+)
+
+func pausedPolicyKey(policyType string, name string) string {
+	return policyType + "/" + name
+}
+
+func (db *AgbotMemoryDB) PausePolicy(pp *persistence.PausedPolicy) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	db.pausedPolicies[pausedPolicyKey(pp.Type, pp.Name)] = *pp
+	return nil
+}
+
+func (db *AgbotMemoryDB) ResumePolicy(policyType string, name string) (bool, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	key := pausedPolicyKey(policyType, name)
+	if _, ok := db.pausedPolicies[key]; !ok {
+		return false, nil
+	}
+	delete(db.pausedPolicies, key)
+	return true, nil
+}
+
+// The paused policies are returned in order of type and name.
+func (db *AgbotMemoryDB) FindPausedPolicies() ([]persistence.PausedPolicy, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	paused := make([]persistence.PausedPolicy, 0, len(db.pausedPolicies))
+	for _, pp := range db.pausedPolicies {
+		paused = append(paused, pp)
+	}
+	sort.Slice(paused, func(i, j int) bool {
+		return pausedPolicyKey(paused[i].Type, paused[i].Name) < pausedPolicyKey(paused[j].Type, paused[j].Name)
+	})
+	return paused, nil
+}
@@ -0,0 +1,129 @@
+package memory
+
+import (
+	"errors"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/agreementbot/persistence"
+	"sort"
+	"strconv"
+)
+
+// The in-memory database keeps a search session for each policy, with the same behavior as the search sessions in
+// the Postgresql database.
+type searchSession struct {
+	persistence.SearchSession
+	RestartChangedSince uint64 // The changedSince to use when the next session is started, because an agbot restarted.
+}
+
+// The session token of the first search session of a policy, the same as in the Postgresql database.
+const FIRST_SESSION_TOKEN = 1999999998
+
+// Get a session token and changedSince values so that the caller can use it to perform a node search. The
+// returned token might be a new session token or it might be the current session, depends whether or not the
+// session has ended.
+func (db *AgbotMemoryDB) ObtainSearchSession(policyName string) (string, uint64, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	ss, ok := db.searchSessions[policyName]
+	if !ok {
+		ss = &searchSession{SearchSession: persistence.SearchSession{PolicyName: policyName, SessionToken: FIRST_SESSION_TOKEN}}
+		db.searchSessions[policyName] = ss
+	} else if ss.SessionEnded {
+		if ss.RestartChangedSince != 0 {
+			ss.ChangedSince = ss.RestartChangedSince
+			ss.RestartChangedSince = 0
+		}
+		// The session token is actually a number so be careful of the number rolling over.
+		if ss.SessionToken >= 2000000000 {
+			ss.SessionToken = 1
+		} else {
+			ss.SessionToken += 1
+		}
+		ss.SessionEnded = false
+	}
+	return strconv.FormatUint(ss.SessionToken, 10), ss.ChangedSince, nil
+}
+
+// Update the changed since time and end the current session, if the session has not already been ended. The returned
+// boolean indicates whether or not the session was already ended.
+func (db *AgbotMemoryDB) UpdateSearchSessionChangedSince(currentChangedSince uint64, newChangedSince uint64, policyName string) (bool, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	ss, ok := db.searchSessions[policyName]
+	if !ok || ss.SessionEnded || ss.ChangedSince != currentChangedSince {
+		return true, nil
+	}
+	ss.ChangedSince = newChangedSince
+	ss.SessionEnded = true
+	return false, nil
+}
+
+// Sessions in flight pick up the new changedSince when their next session starts.
+func (db *AgbotMemoryDB) ResetAllChangedSince(newChangedSince uint64) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	for _, ss := range db.searchSessions {
+		if ss.SessionEnded {
+			ss.ChangedSince = newChangedSince
+		} else {
+			ss.RestartChangedSince = newChangedSince
+		}
+	}
+	return nil
+}
+
+func (db *AgbotMemoryDB) ResetPolicyChangedSince(policy string, newChangedSince uint64) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if ss, ok := db.searchSessions[policy]; ok && (ss.RestartChangedSince == 0 || ss.RestartChangedSince > newChangedSince) {
+		ss.RestartChangedSince = newChangedSince
+	}
+	return nil
+}
+
+func (db *AgbotMemoryDB) DumpSearchSessions() error {
+	sessions, _ := db.FindSearchSessions()
+	for _, ss := range sessions {
+		glog.V(4).Infof("Search Session: %v", ss)
+	}
+	return nil
+}
+
+// The search sessions are returned in order of policy name.
+func (db *AgbotMemoryDB) FindSearchSessions() ([]persistence.SearchSession, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	sessions := make([]persistence.SearchSession, 0, len(db.searchSessions))
+	for _, ss := range db.searchSessions {
+		sessions = append(sessions, ss.SearchSession)
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].PolicyName < sessions[j].PolicyName })
+	return sessions, nil
+}
+
+// An imported search session is ended. A search session that already exists keeps the earlier changedSince.
+func (db *AgbotMemoryDB) ImportSearchSession(imported *persistence.SearchSession) error {
+	if imported.PolicyName == "" {
+		return errors.New("unable to import a search session without a policy name")
+	}
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if ss, ok := db.searchSessions[imported.PolicyName]; !ok {
+		db.searchSessions[imported.PolicyName] = &searchSession{SearchSession: persistence.SearchSession{
+			PolicyName:   imported.PolicyName,
+			ChangedSince: imported.ChangedSince,
+			SessionToken: imported.SessionToken,
+			SessionEnded: true,
+		}}
+	} else if ss.ChangedSince > imported.ChangedSince {
+		ss.ChangedSince = imported.ChangedSince
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package memory
+
+import (
+	"github.com/open-horizon/anax/agreementbot/persistence"
+)
+
+func (db *AgbotMemoryDB) QueueWebhookDelivery(d *persistence.WebhookDelivery) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	db.webhookDeliveries[d.Id] = *d
+	return nil
+}
+
+func (db *AgbotMemoryDB) ClaimWebhookDeliveries(now uint64, leaseS uint64, limit int) ([]persistence.WebhookDelivery, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	due := make([]persistence.WebhookDelivery, 0)
+	for _, d := range db.webhookDeliveries {
+		if d.NextAttempt <= now {
+			due = append(due, d)
+		}
+	}
+	persistence.SortWebhookDeliveries(due)
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	for i := range due {
+		d := db.webhookDeliveries[due[i].Id]
+		d.NextAttempt = now + leaseS
+		db.webhookDeliveries[d.Id] = d
+	}
+	return due, nil
+}
+
+func (db *AgbotMemoryDB) RescheduleWebhookDelivery(id string, attempts int, nextAttempt uint64, lastError string) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if d, ok := db.webhookDeliveries[id]; ok {
+		d.Attempts = attempts
+		d.NextAttempt = nextAttempt
+		d.LastError = lastError
+		db.webhookDeliveries[id] = d
+	}
+	return nil
+}
+
+func (db *AgbotMemoryDB) DeleteWebhookDelivery(id string) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	delete(db.webhookDeliveries, id)
+	return nil
+}
+
+// The webhook deliveries are returned oldest first.
+func (db *AgbotMemoryDB) FindWebhookDeliveries() ([]persistence.WebhookDelivery, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	deliveries := make([]persistence.WebhookDelivery, 0, len(db.webhookDeliveries))
+	for _, d := range db.webhookDeliveries {
+		deliveries = append(deliveries, d)
+	}
+	persistence.SortWebhookDeliveries(deliveries)
+	return deliveries, nil
+}
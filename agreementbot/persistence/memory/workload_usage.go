@@ -0,0 +1,178 @@
+package memory
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/agreementbot/persistence"
+	"sort"
+)
+
+func (db *AgbotMemoryDB) NewWorkloadUsage(deviceId string, hapartners []string, policy string, policyName string, priority int, retryDurationS int, verifiedDurationS int, reqsNotMet bool, agid string) error {
+	if wlUsage, err := persistence.NewWorkloadUsage(deviceId, hapartners, policy, policyName, priority, retryDurationS, verifiedDurationS, reqsNotMet, agid); err != nil {
+		return err
+	} else {
+		return db.wuPersistNew(wlUsage)
+	}
+}
+
+// The imported record gets a new primary key, the same as in the bolt database.
+func (db *AgbotMemoryDB) ImportWorkloadUsage(wu *persistence.WorkloadUsage) error {
+	return db.wuPersistNew(wu)
+}
+
+func (db *AgbotMemoryDB) GetWorkloadUsagesCount(partition string) (int64, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+	return int64(len(db.workloadUsages)), nil
+}
+
+func (db *AgbotMemoryDB) FindSingleWorkloadUsageByDeviceAndPolicyName(deviceid string, policyName string) (*persistence.WorkloadUsage, error) {
+	if wlUsages, err := db.FindWorkloadUsages([]persistence.WUFilter{persistence.DaPWUFilter(deviceid, policyName)}); err != nil {
+		return nil, err
+	} else if len(wlUsages) > 1 {
+		return nil, fmt.Errorf("Expected only one record for device: %v and policy: %v, but retrieved: %v", deviceid, policyName, wlUsages)
+	} else if len(wlUsages) == 0 {
+		return nil, nil
+	} else {
+		return &wlUsages[0], nil
+	}
+}
+
+// The workload usages are returned in the order they were created.
+func (db *AgbotMemoryDB) FindWorkloadUsages(filters []persistence.WUFilter) ([]persistence.WorkloadUsage, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+	return db.findWorkloadUsages(filters)
+}
+
+func (db *AgbotMemoryDB) UpdatePendingUpgrade(deviceid string, policyName string) (*persistence.WorkloadUsage, error) {
+	return persistence.UpdatePendingUpgrade(db, deviceid, policyName)
+}
+
+func (db *AgbotMemoryDB) UpdateRetryCount(deviceid string, policyName string, retryCount int, agid string) (*persistence.WorkloadUsage, error) {
+	return persistence.UpdateRetryCount(db, deviceid, policyName, retryCount, agid)
+}
+
+func (db *AgbotMemoryDB) UpdatePriority(deviceid string, policyName string, priority int, retryDurationS int, verifiedDurationS int, agid string) (*persistence.WorkloadUsage, error) {
+	return persistence.UpdatePriority(db, deviceid, policyName, priority, retryDurationS, verifiedDurationS, agid)
+}
+
+func (db *AgbotMemoryDB) UpdatePolicy(deviceid string, policyName string, pol string) (*persistence.WorkloadUsage, error) {
+	return persistence.UpdatePolicy(db, deviceid, policyName, pol)
+}
+
+func (db *AgbotMemoryDB) UpdateWUAgreementId(deviceid string, policyName string, agid string, protocol string) (*persistence.WorkloadUsage, error) {
+	return persistence.UpdateWUAgreementId(db, deviceid, policyName, agid)
+}
+
+func (db *AgbotMemoryDB) DisableRollbackChecking(deviceid string, policyName string) (*persistence.WorkloadUsage, error) {
+	return persistence.DisableRollbackChecking(db, deviceid, policyName)
+}
+
+func (db *AgbotMemoryDB) SingleWorkloadUsageUpdate(deviceid string, policyName string, fn func(persistence.WorkloadUsage) *persistence.WorkloadUsage) (*persistence.WorkloadUsage, error) {
+	if wlUsage, err := db.FindSingleWorkloadUsageByDeviceAndPolicyName(deviceid, policyName); err != nil {
+		return nil, err
+	} else if wlUsage == nil {
+		return nil, fmt.Errorf("Unable to locate workload usage for device: %v, and policy: %v", deviceid, policyName)
+	} else {
+		updated := fn(*wlUsage)
+		return updated, db.persistUpdatedWorkloadUsage(wlUsage.Id, updated)
+	}
+}
+
+// The current record is read and updated under the database lock, so the state transitions are checked against the
+// latest record, the same way as in the transaction of the other databases.
+func (db *AgbotMemoryDB) persistUpdatedWorkloadUsage(id uint64, update *persistence.WorkloadUsage) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	var mod persistence.WorkloadUsage
+	if current, ok := db.workloadUsages[id]; !ok {
+		return fmt.Errorf("No workload usage with id %v available to update", id)
+	} else if err := json.Unmarshal(current, &mod); err != nil {
+		return fmt.Errorf("Failed to unmarshal workload usage DB data: %v", string(current))
+	}
+
+	persistence.ValidateWUStateTransition(&mod, update)
+
+	if serialized, err := json.Marshal(mod); err != nil {
+		return fmt.Errorf("Failed to serialize workload usage record: %v", mod)
+	} else {
+		db.workloadUsages[id] = serialized
+		glog.V(2).Infof("Succeeded updating workload usage record to %v", mod.ShortString())
+	}
+	return nil
+}
+
+func (db *AgbotMemoryDB) DeleteWorkloadUsage(deviceid string, policyName string) error {
+	if deviceid == "" || policyName == "" {
+		return fmt.Errorf("Missing required arg deviceid or policyName")
+	}
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if wlUsages, err := db.findWorkloadUsages([]persistence.WUFilter{persistence.DaPWUFilter(deviceid, policyName)}); err != nil {
+		return err
+	} else if len(wlUsages) == 0 {
+		glog.Warningf("Warning: workload usage deletion requested, but record does not exist for device: %v, and policy: %v", deviceid, policyName)
+		return nil // handle already-deleted workload usage as success
+	} else {
+		for _, wu := range wlUsages {
+			delete(db.workloadUsages, wu.Id)
+		}
+		glog.V(3).Infof("Deleted workload usage record for %v with policy %v", deviceid, policyName)
+	}
+	return nil
+}
+
+// This function assumes that the caller holds the database lock.
+func (db *AgbotMemoryDB) findWorkloadUsages(filters []persistence.WUFilter) ([]persistence.WorkloadUsage, error) {
+	ids := make([]uint64, 0, len(db.workloadUsages))
+	for id := range db.workloadUsages {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	wlUsages := make([]persistence.WorkloadUsage, 0)
+	for _, id := range ids {
+		var wu persistence.WorkloadUsage
+		if err := json.Unmarshal(db.workloadUsages[id], &wu); err != nil {
+			return nil, fmt.Errorf("Unable to deserialize workload usage record: %v", string(db.workloadUsages[id]))
+		}
+		exclude := false
+		for _, filterFn := range filters {
+			if !filterFn(wu) {
+				exclude = true
+			}
+		}
+		if !exclude {
+			wlUsages = append(wlUsages, wu)
+		}
+	}
+	return wlUsages, nil
+}
+
+// The primary key of the new record is allocated from the database's sequence counter, the record is updated with
+// this key before it is written.
+func (db *AgbotMemoryDB) wuPersistNew(record *persistence.WorkloadUsage) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if existing, err := db.findWorkloadUsages([]persistence.WUFilter{persistence.DaPWUFilter(record.DeviceId, record.PolicyName)}); err != nil {
+		return err
+	} else if len(existing) != 0 {
+		return fmt.Errorf("Workload usage record for device %v and policy name %v already exists.", record.DeviceId, record.PolicyName)
+	}
+
+	db.nextWorkloadUsageId += 1
+	record.Id = db.nextWorkloadUsageId
+	if serialized, err := json.Marshal(record); err != nil {
+		return fmt.Errorf("Unable to serialize record %v. Error: %v", record, err)
+	} else {
+		db.workloadUsages[record.Id] = serialized
+		glog.V(2).Infof("Succeeded writing workload usage record identified by key %v, record %v", record.Id, record.ShortString())
+	}
+	return nil
+}
@@ -0,0 +1,278 @@
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/agreementbot/persistence"
+	"github.com/open-horizon/anax/policy"
+	"sort"
+)
+
+// The counts of the records that were found in the source database and the records that were written to the target
+// database. Records that already exist in the target database are skipped, so that a migration which failed half way
+// can be run again.
+type Result struct {
+	Partition          string `json:"partition,omitempty"`
+	DryRun             bool   `json:"dryRun"`
+	ActiveAgreements   int    `json:"activeAgreements"`
+	ArchivedAgreements int    `json:"archivedAgreements"`
+	WorkloadUsages     int    `json:"workloadUsages"`
+	SearchSessions     int    `json:"searchSessions"`
+	PausedPolicies     int    `json:"pausedPolicies"`
+	WebhookDeliveries  int    `json:"webhookDeliveries"`
+	Imported           int    `json:"imported"`
+	Skipped            int    `json:"skipped"`
+}
+
+func (r Result) String() string {
+	return fmt.Sprintf("Partition: %v, DryRun: %v, ActiveAgreements: %v, ArchivedAgreements: %v, WorkloadUsages: %v, SearchSessions: %v, PausedPolicies: %v, WebhookDeliveries: %v, Imported: %v, Skipped: %v",
+		r.Partition, r.DryRun, r.ActiveAgreements, r.ArchivedAgreements, r.WorkloadUsages, r.SearchSessions, r.PausedPolicies, r.WebhookDeliveries, r.Imported, r.Skipped)
+}
+
+// The records of an agbot database, as they are read from the source database.
+type records struct {
+	agreements     map[string][]persistence.Agreement // keyed by agreement protocol
+	workloadUsages []persistence.WorkloadUsage
+	searchSessions []persistence.SearchSession
+	pausedPolicies []persistence.PausedPolicy
+	deliveries     []persistence.WebhookDelivery
+}
+
+// Copy all the agreements (active and archived), workload usages, search sessions, paused policies and queued webhook
+// deliveries of the src database into the primary partition of the dst database, and then verify that dst contains all
+// of them. Both databases must already be initialized. When dryRun is true, the src database is read and counted but
+// nothing is written to dst.
+func Migrate(src persistence.AgbotDatabase, dst persistence.AgbotDatabase, dryRun bool) (*Result, error) {
+
+	res := &Result{DryRun: dryRun}
+
+	recs, err := read(src, res)
+	if err != nil {
+		return res, err
+	}
+	glog.V(3).Infof("Agbot database migration found %v active agreements, %v archived agreements, %v workload usages and %v search sessions", res.ActiveAgreements, res.ArchivedAgreements, res.WorkloadUsages, res.SearchSessions)
+
+	if dryRun {
+		return res, nil
+	}
+
+	if p, ok := dst.(interface{ PrimaryPartition() string }); ok {
+		res.Partition = p.PrimaryPartition()
+	}
+
+	if err := write(dst, recs, res); err != nil {
+		return res, err
+	}
+	return res, verify(dst, recs)
+}
+
+// Read all the records of the source database.
+func read(src persistence.AgbotDatabase, res *Result) (*records, error) {
+	recs := &records{agreements: make(map[string][]persistence.Agreement)}
+
+	for _, protocol := range policy.AllAgreementProtocols() {
+		ags, err := src.FindAgreements([]persistence.AFilter{}, protocol)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("unable to read %v agreements, error: %v", protocol, err))
+		}
+		for _, ag := range ags {
+			if ag.Archived {
+				res.ArchivedAgreements++
+			} else {
+				res.ActiveAgreements++
+			}
+		}
+		recs.agreements[protocol] = ags
+	}
+
+	wus, err := src.FindWorkloadUsages([]persistence.WUFilter{})
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("unable to read workload usages, error: %v", err))
+	}
+	recs.workloadUsages = wus
+	res.WorkloadUsages = len(wus)
+
+	sessions, err := src.FindSearchSessions()
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("unable to read search sessions, error: %v", err))
+	}
+	recs.searchSessions = expandSearchSessions(sessions, recs)
+	res.SearchSessions = len(recs.searchSessions)
+
+	paused, err := src.FindPausedPolicies()
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("unable to read paused policies, error: %v", err))
+	}
+	recs.pausedPolicies = paused
+	res.PausedPolicies = len(paused)
+
+	deliveries, err := src.FindWebhookDeliveries()
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("unable to read webhook deliveries, error: %v", err))
+	}
+	recs.deliveries = deliveries
+	res.WebhookDeliveries = len(deliveries)
+
+	return recs, nil
+}
+
+// The bolt database has one search session for all policies, it has no policy name. The postgresql database has a
+// search session per policy, so the session is copied for each policy that is used by an agreement or a workload usage.
+// Policies without agreements get a new search session when the agbot first searches for them.
+func expandSearchSessions(sessions []persistence.SearchSession, recs *records) []persistence.SearchSession {
+	policyNames := make(map[string]bool)
+	for _, ags := range recs.agreements {
+		for _, ag := range ags {
+			if ag.PolicyName != "" {
+				policyNames[ag.PolicyName] = true
+			}
+		}
+	}
+	for _, wu := range recs.workloadUsages {
+		if wu.PolicyName != "" {
+			policyNames[wu.PolicyName] = true
+		}
+	}
+	names := make([]string, 0, len(policyNames))
+	for n := range policyNames {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	expanded := make([]persistence.SearchSession, 0, len(sessions))
+	for _, ss := range sessions {
+		if ss.PolicyName != "" {
+			expanded = append(expanded, ss)
+			continue
+		}
+		for _, n := range names {
+			policySession := ss
+			policySession.PolicyName = n
+			expanded = append(expanded, policySession)
+		}
+	}
+	return expanded
+}
+
+// A target database with more than one partition, like postgresql, only finds the records in the partitions that it owns
+// with the AgbotDatabase functions. The records that an earlier migration wrote into another partition are only found
+// in all the partitions of the database.
+type allPartitionsFinder interface {
+	FindAgreementInAllPartitions(agreementId string, protocol string) (*persistence.Agreement, error)
+	FindWorkloadUsageInAllPartitions(deviceId string, policyName string) (*persistence.WorkloadUsage, error)
+}
+
+// Find the agreement in any partition of the target database.
+func findAgreement(dst persistence.AgbotDatabase, agreementId string, protocol string) (*persistence.Agreement, error) {
+	if f, ok := dst.(allPartitionsFinder); ok {
+		return f.FindAgreementInAllPartitions(agreementId, protocol)
+	}
+	return dst.FindSingleAgreementByAgreementId(agreementId, protocol, []persistence.AFilter{})
+}
+
+// Find the workload usage in any partition of the target database.
+func findWorkloadUsage(dst persistence.AgbotDatabase, deviceId string, policyName string) (*persistence.WorkloadUsage, error) {
+	if f, ok := dst.(allPartitionsFinder); ok {
+		return f.FindWorkloadUsageInAllPartitions(deviceId, policyName)
+	}
+	return dst.FindSingleWorkloadUsageByDeviceAndPolicyName(deviceId, policyName)
+}
+
+// Write the records that do not exist yet into the target database.
+func write(dst persistence.AgbotDatabase, recs *records, res *Result) error {
+
+	for _, protocol := range policy.AllAgreementProtocols() {
+		for i := range recs.agreements[protocol] {
+			ag := &recs.agreements[protocol][i]
+			if existing, err := findAgreement(dst, ag.CurrentAgreementId, protocol); err != nil {
+				return errors.New(fmt.Sprintf("unable to read agreement %v, error: %v", ag.CurrentAgreementId, err))
+			} else if existing != nil {
+				glog.V(3).Infof("Agbot database migration skipped agreement %v, it already exists", ag.CurrentAgreementId)
+				res.Skipped++
+			} else if err := dst.ImportAgreement(ag, protocol); err != nil {
+				return errors.New(fmt.Sprintf("unable to write agreement %v, error: %v", ag.CurrentAgreementId, err))
+			} else {
+				res.Imported++
+			}
+		}
+	}
+
+	for i := range recs.workloadUsages {
+		wu := &recs.workloadUsages[i]
+		if existing, err := findWorkloadUsage(dst, wu.DeviceId, wu.PolicyName); err != nil {
+			return errors.New(fmt.Sprintf("unable to read workload usage for device %v and policy %v, error: %v", wu.DeviceId, wu.PolicyName, err))
+		} else if existing != nil {
+			glog.V(3).Infof("Agbot database migration skipped workload usage for device %v and policy %v, it already exists", wu.DeviceId, wu.PolicyName)
+			res.Skipped++
+		} else if err := dst.ImportWorkloadUsage(wu); err != nil {
+			return errors.New(fmt.Sprintf("unable to write workload usage for device %v and policy %v, error: %v", wu.DeviceId, wu.PolicyName, err))
+		} else {
+			res.Imported++
+		}
+	}
+
+	for i := range recs.searchSessions {
+		if err := dst.ImportSearchSession(&recs.searchSessions[i]); err != nil {
+			return errors.New(fmt.Sprintf("unable to write search session %v, error: %v", recs.searchSessions[i], err))
+		}
+		res.Imported++
+	}
+
+	// A paused policy that already exists is replaced, it stays paused either way.
+	for i := range recs.pausedPolicies {
+		if err := dst.PausePolicy(&recs.pausedPolicies[i]); err != nil {
+			return errors.New(fmt.Sprintf("unable to write paused policy %v, error: %v", recs.pausedPolicies[i], err))
+		}
+		res.Imported++
+	}
+
+	existing, err := dst.FindWebhookDeliveries()
+	if err != nil {
+		return errors.New(fmt.Sprintf("unable to read webhook deliveries, error: %v", err))
+	}
+	queued := make(map[string]bool, len(existing))
+	for _, d := range existing {
+		queued[d.Id] = true
+	}
+	for i := range recs.deliveries {
+		d := &recs.deliveries[i]
+		if queued[d.Id] {
+			glog.V(3).Infof("Agbot database migration skipped webhook delivery %v, it already exists", d.Id)
+			res.Skipped++
+		} else if err := dst.QueueWebhookDelivery(d); err != nil {
+			return errors.New(fmt.Sprintf("unable to write webhook delivery %v, error: %v", d.Id, err))
+		} else {
+			res.Imported++
+		}
+	}
+
+	return nil
+}
+
+// Verify that every agreement and workload usage of the source database is in the target database, with the same
+// archived state.
+func verify(dst persistence.AgbotDatabase, recs *records) error {
+
+	for _, protocol := range policy.AllAgreementProtocols() {
+		for _, ag := range recs.agreements[protocol] {
+			if existing, err := findAgreement(dst, ag.CurrentAgreementId, protocol); err != nil {
+				return errors.New(fmt.Sprintf("unable to verify agreement %v, error: %v", ag.CurrentAgreementId, err))
+			} else if existing == nil {
+				return errors.New(fmt.Sprintf("verification failed, agreement %v is missing", ag.CurrentAgreementId))
+			} else if existing.Archived != ag.Archived {
+				return errors.New(fmt.Sprintf("verification failed, agreement %v has archived %v, expected %v", ag.CurrentAgreementId, existing.Archived, ag.Archived))
+			}
+		}
+	}
+
+	for _, wu := range recs.workloadUsages {
+		if existing, err := findWorkloadUsage(dst, wu.DeviceId, wu.PolicyName); err != nil {
+			return errors.New(fmt.Sprintf("unable to verify workload usage for device %v and policy %v, error: %v", wu.DeviceId, wu.PolicyName, err))
+		} else if existing == nil {
+			return errors.New(fmt.Sprintf("verification failed, workload usage for device %v and policy %v is missing", wu.DeviceId, wu.PolicyName))
+		}
+	}
+
+	return nil
+}
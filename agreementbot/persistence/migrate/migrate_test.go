@@ -0,0 +1,243 @@
+// +build unit
+
+package migrate
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/open-horizon/anax/agreementbot/persistence"
+	"github.com/open-horizon/anax/agreementbot/persistence/bolt"
+	"github.com/open-horizon/anax/agreementbot/persistence/postgresql"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/policy"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// The name of the envvar that holds the json config of a local Postgresql database, see the postgresql conformance test.
+const POSTGRESQL_CONFIG_ENVVAR = "HORIZON_TEST_AGBOT_POSTGRESQL"
+
+const DROP_TABLES = `DROP TABLE IF EXISTS agreements, workload_usages, partitions, shards, paused_policies, webhook_deliveries, search_sessions, version, version_history CASCADE;`
+
+func Test_Migrate(t *testing.T) {
+	src, srcDir := openBolt(t)
+	defer os.RemoveAll(srcDir)
+	defer src.Close()
+	dst, dstDir := openBolt(t)
+	defer os.RemoveAll(dstDir)
+	defer dst.Close()
+
+	protocol := policy.BasicProtocol
+	for _, id := range []string{"ag1", "ag2", "ag3"} {
+		err := src.AgreementAttempt(id, "myorg", "myorg/"+id+"dev", "device", "myorg/pol1", "", "", "", protocol, "", []string{"svc"}, policy.NodeHealth{}, 60, 0)
+		assert.Nil(t, err)
+	}
+	_, err := src.ArchiveAgreement("ag3", protocol, 1, "cancelled")
+	assert.Nil(t, err)
+	assert.Nil(t, src.NewWorkloadUsage("myorg/ag1dev", nil, "", "myorg/pol1", 1, 60, 60, false, "ag1"))
+	assert.Nil(t, src.NewWorkloadUsage("myorg/ag2dev", nil, "", "myorg/pol2", 1, 60, 60, false, "ag2"))
+	assert.Nil(t, src.PausePolicy(&persistence.PausedPolicy{Type: persistence.PAUSED_PATTERN, Name: "myorg/pat1", PausedBy: "myorg/admin", PausedAt: 1000}))
+	assert.Nil(t, src.QueueWebhookDelivery(&persistence.WebhookDelivery{Id: "d1", URL: "https://hooks.example.com", Event: "node_out_of_policy", Payload: "{}", NextAttempt: 1000, CreatedAt: 1000}))
+
+	// a dry run only reads the source database
+	res, err := Migrate(src, dst, true)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, res.ActiveAgreements)
+	assert.Equal(t, 1, res.ArchivedAgreements)
+	assert.Equal(t, 2, res.WorkloadUsages)
+	assert.Equal(t, 2, res.SearchSessions)
+	assert.Equal(t, 1, res.PausedPolicies)
+	assert.Equal(t, 1, res.WebhookDeliveries)
+	assert.Equal(t, 0, res.Imported)
+	active, archived, err := dst.GetAgreementCount("")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), active+archived)
+
+	res, err = Migrate(src, dst, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 3+2+2+1+1, res.Imported)
+	assert.Equal(t, 0, res.Skipped)
+	active, archived, err = dst.GetAgreementCount("")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), active)
+	assert.Equal(t, int64(1), archived)
+	wus, err := dst.FindWorkloadUsages([]persistence.WUFilter{})
+	assert.Nil(t, err)
+	assert.Len(t, wus, 2)
+	paused, err := dst.FindPausedPolicies()
+	assert.Nil(t, err)
+	assert.Len(t, paused, 1)
+	deliveries, err := dst.FindWebhookDeliveries()
+	assert.Nil(t, err)
+	assert.Len(t, deliveries, 1)
+
+	// running the migration again skips the records that were already migrated
+	res, err = Migrate(src, dst, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 2+1, res.Imported)
+	assert.Equal(t, 3+2+1, res.Skipped)
+}
+
+// A migration that failed half way is run again into another partition, it must not import the records that the failed
+// migration wrote into the partition of the first run.
+func Test_Migrate_rerun(t *testing.T) {
+	src, srcDir := openBolt(t)
+	defer os.RemoveAll(srcDir)
+	defer src.Close()
+	fillSource(t, src)
+
+	first, firstDir := openBolt(t)
+	defer os.RemoveAll(firstDir)
+	defer first.Close()
+
+	_, err := Migrate(src, &testTarget{AgbotDatabase: first, failAfter: 2}, false)
+	assert.NotNil(t, err)
+
+	// the second run writes into a new partition, the records of the first run are in another partition
+	second, secondDir := openBolt(t)
+	defer os.RemoveAll(secondDir)
+	defer second.Close()
+
+	res, err := Migrate(src, &testTarget{AgbotDatabase: second, earlier: []persistence.AgbotDatabase{first}}, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, res.Skipped)
+
+	for _, id := range []string{"ag1", "ag2", "ag3"} {
+		inFirst, err := first.FindSingleAgreementByAgreementId(id, policy.BasicProtocol, []persistence.AFilter{})
+		assert.Nil(t, err)
+		inSecond, err := second.FindSingleAgreementByAgreementId(id, policy.BasicProtocol, []persistence.AFilter{})
+		assert.Nil(t, err)
+		assert.True(t, (inFirst == nil) != (inSecond == nil), "agreement %v should be in exactly one partition", id)
+	}
+}
+
+// The same as Test_Migrate_rerun with a Postgresql target database. The partition of the failed migration is taken over
+// by an agbot before the migration is run again, so the second run claims a new partition.
+func Test_Migrate_rerun_postgresql(t *testing.T) {
+	pgConfig := config.PostgresqlConfig{}
+	if envConfig := os.Getenv(POSTGRESQL_CONFIG_ENVVAR); envConfig == "" {
+		t.Skipf("set %v to the config of a local Postgresql database to run this test", POSTGRESQL_CONFIG_ENVVAR)
+	} else if err := json.Unmarshal([]byte(envConfig), &pgConfig); err != nil {
+		t.Fatalf("unable to demarshal %v, error: %v", POSTGRESQL_CONFIG_ENVVAR, err)
+	}
+	cfg := &config.HorizonConfig{AgreementBot: config.AGConfig{Postgresql: pgConfig}}
+
+	connectInfo, _ := pgConfig.MakeConnectionString()
+	pgdb, err := sql.Open("postgres", connectInfo)
+	if err != nil {
+		t.Fatalf("unable to open Postgresql database, error: %v", err)
+	}
+	defer pgdb.Close()
+	_, err = pgdb.Exec(DROP_TABLES)
+	assert.Nil(t, err)
+
+	src, srcDir := openBolt(t)
+	defer os.RemoveAll(srcDir)
+	defer src.Close()
+	fillSource(t, src)
+
+	first := new(postgresql.AgbotPostgresqlDB)
+	assert.Nil(t, first.Initialize(cfg))
+	_, err = Migrate(src, &testTarget{AgbotDatabase: first, failAfter: 2}, false)
+	assert.NotNil(t, err)
+	assert.Nil(t, first.QuiescePartition())
+	first.Close()
+
+	agbot := new(postgresql.AgbotPostgresqlDB)
+	assert.Nil(t, agbot.Initialize(cfg))
+	defer agbot.Close()
+
+	second := new(postgresql.AgbotPostgresqlDB)
+	assert.Nil(t, second.Initialize(cfg))
+	defer second.Close()
+	assert.NotEqual(t, agbot.PrimaryPartition(), second.PrimaryPartition())
+
+	res, err := Migrate(src, second, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, res.Skipped)
+
+	var agreements, workloadUsages int
+	assert.Nil(t, pgdb.QueryRow(`SELECT count(*) FROM agreements;`).Scan(&agreements))
+	assert.Nil(t, pgdb.QueryRow(`SELECT count(*) FROM workload_usages;`).Scan(&workloadUsages))
+	assert.Equal(t, 3, agreements)
+	assert.Equal(t, 2, workloadUsages)
+}
+
+func Test_expandSearchSessions(t *testing.T) {
+	recs := &records{
+		agreements: map[string][]persistence.Agreement{
+			policy.BasicProtocol: {{PolicyName: "org/p2"}, {PolicyName: "org/p1"}, {PolicyName: "org/p2"}},
+		},
+		workloadUsages: []persistence.WorkloadUsage{{PolicyName: "org/p3"}},
+	}
+
+	sessions := expandSearchSessions([]persistence.SearchSession{{ChangedSince: 10, SessionToken: 5}}, recs)
+	assert.Len(t, sessions, 3)
+	assert.Equal(t, "org/p1", sessions[0].PolicyName)
+	assert.Equal(t, "org/p3", sessions[2].PolicyName)
+	assert.Equal(t, uint64(10), sessions[1].ChangedSince)
+
+	// the sessions of a database that has a session per policy are copied as they are
+	sessions = expandSearchSessions([]persistence.SearchSession{{PolicyName: "org/p9"}}, recs)
+	assert.Equal(t, []persistence.SearchSession{{PolicyName: "org/p9"}}, sessions)
+}
+
+func openBolt(t *testing.T) (*bolt.AgbotBoltDB, string) {
+	dir, err := ioutil.TempDir("", "agbot-db-migrate-")
+	assert.Nil(t, err)
+	db := new(bolt.AgbotBoltDB)
+	err = db.Initialize(&config.HorizonConfig{AgreementBot: config.AGConfig{DBPath: dir}})
+	assert.Nil(t, err)
+	return db, dir
+}
+
+// Three agreements, one of them archived, and two workload usages.
+func fillSource(t *testing.T, src persistence.AgbotDatabase) {
+	for _, id := range []string{"ag1", "ag2", "ag3"} {
+		err := src.AgreementAttempt(id, "myorg", "myorg/"+id+"dev", "device", "myorg/pol1", "", "", "", policy.BasicProtocol, "", []string{"svc"}, policy.NodeHealth{}, 60, 0)
+		assert.Nil(t, err)
+	}
+	_, err := src.ArchiveAgreement("ag3", policy.BasicProtocol, 1, "cancelled")
+	assert.Nil(t, err)
+	assert.Nil(t, src.NewWorkloadUsage("myorg/ag1dev", nil, "", "myorg/pol1", 1, 60, 60, false, "ag1"))
+	assert.Nil(t, src.NewWorkloadUsage("myorg/ag2dev", nil, "", "myorg/pol2", 1, 60, 60, false, "ag2"))
+}
+
+// A target database that fails to import agreements after failAfter agreements were imported, zero means never. It
+// finds records in its own partition and in the partitions of the earlier targets, like a Postgresql database finds the
+// records in the partitions of all the agbots.
+type testTarget struct {
+	persistence.AgbotDatabase
+	earlier   []persistence.AgbotDatabase
+	failAfter int
+	imported  int
+}
+
+func (t *testTarget) ImportAgreement(ag *persistence.Agreement, protocol string) error {
+	if t.failAfter != 0 && t.imported == t.failAfter {
+		return errors.New("import failed")
+	}
+	t.imported++
+	return t.AgbotDatabase.ImportAgreement(ag, protocol)
+}
+
+func (t *testTarget) FindAgreementInAllPartitions(agreementId string, protocol string) (*persistence.Agreement, error) {
+	for _, db := range append([]persistence.AgbotDatabase{t.AgbotDatabase}, t.earlier...) {
+		if ag, err := findAgreement(db, agreementId, protocol); err != nil || ag != nil {
+			return ag, err
+		}
+	}
+	return nil, nil
+}
+
+func (t *testTarget) FindWorkloadUsageInAllPartitions(deviceId string, policyName string) (*persistence.WorkloadUsage, error) {
+	for _, db := range append([]persistence.AgbotDatabase{t.AgbotDatabase}, t.earlier...) {
+		if wu, err := findWorkloadUsage(db, deviceId, policyName); err != nil || wu != nil {
+			return wu, err
+		}
+	}
+	return nil, nil
+}
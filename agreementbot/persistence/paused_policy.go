@@ -0,0 +1,55 @@
+package persistence
+
+import (
+	"fmt"
+	"github.com/open-horizon/anax/policy"
+)
+
+// The types of policies that agreement-making can be paused for.
+const PAUSED_DEPLOYMENT_POLICY = "deploymentpol"
+const PAUSED_PATTERN = "pattern"
+
+// An operator can pause agreement-making for a deployment policy or a pattern. While it is paused, the agbots do not search
+// for nodes for it and do not make new agreements with it. The existing agreements are not affected.
+type PausedPolicy struct {
+	Type     string `json:"type"`      // Either PAUSED_DEPLOYMENT_POLICY or PAUSED_PATTERN.
+	Name     string `json:"name"`      // The fully qualified (org/name) name of the deployment policy or pattern.
+	PausedBy string `json:"paused_by"` // The fully qualified (org/user) exchange user that paused it.
+	PausedAt uint64 `json:"paused_at"` // The time it was paused, in seconds since the epoch.
+}
+
+func (p PausedPolicy) String() string {
+	return fmt.Sprintf("Type: %v, Name: %v, PausedBy: %v, PausedAt: %v", p.Type, p.Name, p.PausedBy, p.PausedAt)
+}
+
+func IsPausedPolicyType(t string) bool {
+	return t == PAUSED_DEPLOYMENT_POLICY || t == PAUSED_PATTERN
+}
+
+// Returns true if the consumer policy that the agbot made from a deployment policy or pattern is paused. The name of a
+// policy made from a deployment policy is the fully qualified deployment policy name, a policy made from a pattern has
+// the fully qualified pattern name in its pattern id.
+func (p PausedPolicy) MatchesPolicy(pol *policy.Policy) bool {
+	if p.Type == PAUSED_PATTERN {
+		return pol.PatternId == p.Name
+	}
+	return pol.PatternId == "" && pol.Header.Name == p.Name
+}
+
+// Returns true if the agreement was made with the paused deployment policy or pattern.
+func (p PausedPolicy) MatchesAgreement(ag *Agreement) bool {
+	if p.Type == PAUSED_PATTERN {
+		return ag.Pattern == p.Name
+	}
+	return ag.Pattern == "" && ag.PolicyName == p.Name
+}
+
+// Returns the paused entry that matches the policy, or nil if the policy is not paused.
+func FindPausedPolicy(paused []PausedPolicy, pol *policy.Policy) *PausedPolicy {
+	for i := range paused {
+		if paused[i].MatchesPolicy(pol) {
+			return &paused[i]
+		}
+	}
+	return nil
+}
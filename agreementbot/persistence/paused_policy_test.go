@@ -0,0 +1,32 @@
+// +build unit
+
+package persistence
+
+import (
+	"github.com/open-horizon/anax/policy"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_PausedPolicy_matches(t *testing.T) {
+	deploymentPol := PausedPolicy{Type: PAUSED_DEPLOYMENT_POLICY, Name: "myorg/pol1"}
+	pattern := PausedPolicy{Type: PAUSED_PATTERN, Name: "myorg/pat1"}
+
+	bp := policy.Policy{Header: policy.PolicyHeader{Name: "myorg/pol1"}}
+	pp := policy.Policy{Header: policy.PolicyHeader{Name: "pat1_svc_myorg_amd64"}, PatternId: "myorg/pat1"}
+	assert.True(t, deploymentPol.MatchesPolicy(&bp))
+	assert.False(t, deploymentPol.MatchesPolicy(&pp))
+	assert.True(t, pattern.MatchesPolicy(&pp))
+	assert.False(t, pattern.MatchesPolicy(&bp))
+
+	bpAg := Agreement{PolicyName: "myorg/pol1"}
+	ppAg := Agreement{PolicyName: "pat1_svc_myorg_amd64", Pattern: "myorg/pat1"}
+	assert.True(t, deploymentPol.MatchesAgreement(&bpAg))
+	assert.False(t, deploymentPol.MatchesAgreement(&ppAg))
+	assert.True(t, pattern.MatchesAgreement(&ppAg))
+	assert.False(t, pattern.MatchesAgreement(&bpAg))
+
+	paused := []PausedPolicy{deploymentPol}
+	assert.Equal(t, &paused[0], FindPausedPolicy(paused, &bp))
+	assert.Nil(t, FindPausedPolicy(paused, &pp))
+}
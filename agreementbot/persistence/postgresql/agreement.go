@@ -18,15 +18,11 @@ func init() {
 }
 
 // Constants for the SQL statements that are used to work with agreements. Agreements are partitioned by agbot instances. Each
-// agbot instance "owns" 1 partitions in the database. Postgresql supports automatic partitioning, which is what we want
-// to use but cannot because Postgresql 10 is too new. Instead we have implemented our own partitioning scheme using table inheritance.
-// There is a "main" table (called agreements) that defines the schema for all the partitions, and then there is a
-// set of separate tables (called agreements_<partition_name>, one for each partition) that we have to create. When an agbot
-// comes up it will attempt to create the main table and a partition table (and index) for its primary partition that inherites
-// from the main table. The main table will never have any rows in it. Our code will use the partition name to figure out which table
-// to use for INSERTs/UPDATEs and SELECTs based on the partition value provided in the SQL statement. Postgresql allows us
-// to query the main table for cases where the caller might not know the partition holding the record of interest. We exploit both
-// forms of query.
+// agbot instance "owns" 1 partitions in the database. The "main" table (called agreements) is partitioned by the partition column,
+// and there is a separate table (called agreements_<partition_name>) for each partition. When an agbot comes up it will attempt to
+// create the partition table for its primary partition. Our code will use the partition name to figure out which table to use for
+// INSERTs/UPDATEs and SELECTs based on the partition value provided in the SQL statement. Postgresql allows us to query the main
+// table for cases where the caller might not know the partition holding the record of interest. We exploit both forms of query.
 //
 // IMPORTANT NOTE ====================================================================================================================
 // The lifecycle of the workload usage records is non-standard and therefore likely to be unexpected. Each record is loosely
@@ -51,47 +47,21 @@ func init() {
 // updated:      A timestamp to record last updated time.
 //
 
-/* When we migrate to postgresql 10, we can use these constant definitions because they will allow us to use
-   the built in partitioning support.
-
+// The main table is created as it was in database version v1. The v2 schema migration turns it into a partitioned table.
 const AGREEMENT_CREATE_MAIN_TABLE = `CREATE TABLE IF NOT EXISTS agreements (
 	agreement_id text NOT NULL,
 	protocol text NOT NULL,
 	partition text NOT NULL,
 	agreement jsonb NOT NULL,
 	updated timestamp with time zone DEFAULT current_timestamp
-) PARTITION BY LIST (partition);`
-
+);`
 const AGREEMENT_CREATE_PARTITION_TABLE = `CREATE TABLE IF NOT EXISTS "agreements_ PARTITION OF agreements FOR VALUES IN ('partition_name');`
-const AGREEMENT_TABLE_NAME_ROOT = `"agreements_`
-const AGREEMENT_PARTITION_FILLIN = `partition_name`
-
-const AGREEMENT_QUERY = `SELECT agreement FROM agreements WHERE agreement_id = $1 AND protocol = $2 AND partition = $3;`
-const ALL_AGREEMENTS_QUERY = `SELECT agreement FROM agreements WHERE protocol = $1 AND partition = $2;`
-const AGREEMENT_PARTITION_EMPTY = `SELECT agreement_id FROM agreements WHERE partition = $1;`
-
-const AGREEMENT_COUNT = `SELECT COUNT(*) FROM "agreements_;`
 
-const AGREEMENT_INSERT = `INSERT INTO agreements (agreement_id, protocol, partition, agreement) VALUES ($1, $2, $3, $4);`
-const AGREEMENT_UPDATE = `UPDATE agreements SET agreement = $4, updated = current_timestamp WHERE agreement_id = $1 AND protocol = $2 AND partition = $3;`
-const AGREEMENT_DELETE = `DELETE FROM agreements WHERE agreement_id = $1 AND partition = $2;`
-
-const AGREEMENT_PARTITIONS = `SELECT partition FROM agreements;`
-
-const AGREEMENT_DROP_PARTITION = `DROP TABLE "agreements_;`
-*/
-
-const AGREEMENT_CREATE_MAIN_TABLE = `CREATE TABLE IF NOT EXISTS agreements (
-	agreement_id text NOT NULL,
-	protocol text NOT NULL,
-	partition text NOT NULL,
-	agreement jsonb NOT NULL,
-	updated timestamp with time zone DEFAULT current_timestamp
-);`
-const AGREEMENT_CREATE_PARTITION_TABLE = `CREATE TABLE IF NOT EXISTS "agreements_ (
-	CHECK ( partition = 'partition_name' )
-) INHERITS (agreements);`
-const AGREEMENT_CREATE_PARTITION_INDEX = `CREATE INDEX IF NOT EXISTS "agreement_id_index_on_agreements_ ON "agreements_ (agreement_id);`
+// The indexes are created on the main table by the v3 schema migration, Postgresql creates them on each partition table.
+const AGREEMENT_CREATE_ID_INDEX = `CREATE INDEX IF NOT EXISTS agreement_id_index_on_agreements ON agreements (agreement_id);`
+const AGREEMENT_CREATE_DEVICE_INDEX = `CREATE INDEX IF NOT EXISTS device_index_on_agreements ON agreements ((agreement->>'device_id'));`
+const AGREEMENT_CREATE_POLICY_INDEX = `CREATE INDEX IF NOT EXISTS policy_index_on_agreements ON agreements ((agreement->>'policy_name'));`
+const AGREEMENT_CREATE_STATE_INDEX = `CREATE INDEX IF NOT EXISTS state_index_on_agreements ON agreements (protocol, (agreement->>'archived'));`
 
 // Please note that the following SQL statement has a different syntax where the table name is specified. Note the use of
 // single quotes instead of double quotes that are used in all the other SQL. Don't ya just love SQL syntax consistency.
@@ -104,7 +74,7 @@ const AGREEMENT_QUERY = `SELECT agreement FROM "agreements_ WHERE agreement_id =
 const ALL_AGREEMENTS_QUERY = `SELECT agreement FROM "agreements_ WHERE protocol = $1;`
 const AGREEMENT_PARTITION_EMPTY = `SELECT agreement_id FROM "agreements_;`
 
-const AGREEMENT_COUNT = `SELECT agreement FROM "agreements_;`
+const AGREEMENT_COUNT = `SELECT COUNT(*) FILTER (WHERE agreement->>'archived' = 'false'), COUNT(*) FILTER (WHERE agreement->>'archived' = 'true') FROM "agreements_;`
 
 const AGREEMENT_INSERT = `INSERT INTO "agreements_ (agreement_id, protocol, partition, agreement) VALUES ($1, $2, $3, $4);`
 const AGREEMENT_UPDATE = `UPDATE "agreements_ SET agreement = $3, updated = current_timestamp WHERE agreement_id = $1 AND protocol = $2;`
@@ -151,11 +121,6 @@ func (db *AgbotPostgresqlDB) GetPrimaryAgreementPartitionTableCreate() string {
 	return sql
 }
 
-func (db *AgbotPostgresqlDB) GetPrimaryAgreementPartitionTableIndexCreate() string {
-	sql := strings.Replace(AGREEMENT_CREATE_PARTITION_INDEX, AGREEMENT_TABLE_NAME_ROOT, db.GetAgreementPartitionTableName(db.PrimaryPartition()), 2)
-	return sql
-}
-
 func (db *AgbotPostgresqlDB) GetAgreementPartitionTableDrop(partition string) string {
 	sql := strings.Replace(AGREEMENT_DROP_PARTITION, AGREEMENT_TABLE_NAME_ROOT, db.GetAgreementPartitionTableName(partition), 1)
 	return sql
@@ -223,30 +188,8 @@ func (db *AgbotPostgresqlDB) GetAgreementCount(partition string) (int64, int64,
 
 	var activeNum, archivedNum int64
 
-	rows, err := db.db.Query(db.GetAgreementPartitionTableCount(partition))
-	if err != nil {
-		return 0, 0, errors.New(fmt.Sprintf("error getting rows for agreement counts, error: %v", err))
-	}
-
-	// If the rows object doesnt get closed, memory and connections will grow and/or leak.
-	defer rows.Close()
-	for rows.Next() {
-		agBytes := make([]byte, 0, 2048)
-		ag := new(persistence.Agreement)
-		if err := rows.Scan(&agBytes); err != nil {
-			return 0, 0, errors.New(fmt.Sprintf("error scanning row for agreement counts: %v", err))
-		} else if err := json.Unmarshal(agBytes, ag); err != nil {
-			return 0, 0, errors.New(fmt.Sprintf("error demarshalling row for agreement count: %v, error: %v", string(agBytes), err))
-		} else if ag.Archived {
-			archivedNum += 1
-		} else {
-			activeNum += 1
-		}
-	}
-
-	// The rows.Next() function will exit with false when done or an error occurred. Get any error encountered during iteration.
-	if err = rows.Err(); err != nil {
-		return 0, 0, errors.New(fmt.Sprintf("error iterating rows for agreement counts: %v", err))
+	if err := db.db.QueryRow(db.GetAgreementPartitionTableCount(partition)).Scan(&activeNum, &archivedNum); err != nil {
+		return 0, 0, errors.New(fmt.Sprintf("error scanning row for agreement counts, error: %v", err))
 	}
 
 	return activeNum, archivedNum, nil
@@ -298,11 +241,25 @@ func (db *AgbotPostgresqlDB) FindAgreements(filters []persistence.AFilter, proto
 
 // Find a specific agreement in the database. The input filters are ignored for this query. They are needed by the bolt implementation.
 func (db *AgbotPostgresqlDB) internalFindSingleAgreementByAgreementId(tx *sql.Tx, agreementId string, protocol string, filters []persistence.AFilter) (*persistence.Agreement, string, error) {
+	return db.findSingleAgreementInPartitions(tx, db.AllPartitions(), agreementId, protocol, filters)
+}
+
+// Find a specific agreement in the partitions of all the agbots, not only in the partitions owned by this agbot.
+func (db *AgbotPostgresqlDB) FindAgreementInAllPartitions(agreementId string, protocol string) (*persistence.Agreement, error) {
+	if partitions, err := db.FindPartitions(); err != nil {
+		return nil, err
+	} else {
+		ag, _, err := db.findSingleAgreementInPartitions(nil, partitions, agreementId, protocol, []persistence.AFilter{})
+		return ag, err
+	}
+}
+
+func (db *AgbotPostgresqlDB) findSingleAgreementInPartitions(tx *sql.Tx, partitions []string, agreementId string, protocol string, filters []persistence.AFilter) (*persistence.Agreement, string, error) {
 
 	agBytes := make([]byte, 0, 2048)
 	ag := new(persistence.Agreement)
 
-	for _, currentPartition := range db.AllPartitions() {
+	for _, currentPartition := range partitions {
 
 		// Find the agreement row and read in the agreement object column, run the returned agreement through the filters, then unmarshal
 		// the blob into an in memory agreement object which gets returned to the caller.
@@ -357,6 +314,10 @@ func (db *AgbotPostgresqlDB) FindSingleAgreementByAgreementIdAllProtocols(agreem
 func (db *AgbotPostgresqlDB) AgreementAttempt(agreementid string, org string, deviceid string, deviceType string, policyName string, bcType string, bcName string, bcOrg string, agreementProto string, pattern string, serviceId []string, nhPolicy policy.NodeHealth, protocolTimeout uint64, agreementTimeout uint64) error {
 	if agreement, err := persistence.NewAgreement(agreementid, org, deviceid, deviceType, policyName, bcType, bcName, bcOrg, agreementProto, pattern, serviceId, nhPolicy, protocolTimeout, agreementTimeout); err != nil {
 		return err
+	} else if existing, partition, err := db.internalFindSingleAgreementByAgreementId(nil, agreementid, agreementProto, []persistence.AFilter{}); err != nil {
+		return err
+	} else if existing != nil {
+		return fmt.Errorf("Agreement %v already exists in partition %v.", agreementid, partition)
 	} else if err := db.insertAgreement(agreement, agreementProto); err != nil {
 		return err
 	} else {
@@ -364,6 +325,15 @@ func (db *AgbotPostgresqlDB) AgreementAttempt(agreementid string, org string, de
 	}
 }
 
+func (db *AgbotPostgresqlDB) ImportAgreement(agreement *persistence.Agreement, protocol string) error {
+	if existing, partition, err := db.internalFindSingleAgreementByAgreementId(nil, agreement.CurrentAgreementId, protocol, []persistence.AFilter{}); err != nil {
+		return err
+	} else if existing != nil {
+		return fmt.Errorf("Agreement %v already exists in partition %v.", agreement.CurrentAgreementId, partition)
+	}
+	return db.insertAgreement(agreement, protocol)
+}
+
 func (db *AgbotPostgresqlDB) AgreementFinalized(agreementId string, protocol string) (*persistence.Agreement, error) {
 	return persistence.AgreementFinalized(db, agreementId, protocol)
 }
@@ -409,6 +379,10 @@ func (db *AgbotPostgresqlDB) ArchiveAgreement(agreementid string, protocol strin
 }
 
 func (db *AgbotPostgresqlDB) DeleteAgreement(agreementid string, protocol string) error {
+	if agreementid == "" {
+		return fmt.Errorf("Missing required arg agreementid")
+	}
+
 	tx, err := db.db.Begin()
 	if err != nil {
 		return err
@@ -512,9 +486,12 @@ func (db *AgbotPostgresqlDB) deleteAgreement(tx *sql.Tx, agreementId string, pro
 	// check to see if the partition specific table is now empty.
 
 	checkTableDeletion := false
-	_, partition, err := db.internalFindSingleAgreementByAgreementId(tx, agreementId, protocol, []persistence.AFilter{})
+	ag, partition, err := db.internalFindSingleAgreementByAgreementId(tx, agreementId, protocol, []persistence.AFilter{})
 	if err != nil {
 		return err
+	} else if ag == nil {
+		glog.Warningf("Warning: agreement deletion requested, but agreement %v does not exist.", agreementId)
+		return nil // handle already-deleted agreement as success
 	} else if partition != db.PrimaryPartition() {
 		checkTableDeletion = true
 	}
@@ -0,0 +1,55 @@
+// +build unit
+
+package postgresql
+
+import (
+	"database/sql"
+	"encoding/json"
+	"github.com/open-horizon/anax/agreementbot/persistence"
+	"github.com/open-horizon/anax/agreementbot/persistence/conformance"
+	"github.com/open-horizon/anax/config"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+// The name of the envvar that holds the json config of a local Postgresql database, e.g.
+// {"Host":"localhost","Port":"5432","User":"postgres","Password":"secret","DBName":"agbot_test","SSLMode":"disable"}.
+// All the agbot tables in the database are dropped, so do not use a database that contains anything valuable.
+const POSTGRESQL_CONFIG_ENVVAR = "HORIZON_TEST_AGBOT_POSTGRESQL"
+
+const DROP_TABLES = `DROP TABLE IF EXISTS agreements, workload_usages, partitions, shards, paused_policies, webhook_deliveries, search_sessions, version, version_history CASCADE;`
+
+func Test_Conformance(t *testing.T) {
+	cfg := testConfig(t)
+
+	conformance.RunSuite(t, func(t *testing.T) persistence.AgbotDatabase {
+		testConnection(t, cfg).Close()
+		db := new(AgbotPostgresqlDB)
+		assert.Nil(t, db.Initialize(cfg))
+		return db
+	})
+}
+
+// Returns the agbot config of the local Postgresql database in the envvar, the test is skipped if the envvar is not set.
+func testConfig(t *testing.T) *config.HorizonConfig {
+	pgConfig := config.PostgresqlConfig{}
+	if envConfig := os.Getenv(POSTGRESQL_CONFIG_ENVVAR); envConfig == "" {
+		t.Skipf("set %v to the config of a local Postgresql database to run this test", POSTGRESQL_CONFIG_ENVVAR)
+	} else if err := json.Unmarshal([]byte(envConfig), &pgConfig); err != nil {
+		t.Fatalf("unable to demarshal %v, error: %v", POSTGRESQL_CONFIG_ENVVAR, err)
+	}
+	return &config.HorizonConfig{AgreementBot: config.AGConfig{Postgresql: pgConfig}}
+}
+
+// Returns a connection to the local Postgresql database in which all the agbot tables were dropped.
+func testConnection(t *testing.T, cfg *config.HorizonConfig) *sql.DB {
+	connectInfo, _ := cfg.AgreementBot.Postgresql.MakeConnectionString()
+	pgdb, err := sql.Open("postgres", connectInfo)
+	if err != nil {
+		t.Fatalf("unable to open Postgresql database, error: %v", err)
+	}
+	_, err = pgdb.Exec(DROP_TABLES)
+	assert.Nil(t, err)
+	return pgdb
+}
@@ -42,11 +42,13 @@ func (db *AgbotPostgresqlDB) Initialize(cfg *config.HorizonConfig) error {
 		// Now create the tables and initialize them as necessary.
 		glog.V(3).Infof("Postgresql database tables initializing.")
 
-		// Create the version table if necessary, and insert the current version row if necessary.
+		// Create the version and version history tables if necessary, and insert the current version row if necessary.
 		if _, err := db.db.Exec(VERSION_CREATE_TABLE); err != nil {
 			return errors.New(fmt.Sprintf("unable to create version table, error: %v", err))
 		} else if _, err := db.db.Exec(VERSION_INSERT); err != nil {
 			return errors.New(fmt.Sprintf("unable to insert singleton version row, error: %v", err))
+		} else if _, err := db.db.Exec(VERSION_HISTORY_CREATE_TABLE); err != nil {
+			return errors.New(fmt.Sprintf("unable to create version history table, error: %v", err))
 		}
 
 		// Create the search session table if necessary, and initialize the stored procedure functions.
@@ -65,6 +67,19 @@ func (db *AgbotPostgresqlDB) Initialize(cfg *config.HorizonConfig) error {
 			return errors.New(fmt.Sprintf("unable to create claim unowned partition function, error: %v", err))
 		}
 
+		// Create the workload usage and agreement main tables if necessary.
+		if _, err := db.db.Exec(WORKLOAD_USAGE_CREATE_MAIN_TABLE); err != nil {
+			return errors.New(fmt.Sprintf("unable to create workload usage table, error: %v", err))
+		} else if _, err := db.db.Exec(AGREEMENT_CREATE_MAIN_TABLE); err != nil {
+			return errors.New(fmt.Sprintf("unable to create agreements table, error: %v", err))
+		}
+
+		// Migrate the database tables if necessary, before any partition tables are created for this agbot. The main
+		// tables of a new database are created at version v1, so they are migrated the same way as an existing database.
+		if err := db.migrate(); err != nil {
+			return err
+		}
+
 		// Claim a partition for ourselves.
 		if partition, err := db.ClaimPartition(cfg.GetPartitionStale()); err != nil {
 			return errors.New(fmt.Sprintf("unable to claim a partition, error: %v", err))
@@ -73,59 +88,16 @@ func (db *AgbotPostgresqlDB) Initialize(cfg *config.HorizonConfig) error {
 			db.partitions = append(db.partitions, partition)
 		}
 
-		// Create the workload usage table, partition and index if necessary.
-		if _, err := db.db.Exec(WORKLOAD_USAGE_CREATE_MAIN_TABLE); err != nil {
-			return errors.New(fmt.Sprintf("unable to create workload usage table, error: %v", err))
-		} else if _, err := db.db.Exec(db.GetPrimaryWorkloadUsagePartitionTableCreate()); err != nil {
+		// Create the workload usage and agreement partition tables if necessary. The indexes of the main tables are
+		// created on the partition tables by Postgresql.
+		if _, err := db.db.Exec(db.GetPrimaryWorkloadUsagePartitionTableCreate()); err != nil {
 			return errors.New(fmt.Sprintf("unable to create workload usage partition table, error: %v", err))
-		} else if _, err := db.db.Exec(db.GetPrimaryWorkloadUsagePartitionTableIndexCreate()); err != nil {
-			return errors.New(fmt.Sprintf("unable to create workload usage partition table index, error: %v", err))
-		}
-
-		// Create the agreement table, partition and index if necessary.
-		if _, err := db.db.Exec(AGREEMENT_CREATE_MAIN_TABLE); err != nil {
-			return errors.New(fmt.Sprintf("unable to create agreements table, error: %v", err))
 		} else if _, err := db.db.Exec(db.GetPrimaryAgreementPartitionTableCreate()); err != nil {
 			return errors.New(fmt.Sprintf("unable to create agreements partition table, error: %v", err))
-		} else if _, err := db.db.Exec(db.GetPrimaryAgreementPartitionTableIndexCreate()); err != nil {
-			return errors.New(fmt.Sprintf("unable to create agreements partition table index, error: %v", err))
 		}
 
 		glog.V(3).Infof("Postgresql primary partition database tables exist.")
 
-		// Migrate the database tables if necessary. Extract the current schema version from the version table,
-		// and then run each version's migration SQL to bring the database up to the current version supported
-		// by this code.
-		var dbVersion int
-		var description string
-		var timestamp string
-		if err := db.db.QueryRow(VERSION_QUERY).Scan(&dbVersion, &description, &timestamp); err != nil {
-			return errors.New(fmt.Sprintf("error scanning row for current version, error: %v", err))
-		} else {
-			glog.V(3).Infof("Postgresql database tables are at version %v, %v, as of %v.", dbVersion, description, timestamp)
-		}
-
-		if dbVersion < HIGHEST_DATABASE_VERSION {
-			glog.V(3).Infof("Postgresql database tables upgrading from version %v to %v.", dbVersion, HIGHEST_DATABASE_VERSION)
-
-			// Each new database version has it's own key in the migration SQL map.
-			for v := dbVersion + 1; v <= HIGHEST_DATABASE_VERSION; v++ {
-
-				// Run each SQL statement in the array of SQL statements for the current verion.
-				for si := 0; si < len(migrationSQL[v].sql); si++ {
-					if _, err := db.db.Exec(migrationSQL[v].sql[si]); err != nil {
-						return errors.New(fmt.Sprintf("unable to run SQL migration statement version %v, index %v, statement %v, error: %v", v, si, migrationSQL[v].sql[si], err))
-					} else if _, err := db.db.Exec(VERSION_UPDATE, HIGHEST_DATABASE_VERSION, migrationSQL[v].description); err != nil {
-						return errors.New(fmt.Sprintf("unable to create version table, error: %v", err))
-					} else {
-						glog.V(3).Infof("Postgresql database tables upgraded to version %v, %v", v, migrationSQL[v].description)
-					}
-				}
-			}
-
-			glog.V(3).Infof("Postgresql database tables upgraded to version %v", HIGHEST_DATABASE_VERSION)
-		}
-
 		glog.V(3).Infof("Postgresql database tables initialized.")
 
 	}
@@ -18,9 +18,10 @@ import (
 // and is no longer using its partition? The agbot is configured with a "stale" timeout. When a partition is not heartbeated
 // within the "stale" timeout time, the partition is considered stale and can be taken over by another agbot.
 //
-// Postgresql supports automatic partitioning, which is what we want to use but cannot because Postgresql 10 is too new. Instead
-// we have implemented our own partitioning scheme using table inheritance for agreement related records. This table keeps track of
-// which partitions exist and who owns them if any.
+// The agreement related records are kept in tables that use the declarative partitioning of Postgresql, partitioned by the
+// partition column. Before database version v2, the partitioning was emulated with table inheritance. The partition tables have
+// the same names in both schemes, so agbots of both versions can work with the same database during a rolling upgrade. This
+// table keeps track of which partitions exist and who owns them if any.
 //
 // partitions schema:
 // id:        The partition id, serially incremented by the database when a new partition is created.
@@ -79,6 +80,48 @@ END $$ LANGUAGE plpgsql;
 `
 const PARTITION_CLAIM_UNOWNED_BY_FUNCTION = `SELECT * FROM claim_ownerless($1, $2);`
 
+// Used by the v2 schema migration. Agbots that have not been upgraded yet wait until the migration has committed.
+const PARTITION_LOCK_TABLES = `LOCK TABLE partitions, agreements, workload_usages IN ACCESS EXCLUSIVE MODE;`
+
+// Used by the v2 schema migration to turn a table whose partitions inherit from it into a partitioned table. The partition tables
+// are detached from the renamed original table and attached to the new partitioned table, so their rows and indexes are kept. Their
+// CHECK constraint on the partition column allows Postgresql to attach them without scanning their rows.
+const PARTITION_INHERITED_TABLE_FUNCTION = `
+CREATE OR REPLACE FUNCTION partition_inherited_table(tbl text)
+	RETURNS void AS $$
+DECLARE
+	child record;
+	inherited text := tbl || '_inherited';
+BEGIN
+IF (SELECT relkind FROM pg_class WHERE oid = to_regclass(tbl)) IS DISTINCT FROM 'r' THEN
+	RETURN;
+END IF;
+EXECUTE format('ALTER TABLE %I RENAME TO %I', tbl, inherited);
+EXECUTE format('CREATE TABLE %I (LIKE %I INCLUDING DEFAULTS) PARTITION BY LIST (partition)', tbl, inherited);
+FOR child IN SELECT c.relname FROM pg_inherits i JOIN pg_class c ON c.oid = i.inhrelid WHERE i.inhparent = to_regclass(inherited) LOOP
+	EXECUTE format('ALTER TABLE %I NO INHERIT %I', child.relname, inherited);
+	EXECUTE format('ALTER TABLE %I ATTACH PARTITION %I FOR VALUES IN (%L)', tbl, child.relname, substr(child.relname, length(tbl) + 2));
+END LOOP;
+EXECUTE format('INSERT INTO %I SELECT * FROM ONLY %I', tbl, inherited);
+EXECUTE format('DROP TABLE %I', inherited);
+END $$ LANGUAGE plpgsql;
+`
+
+// Create the partition tables of a new partition when it is inserted. Agbots that have not been upgraded to v2 yet create their
+// partition tables with table inheritance, which Postgresql rejects for a partitioned table. Their CREATE TABLE IF NOT EXISTS
+// finds the tables that this trigger created, so they keep working during a rolling upgrade.
+const PARTITION_CREATE_TABLES_FUNCTION = `
+CREATE OR REPLACE FUNCTION create_partition_tables()
+	RETURNS trigger AS $$
+BEGIN
+EXECUTE format('CREATE TABLE IF NOT EXISTS %I PARTITION OF agreements FOR VALUES IN (%L)', 'agreements_' || NEW.id, NEW.id);
+EXECUTE format('CREATE TABLE IF NOT EXISTS %I PARTITION OF workload_usages FOR VALUES IN (%L)', 'workload_usages_' || NEW.id, NEW.id);
+RETURN NEW;
+END $$ LANGUAGE plpgsql;
+`
+const PARTITION_CREATE_TABLES_TRIGGER_DROP = `DROP TRIGGER IF EXISTS create_partition_tables ON partitions;`
+const PARTITION_CREATE_TABLES_TRIGGER = `CREATE TRIGGER create_partition_tables AFTER INSERT ON partitions FOR EACH ROW EXECUTE PROCEDURE create_partition_tables();`
+
 // Functions related to partitions in the postgresql database. The workload usages should always be using the same partitions
 // as the agreements, or fewer partitions if an agreement partition contains only archived records.
 
@@ -0,0 +1,80 @@
+package postgresql
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/agreementbot/persistence"
+)
+
+// Constants for the SQL statements that are used to pause agreement-making for deployment policies and patterns. The table
+// is shared by all the agbots, so a policy that is paused through any agbot is paused for all of them.
+//
+// paused_policies schema:
+// type:      The type of the paused policy, deploymentpol or pattern.
+// name:      The fully qualified (org/name) name of the deployment policy or pattern.
+// paused_by: The exchange user that paused the policy.
+// paused_at: The time when the policy was paused.
+//
+
+const PAUSED_POLICY_CREATE_TABLE = `CREATE TABLE IF NOT EXISTS paused_policies (
+	type text NOT NULL,
+	name text NOT NULL,
+	paused_by text NOT NULL,
+	paused_at timestamp with time zone NOT NULL,
+	PRIMARY KEY (type, name)
+);`
+
+const PAUSED_POLICY_UPSERT = `INSERT INTO paused_policies (type, name, paused_by, paused_at) VALUES ($1, $2, $3, to_timestamp($4))
+	ON CONFLICT (type, name) DO UPDATE SET paused_by = EXCLUDED.paused_by, paused_at = EXCLUDED.paused_at;`
+
+const PAUSED_POLICY_DELETE = `DELETE FROM paused_policies WHERE type = $1 AND name = $2;`
+
+const PAUSED_POLICY_QUERY = `SELECT type, name, paused_by, EXTRACT (EPOCH FROM paused_at) FROM paused_policies ORDER BY type, name;`
+
+func (db *AgbotPostgresqlDB) PausePolicy(pp *persistence.PausedPolicy) error {
+
+	if _, err := db.db.Exec(PAUSED_POLICY_UPSERT, pp.Type, pp.Name, pp.PausedBy, pp.PausedAt); err != nil {
+		return errors.New(fmt.Sprintf("unable to pause %v %v, error: %v", pp.Type, pp.Name, err))
+	}
+	glog.V(3).Infof("AgreementBot %v paused %v", db.identity, pp)
+	return nil
+}
+
+func (db *AgbotPostgresqlDB) ResumePolicy(policyType string, name string) (bool, error) {
+
+	res, err := db.db.Exec(PAUSED_POLICY_DELETE, policyType, name)
+	if err != nil {
+		return false, errors.New(fmt.Sprintf("unable to resume %v %v, error: %v", policyType, name, err))
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, errors.New(fmt.Sprintf("unable to get the number of resumed policies for %v %v, error: %v", policyType, name, err))
+	}
+	return rows != 0, nil
+}
+
+func (db *AgbotPostgresqlDB) FindPausedPolicies() ([]persistence.PausedPolicy, error) {
+
+	rows, err := db.db.Query(PAUSED_POLICY_QUERY)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("error querying for paused policies, error: %v", err))
+	}
+	defer rows.Close()
+
+	paused := make([]persistence.PausedPolicy, 0, 10)
+	for rows.Next() {
+		var pp persistence.PausedPolicy
+		var pausedAt sql.NullFloat64
+		if err := rows.Scan(&pp.Type, &pp.Name, &pp.PausedBy, &pausedAt); err != nil {
+			return nil, errors.New(fmt.Sprintf("error scanning row for paused policy, error: %v", err))
+		}
+		pp.PausedAt = uint64(pausedAt.Float64)
+		paused = append(paused, pp)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.New(fmt.Sprintf("error iterating paused policies, error: %v", err))
+	}
+	return paused, nil
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/golang/glog"
+	"github.com/open-horizon/anax/agreementbot/persistence"
 	"github.com/open-horizon/anax/cutil"
 	"strconv"
 	"time"
@@ -113,6 +114,16 @@ const SEARCH_SESSIONS_RESET_CHANGED_SINCE_FOR_POLICY = `UPDATE search_sessions
 	WHERE policyName = $2 AND (restartChangedSince = 0 OR restartChangedSince > $1);
 `
 
+const SEARCH_SESSIONS_FIND = `SELECT policyName, changedSince, sessionToken, sessionEnded FROM search_sessions;`
+
+// Insert an imported search session. An existing session keeps the earlier changedSince so that no node changes are missed.
+const SEARCH_SESSIONS_IMPORT = `INSERT INTO search_sessions (policyName, changedSince, sessionToken, sessionEnded, restartChangedSince, updatingAgbot)
+	VALUES ($1, $2, $3, true, 0, $4)
+	ON CONFLICT (policyName) DO UPDATE
+	SET changedSince = EXCLUDED.changedSince, updatingAgbot = EXCLUDED.updatingAgbot, updated = current_timestamp
+	WHERE search_sessions.changedSince > EXCLUDED.changedSince;
+`
+
 // Functions related to the search session table.
 
 // Get the current search session from the DB. If the current session is ended, then a new session token will
@@ -139,7 +150,11 @@ func (db *AgbotPostgresqlDB) ObtainSearchSession(policyName string) (string, uin
 func (db *AgbotPostgresqlDB) UpdateSearchSessionChangedSince(currentChangedSince uint64, newChangedSince uint64, policyName string) (bool, error) {
 	var se sql.NullBool
 	glog.V(3).Infof("AgreementBot updating changedSince from %v to %v for %v search session", time.Unix(int64(currentChangedSince), 0).Format(cutil.ExchangeTimeFormat), time.Unix(int64(newChangedSince), 0).Format(cutil.ExchangeTimeFormat), policyName)
-	if err := db.db.QueryRow(SEARCH_SESSIONS_UPDATE_CHANGED_SINCE, currentChangedSince, newChangedSince, db.identity, policyName).Scan(&se); err != nil {
+	if err := db.db.QueryRow(SEARCH_SESSIONS_UPDATE_CHANGED_SINCE, currentChangedSince, newChangedSince, db.identity, policyName).Scan(&se); err == sql.ErrNoRows {
+		// No row was updated, the session was already ended by another agbot, or it was already ended and a new session
+		// has a different changedSince.
+		return true, nil
+	} else if err != nil {
 		return false, errors.New(fmt.Sprintf("error updating %v search session changedSince, error: %v", policyName, err))
 	} else if !se.Valid {
 		return false, errors.New(fmt.Sprintf("returned search session state for %v is not a valid boolean, error: %v", policyName, err))
@@ -195,3 +210,37 @@ func (db *AgbotPostgresqlDB) DumpSearchSessions() error {
 	}
 	return nil
 }
+
+// Return the search sessions of all policies.
+func (db *AgbotPostgresqlDB) FindSearchSessions() ([]persistence.SearchSession, error) {
+	rows, err := db.db.Query(SEARCH_SESSIONS_FIND)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("error querying for search sessions, error: %v", err))
+	}
+	defer rows.Close()
+
+	sessions := make([]persistence.SearchSession, 0, 10)
+	for rows.Next() {
+		var cs, st int64
+		ss := persistence.SearchSession{}
+		if err := rows.Scan(&ss.PolicyName, &cs, &st, &ss.SessionEnded); err != nil {
+			return nil, errors.New(fmt.Sprintf("error scanning row: %v", err))
+		}
+		ss.ChangedSince = uint64(cs)
+		ss.SessionToken = uint64(st)
+		sessions = append(sessions, ss)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.New(fmt.Sprintf("error iterating: %v", err))
+	}
+	return sessions, nil
+}
+
+func (db *AgbotPostgresqlDB) ImportSearchSession(ss *persistence.SearchSession) error {
+	if ss.PolicyName == "" {
+		return errors.New("unable to import a search session without a policy name")
+	} else if _, err := db.db.Exec(SEARCH_SESSIONS_IMPORT, ss.PolicyName, ss.ChangedSince, ss.SessionToken, db.identity); err != nil {
+		return errors.New(fmt.Sprintf("error importing %v search session, error: %v", ss.PolicyName, err))
+	}
+	return nil
+}
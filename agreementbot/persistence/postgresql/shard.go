@@ -0,0 +1,173 @@
+package postgresql
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/agreementbot/persistence"
+)
+
+// Constants for the SQL statements that are used to work with node search shards. The deployment policies and patterns served
+// by the agbots are split into a fixed number of shards, and each agbot only searches for nodes for the policies of the shards that
+// it owns. Shard ownership is a lease, it is renewed each time the owning agbot balances the shards. When an agbot balances the
+// shards, it counts the live agbots, which are the agbots that own a partition with a fresh heartbeat, and gives up or claims shards
+// so that each live agbot owns about the same number of shards. An agbot that quiesces releases its shards so that they are claimed
+// by the other agbots right away. An agbot that terminates unexpectedly stops renewing the leases on its shards, so they become
+// eligible to be claimed by another agbot after the "stale" timeout, the same as its partition.
+//
+// shards schema:
+// id:        The shard id, from 0 to the configured number of shards - 1.
+// owner:     The UUID of the agbot that owns this shard. NULL means that the shard is available to be claimed immediately.
+// heartbeat: A timestamp to record the last lease renewal.
+//
+
+const SHARD_CREATE_TABLE = `CREATE TABLE IF NOT EXISTS shards (
+	id int PRIMARY KEY,
+	owner text,
+	heartbeat timestamp with time zone
+);`
+
+// Only one agbot at a time balances the shards, the lock is held until the end of the balancing transaction. Other agbots
+// can still read the shards table.
+const SHARD_LOCK_TABLE = `LOCK TABLE shards IN SHARE ROW EXCLUSIVE MODE;`
+
+const SHARD_INSERT_MISSING = `INSERT INTO shards (id) SELECT generate_series(0, $1 - 1) ON CONFLICT (id) DO NOTHING;`
+
+const SHARD_DELETE_EXTRA = `DELETE FROM shards WHERE id >= $1;`
+
+const SHARD_RENEW = `UPDATE shards SET heartbeat = current_timestamp WHERE owner = $1;`
+
+const SHARD_COUNT_LIVE_AGBOTS = `SELECT count(DISTINCT owner) FROM partitions
+	WHERE owner IS NOT NULL AND (SELECT EXTRACT ('epoch' FROM (SELECT AGE(current_timestamp, heartbeat)))) <= $1;`
+
+const SHARD_OWNED = `SELECT id FROM shards WHERE owner = $1 ORDER BY id;`
+
+const SHARD_RELEASE_EXTRA = `UPDATE shards SET owner = NULL, heartbeat = NULL
+	WHERE id IN (SELECT id FROM shards WHERE owner = $1 ORDER BY id DESC LIMIT $2);`
+
+const SHARD_CLAIM = `UPDATE shards SET owner = $1, heartbeat = current_timestamp
+	WHERE id IN (
+		SELECT id FROM shards
+			WHERE
+				owner IS NULL
+				OR
+				(SELECT EXTRACT ('epoch' FROM (SELECT AGE(current_timestamp, heartbeat)))) > $2
+			ORDER BY id
+			LIMIT $3
+	);`
+
+const SHARD_QUERY = `SELECT id, owner, EXTRACT (EPOCH FROM heartbeat) FROM shards ORDER BY id;`
+
+const SHARD_RELEASE = `UPDATE shards SET owner = NULL, heartbeat = NULL WHERE owner = $1;`
+
+// Renew the leases on our shards and give up or claim shards so that all the live agbots own about the same number of shards.
+// This is all done in a single transaction, so the shards of an agbot that terminates in the middle of it are left as they were.
+func (db *AgbotPostgresqlDB) BalanceShards(shardCount int, timeout uint64) ([]int, error) {
+
+	tx, err := db.db.Begin()
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("unable to start transaction for balancing shards, error: %v", err))
+	}
+	defer tx.Rollback()
+
+	var live int
+	if _, err := tx.Exec(SHARD_LOCK_TABLE); err != nil {
+		return nil, errors.New(fmt.Sprintf("unable to lock shards table, error: %v", err))
+	} else if _, err := tx.Exec(SHARD_DELETE_EXTRA, shardCount); err != nil {
+		return nil, errors.New(fmt.Sprintf("unable to delete shards above %v, error: %v", shardCount, err))
+	} else if shardCount > 0 {
+		if _, err := tx.Exec(SHARD_INSERT_MISSING, shardCount); err != nil {
+			return nil, errors.New(fmt.Sprintf("unable to insert missing shards, error: %v", err))
+		}
+	}
+
+	if _, err := tx.Exec(SHARD_RENEW, db.identity); err != nil {
+		return nil, errors.New(fmt.Sprintf("AgreementBot %v unable to renew shards, error: %v", db.identity, err))
+	} else if err := tx.QueryRow(SHARD_COUNT_LIVE_AGBOTS, timeout).Scan(&live); err != nil {
+		return nil, errors.New(fmt.Sprintf("error scanning live agbot count, error: %v", err))
+	}
+
+	owned, err := db.ownedShards(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	target := persistence.ShardTarget(shardCount, live)
+	if len(owned) > target {
+		if _, err := tx.Exec(SHARD_RELEASE_EXTRA, db.identity, len(owned)-target); err != nil {
+			return nil, errors.New(fmt.Sprintf("AgreementBot %v unable to release %v shards, error: %v", db.identity, len(owned)-target, err))
+		}
+	} else if len(owned) < target {
+		if _, err := tx.Exec(SHARD_CLAIM, db.identity, timeout, target-len(owned)); err != nil {
+			return nil, errors.New(fmt.Sprintf("AgreementBot %v unable to claim %v shards, error: %v", db.identity, target-len(owned), err))
+		}
+	}
+
+	if owned, err = db.ownedShards(tx); err != nil {
+		return nil, err
+	} else if err := tx.Commit(); err != nil {
+		return nil, errors.New(fmt.Sprintf("unable to commit transaction for balancing shards, error: %v", err))
+	}
+
+	glog.V(3).Infof("AgreementBot %v owns shards %v of %v, target %v with %v live agbots", db.identity, owned, shardCount, target, live)
+	return owned, nil
+}
+
+func (db *AgbotPostgresqlDB) ownedShards(tx *sql.Tx) ([]int, error) {
+
+	rows, err := tx.Query(SHARD_OWNED, db.identity)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("error querying for shards owned by %v, error: %v", db.identity, err))
+	}
+	defer rows.Close()
+
+	owned := make([]int, 0, 10)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, errors.New(fmt.Sprintf("error scanning row for owned shard, error: %v", err))
+		}
+		owned = append(owned, id)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.New(fmt.Sprintf("error iterating owned shards, error: %v", err))
+	}
+	return owned, nil
+}
+
+// Locate all the shards currently found in the database, for all agbots.
+func (db *AgbotPostgresqlDB) FindShards() ([]persistence.Shard, error) {
+
+	rows, err := db.db.Query(SHARD_QUERY)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("error querying for shards, error: %v", err))
+	}
+	defer rows.Close()
+
+	shards := make([]persistence.Shard, 0, 10)
+	for rows.Next() {
+		var id int
+		var owner sql.NullString
+		var hb sql.NullFloat64
+		if err := rows.Scan(&id, &owner, &hb); err != nil {
+			return nil, errors.New(fmt.Sprintf("error scanning row for shard, error: %v", err))
+		}
+		shards = append(shards, persistence.Shard{Id: id, Owner: owner.String, Heartbeat: uint64(hb.Float64)})
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.New(fmt.Sprintf("error iterating shards, error: %v", err))
+	}
+	return shards, nil
+}
+
+// Release our shards so that the other agbots can claim them right away.
+func (db *AgbotPostgresqlDB) ReleaseShards() error {
+
+	if _, err := db.db.Exec(SHARD_RELEASE, db.identity); err != nil {
+		return errors.New(fmt.Sprintf("AgreementBot %v unable to release shards, error: %v", db.identity, err))
+	} else {
+		glog.V(3).Infof("AgreementBot %v released its shards", db.identity)
+	}
+	return nil
+}
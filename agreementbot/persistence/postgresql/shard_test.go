@@ -0,0 +1,26 @@
+// +build unit
+
+package postgresql
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// An agbot that took over a stale partition owns two partitions until it moved the records, it is still one live agbot.
+func Test_BalanceShards_two_partitions(t *testing.T) {
+	cfg := testConfig(t)
+	pgdb := testConnection(t, cfg)
+	defer pgdb.Close()
+
+	db := new(AgbotPostgresqlDB)
+	assert.Nil(t, db.Initialize(cfg))
+	defer db.Close()
+
+	_, err := pgdb.Exec(`INSERT INTO partitions (owner, heartbeat) VALUES ($1, current_timestamp);`, db.identity)
+	assert.Nil(t, err)
+
+	owned, err := db.BalanceShards(8, 3600)
+	assert.Nil(t, err)
+	assert.Len(t, owned, 8)
+}
@@ -1,6 +1,11 @@
 package postgresql
 
-import ()
+import (
+	"errors"
+	"fmt"
+	"github.com/golang/glog"
+	"strconv"
+)
 
 // Constants for the SQL statements that are used to work with the database version. The entire database schema has a single
 // version that is kept in the version table. Agbots automatically upgrade the database during initialization based on their version
@@ -19,6 +24,9 @@ const VERSION_CREATE_TABLE = `CREATE TABLE IF NOT EXISTS version (
 
 const VERSION_QUERY = `SELECT ver, description, updated FROM version WHERE id = 1;`
 
+// Locks the version row until the end of the transaction, so that only one agbot at a time upgrades the schema.
+const VERSION_QUERY_FOR_UPDATE = `SELECT ver FROM version WHERE id = 1 FOR UPDATE;`
+
 // There should only be 1 row in this table.
 const VERSION_INSERT = `DO $$
 BEGIN
@@ -29,12 +37,163 @@ END $$`
 
 const VERSION_UPDATE = `UPDATE version SET ver = $1, description = $2, updated = current_timestamp WHERE id = 1;`
 
-const HIGHEST_DATABASE_VERSION = v1
+// version_history schema:
+// ver:         A schema version that was applied to the database.
+// description: The description of the schema change.
+// agbot:       The UUID of the agbot that applied the schema change.
+// applied:     The time when the schema change was applied.
+//
+const VERSION_HISTORY_CREATE_TABLE = `CREATE TABLE IF NOT EXISTS version_history (
+	ver int PRIMARY KEY,
+	description text NOT NULL,
+	agbot text NOT NULL,
+	applied timestamp with time zone DEFAULT current_timestamp
+);`
+
+const VERSION_HISTORY_INSERT = `INSERT INTO version_history (ver, description, agbot) VALUES ($1, $2, $3);`
+
+const SERVER_VERSION_QUERY = `SHOW server_version_num;`
+
+const HIGHEST_DATABASE_VERSION = v6
 const v1 = 0
+const v2 = 1
+const v3 = 2
+const v4 = 3
+const v5 = 4
+const v6 = 5
 
 type SchemaUpdate struct {
-	sql         []string // The SQL statements to run for an update to the schema.
-	description string   // A description of the schema change.
+	sql              []string // The SQL statements to run for an update to the schema.
+	description      string   // A description of the schema change.
+	minServerVersion int      // The lowest Postgresql server_version_num that supports the SQL statements, 0 for any version.
+}
+
+// The schema updates, keyed by the version they bring the database to. All the statements of a version run in a single
+// transaction, so they must be safe to run while agbots of the previous version keep working with the database.
+var migrationSQL = map[int]SchemaUpdate{
+	v2: SchemaUpdate{
+		sql: []string{
+			PARTITION_LOCK_TABLES,
+			PARTITION_INHERITED_TABLE_FUNCTION,
+			`SELECT partition_inherited_table('agreements');`,
+			`SELECT partition_inherited_table('workload_usages');`,
+			`DROP FUNCTION partition_inherited_table(text);`,
+			PARTITION_CREATE_TABLES_FUNCTION,
+			PARTITION_CREATE_TABLES_TRIGGER_DROP,
+			PARTITION_CREATE_TABLES_TRIGGER,
+		},
+		description:      "native partitioning of agreements and workload usages",
+		minServerVersion: 110000,
+	},
+	v3: SchemaUpdate{
+		sql: []string{
+			AGREEMENT_CREATE_ID_INDEX,
+			AGREEMENT_CREATE_DEVICE_INDEX,
+			AGREEMENT_CREATE_POLICY_INDEX,
+			AGREEMENT_CREATE_STATE_INDEX,
+			WORKLOAD_USAGE_CREATE_DEVICE_INDEX,
+			WORKLOAD_USAGE_CREATE_POLICY_INDEX,
+		},
+		description:      "indexes on agreement id, device id, policy name and agreement state",
+		minServerVersion: 110000,
+	},
+	v4: SchemaUpdate{
+		sql: []string{
+			SHARD_CREATE_TABLE,
+		},
+		description: "node search shards",
+	},
+	v5: SchemaUpdate{
+		sql: []string{
+			PAUSED_POLICY_CREATE_TABLE,
+		},
+		description: "paused deployment policies and patterns",
+	},
+	v6: SchemaUpdate{
+		sql: []string{
+			WEBHOOK_DELIVERY_CREATE_TABLE,
+			WEBHOOK_DELIVERY_CREATE_NEXT_ATTEMPT_INDEX,
+		},
+		description: "webhook notification deliveries",
+	},
+}
+
+// Bring the database schema up to the highest version supported by this agbot. Each version is applied in its own transaction,
+// together with the update of the version row and a new row in the version history, so a version is either applied completely
+// or not at all. The version row stays locked until the transaction ends, so when several agbots start at the same time, only
+// one of them applies each version and the others find that it has already been applied.
+func (db *AgbotPostgresqlDB) migrate() error {
+
+	var dbVersion int
+	var description string
+	var timestamp string
+	if err := db.db.QueryRow(VERSION_QUERY).Scan(&dbVersion, &description, &timestamp); err != nil {
+		return errors.New(fmt.Sprintf("error scanning row for current version, error: %v", err))
+	} else {
+		glog.V(3).Infof("Postgresql database tables are at version %v, %v, as of %v.", dbVersion, description, timestamp)
+	}
+
+	if dbVersion > HIGHEST_DATABASE_VERSION {
+		// A newer agbot has already upgraded the database, which happens during a rolling upgrade of the agbots.
+		glog.Warningf("Postgresql database tables are at version %v, which is newer than version %v of this agbot.", dbVersion, HIGHEST_DATABASE_VERSION)
+		return nil
+	}
+
+	for v := dbVersion + 1; v <= HIGHEST_DATABASE_VERSION; v++ {
+		if err := db.applySchemaUpdate(v); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-var migrationSQL = map[int]SchemaUpdate{}
+func (db *AgbotPostgresqlDB) applySchemaUpdate(v int) error {
+
+	update, ok := migrationSQL[v]
+	if !ok {
+		return errors.New(fmt.Sprintf("there is no SQL migration for version %v", v))
+	}
+
+	if update.minServerVersion != 0 {
+		var serverVersion string
+		if err := db.db.QueryRow(SERVER_VERSION_QUERY).Scan(&serverVersion); err != nil {
+			return errors.New(fmt.Sprintf("unable to get the Postgresql server version, error: %v", err))
+		} else if sv, err := strconv.Atoi(serverVersion); err != nil {
+			return errors.New(fmt.Sprintf("unable to parse the Postgresql server version %v, error: %v", serverVersion, err))
+		} else if sv < update.minServerVersion {
+			return errors.New(fmt.Sprintf("SQL migration version %v, %v, requires Postgresql server version %v or later, the server version is %v", v, update.description, update.minServerVersion, sv))
+		}
+	}
+
+	tx, err := db.db.Begin()
+	if err != nil {
+		return errors.New(fmt.Sprintf("unable to start transaction for SQL migration version %v, error: %v", v, err))
+	}
+	defer tx.Rollback()
+
+	var current int
+	if err := tx.QueryRow(VERSION_QUERY_FOR_UPDATE).Scan(&current); err != nil {
+		return errors.New(fmt.Sprintf("error scanning row for current version, error: %v", err))
+	} else if current >= v {
+		glog.V(3).Infof("Postgresql database tables were already upgraded to version %v by another agbot.", current)
+		return tx.Commit()
+	}
+
+	// Run each SQL statement in the array of SQL statements for the current verion.
+	for si, stmt := range update.sql {
+		if _, err := tx.Exec(stmt); err != nil {
+			return errors.New(fmt.Sprintf("unable to run SQL migration statement version %v, index %v, statement %v, error: %v", v, si, stmt, err))
+		}
+	}
+
+	if _, err := tx.Exec(VERSION_UPDATE, v, update.description); err != nil {
+		return errors.New(fmt.Sprintf("unable to update version to %v, error: %v", v, err))
+	} else if _, err := tx.Exec(VERSION_HISTORY_INSERT, v, update.description, db.identity); err != nil {
+		return errors.New(fmt.Sprintf("unable to record version %v in the version history, error: %v", v, err))
+	} else if err := tx.Commit(); err != nil {
+		return errors.New(fmt.Sprintf("unable to commit SQL migration version %v, error: %v", v, err))
+	}
+
+	glog.V(3).Infof("Postgresql database tables upgraded to version %v, %v", v, update.description)
+	return nil
+}
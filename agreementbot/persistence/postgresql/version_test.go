@@ -0,0 +1,92 @@
+// +build unit
+
+package postgresql
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/open-horizon/anax/agreementbot/persistence"
+	"github.com/open-horizon/anax/policy"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+// The partition tables of a version 1 database, which inherit from the main tables.
+const v1_AGREEMENT_PARTITION_TABLE = `CREATE TABLE "agreements_%[1]v" (CHECK ( partition = '%[1]v' )) INHERITS (agreements);`
+const v1_AGREEMENT_PARTITION_INDEX = `CREATE INDEX "agreement_id_index_on_agreements_%[1]v" ON "agreements_%[1]v" (agreement_id);`
+const v1_WORKLOAD_USAGE_PARTITION_TABLE = `CREATE TABLE "workload_usages_%[1]v" (CHECK ( partition = '%[1]v' )) INHERITS (workload_usages);`
+const v1_WORKLOAD_USAGE_PARTITION_INDEX = `CREATE INDEX "device_index_on_workload_usages_%[1]v" ON "workload_usages_%[1]v" (device_id, policy_name);`
+
+func Test_migrationSQL(t *testing.T) {
+	// every version after the initial tables has a migration, so that a database at any version can be brought up to date
+	assert.Len(t, migrationSQL, HIGHEST_DATABASE_VERSION-v1)
+	for v := v1 + 1; v <= HIGHEST_DATABASE_VERSION; v++ {
+		update, ok := migrationSQL[v]
+		assert.True(t, ok, "version %v", v)
+		assert.NotEmpty(t, update.description, "version %v", v)
+		assert.NotEmpty(t, update.sql, "version %v", v)
+	}
+}
+
+func Test_partitionTableCreate(t *testing.T) {
+	db := &AgbotPostgresqlDB{primaryPartition: "5"}
+	assert.Equal(t, `CREATE TABLE IF NOT EXISTS "agreements_5" PARTITION OF agreements FOR VALUES IN ('5');`, db.GetPrimaryAgreementPartitionTableCreate())
+	assert.Equal(t, `CREATE TABLE IF NOT EXISTS "workload_usages_5" PARTITION OF workload_usages FOR VALUES IN ('5');`, db.GetPrimaryWorkloadUsagePartitionTableCreate())
+	assert.True(t, strings.HasSuffix(db.GetAgreementPartitionTableCount("5"), `FROM "agreements_5";`))
+}
+
+// Upgrade a version 1 database that has rows in its inherited partition tables. The agbot that claims the partition
+// after the upgrade finds the rows.
+func Test_migrate_v1(t *testing.T) {
+	cfg := testConfig(t)
+	pgdb := testConnection(t, cfg)
+	defer pgdb.Close()
+
+	// Create the tables of a version 1 database with an unowned partition.
+	for _, stmt := range []string{VERSION_CREATE_TABLE, VERSION_INSERT, PARTITION_CREATE_MAIN_TABLE, AGREEMENT_CREATE_MAIN_TABLE, WORKLOAD_USAGE_CREATE_MAIN_TABLE} {
+		_, err := pgdb.Exec(stmt)
+		assert.Nil(t, err, stmt)
+	}
+	var partition string
+	assert.Nil(t, pgdb.QueryRow(`INSERT INTO partitions (owner, heartbeat) VALUES (NULL, NULL) RETURNING id;`).Scan(&partition))
+	for _, stmt := range []string{v1_AGREEMENT_PARTITION_TABLE, v1_AGREEMENT_PARTITION_INDEX, v1_WORKLOAD_USAGE_PARTITION_TABLE, v1_WORKLOAD_USAGE_PARTITION_INDEX} {
+		_, err := pgdb.Exec(fmt.Sprintf(stmt, partition))
+		assert.Nil(t, err, stmt)
+	}
+
+	ag, err := json.Marshal(persistence.Agreement{CurrentAgreementId: "ag1", AgreementProtocol: policy.BasicProtocol, DeviceId: "myorg/dev1", PolicyName: "myorg/pol1"})
+	assert.Nil(t, err)
+	_, err = pgdb.Exec(fmt.Sprintf(`INSERT INTO "agreements_%v" (agreement_id, protocol, partition, agreement) VALUES ($1, $2, $3, $4);`, partition), "ag1", policy.BasicProtocol, partition, ag)
+	assert.Nil(t, err)
+	wu, err := json.Marshal(persistence.WorkloadUsage{DeviceId: "myorg/dev1", PolicyName: "myorg/pol1", CurrentAgreementId: "ag1"})
+	assert.Nil(t, err)
+	_, err = pgdb.Exec(fmt.Sprintf(`INSERT INTO "workload_usages_%v" (device_id, policy_name, partition, workload_usage) VALUES ($1, $2, $3, $4);`, partition), "myorg/dev1", "myorg/pol1", partition, wu)
+	assert.Nil(t, err)
+
+	db := new(AgbotPostgresqlDB)
+	assert.Nil(t, db.Initialize(cfg))
+	defer db.Close()
+
+	var ver int
+	var relkind string
+	assert.Nil(t, pgdb.QueryRow(`SELECT ver FROM version WHERE id = 1;`).Scan(&ver))
+	assert.Equal(t, HIGHEST_DATABASE_VERSION, ver)
+	assert.Nil(t, pgdb.QueryRow(`SELECT relkind FROM pg_class WHERE relname = 'agreements';`).Scan(&relkind))
+	assert.Equal(t, "p", relkind, "agreements should be a partitioned table")
+
+	assert.Equal(t, partition, db.PrimaryPartition())
+	if ag, err := db.FindSingleAgreementByAgreementId("ag1", policy.BasicProtocol, []persistence.AFilter{}); assert.Nil(t, err) && assert.NotNil(t, ag) {
+		assert.Equal(t, "myorg/dev1", ag.DeviceId)
+	}
+	if wu, err := db.FindSingleWorkloadUsageByDeviceAndPolicyName("myorg/dev1", "myorg/pol1"); assert.Nil(t, err) && assert.NotNil(t, wu) {
+		assert.Equal(t, "ag1", wu.CurrentAgreementId)
+	}
+
+	// the rows were attached with their partition tables, not copied
+	var agreements, workloadUsages int
+	assert.Nil(t, pgdb.QueryRow(`SELECT count(*) FROM agreements;`).Scan(&agreements))
+	assert.Nil(t, pgdb.QueryRow(`SELECT count(*) FROM workload_usages;`).Scan(&workloadUsages))
+	assert.Equal(t, 1, agreements)
+	assert.Equal(t, 1, workloadUsages)
+}
@@ -0,0 +1,127 @@
+package postgresql
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/agreementbot/persistence"
+)
+
+// Constants for the SQL statements that are used to queue webhook notifications until they are delivered. The table is
+// shared by all the agbots, so a delivery that was queued by an agbot that stopped is delivered by another agbot.
+//
+// webhook_deliveries schema:
+// id:           The unique id of the delivery.
+// url:          The URL of the webhook endpoint.
+// event:        The type of the notified event.
+// payload:      The JSON payload that is posted to the endpoint.
+// attempts:     The number of failed delivery attempts.
+// next_attempt: The time of the next delivery attempt. While an agbot is delivering it, it is the end of the agbot's lease.
+// last_error:   The error of the last failed delivery attempt.
+// created_at:   The time the delivery was queued.
+//
+
+const WEBHOOK_DELIVERY_CREATE_TABLE = `CREATE TABLE IF NOT EXISTS webhook_deliveries (
+	id text PRIMARY KEY,
+	url text NOT NULL,
+	event text NOT NULL,
+	payload text NOT NULL,
+	attempts int NOT NULL DEFAULT 0,
+	next_attempt timestamp with time zone NOT NULL,
+	last_error text NOT NULL DEFAULT '',
+	created_at timestamp with time zone NOT NULL
+);`
+
+const WEBHOOK_DELIVERY_CREATE_NEXT_ATTEMPT_INDEX = `CREATE INDEX IF NOT EXISTS webhook_deliveries_next_attempt ON webhook_deliveries (next_attempt);`
+
+const WEBHOOK_DELIVERY_UPSERT = `INSERT INTO webhook_deliveries (id, url, event, payload, attempts, next_attempt, last_error, created_at)
+	VALUES ($1, $2, $3, $4, $5, to_timestamp($6), $7, to_timestamp($8))
+	ON CONFLICT (id) DO UPDATE SET url = EXCLUDED.url, event = EXCLUDED.event, payload = EXCLUDED.payload, attempts = EXCLUDED.attempts,
+	next_attempt = EXCLUDED.next_attempt, last_error = EXCLUDED.last_error, created_at = EXCLUDED.created_at;`
+
+// The due deliveries are locked while they are claimed, the ones that another agbot is claiming at the same time are skipped.
+// The deliveries are returned as they were before they were claimed.
+const WEBHOOK_DELIVERY_CLAIM = `WITH due AS (
+	SELECT id, url, event, payload, attempts, next_attempt, last_error, created_at FROM webhook_deliveries
+	WHERE next_attempt <= to_timestamp($1) ORDER BY created_at, id LIMIT $3 FOR UPDATE SKIP LOCKED
+)
+UPDATE webhook_deliveries SET next_attempt = to_timestamp($2) FROM due WHERE webhook_deliveries.id = due.id
+RETURNING due.id, due.url, due.event, due.payload, due.attempts, EXTRACT (EPOCH FROM due.next_attempt), due.last_error, EXTRACT (EPOCH FROM due.created_at);`
+
+const WEBHOOK_DELIVERY_RESCHEDULE = `UPDATE webhook_deliveries SET attempts = $2, next_attempt = to_timestamp($3), last_error = $4 WHERE id = $1;`
+
+const WEBHOOK_DELIVERY_DELETE = `DELETE FROM webhook_deliveries WHERE id = $1;`
+
+const WEBHOOK_DELIVERY_QUERY = `SELECT id, url, event, payload, attempts, EXTRACT (EPOCH FROM next_attempt), last_error, EXTRACT (EPOCH FROM created_at)
+	FROM webhook_deliveries ORDER BY created_at, id;`
+
+func (db *AgbotPostgresqlDB) QueueWebhookDelivery(d *persistence.WebhookDelivery) error {
+
+	if _, err := db.db.Exec(WEBHOOK_DELIVERY_UPSERT, d.Id, d.URL, d.Event, d.Payload, d.Attempts, d.NextAttempt, d.LastError, d.CreatedAt); err != nil {
+		return errors.New(fmt.Sprintf("unable to queue webhook delivery %v, error: %v", d.Id, err))
+	}
+	glog.V(5).Infof("AgreementBot %v queued webhook delivery %v", db.identity, d)
+	return nil
+}
+
+func (db *AgbotPostgresqlDB) ClaimWebhookDeliveries(now uint64, leaseS uint64, limit int) ([]persistence.WebhookDelivery, error) {
+
+	rows, err := db.db.Query(WEBHOOK_DELIVERY_CLAIM, now, now+leaseS, limit)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("error claiming webhook deliveries, error: %v", err))
+	}
+	deliveries, err := scanWebhookDeliveries(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	// The returned rows are in no particular order.
+	persistence.SortWebhookDeliveries(deliveries)
+	return deliveries, nil
+}
+
+func (db *AgbotPostgresqlDB) RescheduleWebhookDelivery(id string, attempts int, nextAttempt uint64, lastError string) error {
+
+	if _, err := db.db.Exec(WEBHOOK_DELIVERY_RESCHEDULE, id, attempts, nextAttempt, lastError); err != nil {
+		return errors.New(fmt.Sprintf("unable to reschedule webhook delivery %v, error: %v", id, err))
+	}
+	return nil
+}
+
+func (db *AgbotPostgresqlDB) DeleteWebhookDelivery(id string) error {
+
+	if _, err := db.db.Exec(WEBHOOK_DELIVERY_DELETE, id); err != nil {
+		return errors.New(fmt.Sprintf("unable to delete webhook delivery %v, error: %v", id, err))
+	}
+	return nil
+}
+
+func (db *AgbotPostgresqlDB) FindWebhookDeliveries() ([]persistence.WebhookDelivery, error) {
+
+	rows, err := db.db.Query(WEBHOOK_DELIVERY_QUERY)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("error querying for webhook deliveries, error: %v", err))
+	}
+	return scanWebhookDeliveries(rows)
+}
+
+func scanWebhookDeliveries(rows *sql.Rows) ([]persistence.WebhookDelivery, error) {
+	defer rows.Close()
+
+	deliveries := make([]persistence.WebhookDelivery, 0, 10)
+	for rows.Next() {
+		var d persistence.WebhookDelivery
+		var nextAttempt, createdAt sql.NullFloat64
+		if err := rows.Scan(&d.Id, &d.URL, &d.Event, &d.Payload, &d.Attempts, &nextAttempt, &d.LastError, &createdAt); err != nil {
+			return nil, errors.New(fmt.Sprintf("error scanning row for webhook delivery, error: %v", err))
+		}
+		d.NextAttempt = uint64(nextAttempt.Float64)
+		d.CreatedAt = uint64(createdAt.Float64)
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.New(fmt.Sprintf("error iterating webhook deliveries, error: %v", err))
+	}
+	return deliveries, nil
+}
@@ -12,13 +12,11 @@ import (
 
 // Constants for the SQL statements that are used to work with workload usages. These records are used to track what workload
 // is running on each device so that we can do proper management of HA devices. Workload usages are partitioned by agbot instances.
-// Each agbot instance "owns" 1 partition in the database. We are implementing our own partitioning scheme. There is a
-// "main" table (called workload_usages) that defines the schema for all the partitions, and then there is a
-// set of separate tables (called workload_usages_<partition_name>, one for each partition) which inherit from the main table and
-// that we have to create. When an agbot comes up it will attempt to create the main table and a partition table for its primary
-// partition. Our code will correctly route INSERTs/UPDATEs and SELECTs to the correct partition based on the partition value
-// provided in the SQL statement. Postgresql allows you to query the main table for cases where the caller might not know the
-// partition holding the record of interest. We exploit both forms of query.
+// Each agbot instance "owns" 1 partition in the database. The "main" table (called workload_usages) is partitioned by the partition
+// column, and there is a separate table (called workload_usages_<partition_name>) for each partition. When an agbot comes up it will
+// attempt to create the partition table for its primary partition. Our code will correctly route INSERTs/UPDATEs and SELECTs to the
+// correct partition based on the partition value provided in the SQL statement. Postgresql allows you to query the main table for
+// cases where the caller might not know the partition holding the record of interest. We exploit both forms of query.
 //
 
 // workload_usages schema:
@@ -29,45 +27,19 @@ import (
 // updated:        A timestamp to record last updated time.
 //
 
-/* When we migrate to postgresql 10, we can use these constant definitions because they will allow us to use
-   the built in partitioning support.
+// The main table is created as it was in database version v1. The v2 schema migration turns it into a partitioned table.
 const WORKLOAD_USAGE_CREATE_MAIN_TABLE = `CREATE TABLE IF NOT EXISTS workload_usages (
 	device_id text NOT NULL,
 	policy_name text NOT NULL,
 	partition text NOT NULL,
 	workload_usage jsonb NOT NULL,
 	updated timestamp with time zone DEFAULT current_timestamp
-) PARTITION BY LIST (partition);`
-
+);`
 const WORKLOAD_USAGE_CREATE_PARTITION_TABLE = `CREATE TABLE IF NOT EXISTS "workload_usages_ PARTITION OF workload_usages FOR VALUES IN ('partition_name');`
-const WORKLOAD_USAGE_TABLE_NAME_ROOT = `"workload_usages_`
-const WORKLOAD_USAGE_PARTITION_FILLIN = `partition_name`
-
-const WORKLOAD_USAGE_QUERY = `SELECT workload_usage FROM workload_usages WHERE device_id = $1 AND policy_name = $2 AND partition = $3;`
-const ALL_WORKLOAD_USAGE_QUERY = `SELECT workload_usage FROM workload_usages WHERE partition = $1;`
-
-const WORKLOAD_USAGE_PARTITIONS = `SELECT partition FROM workload_usages;`
-
-const WORKLOAD_USAGE_COUNT = `SELECT COUNT(*) FROM "workload_usages_;`
-
-const WORKLOAD_USAGE_INSERT = `INSERT INTO workload_usages (device_id, policy_name, partition, workload_usage) VALUES ($1, $2, $3, $4);`
-const WORKLOAD_USAGE_UPDATE = `UPDATE workload_usages SET workload_usage = $4, updated = current_timestamp WHERE device_id = $1 AND policy_name = $2 AND partition = $3;`
-const WORKLOAD_USAGE_DELETE = `DELETE FROM workload_usages WHERE device_id = $1 AND policy_name = $2 AND partition = $3;`
-
-const WORKLOAD_USAGE_DROP_PARTITION = `DROP TABLE "workload_usages_;`
-*/
 
-const WORKLOAD_USAGE_CREATE_MAIN_TABLE = `CREATE TABLE IF NOT EXISTS workload_usages (
-	device_id text NOT NULL,
-	policy_name text NOT NULL,
-	partition text NOT NULL,
-	workload_usage jsonb NOT NULL,
-	updated timestamp with time zone DEFAULT current_timestamp
-);`
-const WORKLOAD_USAGE_CREATE_PARTITION_TABLE = `CREATE TABLE IF NOT EXISTS "workload_usages_ (
-	CHECK ( partition = 'partition_name' )
-) INHERITS (workload_usages);`
-const WORKLOAD_USAGE_CREATE_PARTITION_INDEX = `CREATE INDEX IF NOT EXISTS "device_index_on_workload_usages_ ON "workload_usages_ (device_id, policy_name);`
+// The indexes are created on the main table by the v3 schema migration, Postgresql creates them on each partition table.
+const WORKLOAD_USAGE_CREATE_DEVICE_INDEX = `CREATE INDEX IF NOT EXISTS device_index_on_workload_usages ON workload_usages (device_id, policy_name);`
+const WORKLOAD_USAGE_CREATE_POLICY_INDEX = `CREATE INDEX IF NOT EXISTS policy_index_on_workload_usages ON workload_usages (policy_name);`
 
 // Please note that the following SQL statement has a different syntax where the table name is specified. Note the use of
 // single quotes instead of double quotes that are used in all the other SQL. Don't ya just love SQL syntax consistency.
@@ -104,11 +76,6 @@ func (db *AgbotPostgresqlDB) GetPrimaryWorkloadUsagePartitionTableCreate() strin
 	return sql
 }
 
-func (db *AgbotPostgresqlDB) GetPrimaryWorkloadUsagePartitionTableIndexCreate() string {
-	sql := strings.Replace(WORKLOAD_USAGE_CREATE_PARTITION_INDEX, WORKLOAD_USAGE_TABLE_NAME_ROOT, db.GetWorkloadUsagePartitionTableName(db.PrimaryPartition()), 2)
-	return sql
-}
-
 func (db *AgbotPostgresqlDB) GetWorkloadUsagePartitionTableDrop(partition string) string {
 	sql := strings.Replace(WORKLOAD_USAGE_DROP_PARTITION, WORKLOAD_USAGE_TABLE_NAME_ROOT, db.GetWorkloadUsagePartitionTableName(partition), 1)
 	return sql
@@ -146,11 +113,25 @@ func (db *AgbotPostgresqlDB) GetWorkloadUsagesCount(partition string) (int64, er
 
 // Find the workload usage record, but constrain the search to partitions owned by this agbot.
 func (db *AgbotPostgresqlDB) internalFindSingleWorkloadUsageByDeviceAndPolicyName(tx *sql.Tx, deviceid string, policyName string) (*persistence.WorkloadUsage, string, error) {
+	return db.findSingleWorkloadUsageInPartitions(tx, db.AllPartitions(), deviceid, policyName)
+}
+
+// Find the workload usage record in the partitions of all the agbots, not only in the partitions owned by this agbot.
+func (db *AgbotPostgresqlDB) FindWorkloadUsageInAllPartitions(deviceid string, policyName string) (*persistence.WorkloadUsage, error) {
+	if partitions, err := db.FindPartitions(); err != nil {
+		return nil, err
+	} else {
+		wu, _, err := db.findSingleWorkloadUsageInPartitions(nil, partitions, deviceid, policyName)
+		return wu, err
+	}
+}
+
+func (db *AgbotPostgresqlDB) findSingleWorkloadUsageInPartitions(tx *sql.Tx, partitions []string, deviceid string, policyName string) (*persistence.WorkloadUsage, string, error) {
 
 	wuBytes := make([]byte, 0, 2048)
 	wu := new(persistence.WorkloadUsage)
 
-	for _, currentPartition := range db.AllPartitions() {
+	for _, currentPartition := range partitions {
 
 		// Find the workload usage row and read in the workload usage object column, then unmarshal the blob into an
 		// in memory workload usage object which gets returned to the caller.
@@ -244,6 +225,15 @@ func (db *AgbotPostgresqlDB) NewWorkloadUsage(deviceId string, hapartners []stri
 	}
 }
 
+func (db *AgbotPostgresqlDB) ImportWorkloadUsage(wu *persistence.WorkloadUsage) error {
+	if existing, partition, err := db.internalFindSingleWorkloadUsageByDeviceAndPolicyName(nil, wu.DeviceId, wu.PolicyName); err != nil {
+		return err
+	} else if existing != nil {
+		return fmt.Errorf("Workload usage record for device %v and policy name %v already exists in partition %v.", wu.DeviceId, wu.PolicyName, partition)
+	}
+	return db.insertWorkloadUsage(nil, wu)
+}
+
 func (db *AgbotPostgresqlDB) UpdatePendingUpgrade(deviceid string, policyName string) (*persistence.WorkloadUsage, error) {
 	return persistence.UpdatePendingUpgrade(db, deviceid, policyName)
 }
@@ -306,6 +296,10 @@ func (db *AgbotPostgresqlDB) DisableRollbackChecking(deviceid string, policyName
 }
 
 func (db *AgbotPostgresqlDB) DeleteWorkloadUsage(deviceid string, policyName string) error {
+	if deviceid == "" || policyName == "" {
+		return fmt.Errorf("Missing required arg deviceid or policyName")
+	}
+
 	tx, err := db.db.Begin()
 	if err != nil {
 		return err
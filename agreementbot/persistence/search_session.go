@@ -0,0 +1,18 @@
+package persistence
+
+import (
+	"fmt"
+)
+
+// The state of the node searches of a policy, as it is kept by the database. The bolt database has one search session
+// for all the policies, its policy name is empty.
+type SearchSession struct {
+	PolicyName   string `json:"policy_name"`
+	ChangedSince uint64 `json:"changed_since"`
+	SessionToken uint64 `json:"session_token"`
+	SessionEnded bool   `json:"session_ended"`
+}
+
+func (s SearchSession) String() string {
+	return fmt.Sprintf("PolicyName: %v, ChangedSince: %v, SessionToken: %v, SessionEnded: %v", s.PolicyName, s.ChangedSince, s.SessionToken, s.SessionEnded)
+}
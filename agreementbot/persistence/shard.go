@@ -0,0 +1,39 @@
+package persistence
+
+import (
+	"fmt"
+	"hash/fnv"
+)
+
+// Clustered agbots split the deployment policies and patterns they serve into a fixed number of shards, and each agbot
+// searches the exchange for nodes only for the policies of the shards it owns. Ownership of a shard is a lease that is
+// renewed by the owning agbot, like the heartbeat of a partition. The bolt database has only 1 agbot, which owns all
+// the shards.
+type Shard struct {
+	Id        int    `json:"id"`
+	Owner     string `json:"owner"`     // The owning agbot, empty when the shard is not owned.
+	Heartbeat uint64 `json:"heartbeat"` // The time of the last lease renewal, in seconds since the epoch.
+}
+
+func (s Shard) String() string {
+	return fmt.Sprintf("Id: %v, Owner: %v, Heartbeat: %v", s.Id, s.Owner, s.Heartbeat)
+}
+
+// Return the shard of a deployment policy or pattern, given its fully qualified policy name. Every agbot has to get the
+// same answer, so the hash of the name is used.
+func ShardOf(policyName string, shardCount int) int {
+	if shardCount <= 1 {
+		return 0
+	}
+	h := fnv.New32a()
+	h.Write([]byte(policyName))
+	return int(h.Sum32() % uint32(shardCount))
+}
+
+// The number of shards that each of the live agbots should own, so that together they own all the shards.
+func ShardTarget(shardCount int, liveAgbots int) int {
+	if liveAgbots < 1 {
+		liveAgbots = 1
+	}
+	return (shardCount + liveAgbots - 1) / liveAgbots
+}
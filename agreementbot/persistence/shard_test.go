@@ -0,0 +1,32 @@
+// +build unit
+
+package persistence
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_ShardOf(t *testing.T) {
+	// Every agbot has to put a policy in the same shard.
+	assert.Equal(t, ShardOf("myorg/pol1", 32), ShardOf("myorg/pol1", 32))
+	assert.Equal(t, 0, ShardOf("myorg/pol1", 1))
+	assert.Equal(t, 0, ShardOf("myorg/pol1", 0))
+
+	// The policies are spread over the shards.
+	used := make(map[int]bool)
+	for _, name := range []string{"myorg/pol1", "myorg/pol2", "myorg/pol3", "myorg/pol4", "myorg/pat1", "otherorg/pol1", "otherorg/pat1", "otherorg/pat2"} {
+		shard := ShardOf(name, 4)
+		assert.True(t, shard >= 0 && shard < 4, "shard %v of %v", shard, name)
+		used[shard] = true
+	}
+	assert.True(t, len(used) > 1)
+}
+
+func Test_ShardTarget(t *testing.T) {
+	assert.Equal(t, 32, ShardTarget(32, 0))
+	assert.Equal(t, 32, ShardTarget(32, 1))
+	assert.Equal(t, 11, ShardTarget(32, 3))
+	assert.Equal(t, 1, ShardTarget(4, 5))
+	assert.Equal(t, 0, ShardTarget(0, 2))
+}
@@ -0,0 +1,33 @@
+package persistence
+
+import (
+	"fmt"
+	"sort"
+)
+
+// A webhook notification that is waiting to be delivered to one webhook endpoint. Deliveries are kept in the agbot database
+// until they are delivered or have used up their retries, so that notifications survive a restart of the agbot.
+type WebhookDelivery struct {
+	Id          string `json:"id"`           // A unique id of the delivery.
+	URL         string `json:"url"`          // The URL of the webhook endpoint.
+	Event       string `json:"event"`        // The type of the notified event.
+	Payload     string `json:"payload"`      // The JSON payload that is posted to the endpoint.
+	Attempts    int    `json:"attempts"`     // The number of failed delivery attempts.
+	NextAttempt uint64 `json:"next_attempt"` // The time of the next delivery attempt, in seconds since the epoch.
+	LastError   string `json:"last_error"`   // The error of the last failed delivery attempt.
+	CreatedAt   uint64 `json:"created_at"`   // The time the delivery was queued, in seconds since the epoch.
+}
+
+func (d WebhookDelivery) String() string {
+	return fmt.Sprintf("Id: %v, URL: %v, Event: %v, Attempts: %v, NextAttempt: %v, LastError: %v, CreatedAt: %v", d.Id, d.URL, d.Event, d.Attempts, d.NextAttempt, d.LastError, d.CreatedAt)
+}
+
+// Sort webhook deliveries oldest first, which is the order they are delivered in.
+func SortWebhookDeliveries(deliveries []WebhookDelivery) {
+	sort.Slice(deliveries, func(i, j int) bool {
+		if deliveries[i].CreatedAt != deliveries[j].CreatedAt {
+			return deliveries[i].CreatedAt < deliveries[j].CreatedAt
+		}
+		return deliveries[i].Id < deliveries[j].Id
+	})
+}
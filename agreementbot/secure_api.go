@@ -157,6 +157,13 @@ func (a *SecureAPI) listen() {
 		router.HandleFunc("/deploycheck/userinputcompatible", a.userinput_compatible).Methods("GET", "OPTIONS")
 		router.HandleFunc("/deploycheck/deploycompatible", a.deploy_compatible).Methods("GET", "OPTIONS")
 
+		// The admin APIs take the credentials of an exchange admin user, so they are only served over TLS.
+		if bSecure {
+			a.addAdminRoutes(router)
+		} else {
+			glog.Warningf(APIlogString("The admin APIs are not available, they require the secure API server cert file and key file."))
+		}
+
 		apiListen := fmt.Sprintf("%v:%v", apiListenHost, apiListenPort)
 
 		var err error
@@ -464,8 +471,18 @@ func (a *SecureAPI) authenticateWithExchange(user string, userPasswd string, msg
 	}
 
 	user_ec := a.createUserExchangeContext(user, userPasswd)
+	if _, err := verifyExchangeUser(a.httpClient, user_ec, msgPrinter); err != nil {
+		return nil, err
+	}
+	return user_ec, nil
+}
+
+// Invoke the exchange API to verify the credentials of the user in the exchange context, and return the user as it is
+// defined in the exchange.
+func verifyExchangeUser(httpClient *http.Client, user_ec exchange.ExchangeContext, msgPrinter *message.Printer) (*exchange.UserDefinition, error) {
+	user := user_ec.GetExchangeId()
+	orgId, userId := cutil.SplitOrgSpecUrl(user)
 
-	// Invoke the exchange API to verify the user.
 	retryCount := user_ec.GetHTTPFactory().RetryCount
 	retryInterval := user_ec.GetHTTPFactory().GetRetryInterval()
 	for {
@@ -475,7 +492,7 @@ func (a *SecureAPI) authenticateWithExchange(user string, userPasswd string, msg
 		resp = new(exchange.GetUsersResponse)
 		targetURL := fmt.Sprintf("%vorgs/%v/users/%v", user_ec.GetExchangeURL(), orgId, userId)
 
-		if err, tpErr := exchange.InvokeExchange(a.httpClient, "GET", targetURL, user, userPasswd, nil, &resp); err != nil {
+		if err, tpErr := exchange.InvokeExchange(httpClient, "GET", targetURL, user, user_ec.GetExchangeToken(), nil, &resp); err != nil {
 			glog.Errorf(APIlogString(err.Error()))
 
 			if strings.Contains(err.Error(), "401") {
@@ -492,7 +509,17 @@ func (a *SecureAPI) authenticateWithExchange(user string, userPasswd string, msg
 			time.Sleep(time.Duration(retryInterval) * time.Second)
 			continue
 		} else {
-			return user_ec, nil
+			// The user is keyed by its fully qualified name, unless an api key was used. Then the only user in the
+			// response is the owner of the api key.
+			users := resp.(*exchange.GetUsersResponse).Users
+			if ud, ok := users[user]; ok {
+				return &ud, nil
+			} else if len(users) == 1 {
+				for _, ud := range users {
+					return &ud, nil
+				}
+			}
+			return nil, fmt.Errorf(msgPrinter.Sprintf("User %v is not found in the Exchange.", user))
 		}
 	}
 }
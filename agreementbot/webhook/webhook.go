@@ -0,0 +1,306 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/agreementbot/persistence"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/cutil"
+	"github.com/satori/go.uuid"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// The http headers of a webhook notification.
+const (
+	HEADER_EVENT     = "X-Horizon-Event"     // the type of the event
+	HEADER_DELIVERY  = "X-Horizon-Delivery"  // the id of the delivery, which is the same for every attempt to deliver it
+	HEADER_SIGNATURE = "X-Horizon-Signature" // sha256=<hex HMAC-SHA256 of the payload>, when the endpoint has a secret
+)
+
+// The types of the policies that events are about. They are the same as the types of paused policies.
+const (
+	POLICY_TYPE_DEPLOYMENT_POLICY = persistence.PAUSED_DEPLOYMENT_POLICY
+	POLICY_TYPE_PATTERN           = persistence.PAUSED_PATTERN
+)
+
+// The maximum number of notifications that are claimed at once for delivery.
+const deliveryBatchSize = 50
+
+// An agbot event, as it is posted to the webhook endpoints.
+type Event struct {
+	Id          string                 `json:"id"`                     // A unique id of the event.
+	Type        string                 `json:"event"`                  // The type of the event, one of the config.WebhookEvent constants.
+	Time        uint64                 `json:"time"`                   // The time of the event, in seconds since the epoch.
+	Agbot       string                 `json:"agbot"`                  // The agbot that observed the event.
+	PolicyType  string                 `json:"policy_type"`            // Either deploymentpol or pattern.
+	Policy      string                 `json:"policy"`                 // The fully qualified name of the deployment policy or pattern.
+	Node        string                 `json:"node,omitempty"`         // The fully qualified id of the node.
+	AgreementId string                 `json:"agreement_id,omitempty"` // The agreement that the event is about.
+	Details     map[string]interface{} `json:"details,omitempty"`      // Additional information that depends on the type of the event.
+}
+
+func (e Event) String() string {
+	return fmt.Sprintf("Id: %v, Type: %v, PolicyType: %v, Policy: %v, Node: %v, AgreementId: %v, Details: %v", e.Id, e.Type, e.PolicyType, e.Policy, e.Node, e.AgreementId, e.Details)
+}
+
+// Create an event about an agreement. The policy of the event is the pattern of the agreement, or its deployment policy
+// when it was not made from a pattern.
+func NewAgreementEvent(eventType string, ag *persistence.Agreement, details map[string]interface{}) *Event {
+	e := &Event{
+		Type:        eventType,
+		PolicyType:  POLICY_TYPE_DEPLOYMENT_POLICY,
+		Policy:      ag.PolicyName,
+		Node:        ag.DeviceId,
+		AgreementId: ag.CurrentAgreementId,
+		Details:     details,
+	}
+	if ag.Pattern != "" {
+		e.PolicyType = POLICY_TYPE_PATTERN
+		e.Policy = ag.Pattern
+	}
+	return e
+}
+
+// Returns true if the event passes the filters of the endpoint.
+func (e *Event) Matches(ep *config.WebhookEndpoint) bool {
+	contains := func(list []string, values ...string) bool {
+		if len(list) == 0 {
+			return true
+		}
+		for _, l := range list {
+			for _, v := range values {
+				if l == v {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	policyOrg, _ := cutil.SplitOrgSpecUrl(e.Policy)
+	nodeOrg, _ := cutil.SplitOrgSpecUrl(e.Node)
+	return contains(ep.Events, e.Type) && contains(ep.Orgs, policyOrg, nodeOrg) && contains(ep.Policies, e.Policy)
+}
+
+// Returns the signature of the payload for the X-Horizon-Signature header.
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// The notifier queues the agbot events for the webhook endpoints whose filters they pass, and delivers them. The queue
+// is kept in the agbot database. When several agbots share a database, any of them can deliver a notification. The
+// methods of a nil notifier do nothing, so callers do not have to check whether webhooks are configured.
+type Notifier struct {
+	config     *config.WebhookConfig
+	db         persistence.AgbotDatabase
+	agbotId    string
+	httpClient *http.Client
+	lock       sync.Mutex
+	failures   map[string]int // the number of agreements in a row that failed, by node and policy
+}
+
+// Returns a notifier for the webhook config, or nil if there are no webhook endpoints.
+func NewNotifier(cfg *config.WebhookConfig, db persistence.AgbotDatabase, agbotId string, httpClient *http.Client) *Notifier {
+	if !cfg.IsEnabled() {
+		return nil
+	}
+	return &Notifier{
+		config:     cfg,
+		db:         db,
+		agbotId:    agbotId,
+		httpClient: httpClient,
+		failures:   make(map[string]int),
+	}
+}
+
+// Queue the event for every endpoint whose filters it passes.
+func (n *Notifier) Notify(e *Event) {
+	if n == nil {
+		return
+	}
+
+	id, err := uuid.NewV4()
+	if err != nil {
+		glog.Errorf(whlogString(fmt.Sprintf("unable to generate an id for event %v, error: %v", e, err)))
+		return
+	}
+	e.Id = id.String()
+	e.Time = uint64(time.Now().Unix())
+	e.Agbot = n.agbotId
+
+	payload, err := json.Marshal(e)
+	if err != nil {
+		glog.Errorf(whlogString(fmt.Sprintf("unable to marshal event %v, error: %v", e, err)))
+		return
+	}
+
+	for i := range n.config.Endpoints {
+		ep := &n.config.Endpoints[i]
+		if !e.Matches(ep) {
+			continue
+		}
+		d := &persistence.WebhookDelivery{
+			Id:          fmt.Sprintf("%v-%v", e.Id, i),
+			URL:         ep.URL,
+			Event:       e.Type,
+			Payload:     string(payload),
+			NextAttempt: e.Time,
+			CreatedAt:   e.Time,
+		}
+		if err := n.db.QueueWebhookDelivery(d); err != nil {
+			glog.Errorf(whlogString(fmt.Sprintf("unable to queue event %v for %v, error: %v", e.Id, ep.URL, err)))
+		} else {
+			glog.V(3).Infof(whlogString(fmt.Sprintf("queued %v for %v", e, ep.URL)))
+		}
+	}
+}
+
+func failureKey(ag *persistence.Agreement) string {
+	return ag.DeviceId + "|" + ag.Pattern + "|" + ag.PolicyName
+}
+
+// Count an agreement that failed before it was finalized. When FailureThreshold agreements in a row have failed with the
+// node for the same deployment policy or pattern, agreement_failures is notified and the count starts again. The counts are
+// kept in memory, so they start again when the agbot restarts.
+func (n *Notifier) AgreementFailed(ag *persistence.Agreement, reason string) {
+	if n == nil {
+		return
+	}
+
+	n.lock.Lock()
+	key := failureKey(ag)
+	n.failures[key]++
+	failures := n.failures[key]
+	if failures >= n.config.FailureThreshold {
+		delete(n.failures, key)
+	}
+	n.lock.Unlock()
+
+	if failures >= n.config.FailureThreshold {
+		n.Notify(NewAgreementEvent(config.WebhookEventAgreementFailures, ag, map[string]interface{}{"failures": failures, "reason": reason}))
+	}
+}
+
+// Reset the count of failed agreements with the node for the deployment policy or pattern of the agreement.
+func (n *Notifier) AgreementFinalized(ag *persistence.Agreement) {
+	if n == nil {
+		return
+	}
+
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	delete(n.failures, failureKey(ag))
+}
+
+// Returns the number of seconds to wait before the next attempt to deliver a notification that failed attempts times.
+func (n *Notifier) backoff(attempts int) uint64 {
+	backoff := n.config.RetryBackoffS
+	for i := 1; i < attempts && backoff < n.config.MaxRetryBackoffS; i++ {
+		backoff *= 2
+	}
+	if backoff > n.config.MaxRetryBackoffS {
+		backoff = n.config.MaxRetryBackoffS
+	}
+	return uint64(backoff)
+}
+
+// Deliver the notifications whose next attempt is due. A notification that fails is retried with exponential backoff
+// until it has been attempted MaxAttempts times. Notifications for endpoints that are no longer configured are dropped.
+func (n *Notifier) Deliver() {
+	if n == nil {
+		return
+	}
+
+	for {
+		now := uint64(time.Now().Unix())
+
+		// The lease covers the delivery of the whole batch, so that no other agbot delivers the same notifications.
+		deliveries, err := n.db.ClaimWebhookDeliveries(now, uint64(n.config.TimeoutS*(deliveryBatchSize+1)), deliveryBatchSize)
+		if err != nil {
+			glog.Errorf(whlogString(fmt.Sprintf("unable to claim webhook deliveries, error: %v", err)))
+			return
+		}
+
+		for i := range deliveries {
+			n.deliver(&deliveries[i])
+		}
+
+		if len(deliveries) < deliveryBatchSize {
+			return
+		}
+	}
+}
+
+func (n *Notifier) deliver(d *persistence.WebhookDelivery) {
+	ep := n.config.GetEndpoint(d.URL)
+	if ep == nil {
+		glog.Warningf(whlogString(fmt.Sprintf("dropping delivery %v, %v is no longer a webhook endpoint", d.Id, d.URL)))
+		if err := n.db.DeleteWebhookDelivery(d.Id); err != nil {
+			glog.Errorf(whlogString(fmt.Sprintf("unable to delete delivery %v, error: %v", d.Id, err)))
+		}
+		return
+	}
+
+	if err := n.post(ep, d); err == nil {
+		glog.V(3).Infof(whlogString(fmt.Sprintf("delivered %v %v to %v", d.Event, d.Id, d.URL)))
+		if err := n.db.DeleteWebhookDelivery(d.Id); err != nil {
+			glog.Errorf(whlogString(fmt.Sprintf("unable to delete delivery %v, error: %v", d.Id, err)))
+		}
+	} else if attempts := d.Attempts + 1; attempts >= n.config.MaxAttempts {
+		glog.Errorf(whlogString(fmt.Sprintf("dropping delivery %v of %v to %v after %v attempts, error: %v", d.Id, d.Event, d.URL, attempts, err)))
+		if err := n.db.DeleteWebhookDelivery(d.Id); err != nil {
+			glog.Errorf(whlogString(fmt.Sprintf("unable to delete delivery %v, error: %v", d.Id, err)))
+		}
+	} else {
+		next := uint64(time.Now().Unix()) + n.backoff(attempts)
+		glog.Warningf(whlogString(fmt.Sprintf("delivery %v of %v to %v failed, attempt %v will be at %v, error: %v", d.Id, d.Event, d.URL, attempts+1, next, err)))
+		if err := n.db.RescheduleWebhookDelivery(d.Id, attempts, next, err.Error()); err != nil {
+			glog.Errorf(whlogString(fmt.Sprintf("unable to reschedule delivery %v, error: %v", d.Id, err)))
+		}
+	}
+}
+
+// Post the payload of the delivery to the endpoint. Any 2xx response is a successful delivery.
+func (n *Notifier) post(ep *config.WebhookEndpoint, d *persistence.WebhookDelivery) error {
+	req, err := http.NewRequest(http.MethodPost, ep.URL, bytes.NewBufferString(d.Payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range ep.Headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set(HEADER_EVENT, d.Event)
+	req.Header.Set(HEADER_DELIVERY, d.Id)
+	if ep.Secret != "" {
+		req.Header.Set(HEADER_SIGNATURE, Sign(ep.Secret, []byte(d.Payload)))
+	}
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.New(fmt.Sprintf("HTTP status %v", resp.StatusCode))
+	}
+	return nil
+}
+
+var whlogString = func(v interface{}) string {
+	return fmt.Sprintf("Webhook Notifier: %v", v)
+}
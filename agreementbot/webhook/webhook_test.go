@@ -0,0 +1,177 @@
+// +build unit
+
+package webhook
+
+import (
+	"encoding/json"
+	"github.com/open-horizon/anax/agreementbot/persistence"
+	"github.com/open-horizon/anax/agreementbot/persistence/memory"
+	"github.com/open-horizon/anax/config"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newTestNotifier(t *testing.T, endpoints ...config.WebhookEndpoint) (*Notifier, persistence.AgbotDatabase) {
+	db := new(memory.AgbotMemoryDB)
+	assert.Nil(t, db.Initialize(nil))
+	cfg := &config.WebhookConfig{
+		Endpoints:        endpoints,
+		FailureThreshold: 2,
+		MaxAttempts:      2,
+		RetryBackoffS:    5,
+		MaxRetryBackoffS: 60,
+		TimeoutS:         5,
+	}
+	return NewNotifier(cfg, db, "agbotorg/agbot1", http.DefaultClient), db
+}
+
+func Test_Event_Matches(t *testing.T) {
+	patternAg := &persistence.Agreement{CurrentAgreementId: "ag1", DeviceId: "nodeorg/node1", PolicyName: "patorg/pat1_bp", Pattern: "patorg/pat1"}
+	e := NewAgreementEvent(config.WebhookEventNodeOutOfPolicy, patternAg, nil)
+	assert.Equal(t, POLICY_TYPE_PATTERN, e.PolicyType)
+	assert.Equal(t, "patorg/pat1", e.Policy)
+
+	assert.True(t, e.Matches(&config.WebhookEndpoint{}), "an endpoint without filters gets every event")
+	assert.True(t, e.Matches(&config.WebhookEndpoint{Events: []string{config.WebhookEventWorkloadRollback, config.WebhookEventNodeOutOfPolicy}}))
+	assert.False(t, e.Matches(&config.WebhookEndpoint{Events: []string{config.WebhookEventWorkloadRollback}}))
+	assert.True(t, e.Matches(&config.WebhookEndpoint{Orgs: []string{"nodeorg"}}), "the org of the node should match")
+	assert.True(t, e.Matches(&config.WebhookEndpoint{Orgs: []string{"patorg"}}), "the org of the pattern should match")
+	assert.False(t, e.Matches(&config.WebhookEndpoint{Orgs: []string{"otherorg"}}))
+	assert.True(t, e.Matches(&config.WebhookEndpoint{Policies: []string{"patorg/pat1"}}))
+	assert.False(t, e.Matches(&config.WebhookEndpoint{Orgs: []string{"patorg"}, Policies: []string{"patorg/pat2"}}), "all the filters should apply")
+
+	policyAg := &persistence.Agreement{CurrentAgreementId: "ag2", DeviceId: "nodeorg/node1", PolicyName: "polorg/pol1"}
+	e = NewAgreementEvent(config.WebhookEventNodeOutOfPolicy, policyAg, nil)
+	assert.Equal(t, POLICY_TYPE_DEPLOYMENT_POLICY, e.PolicyType)
+	assert.True(t, e.Matches(&config.WebhookEndpoint{Policies: []string{"polorg/pol1"}}))
+}
+
+func Test_Sign(t *testing.T) {
+	// The expected signature is computed with: echo -n '{"id":"e1"}' | openssl dgst -sha256 -hmac secret
+	assert.Equal(t, "sha256=a83b68e806fa5bcc88ffa0042752dda08ea403e684f99e3099dc8ae3ccbab537", Sign("secret", []byte(`{"id":"e1"}`)))
+}
+
+func Test_NilNotifier(t *testing.T) {
+	n := NewNotifier(&config.WebhookConfig{}, nil, "agbotorg/agbot1", nil)
+	assert.Nil(t, n, "there should be no notifier without endpoints")
+
+	// None of these should panic.
+	ag := &persistence.Agreement{}
+	n.Notify(NewAgreementEvent(config.WebhookEventNodeOutOfPolicy, ag, nil))
+	n.AgreementFailed(ag, "NoReply")
+	n.AgreementFinalized(ag)
+	n.Deliver()
+}
+
+func Test_Notifier_Deliver(t *testing.T) {
+	var received []*http.Request
+	var payloads [][]byte
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		received = append(received, r)
+		payloads = append(payloads, body)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	n, db := newTestNotifier(t,
+		config.WebhookEndpoint{URL: server.URL + "/all", Secret: "secret", Headers: map[string]string{"Authorization": "Bearer token"}},
+		config.WebhookEndpoint{URL: server.URL + "/rollbacks", Events: []string{config.WebhookEventWorkloadRollback}})
+
+	ag := &persistence.Agreement{CurrentAgreementId: "ag1", DeviceId: "nodeorg/node1", PolicyName: "polorg/pol1"}
+	n.Notify(NewAgreementEvent(config.WebhookEventNodeOutOfPolicy, ag, map[string]interface{}{"missing_heartbeat_interval": 120}))
+
+	deliveries, err := db.FindWebhookDeliveries()
+	assert.Nil(t, err)
+	assert.Len(t, deliveries, 1, "the event should only be queued for the endpoint whose filters it passes")
+
+	n.Deliver()
+	if assert.Len(t, received, 1) {
+		assert.Equal(t, "/all", received[0].URL.Path)
+		assert.Equal(t, config.WebhookEventNodeOutOfPolicy, received[0].Header.Get(HEADER_EVENT))
+		assert.Equal(t, deliveries[0].Id, received[0].Header.Get(HEADER_DELIVERY))
+		assert.Equal(t, Sign("secret", payloads[0]), received[0].Header.Get(HEADER_SIGNATURE))
+		assert.Equal(t, "Bearer token", received[0].Header.Get("Authorization"))
+
+		var e Event
+		assert.Nil(t, json.Unmarshal(payloads[0], &e))
+		assert.Equal(t, config.WebhookEventNodeOutOfPolicy, e.Type)
+		assert.Equal(t, "agbotorg/agbot1", e.Agbot)
+		assert.Equal(t, "polorg/pol1", e.Policy)
+		assert.Equal(t, "nodeorg/node1", e.Node)
+		assert.Equal(t, "ag1", e.AgreementId)
+		assert.Equal(t, float64(120), e.Details["missing_heartbeat_interval"])
+	}
+	deliveries, err = db.FindWebhookDeliveries()
+	assert.Nil(t, err)
+	assert.Empty(t, deliveries, "a delivered notification should be removed from the queue")
+
+	// A failed delivery is rescheduled, and dropped after MaxAttempts.
+	status = http.StatusServiceUnavailable
+	n.Notify(NewAgreementEvent(config.WebhookEventWorkloadRollback, ag, nil))
+	n.Deliver()
+	assert.Len(t, received, 3)
+	deliveries, err = db.FindWebhookDeliveries()
+	assert.Nil(t, err)
+	if assert.Len(t, deliveries, 2) {
+		assert.Equal(t, 1, deliveries[0].Attempts)
+		assert.Equal(t, "HTTP status 503", deliveries[0].LastError)
+		assert.True(t, deliveries[0].NextAttempt > deliveries[0].CreatedAt)
+	}
+
+	// Make the retries due.
+	for _, d := range deliveries {
+		assert.Nil(t, db.RescheduleWebhookDelivery(d.Id, d.Attempts, 0, d.LastError))
+	}
+	n.Deliver()
+	assert.Len(t, received, 5)
+	deliveries, err = db.FindWebhookDeliveries()
+	assert.Nil(t, err)
+	assert.Empty(t, deliveries)
+}
+
+func Test_Notifier_AgreementFailed(t *testing.T) {
+	n, db := newTestNotifier(t, config.WebhookEndpoint{URL: "https://hooks.example.com"})
+
+	ag := &persistence.Agreement{CurrentAgreementId: "ag1", DeviceId: "nodeorg/node1", PolicyName: "polorg/pol1"}
+	other := &persistence.Agreement{CurrentAgreementId: "ag2", DeviceId: "nodeorg/node2", PolicyName: "polorg/pol1"}
+
+	n.AgreementFailed(ag, "NoReply")
+	n.AgreementFailed(other, "NoReply")
+	n.AgreementFinalized(ag)
+	n.AgreementFailed(ag, "NoReply")
+	deliveries, err := db.FindWebhookDeliveries()
+	assert.Nil(t, err)
+	assert.Empty(t, deliveries, "a finalized agreement should reset the count")
+
+	n.AgreementFailed(ag, "NegativeReply")
+	deliveries, err = db.FindWebhookDeliveries()
+	assert.Nil(t, err)
+	if assert.Len(t, deliveries, 1) {
+		var e Event
+		assert.Nil(t, json.Unmarshal([]byte(deliveries[0].Payload), &e))
+		assert.Equal(t, config.WebhookEventAgreementFailures, e.Type)
+		assert.Equal(t, "nodeorg/node1", e.Node)
+		assert.Equal(t, float64(2), e.Details["failures"])
+		assert.Equal(t, "NegativeReply", e.Details["reason"])
+	}
+
+	// The count starts again after a notification.
+	n.AgreementFailed(ag, "NoReply")
+	deliveries, err = db.FindWebhookDeliveries()
+	assert.Nil(t, err)
+	assert.Len(t, deliveries, 1)
+}
+
+func Test_Notifier_backoff(t *testing.T) {
+	n, _ := newTestNotifier(t, config.WebhookEndpoint{URL: "https://hooks.example.com"})
+	assert.Equal(t, uint64(5), n.backoff(1))
+	assert.Equal(t, uint64(10), n.backoff(2))
+	assert.Equal(t, uint64(40), n.backoff(4))
+	assert.Equal(t, uint64(60), n.backoff(5))
+	assert.Equal(t, uint64(60), n.backoff(50))
+}
@@ -27,6 +27,7 @@ type API struct {
 	bcStateLock    sync.Mutex
 	shutdownError  string
 	EC             *worker.BaseExchangeContext
+	auth           *apiAuthenticator // nil when the local API is not authenticated
 }
 
 type BlockchainState struct {
@@ -62,6 +63,15 @@ func NewAPIListener(name string, cfg *config.HorizonConfig, db *bolt.DB, pm *pol
 		listener.EC = worker.NewExchangeContext(fmt.Sprintf("%v/%v", pDevice.Org, pDevice.Id), pDevice.Token, cfg.Edge.ExchangeURL, cfg.GetCSSURL(), cfg.Collaborators.HTTPClientFactory)
 	}
 
+	// setup the authentication of the local API
+	if cfg.Edge.APIAuth.Enabled {
+		if auth, err := newAPIAuthenticator(&cfg.Edge.APIAuth); err != nil {
+			glog.Fatalf(apiLogString(fmt.Sprintf("Unable to setup the API authentication, error %v", err)))
+		} else {
+			listener.auth = auth
+		}
+	}
+
 	listener.listen(cfg)
 	return listener
 }
@@ -70,50 +80,55 @@ func (a *API) router(includeStaticRedirects bool) *mux.Router {
 	router := mux.NewRouter()
 
 	// For working with global and microservice specific attributes directly
-	router.HandleFunc("/attribute", a.attribute).Methods("OPTIONS", "HEAD", "GET", "POST")
-	router.HandleFunc("/attribute/{id}", a.attribute).Methods("OPTIONS", "HEAD", "GET", "PUT", "PATCH", "DELETE")
+	router.HandleFunc("/attribute", a.authorize(adminAccess, a.attribute)).Methods("OPTIONS", "HEAD", "GET", "POST")
+	router.HandleFunc("/attribute/{id}", a.authorize(adminAccess, a.attribute)).Methods("OPTIONS", "HEAD", "GET", "PUT", "PATCH", "DELETE")
 
 	// For working with existing or archived agreements
-	router.HandleFunc("/agreement", a.agreement).Methods("GET", "OPTIONS")
-	router.HandleFunc("/agreement/{id}", a.agreement).Methods("GET", "DELETE", "OPTIONS")
+	router.HandleFunc("/agreement", a.authorize(readOnlyAccess, a.agreement)).Methods("GET", "OPTIONS")
+	router.HandleFunc("/agreement/{id}", a.authorize(readOnlyAccess, a.agreement)).Methods("GET", "DELETE", "OPTIONS")
 
 	// For obtaining microservice info or configuring a microservice (sensor) userInput variables
-	router.HandleFunc("/service", a.service).Methods("GET", "OPTIONS")
-	router.HandleFunc("/service/config", a.serviceconfig).Methods("GET", "POST", "OPTIONS")
-	router.HandleFunc("/service/configstate", a.service_configstate).Methods("GET", "POST", "OPTIONS")
-	router.HandleFunc("/service/policy", a.servicepolicy).Methods("GET", "OPTIONS")
+	router.HandleFunc("/service", a.authorize(readOnlyAccess, a.service)).Methods("GET", "OPTIONS")
+	router.HandleFunc("/service/config", a.authorize(adminAccess, a.serviceconfig)).Methods("GET", "POST", "OPTIONS")
+	router.HandleFunc("/service/configstate", a.authorize(readOnlyAccess, a.service_configstate)).Methods("GET", "POST", "OPTIONS")
+	router.HandleFunc("/service/policy", a.authorize(readOnlyAccess, a.servicepolicy)).Methods("GET", "OPTIONS")
 
 	// Connectivity and blockchain status info
-	router.HandleFunc("/status", a.status).Methods("GET", "OPTIONS")
-	router.HandleFunc("/status/workers", a.workerstatus).Methods("GET", "OPTIONS")
+	router.HandleFunc("/status", a.authorize(readOnlyAccess, a.status)).Methods("GET", "OPTIONS")
+	router.HandleFunc("/status/workers", a.authorize(readOnlyAccess, a.workerstatus)).Methods("GET", "OPTIONS")
 
 	// Used by the Registration UI to obtain a random token string
-	router.HandleFunc("/token/random", tokenRandom).Methods("GET", "OPTIONS")
+	router.HandleFunc("/token/random", a.authorize(readOnlyAccess, tokenRandom)).Methods("GET", "OPTIONS")
 
 	// Used to configure a node to participate in the Horizon platform
-	router.HandleFunc("/node", a.node).Methods("GET", "HEAD", "POST", "PATCH", "DELETE", "OPTIONS")
-	router.HandleFunc("/node/configstate", a.nodeconfigstate).Methods("GET", "HEAD", "PUT", "OPTIONS")
-	router.HandleFunc("/node/policy", a.nodepolicy).Methods("GET", "HEAD", "PUT", "POST", "PATCH", "DELETE", "OPTIONS")
-	router.HandleFunc("/node/userinput", a.nodeuserinput).Methods("GET", "HEAD", "PUT", "POST", "PATCH", "DELETE", "OPTIONS")
+	router.HandleFunc("/node", a.authorize(readOnlyAccess, a.node)).Methods("GET", "HEAD", "POST", "PATCH", "DELETE", "OPTIONS")
+	router.HandleFunc("/node/configstate", a.authorize(readOnlyAccess, a.nodeconfigstate)).Methods("GET", "HEAD", "PUT", "OPTIONS")
+	router.HandleFunc("/node/policy", a.authorize(readOnlyAccess, a.nodepolicy)).Methods("GET", "HEAD", "PUT", "POST", "PATCH", "DELETE", "OPTIONS")
+	router.HandleFunc("/node/userinput", a.authorize(adminAccess, a.nodeuserinput)).Methods("GET", "HEAD", "PUT", "POST", "PATCH", "DELETE", "OPTIONS")
+	router.HandleFunc("/node/diag", a.authorize(adminAccess, a.nodediag)).Methods("GET", "OPTIONS")
+	router.HandleFunc("/node/messagingkey", a.authorize(adminAccess, a.nodemessagingkey)).Methods("POST", "OPTIONS")
 
 	// Used to get the event logs on this node.
 	// get the eventlogs for current registration.
-	router.HandleFunc("/eventlog", a.eventlog).Methods("GET", "OPTIONS")
+	router.HandleFunc("/eventlog", a.authorize(readOnlyAccess, a.eventlog)).Methods("GET", "OPTIONS")
 	// get the eventlogs for all registrations.
-	router.HandleFunc("/eventlog/all", a.eventlog).Methods("GET", "OPTIONS")
+	router.HandleFunc("/eventlog/all", a.authorize(readOnlyAccess, a.eventlog)).Methods("GET", "OPTIONS")
+	// stream the eventlogs as they are saved, for the current or all registrations.
+	router.HandleFunc("/eventlog/stream", a.authorize(readOnlyAccess, a.eventlogstream)).Methods("GET", "OPTIONS")
+	router.HandleFunc("/eventlog/all/stream", a.authorize(readOnlyAccess, a.eventlogstream)).Methods("GET", "OPTIONS")
 	//get the active surface errors for this node
-	router.HandleFunc("/eventlog/surface", a.surface).Methods("GET", "OPTIONS")
+	router.HandleFunc("/eventlog/surface", a.authorize(readOnlyAccess, a.surface)).Methods("GET", "OPTIONS")
 
 	// For importing workload public signing keys (RSA-PSS key pair public key)
-	router.HandleFunc("/{p:(?:publickey|trust)}", a.publickey).Methods("GET", "OPTIONS")
-	router.HandleFunc("/{p:(?:publickey|trust)}/{filename}", a.publickey).Methods("GET", "PUT", "DELETE", "OPTIONS")
+	router.HandleFunc("/{p:(?:publickey|trust)}", a.authorize(readOnlyAccess, a.publickey)).Methods("GET", "OPTIONS")
+	router.HandleFunc("/{p:(?:publickey|trust)}/{filename}", a.authorize(readOnlyAccess, a.publickey)).Methods("GET", "PUT", "DELETE", "OPTIONS")
 
 	if includeStaticRedirects {
 		// redirect to index.html because SPA
-		router.HandleFunc(`/{p:[\w\/]+}`, func(w http.ResponseWriter, r *http.Request) {
+		router.HandleFunc(`/{p:[\w\/]+}`, a.authorize(readOnlyAccess, func(w http.ResponseWriter, r *http.Request) {
 			http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
-		})
-		router.PathPrefix("/").Handler(http.FileServer(http.Dir(a.Config.Edge.StaticWebContent)))
+		}))
+		router.PathPrefix("/").Handler(a.authorize(readOnlyAccess, http.FileServer(http.Dir(a.Config.Edge.StaticWebContent)).ServeHTTP))
 		glog.Infof(apiLogString(fmt.Sprintf("Include static redirects: %v", includeStaticRedirects)))
 		glog.Infof(apiLogString(fmt.Sprintf("Serving static web content from: %v", a.Config.Edge.StaticWebContent)))
 	}
@@ -142,6 +157,18 @@ func (a *API) listen(cfg *config.HorizonConfig) {
 		}
 	}()
 
+	// The unix socket serves the same API. Its callers are identified by their peer credentials when the API is authenticated.
+	if socket := cfg.Edge.APIAuth.UnixSocket; socket != "" {
+		go func() {
+			server := &http.Server{Handler: nocache(a.router(false)), ConnContext: peerCredentialsContext}
+			if l, err := listenUnixSocket(socket); err != nil {
+				glog.Fatalf(apiLogString(fmt.Sprintf("Failed to start listener on unix socket %v, error %v", socket, err)))
+			} else if err := server.Serve(l); err != nil {
+				glog.Fatalf(apiLogString(fmt.Sprintf("Failed to serve on unix socket %v, error %v", socket, err)))
+			}
+		}()
+	}
+
 }
 
 // Worker framework functions
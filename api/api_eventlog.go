@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/golang/glog"
 	"github.com/open-horizon/anax/i18n"
+	"github.com/open-horizon/anax/persistence"
 	"net/http"
 	"strings"
 )
@@ -51,6 +52,57 @@ func (a *API) eventlog(w http.ResponseWriter, r *http.Request) {
 
 }
 
+// stream the eventlogs for the current or all registrations. The existing event logs that match the selection are sent
+// first, then every new event log that matches the selection is sent as soon as it is saved. The records are written as
+// json lines, or as server-sent events if the client accepts text/event-stream.
+func (a *API) eventlogstream(w http.ResponseWriter, r *http.Request) {
+
+	resource := "eventlog/stream"
+
+	errorHandler := GetHTTPErrorHandler(w)
+
+	switch r.Method {
+	case "GET":
+		lan := r.Header.Get("Accept-Language")
+		if lan == "" {
+			lan = i18n.DEFAULT_LANGUAGE
+		}
+		msgPrinter := i18n.GetMessagePrinterWithLocale(lan)
+
+		all_logs := false
+		if r.URL != nil && strings.Contains(r.URL.Path, "all") {
+			all_logs = true
+		}
+
+		if err := r.ParseForm(); err != nil {
+			errorHandler(NewAPIUserInputError(msgPrinter.Sprintf("Error parsing the selections %v. %v", r.Form, err), "selection"))
+			return
+		}
+
+		glog.V(5).Infof(apiLogString(fmt.Sprintf("Handling %v on resource %v with selection %v. Language: %v", r.Method, resource, r.Form, lan)))
+
+		if _, ok := w.(http.Flusher); !ok {
+			errorHandler(NewSystemError(msgPrinter.Sprintf("Error streaming %v, the connection does not support streaming", resource)))
+			return
+		}
+
+		// Subscribe before reading the existing records so that nothing saved in between is missed.
+		sub := persistence.SubscribeEventLogs(EVENTLOG_STREAM_BUFFER_SIZE)
+		defer sub.Close()
+
+		if err := StreamEventLogsForOutput(r.Context(), w, a.db, all_logs, r.Form, strings.Contains(r.Header.Get("Accept"), EVENTLOG_STREAM_SSE), sub, msgPrinter); err != nil {
+			errorHandler(NewSystemError(msgPrinter.Sprintf("Error streaming %v, error %v", resource, err)))
+		}
+
+	case "OPTIONS":
+		w.Header().Set("Allow", "GET, OPTIONS")
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+
+}
+
 func (a *API) surface(w http.ResponseWriter, r *http.Request) {
 	resource := "eventlog/surface"
 	errorHandler := GetHTTPErrorHandler(w)
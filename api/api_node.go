@@ -1,6 +1,7 @@
 package api
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -8,11 +9,13 @@ import (
 	"strconv"
 
 	"github.com/golang/glog"
+	"github.com/open-horizon/anax/apicommon"
 	"github.com/open-horizon/anax/eventlog"
 	"github.com/open-horizon/anax/events"
 	"github.com/open-horizon/anax/exchange"
 	"github.com/open-horizon/anax/exchangesync"
 	"github.com/open-horizon/anax/externalpolicy"
+	"github.com/open-horizon/anax/i18n"
 	"github.com/open-horizon/anax/persistence"
 	"github.com/open-horizon/anax/policy"
 	"github.com/open-horizon/anax/version"
@@ -537,3 +540,70 @@ func (a *API) nodeuserinput(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 	}
 }
+
+// Collect the node diagnostics into a gzipped tar bundle. The bundle is collected in memory first, so that an error
+// can still be returned as an error response.
+func (a *API) nodediag(w http.ResponseWriter, r *http.Request) {
+
+	resource := "node/diag"
+
+	errorHandler := GetHTTPErrorHandler(w)
+
+	switch r.Method {
+	case "GET":
+		// get message printer with the language passed in from the header
+		lan := r.Header.Get("Accept-Language")
+		if lan == "" {
+			lan = i18n.DEFAULT_LANGUAGE
+		}
+		msgPrinter := i18n.GetMessagePrinterWithLocale(lan)
+
+		glog.V(5).Infof(apiLogString(fmt.Sprintf("Handling %v on resource %v", r.Method, resource)))
+
+		info := apicommon.NewInfo(a.GetHTTPFactory(), a.GetExchangeURL(), a.GetCSSURL(), a.GetExchangeId(), a.GetExchangeToken())
+
+		bundle := new(bytes.Buffer)
+		if err := WriteNodeDiagBundle(bundle, a.pm, a.db, a.Config, info, a.GetExchangeToken(), msgPrinter); err != nil {
+			errorHandler(NewSystemError(fmt.Sprintf("Error collecting %v, error %v", resource, err)))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Header().Set("Content-Disposition", "attachment; filename=\"node-diag.tgz\"")
+		w.Header().Set("Content-Length", strconv.Itoa(bundle.Len()))
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(bundle.Bytes()); err != nil {
+			glog.Error(apiLogString(err))
+		}
+
+	case "OPTIONS":
+		w.Header().Set("Allow", "GET, OPTIONS")
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// Rotate the messaging key of the node. The new public key is published to the exchange, and the old key can still decrypt
+// messages for the configured grace period. The rotation is recorded in the event log.
+func (a *API) nodemessagingkey(w http.ResponseWriter, r *http.Request) {
+
+	resource := "node/messagingkey"
+
+	errorHandler := GetHTTPErrorHandler(w)
+
+	switch r.Method {
+	case "POST":
+		glog.V(5).Infof(apiLogString(fmt.Sprintf("Handling %v on resource %v", r.Method, resource)))
+
+		if errHandled := RotateNodeMessagingKey(errorHandler, a.Messages(), a.db); !errHandled {
+			w.WriteHeader(http.StatusAccepted)
+		}
+
+	case "OPTIONS":
+		w.Header().Set("Allow", "POST, OPTIONS")
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,235 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/user"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/config"
+)
+
+// The access that a route needs. GET, HEAD and OPTIONS requests read the route, every other method changes it.
+type routeAccess struct {
+	read  string
+	write string
+}
+
+// Routes that any authenticated caller can read.
+var readOnlyAccess = routeAccess{read: config.APIRoleReadOnly, write: config.APIRoleAdmin}
+
+// Routes that can contain secrets, e.g. user input values, so that only admins can read them.
+var adminAccess = routeAccess{read: config.APIRoleAdmin, write: config.APIRoleAdmin}
+
+// The number of random bytes in a generated token.
+const API_TOKEN_BYTES = 32
+
+// The credentials of the process on the other end of a unix socket connection.
+type peerCredentials struct {
+	Pid int32
+	Uid uint32
+	Gid uint32
+}
+
+type peerCredentialsKey struct{}
+
+// The authenticator decides the role of each request to the local API. Requests on the unix socket get a role
+// from the peer credentials of the connection, requests on the TCP listener get the anonymous role. A bearer
+// token in the request raises the role to the token's role.
+type apiAuthenticator struct {
+	cfg           config.APIAuthConfig
+	adminToken    []byte
+	readOnlyToken []byte
+}
+
+func newAPIAuthenticator(cfg *config.APIAuthConfig) (*apiAuthenticator, error) {
+	aa := &apiAuthenticator{cfg: *cfg}
+
+	// The readonly token is only shared with the members of the token group, if there is one.
+	readOnlyPerm, readOnlyGid := os.FileMode(0600), -1
+	if cfg.TokenGroup != "" {
+		if grp, err := user.LookupGroup(cfg.TokenGroup); err != nil {
+			return nil, errors.New(fmt.Sprintf("unable to find API token group %v, error: %v", cfg.TokenGroup, err))
+		} else if gid, err := strconv.Atoi(grp.Gid); err != nil {
+			return nil, errors.New(fmt.Sprintf("API token group %v has an invalid gid %v, error: %v", cfg.TokenGroup, grp.Gid, err))
+		} else {
+			readOnlyPerm, readOnlyGid = 0640, gid
+		}
+	}
+
+	var err error
+	if aa.adminToken, err = loadOrCreateToken(cfg.GetTokenFile(config.APIRoleAdmin), 0600, -1); err != nil {
+		return nil, err
+	} else if aa.readOnlyToken, err = loadOrCreateToken(cfg.GetTokenFile(config.APIRoleReadOnly), readOnlyPerm, readOnlyGid); err != nil {
+		return nil, err
+	}
+	return aa, nil
+}
+
+// Read the token in the file, or generate a token and save it in the file if the file does not exist. The file
+// permissions and group control which local users can use the token. A gid of -1 keeps the default group.
+func loadOrCreateToken(file string, perm os.FileMode, gid int) ([]byte, error) {
+	if tok, err := ioutil.ReadFile(file); err == nil {
+		tok = []byte(strings.TrimSpace(string(tok)))
+		if len(tok) == 0 {
+			return nil, errors.New(fmt.Sprintf("API token file %v is empty", file))
+		}
+		return tok, nil
+	} else if !os.IsNotExist(err) {
+		return nil, errors.New(fmt.Sprintf("unable to read API token file %v, error: %v", file, err))
+	}
+
+	b := make([]byte, API_TOKEN_BYTES)
+	if _, err := rand.Read(b); err != nil {
+		return nil, errors.New(fmt.Sprintf("unable to generate API token, error: %v", err))
+	}
+	tok := []byte(hex.EncodeToString(b))
+
+	if err := os.MkdirAll(path.Dir(file), 0755); err != nil {
+		return nil, errors.New(fmt.Sprintf("unable to create API token directory %v, error: %v", path.Dir(file), err))
+	} else if err := ioutil.WriteFile(file, append(tok, '\n'), perm); err != nil {
+		return nil, errors.New(fmt.Sprintf("unable to write API token file %v, error: %v", file, err))
+	} else if err := os.Chown(file, -1, gid); err != nil {
+		return nil, errors.New(fmt.Sprintf("unable to set the group of API token file %v, error: %v", file, err))
+	} else if err := os.Chmod(file, perm); err != nil {
+		return nil, errors.New(fmt.Sprintf("unable to set the permissions of API token file %v, error: %v", file, err))
+	}
+	glog.V(3).Infof(apiLogString(fmt.Sprintf("generated API token file %v", file)))
+	return tok, nil
+}
+
+// Returns the role of the request. An error is returned if the request has credentials that are not valid.
+func (aa *apiAuthenticator) getRole(r *http.Request) (string, error) {
+	role := aa.cfg.AnonymousRole
+	if cred, ok := r.Context().Value(peerCredentialsKey{}).(*peerCredentials); ok {
+		role = aa.peerRole(cred)
+	}
+
+	if authz := r.Header.Get("Authorization"); authz != "" {
+		tok := strings.TrimSpace(strings.TrimPrefix(authz, "Bearer "))
+		if !strings.HasPrefix(authz, "Bearer ") || tok == "" {
+			return config.APIRoleNone, errors.New("the Authorization header must be a bearer token")
+		} else if subtle.ConstantTimeCompare([]byte(tok), aa.adminToken) == 1 {
+			role = config.APIRoleAdmin
+		} else if subtle.ConstantTimeCompare([]byte(tok), aa.readOnlyToken) == 1 {
+			if !hasAPIRole(role, config.APIRoleReadOnly) {
+				role = config.APIRoleReadOnly
+			}
+		} else {
+			return config.APIRoleNone, errors.New("the bearer token is not valid")
+		}
+	}
+	return role, nil
+}
+
+// Returns the role of a unix socket peer. Root always has the admin role.
+func (aa *apiAuthenticator) peerRole(cred *peerCredentials) string {
+	if cred == nil {
+		return config.APIRoleNone
+	} else if cred.Uid == 0 {
+		return config.APIRoleAdmin
+	}
+	for _, uid := range aa.cfg.AdminUIDs {
+		if uid == cred.Uid {
+			return config.APIRoleAdmin
+		}
+	}
+	for _, gid := range aa.cfg.AdminGIDs {
+		if gid == cred.Gid {
+			return config.APIRoleAdmin
+		}
+	}
+	return aa.cfg.PeerRole
+}
+
+// Returns true if the role includes the needed role.
+func hasAPIRole(role string, needed string) bool {
+	rank := map[string]int{config.APIRoleNone: 0, config.APIRoleReadOnly: 1, config.APIRoleAdmin: 2}
+	return rank[role] >= rank[needed]
+}
+
+// Wrap the handler of a route so that it is only called for requests with the role that the route needs. The
+// handler is returned as is when authentication is not enabled.
+func (a *API) authorize(access routeAccess, h http.HandlerFunc) http.HandlerFunc {
+	if a.auth == nil {
+		return h
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		// CORS preflight requests do not carry credentials.
+		if r.Method == http.MethodOptions {
+			h(w, r)
+			return
+		}
+
+		needed := access.write
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			needed = access.read
+		}
+
+		role, err := a.auth.getRole(r)
+		if err != nil {
+			glog.V(3).Infof(apiLogString(fmt.Sprintf("rejected %v %v, error: %v", r.Method, r.URL.Path, err)))
+			w.Header().Set("WWW-Authenticate", `Bearer realm="anax"`)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		} else if !hasAPIRole(role, needed) {
+			glog.V(3).Infof(apiLogString(fmt.Sprintf("rejected %v %v, role %v does not have %v access", r.Method, r.URL.Path, role, needed)))
+			if role == config.APIRoleNone {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="anax"`)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			} else {
+				http.Error(w, fmt.Sprintf("Forbidden, the %v role is needed", needed), http.StatusForbidden)
+			}
+			return
+		}
+
+		h(w, r)
+	}
+}
+
+// Save the peer credentials of unix socket connections in the request context so that the authenticator can
+// find them.
+func peerCredentialsContext(ctx context.Context, c net.Conn) context.Context {
+	uc, ok := c.(*net.UnixConn)
+	if !ok {
+		return ctx
+	}
+
+	cred, err := getPeerCredentials(uc)
+	if err != nil {
+		glog.Errorf(apiLogString(fmt.Sprintf("unable to get the peer credentials of a unix socket connection, error: %v", err)))
+	}
+	// A nil credential gives the connection no role.
+	return context.WithValue(ctx, peerCredentialsKey{}, cred)
+}
+
+// Listen on the unix socket. The socket is accessible to every local user, the peer credentials decide what they
+// can do.
+func listenUnixSocket(socket string) (net.Listener, error) {
+	if err := os.MkdirAll(path.Dir(socket), 0755); err != nil {
+		return nil, err
+	} else if err := os.Remove(socket); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		return nil, err
+	} else if err := os.Chmod(socket, 0666); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
@@ -0,0 +1,263 @@
+// +build unit
+
+package api
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/user"
+	"path"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/open-horizon/anax/config"
+)
+
+func getTestAuthConfig(t *testing.T) (*config.APIAuthConfig, string) {
+	dir, err := ioutil.TempDir("", "apiauth-")
+	if err != nil {
+		t.Fatalf("unable to create temp dir, error %v", err)
+	}
+	return &config.APIAuthConfig{
+		Enabled:       true,
+		TokenDir:      path.Join(dir, "tokens"),
+		PeerRole:      config.APIRoleReadOnly,
+		AnonymousRole: config.APIRoleNone,
+		AdminUIDs:     []uint32{1000},
+		AdminGIDs:     []uint32{2000},
+	}, dir
+}
+
+func Test_APIAuth_tokens(t *testing.T) {
+	cfg, dir := getTestAuthConfig(t)
+	defer os.RemoveAll(dir)
+
+	aa, err := newAPIAuthenticator(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	} else if string(aa.adminToken) == string(aa.readOnlyToken) {
+		t.Errorf("admin and readonly tokens should be different")
+	}
+
+	if fi, err := os.Stat(cfg.GetTokenFile(config.APIRoleAdmin)); err != nil {
+		t.Errorf("admin token file should exist, error %v", err)
+	} else if fi.Mode().Perm() != 0600 {
+		t.Errorf("admin token file should only be readable by its owner, it is %v", fi.Mode().Perm())
+	}
+
+	if fi, err := os.Stat(cfg.GetTokenFile(config.APIRoleReadOnly)); err != nil {
+		t.Errorf("readonly token file should exist, error %v", err)
+	} else if fi.Mode().Perm() != 0600 {
+		t.Errorf("readonly token file should only be readable by its owner without a token group, it is %v", fi.Mode().Perm())
+	}
+
+	// The tokens are kept across restarts.
+	if aa2, err := newAPIAuthenticator(cfg); err != nil {
+		t.Errorf("unexpected error %v", err)
+	} else if string(aa2.adminToken) != string(aa.adminToken) || string(aa2.readOnlyToken) != string(aa.readOnlyToken) {
+		t.Errorf("tokens should be read from the token files")
+	}
+
+	// An empty token file is an error.
+	if err := ioutil.WriteFile(cfg.GetTokenFile(config.APIRoleReadOnly), []byte("\n"), 0644); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	} else if _, err := newAPIAuthenticator(cfg); err == nil {
+		t.Errorf("an empty token file should be an error")
+	}
+}
+
+func Test_APIAuth_token_group(t *testing.T) {
+	cfg, dir := getTestAuthConfig(t)
+	defer os.RemoveAll(dir)
+
+	// The test process can always give its files to its own group.
+	grp, err := user.LookupGroupId(strconv.Itoa(os.Getgid()))
+	if err != nil {
+		t.Skipf("unable to look up the group of the test process, error %v", err)
+	}
+	cfg.TokenGroup = grp.Name
+
+	if _, err := newAPIAuthenticator(cfg); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if fi, err := os.Stat(cfg.GetTokenFile(config.APIRoleReadOnly)); err != nil {
+		t.Errorf("readonly token file should exist, error %v", err)
+	} else if fi.Mode().Perm() != 0640 {
+		t.Errorf("readonly token file should be readable by the token group, it is %v", fi.Mode().Perm())
+	} else if st, ok := fi.Sys().(*syscall.Stat_t); ok && int(st.Gid) != os.Getgid() {
+		t.Errorf("readonly token file should be owned by group %v, it is owned by %v", os.Getgid(), st.Gid)
+	}
+
+	if fi, err := os.Stat(cfg.GetTokenFile(config.APIRoleAdmin)); err != nil {
+		t.Errorf("admin token file should exist, error %v", err)
+	} else if fi.Mode().Perm() != 0600 {
+		t.Errorf("admin token file should only be readable by its owner, it is %v", fi.Mode().Perm())
+	}
+
+	// A token group that does not exist is an error.
+	cfg.TokenGroup = "no-such-group-for-the-api-tokens"
+	os.RemoveAll(cfg.TokenDir)
+	if _, err := newAPIAuthenticator(cfg); err == nil {
+		t.Errorf("an unknown token group should be an error")
+	}
+}
+
+func Test_APIAuth_roles(t *testing.T) {
+	cfg, dir := getTestAuthConfig(t)
+	defer os.RemoveAll(dir)
+
+	aa, err := newAPIAuthenticator(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		cred   *peerCredentials
+		peer   bool
+		authz  string
+		role   string
+		hasErr bool
+	}{
+		{name: "anonymous", role: config.APIRoleNone},
+		{name: "admin token", authz: "Bearer " + string(aa.adminToken), role: config.APIRoleAdmin},
+		{name: "readonly token", authz: "Bearer " + string(aa.readOnlyToken), role: config.APIRoleReadOnly},
+		{name: "bad token", authz: "Bearer nope", role: config.APIRoleNone, hasErr: true},
+		{name: "basic auth", authz: "Basic dXNlcjpwdw==", role: config.APIRoleNone, hasErr: true},
+		{name: "root peer", peer: true, cred: &peerCredentials{Uid: 0, Gid: 0}, role: config.APIRoleAdmin},
+		{name: "admin uid peer", peer: true, cred: &peerCredentials{Uid: 1000, Gid: 1000}, role: config.APIRoleAdmin},
+		{name: "admin gid peer", peer: true, cred: &peerCredentials{Uid: 1001, Gid: 2000}, role: config.APIRoleAdmin},
+		{name: "other peer", peer: true, cred: &peerCredentials{Uid: 1001, Gid: 1001}, role: config.APIRoleReadOnly},
+		{name: "other peer with admin token", peer: true, cred: &peerCredentials{Uid: 1001, Gid: 1001}, authz: "Bearer " + string(aa.adminToken), role: config.APIRoleAdmin},
+		{name: "admin peer with readonly token", peer: true, cred: &peerCredentials{Uid: 0}, authz: "Bearer " + string(aa.readOnlyToken), role: config.APIRoleAdmin},
+		{name: "unknown peer", peer: true, role: config.APIRoleNone},
+	}
+
+	for _, tc := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/status", nil)
+		if tc.peer {
+			r = r.WithContext(context.WithValue(r.Context(), peerCredentialsKey{}, tc.cred))
+		}
+		if tc.authz != "" {
+			r.Header.Set("Authorization", tc.authz)
+		}
+		role, err := aa.getRole(r)
+		if tc.hasErr && err == nil {
+			t.Errorf("%v: expected an error", tc.name)
+		} else if !tc.hasErr && err != nil {
+			t.Errorf("%v: unexpected error %v", tc.name, err)
+		} else if role != tc.role {
+			t.Errorf("%v: expected role %v, got %v", tc.name, tc.role, role)
+		}
+	}
+}
+
+func Test_APIAuth_authorize(t *testing.T) {
+	cfg, dir := getTestAuthConfig(t)
+	defer os.RemoveAll(dir)
+
+	aa, err := newAPIAuthenticator(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	// Without authentication every request is passed to the handler.
+	a := &API{}
+	w := httptest.NewRecorder()
+	a.authorize(adminAccess, ok)(w, httptest.NewRequest(http.MethodDelete, "/node", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected %v without authentication, got %v", http.StatusOK, w.Code)
+	}
+
+	a.auth = aa
+	tests := []struct {
+		method string
+		access routeAccess
+		token  []byte
+		code   int
+	}{
+		{http.MethodGet, readOnlyAccess, nil, http.StatusUnauthorized},
+		{http.MethodOptions, readOnlyAccess, nil, http.StatusOK},
+		{http.MethodGet, readOnlyAccess, aa.readOnlyToken, http.StatusOK},
+		{http.MethodHead, readOnlyAccess, aa.readOnlyToken, http.StatusOK},
+		{http.MethodPost, readOnlyAccess, aa.readOnlyToken, http.StatusForbidden},
+		{http.MethodDelete, readOnlyAccess, aa.adminToken, http.StatusOK},
+		{http.MethodGet, adminAccess, aa.readOnlyToken, http.StatusForbidden},
+		{http.MethodGet, adminAccess, aa.adminToken, http.StatusOK},
+		{http.MethodGet, adminAccess, []byte("bad"), http.StatusUnauthorized},
+	}
+
+	for _, tc := range tests {
+		r := httptest.NewRequest(tc.method, "/node", nil)
+		if tc.token != nil {
+			r.Header.Set("Authorization", "Bearer "+string(tc.token))
+		}
+		w := httptest.NewRecorder()
+		a.authorize(tc.access, ok)(w, r)
+		if w.Code != tc.code {
+			t.Errorf("%v with token %v: expected %v, got %v", tc.method, string(tc.token), tc.code, w.Code)
+		}
+	}
+}
+
+func Test_APIAuth_unix_socket(t *testing.T) {
+	cfg, dir := getTestAuthConfig(t)
+	defer os.RemoveAll(dir)
+
+	aa, err := newAPIAuthenticator(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	a := &API{auth: aa}
+
+	socket := path.Join(dir, "run", "anax.sock")
+	l, err := listenUnixSocket(socket)
+	if err != nil {
+		t.Fatalf("unable to listen on %v, error %v", socket, err)
+	}
+
+	server := &http.Server{
+		Handler: a.authorize(readOnlyAccess, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}),
+		ConnContext: peerCredentialsContext,
+	}
+	go server.Serve(l)
+	defer server.Close()
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socket)
+			},
+		},
+	}
+
+	// The test process is a socket peer, so it can read without a token on linux.
+	if resp, err := client.Get("http://localhost/status"); err != nil {
+		t.Errorf("unexpected error %v", err)
+	} else if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected %v for a socket peer, got %v", http.StatusOK, resp.StatusCode)
+	}
+
+	// A non-admin peer needs the admin token to change the API.
+	req, _ := http.NewRequest(http.MethodPost, "http://localhost/status", strings.NewReader("{}"))
+	req.Header.Set("Authorization", "Bearer "+string(aa.adminToken))
+	if resp, err := client.Do(req); err != nil {
+		t.Errorf("unexpected error %v", err)
+	} else if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected %v with the admin token, got %v", http.StatusOK, resp.StatusCode)
+	}
+}
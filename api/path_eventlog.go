@@ -1,15 +1,30 @@
 package api
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/boltdb/bolt"
 	"github.com/golang/glog"
 	"github.com/open-horizon/anax/eventlog"
 	"github.com/open-horizon/anax/persistence"
 	"golang.org/x/text/message"
+	"net/http"
 	"sort"
+	"strconv"
+	"time"
 )
 
+// The number of new event logs that can be queued for a stream before records are dropped.
+const EVENTLOG_STREAM_BUFFER_SIZE = 100
+
+// The number of seconds between keep alive lines on an idle stream.
+const EVENTLOG_STREAM_KEEPALIVE_S = 30
+
+// The content types of the event log stream.
+const EVENTLOG_STREAM_SSE = "text/event-stream"
+const EVENTLOG_STREAM_JSON_LINES = "application/x-ndjson"
+
 // This API returns the event logs saved on the db.
 func FindEventLogsForOutput(db *bolt.DB, all_logs bool, selections map[string][]string, msgPrinter *message.Printer) ([]persistence.EventLog, error) {
 
@@ -32,6 +47,102 @@ func FindEventLogsForOutput(db *bolt.DB, all_logs bool, selections map[string][]
 	}
 }
 
+// This API writes the event logs that match the selections to the given writer, then keeps writing the new event logs
+// received on the subscription that match the selections until the context is done. An error is only returned if
+// nothing has been written yet.
+func StreamEventLogsForOutput(ctx context.Context, w http.ResponseWriter, db *bolt.DB, all_logs bool, selections map[string][]string, sse bool, sub *persistence.EventLogSubscription, msgPrinter *message.Printer) error {
+
+	s, err := persistence.ConvertToSelectors(selections)
+	if err != nil {
+		return fmt.Errorf(msgPrinter.Sprintf("Error converting the selections into Selectors: %v", err))
+	}
+
+	existing, err := FindEventLogsForOutput(db, all_logs, selections, msgPrinter)
+	if err != nil {
+		return err
+	}
+
+	if sse {
+		w.Header().Set("Content-Type", EVENTLOG_STREAM_SSE)
+	} else {
+		w.Header().Set("Content-Type", EVENTLOG_STREAM_JSON_LINES)
+	}
+	w.WriteHeader(http.StatusOK)
+
+	// The records are sorted by record id, remember the last one so that records that are both in the db
+	// and on the subscription are only sent once.
+	lastId := uint64(0)
+	for _, el := range existing {
+		if err := writeEventLogToStream(w, el, sse); err != nil {
+			glog.V(3).Infof(apiLogString(fmt.Sprintf("Event log stream closed, error %v", err)))
+			return nil
+		}
+		if id, err := strconv.ParseUint(el.Id, 10, 64); err == nil && id > lastId {
+			lastId = id
+		}
+	}
+	w.(http.Flusher).Flush()
+
+	keepAlive := time.NewTicker(EVENTLOG_STREAM_KEEPALIVE_S * time.Second)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			glog.V(5).Infof(apiLogString("Event log stream client disconnected."))
+			return nil
+
+		case <-keepAlive.C:
+			keepAliveLine := "\n"
+			if sse {
+				keepAliveLine = ":\n\n"
+			}
+			if _, err := w.Write([]byte(keepAliveLine)); err != nil {
+				return nil
+			}
+			w.(http.Flusher).Flush()
+
+		case el, ok := <-sub.C:
+			if !ok {
+				return nil
+			}
+			if id, err := strconv.ParseUint(el.Id, 10, 64); err == nil && id <= lastId {
+				continue
+			}
+
+			// Translate the message the same way as the event logs read from the db, before matching the selectors.
+			if el.MessageMeta != nil && el.MessageMeta.MessageKey != "" {
+				el.Message = msgPrinter.Sprintf(el.MessageMeta.MessageKey, el.MessageMeta.MessageArgs...)
+				el.MessageMeta = nil
+			}
+			if !el.Matches(s) {
+				continue
+			}
+
+			if err := writeEventLogToStream(w, el, sse); err != nil {
+				glog.V(3).Infof(apiLogString(fmt.Sprintf("Event log stream closed, error %v", err)))
+				return nil
+			}
+			w.(http.Flusher).Flush()
+		}
+	}
+}
+
+// Write one event log to the stream, as a json line or as a server-sent event.
+func writeEventLogToStream(w http.ResponseWriter, el persistence.EventLog, sse bool) error {
+	serial, err := json.Marshal(el)
+	if err != nil {
+		return err
+	}
+
+	if sse {
+		_, err = fmt.Fprintf(w, "id: %v\ndata: %s\n\n", el.Id, serial)
+	} else {
+		_, err = fmt.Fprintf(w, "%s\n", serial)
+	}
+	return err
+}
+
 func FindSurfaceLogsForOutput(db *bolt.DB, msgPrinter *message.Printer) ([]persistence.SurfaceError, error) {
 	outputLogs := make([]persistence.SurfaceError, 0)
 	surfaceLogs, err := persistence.FindSurfaceErrors(db)
@@ -3,13 +3,18 @@
 package api
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"flag"
 	"github.com/open-horizon/anax/eventlog"
 	"github.com/open-horizon/anax/i18n"
 	"github.com/open-horizon/anax/persistence"
 	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
 	"strconv"
 	"testing"
+	"time"
 )
 
 func init() {
@@ -115,3 +120,63 @@ func Test_FindEventLogsForOutput(t *testing.T) {
 	}
 
 }
+
+func Test_StreamEventLogsForOutput(t *testing.T) {
+
+	dir, db, err := utsetup()
+	if err != nil {
+		t.Error(err)
+	}
+	defer cleanTestDir(dir)
+
+	msgPrinter := i18n.GetMessagePrinterWithLocale("en")
+
+	sp1 := persistence.ServiceSpec{Url: "http://sensor1.org", Org: "sensor1"}
+	wl1 := persistence.WorkloadInfo{"http://top1.com", "myorg", "1.0.0", "amd64"}
+
+	// save an event log before the stream starts
+	if err := eventlog.LogAgreementEvent2(db, persistence.SEVERITY_INFO, persistence.NewMessageMeta("proposal received."), persistence.EC_RECEIVED_PROPOSAL, "agreementId1", wl1, []persistence.ServiceSpec{sp1}, "consumerId", "Basic"); err != nil {
+		t.Errorf("error saving event log: %v", err)
+	}
+
+	sub := persistence.SubscribeEventLogs(EVENTLOG_STREAM_BUFFER_SIZE)
+	defer sub.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w := httptest.NewRecorder()
+	done := make(chan error)
+	go func() {
+		done <- StreamEventLogsForOutput(ctx, w, db, false, map[string][]string{"message": {"~received"}}, false, sub, msgPrinter)
+	}()
+
+	// save event logs while the stream is running, only the first one matches the selection
+	if err := eventlog.LogAgreementEvent2(db, persistence.SEVERITY_INFO, persistence.NewMessageMeta("reply received."), persistence.EC_RECEIVED_REPLYACK_MESSAGE, "agreementId1", wl1, []persistence.ServiceSpec{sp1}, "consumerId", "Basic"); err != nil {
+		t.Errorf("error saving event log: %v", err)
+	} else if err := eventlog.LogAgreementEvent2(db, persistence.SEVERITY_INFO, persistence.NewMessageMeta("agreement finalized."), persistence.EC_AGREEMENT_REACHED, "agreementId1", wl1, []persistence.ServiceSpec{sp1}, "consumerId", "Basic"); err != nil {
+		t.Errorf("error saving event log: %v", err)
+	}
+
+	time.Sleep(500 * time.Millisecond)
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("error streaming event logs: %v", err)
+	}
+
+	assert.Equal(t, EVENTLOG_STREAM_JSON_LINES, w.Header().Get("Content-Type"), "The stream should be json lines.")
+
+	lines := bytes.Split(bytes.TrimSpace(w.Body.Bytes()), []byte("\n"))
+	if assert.Equal(t, 2, len(lines), "The stream should have the existing and the new matching event log.") {
+		var el persistence.EventLogRaw
+		if err := json.Unmarshal(lines[0], &el); err != nil {
+			t.Errorf("error demarshalling event log %s: %v", lines[0], err)
+		} else {
+			assert.Equal(t, "1", el.Id, "The existing event log should be first.")
+		}
+		if err := json.Unmarshal(lines[1], &el); err != nil {
+			t.Errorf("error demarshalling event log %s: %v", lines[1], err)
+		} else {
+			assert.Equal(t, "2", el.Id, "The new event log should be second.")
+			assert.Equal(t, "reply received.", el.Message, "The new event log message should be translated.")
+		}
+	}
+}
@@ -408,3 +408,21 @@ func DeleteHorizonDevice(removeNode string,
 	return false
 
 }
+
+// Handles the POST verb on the node/messagingkey resource. The rotation of the messaging key is done by the governance
+// worker, in the background.
+func RotateNodeMessagingKey(errorhandler ErrorHandler, msgQueue chan events.Message, db *bolt.DB) bool {
+
+	pDevice, err := persistence.FindExchangeDevice(db)
+	if err != nil {
+		return errorhandler(NewSystemError(fmt.Sprintf("Unable to read node object, error %v", err)))
+	} else if pDevice == nil {
+		return errorhandler(NewNotFoundError("The node is not registered.", "node"))
+	} else if !pDevice.IsState(persistence.CONFIGSTATE_CONFIGURED) {
+		return errorhandler(NewBadRequestError(fmt.Sprintf("INVALID_NODE_STATE. The node must be in configured state in order to rotate its messaging key.")))
+	}
+
+	glog.V(3).Infof(apiLogString(fmt.Sprintf("requesting a rotation of the messaging key of node %v/%v", pDevice.Org, pDevice.Id)))
+	msgQueue <- events.NewMessageKeyRotateMessage(events.ROTATE_MESSAGE_KEY)
+	return false
+}
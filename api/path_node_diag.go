@@ -0,0 +1,58 @@
+package api
+
+import (
+	"github.com/boltdb/bolt"
+	"github.com/open-horizon/anax/apicommon"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/diag"
+	"github.com/open-horizon/anax/policy"
+	"golang.org/x/text/message"
+	"io"
+)
+
+// The file with the environment variables of the agent, it sets the exchange url and the node credentials.
+const HORIZON_DEFAULTS_FILE = "/etc/default/horizon"
+
+// Collect the diagnostics of the node into a gzipped tar bundle, the same bundle that hzn node diag collects through
+// the agent API. Each item that cannot be collected is recorded in the manifest of the bundle instead of failing the
+// whole bundle. Tokens, secrets and the values of the user inputs marked sensitive are redacted.
+func WriteNodeDiagBundle(w io.Writer,
+	pm *policy.PolicyManager,
+	db *bolt.DB,
+	cfg *config.HorizonConfig,
+	info *apicommon.Info,
+	exchangeToken string,
+	msgPrinter *message.Printer) error {
+
+	b := diag.NewBundle(w, "GET /node/diag")
+	b.Redactor.AddSecrets(exchangeToken)
+
+	// The service definitions are added first, because they mark the user inputs that are sensitive.
+	addDiagItem(b, diag.SERVICES_FILE, "GET /service", func() (interface{}, error) { return FindServicesForOutput(pm, db, cfg) })
+	addDiagItem(b, diag.NODE_FILE, "GET /node", func() (interface{}, error) { return FindHorizonDeviceForOutput(db) })
+	b.AddJson(diag.STATUS_FILE, "GET /status", info)
+	addDiagItem(b, diag.AGREEMENTS_FILE, "GET /agreement", func() (interface{}, error) { return FindAgreementsForOutput(db) })
+	addDiagItem(b, diag.EVENTLOGS_FILE, "GET /eventlog/all", func() (interface{}, error) {
+		return FindEventLogsForOutput(db, true, map[string][]string{}, msgPrinter)
+	})
+	addDiagItem(b, diag.SURFACE_ERRORS_FILE, "GET /eventlog/surface", func() (interface{}, error) { return FindSurfaceLogsForOutput(db, msgPrinter) })
+	addDiagItem(b, diag.SERVICE_CONFIG_FILE, "GET /service/config", func() (interface{}, error) { return FindServiceConfigForOutput(pm, db) })
+	addDiagItem(b, diag.NODE_POLICY_FILE, "GET /node/policy", func() (interface{}, error) { return FindNodePolicyForOutput(db) })
+	addDiagItem(b, diag.NODE_USERINPUT_FILE, "GET /node/userinput", func() (interface{}, error) { return FindNodeUserInputForOutput(db) })
+
+	b.AddJson(diag.ANAX_CONFIG_FILE, "agent configuration", cfg)
+	diag.AddConfigFile(b, diag.HORIZON_DEFAULTS, HORIZON_DEFAULTS_FILE)
+	b.AddJson(diag.ESS_STATUS_FILE, "ESS", diag.GetESSStatus(cfg))
+	diag.AddDockerContainers(b, cfg.Edge.DockerEndpoint)
+	diag.AddAgentLog(b, cfg.Edge.DockerEndpoint)
+
+	return b.Close()
+}
+
+func addDiagItem(b *diag.Bundle, name string, source string, find func() (interface{}, error)) {
+	if out, err := find(); err != nil {
+		b.AddError(name, source, err)
+	} else {
+		b.AddJson(name, source, out)
+	}
+}
@@ -851,3 +851,51 @@ func getExchangeDevice(pattern string) exchange.DeviceHandler {
 		}, nil
 	}
 }
+
+// A rotation of the messaging key is handed to the governance worker.
+func Test_RotateNodeMessagingKey(t *testing.T) {
+
+	dir, db, err := utsetup()
+	if err != nil {
+		t.Error(err)
+	}
+	defer cleanTestDir(dir)
+
+	var myError error
+	errorhandler := GetPassThroughErrorHandler(&myError)
+	msgQueue := make(chan events.Message, 10)
+
+	// The node is not registered.
+	if errHandled := RotateNodeMessagingKey(errorhandler, msgQueue, db); !errHandled {
+		t.Errorf("expected an error for an unregistered node")
+	} else if _, ok := myError.(*NotFoundError); !ok {
+		t.Errorf("expected a NotFoundError, got %v", myError)
+	}
+
+	device := getBasicDevice("testOrg", "testPattern")
+	pDevice, err := persistence.SaveNewExchangeDevice(db, *device.Id, *device.Token, *device.Name, "device", false, *device.Org, *device.Pattern, persistence.CONFIGSTATE_CONFIGURING)
+	if err != nil {
+		t.Errorf("unexpected error creating device %v", err)
+	}
+
+	// The node is not configured yet.
+	myError = nil
+	if errHandled := RotateNodeMessagingKey(errorhandler, msgQueue, db); !errHandled {
+		t.Errorf("expected an error for a node that is not configured")
+	} else if _, ok := myError.(*BadRequestError); !ok {
+		t.Errorf("expected a BadRequestError, got %v", myError)
+	}
+
+	if _, err := pDevice.SetConfigstate(db, *device.Id, persistence.CONFIGSTATE_CONFIGURED); err != nil {
+		t.Errorf("unexpected error setting the device state %v", err)
+	}
+
+	myError = nil
+	if errHandled := RotateNodeMessagingKey(errorhandler, msgQueue, db); errHandled {
+		t.Errorf("unexpected error %v", myError)
+	} else if len(msgQueue) != 1 {
+		t.Errorf("there should be a message on the queue")
+	} else if msg, ok := (<-msgQueue).(*events.MessageKeyRotateMessage); !ok || msg.Event().Id != events.ROTATE_MESSAGE_KEY {
+		t.Errorf("unexpected message on the queue %v", msg)
+	}
+}
@@ -0,0 +1,14 @@
+// +build !linux
+
+package api
+
+import (
+	"errors"
+	"net"
+)
+
+// Peer credentials are only supported on linux. Socket peers on other platforms have no role, so they need a
+// bearer token.
+func getPeerCredentials(c *net.UnixConn) (*peerCredentials, error) {
+	return nil, errors.New("unix socket peer credentials are not supported on this platform")
+}
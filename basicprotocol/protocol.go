@@ -365,6 +365,7 @@ const AB_USER_REQUESTED = 206
 const AB_CANCEL_FORCED_UPGRADE = 207
 const AB_CANCEL_NODE_HEARTBEAT = 208
 const AB_CANCEL_AG_MISSING = 209
+const AB_CANCEL_POLICY_EXPIRED = 210
 
 // const AB_CANCEL_BC_WRITE_FAILED       = 208  // xd0
 
@@ -401,7 +402,8 @@ func DecodeReasonCode(code uint64) string {
 		AB_CANCEL_FORCED_UPGRADE:   "agreement bot user requested service upgrade",
 		// AB_CANCEL_BC_WRITE_FAILED:   "agreement bot agreement write failed"}
 		AB_CANCEL_NODE_HEARTBEAT: "agreement bot detected node heartbeat stopped",
-		AB_CANCEL_AG_MISSING:     "agreement bot detected agreement missing from node"}
+		AB_CANCEL_AG_MISSING:     "agreement bot detected agreement missing from node",
+		AB_CANCEL_POLICY_EXPIRED: "agreement bot deployment policy expired"}
 
 	if reasonString, ok := codeMeanings[code]; !ok {
 		return "unknown reason code, device might be downlevel"
@@ -3,10 +3,12 @@ package businesspolicy
 import (
 	"fmt"
 	"github.com/golang/glog"
+	"github.com/open-horizon/anax/datetime"
 	"github.com/open-horizon/anax/externalpolicy"
 	"github.com/open-horizon/anax/i18n"
 	"github.com/open-horizon/anax/policy"
 	"strings"
+	"time"
 )
 
 const DEFAULT_MAX_AGREEMENT = 0
@@ -20,17 +22,21 @@ type BusinessPolicy struct {
 	Properties  externalpolicy.PropertyList         `json:"properties,omitempty"`
 	Constraints externalpolicy.ConstraintExpression `json:"constraints,omitempty"`
 	UserInput   []policy.UserInput                  `json:"userInput,omitempty"`
+	ActiveFrom  string                              `json:"activeFrom,omitempty"`  // the date when agreements can start to be made, e.g. 2026-01-01T08:00:00Z
+	ActiveUntil string                              `json:"activeUntil,omitempty"` // the date when the policy expires and its agreements are cancelled
 }
 
 func (w BusinessPolicy) String() string {
-	return fmt.Sprintf("Owner: %v, Label: %v, Description: %v, Service: %v, Properties: %v, Constraints: %v, UserInput: %v",
+	return fmt.Sprintf("Owner: %v, Label: %v, Description: %v, Service: %v, Properties: %v, Constraints: %v, UserInput: %v, ActiveFrom: %v, ActiveUntil: %v",
 		w.Owner,
 		w.Label,
 		w.Description,
 		w.Service,
 		w.Properties,
 		w.Constraints,
-		w.UserInput)
+		w.UserInput,
+		w.ActiveFrom,
+		w.ActiveUntil)
 }
 
 type ServiceRef struct {
@@ -136,6 +142,11 @@ func (b *BusinessPolicy) Validate() error {
 		}
 	}
 
+	// Validate the time window in which the policy is active.
+	if _, _, err := b.GetActiveWindow(); err != nil {
+		return err
+	}
+
 	// Validate the Constraints expression by invoking the plugins.
 	if b != nil && len(b.Constraints) != 0 {
 		_, err := b.Constraints.Validate()
@@ -146,6 +157,31 @@ func (b *BusinessPolicy) Validate() error {
 	return nil
 }
 
+// Returns the dates from which and until which the policy is active. A date is zero when the policy does not
+// have it, which means that the policy is active from the time it is created or that it never expires.
+func (b *BusinessPolicy) GetActiveWindow() (time.Time, time.Time, error) {
+
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	var from, until time.Time
+	var err error
+	if strings.TrimSpace(b.ActiveFrom) != "" {
+		if from, err = datetime.ParseDate(b.ActiveFrom); err != nil {
+			return from, until, fmt.Errorf(msgPrinter.Sprintf("activeFrom is not valid: %v", err))
+		}
+	}
+	if strings.TrimSpace(b.ActiveUntil) != "" {
+		if until, err = datetime.ParseDate(b.ActiveUntil); err != nil {
+			return from, until, fmt.Errorf(msgPrinter.Sprintf("activeUntil is not valid: %v", err))
+		}
+	}
+	if !from.IsZero() && !until.IsZero() && !until.After(from) {
+		return from, until, fmt.Errorf(msgPrinter.Sprintf("activeUntil %v must be later than activeFrom %v.", b.ActiveUntil, b.ActiveFrom))
+	}
+	return from, until, nil
+}
+
 // Check if there is no contraints or not
 func (b *BusinessPolicy) HasNoConstraints() bool {
 	if b.Constraints == nil || len(b.Constraints) == 0 {
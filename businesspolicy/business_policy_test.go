@@ -320,3 +320,60 @@ func Test_GenPolicyFromBusinessPolicy_Complicated(t *testing.T) {
 		t.Errorf("Second user input variable value for service cpu should be val2 but got %v.", pPolicy.UserInput[0].Inputs[1].Value)
 	}
 }
+
+// active window
+func Test_Validate_ActiveWindow(t *testing.T) {
+
+	service := ServiceRef{
+		Name:            "cpu",
+		Org:             "mycomp",
+		Arch:            "amd64",
+		ServiceVersions: []WorkloadChoice{WorkloadChoice{Version: "1.0.0"}},
+	}
+
+	bPolicy := BusinessPolicy{
+		Owner:       "me",
+		Label:       "my business policy",
+		Service:     service,
+		ActiveFrom:  "2026-01-01T08:00:00Z",
+		ActiveUntil: "2026-02-01",
+	}
+
+	if err := bPolicy.Validate(); err != nil {
+		t.Errorf("Validate should have not have returned error but got: %v", err)
+	} else if from, until, err := bPolicy.GetActiveWindow(); err != nil {
+		t.Errorf("GetActiveWindow should have not have returned error but got: %v", err)
+	} else if from.Unix() != 1767254400 || until.Unix() != 1769904000 {
+		t.Errorf("wrong active window %v, %v", from, until)
+	}
+
+	bPolicy.ActiveFrom = ""
+	if from, until, err := bPolicy.GetActiveWindow(); err != nil {
+		t.Errorf("GetActiveWindow should have not have returned error but got: %v", err)
+	} else if !from.IsZero() || until.Unix() != 1769904000 {
+		t.Errorf("policy without activeFrom should only have an end, the window is %v, %v", from, until)
+	}
+
+	bPolicy.ActiveUntil = ""
+	if from, until, err := bPolicy.GetActiveWindow(); err != nil {
+		t.Errorf("GetActiveWindow should have not have returned error but got: %v", err)
+	} else if !from.IsZero() || !until.IsZero() {
+		t.Errorf("active window should be unset but got %v, %v", from, until)
+	}
+
+	bPolicy.ActiveFrom = "2026-02-01"
+	bPolicy.ActiveUntil = "2026-01-01"
+	if err := bPolicy.Validate(); err == nil {
+		t.Errorf("Validate should have returned error but not.")
+	} else if !strings.Contains(err.Error(), "must be later than activeFrom") {
+		t.Errorf("Wrong error string: %v", err)
+	}
+
+	bPolicy.ActiveFrom = "next tuesday"
+	bPolicy.ActiveUntil = ""
+	if err := bPolicy.Validate(); err == nil {
+		t.Errorf("Validate should have returned error but not.")
+	} else if !strings.Contains(err.Error(), "activeFrom is not valid") {
+		t.Errorf("Wrong error string: %v", err)
+	}
+}
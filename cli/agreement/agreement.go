@@ -1,8 +1,6 @@
 package agreement
 
 import (
-	"encoding/json"
-	"fmt"
 	"github.com/open-horizon/anax/cli/cliutils"
 	"github.com/open-horizon/anax/i18n"
 	"github.com/open-horizon/anax/persistence"
@@ -93,6 +91,33 @@ func GetAgreements(archivedAgreements bool) (apiAgreements []persistence.Establi
 	return
 }
 
+// The table output formats of 'hzn agreement list'.
+var agreementTable = &cliutils.TableFormat{Columns: []cliutils.TableColumn{
+	{Header: "AGREEMENT ID", Path: ".current_agreement_id"},
+	{Header: "SERVICE", Path: ".workload_to_run.url"},
+	{Header: "ORG", Path: ".workload_to_run.org"},
+	{Header: "VERSION", Path: ".workload_to_run.version"},
+	{Header: "STARTED", Path: ".agreement_execution_start_time"},
+	{Header: "NAME", Path: ".name", Wide: true},
+	{Header: "ARCH", Path: ".workload_to_run.arch", Wide: true},
+	{Header: "CONSUMER", Path: ".consumer_id", Wide: true},
+	{Header: "CREATED", Path: ".agreement_creation_time", Wide: true},
+	{Header: "FINALIZED", Path: ".agreement_finalized_time", Wide: true},
+}}
+
+var archivedAgreementTable = &cliutils.TableFormat{Columns: []cliutils.TableColumn{
+	{Header: "AGREEMENT ID", Path: ".current_agreement_id"},
+	{Header: "SERVICE", Path: ".workload_to_run.url"},
+	{Header: "VERSION", Path: ".workload_to_run.version"},
+	{Header: "TERMINATED", Path: ".agreement_terminated_time"},
+	{Header: "REASON", Path: ".terminated_description"},
+	{Header: "ORG", Path: ".workload_to_run.org", Wide: true},
+	{Header: "ARCH", Path: ".workload_to_run.arch", Wide: true},
+	{Header: "CONSUMER", Path: ".consumer_id", Wide: true},
+	{Header: "CREATED", Path: ".agreement_creation_time", Wide: true},
+	{Header: "STARTED", Path: ".agreement_execution_start_time", Wide: true},
+}}
+
 func List(archivedAgreements bool, agreementId string) {
 	apiAgreements := GetAgreements(archivedAgreements)
 
@@ -104,11 +129,7 @@ func List(archivedAgreements bool, agreementId string) {
 		for i := range apiAgreements {
 			if agreementId == apiAgreements[i].CurrentAgreementId {
 				// Found it
-				jsonBytes, err := json.MarshalIndent(apiAgreements[i], "", cliutils.JSON_INDENT)
-				if err != nil {
-					cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal agreement with index %d: %v", i, err))
-				}
-				fmt.Printf("%s\n", jsonBytes)
+				cliutils.PrintOutput(apiAgreements[i], nil, "agreement list")
 				return
 			}
 		}
@@ -121,22 +142,14 @@ func List(archivedAgreements bool, agreementId string) {
 			for i := range apiAgreements {
 				agreements[i].CopyAgreementInto(apiAgreements[i])
 			}
-			jsonBytes, err := json.MarshalIndent(agreements, "", cliutils.JSON_INDENT)
-			if err != nil {
-				cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'hzn agreement list' output: %v", err))
-			}
-			fmt.Printf("%s\n", jsonBytes)
+			cliutils.PrintOutput(agreements, agreementTable, "agreement list")
 		} else {
 			// Archived agreements
 			agreements := make([]ArchivedAgreement, len(apiAgreements))
 			for i := range apiAgreements {
 				agreements[i].CopyAgreementInto(apiAgreements[i])
 			}
-			jsonBytes, err := json.MarshalIndent(agreements, "", cliutils.JSON_INDENT)
-			if err != nil {
-				cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'hzn agreement list' output: %v", err))
-			}
-			fmt.Printf("%s\n", jsonBytes)
+			cliutils.PrintOutput(agreements, archivedAgreementTable, "agreement list")
 		}
 	}
 }
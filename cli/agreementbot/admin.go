@@ -0,0 +1,142 @@
+package agreementbot
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/open-horizon/anax/agreementbot/persistence"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"github.com/open-horizon/anax/i18n"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// The agbot admin API requires the credentials of an exchange admin user. The user is prepended with the org of the resource
+// being administered, unless it already has an org.
+func adminCreds(org string, userPw string) string {
+	userPw = *cliutils.WithDefaultEnvVar(&userPw, "HZN_EXCHANGE_USER_AUTH")
+	if userPw == "" {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, i18n.GetMessagePrinter().Sprintf("exchange user authentication must be specified with the -u flag or HZN_EXCHANGE_USER_AUTH"))
+	}
+	cliutils.SetWhetherUsingApiKey(userPw)
+	return cliutils.OrgAndCreds(org, userPw)
+}
+
+// Split an org/name id, the org defaults to HZN_ORG_ID.
+func adminOrgAndName(id string) (string, string) {
+	org, name := cliutils.TrimOrg(os.Getenv("HZN_ORG_ID"), id)
+	if org == "" {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, i18n.GetMessagePrinter().Sprintf("the organization of %v must be specified as org/name or with HZN_ORG_ID", id))
+	}
+	return org, name
+}
+
+func adminPolicyType(pattern bool) string {
+	if pattern {
+		return persistence.PAUSED_PATTERN
+	}
+	return persistence.PAUSED_DEPLOYMENT_POLICY
+}
+
+// Call the agbot admin API and exit with the error returned by the agbot if the HTTP code is not one of the good codes.
+func invokeAgbotAdmin(method string, urlSuffix string, creds string, goodHttpCodes []int, structure interface{}) int {
+	// The admin APIs are only served by the agbot secure API, so that the credentials are not sent in the clear.
+	urlBase := cliutils.GetAgbotSecureAPIUrlBase()
+	if urlBase == "" {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, i18n.GetMessagePrinter().Sprintf("the agbot secure API URL must be specified with HZN_AGBOT_SECURE_API"))
+	} else if !strings.HasPrefix(strings.ToLower(urlBase), "https://") {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, i18n.GetMessagePrinter().Sprintf("the agbot secure API URL %v must be an https URL", urlBase))
+	}
+
+	var body []byte
+	var httpCode int
+	if method == http.MethodGet {
+		httpCode = cliutils.ExchangeGet("Agbot", urlBase, urlSuffix, creds, []int{}, &body)
+	} else {
+		httpCode = cliutils.ExchangePutPost("Agbot", method, urlBase, urlSuffix, creds, []int{}, nil, &body)
+	}
+
+	if cliutils.IsDryRun() {
+		return httpCode
+	}
+
+	for _, code := range goodHttpCodes {
+		if code == httpCode {
+			if structure != nil && len(body) > 0 {
+				if err := json.Unmarshal(body, structure); err != nil {
+					cliutils.Fatal(cliutils.JSON_PARSING_ERROR, i18n.GetMessagePrinter().Sprintf("failed to unmarshal agbot response from %v %v: %v", method, urlSuffix, err))
+				}
+			}
+			return httpCode
+		}
+	}
+
+	// The agbot returns either a message or an input error.
+	msg := string(body)
+	var inputErr struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &inputErr); err == nil && inputErr.Error != "" {
+		msg = inputErr.Error
+	} else {
+		json.Unmarshal(body, &msg)
+	}
+	cliutils.Fatal(cliutils.HTTP_ERROR, i18n.GetMessagePrinter().Sprintf("bad HTTP code %d from %v %v: %v", httpCode, method, urlSuffix, msg))
+	return httpCode
+}
+
+// Pause agreement-making for a deployment policy or pattern.
+func PolicyPause(id string, pattern bool, userPw string) {
+	org, name := adminOrgAndName(id)
+	var pp persistence.PausedPolicy
+	invokeAgbotAdmin(http.MethodPost, fmt.Sprintf("admin/pause/%v/%v/%v", adminPolicyType(pattern), org, name), adminCreds(org, userPw), []int{200}, &pp)
+	i18n.GetMessagePrinter().Printf("Agreement-making for %v %v/%v is paused.", pp.Type, org, name)
+	i18n.GetMessagePrinter().Println()
+}
+
+// Resume agreement-making for a deployment policy or pattern.
+func PolicyResume(id string, pattern bool, userPw string) {
+	org, name := adminOrgAndName(id)
+	invokeAgbotAdmin(http.MethodDelete, fmt.Sprintf("admin/pause/%v/%v/%v", adminPolicyType(pattern), org, name), adminCreds(org, userPw), []int{204}, nil)
+	i18n.GetMessagePrinter().Printf("Agreement-making for %v %v/%v is resumed.", adminPolicyType(pattern), org, name)
+	i18n.GetMessagePrinter().Println()
+}
+
+// List the paused deployment policies and patterns.
+func PolicyPausedList(userPw string) {
+	paused := make([]persistence.PausedPolicy, 0)
+	invokeAgbotAdmin(http.MethodGet, "admin/pause", adminCreds(os.Getenv("HZN_ORG_ID"), userPw), []int{200}, &paused)
+	cliutils.PrintOutput(paused, nil, "agbot policy paused")
+}
+
+// Cancel all the agreements made with a deployment policy or pattern.
+func PolicyCancelAgreements(id string, pattern bool, userPw string) {
+	org, name := adminOrgAndName(id)
+	cancelled := make(map[string][]string)
+	invokeAgbotAdmin(http.MethodPost, fmt.Sprintf("admin/cancel/%v/%v/%v", adminPolicyType(pattern), org, name), adminCreds(org, userPw), []int{200}, &cancelled)
+	i18n.GetMessagePrinter().Printf("Canceled %v agreements with %v %v/%v.", len(cancelled["agreements"]), adminPolicyType(pattern), org, name)
+	i18n.GetMessagePrinter().Println()
+}
+
+// Ask the agbot to re-evaluate a node against the policies and patterns it serves.
+func NodeReevaluate(id string, userPw string) {
+	org, node := adminOrgAndName(id)
+	invokeAgbotAdmin(http.MethodPost, fmt.Sprintf("admin/reevaluate/%v/%v", org, node), adminCreds(org, userPw), []int{202}, nil)
+	i18n.GetMessagePrinter().Printf("The agbot is re-evaluating node %v/%v.", org, node)
+	i18n.GetMessagePrinter().Println()
+}
+
+// Drain the agbot. It stops making agreements, and terminates once its agreements are settled and its partition has been
+// given to the other agbots.
+func Drain(userPw string) {
+	invokeAgbotAdmin(http.MethodPost, "admin/drain", adminCreds(os.Getenv("HZN_ORG_ID"), userPw), []int{202}, nil)
+	i18n.GetMessagePrinter().Printf("The agbot is draining.")
+	i18n.GetMessagePrinter().Println()
+}
+
+// Rotate the messaging key of the agbot. The agbot publishes the new public key to the exchange.
+func RotateMessagingKey(userPw string) {
+	invokeAgbotAdmin(http.MethodPost, "admin/messagingkey", adminCreds(os.Getenv("HZN_ORG_ID"), userPw), []int{202}, nil)
+	i18n.GetMessagePrinter().Printf("The agbot is rotating its messaging key.")
+	i18n.GetMessagePrinter().Println()
+}
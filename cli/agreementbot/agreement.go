@@ -1,8 +1,6 @@
 package agreementbot
 
 import (
-	"encoding/json"
-	"fmt"
 	agbot "github.com/open-horizon/anax/agreementbot/persistence"
 	"github.com/open-horizon/anax/cli/cliutils"
 	"github.com/open-horizon/anax/i18n"
@@ -91,10 +89,30 @@ func getAgreements(archivedAgreements bool) (apiAgreements []agbot.Agreement) {
 	return
 }
 
-func AgreementList(archivedAgreements bool, agreement string) {
-	// get message printer
-	msgPrinter := i18n.GetMessagePrinter()
+// The table output formats of 'hzn agbot agreement list'.
+var agbotAgreementTable = &cliutils.TableFormat{Columns: []cliutils.TableColumn{
+	{Header: "AGREEMENT ID", Path: ".current_agreement_id"},
+	{Header: "NODE", Path: ".edge_node_id"},
+	{Header: "POLICY", Path: ".policy_name"},
+	{Header: "PATTERN", Path: ".pattern"},
+	{Header: "FINALIZED", Path: ".agreement_finalized_time"},
+	{Header: "ORG", Path: ".org", Wide: true},
+	{Header: "CREATED", Path: ".agreement_creation_time", Wide: true},
+	{Header: "DATA VERIFIED", Path: ".data_verification_time", Wide: true},
+}}
+
+var agbotArchivedAgreementTable = &cliutils.TableFormat{Columns: []cliutils.TableColumn{
+	{Header: "AGREEMENT ID", Path: ".current_agreement_id"},
+	{Header: "NODE", Path: ".edge_node_id"},
+	{Header: "POLICY", Path: ".policy_name"},
+	{Header: "REASON", Path: ".terminated_description"},
+	{Header: "PATTERN", Path: ".pattern", Wide: true},
+	{Header: "ORG", Path: ".org", Wide: true},
+	{Header: "CREATED", Path: ".agreement_creation_time", Wide: true},
+	{Header: "TIMED OUT", Path: ".agreement_timeout", Wide: true},
+}}
 
+func AgreementList(archivedAgreements bool, agreement string) {
 	apiAgreements := getAgreements(archivedAgreements)
 
 	// Go thru the apiAgreements and convert into our output struct and then print
@@ -103,21 +121,13 @@ func AgreementList(archivedAgreements bool, agreement string) {
 		for i := range apiAgreements {
 			agreements[i] = *NewActiveAgreement(apiAgreements[i])
 		}
-		jsonBytes, err := json.MarshalIndent(agreements, "", cliutils.JSON_INDENT)
-		if err != nil {
-			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'agreement list' output: %v", err))
-		}
-		fmt.Printf("%s\n", jsonBytes)
+		cliutils.PrintOutput(agreements, agbotAgreementTable, "agreement list")
 	} else {
 		agreements := make([]ArchivedAgreement, len(apiAgreements))
 		for i := range apiAgreements {
 			agreements[i] = *NewArchivedAgreement(apiAgreements[i])
 		}
-		jsonBytes, err := json.MarshalIndent(agreements, "", cliutils.JSON_INDENT)
-		if err != nil {
-			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'agreement list' output: %v", err))
-		}
-		fmt.Printf("%s\n", jsonBytes)
+		cliutils.PrintOutput(agreements, agbotArchivedAgreementTable, "agreement list")
 	}
 }
 
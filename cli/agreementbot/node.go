@@ -1,8 +1,6 @@
 package agreementbot
 
 import (
-	"encoding/json"
-	"fmt"
 	"github.com/open-horizon/anax/agreementbot"
 	"github.com/open-horizon/anax/apicommon"
 	"github.com/open-horizon/anax/cli/cliutils"
@@ -33,6 +31,15 @@ func (n *AgbotAndStatus) CopyStatusInto(status *apicommon.Info) {
 	n.Configuration = status.Configuration
 }
 
+// The table output format of 'hzn agbot list'.
+var agbotTable = &cliutils.TableFormat{Columns: []cliutils.TableColumn{
+	{Header: "ID", Path: ".agbot_id"},
+	{Header: "ORG", Path: ".organization"},
+	{Header: "VERSION", Path: ".configuration.horizon_version"},
+	{Header: "EXCHANGE", Path: ".configuration.exchange_api", Wide: true},
+	{Header: "MMS", Path: ".configuration.mms_api", Wide: true},
+}}
+
 func List() {
 	// set env to call agbot url
 	if err := os.Setenv("HORIZON_URL", cliutils.GetAgbotUrlBase()); err != nil {
@@ -51,9 +58,5 @@ func List() {
 	nodeInfo.CopyStatusInto(&status)
 
 	// Output the combined info
-	jsonBytes, err := json.MarshalIndent(nodeInfo, "", cliutils.JSON_INDENT)
-	if err != nil {
-		cliutils.Fatal(cliutils.JSON_PARSING_ERROR, i18n.GetMessagePrinter().Sprintf("failed to marshal 'hzn node list' output: %v", err))
-	}
-	fmt.Printf("%s\n", jsonBytes) //todo: is there a way to output with json syntax highlighting like jq does?
+	cliutils.PrintOutput(nodeInfo, agbotTable, "agbot list")
 }
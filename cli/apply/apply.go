@@ -0,0 +1,335 @@
+package apply
+
+import (
+	"fmt"
+	"github.com/open-horizon/anax/cli/cliconfig"
+	"github.com/open-horizon/anax/cli/cliutils"
+	cliexchange "github.com/open-horizon/anax/cli/exchange"
+	"github.com/open-horizon/anax/common"
+	"github.com/open-horizon/anax/exchange"
+	"github.com/open-horizon/anax/externalpolicy"
+	"github.com/open-horizon/anax/i18n"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// The operations in a plan.
+const OP_CREATE = "create"
+const OP_UPDATE = "update"
+const OP_DELETE = "delete"
+
+// A change that applying the manifests makes in the exchange.
+type Change struct {
+	Op     string
+	Kind   string
+	Id     string      // the exchange id of the resource, without the org
+	Input  interface{} // the resource that is sent to the exchange, nil for a delete
+	PubKey string      // the public key file to store with a service or pattern
+	Svc    *common.ServiceFile
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("Op: %v, Kind: %v, Id: %v", c.Op, c.Kind, c.Id)
+}
+
+// Make the resources in the org match the manifests in the directory. The plan is displayed first, then the changes
+// are made unless dryRun is set. With prune, the resources in the org that are not in a manifest are removed.
+func Apply(org, userPw, dir, keyFilePath, pubKeyFilePath string, dryRun, prune, force, noConstraints bool) {
+
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	cliutils.SetWhetherUsingApiKey(userPw)
+
+	resources := readManifests(dir, org)
+
+	// if the --no-constraints flag is not specified and a deployment policy has no constraints, alert the user.
+	for _, r := range resources {
+		if r.Kind == KIND_DEPLOYMENT_POLICY && !noConstraints && r.DepPolicy.HasNoConstraints() {
+			cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("The deployment policy in %v has no constraints which might result in the service being deployed to all nodes. Please specify --no-constraints to confirm that this is acceptable.", r.File))
+		}
+	}
+
+	changes, unchanged := makePlan(org, userPw, resources, keyFilePath, pubKeyFilePath, prune)
+	deletes := printPlan(org, changes, unchanged)
+
+	if dryRun || len(changes) == 0 {
+		return
+	}
+	if deletes > 0 && !force {
+		cliutils.ConfirmRemove(msgPrinter.Sprintf("Are you sure you want to remove %v resources from organization %v in the Exchange?", deletes, org))
+	}
+
+	for _, c := range changes {
+		execute(org, userPw, c)
+	}
+	msgPrinter.Printf("Applied %v changes to organization %v.", len(changes), org)
+	msgPrinter.Println()
+}
+
+// Read and parse all the manifests in the directory.
+func readManifests(dir string, org string) []*Resource {
+
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	files, err := FindManifestFiles(dir)
+	if err != nil {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("failed to read directory %v: %v", dir, err))
+	} else if len(files) == 0 {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("no manifests found in directory %v.", dir))
+	}
+
+	resources := make([]*Resource, 0, len(files))
+	for _, file := range files {
+		content := cliconfig.ReadJsonFileWithLocalConfig(file)
+		r, err := ParseManifest(file, content, org)
+		if err != nil {
+			cliutils.Fatal(cliutils.CLI_INPUT_ERROR, err.Error())
+		}
+		resources = append(resources, r)
+	}
+
+	if err := CheckDuplicates(resources); err != nil {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, err.Error())
+	}
+	return resources
+}
+
+// Compare the resources with the exchange and return the changes in the order in which they are made, and the
+// number of resources that do not change. The services and patterns are signed, so that they can be compared.
+func makePlan(org, userPw string, resources []*Resource, keyFilePath, pubKeyFilePath string, prune bool) ([]Change, int) {
+
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	exchUrl := cliutils.GetExchangeUrl()
+	creds := cliutils.OrgAndCreds(org, userPw)
+
+	var services exchange.GetServicesResponse
+	cliutils.ExchangeGet("Exchange", exchUrl, "orgs/"+org+"/services", creds, []int{200, 404}, &services)
+	var patterns cliexchange.ExchangePatterns
+	cliutils.ExchangeGet("Exchange", exchUrl, "orgs/"+org+"/patterns", creds, []int{200, 404}, &patterns)
+	var depPols exchange.GetBusinessPolicyResponse
+	cliutils.ExchangeGet("Exchange", exchUrl, "orgs/"+org+"/business/policies", creds, []int{200, 404}, &depPols)
+
+	svcResources := []*Resource{}
+	managed := map[string]bool{}
+	for _, r := range resources {
+		managed[r.Kind+"/"+r.Id] = true
+		if r.Kind == KIND_SERVICE {
+			svcResources = append(svcResources, r)
+		}
+	}
+
+	svcResources, err := OrderServices(svcResources, org)
+	if err != nil {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, err.Error())
+	}
+
+	changes := []Change{}
+	unchanged := 0
+	add := func(op string, kind string, id string, input interface{}, pubKey string, svc *common.ServiceFile) {
+		changes = append(changes, Change{Op: op, Kind: kind, Id: id, Input: input, PubKey: pubKey, Svc: svc})
+	}
+
+	// services and their policies
+	for _, r := range svcResources {
+		msgPrinter.Printf("Signing service %v/%v...", org, r.Id)
+		msgPrinter.Println()
+		svcInput, pubKey := cliexchange.SignService(r.Service, r.File, keyFilePath, pubKeyFilePath, true, false)
+		existing, found := services.Services[org+"/"+r.Id]
+		if !found {
+			add(OP_CREATE, KIND_SERVICE, r.Id, svcInput, pubKey, r.Service)
+		} else if IsChanged(svcInput, existing) {
+			add(OP_UPDATE, KIND_SERVICE, r.Id, svcInput, pubKey, r.Service)
+		} else {
+			unchanged++
+		}
+
+		// the policy of a new service does not exist yet
+		var existingPol exchange.ExchangePolicy
+		polFound := false
+		if found {
+			httpCode := cliutils.ExchangeGet("Exchange", exchUrl, "orgs/"+org+"/services/"+r.Id+"/policy", creds, []int{200, 404}, &existingPol)
+			polFound = httpCode == 200
+		}
+
+		if r.SvcPolicy != nil {
+			svcPol := *r.SvcPolicy
+			svcPol.Properties = append(externalpolicy.PropertyList{}, r.SvcPolicy.Properties...)
+			cliexchange.AddServicePolicyBuiltInProperties(&svcPol, org, r.Service.URL, r.Service.Version, r.Service.Arch)
+			if err := svcPol.ValidateAndNormalize(); err != nil {
+				cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("Incorrect service policy format in %v: %v", r.File, err))
+			}
+			if !polFound {
+				add(OP_CREATE, KIND_SERVICE_POLICY, r.Id, svcPol, "", nil)
+			} else if IsChanged(svcPol, existingPol.GetExternalPolicy()) {
+				add(OP_UPDATE, KIND_SERVICE_POLICY, r.Id, svcPol, "", nil)
+			} else {
+				unchanged++
+			}
+		} else if prune && polFound {
+			add(OP_DELETE, KIND_SERVICE_POLICY, r.Id, nil, "", nil)
+		}
+	}
+
+	// patterns
+	for _, r := range resources {
+		if r.Kind != KIND_PATTERN {
+			continue
+		}
+		patInput, pubKey := cliexchange.SignPattern(r.Pattern, keyFilePath, pubKeyFilePath)
+		existing, found := patterns.Patterns[org+"/"+r.Id]
+		if !found {
+			add(OP_CREATE, KIND_PATTERN, r.Id, patInput, pubKey, nil)
+		} else if IsChanged(patInput, existing, "agreementProtocols", "nodeHealth", "dataVerification") {
+			add(OP_UPDATE, KIND_PATTERN, r.Id, patInput, pubKey, nil)
+		} else {
+			unchanged++
+		}
+	}
+
+	// deployment policies
+	for _, r := range resources {
+		if r.Kind != KIND_DEPLOYMENT_POLICY {
+			continue
+		}
+		existing, found := depPols.BusinessPolicy[org+"/"+r.Id]
+		if !found {
+			add(OP_CREATE, KIND_DEPLOYMENT_POLICY, r.Id, r.DepPolicy, "", nil)
+		} else if IsChanged(r.DepPolicy, existing, "nodeHealth") {
+			add(OP_UPDATE, KIND_DEPLOYMENT_POLICY, r.Id, r.DepPolicy, "", nil)
+		} else {
+			unchanged++
+		}
+	}
+
+	// the resources that are not in a manifest, the deployment policies and patterns are removed before the
+	// services that they refer to.
+	if prune {
+		for _, id := range unmanagedIds(org, KIND_DEPLOYMENT_POLICY, depPols.BusinessPolicy, managed) {
+			add(OP_DELETE, KIND_DEPLOYMENT_POLICY, id, nil, "", nil)
+		}
+		for _, id := range unmanagedIds(org, KIND_PATTERN, patterns.Patterns, managed) {
+			add(OP_DELETE, KIND_PATTERN, id, nil, "", nil)
+		}
+		for _, id := range unmanagedIds(org, KIND_SERVICE, services.Services, managed) {
+			add(OP_DELETE, KIND_SERVICE, id, nil, "", nil)
+		}
+	}
+
+	return changes, unchanged
+}
+
+// Return the sorted ids, without the org, of the exchange resources of a kind that are not in a manifest.
+// The keys of the exchange map are org/id.
+func unmanagedIds(org string, kind string, exchResources interface{}, managed map[string]bool) []string {
+	keys := []string{}
+	switch res := exchResources.(type) {
+	case map[string]exchange.ExchangeBusinessPolicy:
+		for k := range res {
+			keys = append(keys, k)
+		}
+	case map[string]cliexchange.PatternOutput:
+		for k := range res {
+			keys = append(keys, k)
+		}
+	case map[string]exchange.ServiceDefinition:
+		for k := range res {
+			keys = append(keys, k)
+		}
+	}
+
+	ids := []string{}
+	for _, k := range keys {
+		if id := strings.TrimPrefix(k, org+"/"); id != k && !managed[kind+"/"+id] {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Display the plan and return the number of deletes.
+func printPlan(org string, changes []Change, unchanged int) int {
+
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	counts := map[string]int{}
+	msgPrinter.Printf("Plan for organization %v:", org)
+	msgPrinter.Println()
+	for _, c := range changes {
+		counts[c.Op]++
+		fmt.Printf("  %-6s %-16s %v/%v\n", c.Op, c.Kind, org, c.Id)
+	}
+	msgPrinter.Printf("%v to create, %v to update, %v to delete, %v unchanged.", counts[OP_CREATE], counts[OP_UPDATE], counts[OP_DELETE], unchanged)
+	msgPrinter.Println()
+	return counts[OP_DELETE]
+}
+
+// Make one change in the exchange.
+func execute(org, userPw string, c Change) {
+
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	exchUrl := cliutils.GetExchangeUrl()
+	creds := cliutils.OrgAndCreds(org, userPw)
+
+	switch c.Op {
+	case OP_CREATE:
+		msgPrinter.Printf("Creating %v %v/%v in the Exchange...", c.Kind, org, c.Id)
+	case OP_UPDATE:
+		msgPrinter.Printf("Updating %v %v/%v in the Exchange...", c.Kind, org, c.Id)
+	case OP_DELETE:
+		msgPrinter.Printf("Removing %v %v/%v from the Exchange...", c.Kind, org, c.Id)
+	}
+	msgPrinter.Println()
+
+	var resourcePath string
+	switch c.Kind {
+	case KIND_SERVICE:
+		resourcePath = "orgs/" + org + "/services/" + c.Id
+	case KIND_SERVICE_POLICY:
+		resourcePath = "orgs/" + org + "/services/" + c.Id + "/policy"
+	case KIND_PATTERN:
+		resourcePath = "orgs/" + org + "/patterns/" + c.Id
+	case KIND_DEPLOYMENT_POLICY:
+		resourcePath = "orgs/" + org + "/business/policies/" + c.Id
+	}
+
+	if c.Op == OP_DELETE {
+		cliutils.ExchangeDelete("Exchange", exchUrl, resourcePath, creds, []int{204, 404})
+		return
+	}
+
+	// the required services are validated now, because they might have been created earlier in this apply
+	if c.Kind == KIND_SERVICE {
+		ec := cliutils.GetUserExchangeContext(org, userPw)
+		if err := common.ValidateService(exchange.GetHTTPServiceDefResolverHandler(ec), c.Svc, msgPrinter); err != nil {
+			cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("Error validating the service %v/%v: %v", org, c.Id, err))
+		}
+	}
+
+	switch {
+	case c.Op == OP_UPDATE || c.Kind == KIND_SERVICE_POLICY:
+		cliutils.ExchangePutPost("Exchange", http.MethodPut, exchUrl, resourcePath, creds, []int{201}, c.Input, nil)
+	case c.Kind == KIND_SERVICE:
+		cliutils.ExchangePutPost("Exchange", http.MethodPost, exchUrl, "orgs/"+org+"/services", creds, []int{201}, c.Input, nil)
+	default:
+		cliutils.ExchangePutPost("Exchange", http.MethodPost, exchUrl, resourcePath, creds, []int{201}, c.Input, nil)
+	}
+
+	// Store the public key in the exchange, if it is used
+	if c.PubKey != "" {
+		bodyBytes := cliutils.ReadFile(c.PubKey)
+		baseName := filepath.Base(c.PubKey)
+		msgPrinter.Printf("Storing %s with the %v in the Exchange...", baseName, c.Kind)
+		msgPrinter.Println()
+		cliutils.ExchangePutPost("Exchange", http.MethodPut, exchUrl, resourcePath+"/keys/"+baseName, creds, []int{201}, bodyBytes, nil)
+	}
+}
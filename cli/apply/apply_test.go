@@ -0,0 +1,160 @@
+// +build unit
+
+package apply
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_ParseManifest_kinds(t *testing.T) {
+
+	svc := `{"kind":"service","spec":{"url":"my.svc","version":"1.0.0","arch":"amd64"},"policy":{"properties":[{"name":"p1","value":"v1"}]}}`
+	if r, err := ParseManifest("/m/svc.json", []byte(svc), "myorg"); err != nil {
+		t.Errorf("unexpected error %v", err)
+	} else if r.Kind != KIND_SERVICE || r.Id != "my.svc_1.0.0_amd64" || r.Service.Org != "myorg" || r.SvcPolicy == nil {
+		t.Errorf("wrong service resource %v", r)
+	}
+
+	pat := `{"kind":"pattern","spec":{"services":[{"serviceUrl":"my.svc","serviceOrgid":"myorg","serviceArch":"amd64","serviceVersions":[{"version":"1.0.0"}]}]}}`
+	if r, err := ParseManifest("/m/my@pattern.json", []byte(pat), "myorg"); err != nil {
+		t.Errorf("unexpected error %v", err)
+	} else if r.Kind != KIND_PATTERN || r.Id != "my-pattern" || r.Pattern.Org != "myorg" {
+		t.Errorf("wrong pattern resource %v", r)
+	}
+
+	pol := `{"kind":"deploymentPolicy","name":"pol1","spec":{"service":{"name":"my.svc","org":"myorg","arch":"amd64","serviceVersions":[{"version":"1.0.0"}]},"constraints":["a == b"]}}`
+	if r, err := ParseManifest("/m/pol.json", []byte(pol), "myorg"); err != nil {
+		t.Errorf("unexpected error %v", err)
+	} else if r.Kind != KIND_DEPLOYMENT_POLICY || r.Id != "pol1" {
+		t.Errorf("wrong deployment policy resource %v", r)
+	}
+}
+
+func Test_ParseManifest_errors(t *testing.T) {
+
+	bad := map[string]string{
+		"unknown kind":      `{"kind":"node","spec":{}}`,
+		"no spec":           `{"kind":"service"}`,
+		"org mismatch":      `{"kind":"service","org":"other","spec":{"url":"s","version":"1.0.0","arch":"amd64"}}`,
+		"spec org mismatch": `{"kind":"service","spec":{"org":"other","url":"s","version":"1.0.0","arch":"amd64"}}`,
+		"missing version":   `{"kind":"service","spec":{"url":"s","arch":"amd64"}}`,
+		"pattern policy":    `{"kind":"pattern","policy":{},"spec":{"services":[{"serviceUrl":"s"}]}}`,
+		"pattern services":  `{"kind":"pattern","spec":{}}`,
+		"not json":          `{"kind":`,
+	}
+	for name, m := range bad {
+		if _, err := ParseManifest("/m/x.json", []byte(m), "myorg"); err == nil {
+			t.Errorf("%v: expected an error", name)
+		}
+	}
+}
+
+func Test_FindManifestFiles(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "apply")
+	if err != nil {
+		t.Fatalf("error creating temp dir %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, f := range []string{"b.json", "a.json", "hzn.json", "README.md", ".hidden.json", "sub/c.json", ".git/d.json"} {
+		p := filepath.Join(dir, f)
+		os.MkdirAll(filepath.Dir(p), 0755)
+		if err := ioutil.WriteFile(p, []byte("{}"), 0644); err != nil {
+			t.Fatalf("error writing %v: %v", p, err)
+		}
+	}
+
+	files, err := FindManifestFiles(dir)
+	if err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	expected := []string{filepath.Join(dir, "a.json"), filepath.Join(dir, "b.json"), filepath.Join(dir, "sub/c.json")}
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("expected %v, got %v", expected, files)
+	}
+}
+
+func Test_OrderServices(t *testing.T) {
+
+	parse := func(name, spec string) *Resource {
+		r, err := ParseManifest(name+".json", []byte(`{"kind":"service","spec":`+spec+`}`), "myorg")
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		return r
+	}
+
+	top := parse("top", `{"url":"top","version":"1.0.0","arch":"amd64","requiredServices":[{"url":"mid","org":"myorg","versionRange":"1.0.0","arch":"amd64"}]}`)
+	mid := parse("mid", `{"url":"mid","version":"1.0.0","arch":"amd64","requiredServices":[{"url":"base","org":"myorg","versionRange":"1.0.0","arch":"amd64"},{"url":"ext","org":"other","versionRange":"1.0.0","arch":"amd64"}]}`)
+	base := parse("base", `{"url":"base","version":"1.0.0","arch":"amd64"}`)
+
+	if ordered, err := OrderServices([]*Resource{top, mid, base}, "myorg"); err != nil {
+		t.Errorf("unexpected error %v", err)
+	} else if !reflect.DeepEqual(ordered, []*Resource{base, mid, top}) {
+		t.Errorf("wrong order %v", ordered)
+	}
+
+	// a cycle
+	a := parse("a", `{"url":"a","version":"1.0.0","arch":"amd64","requiredServices":[{"url":"b","org":"myorg","versionRange":"1.0.0","arch":"amd64"}]}`)
+	b := parse("b", `{"url":"b","version":"1.0.0","arch":"amd64","requiredServices":[{"url":"a","org":"myorg","versionRange":"1.0.0","arch":"amd64"}]}`)
+	if _, err := OrderServices([]*Resource{a, b, base}, "myorg"); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("expected a cycle error, got %v", err)
+	}
+}
+
+func Test_CheckDuplicates(t *testing.T) {
+	r1 := &Resource{Kind: KIND_PATTERN, Id: "p1", File: "a.json"}
+	r2 := &Resource{Kind: KIND_DEPLOYMENT_POLICY, Id: "p1", File: "b.json"}
+	r3 := &Resource{Kind: KIND_PATTERN, Id: "p1", File: "c.json"}
+	if err := CheckDuplicates([]*Resource{r1, r2}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	} else if err := CheckDuplicates([]*Resource{r1, r2, r3}); err == nil {
+		t.Errorf("expected a duplicate error")
+	}
+}
+
+func Test_IsChanged(t *testing.T) {
+
+	desired := map[string]interface{}{
+		"label":      "svc",
+		"public":     false,
+		"deployment": `{"services":{"s":{"image":"i:1"}}}`,
+		"userInput":  []interface{}{},
+	}
+
+	// ignored fields, empty values and the deployment in a different json format
+	existing := map[string]interface{}{
+		"label":               "svc",
+		"owner":               "myorg/me",
+		"lastUpdated":         "2020-01-01",
+		"deploymentSignature": "abc",
+		"deployment":          `{ "services": { "s": { "image": "i:1" } } }`,
+	}
+	if IsChanged(desired, existing) {
+		t.Errorf("expected no change")
+	}
+
+	existing["deployment"] = `{"services":{"s":{"image":"i:2"}}}`
+	if !IsChanged(desired, existing) {
+		t.Errorf("expected a change in the deployment")
+	}
+
+	// a defaulted field is only compared when it is desired
+	existing["deployment"] = desired["deployment"]
+	existing["nodeHealth"] = map[string]interface{}{"missing_heartbeat_interval": 600}
+	if !IsChanged(desired, existing) {
+		t.Errorf("expected a change in nodeHealth")
+	} else if IsChanged(desired, existing, "nodeHealth") {
+		t.Errorf("expected no change for a defaulted field")
+	}
+	desired["nodeHealth"] = map[string]interface{}{"missing_heartbeat_interval": 120}
+	if !IsChanged(desired, existing, "nodeHealth") {
+		t.Errorf("expected a change in the desired defaulted field")
+	}
+}
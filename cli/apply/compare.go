@@ -0,0 +1,130 @@
+package apply
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// The fields that are set by the exchange or that change every time a resource is signed. They are never compared.
+var ignoredFields = map[string]bool{
+	"owner":                          true,
+	"lastUpdated":                    true,
+	"created":                        true,
+	"deploymentSignature":            true,
+	"clusterDeploymentSignature":     true,
+	"deployment_overrides_signature": true,
+}
+
+// Return true if the desired resource is different from the resource in the exchange. The resources are compared as
+// JSON, ignoring empty values and the fields that are set by the exchange. Strings that contain JSON, like the deployment
+// of a service, are compared as JSON too. The defaulted fields are only compared when the desired resource has them,
+// because the exchange fills in a default value when they are not set.
+func IsChanged(desired interface{}, existing interface{}, defaulted ...string) bool {
+	defaultedFields := map[string]bool{}
+	for _, f := range defaulted {
+		defaultedFields[f] = true
+	}
+	return !equalJSON(prune(toJSON(desired)), prune(toJSON(existing)), defaultedFields)
+}
+
+// Convert a value into the generic form that encoding/json produces.
+func toJSON(v interface{}) interface{} {
+	var generic interface{}
+	if b, err := json.Marshal(v); err != nil {
+		return v
+	} else if err := json.Unmarshal(b, &generic); err != nil {
+		return v
+	}
+	return generic
+}
+
+// Remove the ignored fields and the empty values, and parse the strings that contain a JSON object or array.
+// An empty value is nil, false, 0, an empty string or an empty object or array.
+func prune(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		res := map[string]interface{}{}
+		for k, fv := range val {
+			if ignoredFields[k] {
+				continue
+			} else if pv := prune(fv); !isEmpty(pv) {
+				res[k] = pv
+			}
+		}
+		return res
+	case []interface{}:
+		res := make([]interface{}, len(val))
+		for i, ev := range val {
+			res[i] = prune(ev)
+		}
+		return res
+	case string:
+		if s := strings.TrimSpace(val); strings.HasPrefix(s, "{") || strings.HasPrefix(s, "[") {
+			var generic interface{}
+			if err := json.Unmarshal([]byte(s), &generic); err == nil {
+				return prune(generic)
+			}
+		}
+		return val
+	default:
+		return val
+	}
+}
+
+func isEmpty(v interface{}) bool {
+	switch val := v.(type) {
+	case nil:
+		return true
+	case bool:
+		return !val
+	case float64:
+		return val == 0
+	case string:
+		return val == ""
+	case map[string]interface{}:
+		return len(val) == 0
+	case []interface{}:
+		return len(val) == 0
+	}
+	return false
+}
+
+// Compare pruned JSON values, skipping the defaulted fields that are not in the desired value.
+func equalJSON(desired interface{}, existing interface{}, defaulted map[string]bool) bool {
+	switch d := desired.(type) {
+	case map[string]interface{}:
+		e, ok := existing.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for k, ev := range e {
+			if dv, found := d[k]; !found {
+				if !defaulted[k] {
+					return false
+				}
+			} else if !equalJSON(dv, ev, defaulted) {
+				return false
+			}
+		}
+		for k := range d {
+			if _, found := e[k]; !found {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		e, ok := existing.([]interface{})
+		if !ok || len(d) != len(e) {
+			return false
+		}
+		for i := range d {
+			if !equalJSON(d[i], e[i], defaulted) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(desired, existing)
+	}
+}
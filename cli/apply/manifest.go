@@ -0,0 +1,213 @@
+package apply
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/open-horizon/anax/businesspolicy"
+	"github.com/open-horizon/anax/common"
+	"github.com/open-horizon/anax/cutil"
+	"github.com/open-horizon/anax/externalpolicy"
+	"github.com/open-horizon/anax/i18n"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// The kinds of exchange resources that can be in a manifest.
+const KIND_SERVICE = "service"
+const KIND_PATTERN = "pattern"
+const KIND_DEPLOYMENT_POLICY = "deploymentPolicy"
+
+// The kind that is used in the plan for the policy of a service. It is not a manifest kind, the service policy
+// is part of the service manifest.
+const KIND_SERVICE_POLICY = "servicePolicy"
+
+// The local configuration file that can be in the manifest directory. It is not a manifest.
+const LOCAL_CONFIG_FILE = "hzn.json"
+
+// A manifest describes one exchange resource. The spec has the same format as the file that is used to
+// publish the resource with the hzn exchange commands, e.g. the spec of a service is a service definition file.
+type Manifest struct {
+	Kind   string                         `json:"kind"`
+	Name   string                         `json:"name,omitempty"`   // the name of a pattern or deployment policy, the file name is used if it is not set
+	Org    string                         `json:"org,omitempty"`    // optional, must be the org that the manifests are applied to
+	Spec   json.RawMessage                `json:"spec"`             // the resource
+	Policy *externalpolicy.ExternalPolicy `json:"policy,omitempty"` // the service policy, only for a service
+}
+
+// A resource that was read from a manifest file.
+type Resource struct {
+	Kind      string
+	Id        string // the exchange id of the resource, without the org
+	File      string
+	Service   *common.ServiceFile
+	SvcPolicy *externalpolicy.ExternalPolicy
+	Pattern   *common.PatternFile
+	DepPolicy *businesspolicy.BusinessPolicy
+}
+
+func (r Resource) String() string {
+	return fmt.Sprintf("Kind: %v, Id: %v, File: %v", r.Kind, r.Id, r.File)
+}
+
+// Find the manifest files in the directory and its sub directories, sorted by path. Hidden files and
+// directories are skipped.
+func FindManifestFiles(dir string) ([]string, error) {
+	files := []string{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(info.Name(), ".") && path != dir {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.IsDir() && filepath.Ext(path) == ".json" && info.Name() != LOCAL_CONFIG_FILE {
+			files = append(files, path)
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
+
+// Convert the content of a manifest file into a resource in the given org.
+func ParseManifest(file string, content []byte, org string) (*Resource, error) {
+
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	var m Manifest
+	if err := json.Unmarshal(content, &m); err != nil {
+		return nil, errors.New(msgPrinter.Sprintf("failed to unmarshal manifest %v: %v", file, err))
+	} else if m.Org != "" && m.Org != org {
+		return nil, errors.New(msgPrinter.Sprintf("the org %v in manifest %v must match the org %v that the manifests are applied to.", m.Org, file, org))
+	} else if len(m.Spec) == 0 {
+		return nil, errors.New(msgPrinter.Sprintf("manifest %v does not have a spec.", file))
+	} else if m.Policy != nil && m.Kind != KIND_SERVICE {
+		return nil, errors.New(msgPrinter.Sprintf("manifest %v has a policy, which is only supported for the %v kind.", file, KIND_SERVICE))
+	}
+
+	// The default name of a pattern or deployment policy is the file name.
+	defaultName := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+
+	r := &Resource{Kind: m.Kind, File: file}
+	switch m.Kind {
+	case KIND_SERVICE:
+		r.Service = new(common.ServiceFile)
+		if err := json.Unmarshal(m.Spec, r.Service); err != nil {
+			return nil, errors.New(msgPrinter.Sprintf("failed to unmarshal the service in manifest %v: %v", file, err))
+		} else if r.Service.Org != "" && r.Service.Org != org {
+			return nil, errors.New(msgPrinter.Sprintf("the org %v of the service in manifest %v must match the org %v that the manifests are applied to.", r.Service.Org, file, org))
+		} else if r.Service.URL == "" || r.Service.Version == "" || r.Service.Arch == "" {
+			return nil, errors.New(msgPrinter.Sprintf("the service in manifest %v must have a url, version and arch.", file))
+		}
+		r.Service.Org = org
+		r.Service.SupportVersionRange()
+		r.Id = cutil.FormExchangeIdForService(r.Service.URL, r.Service.Version, r.Service.Arch)
+
+		if m.Policy != nil {
+			r.SvcPolicy = m.Policy
+			if err := r.SvcPolicy.ValidateAndNormalize(); err != nil {
+				return nil, errors.New(msgPrinter.Sprintf("incorrect service policy format in manifest %v: %v", file, err))
+			}
+		}
+
+	case KIND_PATTERN:
+		r.Pattern = new(common.PatternFile)
+		if err := json.Unmarshal(m.Spec, r.Pattern); err != nil {
+			return nil, errors.New(msgPrinter.Sprintf("failed to unmarshal the pattern in manifest %v: %v", file, err))
+		} else if r.Pattern.Org != "" && r.Pattern.Org != org {
+			return nil, errors.New(msgPrinter.Sprintf("the org %v of the pattern in manifest %v must match the org %v that the manifests are applied to.", r.Pattern.Org, file, org))
+		} else if len(r.Pattern.Services) == 0 {
+			return nil, errors.New(msgPrinter.Sprintf("the pattern in manifest %v must contain services.", file))
+		}
+		r.Pattern.Org = org
+		if m.Name != "" {
+			r.Id = m.Name
+		} else if r.Pattern.Name != "" {
+			r.Id = r.Pattern.Name
+		} else {
+			r.Id = defaultName
+		}
+		r.Id = cutil.FormExchangeId(r.Id)
+
+	case KIND_DEPLOYMENT_POLICY:
+		r.DepPolicy = new(businesspolicy.BusinessPolicy)
+		if err := json.Unmarshal(m.Spec, r.DepPolicy); err != nil {
+			return nil, errors.New(msgPrinter.Sprintf("failed to unmarshal the deployment policy in manifest %v: %v", file, err))
+		} else if err := r.DepPolicy.Validate(); err != nil {
+			return nil, errors.New(msgPrinter.Sprintf("incorrect deployment policy format in manifest %v: %v", file, err))
+		}
+		r.Id = m.Name
+		if r.Id == "" {
+			r.Id = defaultName
+		}
+		r.Id = cutil.FormExchangeId(r.Id)
+
+	default:
+		return nil, errors.New(msgPrinter.Sprintf("manifest %v has kind %v, it must be one of %v, %v or %v.", file, m.Kind, KIND_SERVICE, KIND_PATTERN, KIND_DEPLOYMENT_POLICY))
+	}
+
+	return r, nil
+}
+
+// Check that each resource is only in one manifest.
+func CheckDuplicates(resources []*Resource) error {
+	seen := map[string]*Resource{}
+	for _, r := range resources {
+		key := r.Kind + "/" + r.Id
+		if other, ok := seen[key]; ok {
+			return errors.New(i18n.GetMessagePrinter().Sprintf("%v %v is in both %v and %v.", r.Kind, r.Id, other.File, r.File))
+		}
+		seen[key] = r
+	}
+	return nil
+}
+
+// Sort the services so that a service comes after the services in the same org that it requires, so that they
+// are created first. The order of the other services is not changed.
+func OrderServices(services []*Resource, org string) ([]*Resource, error) {
+	ordered := make([]*Resource, 0, len(services))
+	done := map[*Resource]bool{}
+
+	// Return true if the service requires another service in the list that is not yet ordered.
+	waiting := func(r *Resource) bool {
+		for _, rs := range r.Service.RequiredServices {
+			if rs.Org != org {
+				continue
+			}
+			for _, other := range services {
+				if other != r && !done[other] && other.Service.URL == rs.URL && (rs.Arch == "" || other.Service.Arch == rs.Arch) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	for len(ordered) < len(services) {
+		progress := false
+		for _, r := range services {
+			if !done[r] && !waiting(r) {
+				ordered = append(ordered, r)
+				done[r] = true
+				progress = true
+			}
+		}
+		if !progress {
+			left := []string{}
+			for _, r := range services {
+				if !done[r] {
+					left = append(left, r.Id)
+				}
+			}
+			return nil, errors.New(i18n.GetMessagePrinter().Sprintf("the required services of %v form a cycle.", strings.Join(left, ", ")))
+		}
+	}
+	return ordered, nil
+}
@@ -36,9 +36,13 @@ type HorizonCliConfig struct {
 	// the locale that the hzn cli will run under, for example pt-BR, es, fr, de, it, ja, ko, zh-CN, zh-TW.
 	HZN_LANG string `json:"HZN_LANG,omitempty"`
 
-	// the url to the horizon agent, the default is "http://localhost:8510" for linux and "http://localhost:8081" for mac
+	// the url to the horizon agent, the default is "http://localhost:8510" for linux and "http://localhost:8081" for mac.
+	// It can also be the agent's unix socket, for example "unix:///var/run/horizon/anax.sock"
 	HORIZON_URL string `json:"HORIZON_URL,omitempty"`
 
+	// the file that holds the bearer token for the horizon agent API when the agent API is authenticated
+	HZN_AGENT_API_TOKEN_FILE string `json:"HZN_AGENT_API_TOKEN_FILE,omitempty"`
+
 	// exchange url, the default is shipped with the horizon-cli package
 	HZN_EXCHANGE_URL string `json:"HZN_EXCHANGE_URL,omitempty"`
 
@@ -380,8 +384,8 @@ func ReadJsonFileWithLocalConfig(filePath string) []byte {
 	return contents
 }
 
-// set up the environment variables from the config files.
-// the precedence order is: environmental variables, user config file, package config file
+// set up the environment variables from the config files and the context.
+// the precedence order is: environmental variables, context, user config file, package config file
 func SetEnvVarsFromConfigFiles(project_dir string) error {
 	var err error
 
@@ -427,6 +431,11 @@ func SetEnvVarsFromConfigFiles(project_dir string) error {
 		}
 	}
 
+	// the context in ~/.hzn/contexts.json overrides the config files
+	if err := SetEnvVarsFromContext(orig_env_vars); err != nil {
+		cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, msgPrinter.Sprintf("Error reading environment variables from the context. %v", err))
+	}
+
 	return nil
 
 }
@@ -0,0 +1,298 @@
+package cliconfig
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/i18n"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// The file in ~/.hzn that holds the CLI contexts.
+const CONTEXT_FILE_NAME = "contexts.json"
+
+// The env var that selects the context when the --context flag is not specified.
+const CONTEXT_ENV_VAR = "HZN_CONTEXT"
+
+// The prefixes of a credentials reference. The credentials are never stored in the context file itself.
+const CRED_REF_ENV = "env:"
+const CRED_REF_FILE = "file:"
+const CRED_REF_STORE = "store:"
+
+// The context that is used by this invocation of hzn, it is empty when no context is used.
+var CURRENT_CONTEXT string
+
+// The context from the --context flag. It has to be set before SetEnvVarsFromConfigFiles is called, because the
+// flags are parsed after the environment is set up.
+var CONTEXT_OVERRIDE string
+
+// A named set of exchange, org and credentials settings, like a kubectl context.
+type CliContext struct {
+	ExchangeUrl    string `json:"exchangeUrl,omitempty"`
+	CssUrl         string `json:"cssUrl,omitempty"`
+	Org            string `json:"org,omitempty"`
+	CredentialsRef string `json:"credentialsRef,omitempty"` // where the exchange user credentials are, e.g. env:MY_AUTH, file:/path/to/auth or store:prod
+	CertPath       string `json:"certPath,omitempty"`       // the management hub certificate
+}
+
+func (c CliContext) String() string {
+	return fmt.Sprintf("ExchangeUrl: %v, CssUrl: %v, Org: %v, CredentialsRef: %v, CertPath: %v", c.ExchangeUrl, c.CssUrl, c.Org, c.CredentialsRef, c.CertPath)
+}
+
+// The content of the context file.
+type CliContexts struct {
+	CurrentContext string                `json:"currentContext,omitempty"`
+	Contexts       map[string]CliContext `json:"contexts"`
+}
+
+// Return the path of the context file.
+func GetContextFile() string {
+	return filepath.Join(os.Getenv("HOME"), ".hzn", CONTEXT_FILE_NAME)
+}
+
+// Read the contexts from the file. An empty set of contexts is returned if the file does not exist.
+func LoadContexts(contextFile string) (*CliContexts, error) {
+	contexts := &CliContexts{Contexts: map[string]CliContext{}}
+	fileBytes, err := ioutil.ReadFile(contextFile)
+	if os.IsNotExist(err) {
+		return contexts, nil
+	} else if err != nil {
+		return nil, errors.New(i18n.GetMessagePrinter().Sprintf("Unable to read context file %v. %v", contextFile, err))
+	} else if err := json.Unmarshal(fileBytes, contexts); err != nil {
+		return nil, errors.New(i18n.GetMessagePrinter().Sprintf("Unable to decode content of context file %v. %v", contextFile, err))
+	}
+	if contexts.Contexts == nil {
+		contexts.Contexts = map[string]CliContext{}
+	}
+	return contexts, nil
+}
+
+// Write the contexts to the file, only the user can read it.
+func SaveContexts(contextFile string, contexts *CliContexts) error {
+	jsonBytes, err := json.MarshalIndent(contexts, "", cliutils.JSON_INDENT)
+	if err != nil {
+		return err
+	} else if err := os.MkdirAll(filepath.Dir(contextFile), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(contextFile, jsonBytes, 0600)
+}
+
+// Return the value of the --context flag in the command line arguments, or an empty string if it is not there.
+func GetContextFromArgs(args []string) string {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		} else if strings.HasPrefix(arg, "--context=") {
+			return strings.TrimPrefix(arg, "--context=")
+		} else if arg == "--context" && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+// Return the credentials that a credentials reference points to. A reference to the credential store is not resolved
+// here, so that the store is only unlocked by the commands that need the credentials.
+func ResolveCredentialsRef(ref string) (string, error) {
+	switch {
+	case ref == "" || strings.HasPrefix(ref, CRED_REF_STORE):
+		return "", nil
+	case strings.HasPrefix(ref, CRED_REF_ENV):
+		return os.Getenv(strings.TrimPrefix(ref, CRED_REF_ENV)), nil
+	case strings.HasPrefix(ref, CRED_REF_FILE):
+		fileBytes, err := ioutil.ReadFile(strings.TrimPrefix(ref, CRED_REF_FILE))
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(fileBytes)), nil
+	}
+	return "", errors.New(i18n.GetMessagePrinter().Sprintf("credentials reference %v must start with %v, %v or %v.", ref, CRED_REF_ENV, CRED_REF_FILE, CRED_REF_STORE))
+}
+
+// Return the env vars that the context sets.
+func (c CliContext) GetEnvVars() (map[string]string, error) {
+	hzn_vars := map[string]string{}
+	setIf := func(name, value string) {
+		if value != "" {
+			hzn_vars[name] = value
+		}
+	}
+	setIf("HZN_EXCHANGE_URL", c.ExchangeUrl)
+	setIf("HZN_FSS_CSSURL", c.CssUrl)
+	setIf("HZN_ORG_ID", c.Org)
+	setIf(config.ManagementHubCertPath, c.CertPath)
+
+	creds, err := ResolveCredentialsRef(c.CredentialsRef)
+	if err != nil {
+		return nil, err
+	}
+	setIf("HZN_EXCHANGE_USER_AUTH", creds)
+	return hzn_vars, nil
+}
+
+// Set up the environment variables from the context that is selected by the --context flag, the HZN_CONTEXT env var
+// or the current context in the context file, in that order. The env vars in orig_env_vars are not changed.
+func SetEnvVarsFromContext(orig_env_vars map[string]string) error {
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	contextName := CONTEXT_OVERRIDE
+	explicit := true
+	if contextName == "" {
+		contextName = orig_env_vars[CONTEXT_ENV_VAR]
+	}
+
+	contextFile := GetContextFile()
+	contexts, err := LoadContexts(contextFile)
+	if err != nil {
+		return err
+	}
+	if contextName == "" {
+		contextName = contexts.CurrentContext
+		explicit = false
+	}
+	if contextName == "" {
+		return nil
+	}
+
+	ctx, ok := contexts.Contexts[contextName]
+	if !ok {
+		if explicit {
+			return errors.New(msgPrinter.Sprintf("context %v is not defined in %v.", contextName, contextFile))
+		}
+		cliutils.Warning(msgPrinter.Sprintf("the current context %v is not defined in %v, it is ignored.", contextName, contextFile))
+		return nil
+	}
+
+	cliutils.Verbose(msgPrinter.Sprintf("Using context %v", contextName))
+	hzn_vars, err := ctx.GetEnvVars()
+	if err != nil {
+		return errors.New(msgPrinter.Sprintf("Failed to get the credentials of context %v. %v", contextName, err))
+	}
+	// the context overrides the config files, but not the env vars that were set before hzn was started
+	if err := SetEnvVars(hzn_vars, orig_env_vars, false); err != nil {
+		return err
+	}
+	if strings.HasPrefix(ctx.CredentialsRef, CRED_REF_STORE) {
+		if _, found := orig_env_vars["HZN_EXCHANGE_USER_AUTH"]; !found {
+			os.Unsetenv("HZN_EXCHANGE_USER_AUTH")
+			cliutils.StoredCredentialNames["HZN_EXCHANGE_USER_AUTH"] = strings.TrimPrefix(ctx.CredentialsRef, CRED_REF_STORE)
+		}
+	}
+	CURRENT_CONTEXT = contextName
+	return nil
+}
+
+// Create or replace a context. If use is true, it also becomes the current context.
+func ContextAdd(name string, ctx CliContext, use bool) {
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	if name == "" {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("the context name must not be empty."))
+	} else if _, err := ResolveCredentialsRef(ctx.CredentialsRef); err != nil && !os.IsNotExist(err) {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, err.Error())
+	}
+
+	contextFile := GetContextFile()
+	contexts := loadContextsOrFatal(contextFile)
+	_, replaced := contexts.Contexts[name]
+	contexts.Contexts[name] = ctx
+	if use {
+		contexts.CurrentContext = name
+	}
+	saveContextsOrFatal(contextFile, contexts)
+
+	if replaced {
+		msgPrinter.Printf("Context %v updated.", name)
+	} else {
+		msgPrinter.Printf("Context %v added.", name)
+	}
+	msgPrinter.Println()
+}
+
+// Make a context the current context.
+func ContextUse(name string) {
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	contextFile := GetContextFile()
+	contexts := loadContextsOrFatal(contextFile)
+	if _, ok := contexts.Contexts[name]; !ok {
+		cliutils.Fatal(cliutils.NOT_FOUND, msgPrinter.Sprintf("context %v is not defined in %v.", name, contextFile))
+	}
+	contexts.CurrentContext = name
+	saveContextsOrFatal(contextFile, contexts)
+
+	msgPrinter.Printf("Switched to context %v.", name)
+	msgPrinter.Println()
+}
+
+// Display the contexts. The credentials references are displayed, but not the credentials.
+func ContextList(namesOnly bool) {
+	contexts := loadContextsOrFatal(GetContextFile())
+
+	var output interface{} = contexts
+	if namesOnly {
+		names := []string{}
+		for name := range contexts.Contexts {
+			if name == contexts.CurrentContext {
+				name = "* " + name
+			}
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		output = names
+	}
+
+	jsonBytes, err := json.MarshalIndent(output, "", cliutils.JSON_INDENT)
+	if err != nil {
+		cliutils.Fatal(cliutils.JSON_PARSING_ERROR, i18n.GetMessagePrinter().Sprintf("failed to marshal 'hzn config context list' output: %v", err))
+	}
+	fmt.Printf("%s\n", jsonBytes)
+}
+
+// Remove a context. If it is the current context, no context is current afterwards.
+func ContextDelete(name string, force bool) {
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	contextFile := GetContextFile()
+	contexts := loadContextsOrFatal(contextFile)
+	if _, ok := contexts.Contexts[name]; !ok {
+		cliutils.Fatal(cliutils.NOT_FOUND, msgPrinter.Sprintf("context %v is not defined in %v.", name, contextFile))
+	}
+	if !force {
+		cliutils.ConfirmRemove(msgPrinter.Sprintf("Are you sure you want to remove context %v?", name))
+	}
+
+	delete(contexts.Contexts, name)
+	if contexts.CurrentContext == name {
+		contexts.CurrentContext = ""
+	}
+	saveContextsOrFatal(contextFile, contexts)
+
+	msgPrinter.Printf("Context %v removed.", name)
+	msgPrinter.Println()
+}
+
+func loadContextsOrFatal(contextFile string) *CliContexts {
+	contexts, err := LoadContexts(contextFile)
+	if err != nil {
+		cliutils.Fatal(cliutils.FILE_IO_ERROR, err.Error())
+	}
+	return contexts
+}
+
+func saveContextsOrFatal(contextFile string, contexts *CliContexts) {
+	if err := SaveContexts(contextFile, contexts); err != nil {
+		cliutils.Fatal(cliutils.FILE_IO_ERROR, i18n.GetMessagePrinter().Sprintf("Unable to write context file %v. %v", contextFile, err))
+	}
+}
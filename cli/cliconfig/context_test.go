@@ -0,0 +1,141 @@
+// +build unit
+
+package cliconfig
+
+import (
+	"github.com/open-horizon/anax/cli/cliutils"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_GetContextFromArgs(t *testing.T) {
+	tests := map[string][]string{
+		"":     {"exchange", "node", "list"},
+		"dev":  {"exchange", "--context", "dev", "node", "list"},
+		"prod": {"--context=prod", "exchange", "node", "list"},
+		"x":    {"exchange", "node", "list", "--context", "x"},
+	}
+	for expected, args := range tests {
+		if c := GetContextFromArgs(args); c != expected {
+			t.Errorf("expected context %v for %v, got %v", expected, args, c)
+		}
+	}
+
+	if c := GetContextFromArgs([]string{"dev", "--", "--context", "x"}); c != "" {
+		t.Errorf("expected no context after --, got %v", c)
+	} else if c := GetContextFromArgs([]string{"exchange", "--context"}); c != "" {
+		t.Errorf("expected no context without a value, got %v", c)
+	}
+}
+
+func Test_ResolveCredentialsRef(t *testing.T) {
+	dir, err := ioutil.TempDir("", "context")
+	if err != nil {
+		t.Fatalf("error creating temp dir %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	credFile := filepath.Join(dir, "auth")
+	if err := ioutil.WriteFile(credFile, []byte("user:pw\n"), 0600); err != nil {
+		t.Fatalf("error writing %v: %v", credFile, err)
+	}
+	os.Setenv("TEST_CONTEXT_AUTH", "envuser:envpw")
+	defer os.Unsetenv("TEST_CONTEXT_AUTH")
+
+	if creds, err := ResolveCredentialsRef("file:" + credFile); err != nil || creds != "user:pw" {
+		t.Errorf("expected user:pw from the file, got %v %v", creds, err)
+	} else if creds, err := ResolveCredentialsRef("env:TEST_CONTEXT_AUTH"); err != nil || creds != "envuser:envpw" {
+		t.Errorf("expected envuser:envpw from the env var, got %v %v", creds, err)
+	} else if _, err := ResolveCredentialsRef("user:pw"); err == nil {
+		t.Errorf("expected an error for a reference without a prefix")
+	} else if _, err := ResolveCredentialsRef("file:" + filepath.Join(dir, "missing")); err == nil {
+		t.Errorf("expected an error for a missing file")
+	}
+}
+
+func Test_SetEnvVarsFromContext(t *testing.T) {
+	dir, err := ioutil.TempDir("", "context")
+	if err != nil {
+		t.Fatalf("error creating temp dir %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	origHome := os.Getenv("HOME")
+	os.Setenv("HOME", dir)
+	defer os.Setenv("HOME", origHome)
+
+	contexts := &CliContexts{
+		CurrentContext: "dev",
+		Contexts: map[string]CliContext{
+			"dev":  {ExchangeUrl: "https://dev/v1", Org: "devorg"},
+			"prod": {ExchangeUrl: "https://prod/v1", Org: "prodorg"},
+		},
+	}
+	if err := SaveContexts(GetContextFile(), contexts); err != nil {
+		t.Fatalf("error saving contexts %v", err)
+	}
+
+	for _, name := range []string{"HZN_EXCHANGE_URL", "HZN_ORG_ID", CONTEXT_ENV_VAR} {
+		defer os.Setenv(name, os.Getenv(name))
+		os.Unsetenv(name)
+	}
+
+	// the current context, an env var that was set before hzn started is not changed
+	os.Setenv("HZN_ORG_ID", "myorg")
+	if err := SetEnvVarsFromContext(GetEnvVars()); err != nil {
+		t.Errorf("unexpected error %v", err)
+	} else if os.Getenv("HZN_EXCHANGE_URL") != "https://dev/v1" || os.Getenv("HZN_ORG_ID") != "myorg" || CURRENT_CONTEXT != "dev" {
+		t.Errorf("wrong env vars for the current context: %v %v %v", os.Getenv("HZN_EXCHANGE_URL"), os.Getenv("HZN_ORG_ID"), CURRENT_CONTEXT)
+	}
+
+	// the --context flag
+	os.Unsetenv("HZN_EXCHANGE_URL")
+	os.Unsetenv("HZN_ORG_ID")
+	CONTEXT_OVERRIDE = "prod"
+	defer func() { CONTEXT_OVERRIDE = "" }()
+	if err := SetEnvVarsFromContext(GetEnvVars()); err != nil {
+		t.Errorf("unexpected error %v", err)
+	} else if os.Getenv("HZN_EXCHANGE_URL") != "https://prod/v1" || os.Getenv("HZN_ORG_ID") != "prodorg" || CURRENT_CONTEXT != "prod" {
+		t.Errorf("wrong env vars for the --context flag: %v %v %v", os.Getenv("HZN_EXCHANGE_URL"), os.Getenv("HZN_ORG_ID"), CURRENT_CONTEXT)
+	}
+
+	// an explicit context that does not exist
+	CONTEXT_OVERRIDE = "missing"
+	if err := SetEnvVarsFromContext(GetEnvVars()); err == nil {
+		t.Errorf("expected an error for a missing context")
+	}
+}
+
+func Test_SetEnvVarsFromContext_StoreRef(t *testing.T) {
+	dir, err := ioutil.TempDir("", "context")
+	if err != nil {
+		t.Fatalf("error creating temp dir %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	origHome := os.Getenv("HOME")
+	os.Setenv("HOME", dir)
+	defer os.Setenv("HOME", origHome)
+
+	contexts := &CliContexts{
+		CurrentContext: "prod",
+		Contexts:       map[string]CliContext{"prod": {Org: "prodorg", CredentialsRef: "store:prodauth"}},
+	}
+	if err := SaveContexts(GetContextFile(), contexts); err != nil {
+		t.Fatalf("error saving contexts %v", err)
+	}
+	for _, name := range []string{"HZN_ORG_ID", "HZN_EXCHANGE_USER_AUTH", CONTEXT_ENV_VAR} {
+		defer os.Setenv(name, os.Getenv(name))
+		os.Unsetenv(name)
+	}
+	defer delete(cliutils.StoredCredentialNames, "HZN_EXCHANGE_USER_AUTH")
+
+	// the store is not unlocked, the credential name is remembered for when the credentials are needed
+	if err := SetEnvVarsFromContext(GetEnvVars()); err != nil {
+		t.Errorf("unexpected error %v", err)
+	} else if os.Getenv("HZN_EXCHANGE_USER_AUTH") != "" || cliutils.StoredCredentialNames["HZN_EXCHANGE_USER_AUTH"] != "prodauth" {
+		t.Errorf("wrong credentials for a store reference: %v %v", os.Getenv("HZN_EXCHANGE_USER_AUTH"), cliutils.StoredCredentialNames)
+	}
+}
@@ -0,0 +1,125 @@
+package cliutils
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/i18n"
+)
+
+const (
+	// HORIZON_URL can be a url with this scheme to connect to the agent's unix socket, e.g. unix:///var/run/horizon/anax.sock
+	HZN_API_UNIX_SCHEME = "unix://"
+
+	// the base url of the requests that are sent on the agent's unix socket
+	HZN_API_UNIX_URL = "http://localhost"
+
+	// the bearer token for the local agent API, or the file that holds it
+	HZN_AGENT_API_TOKEN      = "HZN_AGENT_API_TOKEN"
+	HZN_AGENT_API_TOKEN_FILE = "HZN_AGENT_API_TOKEN_FILE"
+)
+
+// the API authentication config of the local agent, read once from the anax config file
+var agentAPIAuth *config.APIAuthConfig
+var agentAPIAuthOnce sync.Once
+
+func getAgentAPIAuthConfig() *config.APIAuthConfig {
+	agentAPIAuthOnce.Do(func() {
+		if anaxConfig, err := GetAnaxConfig(ANAX_CONFIG_FILE); err != nil {
+			Verbose(i18n.GetMessagePrinter().Sprintf("Unable to read %v to find the agent API authentication, error: %v", ANAX_CONFIG_FILE, err))
+		} else if anaxConfig != nil && anaxConfig.Edge.APIAuth.Enabled {
+			agentAPIAuth = &anaxConfig.Edge.APIAuth
+			if agentAPIAuth.TokenDir == "" {
+				base := os.Getenv("HZN_VAR_BASE")
+				if base == "" {
+					base = config.HZN_VAR_BASE_DEFAULT
+				}
+				agentAPIAuth.TokenDir = filepath.Join(base, config.APIAuthTokenPath_DEFAULT)
+			}
+		}
+	})
+	return agentAPIAuth
+}
+
+// Returns the unix socket of the local agent API, or an empty string if the API is reached over TCP. The socket is
+// used when HORIZON_URL is a unix:// url, or when HORIZON_URL is not set and the local agent serves its API on a socket.
+func getAgentAPISocket() string {
+	if envVar := os.Getenv("HORIZON_URL"); envVar != "" {
+		if strings.HasPrefix(envVar, HZN_API_UNIX_SCHEME) {
+			return strings.TrimPrefix(envVar, HZN_API_UNIX_SCHEME)
+		}
+		return ""
+	}
+
+	if auth := getAgentAPIAuthConfig(); auth != nil && auth.UnixSocket != "" {
+		if _, err := os.Stat(auth.UnixSocket); err == nil {
+			return auth.UnixSocket
+		}
+	}
+	return ""
+}
+
+// Returns the bearer token for the local agent API, or an empty string if there is none. The token comes from the
+// HZN_AGENT_API_TOKEN or HZN_AGENT_API_TOKEN_FILE env vars. When HORIZON_URL is not set, the token files of the
+// local agent are also tried, the admin token first. A user who cannot read a token file does not get its role.
+func getAgentAPIToken() string {
+	if tok := os.Getenv(HZN_AGENT_API_TOKEN); tok != "" {
+		Verbose(i18n.GetMessagePrinter().Sprintf("Using the agent API token from %v", HZN_AGENT_API_TOKEN))
+		return tok
+	}
+
+	files := []string{}
+	if file := os.Getenv(HZN_AGENT_API_TOKEN_FILE); file != "" {
+		files = append(files, file)
+	} else if os.Getenv("HORIZON_URL") == "" {
+		if auth := getAgentAPIAuthConfig(); auth != nil {
+			files = append(files, auth.GetTokenFile(config.APIRoleAdmin), auth.GetTokenFile(config.APIRoleReadOnly))
+		}
+	}
+
+	for _, file := range files {
+		if tok, err := ioutil.ReadFile(file); err == nil && len(strings.TrimSpace(string(tok))) != 0 {
+			Verbose(i18n.GetMessagePrinter().Sprintf("Using the agent API token in %v", file))
+			return strings.TrimSpace(string(tok))
+		}
+	}
+	return ""
+}
+
+// Returns the url of a local agent API path.
+func getAgentAPIUrl(urlSuffix string) string {
+	if getAgentAPISocket() != "" {
+		return HZN_API_UNIX_URL + "/" + urlSuffix
+	}
+	return GetHorizonUrlBase() + "/" + urlSuffix
+}
+
+// GetAgentHTTPClient returns an HTTP client for the local agent API. It connects to the agent's unix socket if there is one.
+func GetAgentHTTPClient(timeout int) *http.Client {
+	httpClient := GetHTTPClient(timeout)
+	if socket := getAgentAPISocket(); socket != "" {
+		Verbose(i18n.GetMessagePrinter().Sprintf("Connecting to the agent API on unix socket %v", socket))
+		if transport, ok := httpClient.Transport.(*http.Transport); ok {
+			transport.Dial = nil
+			transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socket)
+			}
+		}
+	}
+	return httpClient
+}
+
+// Add the agent API credentials to a request for the local agent API.
+func addAgentAPIAuth(req *http.Request) {
+	if tok := getAgentAPIToken(); tok != "" {
+		req.Header.Set("Authorization", "Bearer "+tok)
+	}
+}
@@ -75,7 +75,8 @@ const (
 type GlobalOptions struct {
 	Verbose     *bool
 	IsDryRun    *bool
-	UsingApiKey bool // should go away soon
+	Output      *string // the --output format, see output.go
+	UsingApiKey bool    // should go away soon
 }
 
 var Opts GlobalOptions
@@ -468,24 +469,26 @@ func ConfirmRemove(question string) {
 	}
 }
 
-// WithDefaultEnvVar returns the specified flag ptr if it has a non-blank value, or the env var value.
+// WithDefaultEnvVar returns the specified flag ptr if it has a non-blank value, or the env var value. If the env var is
+// not set, the credential with the same name in the credential store is used.
 func WithDefaultEnvVar(flag *string, envVarName string) *string {
 	if *flag != "" {
 		return flag
 	}
-	newFlag := os.Getenv(envVarName)
+	newFlag := GetEnvVarOrStoredCredential(envVarName)
 	if newFlag != "" {
 		return &newFlag
 	}
 	return flag // it is empty, but we did not find an env var value
 }
 
-// RequiredWithDefaultEnvVar returns the specified flag ptr if it has a non-blank value, or the env var value.
+// RequiredWithDefaultEnvVar returns the specified flag ptr if it has a non-blank value, or the env var value, or the
+// credential with the same name in the credential store.
 func RequiredWithDefaultEnvVar(flag *string, envVarName, errMsg string) *string {
 	if *flag != "" {
 		return flag
 	}
-	newFlag := os.Getenv(envVarName)
+	newFlag := GetEnvVarOrStoredCredential(envVarName)
 	if newFlag != "" {
 		return &newFlag
 	}
@@ -516,6 +519,11 @@ func GetAgbotUrlBase() string {
 	return GetHorizonUrlBase()
 }
 
+// Returns the url of the agbot secure API from HZN_AGBOT_SECURE_API, or an empty string if it is not set.
+func GetAgbotSecureAPIUrlBase() string {
+	return os.Getenv("HZN_AGBOT_SECURE_API")
+}
+
 // GetRespBodyAsString converts an http response body to a string
 func GetRespBodyAsString(responseBody io.ReadCloser) string {
 	if responseBody == nil {
@@ -566,9 +574,9 @@ func HorizonGet(urlSuffix string, goodHttpCodes []int, structure interface{}, qu
 	// get message printer
 	msgPrinter := i18n.GetMessagePrinter()
 
-	httpClient := GetHTTPClient(0)
+	httpClient := GetAgentHTTPClient(0)
 
-	url := GetHorizonUrlBase() + "/" + urlSuffix
+	url := getAgentAPIUrl(urlSuffix)
 	apiMsg := http.MethodGet + " " + url
 	Verbose(apiMsg)
 	// Create the request and run it
@@ -578,6 +586,7 @@ func HorizonGet(urlSuffix string, goodHttpCodes []int, structure interface{}, qu
 	}
 	req.Close = true
 	req.Header.Add("Accept", "application/json")
+	addAgentAPIAuth(req)
 
 	// add the language request to the http header
 	localeTag, err := i18n.GetLocale()
@@ -645,10 +654,65 @@ func HorizonGet(urlSuffix string, goodHttpCodes []int, structure interface{}, qu
 	return
 }
 
+// HorizonGetStream runs a GET on a streaming anax api and calls the line handler for every non-empty line of the response
+// body until the agent closes the stream. The http code is returned without reading the body if it is not the 1st element
+// in goodHttpCodes, so that the caller can fall back to a non-streaming api.
+func HorizonGetStream(urlSuffix string, goodHttpCodes []int, lineHandler func(line []byte)) (httpCode int) {
+
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	// a stream has no overall request timeout
+	httpClient := GetAgentHTTPClient(0)
+
+	url := getAgentAPIUrl(urlSuffix)
+	apiMsg := http.MethodGet + " " + url
+	Verbose(apiMsg)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		Fatal(HTTP_ERROR, msgPrinter.Sprintf("%s new request failed: %v", apiMsg, err))
+	}
+	req.Header.Add("Accept", "application/x-ndjson")
+	addAgentAPIAuth(req)
+
+	// add the language request to the http header
+	localeTag, err := i18n.GetLocale()
+	if err != nil {
+		localeTag = language.English
+	}
+	req.Header.Add("Accept-Language", localeTag.String())
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		printHorizonRestError(apiMsg, err)
+	}
+	defer resp.Body.Close()
+	httpCode = resp.StatusCode
+	Verbose(msgPrinter.Sprintf("HTTP code: %d", httpCode))
+	if len(goodHttpCodes) == 0 || httpCode != goodHttpCodes[0] {
+		if !isGoodCode(httpCode, goodHttpCodes) {
+			Fatal(HTTP_ERROR, msgPrinter.Sprintf("bad HTTP code from %s: %d", apiMsg, httpCode))
+		}
+		return
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if line := bytes.TrimSpace(scanner.Bytes()); len(line) != 0 {
+			lineHandler(line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		Fatal(HTTP_ERROR, msgPrinter.Sprintf("failed to read body response from %s: %v", apiMsg, err))
+	}
+	return
+}
+
 // HorizonDelete runs a DELETE on the anax api.
 // If the list of goodHttpCodes is not empty and none match the actual http code, it will exit with an error. Otherwise the actual code is returned.
 func HorizonDelete(urlSuffix string, goodHttpCodes []int, expectedHttpErrorCodes []int, quiet bool) (httpCode int, retError error) {
-	url := GetHorizonUrlBase() + "/" + urlSuffix
+	url := getAgentAPIUrl(urlSuffix)
 	apiMsg := http.MethodDelete + " " + url
 
 	// get message printer
@@ -658,7 +722,7 @@ func HorizonDelete(urlSuffix string, goodHttpCodes []int, expectedHttpErrorCodes
 	if IsDryRun() {
 		return 204, nil
 	}
-	httpClient := GetHTTPClient(0)
+	httpClient := GetAgentHTTPClient(0)
 	req, err := http.NewRequest(http.MethodDelete, url, nil)
 	if err != nil {
 		if quiet {
@@ -669,6 +733,7 @@ func HorizonDelete(urlSuffix string, goodHttpCodes []int, expectedHttpErrorCodes
 		}
 	}
 	req.Close = true
+	addAgentAPIAuth(req)
 
 	resp, err := httpClient.Do(req)
 	if err != nil {
@@ -712,13 +777,13 @@ func HorizonDelete(urlSuffix string, goodHttpCodes []int, expectedHttpErrorCodes
 // HorizonPutPost runs a PUT or POST to the anax api to create or update a resource.
 // If the list of goodHttpCodes is not empty and none match the actual http code, it will exit with an error. Otherwise the actual code is returned.
 func HorizonPutPost(method string, urlSuffix string, goodHttpCodes []int, body interface{}, exitOnErr bool) (httpCode int, resp_body string, err error) {
-	url := GetHorizonUrlBase() + "/" + urlSuffix
+	url := getAgentAPIUrl(urlSuffix)
 	apiMsg := method + " " + url
 	Verbose(apiMsg)
 	if IsDryRun() {
 		return 201, "", nil
 	}
-	httpClient := GetHTTPClient(0)
+	httpClient := GetAgentHTTPClient(0)
 
 	// get message printer
 	msgPrinter := i18n.GetMessagePrinter()
@@ -755,6 +820,7 @@ func HorizonPutPost(method string, urlSuffix string, goodHttpCodes []int, body i
 	}
 	req.Close = true
 	req.Header.Add("Accept", "application/json")
+	addAgentAPIAuth(req)
 	if bodyIsBytes {
 		req.Header.Add("Content-Length", strconv.Itoa(len(jsonBytes)))
 	} else {
@@ -0,0 +1,316 @@
+package cliutils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/open-horizon/anax/i18n"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/crypto/ssh/terminal"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// The file in ~/.hzn that holds the encrypted credentials.
+const CREDENTIALS_FILE_NAME = "credentials.json"
+
+// The env vars that unlock the credential store without a prompt. The key file takes precedence.
+const CREDENTIALS_PASSPHRASE_ENV_VAR = "HZN_CREDENTIALS_PASSPHRASE"
+const CREDENTIALS_KEY_FILE_ENV_VAR = "HZN_CREDENTIALS_KEY_FILE"
+
+// The ways a credential store can be protected.
+const CRED_PROTECTION_PASSPHRASE = "passphrase"
+const CRED_PROTECTION_KEY_FILE = "keyFile"
+
+const CREDENTIALS_STORE_VERSION = 1
+
+// The scrypt parameters that derive the encryption key from the passphrase or the key file.
+const scryptN = 32768
+const scryptR = 8
+const scryptP = 1
+const credKeyLen = 32
+
+// An encrypted credential. The nonce is different for each credential.
+type EncryptedCredential struct {
+	Nonce []byte `json:"nonce"`
+	Data  []byte `json:"data"`
+}
+
+// The credential store file. The names of the credentials are not encrypted, so that hzn knows which credentials
+// are in the store without asking for the passphrase.
+type CredentialStore struct {
+	Version     int                            `json:"version"`
+	Protection  string                         `json:"protection"`
+	Salt        []byte                         `json:"salt"`
+	Credentials map[string]EncryptedCredential `json:"credentials"`
+	key         []byte                         // the derived key, once the store is unlocked
+}
+
+// The names of the credentials in the store that are used for env vars that are not set, when the name is not
+// the env var name. They are set up by the context.
+var StoredCredentialNames = map[string]string{}
+
+// The credential store once it is unlocked by this invocation of hzn, so that the secret is only asked for once.
+var unlockedCredentialStore *CredentialStore
+
+// Return the path of the credential store file.
+func GetCredentialsFile() string {
+	return filepath.Join(os.Getenv("HOME"), ".hzn", CREDENTIALS_FILE_NAME)
+}
+
+// Read the credential store. A new empty store is returned if the file does not exist.
+func LoadCredentialStore(credFile string) (*CredentialStore, error) {
+	store := &CredentialStore{Version: CREDENTIALS_STORE_VERSION, Credentials: map[string]EncryptedCredential{}}
+	fileBytes, err := ioutil.ReadFile(credFile)
+	if os.IsNotExist(err) {
+		return store, nil
+	} else if err != nil {
+		return nil, errors.New(i18n.GetMessagePrinter().Sprintf("Unable to read credentials file %v. %v", credFile, err))
+	} else if err := json.Unmarshal(fileBytes, store); err != nil {
+		return nil, errors.New(i18n.GetMessagePrinter().Sprintf("Unable to decode content of credentials file %v. %v", credFile, err))
+	} else if store.Version != CREDENTIALS_STORE_VERSION {
+		return nil, errors.New(i18n.GetMessagePrinter().Sprintf("credentials file %v has version %v, only version %v is supported.", credFile, store.Version, CREDENTIALS_STORE_VERSION))
+	}
+	if store.Credentials == nil {
+		store.Credentials = map[string]EncryptedCredential{}
+	}
+	return store, nil
+}
+
+// Write the credential store, only the user can read it.
+func (s *CredentialStore) Save(credFile string) error {
+	jsonBytes, err := json.MarshalIndent(s, "", JSON_INDENT)
+	if err != nil {
+		return err
+	} else if err := os.MkdirAll(filepath.Dir(credFile), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(credFile, jsonBytes, 0600)
+}
+
+// Return true if the store has never been unlocked with a secret, i.e. it has no salt yet.
+func (s *CredentialStore) IsNew() bool {
+	return len(s.Salt) == 0
+}
+
+// Return the sorted names of the credentials in the store.
+func (s *CredentialStore) Names() []string {
+	names := make([]string, 0, len(s.Credentials))
+	for name := range s.Credentials {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Derive the encryption key from the secret, which is the passphrase or the content of the key file. A new store
+// gets a new salt and is protected the given way.
+func (s *CredentialStore) Unlock(protection string, secret []byte) error {
+	if s.IsNew() {
+		s.Salt = make([]byte, 16)
+		if _, err := io.ReadFull(rand.Reader, s.Salt); err != nil {
+			return err
+		}
+		s.Protection = protection
+	} else if s.Protection != protection {
+		return errors.New(i18n.GetMessagePrinter().Sprintf("the credential store is protected with a %v, not a %v.", s.Protection, protection))
+	}
+	if len(secret) == 0 {
+		return errors.New(i18n.GetMessagePrinter().Sprintf("the %v of the credential store must not be empty.", protection))
+	}
+
+	key, err := scrypt.Key(secret, s.Salt, scryptN, scryptR, scryptP, credKeyLen)
+	if err != nil {
+		return err
+	}
+	s.key = key
+
+	// check the key with one of the credentials, so that a wrong secret is reported right away
+	for _, name := range s.Names() {
+		if _, err := s.Get(name); err != nil {
+			s.key = nil
+			return err
+		}
+		break
+	}
+	return nil
+}
+
+func (s *CredentialStore) aead() (cipher.AEAD, error) {
+	if s.key == nil {
+		return nil, errors.New(i18n.GetMessagePrinter().Sprintf("the credential store is locked."))
+	}
+	block, err := aes.NewCipher(s.key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Encrypt and add a credential to an unlocked store. The name is authenticated with the value, so that an encrypted
+// value can not be moved to another name.
+func (s *CredentialStore) Set(name string, value string) error {
+	gcm, err := s.aead()
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+	s.Credentials[name] = EncryptedCredential{Nonce: nonce, Data: gcm.Seal(nil, nonce, []byte(value), []byte(name))}
+	return nil
+}
+
+// Decrypt a credential of an unlocked store.
+func (s *CredentialStore) Get(name string) (string, error) {
+	ec, ok := s.Credentials[name]
+	if !ok {
+		return "", errors.New(i18n.GetMessagePrinter().Sprintf("credential %v is not in the credential store.", name))
+	}
+	gcm, err := s.aead()
+	if err != nil {
+		return "", err
+	}
+	value, err := gcm.Open(nil, ec.Nonce, ec.Data, []byte(name))
+	if err != nil {
+		return "", errors.New(i18n.GetMessagePrinter().Sprintf("unable to decrypt credential %v, the %v of the credential store is not correct.", name, s.Protection))
+	}
+	return string(value), nil
+}
+
+// Get the secret that unlocks the store, from the key file, the passphrase env var or a prompt, in that order.
+// A key file given as keyFile overrides the key file env var. For a new store, confirm is used to prompt for
+// the passphrase twice.
+func GetCredentialStoreSecret(store *CredentialStore, keyFile string, confirm bool) (string, []byte, error) {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	if keyFile == "" {
+		keyFile = os.Getenv(CREDENTIALS_KEY_FILE_ENV_VAR)
+	}
+	if keyFile != "" && (store.IsNew() || store.Protection == CRED_PROTECTION_KEY_FILE) {
+		secret, err := ioutil.ReadFile(keyFile)
+		if err != nil {
+			return "", nil, errors.New(msgPrinter.Sprintf("Unable to read credential store key file %v. %v", keyFile, err))
+		}
+		return CRED_PROTECTION_KEY_FILE, secret, nil
+	} else if store.Protection == CRED_PROTECTION_KEY_FILE {
+		return "", nil, errors.New(msgPrinter.Sprintf("the credential store is protected with a key file, specify it with %v.", CREDENTIALS_KEY_FILE_ENV_VAR))
+	}
+
+	if passphrase := os.Getenv(CREDENTIALS_PASSPHRASE_ENV_VAR); passphrase != "" {
+		return CRED_PROTECTION_PASSPHRASE, []byte(passphrase), nil
+	}
+
+	passphrase, err := PromptSecret(msgPrinter.Sprintf("Credential store passphrase: "))
+	if err != nil {
+		return "", nil, err
+	}
+	if confirm {
+		again, err := PromptSecret(msgPrinter.Sprintf("Confirm the passphrase: "))
+		if err != nil {
+			return "", nil, err
+		} else if again != passphrase {
+			return "", nil, errors.New(msgPrinter.Sprintf("the passphrases do not match."))
+		}
+	}
+	return CRED_PROTECTION_PASSPHRASE, []byte(passphrase), nil
+}
+
+// Prompt for a secret on the terminal without echoing it.
+func PromptSecret(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !terminal.IsTerminal(fd) {
+		return "", errors.New(i18n.GetMessagePrinter().Sprintf("unable to prompt for a secret, stdin is not a terminal."))
+	}
+	fmt.Fprint(os.Stderr, prompt)
+	secret, err := terminal.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(secret)), nil
+}
+
+// Load and unlock the credential store.
+func OpenCredentialStore(keyFile string, confirmNew bool) (*CredentialStore, error) {
+	store, err := LoadCredentialStore(GetCredentialsFile())
+	if err != nil {
+		return nil, err
+	}
+	protection, secret, err := GetCredentialStoreSecret(store, keyFile, confirmNew && store.IsNew())
+	if err != nil {
+		return nil, err
+	}
+	if err := store.Unlock(protection, secret); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// Return the credential with the given name from the credential store, or an empty string if the store does not
+// have it. The store is only unlocked when it has the credential. The value is never logged.
+func GetStoredCredential(name string) string {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	store := unlockedCredentialStore
+	if store == nil {
+		var err error
+		if store, err = LoadCredentialStore(GetCredentialsFile()); err != nil {
+			Warning(err.Error())
+			return ""
+		}
+	}
+	if _, ok := store.Credentials[name]; !ok {
+		return ""
+	}
+
+	Verbose(msgPrinter.Sprintf("Using %v from the credential store", name))
+	if unlockedCredentialStore == nil {
+		protection, secret, err := GetCredentialStoreSecret(store, "", false)
+		if err == nil {
+			err = store.Unlock(protection, secret)
+		}
+		if err != nil {
+			Fatal(CLI_GENERAL_ERROR, msgPrinter.Sprintf("Unable to unlock the credential store to get %v. %v", name, err))
+		}
+		unlockedCredentialStore = store
+	}
+
+	value, err := store.Get(name)
+	if err != nil {
+		Fatal(CLI_GENERAL_ERROR, msgPrinter.Sprintf("Unable to get %v from the credential store. %v", name, err))
+	}
+	return value
+}
+
+// Return true if the credential store has the credential that is used for the env var. The store is not unlocked.
+func HasStoredCredential(envVarName string) bool {
+	name := envVarName
+	if n, ok := StoredCredentialNames[envVarName]; ok {
+		name = n
+	}
+	if store, err := LoadCredentialStore(GetCredentialsFile()); err == nil {
+		_, ok := store.Credentials[name]
+		return ok
+	}
+	return false
+}
+
+// Return the value of the env var if it is set. Otherwise return the credential from the credential store that the
+// context names for the env var, or the credential with the same name as the env var.
+func GetEnvVarOrStoredCredential(envVarName string) string {
+	if value := os.Getenv(envVarName); value != "" {
+		return value
+	} else if name, ok := StoredCredentialNames[envVarName]; ok {
+		return GetStoredCredential(name)
+	}
+	return GetStoredCredential(envVarName)
+}
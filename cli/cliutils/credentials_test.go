@@ -0,0 +1,128 @@
+// +build unit
+
+package cliutils
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_CredentialStore_SetGet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "credentials")
+	if err != nil {
+		t.Fatalf("error creating temp dir %v", err)
+	}
+	defer os.RemoveAll(dir)
+	credFile := filepath.Join(dir, CREDENTIALS_FILE_NAME)
+
+	store, err := LoadCredentialStore(credFile)
+	if err != nil {
+		t.Fatalf("unexpected error loading a missing store %v", err)
+	} else if !store.IsNew() {
+		t.Errorf("expected a new store")
+	} else if err := store.Set("HZN_EXCHANGE_USER_AUTH", "user:pw"); err == nil {
+		t.Errorf("expected an error setting a credential in a locked store")
+	}
+
+	if err := store.Unlock(CRED_PROTECTION_PASSPHRASE, []byte("secret")); err != nil {
+		t.Fatalf("unexpected error unlocking a new store %v", err)
+	} else if err := store.Set("HZN_EXCHANGE_USER_AUTH", "user:pw"); err != nil {
+		t.Fatalf("unexpected error setting a credential %v", err)
+	} else if err := store.Set("prod", "produser:prodpw"); err != nil {
+		t.Fatalf("unexpected error setting a credential %v", err)
+	} else if err := store.Save(credFile); err != nil {
+		t.Fatalf("unexpected error saving the store %v", err)
+	}
+
+	if fileBytes, err := ioutil.ReadFile(credFile); err != nil {
+		t.Fatalf("error reading %v: %v", credFile, err)
+	} else if len(fileBytes) == 0 || strings.Contains(string(fileBytes), "user:pw") {
+		t.Errorf("the credentials file must not contain the credentials in clear text: %s", fileBytes)
+	} else if fi, _ := os.Stat(credFile); fi.Mode().Perm() != 0600 {
+		t.Errorf("expected mode 0600 for the credentials file, got %v", fi.Mode().Perm())
+	}
+
+	// load it again and unlock it with the same and with a wrong passphrase
+	store, err = LoadCredentialStore(credFile)
+	if err != nil {
+		t.Fatalf("unexpected error loading the store %v", err)
+	} else if names := store.Names(); len(names) != 2 || names[0] != "HZN_EXCHANGE_USER_AUTH" || names[1] != "prod" {
+		t.Errorf("wrong names %v", names)
+	} else if err := store.Unlock(CRED_PROTECTION_PASSPHRASE, []byte("wrong")); err == nil {
+		t.Errorf("expected an error for a wrong passphrase")
+	} else if err := store.Unlock(CRED_PROTECTION_KEY_FILE, []byte("secret")); err == nil {
+		t.Errorf("expected an error for a key file on a store protected with a passphrase")
+	} else if err := store.Unlock(CRED_PROTECTION_PASSPHRASE, []byte("secret")); err != nil {
+		t.Fatalf("unexpected error unlocking the store %v", err)
+	} else if value, err := store.Get("prod"); err != nil || value != "produser:prodpw" {
+		t.Errorf("expected produser:prodpw, got %v %v", value, err)
+	} else if _, err := store.Get("missing"); err == nil {
+		t.Errorf("expected an error for a missing credential")
+	}
+
+	// an encrypted value that is moved to another name can not be decrypted
+	store.Credentials["moved"] = store.Credentials["prod"]
+	if _, err := store.Get("moved"); err == nil {
+		t.Errorf("expected an error for a credential that was moved to another name")
+	}
+}
+
+func Test_GetEnvVarOrStoredCredential(t *testing.T) {
+	dir, err := ioutil.TempDir("", "credentials")
+	if err != nil {
+		t.Fatalf("error creating temp dir %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	origHome := os.Getenv("HOME")
+	os.Setenv("HOME", dir)
+	defer os.Setenv("HOME", origHome)
+	for _, name := range []string{"HZN_TEST_AUTH", CREDENTIALS_PASSPHRASE_ENV_VAR, CREDENTIALS_KEY_FILE_ENV_VAR} {
+		defer os.Setenv(name, os.Getenv(name))
+		os.Unsetenv(name)
+	}
+	defer func() { unlockedCredentialStore = nil }()
+
+	keyFile := filepath.Join(dir, "store.key")
+	if err := ioutil.WriteFile(keyFile, []byte("a key"), 0600); err != nil {
+		t.Fatalf("error writing %v: %v", keyFile, err)
+	}
+	os.Setenv(CREDENTIALS_KEY_FILE_ENV_VAR, keyFile)
+
+	store, err := OpenCredentialStore("", false)
+	if err != nil {
+		t.Fatalf("unexpected error opening a new store %v", err)
+	} else if store.Protection != CRED_PROTECTION_KEY_FILE {
+		t.Errorf("expected a store protected with a key file, got %v", store.Protection)
+	} else if err := store.Set("HZN_TEST_AUTH", "stored:pw"); err != nil {
+		t.Fatalf("unexpected error setting a credential %v", err)
+	} else if err := store.Set("other", "other:pw"); err != nil {
+		t.Fatalf("unexpected error setting a credential %v", err)
+	} else if err := store.Save(GetCredentialsFile()); err != nil {
+		t.Fatalf("unexpected error saving the store %v", err)
+	}
+
+	if !HasStoredCredential("HZN_TEST_AUTH") || HasStoredCredential("HZN_MISSING_AUTH") {
+		t.Errorf("wrong result of HasStoredCredential")
+	} else if value := GetEnvVarOrStoredCredential("HZN_TEST_AUTH"); value != "stored:pw" {
+		t.Errorf("expected the stored credential, got %v", value)
+	} else if value := GetEnvVarOrStoredCredential("HZN_MISSING_AUTH"); value != "" {
+		t.Errorf("expected no credential, got %v", value)
+	}
+
+	// a credential name that is set up by a context
+	StoredCredentialNames["HZN_TEST_AUTH"] = "other"
+	defer delete(StoredCredentialNames, "HZN_TEST_AUTH")
+	if value := GetEnvVarOrStoredCredential("HZN_TEST_AUTH"); value != "other:pw" {
+		t.Errorf("expected the credential named by the context, got %v", value)
+	}
+
+	// the env var takes precedence
+	os.Setenv("HZN_TEST_AUTH", "env:pw")
+	if value := GetEnvVarOrStoredCredential("HZN_TEST_AUTH"); value != "env:pw" {
+		t.Errorf("expected the env var, got %v", value)
+	}
+}
@@ -0,0 +1,307 @@
+package cliutils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/open-horizon/anax/i18n"
+	"gopkg.in/yaml.v2"
+	"k8s.io/client-go/util/jsonpath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+)
+
+// The output formats of the --output flag.
+const OUTPUT_JSON = "json"
+const OUTPUT_YAML = "yaml"
+const OUTPUT_TABLE = "table"
+const OUTPUT_WIDE = "wide"
+const OUTPUT_JSONPATH = "jsonpath="
+const OUTPUT_GO_TEMPLATE = "go-template="
+
+// A column of the table and wide output formats.
+type TableColumn struct {
+	Header string
+	Path   string // a jsonpath expression relative to the row, e.g. {.owner}. An empty path is the key of the row in a map, or the row itself when it is not an object.
+	Wide   bool   // only displayed by the wide output format
+}
+
+// How a command displays its output as a table. Rows is a jsonpath expression that selects the rows in the output,
+// the output itself when it is empty. An array has a row for each element, a map of objects has a row for each
+// entry and anything else is a single row.
+type TableFormat struct {
+	Rows    string
+	Columns []TableColumn
+}
+
+// The table format of the commands that display a list of names.
+var NamesTable = &TableFormat{Columns: []TableColumn{{Header: "NAME"}}}
+
+// Return the output format of the --output flag, json when it is not specified.
+func GetOutputFormat() string {
+	if Opts.Output == nil || *Opts.Output == "" {
+		return OUTPUT_JSON
+	}
+	return *Opts.Output
+}
+
+// Return an error if the output format is not one of the supported formats.
+func CheckOutputFormat(format string) error {
+	switch {
+	case format == "", format == OUTPUT_JSON, format == OUTPUT_YAML, format == OUTPUT_TABLE, format == OUTPUT_WIDE:
+		return nil
+	case strings.HasPrefix(format, OUTPUT_JSONPATH):
+		_, err := newJsonPath(strings.TrimPrefix(format, OUTPUT_JSONPATH))
+		return err
+	case strings.HasPrefix(format, OUTPUT_GO_TEMPLATE):
+		_, err := template.New("output").Parse(strings.TrimPrefix(format, OUTPUT_GO_TEMPLATE))
+		return err
+	}
+	return errors.New(i18n.GetMessagePrinter().Sprintf("output format %v is not supported, it must be one of %v, %v, %v, %v, %v<expression> or %v<template>.", format, OUTPUT_JSON, OUTPUT_YAML, OUTPUT_TABLE, OUTPUT_WIDE, OUTPUT_JSONPATH, OUTPUT_GO_TEMPLATE))
+}
+
+// Display the output of a command in the format of the --output flag. The json format is the indented json that
+// the commands have always displayed, without escaping the html characters of constraints like "a < 2".
+// When table is nil, the table and wide formats display json too.
+func PrintOutput(v interface{}, table *TableFormat, errMsg string) {
+	output, err := FormatOutput(v, GetOutputFormat(), table)
+	if err != nil {
+		Fatal(JSON_PARSING_ERROR, i18n.GetMessagePrinter().Sprintf("failed to format the output of %s: %v", errMsg, err))
+	}
+	fmt.Println(output)
+}
+
+// Display the output of a command that is already json, e.g. the body of an exchange response.
+func PrintJsonOutput(jsonBytes []byte, table *TableFormat, errMsg string) {
+	if GetOutputFormat() == OUTPUT_JSON {
+		fmt.Println(string(jsonBytes))
+		return
+	}
+	var v interface{}
+	if err := json.Unmarshal(jsonBytes, &v); err != nil {
+		Fatal(JSON_PARSING_ERROR, i18n.GetMessagePrinter().Sprintf("failed to unmarshal the output of %s: %v", errMsg, err))
+	}
+	PrintOutput(v, table, errMsg)
+}
+
+// Format the output in one of the output formats.
+func FormatOutput(v interface{}, format string, table *TableFormat) (string, error) {
+	if format == OUTPUT_JSON || ((format == OUTPUT_TABLE || format == OUTPUT_WIDE) && table == nil) {
+		output, err := DisplayAsJson(v)
+		return strings.TrimSuffix(output, "\n"), err
+	}
+
+	// the other formats work on the json representation, so that they use the same field names
+	data, err := toJsonData(v)
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case format == OUTPUT_YAML:
+		yamlBytes, err := yaml.Marshal(data)
+		return strings.TrimSuffix(string(yamlBytes), "\n"), err
+	case format == OUTPUT_TABLE || format == OUTPUT_WIDE:
+		return formatTable(data, table, format == OUTPUT_WIDE)
+	case strings.HasPrefix(format, OUTPUT_JSONPATH):
+		jp, err := newJsonPath(strings.TrimPrefix(format, OUTPUT_JSONPATH))
+		if err != nil {
+			return "", err
+		}
+		buf := new(bytes.Buffer)
+		if err := jp.Execute(buf, data); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	case strings.HasPrefix(format, OUTPUT_GO_TEMPLATE):
+		tmpl, err := template.New("output").Parse(strings.TrimPrefix(format, OUTPUT_GO_TEMPLATE))
+		if err != nil {
+			return "", err
+		}
+		buf := new(bytes.Buffer)
+		if err := tmpl.Execute(buf, data); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	}
+	return "", CheckOutputFormat(format)
+}
+
+// Return the json representation of v as maps, arrays and values. The whole numbers stay integers, so that they
+// are not displayed in exponent notation.
+func toJsonData(v interface{}) (interface{}, error) {
+	jsonBytes, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var data interface{}
+	decoder := json.NewDecoder(bytes.NewReader(jsonBytes))
+	decoder.UseNumber()
+	if err := decoder.Decode(&data); err != nil {
+		return nil, err
+	}
+	return convertNumbers(data), nil
+}
+
+func convertNumbers(data interface{}) interface{} {
+	switch d := data.(type) {
+	case map[string]interface{}:
+		for k, v := range d {
+			d[k] = convertNumbers(v)
+		}
+	case []interface{}:
+		for i, v := range d {
+			d[i] = convertNumbers(v)
+		}
+	case json.Number:
+		if i, err := d.Int64(); err == nil {
+			return i
+		} else if f, err := d.Float64(); err == nil {
+			return f
+		}
+		return d.String()
+	}
+	return data
+}
+
+// Parse a jsonpath expression. The braces are optional, e.g. .nodes and {.nodes} are the same.
+func newJsonPath(expr string) (*jsonpath.JSONPath, error) {
+	if !strings.Contains(expr, "{") {
+		expr = "{" + expr + "}"
+	}
+	jp := jsonpath.New("output").AllowMissingKeys(true)
+	if err := jp.Parse(expr); err != nil {
+		return nil, err
+	}
+	return jp, nil
+}
+
+// Return the rows of the table, with the key of each row when the rows are the entries of a map.
+func tableRows(data interface{}) ([]interface{}, []string) {
+	switch d := data.(type) {
+	case []interface{}:
+		return d, make([]string, len(d))
+	case map[string]interface{}:
+		keys := make([]string, 0, len(d))
+		for k, v := range d {
+			if _, ok := v.(map[string]interface{}); !ok {
+				return []interface{}{d}, []string{""}
+			}
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		rows := make([]interface{}, len(keys))
+		for i, k := range keys {
+			rows[i] = d[k]
+		}
+		return rows, keys
+	case nil:
+		return []interface{}{}, []string{}
+	}
+	return []interface{}{data}, []string{""}
+}
+
+func formatTable(data interface{}, table *TableFormat, wide bool) (string, error) {
+	if table.Rows != "" {
+		jp, err := newJsonPath(table.Rows)
+		if err != nil {
+			return "", err
+		}
+		results, err := jp.FindResults(data)
+		if err != nil {
+			return "", err
+		}
+		data = nil
+		if len(results) > 0 && len(results[0]) > 0 && results[0][0].CanInterface() {
+			data = results[0][0].Interface()
+		}
+	}
+
+	columns := []TableColumn{}
+	cellPaths := []*jsonpath.JSONPath{}
+	for _, c := range table.Columns {
+		if c.Wide && !wide {
+			continue
+		}
+		var jp *jsonpath.JSONPath
+		if c.Path != "" {
+			var err error
+			if jp, err = newJsonPath(c.Path); err != nil {
+				return "", err
+			}
+		}
+		columns = append(columns, c)
+		cellPaths = append(cellPaths, jp)
+	}
+
+	buf := new(bytes.Buffer)
+	w := tabwriter.NewWriter(buf, 0, 8, 3, ' ', 0)
+	headers := make([]string, len(columns))
+	for i, c := range columns {
+		headers[i] = c.Header
+	}
+	fmt.Fprintln(w, strings.Join(headers, "\t"))
+
+	rows, keys := tableRows(data)
+	for r, row := range rows {
+		cells := make([]string, len(columns))
+		for i, jp := range cellPaths {
+			if jp == nil {
+				if keys[r] != "" {
+					cells[i] = keys[r]
+				} else {
+					cells[i] = cellValue(row)
+				}
+				continue
+			}
+			results, err := jp.FindResults(row)
+			if err != nil {
+				return "", err
+			}
+			values := []string{}
+			for _, result := range results {
+				for _, value := range result {
+					if value.CanInterface() {
+						values = append(values, cellValue(value.Interface()))
+					}
+				}
+			}
+			cells[i] = strings.Join(values, ",")
+		}
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+	if err := w.Flush(); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// Return the text of a table cell, objects and arrays are displayed as compact json.
+func cellValue(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case map[string]interface{}, []interface{}:
+		jsonBytes, err := json.Marshal(value)
+		if err != nil {
+			return ""
+		}
+		return string(jsonBytes)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// Exit with an error when the output format of the --output flag is not valid.
+func CheckOutputFlag() {
+	if err := CheckOutputFormat(GetOutputFormat()); err != nil {
+		Fatal(CLI_INPUT_ERROR, err.Error())
+	}
+}
@@ -0,0 +1,113 @@
+// +build unit
+
+package cliutils
+
+import (
+	"strings"
+	"testing"
+)
+
+type testResource struct {
+	Label    string            `json:"label"`
+	Arch     string            `json:"arch"`
+	Count    int               `json:"count"`
+	Services []testServiceRef  `json:"services"`
+	Tags     map[string]string `json:"tags,omitempty"`
+}
+
+type testServiceRef struct {
+	Url string `json:"url"`
+}
+
+var testTable = &TableFormat{Columns: []TableColumn{
+	{Header: "ID"},
+	{Header: "LABEL", Path: ".label"},
+	{Header: "SERVICES", Path: ".services[*].url"},
+	{Header: "COUNT", Path: ".count", Wide: true},
+}}
+
+func testResources() map[string]testResource {
+	return map[string]testResource{
+		"org/b": {Label: "second", Arch: "arm", Count: 1700000000},
+		"org/a": {Label: "first <x>", Arch: "amd64", Count: 2, Services: []testServiceRef{{Url: "s1"}, {Url: "s2"}}},
+	}
+}
+
+func Test_FormatOutput_Json(t *testing.T) {
+	if output, err := FormatOutput(testResources(), OUTPUT_JSON, testTable); err != nil {
+		t.Errorf("unexpected error %v", err)
+	} else if !strings.Contains(output, `"label": "first <x>"`) || strings.HasSuffix(output, "\n") {
+		t.Errorf("wrong json output: %v", output)
+	}
+
+	// without a table format, the table formats display json
+	if output, err := FormatOutput(testResources(), OUTPUT_TABLE, nil); err != nil {
+		t.Errorf("unexpected error %v", err)
+	} else if !strings.HasPrefix(output, "{") {
+		t.Errorf("expected json without a table format, got %v", output)
+	}
+}
+
+func Test_FormatOutput_Table(t *testing.T) {
+	output, err := FormatOutput(testResources(), OUTPUT_TABLE, testTable)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	lines := strings.Split(output, "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected a header and 2 rows, got %v", output)
+	} else if strings.Fields(lines[0])[0] != "ID" || strings.Contains(lines[0], "COUNT") {
+		t.Errorf("wrong header %v", lines[0])
+	} else if f := strings.Fields(lines[1]); f[0] != "org/a" || f[len(f)-1] != "s1,s2" {
+		t.Errorf("wrong first row %v", lines[1])
+	} else if !strings.HasPrefix(lines[2], "org/b") {
+		t.Errorf("wrong second row %v", lines[2])
+	}
+
+	output, err = FormatOutput(testResources(), OUTPUT_WIDE, testTable)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	} else if !strings.Contains(output, "COUNT") || !strings.Contains(output, "1700000000") {
+		t.Errorf("wrong wide output: %v", output)
+	}
+
+	// a list of names and rows that are selected from the output
+	if output, err := FormatOutput([]string{"n1", "n2"}, OUTPUT_TABLE, NamesTable); err != nil || output != "NAME\nn1\nn2" {
+		t.Errorf("wrong names output: %v %v", output, err)
+	}
+	rowsTable := &TableFormat{Rows: ".services", Columns: []TableColumn{{Header: "URL", Path: ".url"}}}
+	if output, err := FormatOutput(testResources()["org/a"], OUTPUT_TABLE, rowsTable); err != nil || output != "URL\ns1\ns2" {
+		t.Errorf("wrong output for selected rows: %v %v", output, err)
+	}
+}
+
+func Test_FormatOutput_Other(t *testing.T) {
+	if output, err := FormatOutput(testResources(), OUTPUT_YAML, testTable); err != nil {
+		t.Errorf("unexpected error %v", err)
+	} else if !strings.Contains(output, "org/b:") || !strings.Contains(output, "count: 1700000000") {
+		t.Errorf("wrong yaml output: %v", output)
+	}
+
+	if output, err := FormatOutput(testResources(), OUTPUT_JSONPATH+"{.org/a.services[*].url}", testTable); err != nil || output != "s1 s2" {
+		t.Errorf("wrong jsonpath output: %v %v", output, err)
+	} else if output, err := FormatOutput(testResources(), OUTPUT_JSONPATH+".org/b.label", testTable); err != nil || output != "second" {
+		t.Errorf("wrong jsonpath output without braces: %v %v", output, err)
+	}
+
+	if output, err := FormatOutput(testResources(), OUTPUT_GO_TEMPLATE+"{{range $k, $v := .}}{{$k}}={{$v.arch}} {{end}}", testTable); err != nil || output != "org/a=amd64 org/b=arm " {
+		t.Errorf("wrong go-template output: %v %v", output, err)
+	}
+}
+
+func Test_CheckOutputFormat(t *testing.T) {
+	for _, format := range []string{"", OUTPUT_JSON, OUTPUT_YAML, OUTPUT_TABLE, OUTPUT_WIDE, "jsonpath={.a}", "go-template={{.a}}"} {
+		if err := CheckOutputFormat(format); err != nil {
+			t.Errorf("unexpected error for %v: %v", format, err)
+		}
+	}
+	for _, format := range []string{"xml", "jsonpath={.a", "go-template={{.a"} {
+		if err := CheckOutputFormat(format); err == nil {
+			t.Errorf("expected an error for %v", format)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package credentials
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"github.com/open-horizon/anax/i18n"
+	"golang.org/x/crypto/ssh/terminal"
+	"os"
+	"strings"
+)
+
+// Encrypt a credential and store it in the credential store. If the value is not specified, it is prompted for, or
+// read from stdin when stdin is not a terminal, so that it does not end up in the shell history.
+func Set(name string, value string, keyFile string) {
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	if name == "" {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("the credential name must not be empty."))
+	}
+	if value == "" {
+		if terminal.IsTerminal(int(os.Stdin.Fd())) {
+			var err error
+			if value, err = cliutils.PromptSecret(msgPrinter.Sprintf("Value of %v: ", name)); err != nil {
+				cliutils.Fatal(cliutils.CLI_INPUT_ERROR, err.Error())
+			}
+		} else {
+			line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+			if err != nil && line == "" {
+				cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("Unable to read the value of %v from stdin. %v", name, err))
+			}
+			value = strings.TrimSpace(line)
+		}
+	}
+	if value == "" {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("the value of credential %v must not be empty.", name))
+	}
+
+	store := openStoreOrFatal(keyFile, true)
+	if err := store.Set(name, value); err != nil {
+		cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, msgPrinter.Sprintf("Unable to encrypt credential %v. %v", name, err))
+	}
+	saveStoreOrFatal(store)
+
+	msgPrinter.Printf("Credential %v stored.", name)
+	msgPrinter.Println()
+}
+
+// Decrypt a credential and display it. This is the only command that displays a stored credential.
+func Get(name string, keyFile string) {
+	store := openStoreOrFatal(keyFile, false)
+	value, err := store.Get(name)
+	if err != nil {
+		cliutils.Fatal(cliutils.NOT_FOUND, err.Error())
+	}
+	fmt.Println(value)
+}
+
+// Display the names of the stored credentials. The store does not have to be unlocked for this.
+func List() {
+	store, err := cliutils.LoadCredentialStore(cliutils.GetCredentialsFile())
+	if err != nil {
+		cliutils.Fatal(cliutils.FILE_IO_ERROR, err.Error())
+	}
+	for _, name := range store.Names() {
+		fmt.Println(name)
+	}
+}
+
+// Remove a credential from the credential store. The names are not encrypted, so the store does not have to be
+// unlocked for this.
+func Remove(name string, force bool) {
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	credFile := cliutils.GetCredentialsFile()
+	store, err := cliutils.LoadCredentialStore(credFile)
+	if err != nil {
+		cliutils.Fatal(cliutils.FILE_IO_ERROR, err.Error())
+	}
+	if _, ok := store.Credentials[name]; !ok {
+		cliutils.Fatal(cliutils.NOT_FOUND, msgPrinter.Sprintf("credential %v is not in the credential store.", name))
+	}
+	if !force {
+		cliutils.ConfirmRemove(msgPrinter.Sprintf("Are you sure you want to remove credential %v?", name))
+	}
+
+	delete(store.Credentials, name)
+	saveStoreOrFatal(store)
+
+	msgPrinter.Printf("Credential %v removed.", name)
+	msgPrinter.Println()
+}
+
+func openStoreOrFatal(keyFile string, confirmNew bool) *cliutils.CredentialStore {
+	store, err := cliutils.OpenCredentialStore(keyFile, confirmNew)
+	if err != nil {
+		cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, i18n.GetMessagePrinter().Sprintf("Unable to unlock the credential store. %v", err))
+	}
+	return store
+}
+
+func saveStoreOrFatal(store *cliutils.CredentialStore) {
+	credFile := cliutils.GetCredentialsFile()
+	if err := store.Save(credFile); err != nil {
+		cliutils.Fatal(cliutils.FILE_IO_ERROR, i18n.GetMessagePrinter().Sprintf("Unable to write credentials file %v. %v", credFile, err))
+	}
+}
@@ -16,7 +16,6 @@ import (
 	"github.com/open-horizon/anax/i18n"
 	"github.com/open-horizon/anax/persistence"
 	"github.com/open-horizon/anax/policy"
-	"os"
 )
 
 // check if the policies are compatible
@@ -184,7 +183,7 @@ func verifyCompCheckParameters(org string, userPw string, nodeId string, nodeTyp
 	} else {
 		if (useBPol && nodePolFile == "") || (!useBPol && nodeUIFile == "") {
 			// get node id from HZN_EXCHANGE_NODE_AUTH
-			if nodeIdTok := os.Getenv("HZN_EXCHANGE_NODE_AUTH"); nodeIdTok != "" {
+			if nodeIdTok := cliutils.GetEnvVarOrStoredCredential("HZN_EXCHANGE_NODE_AUTH"); nodeIdTok != "" {
 				nodeIdToUse, _ = cliutils.SplitIdToken(nodeIdTok)
 				if nodeIdToUse != "" {
 					// true means will use exchange call
@@ -10,7 +10,6 @@ import (
 	"github.com/open-horizon/anax/compcheck"
 	"github.com/open-horizon/anax/externalpolicy"
 	"github.com/open-horizon/anax/i18n"
-	"os"
 )
 
 func readExternalPolicyFile(filePath string, inputFileStruct *externalpolicy.ExternalPolicy) {
@@ -137,7 +136,7 @@ func verifyPolicyCompatibleParameters(org string, userPw string, nodeId string,
 	} else {
 		if nodePolFile == "" {
 			// get node id from HZN_EXCHANGE_NODE_AUTH
-			if nodeIdTok := os.Getenv("HZN_EXCHANGE_NODE_AUTH"); nodeIdTok != "" {
+			if nodeIdTok := cliutils.GetEnvVarOrStoredCredential("HZN_EXCHANGE_NODE_AUTH"); nodeIdTok != "" {
 				nodeIdToUse, _ = cliutils.SplitIdToken(nodeIdTok)
 				if nodeIdToUse != "" {
 					// true means will use exchange call
@@ -13,7 +13,6 @@ import (
 	"github.com/open-horizon/anax/i18n"
 	"github.com/open-horizon/anax/policy"
 	"github.com/open-horizon/anax/semanticversion"
-	"os"
 )
 
 func readServiceFile(filePath string, inputFileStruct *common.ServiceFile) {
@@ -166,7 +165,7 @@ func verifyUserInputCompatibleParameters(org string, userPw string, nodeId strin
 	} else {
 		if nodeUIFile == "" {
 			// get node id from HZN_EXCHANGE_NODE_AUTH
-			if nodeIdTok := os.Getenv("HZN_EXCHANGE_NODE_AUTH"); nodeIdTok != "" {
+			if nodeIdTok := cliutils.GetEnvVarOrStoredCredential("HZN_EXCHANGE_NODE_AUTH"); nodeIdTok != "" {
 				nodeIdToUse, _ = cliutils.SplitIdToken(nodeIdTok)
 				if nodeIdToUse != "" {
 					// true means will use exchange call
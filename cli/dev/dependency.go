@@ -368,6 +368,8 @@ func verifyFetchInput(homeDirectory string, project string, specRef string, url
 		return "", errors.New(msgPrinter.Sprintf("one of --project, or --specRef and --org, or --url and --org must be specified."))
 	} else if (specRef != "" && org == "") || (specRef == "" && org != "" && url == "") || (url != "" && org == "") {
 		return "", errors.New(msgPrinter.Sprintf("either --specRef and --org, or --url and --org must be specified."))
+	} else if version != "" && !semanticversion.IsVersionString(version) && !semanticversion.IsVersionExpression(version) {
+		return "", errors.New(msgPrinter.Sprintf("--ver %v is not a valid version or version range.", version))
 	}
 
 	// Verify that the inputs match with the project type.
@@ -565,7 +567,7 @@ func fetchExchangeProjectDependency(homeDirectory string, specRef string, url st
 	msgPrinter := i18n.GetMessagePrinter()
 
 	// Pull the metadata from the exchange, including any of this dependency's dependencies.
-	sDef, err := getExchangeDefinition(homeDirectory, specRef, url, org, "", arch, userCreds, userInputFile)
+	sDef, err := getExchangeDefinition(homeDirectory, specRef, url, org, version, arch, userCreds, userInputFile)
 	if err != nil {
 		return err
 	}
@@ -731,7 +733,11 @@ func getServiceDefinitionDependencies(homeDirectory string, serviceDef *common.S
 	for _, rs := range serviceDef.RequiredServices {
 		// Get the service definition for each required service. Dependencies refer to each other by version range, so the
 		// service we're looking for might not be at the exact version specified in the required service element.
-		if sDef, err := getServiceDefinition(homeDirectory, rs.URL, rs.Org, "", rs.Arch, userCreds); err != nil {
+		version := ""
+		if semanticversion.IsVersionExpression(rs.VersionRange) {
+			version = rs.VersionRange
+		}
+		if sDef, err := getServiceDefinition(homeDirectory, rs.URL, rs.Org, version, rs.Arch, userCreds); err != nil {
 			return err
 		} else if err := UpdateDependencyFile(homeDirectory, sDef); err != nil {
 			return err
@@ -746,9 +752,16 @@ func getServiceDefinition(homeDirectory, surl string, org string, version string
 	// get message printer
 	msgPrinter := i18n.GetMessagePrinter()
 
-	// Construct the resource URL suffix.
+	// Construct the resource URL suffix. A version range is resolved to the highest version within the range,
+	// a specific version is searched for in the Exchange.
 	resSuffix := fmt.Sprintf("orgs/%v/services?url=%v", org, surl)
-	if version != "" {
+	var vRange *semanticversion.Version_Expression
+	if semanticversion.IsVersionExpression(version) {
+		var err error
+		if vRange, err = semanticversion.Version_Expression_Factory(version); err != nil {
+			return nil, errors.New(msgPrinter.Sprintf("version range %v is not valid: %v", version, err))
+		}
+	} else if version != "" {
 		resSuffix += fmt.Sprintf("&version=%v", version)
 	}
 	if arch == "" {
@@ -769,10 +782,12 @@ func getServiceDefinition(homeDirectory, surl string, org string, version string
 	// Parse the response and extract the highest version service definition or return an error.
 	var serviceDef exchange.ServiceDefinition
 	var serviceId string
-	if len(resp.Services) > 1 {
-		highest, sDef, sId, err := exchange.GetHighestVersion(resp.Services, nil)
+	if len(resp.Services) > 1 || (len(resp.Services) == 1 && vRange != nil) {
+		highest, sDef, sId, err := exchange.GetHighestVersion(resp.Services, vRange)
 		if err != nil {
 			return nil, err
+		} else if highest == "" && vRange != nil {
+			return nil, errors.New(msgPrinter.Sprintf("unable to find a version of %v %v within %v in the Exchange.", surl, org, vRange.Get_expression()))
 		} else if highest == "" {
 			return nil, errors.New(msgPrinter.Sprintf("unable to find highest version of %v %v in the Exchange: %v", surl, org, resp.Services))
 		} else {
@@ -341,6 +341,12 @@ func createEnvVarMap(agreementId string,
 		cw.Config.GetFileSyncServiceAPIListen(),
 		strconv.Itoa(int(cw.Config.GetFileSyncServiceAPIPort())))
 
+	// The workload API is started with the services, see StartWorkloadAPI.
+	cutil.SetWorkloadAPIEnvvars(envvars,
+		config.ENVVAR_PREFIX,
+		cw.Config.GetWorkloadAPIListen(),
+		strconv.Itoa(int(cw.Config.GetWorkloadAPIPort())))
+
 	// Second, add the Horizon system env vars. Some of these can come from the global section of a user inputs file. To do this we have to
 	// convert the attributes in the userinput file into API attributes so that they can be validity checked. Then they are converted to
 	// persistence attributes so that they can be further converted to environment variables. This is the progression that anax uses when
@@ -402,7 +408,19 @@ func createContainerWorker() (*container.ContainerWorker, error) {
 		return nil, err
 	}
 
-	config := &config.HorizonConfig{
+	config := createDevConfig(workloadStorageDir)
+
+	// Create the folder for SSL certificates (under authentication path)
+	if err := os.MkdirAll(config.GetESSSSLClientCertPath(), 0755); err != nil {
+		return nil, err
+	}
+
+	return container.CreateCLIContainerWorker(config)
+}
+
+// The agent config of the mocked Horizon Agent environment.
+func createDevConfig(workloadStorageDir string) *config.HorizonConfig {
+	return &config.HorizonConfig{
 		Edge: config.Config{
 			ServiceStorage:                workloadStorageDir,
 			DefaultServiceRegistrationRAM: 0,
@@ -415,13 +433,6 @@ func createContainerWorker() (*container.ContainerWorker, error) {
 		AgreementBot:  config.AGConfig{},
 		Collaborators: config.Collaborators{},
 	}
-
-	// Create the folder for SSL certificates (under authentication path)
-	if err := os.MkdirAll(config.GetESSSSLClientCertPath(), 0755); err != nil {
-		return nil, err
-	}
-
-	return container.CreateCLIContainerWorker(config)
 }
 
 // This function is used to setup context to execute a service container.
@@ -0,0 +1,163 @@
+package dev
+
+import (
+	"errors"
+	"github.com/boltdb/bolt"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/container"
+	"github.com/open-horizon/anax/events"
+	"github.com/open-horizon/anax/i18n"
+	"github.com/open-horizon/anax/persistence"
+	"github.com/open-horizon/anax/resource"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// The hidden command that serves the workload API in the background while services run in test mode.
+const SERVICE_WORKLOAD_API_COMMAND = "workloadapi"
+
+// The files of the workload API in the dev working directory. The database is kept across test runs, so that the keys
+// in the service scope of the key-value store are kept, as they would be when a service is upgraded on a node.
+const WORKLOAD_API_DB_FILE = "workloadapi.db"
+const WORKLOAD_API_PID_FILE = "workloadapi.pid"
+const WORKLOAD_API_LOG_FILE = "workloadapi.log"
+
+// The number of seconds to wait for the workload API to start or stop.
+const WORKLOAD_API_WAIT_S = 10
+
+// Start the workload API in a background hzn process, so that the services running in test mode can report their
+// status and use the key-value store. The SSL certificate of the ESS is used, it is created if the ESS is not running.
+func StartWorkloadAPI(cw *container.ContainerWorker, org string) error {
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	// Stop the workload API of a previous test that was not stopped.
+	if err := StopWorkloadAPI(); err != nil {
+		return err
+	}
+
+	certFile := path.Join(cw.Config.GetESSSSLClientCertPath(), config.HZN_FSS_CERT_FILE)
+	if _, err := os.Stat(certFile); os.IsNotExist(err) {
+		if err := resource.CreateCertificate(org, cw.Config.GetESSSSLCertKeyPath(), cw.Config.GetESSSSLClientCertPath()); err != nil {
+			return errors.New(msgPrinter.Sprintf("unable to create SSL certificate for the workload API, error %v", err))
+		}
+	}
+
+	hzn, err := os.Executable()
+	if err != nil {
+		return errors.New(msgPrinter.Sprintf("unable to find the hzn executable, error %v", err))
+	}
+
+	logFile, err := os.Create(path.Join(GetDevWorkingDirectory(), WORKLOAD_API_LOG_FILE))
+	if err != nil {
+		return errors.New(msgPrinter.Sprintf("unable to create the workload API log file, error %v", err))
+	}
+	defer logFile.Close()
+
+	// The process is in its own session, so that it keeps running after this command exits.
+	cmd := exec.Command(hzn, "dev", SERVICE_COMMAND, SERVICE_WORKLOAD_API_COMMAND)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		return errors.New(msgPrinter.Sprintf("unable to start the workload API, error %v", err))
+	}
+
+	pid := cmd.Process.Pid
+	cmd.Process.Release()
+	if err := ioutil.WriteFile(path.Join(GetDevWorkingDirectory(), WORKLOAD_API_PID_FILE), []byte(strconv.Itoa(pid)), 0600); err != nil {
+		syscall.Kill(pid, syscall.SIGTERM)
+		return errors.New(msgPrinter.Sprintf("unable to save the workload API process id, error %v", err))
+	}
+
+	socket := cw.Config.GetWorkloadAPIListen()
+	for i := 0; i < WORKLOAD_API_WAIT_S*10; i++ {
+		if _, err := os.Stat(socket); err == nil {
+			cliutils.Verbose(msgPrinter.Sprintf("Started the workload API on %v, process %v.", socket, pid))
+			return nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	StopWorkloadAPI()
+	return errors.New(msgPrinter.Sprintf("the workload API did not start, see %v", logFile.Name()))
+}
+
+// Stop the workload API that was started by StartWorkloadAPI, if it is running.
+func StopWorkloadAPI() error {
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	pidFile := path.Join(GetDevWorkingDirectory(), WORKLOAD_API_PID_FILE)
+	pidBytes, err := ioutil.ReadFile(pidFile)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return errors.New(msgPrinter.Sprintf("unable to read the workload API process id, error %v", err))
+	}
+
+	if pid, err := strconv.Atoi(strings.TrimSpace(string(pidBytes))); err != nil {
+		cliutils.Verbose(msgPrinter.Sprintf("Ignoring the invalid workload API process id %v.", string(pidBytes)))
+	} else if err := syscall.Kill(pid, syscall.SIGTERM); err == nil {
+		// Give the workload API a chance to close its database.
+		for i := 0; i < WORKLOAD_API_WAIT_S*10 && syscall.Kill(pid, 0) == nil; i++ {
+			time.Sleep(100 * time.Millisecond)
+		}
+		cliutils.Verbose(msgPrinter.Sprintf("Stopped the workload API, process %v.", pid))
+	}
+
+	if err := os.Remove(pidFile); err != nil && !os.IsNotExist(err) {
+		return errors.New(msgPrinter.Sprintf("unable to remove the workload API process id file, error %v", err))
+	}
+	return nil
+}
+
+// Serve the workload API until the process is stopped. This is the background process that StartWorkloadAPI runs.
+// The keys in the scope of an agreement are removed when the workload API stops, because the test agreement ends
+// when the services are stopped.
+func ServeWorkloadAPI() {
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	cfg := createDevConfig("")
+
+	db, err := bolt.Open(path.Join(GetDevWorkingDirectory(), WORKLOAD_API_DB_FILE), 0600, &bolt.Options{Timeout: 10 * time.Second})
+	if err != nil {
+		cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, msgPrinter.Sprintf("unable to open the workload API database, error %v", err))
+	}
+	defer db.Close()
+
+	// The status reports are only logged, there is no agent to send them to.
+	messages := make(chan events.Message, 10)
+	go func() {
+		for msg := range messages {
+			if report, ok := msg.(*events.WorkloadReportMessage); ok {
+				msgPrinter.Printf("%v reported health %v: %v", report.Report.Service, report.Report.Health, report.Report.Message)
+				msgPrinter.Println()
+			}
+		}
+	}()
+
+	api := resource.NewWorkloadAPI(cfg, db, resource.NewAuthenticationManager(cfg.GetFileSyncServiceAuthPath()), messages)
+	if err := api.Start(); err != nil {
+		cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, msgPrinter.Sprintf("unable to start the workload API, error %v", err))
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT)
+	<-stop
+
+	api.Stop()
+	if err := persistence.DeleteAllWorkloadKVAgreements(db); err != nil {
+		msgPrinter.Printf("Failed to remove the keys of the test agreements, error: %v", err)
+		msgPrinter.Println()
+	}
+}
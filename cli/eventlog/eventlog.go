@@ -6,7 +6,9 @@ import (
 	"github.com/open-horizon/anax/cli/cliutils"
 	"github.com/open-horizon/anax/i18n"
 	"github.com/open-horizon/anax/persistence"
+	"os"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -71,55 +73,46 @@ func List(all bool, detail bool, selections []string, tailing bool) {
 		url_s = fmt.Sprintf("%v/all", url_s)
 	}
 
+	sel_s := ""
 	if len(selections) > 0 {
 		if s, err := getSelectionString(selections); err != nil {
 			cliutils.Fatal(cliutils.CLI_INPUT_ERROR, "%v", err)
 		} else {
-			url_s = fmt.Sprintf("%v?%v", url_s, s)
+			sel_s = s
 		}
 	}
 
+	if tailing {
+		// follow the event log stream, it returns the existing records first and then the new ones as they are saved.
+		stream_url := fmt.Sprintf("%v/stream", url_s)
+		if sel_s != "" {
+			stream_url = fmt.Sprintf("%v?%v", stream_url, sel_s)
+		}
+		httpCode := cliutils.HorizonGetStream(stream_url, []int{200, 404}, func(line []byte) {
+			var el persistence.EventLogRaw
+			if err := json.Unmarshal(line, &el); err != nil {
+				cliutils.Fatal(cliutils.JSON_PARSING_ERROR, i18n.GetMessagePrinter().Sprintf("failed to unmarshal 'hzn eventlog list' output: %v", err))
+			}
+			printEventLogs([]persistence.EventLogRaw{el}, detail)
+		})
+
+		// an older agent does not have the stream api, poll it instead.
+		if httpCode != 404 {
+			return
+		}
+	}
+
+	if sel_s != "" {
+		url_s = fmt.Sprintf("%v?%v", url_s, sel_s)
+	}
+
 	for {
 		// get the eventlog from anax
 		apiOutput := make([]persistence.EventLogRaw, 0)
 		cliutils.HorizonGet(url_s, []int{200}, &apiOutput, false)
 
 		//output
-
-		if detail {
-			long_output := make([]EventLog, len(apiOutput))
-			for i, v := range apiOutput {
-				long_output[i].Id = v.Id
-				long_output[i].Timestamp = cliutils.ConvertTime(v.Timestamp)
-				long_output[i].Severity = v.Severity
-				long_output[i].Message = v.Message
-				long_output[i].EventCode = v.EventCode
-				long_output[i].SourceType = v.SourceType
-				long_output[i].Source = v.Source
-			}
-
-			jsonBytes, err := cliutils.DisplayAsJson(long_output)
-			if err != nil {
-				cliutils.Fatal(cliutils.JSON_PARSING_ERROR, i18n.GetMessagePrinter().Sprintf("failed to marshal 'hzn eventlog list' output: %v", err))
-			}
-			if len(jsonBytes) > 3 {
-				fmt.Printf("%s", jsonBytes[2:len(jsonBytes)-2])
-			}
-		} else {
-			short_output := make([]string, len(apiOutput))
-			for i, v := range apiOutput {
-				t := time.Unix(int64(v.Timestamp), 0)
-				short_output[i] = fmt.Sprintf("%v:   %v", t.Format("2006-01-02 15:04:05"), v.Message)
-			}
-			jsonBytes, err := cliutils.DisplayAsJson(short_output)
-			if err != nil {
-				cliutils.Fatal(cliutils.JSON_PARSING_ERROR, i18n.GetMessagePrinter().Sprintf("failed to marshal 'hzn eventlog list' output: %v", err))
-			}
-
-			if len(jsonBytes) > 3 {
-				fmt.Printf("%s", jsonBytes[2:len(jsonBytes)-2])
-			}
-		}
+		printEventLogs(apiOutput, detail)
 
 		if tailing {
 			// selection contraints for most recent records
@@ -150,6 +143,93 @@ func List(all bool, detail bool, selections []string, tailing bool) {
 	}
 }
 
+// The table output format of the event logs.
+var eventLogTable = &cliutils.TableFormat{Columns: []cliutils.TableColumn{
+	{Header: "TIME", Path: ".timestamp"},
+	{Header: "SEVERITY", Path: ".severity"},
+	{Header: "MESSAGE", Path: ".message"},
+	{Header: "ID", Path: ".record_id", Wide: true},
+	{Header: "EVENT CODE", Path: ".event_code", Wide: true},
+	{Header: "SOURCE TYPE", Path: ".source_type", Wide: true},
+}}
+
+// The table header is only displayed once when the event log is followed.
+var eventLogHeaderPrinted bool
+
+// print the event logs, either with details or as time stamped messages. The output formats other than json always
+// display the details.
+func printEventLogs(apiOutput []persistence.EventLogRaw, detail bool) {
+	format := cliutils.GetOutputFormat()
+	if detail || format != cliutils.OUTPUT_JSON {
+		long_output := make([]EventLog, len(apiOutput))
+		for i, v := range apiOutput {
+			long_output[i].Id = v.Id
+			long_output[i].Timestamp = cliutils.ConvertTime(v.Timestamp)
+			long_output[i].Severity = v.Severity
+			long_output[i].Message = v.Message
+			long_output[i].EventCode = v.EventCode
+			long_output[i].SourceType = v.SourceType
+			long_output[i].Source = v.Source
+		}
+
+		if format != cliutils.OUTPUT_JSON {
+			printFormattedEventLogs(long_output, format)
+			return
+		}
+
+		jsonBytes, err := cliutils.DisplayAsJson(long_output)
+		if err != nil {
+			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, i18n.GetMessagePrinter().Sprintf("failed to marshal 'hzn eventlog list' output: %v", err))
+		}
+		if len(jsonBytes) > 3 {
+			fmt.Printf("%s", jsonBytes[2:len(jsonBytes)-2])
+		}
+	} else {
+		short_output := make([]string, len(apiOutput))
+		for i, v := range apiOutput {
+			t := time.Unix(int64(v.Timestamp), 0)
+			short_output[i] = fmt.Sprintf("%v:   %v", t.Format("2006-01-02 15:04:05"), v.Message)
+		}
+		jsonBytes, err := cliutils.DisplayAsJson(short_output)
+		if err != nil {
+			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, i18n.GetMessagePrinter().Sprintf("failed to marshal 'hzn eventlog list' output: %v", err))
+		}
+
+		if len(jsonBytes) > 3 {
+			fmt.Printf("%s", jsonBytes[2:len(jsonBytes)-2])
+		}
+	}
+}
+
+// print the event logs in one of the other output formats. The records of a followed event log are displayed as
+// they arrive, so the table header is only displayed for the first of them.
+func printFormattedEventLogs(eventLogs []EventLog, format string) {
+	if (format == cliutils.OUTPUT_TABLE || format == cliutils.OUTPUT_WIDE) && eventLogHeaderPrinted && len(eventLogs) == 0 {
+		return
+	}
+	output, err := cliutils.FormatOutput(eventLogs, format, eventLogTable)
+	if err != nil {
+		cliutils.Fatal(cliutils.JSON_PARSING_ERROR, i18n.GetMessagePrinter().Sprintf("failed to format 'hzn eventlog list' output: %v", err))
+	}
+	if format == cliutils.OUTPUT_TABLE || format == cliutils.OUTPUT_WIDE {
+		if eventLogHeaderPrinted {
+			output = output[strings.Index(output, "\n")+1:]
+		}
+		eventLogHeaderPrinted = true
+	}
+	fmt.Println(output)
+}
+
+// The table output format of the surfaced errors.
+var surfaceErrorTable = &cliutils.TableFormat{Columns: []cliutils.TableColumn{
+	{Header: "ID", Path: ".record_id"},
+	{Header: "TIME", Path: ".timestamp"},
+	{Header: "SERVICE", Path: ".workload.url"},
+	{Header: "MESSAGE", Path: ".message"},
+	{Header: "EVENT CODE", Path: ".event_code", Wide: true},
+	{Header: "HIDDEN", Path: ".hidden", Wide: true},
+}}
+
 func ListSurfaced(long bool) {
 	apiOutput := make([]persistence.SurfaceError, 0)
 	cliutils.HorizonGet("eventlog/surface", []int{200}, &apiOutput, false)
@@ -171,19 +251,80 @@ func ListSurfaced(long bool) {
 			long_output[i].SourceType = fullV.SourceType
 			long_output[i].Source = fullV.Source
 		}
-		jsonBytes, err := cliutils.DisplayAsJson(long_output)
-		if err != nil {
-			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, i18n.GetMessagePrinter().Sprintf("failed to marshal 'hzn eventlog surface' output: %v", err))
-		}
-		fmt.Printf("%s\n", jsonBytes)
+		cliutils.PrintOutput(long_output, eventLogTable, "eventlog surface")
 	} else {
 		if len(apiOutput) == 0 {
 			apiOutput = []persistence.SurfaceError{}
 		}
-		jsonBytes, err := cliutils.DisplayAsJson(apiOutput)
+		cliutils.PrintOutput(apiOutput, surfaceErrorTable, "eventlog surface")
+	}
+}
+
+// Convert the since flag into unix seconds. It can be a duration before now, an RFC3339 time or unix seconds.
+func parseSince(since string) (uint64, error) {
+	if d, err := time.ParseDuration(since); err == nil {
+		return uint64(time.Now().Add(-d).Unix()), nil
+	} else if t, err := time.Parse(time.RFC3339, since); err == nil {
+		return uint64(t.Unix()), nil
+	} else if s, err := strconv.ParseUint(since, 10, 64); err == nil {
+		return s, nil
+	}
+	return 0, fmt.Errorf(i18n.GetMessagePrinter().Sprintf("The since value %v is not a valid duration, RFC3339 time or unix seconds.", since))
+}
+
+// Export the event logs as json lines, one event log per line, to stdout or to the given file.
+func Export(all bool, since string, selections []string, file string) {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	url_s := "eventlog"
+	if all {
+		url_s = fmt.Sprintf("%v/all", url_s)
+	}
+
+	sels := make([]string, len(selections))
+	copy(sels, selections)
+	if since != "" {
+		if s, err := parseSince(since); err != nil {
+			cliutils.Fatal(cliutils.CLI_INPUT_ERROR, "%v", err)
+		} else if s > 0 {
+			// the api only has the greater than operator
+			sels = append(sels, fmt.Sprintf("timestamp>%v", s-1))
+		}
+	}
+
+	if len(sels) > 0 {
+		if s, err := getSelectionString(sels); err != nil {
+			cliutils.Fatal(cliutils.CLI_INPUT_ERROR, "%v", err)
+		} else {
+			url_s = fmt.Sprintf("%v?%v", url_s, s)
+		}
+	}
+
+	apiOutput := make([]persistence.EventLogRaw, 0)
+	cliutils.HorizonGet(url_s, []int{200}, &apiOutput, false)
+
+	out := os.Stdout
+	if file != "" {
+		f, err := os.OpenFile(file, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+		if err != nil {
+			cliutils.Fatal(cliutils.FILE_IO_ERROR, msgPrinter.Sprintf("failed to create file %v: %v", file, err))
+		}
+		defer f.Close()
+		out = f
+	}
+
+	for _, el := range apiOutput {
+		line, err := json.Marshal(el)
 		if err != nil {
-			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, i18n.GetMessagePrinter().Sprintf("failed to marshal 'hzn eventlog surface' output: %v", err))
+			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'hzn eventlog export' output: %v", err))
+		}
+		if _, err := fmt.Fprintf(out, "%s\n", line); err != nil {
+			cliutils.Fatal(cliutils.FILE_IO_ERROR, msgPrinter.Sprintf("failed to write the event logs: %v", err))
 		}
-		fmt.Printf("%s\n", jsonBytes)
+	}
+
+	if file != "" {
+		msgPrinter.Fprintf(os.Stderr, "Exported %v event logs to %v.", len(apiOutput), file)
+		msgPrinter.Fprintln(os.Stderr)
 	}
 }
@@ -1,7 +1,6 @@
 package exchange
 
 import (
-	"encoding/json"
 	"fmt"
 	"github.com/open-horizon/anax/cli/cliutils"
 	"github.com/open-horizon/anax/exchange"
@@ -16,6 +15,15 @@ type ExchangeAgbots struct {
 	Agbots    map[string]interface{} `json:"agbots"`
 }
 
+// The table output format of 'hzn exchange agbot list'.
+var exchangeAgbotTable = &cliutils.TableFormat{Columns: []cliutils.TableColumn{
+	{Header: "ID"},
+	{Header: "NAME", Path: ".name"},
+	{Header: "LAST HEARTBEAT", Path: ".lastHeartbeat"},
+	{Header: "OWNER", Path: ".owner", Wide: true},
+	{Header: "MSG ENDPOINT", Path: ".msgEndPoint", Wide: true},
+}}
+
 func AgbotList(org string, userPw string, agbot string, namesOnly bool) {
 	cliutils.SetWhetherUsingApiKey(userPw)
 	var agbotOrg string
@@ -31,11 +39,7 @@ func AgbotList(org string, userPw string, agbot string, namesOnly bool) {
 		for a := range resp.Agbots {
 			agbots = append(agbots, a)
 		}
-		jsonBytes, err := json.MarshalIndent(agbots, "", cliutils.JSON_INDENT)
-		if err != nil {
-			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, i18n.GetMessagePrinter().Sprintf("failed to marshal 'exchange agbot list' output: %v", err))
-		}
-		fmt.Printf("%s\n", jsonBytes)
+		cliutils.PrintOutput(agbots, cliutils.NamesTable, "exchange agbot list")
 	} else {
 		// Display the full resources
 		var agbots ExchangeAgbots
@@ -43,8 +47,7 @@ func AgbotList(org string, userPw string, agbot string, namesOnly bool) {
 		if httpCode == 404 && agbot != "" {
 			cliutils.Fatal(cliutils.NOT_FOUND, i18n.GetMessagePrinter().Sprintf("agbot '%s' not found in org %s", agbot, agbotOrg))
 		}
-		output := cliutils.MarshalIndent(agbots.Agbots, "exchange agbots list")
-		fmt.Println(output)
+		cliutils.PrintOutput(agbots.Agbots, exchangeAgbotTable, "exchange agbots list")
 	}
 }
 
@@ -1,7 +1,6 @@
 package exchange
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
 	"github.com/open-horizon/anax/businesspolicy"
@@ -14,6 +13,19 @@ import (
 	"net/http"
 )
 
+// The table output format of 'hzn exchange deployment listpolicy'.
+var exchangeDeploymentPolicyTable = &cliutils.TableFormat{Columns: []cliutils.TableColumn{
+	{Header: "ID"},
+	{Header: "LABEL", Path: ".label"},
+	{Header: "SERVICE", Path: ".service.name"},
+	{Header: "SERVICE ORG", Path: ".service.org"},
+	{Header: "ARCH", Path: ".service.arch"},
+	{Header: "VERSIONS", Path: ".service.serviceVersions[*].version", Wide: true},
+	{Header: "CONSTRAINTS", Path: ".constraints[*]", Wide: true},
+	{Header: "OWNER", Path: ".owner", Wide: true},
+	{Header: "LAST UPDATED", Path: ".lastUpdated", Wide: true},
+}}
+
 //BusinessListPolicy lists all the policies in the org or only the specified policy if one is given
 func BusinessListPolicy(org string, credToUse string, policy string, namesOnly bool) {
 	cliutils.SetWhetherUsingApiKey(credToUse)
@@ -34,28 +46,15 @@ func BusinessListPolicy(org string, credToUse string, policy string, namesOnly b
 	if httpCode == 404 && policy != "" {
 		cliutils.Fatal(cliutils.NOT_FOUND, msgPrinter.Sprintf("Policy %s not found in org %s", policy, polOrg))
 	} else if httpCode == 404 {
-		policyNameList := []string{}
-		fmt.Println(policyNameList)
+		cliutils.PrintOutput([]string{}, cliutils.NamesTable, "exchange deployment listpolicy")
 	} else if namesOnly && policy == "" {
 		policyNameList := []string{}
 		for bPolicy := range policyList.BusinessPolicy {
 			policyNameList = append(policyNameList, bPolicy)
 		}
-		jsonBytes, err := json.MarshalIndent(policyNameList, "", cliutils.JSON_INDENT)
-		if err != nil {
-			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'hzn exchange deployment listpolicy' output: %v", err))
-		}
-		fmt.Println(string(jsonBytes))
+		cliutils.PrintOutput(policyNameList, cliutils.NamesTable, "exchange deployment listpolicy")
 	} else {
-		buf := new(bytes.Buffer)
-		enc := json.NewEncoder(buf)
-		enc.SetEscapeHTML(false)
-		enc.SetIndent("", cliutils.JSON_INDENT)
-		err := enc.Encode(policyList.BusinessPolicy)
-		if err != nil {
-			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'hzn exchange deployment listpolicy' output: %v", err))
-		}
-		fmt.Println(string(buf.String()))
+		cliutils.PrintOutput(policyList.BusinessPolicy, exchangeDeploymentPolicyTable, "exchange deployment listpolicy")
 	}
 }
 
@@ -16,7 +16,6 @@ import (
 	"github.com/open-horizon/anax/policy"
 	"github.com/open-horizon/anax/semanticversion"
 	"net/http"
-	"os"
 )
 
 type ExchangeNodes struct {
@@ -48,6 +47,19 @@ type ExchangeNodeStatus struct {
 	LastUpdated     string                `json:"lastUpdated,omitempty"`
 }
 
+// The table output format of 'hzn exchange node list'.
+var exchangeNodeTable = &cliutils.TableFormat{Columns: []cliutils.TableColumn{
+	{Header: "ID"},
+	{Header: "NAME", Path: ".name"},
+	{Header: "TYPE", Path: ".nodeType"},
+	{Header: "PATTERN", Path: ".pattern"},
+	{Header: "ARCH", Path: ".arch"},
+	{Header: "LAST HEARTBEAT", Path: ".lastHeartbeat"},
+	{Header: "OWNER", Path: ".owner", Wide: true},
+	{Header: "LAST UPDATED", Path: ".lastUpdated", Wide: true},
+	{Header: "SERVICES", Path: ".registeredServices[*].url", Wide: true},
+}}
+
 func NodeList(org string, credToUse string, node string, namesOnly bool) {
 	cliutils.SetWhetherUsingApiKey(credToUse)
 	var nodeOrg string
@@ -63,11 +75,7 @@ func NodeList(org string, credToUse string, node string, namesOnly bool) {
 		for n := range resp.Nodes {
 			nodes = append(nodes, n)
 		}
-		jsonBytes, err := json.MarshalIndent(nodes, "", cliutils.JSON_INDENT)
-		if err != nil {
-			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, i18n.GetMessagePrinter().Sprintf("failed to marshal 'exchange node list' output: %v", err))
-		}
-		fmt.Printf("%s\n", jsonBytes)
+		cliutils.PrintOutput(nodes, cliutils.NamesTable, "exchange node list")
 	} else {
 		// Display the full resources
 		var nodes ExchangeNodes
@@ -75,8 +83,7 @@ func NodeList(org string, credToUse string, node string, namesOnly bool) {
 		if httpCode == 404 && node != "" {
 			cliutils.Fatal(cliutils.NOT_FOUND, i18n.GetMessagePrinter().Sprintf("node '%s' not found in org %s", node, nodeOrg))
 		}
-		output := cliutils.MarshalIndent(nodes.Nodes, "exchange node list")
-		fmt.Println(output)
+		cliutils.PrintOutput(nodes.Nodes, exchangeNodeTable, "exchange node list")
 	}
 }
 
@@ -333,7 +340,7 @@ func NodeConfirm(org, node, token string, nodeIdTok string) {
 		}
 	} else {
 		if node == "" && token == "" {
-			nodeIdTok = os.Getenv("HZN_EXCHANGE_NODE_AUTH")
+			nodeIdTok = cliutils.GetEnvVarOrStoredCredential("HZN_EXCHANGE_NODE_AUTH")
 		}
 	}
 
@@ -612,8 +619,7 @@ func NodeListStatus(org string, credToUse string, node string) {
 		cliutils.Fatal(cliutils.NOT_FOUND, msgPrinter.Sprintf("node status not found for node '%v/%v'.", nodeOrg, node))
 	}
 
-	output := cliutils.MarshalIndent(nodeStatus, "exchange node liststatus")
-	fmt.Println(output)
+	cliutils.PrintOutput(nodeStatus, nil, "exchange node liststatus")
 
 }
 
@@ -1,8 +1,6 @@
 package exchange
 
 import (
-	"encoding/json"
-	"fmt"
 	"github.com/open-horizon/anax/cli/cliutils"
 	"github.com/open-horizon/anax/exchange"
 	"github.com/open-horizon/anax/i18n"
@@ -22,6 +20,15 @@ type PatchOrgTags struct {
 	Tags map[string]string `json:"tags"`
 }
 
+// The table output format of 'hzn exchange org list'.
+var exchangeOrgTable = &cliutils.TableFormat{Columns: []cliutils.TableColumn{
+	{Header: "ID"},
+	{Header: "LABEL", Path: ".label"},
+	{Header: "DESCRIPTION", Path: ".description"},
+	{Header: "MAX NODES", Path: ".limits.maxNodes", Wide: true},
+	{Header: "LAST UPDATED", Path: ".lastUpdated", Wide: true},
+}}
+
 func OrgList(org, userPwCreds, theOrg string, long bool) {
 	// get message printer
 	msgPrinter := i18n.GetMessagePrinter()
@@ -52,14 +59,9 @@ func OrgList(org, userPwCreds, theOrg string, long bool) {
 			organizations = append(organizations, o)
 		}
 
-		jsonBytes, err := json.MarshalIndent(organizations, "", cliutils.JSON_INDENT)
-		if err != nil {
-			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'exchange org list' output: %v", err))
-		}
-		fmt.Printf("%s\n", jsonBytes)
+		cliutils.PrintOutput(organizations, cliutils.NamesTable, "exchange org list")
 	} else {
-		output := cliutils.MarshalIndent(orgs.Orgs, "exchange orgs list")
-		fmt.Println(output)
+		cliutils.PrintOutput(orgs.Orgs, exchangeOrgTable, "exchange orgs list")
 	}
 }
 
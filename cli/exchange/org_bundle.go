@@ -0,0 +1,538 @@
+package exchange
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/open-horizon/anax/businesspolicy"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"github.com/open-horizon/anax/exchange"
+	"github.com/open-horizon/anax/externalpolicy"
+	"github.com/open-horizon/anax/i18n"
+	"github.com/open-horizon/edge-sync-service/common"
+	"github.com/open-horizon/rsapss-tool/sign"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// The version of the org bundle format.
+const ORG_BUNDLE_VERSION = 1
+
+// An org bundle is the content of an org that is copied from one exchange to another. The maps are keyed
+// by the exchange id of the resource, without the org.
+type OrgBundle struct {
+	BundleVersion      int                                      `json:"bundleVersion"`
+	Org                string                                   `json:"org"`
+	ExchangeUrl        string                                   `json:"exchangeUrl"`
+	Exported           string                                   `json:"exported"`
+	Services           map[string]BundleService                 `json:"services"`
+	Patterns           map[string]BundlePattern                 `json:"patterns"`
+	DeploymentPolicies map[string]businesspolicy.BusinessPolicy `json:"deploymentPolicies"`
+	DockerAuths        []ServiceDockAuthExch                    `json:"dockerAuths,omitempty"` // the docker auths of the org, which every service of the org has
+	Objects            []common.MetaData                        `json:"objects"`               // the MMS object metadata, without the object data
+}
+
+// A service in an org bundle, with the resources that are stored with it in the exchange.
+type BundleService struct {
+	Service     exchange.ServiceDefinition     `json:"service"`
+	Keys        map[string]string              `json:"keys,omitempty"` // the content of the public keys, keyed by key name
+	Policy      *externalpolicy.ExternalPolicy `json:"policy,omitempty"`
+	DockerAuths []ServiceDockAuthExch          `json:"dockerAuths,omitempty"`
+}
+
+// A pattern in an org bundle, with its public keys.
+type BundlePattern struct {
+	Pattern PatternInput      `json:"pattern"`
+	Keys    map[string]string `json:"keys,omitempty"`
+}
+
+// Export the services, patterns, deployment policies and MMS object metadata of an org into a bundle file.
+func OrgExport(org, userPw, theOrg, bundleFile string, noObjects bool) {
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	cliutils.SetWhetherUsingApiKey(userPw)
+	if theOrg == "" {
+		theOrg = org
+	}
+
+	exchUrl := cliutils.GetExchangeUrl()
+	creds := cliutils.OrgAndCreds(org, userPw)
+	bundle := OrgBundle{
+		BundleVersion:      ORG_BUNDLE_VERSION,
+		Org:                theOrg,
+		ExchangeUrl:        exchUrl,
+		Exported:           time.Now().UTC().Format(time.RFC3339),
+		Services:           map[string]BundleService{},
+		Patterns:           map[string]BundlePattern{},
+		DeploymentPolicies: map[string]businesspolicy.BusinessPolicy{},
+		Objects:            []common.MetaData{},
+	}
+
+	// services with their keys, policies and docker auths
+	var services exchange.GetServicesResponse
+	cliutils.ExchangeGet("Exchange", exchUrl, "orgs/"+theOrg+"/services", creds, []int{200, 404}, &services)
+	for svcId, svc := range services.Services {
+		_, id := cliutils.TrimOrg(theOrg, svcId)
+		svcPath := "orgs/" + theOrg + "/services/" + id
+		svc.Owner = ""
+		svc.LastUpdated = ""
+		bs := BundleService{Service: svc, Keys: exportKeys(exchUrl, svcPath, creds)}
+
+		var pol exchange.ExchangePolicy
+		if httpCode := cliutils.ExchangeGet("Exchange", exchUrl, svcPath+"/policy", creds, []int{200, 404}, &pol); httpCode == 200 {
+			extPol := pol.GetExternalPolicy()
+			bs.Policy = &extPol
+		}
+
+		var auths []exchange.ImageDockerAuth
+		cliutils.ExchangeGet("Exchange", exchUrl, svcPath+"/dockauths", creds, []int{200, 404}, &auths)
+		for _, auth := range auths {
+			bs.DockerAuths = append(bs.DockerAuths, ServiceDockAuthExch{Registry: auth.Registry, UserName: auth.UserName, Token: auth.Token})
+		}
+
+		bundle.Services[id] = bs
+	}
+	ExtractOrgDockerAuths(&bundle)
+
+	// patterns with their keys
+	var patterns ExchangePatterns
+	cliutils.ExchangeGet("Exchange", exchUrl, "orgs/"+theOrg+"/patterns", creds, []int{200, 404}, &patterns)
+	for patId, pat := range patterns.Patterns {
+		_, id := cliutils.TrimOrg(theOrg, patId)
+		patInput := PatternInput{Label: pat.Label, Description: pat.Description, Public: pat.Public, Services: pat.Services, AgreementProtocols: pat.AgreementProtocols, UserInput: pat.UserInput}
+		bundle.Patterns[id] = BundlePattern{Pattern: patInput, Keys: exportKeys(exchUrl, "orgs/"+theOrg+"/patterns/"+id, creds)}
+	}
+
+	// deployment policies
+	var depPols exchange.GetBusinessPolicyResponse
+	cliutils.ExchangeGet("Exchange", exchUrl, "orgs/"+theOrg+"/business/policies", creds, []int{200, 404}, &depPols)
+	for polId, pol := range depPols.BusinessPolicy {
+		_, id := cliutils.TrimOrg(theOrg, polId)
+		bundle.DeploymentPolicies[id] = pol.BusinessPolicy
+	}
+
+	// MMS object metadata
+	if !noObjects {
+		cliutils.ExchangeGet("Model Management Service", cliutils.GetMMSUrl(), "api/v1/objects/"+theOrg+"?filters=true", creds, []int{200, 404}, &bundle.Objects)
+	}
+
+	jsonBytes, err := json.MarshalIndent(bundle, "", cliutils.JSON_INDENT)
+	if err != nil {
+		cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal the bundle of org %v: %v", theOrg, err))
+	}
+	// the bundle contains docker registry tokens, so only the owner can read it
+	if err := ioutil.WriteFile(bundleFile, jsonBytes, 0600); err != nil {
+		cliutils.Fatal(cliutils.FILE_IO_ERROR, msgPrinter.Sprintf("failed to write the bundle file %v: %v", bundleFile, err))
+	}
+
+	msgPrinter.Printf("Exported %v services, %v patterns, %v deployment policies, %v org docker auths and %v MMS objects of org %v to %v.", len(bundle.Services), len(bundle.Patterns), len(bundle.DeploymentPolicies), len(bundle.DockerAuths), len(bundle.Objects), theOrg, bundleFile)
+	msgPrinter.Println()
+}
+
+// Get the content of the public keys that are stored with an exchange resource.
+func exportKeys(exchUrl, resourcePath, creds string) map[string]string {
+	var keyNames []string
+	cliutils.ExchangeGet("Exchange", exchUrl, resourcePath+"/keys", creds, []int{200, 404}, &keyNames)
+	if len(keyNames) == 0 {
+		return nil
+	}
+	keys := make(map[string]string, len(keyNames))
+	for _, name := range keyNames {
+		var content []byte
+		cliutils.ExchangeGet("Exchange", exchUrl, resourcePath+"/keys/"+name, creds, []int{200}, &content)
+		keys[name] = string(content)
+	}
+	return keys
+}
+
+// Create or update the resources in an org bundle in the target org. If a private key is given, the services and
+// patterns are signed again with it, and the public key replaces the keys in the bundle. With dryRun, only the
+// changes that would be made are displayed.
+func OrgImport(org, userPw, theOrg, bundleFile, keyFilePath, pubKeyFilePath string, noObjects bool, dryRun bool) {
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	cliutils.SetWhetherUsingApiKey(userPw)
+
+	var bundle OrgBundle
+	if err := json.Unmarshal(cliutils.ReadFile(bundleFile), &bundle); err != nil {
+		cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to unmarshal the bundle file %v: %v", bundleFile, err))
+	} else if bundle.BundleVersion != ORG_BUNDLE_VERSION {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("the bundle file %v has version %v, only version %v is supported.", bundleFile, bundle.BundleVersion, ORG_BUNDLE_VERSION))
+	}
+	if theOrg == "" {
+		theOrg = bundle.Org
+	}
+	RetargetOrgBundle(&bundle, theOrg)
+
+	// sign the services and patterns again with the new key
+	resign := keyFilePath != ""
+	var newKeys map[string]string
+	if resign {
+		var err error
+		if newKeys, err = ResignOrgBundle(&bundle, keyFilePath, pubKeyFilePath); err != nil {
+			cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, err.Error())
+		}
+	}
+
+	exchUrl := cliutils.GetExchangeUrl()
+	creds := cliutils.OrgAndCreds(org, userPw)
+	if dryRun {
+		msgPrinter.Printf("Dry run, the Exchange is not changed.")
+		msgPrinter.Println()
+	}
+
+	// Return true if the resource exists in the exchange and print what is done with it.
+	plan := func(kind, resourcePath, id string) bool {
+		var output string
+		exists := cliutils.ExchangeGet("Exchange", exchUrl, resourcePath, creds, []int{200, 404}, &output) == 200
+		if exists {
+			msgPrinter.Printf("Updating %v %v/%v in the Exchange...", kind, theOrg, id)
+		} else {
+			msgPrinter.Printf("Creating %v %v/%v in the Exchange...", kind, theOrg, id)
+		}
+		msgPrinter.Println()
+		return exists
+	}
+	storeKeys := func(kind, resourcePath string, keys map[string]string) {
+		for name, content := range keys {
+			msgPrinter.Printf("Storing %s with the %v in the Exchange...", name, kind)
+			msgPrinter.Println()
+			if !dryRun {
+				cliutils.ExchangePutPost("Exchange", http.MethodPut, exchUrl, resourcePath+"/keys/"+name, creds, []int{201}, []byte(content), nil)
+			}
+		}
+	}
+
+	// services, with the services they require in the same org first
+	for _, id := range OrderBundleServices(bundle.Services, theOrg) {
+		bs := bundle.Services[id]
+		svcPath := "orgs/" + theOrg + "/services/" + id
+		exists := plan("service", svcPath, id)
+		if !dryRun {
+			if exists {
+				cliutils.ExchangePutPost("Exchange", http.MethodPut, exchUrl, svcPath, creds, []int{201}, bs.Service, nil)
+			} else {
+				cliutils.ExchangePutPost("Exchange", http.MethodPost, exchUrl, "orgs/"+theOrg+"/services", creds, []int{201}, bs.Service, nil)
+			}
+		}
+		if resign {
+			storeKeys("service", svcPath, newKeys)
+		} else {
+			storeKeys("service", svcPath, bs.Keys)
+		}
+
+		if bs.Policy != nil {
+			msgPrinter.Printf("Updating the service policy of %v/%v in the Exchange...", theOrg, id)
+			msgPrinter.Println()
+			if !dryRun {
+				cliutils.ExchangePutPost("Exchange", http.MethodPut, exchUrl, svcPath+"/policy", creds, []int{201}, bs.Policy, nil)
+			}
+		}
+
+		// only add the docker auths of the org and of the service that the service does not have yet
+		var existingAuths []exchange.ImageDockerAuth
+		if exists {
+			cliutils.ExchangeGet("Exchange", exchUrl, svcPath+"/dockauths", creds, []int{200, 404}, &existingAuths)
+		}
+		for _, auth := range BundleServiceDockerAuths(&bundle, id) {
+			found := false
+			for _, ea := range existingAuths {
+				if ea.Registry == auth.Registry && ea.UserName == auth.UserName && ea.Token == auth.Token {
+					found = true
+					break
+				}
+			}
+			if !found {
+				msgPrinter.Printf("Storing the docker auth for registry %v with the service in the Exchange...", auth.Registry)
+				msgPrinter.Println()
+				if !dryRun {
+					cliutils.ExchangePutPost("Exchange", http.MethodPost, exchUrl, svcPath+"/dockauths", creds, []int{201}, auth, nil)
+				}
+			}
+		}
+	}
+
+	// patterns
+	for _, id := range sortedKeys(bundle.Patterns) {
+		bp := bundle.Patterns[id]
+		patPath := "orgs/" + theOrg + "/patterns/" + id
+		exists := plan("pattern", patPath, id)
+		if !dryRun {
+			method := http.MethodPost
+			if exists {
+				method = http.MethodPut
+			}
+			cliutils.ExchangePutPost("Exchange", method, exchUrl, patPath, creds, []int{201}, bp.Pattern, nil)
+		}
+		if resign {
+			storeKeys("pattern", patPath, newKeys)
+		} else {
+			storeKeys("pattern", patPath, bp.Keys)
+		}
+	}
+
+	// deployment policies
+	for _, id := range sortedKeys(bundle.DeploymentPolicies) {
+		polPath := "orgs/" + theOrg + "/business/policies/" + id
+		exists := plan("deployment policy", polPath, id)
+		if !dryRun {
+			method := http.MethodPost
+			if exists {
+				method = http.MethodPut
+			}
+			cliutils.ExchangePutPost("Exchange", method, exchUrl, polPath, creds, []int{201}, bundle.DeploymentPolicies[id], nil)
+		}
+	}
+
+	// MMS object metadata, the object data has to be published separately
+	if !noObjects {
+		for _, obj := range bundle.Objects {
+			msgPrinter.Printf("Adding the metadata of object %v of type %v to the Model Management Service...", obj.ObjectID, obj.ObjectType)
+			msgPrinter.Println()
+			if !dryRun {
+				obj.MetaOnly = true
+				wrapper := struct {
+					Meta common.MetaData `json:"meta"`
+				}{Meta: obj}
+				urlPath := path.Join("api/v1/objects/", theOrg, obj.ObjectType, obj.ObjectID)
+				cliutils.ExchangePutPost("Model Management Service", http.MethodPut, cliutils.GetMMSUrl(), urlPath, creds, []int{204}, wrapper, nil)
+			}
+		}
+	}
+
+	if dryRun {
+		msgPrinter.Printf("Dry run complete, %v services, %v patterns, %v deployment policies and %v MMS objects would be imported into org %v.", len(bundle.Services), len(bundle.Patterns), len(bundle.DeploymentPolicies), len(bundle.Objects), theOrg)
+	} else {
+		msgPrinter.Printf("Imported %v services, %v patterns, %v deployment policies and %v MMS objects into org %v.", len(bundle.Services), len(bundle.Patterns), len(bundle.DeploymentPolicies), len(bundle.Objects), theOrg)
+	}
+	msgPrinter.Println()
+}
+
+// Change the references to the org of the bundle into references to the target org, so that the bundle can be
+// imported into an org with a different name. References to other orgs are not changed.
+func RetargetOrgBundle(bundle *OrgBundle, targetOrg string) {
+	sourceOrg := bundle.Org
+	if sourceOrg == targetOrg {
+		return
+	}
+
+	for id, bs := range bundle.Services {
+		for i := range bs.Service.RequiredServices {
+			if bs.Service.RequiredServices[i].Org == sourceOrg {
+				bs.Service.RequiredServices[i].Org = targetOrg
+			}
+		}
+		if bs.Policy != nil {
+			AddServicePolicyBuiltInProperties(bs.Policy, targetOrg, bs.Service.URL, bs.Service.Version, bs.Service.Arch)
+		}
+		bundle.Services[id] = bs
+	}
+
+	for id, bp := range bundle.Patterns {
+		for i := range bp.Pattern.Services {
+			if bp.Pattern.Services[i].ServiceOrg == sourceOrg {
+				bp.Pattern.Services[i].ServiceOrg = targetOrg
+			}
+		}
+		bundle.Patterns[id] = bp
+	}
+
+	for id, pol := range bundle.DeploymentPolicies {
+		if pol.Service.Org == sourceOrg {
+			pol.Service.Org = targetOrg
+		}
+		bundle.DeploymentPolicies[id] = pol
+	}
+
+	for i := range bundle.Objects {
+		bundle.Objects[i].DestOrgID = targetOrg
+		if dp := bundle.Objects[i].DestinationPolicy; dp != nil {
+			for j := range dp.Services {
+				if dp.Services[j].OrgID == sourceOrg {
+					dp.Services[j].OrgID = targetOrg
+				}
+			}
+		}
+	}
+
+	bundle.Org = targetOrg
+}
+
+// Sign the deployments of the services and the deployment overrides of the patterns in the bundle with the given
+// private key. It returns the public key that should be stored with the resources instead of the keys in the bundle,
+// keyed by the key name. It is empty when no public key should be stored.
+func ResignOrgBundle(bundle *OrgBundle, keyFilePath, pubKeyFilePath string) (map[string]string, error) {
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	keyFilePath, pubKeyFilePath = cliutils.GetSigningKeys(keyFilePath, pubKeyFilePath)
+
+	signString := func(s string) (string, error) {
+		if s == "" {
+			return "", nil
+		}
+		return sign.Input(keyFilePath, []byte(s))
+	}
+
+	for id, bs := range bundle.Services {
+		var err error
+		if bs.Service.DeploymentSignature, err = signString(bs.Service.Deployment); err != nil {
+			return nil, errors.New(msgPrinter.Sprintf("problem signing the deployment of service %v with %s: %v", id, keyFilePath, err))
+		} else if bs.Service.ClusterDeploymentSignature, err = signString(bs.Service.ClusterDeployment); err != nil {
+			return nil, errors.New(msgPrinter.Sprintf("problem signing the cluster deployment of service %v with %s: %v", id, keyFilePath, err))
+		}
+		bs.Keys = nil
+		bundle.Services[id] = bs
+	}
+
+	for id, bp := range bundle.Patterns {
+		for i := range bp.Pattern.Services {
+			for j := range bp.Pattern.Services[i].ServiceVersions {
+				sv := &bp.Pattern.Services[i].ServiceVersions[j]
+				var err error
+				if sv.DeploymentOverridesSignature, err = signString(sv.DeploymentOverrides); err != nil {
+					return nil, errors.New(msgPrinter.Sprintf("problem signing the deployment_overrides of pattern %v with %s: %v", id, keyFilePath, err))
+				}
+			}
+		}
+		bp.Keys = nil
+		bundle.Patterns[id] = bp
+	}
+
+	newKeys := map[string]string{}
+	if pubKeyFilePath != "" {
+		newKeys[filepath.Base(pubKeyFilePath)] = string(cliutils.ReadFile(pubKeyFilePath))
+	}
+	return newKeys, nil
+}
+
+// Move the docker auths that every service in the bundle has into the docker auths of the org, so that a registry
+// token that is shared by the services of the org is only once in the bundle. Importing the bundle stores the docker
+// auths of the org with every service again.
+func ExtractOrgDockerAuths(bundle *OrgBundle) {
+	ids := sortedKeys(bundle.Services)
+	if len(ids) < 2 {
+		return
+	}
+
+	hasAuth := func(auths []ServiceDockAuthExch, auth ServiceDockAuthExch) bool {
+		for _, a := range auths {
+			if a == auth {
+				return true
+			}
+		}
+		return false
+	}
+
+	// the auths of the first service that all the other services have too
+	for _, auth := range bundle.Services[ids[0]].DockerAuths {
+		shared := !hasAuth(bundle.DockerAuths, auth)
+		for _, id := range ids[1:] {
+			if !hasAuth(bundle.Services[id].DockerAuths, auth) {
+				shared = false
+				break
+			}
+		}
+		if shared {
+			bundle.DockerAuths = append(bundle.DockerAuths, auth)
+		}
+	}
+
+	for _, id := range ids {
+		bs := bundle.Services[id]
+		var own []ServiceDockAuthExch
+		for _, auth := range bs.DockerAuths {
+			if !hasAuth(bundle.DockerAuths, auth) {
+				own = append(own, auth)
+			}
+		}
+		bs.DockerAuths = own
+		bundle.Services[id] = bs
+	}
+}
+
+// Return the docker auths that should be stored with a service of the bundle, the docker auths of the org followed
+// by the docker auths of the service itself.
+func BundleServiceDockerAuths(bundle *OrgBundle, id string) []ServiceDockAuthExch {
+	auths := make([]ServiceDockAuthExch, 0, len(bundle.DockerAuths)+len(bundle.Services[id].DockerAuths))
+	for _, auth := range append(append([]ServiceDockAuthExch{}, bundle.DockerAuths...), bundle.Services[id].DockerAuths...) {
+		found := false
+		for _, a := range auths {
+			if a == auth {
+				found = true
+				break
+			}
+		}
+		if !found {
+			auths = append(auths, auth)
+		}
+	}
+	return auths
+}
+
+// Return the ids of the services in the bundle, sorted so that a service comes after the services in the same
+// org that it requires. The services in a dependency cycle are returned last.
+func OrderBundleServices(services map[string]BundleService, org string) []string {
+	ids := sortedKeys(services)
+	ordered := make([]string, 0, len(ids))
+	done := map[string]bool{}
+
+	// Return true if the service requires a service in the bundle that is not yet ordered.
+	waiting := func(id string) bool {
+		for _, rs := range services[id].Service.RequiredServices {
+			if rs.Org != org {
+				continue
+			}
+			for _, other := range ids {
+				svc := services[other].Service
+				if other != id && !done[other] && svc.URL == rs.URL && (rs.Arch == "" || svc.Arch == rs.Arch) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	for progress := true; progress; {
+		progress = false
+		for _, id := range ids {
+			if !done[id] && !waiting(id) {
+				ordered = append(ordered, id)
+				done[id] = true
+				progress = true
+			}
+		}
+	}
+	for _, id := range ids {
+		if !done[id] {
+			ordered = append(ordered, id)
+		}
+	}
+	return ordered
+}
+
+// Return the keys of a bundle map in sorted order.
+func sortedKeys(m interface{}) []string {
+	keys := []string{}
+	switch val := m.(type) {
+	case map[string]BundleService:
+		for k := range val {
+			keys = append(keys, k)
+		}
+	case map[string]BundlePattern:
+		for k := range val {
+			keys = append(keys, k)
+		}
+	case map[string]businesspolicy.BusinessPolicy:
+		for k := range val {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
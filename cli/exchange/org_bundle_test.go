@@ -0,0 +1,247 @@
+// +build unit
+
+package exchange
+
+import (
+	"github.com/open-horizon/anax/businesspolicy"
+	"github.com/open-horizon/anax/exchange"
+	"github.com/open-horizon/anax/externalpolicy"
+	"github.com/open-horizon/edge-sync-service/common"
+	"github.com/open-horizon/rsapss-tool/generatekeys"
+	"github.com/open-horizon/rsapss-tool/verify"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func getTestOrgBundle() *OrgBundle {
+	return &OrgBundle{
+		BundleVersion: ORG_BUNDLE_VERSION,
+		Org:           "devorg",
+		Services: map[string]BundleService{
+			"svc1_1.0.0_amd64": BundleService{
+				Service: exchange.ServiceDefinition{URL: "svc1", Version: "1.0.0", Arch: "amd64", Deployment: `{"services":{"svc1":{"image":"svc1:1.0.0"}}}`, DeploymentSignature: "oldsig",
+					RequiredServices: []exchange.ServiceDependency{{URL: "svc2", Org: "devorg", Arch: "amd64"}, {URL: "ibm.gps", Org: "IBM", Arch: "amd64"}}},
+				Keys:   map[string]string{"dev.pem": "devkey"},
+				Policy: &externalpolicy.ExternalPolicy{Properties: externalpolicy.PropertyList{*externalpolicy.Property_Factory(externalpolicy.PROP_SVC_ORG, "devorg")}},
+			},
+			"svc2_1.0.0_amd64": BundleService{
+				Service: exchange.ServiceDefinition{URL: "svc2", Version: "1.0.0", Arch: "amd64", ClusterDeployment: "operator", ClusterDeploymentSignature: "oldsig"},
+				Keys:    map[string]string{"dev.pem": "devkey"},
+			},
+		},
+		Patterns: map[string]BundlePattern{
+			"pat1": BundlePattern{
+				Pattern: PatternInput{Label: "pat1", Services: []ServiceReference{
+					{ServiceURL: "svc1", ServiceOrg: "devorg", ServiceArch: "amd64", ServiceVersions: []ServiceChoice{{Version: "1.0.0", DeploymentOverrides: `{"services":{}}`, DeploymentOverridesSignature: "oldsig"}}},
+					{ServiceURL: "ibm.gps", ServiceOrg: "IBM", ServiceArch: "amd64", ServiceVersions: []ServiceChoice{{Version: "2.0.0"}}},
+				}},
+				Keys: map[string]string{"dev.pem": "devkey"},
+			},
+		},
+		DeploymentPolicies: map[string]businesspolicy.BusinessPolicy{
+			"pol1": businesspolicy.BusinessPolicy{Service: businesspolicy.ServiceRef{Name: "svc1", Org: "devorg", Arch: "amd64"}},
+			"pol2": businesspolicy.BusinessPolicy{Service: businesspolicy.ServiceRef{Name: "ibm.gps", Org: "IBM", Arch: "amd64"}},
+		},
+		Objects: []common.MetaData{
+			{ObjectID: "obj1", ObjectType: "model", DestOrgID: "devorg", DestinationPolicy: &common.Policy{Services: []common.ServiceID{{OrgID: "devorg", ServiceName: "svc1"}, {OrgID: "IBM", ServiceName: "ibm.gps"}}}},
+		},
+	}
+}
+
+func Test_RetargetOrgBundle(t *testing.T) {
+
+	bundle := getTestOrgBundle()
+	RetargetOrgBundle(bundle, "prodorg")
+
+	if bundle.Org != "prodorg" {
+		t.Errorf("the org of the bundle should be prodorg, it is %v", bundle.Org)
+	}
+
+	rs := bundle.Services["svc1_1.0.0_amd64"].Service.RequiredServices
+	if rs[0].Org != "prodorg" || rs[1].Org != "IBM" {
+		t.Errorf("only the required services in the org of the bundle should be retargeted, they are %v", rs)
+	}
+	if prop, err := bundle.Services["svc1_1.0.0_amd64"].Policy.Properties.GetProperty(externalpolicy.PROP_SVC_ORG); err != nil || prop.Value != "prodorg" {
+		t.Errorf("the org property of the service policy should be prodorg, it is %v, error %v", prop, err)
+	}
+
+	ps := bundle.Patterns["pat1"].Pattern.Services
+	if ps[0].ServiceOrg != "prodorg" || ps[1].ServiceOrg != "IBM" {
+		t.Errorf("only the pattern services in the org of the bundle should be retargeted, they are %v", ps)
+	}
+
+	if org := bundle.DeploymentPolicies["pol1"].Service.Org; org != "prodorg" {
+		t.Errorf("the service org of deployment policy pol1 should be prodorg, it is %v", org)
+	} else if org := bundle.DeploymentPolicies["pol2"].Service.Org; org != "IBM" {
+		t.Errorf("the service org of deployment policy pol2 should stay IBM, it is %v", org)
+	}
+
+	obj := bundle.Objects[0]
+	if obj.DestOrgID != "prodorg" {
+		t.Errorf("the object should be in org prodorg, it is in %v", obj.DestOrgID)
+	} else if svcs := obj.DestinationPolicy.Services; svcs[0].OrgID != "prodorg" || svcs[1].OrgID != "IBM" {
+		t.Errorf("only the object destination services in the org of the bundle should be retargeted, they are %v", svcs)
+	}
+
+	// the same org does not change anything.
+	bundle = getTestOrgBundle()
+	RetargetOrgBundle(bundle, "devorg")
+	if !reflect.DeepEqual(bundle, getTestOrgBundle()) {
+		t.Errorf("the bundle should not change when it is imported into the same org")
+	}
+}
+
+func Test_ResignOrgBundle(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "orgbundle-")
+	if err != nil {
+		t.Fatalf("unable to create temp dir, error %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	keyFiles, err := generatekeys.Write(dir, 2048, "prod", "prodorg", time.Now().AddDate(1, 0, 0))
+	if err != nil {
+		t.Fatalf("unable to generate the signing keys, error %v", err)
+	}
+	privKeyFile, pubKeyFile := keyFiles[0], keyFiles[1]
+
+	bundle := getTestOrgBundle()
+	newKeys, err := ResignOrgBundle(bundle, privKeyFile, pubKeyFile)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if len(newKeys) != 1 || newKeys[filepath.Base(pubKeyFile)] == "" {
+		t.Errorf("the public key %v should be returned, the keys are %v", filepath.Base(pubKeyFile), newKeys)
+	}
+
+	svc1 := bundle.Services["svc1_1.0.0_amd64"]
+	if ok, err := verify.Input(pubKeyFile, svc1.Service.DeploymentSignature, []byte(svc1.Service.Deployment)); err != nil || !ok {
+		t.Errorf("the deployment of svc1 should be signed with the new key, error %v", err)
+	} else if svc1.Service.ClusterDeploymentSignature != "" {
+		t.Errorf("svc1 has no cluster deployment, so it should not have a cluster deployment signature")
+	} else if svc1.Keys != nil {
+		t.Errorf("the keys of svc1 should be removed, they are %v", svc1.Keys)
+	}
+
+	svc2 := bundle.Services["svc2_1.0.0_amd64"]
+	if ok, err := verify.Input(pubKeyFile, svc2.Service.ClusterDeploymentSignature, []byte(svc2.Service.ClusterDeployment)); err != nil || !ok {
+		t.Errorf("the cluster deployment of svc2 should be signed with the new key, error %v", err)
+	} else if svc2.Service.DeploymentSignature != "" {
+		t.Errorf("svc2 has no deployment, so it should not have a deployment signature")
+	}
+
+	pat1 := bundle.Patterns["pat1"]
+	sv := pat1.Pattern.Services[0].ServiceVersions[0]
+	if ok, err := verify.Input(pubKeyFile, sv.DeploymentOverridesSignature, []byte(sv.DeploymentOverrides)); err != nil || !ok {
+		t.Errorf("the deployment overrides of pat1 should be signed with the new key, error %v", err)
+	} else if pat1.Pattern.Services[1].ServiceVersions[0].DeploymentOverridesSignature != "" {
+		t.Errorf("a service version without deployment overrides should not have a signature")
+	} else if pat1.Keys != nil {
+		t.Errorf("the keys of pat1 should be removed, they are %v", pat1.Keys)
+	}
+
+	// without a public key, no key is stored with the resources.
+	bundle = getTestOrgBundle()
+	if newKeys, err := ResignOrgBundle(bundle, privKeyFile, ""); err != nil {
+		t.Errorf("unexpected error %v", err)
+	} else if len(newKeys) != 0 {
+		t.Errorf("no public key should be returned, the keys are %v", newKeys)
+	}
+}
+
+func Test_OrderBundleServices(t *testing.T) {
+
+	svc := func(url string, required ...exchange.ServiceDependency) BundleService {
+		return BundleService{Service: exchange.ServiceDefinition{URL: url, Version: "1.0.0", Arch: "amd64", RequiredServices: required}}
+	}
+	dep := func(url, org, arch string) exchange.ServiceDependency {
+		return exchange.ServiceDependency{URL: url, Org: org, Arch: arch}
+	}
+
+	// a requires b, b requires c, d requires a service of another org with the same url as a.
+	services := map[string]BundleService{
+		"a": svc("a", dep("b", "myorg", "amd64")),
+		"b": svc("b", dep("c", "myorg", "")),
+		"c": svc("c"),
+		"d": svc("d", dep("a", "otherorg", "amd64")),
+	}
+	if ordered := OrderBundleServices(services, "myorg"); !reflect.DeepEqual(ordered, []string{"c", "d", "b", "a"}) {
+		t.Errorf("the services should be ordered after the services they require, they are %v", ordered)
+	}
+
+	// a required service of another arch is not a dependency.
+	services = map[string]BundleService{
+		"a": svc("a", dep("b", "myorg", "arm64")),
+		"b": svc("b"),
+	}
+	if ordered := OrderBundleServices(services, "myorg"); !reflect.DeepEqual(ordered, []string{"a", "b"}) {
+		t.Errorf("a required service of another arch should not change the order, it is %v", ordered)
+	}
+
+	// the services in a cycle come last, in sorted order.
+	services = map[string]BundleService{
+		"a": svc("a", dep("b", "myorg", "amd64")),
+		"b": svc("b", dep("a", "myorg", "amd64")),
+		"c": svc("c"),
+	}
+	if ordered := OrderBundleServices(services, "myorg"); !reflect.DeepEqual(ordered, []string{"c", "a", "b"}) {
+		t.Errorf("the services in a cycle should be last, the order is %v", ordered)
+	}
+}
+
+func Test_sortedKeys(t *testing.T) {
+
+	bundle := getTestOrgBundle()
+	if keys := sortedKeys(bundle.Services); !reflect.DeepEqual(keys, []string{"svc1_1.0.0_amd64", "svc2_1.0.0_amd64"}) {
+		t.Errorf("wrong service keys %v", keys)
+	} else if keys := sortedKeys(bundle.Patterns); !reflect.DeepEqual(keys, []string{"pat1"}) {
+		t.Errorf("wrong pattern keys %v", keys)
+	} else if keys := sortedKeys(bundle.DeploymentPolicies); !reflect.DeepEqual(keys, []string{"pol1", "pol2"}) {
+		t.Errorf("wrong deployment policy keys %v", keys)
+	} else if keys := sortedKeys(map[string]string{"a": "b"}); len(keys) != 0 {
+		t.Errorf("a map that is not a bundle map should have no keys, it has %v", keys)
+	}
+}
+
+func Test_OrgDockerAuths(t *testing.T) {
+
+	orgAuth := ServiceDockAuthExch{Registry: "registry.example.com", UserName: "iamapikey", Token: "orgtoken"}
+	svcAuth := ServiceDockAuthExch{Registry: "docker.io", UserName: "me", Token: "svctoken"}
+
+	bundle := getTestOrgBundle()
+	svc1 := bundle.Services["svc1_1.0.0_amd64"]
+	svc1.DockerAuths = []ServiceDockAuthExch{svcAuth, orgAuth}
+	bundle.Services["svc1_1.0.0_amd64"] = svc1
+	svc2 := bundle.Services["svc2_1.0.0_amd64"]
+	svc2.DockerAuths = []ServiceDockAuthExch{orgAuth}
+	bundle.Services["svc2_1.0.0_amd64"] = svc2
+
+	ExtractOrgDockerAuths(bundle)
+
+	if !reflect.DeepEqual(bundle.DockerAuths, []ServiceDockAuthExch{orgAuth}) {
+		t.Errorf("the auth that every service has should be an org auth, the org auths are %v", bundle.DockerAuths)
+	} else if auths := bundle.Services["svc1_1.0.0_amd64"].DockerAuths; !reflect.DeepEqual(auths, []ServiceDockAuthExch{svcAuth}) {
+		t.Errorf("svc1 should only keep its own auth, it has %v", auths)
+	} else if auths := bundle.Services["svc2_1.0.0_amd64"].DockerAuths; len(auths) != 0 {
+		t.Errorf("svc2 should not keep the org auth, it has %v", auths)
+	}
+
+	// the import stores the org auths with every service.
+	if auths := BundleServiceDockerAuths(bundle, "svc1_1.0.0_amd64"); !reflect.DeepEqual(auths, []ServiceDockAuthExch{orgAuth, svcAuth}) {
+		t.Errorf("svc1 should get the org auth and its own auth, it gets %v", auths)
+	} else if auths := BundleServiceDockerAuths(bundle, "svc2_1.0.0_amd64"); !reflect.DeepEqual(auths, []ServiceDockAuthExch{orgAuth}) {
+		t.Errorf("svc2 should get the org auth, it gets %v", auths)
+	}
+
+	// a single service keeps its own auths.
+	bundle = &OrgBundle{Services: map[string]BundleService{"svc1_1.0.0_amd64": svc1}}
+	ExtractOrgDockerAuths(bundle)
+	if len(bundle.DockerAuths) != 0 || len(bundle.Services["svc1_1.0.0_amd64"].DockerAuths) != 2 {
+		t.Errorf("the auths of a single service should not be org auths, the org auths are %v", bundle.DockerAuths)
+	}
+}
@@ -69,6 +69,17 @@ type PatternInput struct {
 	UserInput          []policy.UserInput           `json:"userInput,omitempty"`
 }
 
+// The table output format of 'hzn exchange pattern list'.
+var exchangePatternTable = &cliutils.TableFormat{Columns: []cliutils.TableColumn{
+	{Header: "ID"},
+	{Header: "LABEL", Path: ".label"},
+	{Header: "PUBLIC", Path: ".public"},
+	{Header: "SERVICES", Path: ".services[*].serviceUrl"},
+	{Header: "OWNER", Path: ".owner", Wide: true},
+	{Header: "ARCHES", Path: ".services[*].serviceArch", Wide: true},
+	{Header: "LAST UPDATED", Path: ".lastUpdated", Wide: true},
+}}
+
 // List the pattern resources for the given org.
 // The userPw can be the userId:password auth or the nodeId:token auth.
 func PatternList(org string, userPw string, pattern string, namesOnly bool) {
@@ -92,11 +103,7 @@ func PatternList(org string, userPw string, pattern string, namesOnly bool) {
 		for p := range resp.Patterns {
 			patterns = append(patterns, p)
 		}
-		jsonBytes, err := json.MarshalIndent(patterns, "", cliutils.JSON_INDENT)
-		if err != nil {
-			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'exchange pattern list' output: %v", err))
-		}
-		fmt.Printf("%s\n", jsonBytes)
+		cliutils.PrintOutput(patterns, cliutils.NamesTable, "exchange pattern list")
 	} else {
 		// Display the full resources
 		var patterns ExchangePatterns
@@ -104,11 +111,7 @@ func PatternList(org string, userPw string, pattern string, namesOnly bool) {
 		if httpCode == 404 && pattern != "" {
 			cliutils.Fatal(cliutils.NOT_FOUND, msgPrinter.Sprintf("pattern '%s' not found in org %s", pattern, patOrg))
 		}
-		jsonBytes, err := json.MarshalIndent(patterns.Patterns, "", cliutils.JSON_INDENT)
-		if err != nil {
-			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'hzn exchange pattern list' output: %v", err))
-		}
-		fmt.Println(string(jsonBytes))
+		cliutils.PrintOutput(patterns.Patterns, exchangePatternTable, "exchange pattern list")
 	}
 }
 
@@ -225,6 +228,52 @@ func PatternPublish(org, userPw, jsonFilePath, keyFilePath, pubKeyFilePath, patN
 	if patFile.Org == "" {
 		patFile.Org = org
 	}
+	patInput, pubKeyFilePath := SignPattern(&patFile, keyFilePath, pubKeyFilePath)
+
+	// Create or update resource in the exchange
+	var exchId string
+	if patName != "" {
+		exchId = patName
+	} else if patFile.Name != "" {
+		exchId = patFile.Name
+	} else {
+		// Use the json file base name as the default for the pattern name
+		exchId = filepath.Base(jsonFilePath)                      // remove the leading path
+		exchId = strings.TrimSuffix(exchId, filepath.Ext(exchId)) // strip suffix if there
+	}
+	// replace the unwanted charactors from the id with '-'
+	exchId = cutil.FormExchangeId(exchId)
+
+	var output string
+	httpCode := cliutils.ExchangeGet("Exchange", exchUrl, "orgs/"+patFile.Org+"/patterns/"+exchId, cliutils.OrgAndCreds(org, userPw), []int{200, 404}, &output)
+	if httpCode == 200 {
+		// Pattern exists, update it
+		msgPrinter.Printf("Updating %s in the Exchange...", exchId)
+		msgPrinter.Println()
+		cliutils.ExchangePutPost("Exchange", http.MethodPut, exchUrl, "orgs/"+patFile.Org+"/patterns/"+exchId, cliutils.OrgAndCreds(org, userPw), []int{201}, patInput, nil)
+	} else {
+		// Pattern not there, create it
+		msgPrinter.Printf("Creating %s in the Exchange...", exchId)
+		msgPrinter.Println()
+		cliutils.ExchangePutPost("Exchange", http.MethodPost, exchUrl, "orgs/"+patFile.Org+"/patterns/"+exchId, cliutils.OrgAndCreds(org, userPw), []int{201}, patInput, nil)
+	}
+
+	// Store the public key in the exchange, if they gave it to us
+	if pubKeyFilePath != "" {
+		bodyBytes := cliutils.ReadFile(pubKeyFilePath)
+		baseName := filepath.Base(pubKeyFilePath)
+		msgPrinter.Printf("Storing %s with the pattern in the Exchange...", baseName)
+		msgPrinter.Println()
+		cliutils.ExchangePutPost("Exchange", http.MethodPut, exchUrl, "orgs/"+patFile.Org+"/patterns/"+exchId+"/keys/"+baseName, cliutils.OrgAndCreds(org, userPw), []int{201}, bodyBytes, nil)
+	}
+}
+
+// Convert the pattern file into the pattern for the exchange, signing the deployment_overrides fields if they are not
+// already signed. It returns the pattern and the verified public key file that should be stored with it, if any.
+func SignPattern(patFile *common.PatternFile, keyFilePath, pubKeyFilePath string) (PatternInput, string) {
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
 	patInput := PatternInput{Label: patFile.Label, Description: patFile.Description, Public: patFile.Public, AgreementProtocols: patFile.AgreementProtocols, UserInput: patFile.UserInput}
 
 	//issue 924: Patterns with no services are not allowed
@@ -288,46 +337,12 @@ func PatternPublish(org, userPw, jsonFilePath, keyFilePath, pubKeyFilePath, patN
 		}
 	}
 
-	// Create or update resource in the exchange
-	var exchId string
-	if patName != "" {
-		exchId = patName
-	} else if patFile.Name != "" {
-		exchId = patFile.Name
-	} else {
-		// Use the json file base name as the default for the pattern name
-		exchId = filepath.Base(jsonFilePath)                      // remove the leading path
-		exchId = strings.TrimSuffix(exchId, filepath.Ext(exchId)) // strip suffix if there
+	// Verify the public key that should be stored with the pattern, if they gave it to us
+	if pubKeyFilePath != "" && !keyVerified {
+		pubKeyFilePath = cliutils.GetAndVerifyPublicKey(pubKeyFilePath)
 	}
-	// replace the unwanted charactors from the id with '-'
-	exchId = cutil.FormExchangeId(exchId)
 
-	var output string
-	httpCode := cliutils.ExchangeGet("Exchange", exchUrl, "orgs/"+patFile.Org+"/patterns/"+exchId, cliutils.OrgAndCreds(org, userPw), []int{200, 404}, &output)
-	if httpCode == 200 {
-		// Pattern exists, update it
-		msgPrinter.Printf("Updating %s in the Exchange...", exchId)
-		msgPrinter.Println()
-		cliutils.ExchangePutPost("Exchange", http.MethodPut, exchUrl, "orgs/"+patFile.Org+"/patterns/"+exchId, cliutils.OrgAndCreds(org, userPw), []int{201}, patInput, nil)
-	} else {
-		// Pattern not there, create it
-		msgPrinter.Printf("Creating %s in the Exchange...", exchId)
-		msgPrinter.Println()
-		cliutils.ExchangePutPost("Exchange", http.MethodPost, exchUrl, "orgs/"+patFile.Org+"/patterns/"+exchId, cliutils.OrgAndCreds(org, userPw), []int{201}, patInput, nil)
-	}
-
-	// Store the public key in the exchange, if they gave it to us
-	if pubKeyFilePath != "" {
-		// Note: already verified the file exists
-		if !keyVerified {
-			pubKeyFilePath = cliutils.GetAndVerifyPublicKey(pubKeyFilePath)
-		}
-		bodyBytes := cliutils.ReadFile(pubKeyFilePath)
-		baseName := filepath.Base(pubKeyFilePath)
-		msgPrinter.Printf("Storing %s with the pattern in the Exchange...", baseName)
-		msgPrinter.Println()
-		cliutils.ExchangePutPost("Exchange", http.MethodPut, exchUrl, "orgs/"+patFile.Org+"/patterns/"+exchId+"/keys/"+baseName, cliutils.OrgAndCreds(org, userPw), []int{201}, bodyBytes, nil)
-	}
+	return patInput, pubKeyFilePath
 }
 
 // Verify that the deployment_overrides_signature is valid for the given key.
@@ -35,6 +35,19 @@ type ServicePolicyFile struct {
 	Constraints externalpolicy.ConstraintExpression `json:"constraints"`
 }
 
+// The table output format of 'hzn exchange service list'.
+var exchangeServiceTable = &cliutils.TableFormat{Columns: []cliutils.TableColumn{
+	{Header: "ID"},
+	{Header: "URL", Path: ".url"},
+	{Header: "VERSION", Path: ".version"},
+	{Header: "ARCH", Path: ".arch"},
+	{Header: "PUBLIC", Path: ".public"},
+	{Header: "SHARABLE", Path: ".sharable", Wide: true},
+	{Header: "OWNER", Path: ".owner", Wide: true},
+	{Header: "REQUIRED SERVICES", Path: ".requiredServices[*].url", Wide: true},
+	{Header: "LAST UPDATED", Path: ".lastUpdated", Wide: true},
+}}
+
 // List the the service resources for the given org.
 // The userPw can be the userId:password auth or the nodeId:token auth.
 func ServiceList(credOrg, userPw, service string, namesOnly bool, filePath string, exSvcOpYamlForce bool) {
@@ -65,11 +78,7 @@ func ServiceList(credOrg, userPw, service string, namesOnly bool, filePath strin
 		for k := range resp.Services {
 			services = append(services, k)
 		}
-		jsonBytes, err := json.MarshalIndent(services, "", cliutils.JSON_INDENT)
-		if err != nil {
-			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'hzn exchange service list' output: %v", err))
-		}
-		fmt.Printf("%s\n", jsonBytes)
+		cliutils.PrintOutput(services, cliutils.NamesTable, "exchange service list")
 	} else {
 		// Display the full resources
 		var services exchange.GetServicesResponse
@@ -101,11 +110,7 @@ func ServiceList(credOrg, userPw, service string, namesOnly bool, filePath strin
 				exchServices[sId] = s_copy
 			}
 		}
-		jsonBytes, err := json.MarshalIndent(exchServices, "", cliutils.JSON_INDENT)
-		if err != nil {
-			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'hzn exchange service list' output: %v", err))
-		}
-		fmt.Println(string(jsonBytes))
+		cliutils.PrintOutput(exchServices, exchangeServiceTable, "exchange service list")
 
 		// save the kube operator yaml archive to file if filePath is specified and one service is specified
 		if filePath != "" {
@@ -184,13 +189,7 @@ func SignAndPublish(sf *common.ServiceFile, org, userPw, jsonFilePath, keyFilePa
 	// get message printer
 	msgPrinter := i18n.GetMessagePrinter()
 
-	svcInput := exchange.ServiceDefinition{Label: sf.Label, Description: sf.Description, Public: sf.Public, Documentation: sf.Documentation, URL: sf.URL, Version: sf.Version, Arch: sf.Arch, Sharable: sf.Sharable, MatchHardware: sf.MatchHardware, RequiredServices: sf.RequiredServices, UserInputs: sf.UserInputs}
-
-	baseDir := filepath.Dir(jsonFilePath)
-	usedPubKey := ""
-	usedPubKey_cluster := ""
-	svcInput.Deployment, svcInput.DeploymentSignature, usedPubKey = SignDeployment(sf.Deployment, sf.DeploymentSignature, baseDir, false, keyFilePath, pubKeyFilePath, dontTouchImage, pullImage)
-	svcInput.ClusterDeployment, svcInput.ClusterDeploymentSignature, usedPubKey_cluster = SignDeployment(sf.ClusterDeployment, sf.ClusterDeploymentSignature, baseDir, true, keyFilePath, pubKeyFilePath, dontTouchImage, pullImage)
+	svcInput, pubKeyToStore := SignService(sf, jsonFilePath, keyFilePath, pubKeyFilePath, dontTouchImage, pullImage)
 
 	// Create or update resource in the exchange
 	exchId := cutil.FormExchangeIdForService(svcInput.URL, svcInput.Version, svcInput.Arch)
@@ -213,12 +212,6 @@ func SignAndPublish(sf *common.ServiceFile, org, userPw, jsonFilePath, keyFilePa
 	}
 
 	// Store the public key in the exchange, if they are used
-	pubKeyToStore := ""
-	if usedPubKey != "" {
-		pubKeyToStore = usedPubKey
-	} else if usedPubKey_cluster != "" {
-		pubKeyToStore = usedPubKey_cluster
-	}
 	if pubKeyToStore != "" {
 		bodyBytes := cliutils.ReadFile(pubKeyToStore)
 		baseName := filepath.Base(pubKeyToStore)
@@ -280,6 +273,23 @@ func SignAndPublish(sf *common.ServiceFile, org, userPw, jsonFilePath, keyFilePa
 	return
 }
 
+// Convert the service file into the service definition for the exchange, signing the deployments with the deployment config
+// plugins if they are not already signed. It returns the service definition and the public key that should be stored with it.
+func SignService(sf *common.ServiceFile, jsonFilePath, keyFilePath, pubKeyFilePath string, dontTouchImage bool, pullImage bool) (exchange.ServiceDefinition, string) {
+	svcInput := exchange.ServiceDefinition{Label: sf.Label, Description: sf.Description, Public: sf.Public, Documentation: sf.Documentation, URL: sf.URL, Version: sf.Version, Arch: sf.Arch, Sharable: sf.Sharable, MatchHardware: sf.MatchHardware, RequiredServices: sf.RequiredServices, UserInputs: sf.UserInputs}
+
+	baseDir := filepath.Dir(jsonFilePath)
+	usedPubKey := ""
+	usedPubKey_cluster := ""
+	svcInput.Deployment, svcInput.DeploymentSignature, usedPubKey = SignDeployment(sf.Deployment, sf.DeploymentSignature, baseDir, false, keyFilePath, pubKeyFilePath, dontTouchImage, pullImage)
+	svcInput.ClusterDeployment, svcInput.ClusterDeploymentSignature, usedPubKey_cluster = SignDeployment(sf.ClusterDeployment, sf.ClusterDeploymentSignature, baseDir, true, keyFilePath, pubKeyFilePath, dontTouchImage, pullImage)
+
+	if usedPubKey != "" {
+		return svcInput, usedPubKey
+	}
+	return svcInput, usedPubKey_cluster
+}
+
 // The function signs the given deployment if it is not empty abd not already signed. It returns the deployment, its signature
 // and the public key whose matching private was used for signing the deployment.
 func SignDeployment(deployment interface{}, deploymentSignature string, baseDir string, isCluster bool, keyFilePath string, pubKeyFilePath string, dontTouchImage bool, pullImage bool) (string, string, string) {
@@ -572,14 +582,7 @@ func ServiceAddPolicy(org string, credToUse string, service string, jsonFilePath
 	msgPrinter.Printf("The following property value will be overriden: service.url, service.name, service.org, service.version, service.arch")
 	msgPrinter.Println()
 
-	properties := policyFile.Properties
-	properties.Add_Property(externalpolicy.Property_Factory(externalpolicy.PROP_SVC_URL, serviceName), true)
-	properties.Add_Property(externalpolicy.Property_Factory(externalpolicy.PROP_SVC_NAME, serviceName), true)
-	properties.Add_Property(externalpolicy.Property_Factory(externalpolicy.PROP_SVC_ORG, svcorg), true)
-	properties.Add_Property(externalpolicy.Property_Factory(externalpolicy.PROP_SVC_VERSION, serviceVersion), true)
-	properties.Add_Property(externalpolicy.Property_Factory(externalpolicy.PROP_SVC_ARCH, serviceArch), true)
-
-	policyFile.Properties = properties
+	AddServicePolicyBuiltInProperties(&policyFile, svcorg, serviceName, serviceVersion, serviceArch)
 
 	//Check the policy file format again
 	err = policyFile.ValidateAndNormalize()
@@ -596,6 +599,18 @@ func ServiceAddPolicy(org string, credToUse string, service string, jsonFilePath
 	msgPrinter.Println()
 }
 
+// Set the built-in properties of the service in the service policy, overriding the values that are already there.
+func AddServicePolicyBuiltInProperties(policyFile *externalpolicy.ExternalPolicy, svcOrg string, svcUrl string, svcVersion string, svcArch string) {
+	properties := policyFile.Properties
+	properties.Add_Property(externalpolicy.Property_Factory(externalpolicy.PROP_SVC_URL, svcUrl), true)
+	properties.Add_Property(externalpolicy.Property_Factory(externalpolicy.PROP_SVC_NAME, svcUrl), true)
+	properties.Add_Property(externalpolicy.Property_Factory(externalpolicy.PROP_SVC_ORG, svcOrg), true)
+	properties.Add_Property(externalpolicy.Property_Factory(externalpolicy.PROP_SVC_VERSION, svcVersion), true)
+	properties.Add_Property(externalpolicy.Property_Factory(externalpolicy.PROP_SVC_ARCH, svcArch), true)
+
+	policyFile.Properties = properties
+}
+
 //ServiceRemovePolicy removes the service policy in the exchange
 func ServiceRemovePolicy(org string, credToUse string, service string, force bool) {
 	// get message printer
@@ -1,7 +1,6 @@
 package exchange
 
 import (
-	"encoding/json"
 	"fmt"
 	"github.com/open-horizon/anax/cli/cliutils"
 	"github.com/open-horizon/anax/i18n"
@@ -26,6 +25,16 @@ type ExchangeUser struct {
 	UpdatedBy   string `json:"updatedBy"`
 }
 
+// The table output format of 'hzn exchange user list'. The password is never a column.
+var exchangeUserTable = &cliutils.TableFormat{Columns: []cliutils.TableColumn{
+	{Header: "ID"},
+	{Header: "EMAIL", Path: ".email"},
+	{Header: "ADMIN", Path: ".admin"},
+	{Header: "HUB ADMIN", Path: ".hubAdmin"},
+	{Header: "LAST UPDATED", Path: ".lastUpdated", Wide: true},
+	{Header: "UPDATED BY", Path: ".updatedBy", Wide: true},
+}}
+
 func UserList(org, userPwCreds, theUser string, allUsers, namesOnly bool) {
 	// get message printer
 	msgPrinter := i18n.GetMessagePrinter()
@@ -54,14 +63,9 @@ func UserList(org, userPwCreds, theUser string, allUsers, namesOnly bool) {
 		for u := range users.Users {
 			usernames = append(usernames, u)
 		}
-		jsonBytes, err := json.MarshalIndent(usernames, "", cliutils.JSON_INDENT)
-		if err != nil {
-			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'exchange user list' output: %v", err))
-		}
-		fmt.Printf("%s\n", jsonBytes)
+		cliutils.PrintOutput(usernames, cliutils.NamesTable, "exchange user list")
 	} else { // show full resources
-		output := cliutils.MarshalIndent(users.Users, "exchange users list")
-		fmt.Println(output)
+		cliutils.PrintOutput(users.Users, exchangeUserTable, "exchange users list")
 	}
 }
 
@@ -10,9 +10,11 @@ import (
 
 	"github.com/open-horizon/anax/cli/agreement"
 	"github.com/open-horizon/anax/cli/agreementbot"
+	"github.com/open-horizon/anax/cli/apply"
 	"github.com/open-horizon/anax/cli/attribute"
 	"github.com/open-horizon/anax/cli/cliconfig"
 	"github.com/open-horizon/anax/cli/cliutils"
+	"github.com/open-horizon/anax/cli/credentials"
 	"github.com/open-horizon/anax/cli/deploycheck"
 	"github.com/open-horizon/anax/cli/dev"
 	"github.com/open-horizon/anax/cli/eventlog"
@@ -49,7 +51,9 @@ func main() {
 		i18n.InitMessagePrinter(true)
 	}
 
-	// set up environment variables from the cli package configuration file and user configuration file.
+	// set up environment variables from the cli package configuration file, user configuration file and the context.
+	// The --context flag is needed before the flags are parsed, so get it from the arguments.
+	cliconfig.CONTEXT_OVERRIDE = cliconfig.GetContextFromArgs(os.Args[1:])
 	cliconfig.SetEnvVarsFromConfigFiles("")
 
 	// initialize the message printer for globalization again because HZN_LANG could have changed from the above call.
@@ -86,6 +90,16 @@ Environment Variables:
       to communicate with the Horizon Model Management Service, for example
       https://exchange.bluehorizon.network/css/. (By default hzn will ask the
       Horizon Agent for the URL.)
+  HZN_CONTEXT:  Default value for the '--context' flag, to specify the
+      context in ~/.hzn/contexts.json whose exchange URL, CSS URL, org,
+      credentials and certificate are used. (See 'hzn config context'.)
+  HZN_CREDENTIALS_PASSPHRASE:  The passphrase that unlocks the credential
+      store in ~/.hzn/credentials.json, instead of a prompt. (See 'hzn
+      credentials'.)
+  HZN_CREDENTIALS_KEY_FILE:  The key file that unlocks the credential store,
+      when the store is protected with a key file.
+  HZN_AGBOT_SECURE_API:  The https URL of the agbot secure API, which the
+      agbot admin sub-commands use, for example https://agbot.example.com:8083.
 
   All these environment variables and ones mentioned in the command help can be
   specified in user's configuration file: ~/.hzn/hzn.json with JSON format.
@@ -99,12 +113,45 @@ Environment Variables:
 	app.UsageTemplate(kingpin.CompactUsageTemplate)
 	cliutils.Opts.Verbose = app.Flag("verbose", msgPrinter.Sprintf("Verbose output.")).Short('v').Bool()
 	cliutils.Opts.IsDryRun = app.Flag("dry-run", msgPrinter.Sprintf("When calling the Horizon or Exchange API, do GETs, but don't do PUTs, POSTs, or DELETEs.")).Bool()
+	app.Flag("context", msgPrinter.Sprintf("Use this context from ~/.hzn/contexts.json instead of the current context. The environment variables that are set still take precedence over the context. If not specified, HZN_CONTEXT will be used as a default.")).String()
+	cliutils.Opts.Output = app.Flag("output", msgPrinter.Sprintf("The output format of the commands that display resources: json, yaml, table, wide, jsonpath=<expression> or go-template=<template>. The table and wide formats display the main fields of each resource in columns, the commands without a table format display json. The short flag -o is not available because it is the organization flag of many commands. The default is json.")).String()
 
 	envCmd := app.Command("env", msgPrinter.Sprintf("Show the Horizon Environment Variables."))
 
 	versionCmd := app.Command("version", msgPrinter.Sprintf("Show the Horizon version.")) // using a cmd for this instead of --version flag, because kingpin takes over the latter and can't get version only when it is needed
 	archCmd := app.Command("architecture", msgPrinter.Sprintf("Show the architecture of this machine (as defined by Horizon and golang)."))
 
+	configCmd := app.Command("config", msgPrinter.Sprintf("Manage the configuration of the hzn command."))
+	configContextCmd := configCmd.Command("context", msgPrinter.Sprintf("Manage named contexts, each holding an exchange URL, CSS URL, organization, credentials reference and certificate path. The current context provides the defaults for every command, like the config files do, but environment variables still take precedence."))
+	configContextAddCmd := configContextCmd.Command("add", msgPrinter.Sprintf("Add a context, or replace the context with the same name."))
+	configContextAddName := configContextAddCmd.Arg("name", msgPrinter.Sprintf("The name of the context.")).Required().String()
+	configContextAddExchUrl := configContextAddCmd.Flag("exchange-url", msgPrinter.Sprintf("The URL of the Horizon Exchange, used as HZN_EXCHANGE_URL.")).String()
+	configContextAddCssUrl := configContextAddCmd.Flag("css-url", msgPrinter.Sprintf("The URL of the Model Management Service, used as HZN_FSS_CSSURL.")).String()
+	configContextAddOrg := configContextAddCmd.Flag("org", msgPrinter.Sprintf("The Horizon organization ID, used as HZN_ORG_ID.")).Short('o').String()
+	configContextAddCredRef := configContextAddCmd.Flag("credentials-ref", msgPrinter.Sprintf("Where the Horizon Exchange user credentials for HZN_EXCHANGE_USER_AUTH are, either env:<env var name>, file:<file path> or store:<name of a credential in the credential store>. The credentials themselves are not stored in the context.")).Short('c').String()
+	configContextAddCertPath := configContextAddCmd.Flag("cert-path", msgPrinter.Sprintf("The path of the management hub certificate, used as HZN_MGMT_HUB_CERT_PATH.")).String()
+	configContextAddUse := configContextAddCmd.Flag("use", msgPrinter.Sprintf("Also make it the current context.")).Bool()
+	configContextUseCmd := configContextCmd.Command("use", msgPrinter.Sprintf("Make a context the current context."))
+	configContextUseName := configContextUseCmd.Arg("name", msgPrinter.Sprintf("The name of the context.")).Required().String()
+	configContextListCmd := configContextCmd.Command("list", msgPrinter.Sprintf("Display the names of the contexts. The current context is marked with '*'."))
+	configContextListLong := configContextListCmd.Flag("long", msgPrinter.Sprintf("Display the settings of the contexts and the name of the current context.")).Short('l').Bool()
+	configContextDelCmd := configContextCmd.Command("delete", msgPrinter.Sprintf("Remove a context."))
+	configContextDelName := configContextDelCmd.Arg("name", msgPrinter.Sprintf("The name of the context.")).Required().String()
+	configContextDelForce := configContextDelCmd.Flag("force", msgPrinter.Sprintf("Skip the 'are you sure?' prompt.")).Short('f').Bool()
+
+	credentialsCmd := app.Command("credentials", msgPrinter.Sprintf("Manage the credentials in the encrypted credential store ~/.hzn/credentials.json. A credential that is named like an environment variable, e.g. HZN_EXCHANGE_USER_AUTH, is used when that environment variable is not set. The store is unlocked with HZN_CREDENTIALS_KEY_FILE, HZN_CREDENTIALS_PASSPHRASE or a passphrase prompt."))
+	credentialsSetCmd := credentialsCmd.Command("set", msgPrinter.Sprintf("Encrypt a credential and add it to the credential store, or replace it. The first credential decides whether the store is protected with a passphrase or a key file."))
+	credentialsSetName := credentialsSetCmd.Arg("name", msgPrinter.Sprintf("The name of the credential, e.g. HZN_EXCHANGE_USER_AUTH.")).Required().String()
+	credentialsSetValue := credentialsSetCmd.Arg("value", msgPrinter.Sprintf("The value of the credential. If not specified, it is prompted for, or read from stdin when stdin is not a terminal.")).String()
+	credentialsSetKeyFile := credentialsSetCmd.Flag("key-file", msgPrinter.Sprintf("The key file that protects the credential store. If not specified, HZN_CREDENTIALS_KEY_FILE will be used as a default. If neither is set, the store is protected with a passphrase.")).ExistingFile()
+	credentialsGetCmd := credentialsCmd.Command("get", msgPrinter.Sprintf("Decrypt a credential and display it."))
+	credentialsGetName := credentialsGetCmd.Arg("name", msgPrinter.Sprintf("The name of the credential.")).Required().String()
+	credentialsGetKeyFile := credentialsGetCmd.Flag("key-file", msgPrinter.Sprintf("The key file that protects the credential store. If not specified, HZN_CREDENTIALS_KEY_FILE will be used as a default.")).ExistingFile()
+	credentialsListCmd := credentialsCmd.Command("list", msgPrinter.Sprintf("Display the names of the credentials in the credential store."))
+	credentialsRemoveCmd := credentialsCmd.Command("remove", msgPrinter.Sprintf("Remove a credential from the credential store."))
+	credentialsRemoveName := credentialsRemoveCmd.Arg("name", msgPrinter.Sprintf("The name of the credential.")).Required().String()
+	credentialsRemoveForce := credentialsRemoveCmd.Flag("force", msgPrinter.Sprintf("Skip the 'are you sure?' prompt.")).Short('f').Bool()
+
 	exchangeCmd := app.Command("exchange", msgPrinter.Sprintf("List and manage Horizon Exchange resources."))
 	exOrg := exchangeCmd.Flag("org", msgPrinter.Sprintf("The Horizon exchange organization ID. If not specified, HZN_ORG_ID will be used as a default.")).Short('o').String()
 	exUserPw := exchangeCmd.Flag("user-pw", msgPrinter.Sprintf("Horizon Exchange user credentials to query and create exchange resources. If not specified, HZN_EXCHANGE_USER_AUTH will be used as a default. If you don't prepend it with the user's org, it will automatically be prepended with the -o value. As an alternative to using -o, you can set HZN_ORG_ID with the Horizon exchange organization ID")).Short('u').PlaceHolder("USER:PW").String()
@@ -140,6 +187,17 @@ Environment Variables:
 	exOrgDelFromAgbot := exOrgDelCmd.Flag("agbot", msgPrinter.Sprintf("The agbot to remove the deployment policy from. If omitted, the first agbot found in the exchange will be used. The format is 'agbot_org/agbot_id'.")).Short('a').String()
 	exOrgDelForce := exOrgDelCmd.Flag("force", msgPrinter.Sprintf("Skip the 'are you sure?' prompt.")).Short('f').Bool()
 
+	exOrgExportCmd := exOrgCmd.Command("export", msgPrinter.Sprintf("Export the services (with their keys, service policies and docker auths), patterns (with their keys), deployment policies, docker auths of the organization and MMS object metadata of an organization into a bundle file, which can be imported into another Horizon Exchange with 'hzn exchange org import'."))
+	exOrgExportOrg := exOrgExportCmd.Arg("org", msgPrinter.Sprintf("Export this organization. If omitted, the organization of the -o flag or HZN_ORG_ID is used.")).String()
+	exOrgExportFile := exOrgExportCmd.Flag("file", msgPrinter.Sprintf("The path of the bundle file to write. The bundle contains the docker registry tokens of the services, so it is only readable by its owner.")).Short('f').Required().String()
+	exOrgExportNoObjects := exOrgExportCmd.Flag("no-objects", msgPrinter.Sprintf("Do not export the MMS object metadata, e.g. when the Model Management Service is not available.")).Bool()
+	exOrgImportCmd := exOrgCmd.Command("import", msgPrinter.Sprintf("Create or update the resources in a bundle file that was written by 'hzn exchange org export' in an organization of the Horizon Exchange. The data of the MMS objects is not in the bundle, publish it with 'hzn mms object publish'."))
+	exOrgImportOrg := exOrgImportCmd.Arg("org", msgPrinter.Sprintf("Import into this organization. If omitted, the organization of the bundle is used. The references to the organization of the bundle are changed into references to this organization.")).String()
+	exOrgImportFile := exOrgImportCmd.Flag("file", msgPrinter.Sprintf("The path of the bundle file to import.")).Short('f').Required().ExistingFile()
+	exOrgImportPrivKeyFile := exOrgImportCmd.Flag("private-key-file", msgPrinter.Sprintf("Sign the services and patterns again with this private key, instead of keeping the signatures in the bundle.")).Short('k').ExistingFile()
+	exOrgImportPubKeyFile := exOrgImportCmd.Flag("public-key-file", msgPrinter.Sprintf("The path of public key file (that corresponds to the -k private key) that should be stored with the services and patterns instead of the keys in the bundle, to be used by the Horizon Agent to verify the signatures. If it is not specified, no public key is stored.")).Short('K').ExistingFile()
+	exOrgImportNoObjects := exOrgImportCmd.Flag("no-objects", msgPrinter.Sprintf("Do not import the MMS object metadata.")).Bool()
+
 	exUserCmd := exchangeCmd.Command("user", msgPrinter.Sprintf("List and manage users in the Horizon Exchange."))
 	exUserListCmd := exUserCmd.Command("list", msgPrinter.Sprintf("Display the user resource from the Horizon Exchange. (Normally you can only display your own user. If the user does not exist, you will get an invalid credentials error.)"))
 	exUserListUser := exUserListCmd.Arg("user", msgPrinter.Sprintf("List this one user. Default is your own user. Only admin users can list other users.")).String()
@@ -389,9 +447,15 @@ Environment Variables:
 	keyImportPubKeyFile := keyImportCmd.Flag("public-key-file", msgPrinter.Sprintf("The path of a pem public key file to be imported. The base name in the path is also used as the key name in the Horizon agent. If not specified, the environment variable HZN_PUBLIC_KEY_FILE will be used. If none of them are set, ~/.hzn/keys/service.public.pem is the default.")).Short('k').String()
 	keyDelCmd := keyCmd.Command("remove", msgPrinter.Sprintf("Remove the specified signing key from this Horizon agent."))
 	keyDelName := keyDelCmd.Arg("key-name", msgPrinter.Sprintf("The name of a specific key to remove.")).Required().String()
+	keyRotateCmd := keyCmd.Command("rotate", msgPrinter.Sprintf("Rotate the messaging key of this Horizon agent, or of the agbot with --agbot. The new public key is published to the Exchange, and the old key can still decrypt messages for the configured grace period."))
+	keyRotateMessaging := keyRotateCmd.Flag("messaging", msgPrinter.Sprintf("Rotate the messaging key. This is the only kind of key that can be rotated.")).Bool()
+	keyRotateAgbot := keyRotateCmd.Flag("agbot", msgPrinter.Sprintf("Rotate the messaging key of the agbot instead of the Horizon agent.")).Bool()
+	keyRotateUserPw := keyRotateCmd.Flag("user-pw", msgPrinter.Sprintf("Horizon Exchange admin user credentials of the agbot's org, only used with --agbot. The default is HZN_EXCHANGE_USER_AUTH environment variable. If you don't prepend it with the user's org, it will automatically be prepended with the value of the HZN_ORG_ID environment variable.")).Short('u').PlaceHolder("USER:PW").String()
 
 	nodeCmd := app.Command("node", msgPrinter.Sprintf("List and manage general information about this Horizon edge node."))
 	nodeListCmd := nodeCmd.Command("list", msgPrinter.Sprintf("Display general information about this Horizon edge node."))
+	nodeDiagCmd := nodeCmd.Command("diag", msgPrinter.Sprintf("Collect the diagnostics of this Horizon edge node into a gzipped tar bundle for support: the agent log, node, agreements, event logs, services, node policy and user input, docker containers, agent configuration and ESS status. Tokens, secrets and the values of user inputs marked sensitive are redacted, and the bundle has a manifest of what was collected. The agent API GET /node/diag returns the same bundle."))
+	nodeDiagBundleFile := nodeDiagCmd.Flag("bundle-file", msgPrinter.Sprintf("The file to write the bundle to. The default is node-diag-<date>-<time>.tgz in the current directory.")).Short('o').String()
 
 	policyCmd := app.Command("policy", msgPrinter.Sprintf("List and manage policy for this Horizon edge node."))
 	policyListCmd := policyCmd.Command("list", msgPrinter.Sprintf("Display this edge node's policy."))
@@ -403,6 +467,16 @@ Environment Variables:
 	policyRemoveCmd := policyCmd.Command("remove", msgPrinter.Sprintf("Remove the node's policy."))
 	policyRemoveForce := policyRemoveCmd.Flag("force", msgPrinter.Sprintf("Skip the 'are you sure?' prompt.")).Short('f').Bool()
 
+	applyCmd := app.Command("apply", msgPrinter.Sprintf("Create, update and optionally remove the services, patterns and deployment policies of an organization in the Horizon Exchange so that they match the resource manifests in a directory. The plan of changes is displayed before they are made."))
+	applyOrg := applyCmd.Flag("org", msgPrinter.Sprintf("The Horizon exchange organization ID. If not specified, HZN_ORG_ID will be used as a default.")).Short('o').String()
+	applyUserPw := applyCmd.Flag("user-pw", msgPrinter.Sprintf("Horizon Exchange user credentials. If not specified, HZN_EXCHANGE_USER_AUTH will be used as a default.")).Short('u').PlaceHolder("USER:PW").String()
+	applyDir := applyCmd.Flag("dir", msgPrinter.Sprintf("The directory that contains the resource manifests. The JSON files in the directory and its sub directories are read, except for hidden files and hzn.json.")).Short('f').Required().ExistingDir()
+	applyPrivKeyFile := applyCmd.Flag("private-key-file", msgPrinter.Sprintf("The path of a private key file to be used to sign the services and patterns. If not specified, the environment variable HZN_PRIVATE_KEY_FILE will be used. If none of them are set, ~/.hzn/keys/service.private.key is the default.")).Short('k').ExistingFile()
+	applyPubKeyFile := applyCmd.Flag("public-key-file", msgPrinter.Sprintf("The path of public key file (that corresponds to the private key) that should be stored with the services and patterns, to be used by the Horizon Agent to verify the signatures. If both this and -k flags are not specified, the environment variable HZN_PUBLIC_KEY_FILE will be used. If HZN_PUBLIC_KEY_FILE is not set, ~/.hzn/keys/service.public.pem is the default.")).Short('K').ExistingFile()
+	applyPrune := applyCmd.Flag("prune", msgPrinter.Sprintf("Remove the services, patterns and deployment policies of the organization that are not in a manifest, and the service policies that are not in the manifest of their service.")).Bool()
+	applyForce := applyCmd.Flag("force", msgPrinter.Sprintf("Skip the 'are you sure?' prompt when resources are removed.")).Bool()
+	applyNoConstraints := applyCmd.Flag("no-constraints", msgPrinter.Sprintf("Allow deployment policies without constraints.")).Bool()
+
 	deploycheckCmd := app.Command("deploycheck", msgPrinter.Sprintf("Check deployment compatibility."))
 	deploycheckOrg := deploycheckCmd.Flag("org", msgPrinter.Sprintf("The Horizon exchange organization ID. If not specified, HZN_ORG_ID will be used as a default.")).Short('o').String()
 	deploycheckUserPw := deploycheckCmd.Flag("user-pw", msgPrinter.Sprintf("Horizon exchange user credential to query exchange resources. If not specified, HZN_EXCHANGE_USER_AUTH or HZN_EXCHANGE_NODE_AUTH will be used as a default. If you don't prepend it with the organization id, it will automatically be prepended with the -o value.")).Short('u').PlaceHolder("USER:PW").String()
@@ -501,12 +575,17 @@ Environment Variables:
 
 	eventlogCmd := app.Command("eventlog", msgPrinter.Sprintf("List the event logs for the current or all registrations."))
 	eventlogListCmd := eventlogCmd.Command("list", msgPrinter.Sprintf("List the event logs for the current or all registrations."))
-	listTail := eventlogListCmd.Flag("tail", msgPrinter.Sprintf("Continuously polls the event log to display the most recent records, similar to tail -F behavior.")).Short('f').Bool()
+	listTail := eventlogListCmd.Flag("tail", msgPrinter.Sprintf("Follow the event log and display new records as they are saved, similar to tail -f behavior.")).Short('f').Bool()
 	listAllEventlogs := eventlogListCmd.Flag("all", msgPrinter.Sprintf("List all the event logs including the previous registrations.")).Short('a').Bool()
 	listDetailedEventlogs := eventlogListCmd.Flag("long", msgPrinter.Sprintf("List event logs with details.")).Short('l').Bool()
 	listSelectedEventlogs := eventlogListCmd.Flag("select", msgPrinter.Sprintf("Selection string. This flag can be repeated which means 'AND'. Each flag should be in the format of attribute=value, attribute~value, \"attribute>value\" or \"attribute<value\", where '~' means contains. The common attribute names are timestamp, severity, message, event_code, source_type, agreement_id, service_url etc. Use the '-l' flag to see all the attribute names.")).Short('s').Strings()
 	surfaceErrorsEventlogs := eventlogCmd.Command("surface", msgPrinter.Sprintf("List all the active errors that will be shared with the Exchange if the node is online."))
 	surfaceErrorsEventlogsLong := surfaceErrorsEventlogs.Flag("long", msgPrinter.Sprintf("List the full event logs of the surface errors.")).Short('l').Bool()
+	eventlogExportCmd := eventlogCmd.Command("export", msgPrinter.Sprintf("Export the event logs as json lines, one event log per line."))
+	exportSinceEventlogs := eventlogExportCmd.Flag("since", msgPrinter.Sprintf("Only export the event logs saved at or after this time. It can be a duration before now (e.g. 24h or 30m), an RFC3339 time (e.g. 2020-05-01T00:00:00Z) or unix seconds.")).String()
+	exportAllEventlogs := eventlogExportCmd.Flag("all", msgPrinter.Sprintf("Export all the event logs including the previous registrations.")).Short('a').Bool()
+	exportSelectedEventlogs := eventlogExportCmd.Flag("select", msgPrinter.Sprintf("Selection string. This flag can be repeated which means 'AND'. Each flag should be in the format of attribute=value, attribute~value, \"attribute>value\" or \"attribute<value\", where '~' means contains.")).Short('s').Strings()
+	exportFileEventlogs := eventlogExportCmd.Flag("file", msgPrinter.Sprintf("The file to write the event logs to. If omitted, the event logs are written to stdout.")).String()
 
 	devCmd := app.Command("dev", msgPrinter.Sprintf("Development tools for creation of services."))
 	devHomeDirectory := devCmd.Flag("directory", msgPrinter.Sprintf("Directory containing Horizon project metadata. If omitted, a subdirectory called 'horizon' under current directory will be used.")).Short('d').String()
@@ -528,6 +607,7 @@ Environment Variables:
 	devServiceNoFSS := devServiceStartTestCmd.Flag("noFSS", msgPrinter.Sprintf("Do not bring up file sync service (FSS) containers. They are brought up by default.")).Short('S').Bool()
 	devServiceStartCmdUserPw := devServiceStartTestCmd.Flag("user-pw", msgPrinter.Sprintf("Horizon Exchange user credentials to query exchange resources. Specify it when you want to automatically fetch the missing dependent services from the Exchange. The default is HZN_EXCHANGE_USER_AUTH environment variable. If you don't prepend it with the user's org, it will automatically be prepended with the value of the HZN_ORG_ID environment variable.")).Short('u').PlaceHolder("USER:PW").String()
 	devServiceStopTestCmd := devServiceCmd.Command("stop", msgPrinter.Sprintf("Stop a service that is running in a mocked Horizon Agent environment. This command is not supported for services using the %v deployment configuration.", kube_deployment.KUBE_DEPLOYMENT_CONFIG_TYPE))
+	devServiceWorkloadAPICmd := devServiceCmd.Command(dev.SERVICE_WORKLOAD_API_COMMAND, "").Hidden()
 	devServiceValidateCmd := devServiceCmd.Command("verify", msgPrinter.Sprintf("Validate the project for completeness and schema compliance."))
 	devServiceVerifyUserInputFile := devServiceValidateCmd.Flag("userInputFile", msgPrinter.Sprintf("File containing user input values for verification of a project. If omitted, the userinput file for the project will be used.")).Short('f').String()
 	devServiceValidateCmdUserPw := devServiceValidateCmd.Flag("user-pw", msgPrinter.Sprintf("Horizon Exchange user credentials to query exchange resources. Specify it when you want to automatically fetch the missing dependent services from the Exchange. The default is HZN_EXCHANGE_USER_AUTH environment variable. If you don't prepend it with the user's org, it will automatically be prepended with the value of the HZN_ORG_ID environment variable.")).Short('u').PlaceHolder("USER:PW").String()
@@ -539,7 +619,7 @@ Environment Variables:
 	devDependencyCmdSpecRef := devDependencyCmd.Flag("specRef", msgPrinter.Sprintf("The URL of the service dependency in the Exchange. Mutually exclusive with -p and --url.")).Short('s').String()
 	devDependencyCmdURL := devDependencyCmd.Flag("url", msgPrinter.Sprintf("The URL of the service dependency in the Exchange. Mutually exclusive with -p and --specRef.")).String()
 	devDependencyCmdOrg := devDependencyCmd.Flag("org", msgPrinter.Sprintf("The Org of the service dependency in the Exchange. Mutually exclusive with -p.")).Short('o').String()
-	devDependencyCmdVersion := devDependencyCmd.Flag("ver", msgPrinter.Sprintf("(optional) The Version of the service dependency in the Exchange. Mutually exclusive with -p.")).String()
+	devDependencyCmdVersion := devDependencyCmd.Flag("ver", msgPrinter.Sprintf("(optional) The Version of the service dependency in the Exchange. The fetch command also accepts a version range, e.g. ^1.2 or >=1.0.0,<2.0.0, and fetches the highest version within it. Mutually exclusive with -p.")).String()
 	devDependencyCmdArch := devDependencyCmd.Flag("arch", msgPrinter.Sprintf("(optional) The hardware Architecture of the service dependency in the Exchange. Mutually exclusive with -p.")).Short('a').String()
 	devDependencyFetchCmd := devDependencyCmd.Command("fetch", msgPrinter.Sprintf("Retrieving Horizon metadata for a new dependency."))
 	devDependencyFetchCmdProject := devDependencyFetchCmd.Flag("project", msgPrinter.Sprintf("Horizon project containing the definition of a dependency. Mutually exclusive with -s -o --ver -a and --url.")).Short('p').ExistingDir()
@@ -576,6 +656,26 @@ Environment Variables:
 	agbotPolicyListCmd := agbotPolicyCmd.Command("list", msgPrinter.Sprintf("List policies this Horizon agreement bot hosts."))
 	agbotPolicyOrg := agbotPolicyListCmd.Arg("org", msgPrinter.Sprintf("The organization the policy belongs to.")).String()
 	agbotPolicyName := agbotPolicyListCmd.Arg("name", msgPrinter.Sprintf("The policy name.")).String()
+	agbotPolicyPauseCmd := agbotPolicyCmd.Command("pause", msgPrinter.Sprintf("Stop making new agreements for a deployment policy or pattern. The existing agreements are not affected. The pause is shared by all the agbots using the same database."))
+	agbotPolicyPauseId := agbotPolicyPauseCmd.Arg("policy", msgPrinter.Sprintf("The deployment policy or pattern, as org/name. The org defaults to HZN_ORG_ID.")).Required().String()
+	agbotPolicyPausePattern := agbotPolicyPauseCmd.Flag("pattern", msgPrinter.Sprintf("The argument is a pattern instead of a deployment policy.")).Short('p').Bool()
+	agbotPolicyPauseUserPw := agbotPolicyPauseCmd.Flag("user-pw", msgPrinter.Sprintf("Horizon Exchange admin user credentials. The default is HZN_EXCHANGE_USER_AUTH environment variable. If you don't prepend it with the user's org, it will automatically be prepended with the org of the policy.")).Short('u').PlaceHolder("USER:PW").String()
+	agbotPolicyResumeCmd := agbotPolicyCmd.Command("resume", msgPrinter.Sprintf("Resume making agreements for a paused deployment policy or pattern."))
+	agbotPolicyResumeId := agbotPolicyResumeCmd.Arg("policy", msgPrinter.Sprintf("The deployment policy or pattern, as org/name. The org defaults to HZN_ORG_ID.")).Required().String()
+	agbotPolicyResumePattern := agbotPolicyResumeCmd.Flag("pattern", msgPrinter.Sprintf("The argument is a pattern instead of a deployment policy.")).Short('p').Bool()
+	agbotPolicyResumeUserPw := agbotPolicyResumeCmd.Flag("user-pw", msgPrinter.Sprintf("Horizon Exchange admin user credentials. The default is HZN_EXCHANGE_USER_AUTH environment variable. If you don't prepend it with the user's org, it will automatically be prepended with the org of the policy.")).Short('u').PlaceHolder("USER:PW").String()
+	agbotPolicyPausedCmd := agbotPolicyCmd.Command("paused", msgPrinter.Sprintf("List the paused deployment policies and patterns."))
+	agbotPolicyPausedUserPw := agbotPolicyPausedCmd.Flag("user-pw", msgPrinter.Sprintf("Horizon Exchange admin user credentials. The default is HZN_EXCHANGE_USER_AUTH environment variable. If you don't prepend it with the user's org, it will automatically be prepended with the value of the HZN_ORG_ID environment variable.")).Short('u').PlaceHolder("USER:PW").String()
+	agbotPolicyCancelCmd := agbotPolicyCmd.Command("cancel", msgPrinter.Sprintf("Cancel all the agreements made with a deployment policy or pattern. Pause the policy first to stop the agbot from making new agreements for it."))
+	agbotPolicyCancelId := agbotPolicyCancelCmd.Arg("policy", msgPrinter.Sprintf("The deployment policy or pattern, as org/name. The org defaults to HZN_ORG_ID.")).Required().String()
+	agbotPolicyCancelPattern := agbotPolicyCancelCmd.Flag("pattern", msgPrinter.Sprintf("The argument is a pattern instead of a deployment policy.")).Short('p').Bool()
+	agbotPolicyCancelUserPw := agbotPolicyCancelCmd.Flag("user-pw", msgPrinter.Sprintf("Horizon Exchange admin user credentials. The default is HZN_EXCHANGE_USER_AUTH environment variable. If you don't prepend it with the user's org, it will automatically be prepended with the org of the policy.")).Short('u').PlaceHolder("USER:PW").String()
+	agbotNodeCmd := agbotCmd.Command("node", msgPrinter.Sprintf("Manage the edge nodes this Horizon agreement bot makes agreements with."))
+	agbotNodeReevaluateCmd := agbotNodeCmd.Command("reevaluate", msgPrinter.Sprintf("Re-evaluate a node against the deployment policies and patterns this agbot serves now, instead of waiting for the next node search."))
+	agbotNodeReevaluateId := agbotNodeReevaluateCmd.Arg("node", msgPrinter.Sprintf("The node, as org/id. The org defaults to HZN_ORG_ID.")).Required().String()
+	agbotNodeReevaluateUserPw := agbotNodeReevaluateCmd.Flag("user-pw", msgPrinter.Sprintf("Horizon Exchange admin user credentials. The default is HZN_EXCHANGE_USER_AUTH environment variable. If you don't prepend it with the user's org, it will automatically be prepended with the org of the node.")).Short('u').PlaceHolder("USER:PW").String()
+	agbotDrainCmd := agbotCmd.Command("drain", msgPrinter.Sprintf("Stop this agbot from making agreements, hand its partition over to the other agbots and terminate it."))
+	agbotDrainUserPw := agbotDrainCmd.Flag("user-pw", msgPrinter.Sprintf("Horizon Exchange admin user credentials of the agbot's org. The default is HZN_EXCHANGE_USER_AUTH environment variable. If you don't prepend it with the user's org, it will automatically be prepended with the value of the HZN_ORG_ID environment variable.")).Short('u').PlaceHolder("USER:PW").String()
 	agbotStatusCmd := agbotCmd.Command("status", msgPrinter.Sprintf("Display the current horizon internal status for the Horizon agreement bot."))
 	agbotStatusLong := agbotStatusCmd.Flag("long", msgPrinter.Sprintf("Show detailed status")).Short('l').Bool()
 
@@ -655,6 +755,7 @@ Environment Variables:
 
 	// Parse cmd and apply env var defaults
 	fullCmd := kingpin.MustParse(app.Parse(os.Args[1:]))
+	cliutils.CheckOutputFlag()
 	//cliutils.Verbose("Full command: %s", fullCmd)
 
 	// mms command is not supported for on a cluster node
@@ -807,6 +908,12 @@ Environment Variables:
 		}
 	}
 
+	// For the apply command, make sure that org and exchange credentials are specified in some way.
+	if fullCmd == "apply" {
+		applyOrg = cliutils.RequiredWithDefaultEnvVar(applyOrg, "HZN_ORG_ID", msgPrinter.Sprintf("organization ID must be specified with either the -o flag or HZN_ORG_ID"))
+		applyUserPw = cliutils.RequiredWithDefaultEnvVar(applyUserPw, "HZN_EXCHANGE_USER_AUTH", msgPrinter.Sprintf("exchange user authentication must be specified with either the -u flag or HZN_EXCHANGE_USER_AUTH"))
+	}
+
 	// For the voucher import command family, make sure that org and exchange credentials are specified in some way.
 	if strings.HasPrefix(fullCmd, "voucher import") {
 		voucherOrg = cliutils.RequiredWithDefaultEnvVar(voucherOrg, "HZN_ORG_ID", msgPrinter.Sprintf("organization ID must be specified with either the -o flag or HZN_ORG_ID"))
@@ -840,9 +947,29 @@ Environment Variables:
 	case envCmd.FullCommand():
 		envOrg := os.Getenv("HZN_ORG_ID")
 		envUserPw := os.Getenv("HZN_EXCHANGE_USER_AUTH")
+		if envUserPw == "" && cliutils.HasStoredCredential("HZN_EXCHANGE_USER_AUTH") {
+			// the stored credentials are never displayed
+			envUserPw = msgPrinter.Sprintf("(in the credential store)")
+		}
 		envExchUrl := cliutils.GetExchangeUrl()
 		envCcsUrl := cliutils.GetMMSUrl()
-		node.Env(envOrg, envUserPw, envExchUrl, envCcsUrl)
+		node.Env(envOrg, envUserPw, envExchUrl, envCcsUrl, cliconfig.CURRENT_CONTEXT)
+	case configContextAddCmd.FullCommand():
+		cliconfig.ContextAdd(*configContextAddName, cliconfig.CliContext{ExchangeUrl: *configContextAddExchUrl, CssUrl: *configContextAddCssUrl, Org: *configContextAddOrg, CredentialsRef: *configContextAddCredRef, CertPath: *configContextAddCertPath}, *configContextAddUse)
+	case configContextUseCmd.FullCommand():
+		cliconfig.ContextUse(*configContextUseName)
+	case configContextListCmd.FullCommand():
+		cliconfig.ContextList(!*configContextListLong)
+	case configContextDelCmd.FullCommand():
+		cliconfig.ContextDelete(*configContextDelName, *configContextDelForce)
+	case credentialsSetCmd.FullCommand():
+		credentials.Set(*credentialsSetName, *credentialsSetValue, *credentialsSetKeyFile)
+	case credentialsGetCmd.FullCommand():
+		credentials.Get(*credentialsGetName, *credentialsGetKeyFile)
+	case credentialsListCmd.FullCommand():
+		credentials.List()
+	case credentialsRemoveCmd.FullCommand():
+		credentials.Remove(*credentialsRemoveName, *credentialsRemoveForce)
 	case versionCmd.FullCommand():
 		node.Version()
 	case archCmd.FullCommand():
@@ -860,6 +987,10 @@ Environment Variables:
 		exchange.OrgUpdate(*exOrg, *exUserPw, *exOrgUpdateOrg, *exOrgUpdateLabel, *exOrgUpdateDesc, *exOrgUpdateTags, *exOrgUpdateHBMin, *exOrgUpdateHBMax, *exOrgUpdateHBAdjust, *exOrgUpdateMaxNodes)
 	case exOrgDelCmd.FullCommand():
 		exchange.OrgDel(*exOrg, *exUserPw, *exOrgDelOrg, *exOrgDelFromAgbot, *exOrgDelForce)
+	case exOrgExportCmd.FullCommand():
+		exchange.OrgExport(*exOrg, *exUserPw, *exOrgExportOrg, *exOrgExportFile, *exOrgExportNoObjects)
+	case exOrgImportCmd.FullCommand():
+		exchange.OrgImport(*exOrg, *exUserPw, *exOrgImportOrg, *exOrgImportFile, *exOrgImportPrivKeyFile, *exOrgImportPubKeyFile, *exOrgImportNoObjects, *cliutils.Opts.IsDryRun)
 
 	case exUserListCmd.FullCommand():
 		exchange.UserList(*exOrg, *exUserPw, *exUserListUser, *exUserListAll, *exUserListNamesOnly)
@@ -981,8 +1112,18 @@ Environment Variables:
 		key.Import(*keyImportPubKeyFile)
 	case keyDelCmd.FullCommand():
 		key.Remove(*keyDelName)
+	case keyRotateCmd.FullCommand():
+		if !*keyRotateMessaging {
+			cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("only the messaging key can be rotated, specify --messaging"))
+		} else if *keyRotateAgbot {
+			agreementbot.RotateMessagingKey(*keyRotateUserPw)
+		} else {
+			key.RotateMessagingKey()
+		}
 	case nodeListCmd.FullCommand():
 		node.List()
+	case nodeDiagCmd.FullCommand():
+		node.Diag(*nodeDiagBundleFile)
 	case policyListCmd.FullCommand():
 		policy.List()
 	case policyNewCmd.FullCommand():
@@ -993,6 +1134,8 @@ Environment Variables:
 		policy.Patch(*policyPatchInput)
 	case policyRemoveCmd.FullCommand():
 		policy.Remove(*policyRemoveForce)
+	case applyCmd.FullCommand():
+		apply.Apply(*applyOrg, *applyUserPw, *applyDir, *applyPrivKeyFile, *applyPubKeyFile, *cliutils.Opts.IsDryRun, *applyPrune, *applyForce, *applyNoConstraints)
 	case policyCompCmd.FullCommand():
 		deploycheck.PolicyCompatible(*deploycheckOrg, *deploycheckUserPw, *policyCompNodeId, *policyCompNodeArch, *policyCompNodeType, *policyCompNodePolFile, *policyCompBPolId, *policyCompBPolFile, *policyCompSPolFile, *policyCompSvcFile, *deploycheckCheckAll, *deploycheckLong)
 	case userinputCompCmd.FullCommand():
@@ -1037,12 +1180,16 @@ Environment Variables:
 		eventlog.List(*listAllEventlogs, *listDetailedEventlogs, *listSelectedEventlogs, *listTail)
 	case surfaceErrorsEventlogs.FullCommand():
 		eventlog.ListSurfaced(*surfaceErrorsEventlogsLong)
+	case eventlogExportCmd.FullCommand():
+		eventlog.Export(*exportAllEventlogs, *exportSinceEventlogs, *exportSelectedEventlogs, *exportFileEventlogs)
 	case devServiceNewCmd.FullCommand():
 		dev.ServiceNew(*devHomeDirectory, *devServiceNewCmdOrg, *devServiceNewCmdName, *devServiceNewCmdVer, *devServiceNewCmdImage, *devServiceNewCmdNoImageGen, *devServiceNewCmdCfg, *devServiceNewCmdNoPattern, *devServiceNewCmdNoPolicy)
 	case devServiceStartTestCmd.FullCommand():
 		dev.ServiceStartTest(*devHomeDirectory, *devServiceUserInputFile, *devServiceConfigFile, *devServiceConfigType, *devServiceNoFSS, *devServiceStartCmdUserPw)
 	case devServiceStopTestCmd.FullCommand():
 		dev.ServiceStopTest(*devHomeDirectory)
+	case devServiceWorkloadAPICmd.FullCommand():
+		dev.ServeWorkloadAPI()
 	case devServiceValidateCmd.FullCommand():
 		dev.ServiceValidate(*devHomeDirectory, *devServiceVerifyUserInputFile, []string{}, "", *devServiceValidateCmdUserPw)
 	case devServiceLogCmd.FullCommand():
@@ -1061,6 +1208,18 @@ Environment Variables:
 		agreementbot.List()
 	case agbotPolicyListCmd.FullCommand():
 		agreementbot.PolicyList(*agbotPolicyOrg, *agbotPolicyName)
+	case agbotPolicyPauseCmd.FullCommand():
+		agreementbot.PolicyPause(*agbotPolicyPauseId, *agbotPolicyPausePattern, *agbotPolicyPauseUserPw)
+	case agbotPolicyResumeCmd.FullCommand():
+		agreementbot.PolicyResume(*agbotPolicyResumeId, *agbotPolicyResumePattern, *agbotPolicyResumeUserPw)
+	case agbotPolicyPausedCmd.FullCommand():
+		agreementbot.PolicyPausedList(*agbotPolicyPausedUserPw)
+	case agbotPolicyCancelCmd.FullCommand():
+		agreementbot.PolicyCancelAgreements(*agbotPolicyCancelId, *agbotPolicyCancelPattern, *agbotPolicyCancelUserPw)
+	case agbotNodeReevaluateCmd.FullCommand():
+		agreementbot.NodeReevaluate(*agbotNodeReevaluateId, *agbotNodeReevaluateUserPw)
+	case agbotDrainCmd.FullCommand():
+		agreementbot.Drain(*agbotDrainUserPw)
 	case utilSignCmd.FullCommand():
 		utilcmds.Sign(*utilSignPrivKeyFile)
 	case utilVerifyCmd.FullCommand():
@@ -148,6 +148,15 @@ func Remove(keyName string) {
 	msgPrinter.Println()
 }
 
+// Rotate the messaging key of the Horizon agent. The agent publishes the new public key to the Exchange, and records the
+// rotation in its event log.
+func RotateMessagingKey() {
+	cliutils.HorizonPutPost(http.MethodPost, "node/messagingkey", []int{202}, []byte{}, true)
+	msgPrinter := i18n.GetMessagePrinter()
+	msgPrinter.Printf("The Horizon agent is rotating its messaging key. Use 'hzn eventlog list' to see the result.")
+	msgPrinter.Println()
+}
+
 // verify the inputs, prompt for overwrite if files exist, create direcories if not exist.
 func verifyAndPrepareKeyCreateInput(outputDir string, privKeyFile string, pubKeyFile string, overwrite bool) (string, string, string) {
 	// get message printer
@@ -288,6 +288,14 @@ func (p *NativeDeploymentConfigPlugin) StartTest(homeDirectory string, userInput
 		return false
 	}
 
+	// Stop the file sync service and the workload API when the services cannot be started.
+	stopTestInfrastructure := func() {
+		if !noFSS {
+			sync_service.Stop(cw.GetClient())
+		}
+		dev.StopWorkloadAPI()
+	}
+
 	if !noFSS {
 		// Start the file sync service infrastructure containers so the services can use it in test mode.
 		sserr := sync_service.Start(cw, serviceDef.Org, absConfigFiles, configType)
@@ -297,13 +305,17 @@ func (p *NativeDeploymentConfigPlugin) StartTest(homeDirectory string, userInput
 		}
 	}
 
+	// Start the workload API so the services can report their status and store keys and values in test mode.
+	if waerr := dev.StartWorkloadAPI(cw, serviceDef.Org); waerr != nil {
+		stopTestInfrastructure()
+		cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, msgPrinter.Sprintf("'%v %v' unable to start the workload API, %v", dev.SERVICE_COMMAND, dev.SERVICE_START_COMMAND, waerr))
+	}
+
 	// Get the metadata for each dependency. The metadata is returned as a list of service definition files from
 	// the project's dependency directory.
 	deps, derr := dev.GetServiceDependencies(dir, serviceDef.RequiredServices)
 	if derr != nil {
-		if !noFSS {
-			sync_service.Stop(cw.GetClient())
-		}
+		stopTestInfrastructure()
 		cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, msgPrinter.Sprintf("'%v %v' unable to get service dependencies, %v", dev.SERVICE_COMMAND, dev.SERVICE_START_COMMAND, derr))
 	}
 
@@ -315,36 +327,28 @@ func (p *NativeDeploymentConfigPlugin) StartTest(homeDirectory string, userInput
 	// Generate an agreement id for testing purposes.
 	agreementId, aerr := cutil.GenerateAgreementId()
 	if aerr != nil {
-		if !noFSS {
-			sync_service.Stop(cw.GetClient())
-		}
+		stopTestInfrastructure()
 		cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, msgPrinter.Sprintf("'%v %v' unable to generate test agreementId, %v", dev.SERVICE_COMMAND, dev.SERVICE_START_COMMAND, aerr))
 	}
 
 	// If the service has dependencies, get them started first.
 	msNetworks, perr := dev.ProcessStartDependencies(dir, deps, userInputs.Global, userInputs.Services, cw, agreementId)
 	if perr != nil {
-		if !noFSS {
-			sync_service.Stop(cw.GetClient())
-		}
+		stopTestInfrastructure()
 		cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, msgPrinter.Sprintf("'%v %v' unable to start service dependencies, %v", dev.SERVICE_COMMAND, dev.SERVICE_START_COMMAND, perr))
 	}
 
 	// Get the service's deployment description from the deployment config in the definition.
 	dc, deployment, cerr := serviceDef.ConvertToDeploymentDescription(true)
 	if cerr != nil {
-		if !noFSS {
-			sync_service.Stop(cw.GetClient())
-		}
+		stopTestInfrastructure()
 		cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, "'%v %v' %v", dev.SERVICE_COMMAND, dev.SERVICE_START_COMMAND, cerr)
 	}
 
 	// Now we can start the service container.
 	_, err := dev.StartContainers(deployment, serviceDef.URL, userInputs.Global, serviceDef.UserInputs, userInputs.Services, serviceDef.Org, dc, cw, msNetworks, true, true, agreementId)
 	if err != nil {
-		if !noFSS {
-			sync_service.Stop(cw.GetClient())
-		}
+		stopTestInfrastructure()
 		cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, "'%v %v' %v.", dev.SERVICE_COMMAND, dev.SERVICE_START_COMMAND, err)
 	}
 
@@ -395,6 +399,11 @@ func (p *NativeDeploymentConfigPlugin) StopTest(homeDirectory string) bool {
 		cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, msgPrinter.Sprintf("'%v %v' unable to stop service dependencies, %v", dev.SERVICE_COMMAND, dev.SERVICE_STOP_COMMAND, err))
 	}
 
+	// Stop the workload API now that the service(s) are stopped.
+	if err := dev.StopWorkloadAPI(); err != nil {
+		cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, msgPrinter.Sprintf("'%v %v' unable to stop the workload API, %v", dev.SERVICE_COMMAND, dev.SERVICE_STOP_COMMAND, err))
+	}
+
 	// Perform the execution teardown.
 	dev.ExecutionTearDown(cw)
 
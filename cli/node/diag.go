@@ -0,0 +1,99 @@
+package node
+
+import (
+	"fmt"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/diag"
+	"github.com/open-horizon/anax/i18n"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// The docker endpoint when it is not in the agent configuration.
+const DEFAULT_DOCKER_ENDPOINT = "unix:///var/run/docker.sock"
+
+// Collect the diagnostics of this node into a gzipped tar bundle that can be sent to support. The items come from
+// the agent API, docker and the agent configuration files. The agent does not have to be running, the items that
+// cannot be collected are recorded in the manifest of the bundle.
+func Diag(bundleFile string) {
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	if bundleFile == "" {
+		bundleFile = fmt.Sprintf("node-diag-%v.tgz", time.Now().Format("20060102-150405"))
+	}
+	file, err := os.OpenFile(filepath.Clean(bundleFile), os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		cliutils.Fatal(cliutils.FILE_IO_ERROR, msgPrinter.Sprintf("Unable to create bundle file %v. %v", bundleFile, err))
+	}
+	defer file.Close()
+
+	b := diag.NewBundle(file, "hzn node diag")
+
+	// The credentials in the environment are secrets wherever they appear. The credential store is not unlocked for
+	// this, so that the command does not prompt for a passphrase.
+	for _, envVar := range []string{"HZN_EXCHANGE_NODE_AUTH", "HZN_EXCHANGE_USER_AUTH"} {
+		if auth := os.Getenv(envVar); auth != "" {
+			b.Redactor.AddSecrets(auth)
+			if i := strings.Index(auth, ":"); i >= 0 {
+				b.Redactor.AddSecrets(auth[i+1:])
+			}
+		}
+	}
+
+	// The service definitions are added first, because they mark the user inputs that are sensitive.
+	addAgentItem(b, diag.SERVICES_FILE, "service")
+	addAgentItem(b, diag.NODE_FILE, "node")
+	addAgentItem(b, diag.STATUS_FILE, "status")
+	addAgentItem(b, diag.AGREEMENTS_FILE, "agreement")
+	addAgentItem(b, diag.EVENTLOGS_FILE, "eventlog/all")
+	addAgentItem(b, diag.SURFACE_ERRORS_FILE, "eventlog/surface")
+	addAgentItem(b, diag.SERVICE_CONFIG_FILE, "service/config")
+	addAgentItem(b, diag.NODE_POLICY_FILE, "node/policy")
+	addAgentItem(b, diag.NODE_USERINPUT_FILE, "node/userinput")
+
+	diag.AddConfigFile(b, diag.ANAX_CONFIG_FILE, cliutils.ANAX_CONFIG_FILE)
+	diag.AddConfigFile(b, diag.HORIZON_DEFAULTS, cliutils.ANAX_OVERWRITE_FILE)
+
+	dockerEndpoint := DEFAULT_DOCKER_ENDPOINT
+	if cfg, err := config.Read(cliutils.ANAX_CONFIG_FILE); err != nil {
+		b.AddError(diag.ESS_STATUS_FILE, cliutils.ANAX_CONFIG_FILE, err)
+	} else {
+		if cfg.Edge.DockerEndpoint != "" {
+			dockerEndpoint = cfg.Edge.DockerEndpoint
+		}
+		b.AddJson(diag.ESS_STATUS_FILE, "ESS", diag.GetESSStatus(cfg))
+	}
+	diag.AddDockerContainers(b, dockerEndpoint)
+	diag.AddAgentLog(b, dockerEndpoint)
+
+	if err := b.Close(); err != nil {
+		cliutils.Fatal(cliutils.FILE_IO_ERROR, msgPrinter.Sprintf("Unable to write bundle file %v. %v", bundleFile, err))
+	}
+
+	collected, redacted := 0, 0
+	for _, entry := range b.Manifest().Entries {
+		if entry.Error != "" {
+			cliutils.Warning(msgPrinter.Sprintf("Unable to collect %v from %v: %v", entry.Name, entry.Source, entry.Error))
+			continue
+		}
+		collected++
+		redacted += entry.Redacted
+	}
+	msgPrinter.Printf("Node diagnostics written to %v: %v items collected, %v values redacted.", bundleFile, collected, redacted)
+	msgPrinter.Println()
+}
+
+// Add the output of an agent API to the bundle.
+func addAgentItem(b *diag.Bundle, name string, urlSuffix string) {
+	var out interface{}
+	source := "GET /" + urlSuffix
+	if _, err := cliutils.HorizonGet(urlSuffix, []int{200}, &out, true); err != nil {
+		b.AddError(name, source, err)
+	} else {
+		b.AddJson(name, source, out)
+	}
+}
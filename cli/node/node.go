@@ -1,7 +1,6 @@
 package node
 
 import (
-	"encoding/json"
 	"fmt"
 	"github.com/open-horizon/anax/api"
 	"github.com/open-horizon/anax/apicommon"
@@ -61,6 +60,20 @@ func (n *NodeAndStatus) CopyStatusInto(status *apicommon.Info) {
 	n.Configuration = status.Configuration
 }
 
+// The table output format of 'hzn node list'.
+var nodeTable = &cliutils.TableFormat{Columns: []cliutils.TableColumn{
+	{Header: "ID", Path: ".id"},
+	{Header: "ORG", Path: ".organization"},
+	{Header: "PATTERN", Path: ".pattern"},
+	{Header: "TYPE", Path: ".nodeType"},
+	{Header: "STATE", Path: ".configstate.state"},
+	{Header: "VERSION", Path: ".configuration.horizon_version"},
+	{Header: "NAME", Path: ".name", Wide: true},
+	{Header: "ARCH", Path: ".configuration.architecture", Wide: true},
+	{Header: "EXCHANGE", Path: ".configuration.exchange_api", Wide: true},
+	{Header: "LAST UPDATED", Path: ".configstate.last_update_time", Wide: true},
+}}
+
 func List() {
 	// get message printer
 	msgPrinter := i18n.GetMessagePrinter()
@@ -80,11 +93,7 @@ func List() {
 	nodeInfo.CopyStatusInto(&status)
 
 	// Output the combined info
-	jsonBytes, err := json.MarshalIndent(nodeInfo, "", cliutils.JSON_INDENT)
-	if err != nil {
-		cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'hzn node list' output: %v", err))
-	}
-	fmt.Printf("%s\n", jsonBytes) //todo: is there a way to output with json syntax highlighting like jq does?
+	cliutils.PrintOutput(nodeInfo, nodeTable, "node list")
 }
 
 func Version() {
@@ -114,7 +123,7 @@ func Architecture() {
 	fmt.Printf("%s\n", cutil.ArchString())
 }
 
-func Env(org, userPw, exchUrl, cssUrl string) {
+func Env(org, userPw, exchUrl, cssUrl, context string) {
 	// Show hzn Environment Variables
 	mask := "******"
 	msgPrinter := i18n.GetMessagePrinter()
@@ -134,4 +143,8 @@ func Env(org, userPw, exchUrl, cssUrl string) {
 	msgPrinter.Println()
 	msgPrinter.Printf("HZN_FSS_CSSURL: %s", cssUrl)
 	msgPrinter.Println()
+	if context != "" {
+		msgPrinter.Printf("Context: %s", context)
+		msgPrinter.Println()
+	}
 }
@@ -10,18 +10,20 @@ import (
 	"net/http"
 )
 
+// The table output format of 'hzn policy list', a row for each property.
+var nodePolicyTable = &cliutils.TableFormat{Rows: ".properties", Columns: []cliutils.TableColumn{
+	{Header: "PROPERTY", Path: ".name"},
+	{Header: "VALUE", Path: ".value"},
+	{Header: "TYPE", Path: ".type", Wide: true},
+}}
+
 func List() {
 	// Get the node policy info
 	nodePolicy := externalpolicy.ExternalPolicy{}
 	cliutils.HorizonGet("node/policy", []int{200}, &nodePolicy, false)
 
 	// Output the combined info
-	output, err := cliutils.DisplayAsJson(nodePolicy)
-	if err != nil {
-		cliutils.Fatal(cliutils.JSON_PARSING_ERROR, i18n.GetMessagePrinter().Sprintf("failed to marshal 'hzn policy list' output: %v", err))
-	}
-
-	fmt.Println(output)
+	cliutils.PrintOutput(nodePolicy, nodePolicyTable, "policy list")
 }
 
 func Update(fileName string) {
@@ -30,14 +32,13 @@ func Update(fileName string) {
 	ep := new(externalpolicy.ExternalPolicy)
 	readInputFile(fileName, ep)
 
-	readOnlyBuiltIns := externalpolicy.ListReadOnlyProperties()
 	includedBuiltIns := ""
-	for _, builtInProp := range readOnlyBuiltIns {
-		if ep.Properties.HasProperty(builtInProp) {
+	for _, prop := range ep.Properties {
+		if externalpolicy.IsReadOnlyProperty(prop.Name) {
 			if includedBuiltIns == "" {
-				includedBuiltIns = builtInProp
+				includedBuiltIns = prop.Name
 			} else {
-				includedBuiltIns = fmt.Sprintf("%s, %s", includedBuiltIns, builtInProp)
+				includedBuiltIns = fmt.Sprintf("%s, %s", includedBuiltIns, prop.Name)
 			}
 		}
 	}
@@ -2,7 +2,6 @@ package service
 
 import (
 	"encoding/json"
-	"fmt"
 	"github.com/open-horizon/anax/api"
 	"github.com/open-horizon/anax/apicommon"
 	"github.com/open-horizon/anax/cli/cliutils"
@@ -31,6 +30,30 @@ type OurService struct {
 	Variables map[string]interface{} `json:"variables"`
 }
 
+// The table output formats of the 'hzn service' list commands.
+var serviceTable = &cliutils.TableFormat{Columns: []cliutils.TableColumn{
+	{Header: "URL", Path: ".url"},
+	{Header: "ORG", Path: ".org"},
+	{Header: "VERSION", Path: ".version"},
+	{Header: "ARCH", Path: ".arch"},
+	{Header: "VARIABLES", Path: ".variables", Wide: true},
+}}
+
+var registeredServiceTable = &cliutils.TableFormat{Columns: []cliutils.TableColumn{
+	{Header: "NAME", Path: ".header.name"},
+	{Header: "URL", Path: ".apiSpec[*].specRef"},
+	{Header: "ORG", Path: ".apiSpec[*].organization"},
+	{Header: "VERSION", Path: ".apiSpec[*].version"},
+	{Header: "ARCH", Path: ".apiSpec[*].arch", Wide: true},
+	{Header: "FILE"},
+}}
+
+var serviceConfigStateTable = &cliutils.TableFormat{Rows: ".configstates", Columns: []cliutils.TableColumn{
+	{Header: "URL", Path: ".url"},
+	{Header: "ORG", Path: ".org"},
+	{Header: "STATE", Path: ".configState"},
+}}
+
 func List() {
 	// get message printer
 	msgPrinter := i18n.GetMessagePrinter()
@@ -73,11 +96,7 @@ func List() {
 	}
 
 	// Convert to json and output
-	jsonBytes, err := json.MarshalIndent(services, "", cliutils.JSON_INDENT)
-	if err != nil {
-		cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'hzn service list' output: %v", err))
-	}
-	fmt.Printf("%s\n", jsonBytes)
+	cliutils.PrintOutput(services, serviceTable, "service list")
 }
 
 func Log(serviceName string, tailing bool) {
@@ -166,11 +185,7 @@ func Registered() {
 	}
 
 	// Convert to json and output
-	jsonBytes, err := json.MarshalIndent(apiOutput, "", cliutils.JSON_INDENT)
-	if err != nil {
-		cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'hzn service registered' output: %v", err))
-	}
-	fmt.Printf("%s\n", jsonBytes)
+	cliutils.PrintOutput(apiOutput, registeredServiceTable, "service registered")
 }
 
 func ListConfigState() {
@@ -184,11 +199,7 @@ func ListConfigState() {
 	}
 
 	// Convert to json and output
-	jsonBytes, err := json.MarshalIndent(apiOutput, "", cliutils.JSON_INDENT)
-	if err != nil {
-		cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'hzn service configstate' output: %v", err))
-	}
-	fmt.Printf("%s\n", jsonBytes)
+	cliutils.PrintOutput(apiOutput, serviceConfigStateTable, "service configstate")
 }
 
 func Suspend(forceSuspend bool, applyAll bool, serviceOrg string, serviceUrl string) {
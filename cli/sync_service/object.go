@@ -27,6 +27,17 @@ type MMSObjectInfo struct {
 	ObjectStatus string                      `json:"objectStatus,omitempty"`
 }
 
+// The table output format of 'hzn mms object list'. The object id and type are in the definition when the details
+// of the objects are displayed.
+var mmsObjectTable = &cliutils.TableFormat{Columns: []cliutils.TableColumn{
+	{Header: "OBJECT ID", Path: "{.objectID}{.definition.objectID}"},
+	{Header: "OBJECT TYPE", Path: "{.objectType}{.definition.objectType}"},
+	{Header: "DEST TYPE", Path: "{.destinationType}{.definition.destinationType}", Wide: true},
+	{Header: "DEST ID", Path: "{.destinationID}{.definition.destinationID}", Wide: true},
+	{Header: "VERSION", Path: "{.version}{.definition.version}", Wide: true},
+	{Header: "STATUS", Path: ".objectStatus", Wide: true},
+}}
+
 // Display the object metadata for given flags in the MMS.
 func ObjectList(org string, userPw string, objType string, objId string, destPolicy string, dpService string, dpPropertyName string, dpUpdateTimeSince string, destType string, destId string, withData string, expirationTimeBefore string, long bool, details bool) {
 	// get message printer
@@ -118,7 +129,7 @@ func ObjectList(org string, userPw string, objType string, objId string, destPol
 		cliutils.Fatal(cliutils.NOT_FOUND, msgPrinter.Sprintf("no objects found in org %s", org))
 	}
 
-	output := ""
+	var output interface{}
 
 	if details {
 		// Cut the objectsMeta into batches of size 50. For each batch, process the API call concurrently. Use batches strategy to 1) reduce the processing time, 2) avoid overwhelming API calls sent to CSS server at one time
@@ -182,7 +193,7 @@ func ObjectList(org string, userPw string, objType string, objId string, destPol
 			}
 		}
 
-		output = cliutils.MarshalIndent(mmsObjects, "mms object list")
+		output = mmsObjects
 	} else {
 		if !long {
 			mmsObjects := make([]MMSObjectInfo, 0)
@@ -193,19 +204,18 @@ func ObjectList(org string, userPw string, objType string, objId string, destPol
 				}
 				mmsObjects = append(mmsObjects, mmsObjectInfo)
 			}
-			output = cliutils.MarshalIndent(mmsObjects, "mms object list")
+			output = mmsObjects
 		} else {
-			var err1 error
-			output, err1 = cliutils.DisplayAsJson(objectsMeta)
-			if err1 != nil {
-				cliutils.Fatal(cliutils.JSON_PARSING_ERROR, i18n.GetMessagePrinter().Sprintf("failed to marshal 'hzn mms object list' output: %v", err1))
-			}
+			output = objectsMeta
 		}
 	}
 
-	msgPrinter.Printf("Listing objects in org %v:", org)
-	msgPrinter.Println()
-	fmt.Println(output)
+	// the heading is only displayed with the default output format, so that the other formats can be parsed
+	if cliutils.GetOutputFormat() == cliutils.OUTPUT_JSON {
+		msgPrinter.Printf("Listing objects in org %v:", org)
+		msgPrinter.Println()
+	}
+	cliutils.PrintOutput(output, mmsObjectTable, "mms object list")
 }
 
 func ObjectNew(org string) {
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"fmt"
+	"path"
+)
+
+// The roles of the local agent API.
+const (
+	APIRoleNone     = "none"     // No access.
+	APIRoleReadOnly = "readonly" // Only the routes that read the node's state.
+	APIRoleAdmin    = "admin"    // Every route.
+)
+
+// The names of the token files in the token directory.
+const (
+	APIAdminTokenFile    = "admin.token"
+	APIReadOnlyTokenFile = "readonly.token"
+)
+
+// Configuration for the authentication of the local agent API. When it is not enabled, the API is served without
+// authentication on APIListen, which is the historical behavior.
+type APIAuthConfig struct {
+	Enabled       bool     // When true, every request to the local agent API must be authorized for the route.
+	UnixSocket    string   // When set, the API is also served on this unix domain socket, where the caller is identified by its peer credentials.
+	AdminUIDs     []uint32 // The uids of the socket peers that have the admin role. Root always has the admin role.
+	AdminGIDs     []uint32 // The gids of the socket peers that have the admin role.
+	PeerRole      string   // The role of the other socket peers. The default is readonly.
+	TokenDir      string   // The directory of the admin.token and readonly.token files. The tokens are generated when the files do not exist.
+	TokenGroup    string   // When set, a generated readonly.token file is owned by this group and its members can read it. Otherwise only root can read it.
+	AnonymousRole string   // The role of requests without credentials on APIListen. The default is none.
+}
+
+func (a *APIAuthConfig) String() string {
+	return fmt.Sprintf("Enabled: %v, UnixSocket: %v, AdminUIDs: %v, AdminGIDs: %v, PeerRole: %v, TokenDir: %v, TokenGroup: %v, AnonymousRole: %v", a.Enabled, a.UnixSocket, a.AdminUIDs, a.AdminGIDs, a.PeerRole, a.TokenDir, a.TokenGroup, a.AnonymousRole)
+}
+
+// Returns the path of the file that holds the token for the given role.
+func (a *APIAuthConfig) GetTokenFile(role string) string {
+	if role == APIRoleAdmin {
+		return path.Join(a.TokenDir, APIAdminTokenFile)
+	}
+	return path.Join(a.TokenDir, APIReadOnlyTokenFile)
+}
+
+// Returns true if the role is one of the API roles.
+func IsValidAPIRole(role string) bool {
+	return role == APIRoleNone || role == APIRoleReadOnly || role == APIRoleAdmin
+}
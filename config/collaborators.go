@@ -47,8 +47,9 @@ func (c *Collaborators) String() string {
 
 type HTTPClientFactory struct {
 	NewHTTPClient func(overrideTimeoutS *uint) *http.Client
-	RetryCount    int // number of retries for tranport error.
-	RetryInterval int // retry interval in second for tranport error. The default is 10 seconds.
+	RetryCount    int         // number of retries for tranport error.
+	RetryInterval int         // retry interval in second for tranport error. The default is 10 seconds.
+	TLSConfig     *tls.Config // The TLS configuration of the clients, so that other protocols trust the same certificates.
 }
 
 // default retry interval is 10 seconds
@@ -185,6 +186,7 @@ func newHTTPClientFactory(hConfig HorizonConfig) (*HTTPClientFactory, error) {
 		NewHTTPClient: clientFunc,
 		RetryCount:    0,
 		RetryInterval: 10,
+		TLSConfig:     &tlsConf,
 	}, nil
 }
 
@@ -17,10 +17,14 @@ const ManagementHubCertPath = "HZN_MGMT_HUB_CERT_PATH"
 const AnaxAPIPort = "HZN_AGENT_PORT"
 
 type HorizonConfig struct {
-	Edge          Config
-	AgreementBot  AGConfig
-	Collaborators Collaborators
-	ArchSynonyms  ArchSynonyms
+	Edge               Config
+	AgreementBot       AGConfig
+	Collaborators      Collaborators
+	ArchSynonyms       ArchSynonyms
+	Tracing            TracingConfig
+	Webhooks           WebhookConfig
+	MQTT               MQTTConfig
+	MessageKeyRotation MessageKeyRotationConfig
 }
 
 // This is the configuration options for Edge component flavor of Anax
@@ -39,34 +43,40 @@ type Config struct {
 	ExchangeURL                      string
 	DefaultHTTPClientTimeoutS        uint
 	PolicyPath                       string
-	ExchangeHeartbeat                int       // Seconds between heartbeats
-	ExchangeVersionCheckIntervalM    int64     // Exchange version check interval in minutes. The default is 720. This is now deprecated with the usage of /changes API which returns exchange version on every call.
-	AgreementTimeoutS                uint64    // Number of seconds to wait before declaring agreement not finalized in blockchain
-	AgreementTimeoutScaleFactor      float64   // Time to wait before declaring an agreement did not finalize. Expressed as a scaling factor of the max heartbeat interval for this node
-	DVPrefix                         string    // When passing agreement ids into a workload container, add this prefix to the agreement id
-	RegistrationDelayS               uint64    // The number of seconds to wait after blockchain init before registering with the exchange. This is for testing initialization ONLY.
-	ExchangeMessageTTL               int       // The number of seconds the exchange will keep this message before automatically deleting it
-	ExchangeMessageDynamicPoll       bool      // Will the runtime dynamically increase the message poll interval? Default is true. Set to false to turn off dynamic message poll interval adjustments.
-	ExchangeMessagePollInterval      int       // The number of seconds the node will wait between polls to the exchange. This is the starting value, but at runtime this interval will increase if there is no message activity to reduce load on the exchange. If ExchangeMessageDynamicPoll is false, then the value of this field will never be changed by the runtime.
-	ExchangeMessagePollMaxInterval   int       // As the runtime increases the ExchangeMessagePollInterval, this value is the maximum that value can attain.
-	ExchangeMessagePollIncrement     int       // The number of seconds to increment the ExchangeMessagePollInterval when its time to increase the poll interval.
-	UserPublicKeyPath                string    // The location to store user keys uploaded through the REST API
-	ReportDeviceStatus               bool      // whether to report the device status to the exchange or not.
-	TrustCertUpdatesFromOrg          bool      // whether to trust the certs provided by the organization on the exchange or not.
-	TrustDockerAuthFromOrg           bool      // whether to turst the docker auths provided by the organization on the exchange or not.
-	ServiceUpgradeCheckIntervalS     int64     // service upgrade check interval in seconds. The default is 300 seconds.
-	MultipleAnaxInstances            bool      // multiple anax instances running on the same machine
-	DefaultServiceRetryCount         int       // the default service retry count if retries are not specified by the policy file. The default value is 2.
-	DefaultServiceRetryDuration      uint64    // the default retry duration in seconds. The next retry cycle occurs after the duration. The default value is 600
-	DefaultNodePolicyFile            string    // the default node policy file name.
-	NodeCheckIntervalS               int       // the node check interval. The default is 15 seconds.
-	NodePolicyCheckIntervalS         int       // the node policy check interval. The default is 15 seconds.
-	FileSyncService                  FSSConfig // The config for the embedded ESS sync service.
-	SurfaceErrorTimeoutS             int       // How long surfaced errors will remain active after they're created. Default is no timeout
-	SurfaceErrorCheckIntervalS       int       // Deprecated. Used to be how often the node will check for errors that are no longer active and update the exchange. Default is 15 seconds
-	SurfaceErrorAgreementPersistentS int       // How long an agreement needs to persist before it is considered persistent and the related errors are dismisse. Default is 90 seconds
-	InitialPollingBuffer             int       // the number of seconds to wait before increasing the polling interval while there is no agreement on the node.
-	MaxAgreementPrelaunchTimeM       int64     // The maximum numbers of minutes to wait for workload to start in an agreement
+	ExchangeHeartbeat                int                 // Seconds between heartbeats
+	ExchangeVersionCheckIntervalM    int64               // Exchange version check interval in minutes. The default is 720. This is now deprecated with the usage of /changes API which returns exchange version on every call.
+	AgreementTimeoutS                uint64              // Number of seconds to wait before declaring agreement not finalized in blockchain
+	AgreementTimeoutScaleFactor      float64             // Time to wait before declaring an agreement did not finalize. Expressed as a scaling factor of the max heartbeat interval for this node
+	DVPrefix                         string              // When passing agreement ids into a workload container, add this prefix to the agreement id
+	RegistrationDelayS               uint64              // The number of seconds to wait after blockchain init before registering with the exchange. This is for testing initialization ONLY.
+	ExchangeMessageTTL               int                 // The number of seconds the exchange will keep this message before automatically deleting it
+	ExchangeMessageDynamicPoll       bool                // Will the runtime dynamically increase the message poll interval? Default is true. Set to false to turn off dynamic message poll interval adjustments.
+	ExchangeMessagePollInterval      int                 // The number of seconds the node will wait between polls to the exchange. This is the starting value, but at runtime this interval will increase if there is no message activity to reduce load on the exchange. If ExchangeMessageDynamicPoll is false, then the value of this field will never be changed by the runtime.
+	ExchangeMessagePollMaxInterval   int                 // As the runtime increases the ExchangeMessagePollInterval, this value is the maximum that value can attain.
+	ExchangeMessagePollIncrement     int                 // The number of seconds to increment the ExchangeMessagePollInterval when its time to increase the poll interval.
+	UserPublicKeyPath                string              // The location to store user keys uploaded through the REST API
+	ReportDeviceStatus               bool                // whether to report the device status to the exchange or not.
+	TrustCertUpdatesFromOrg          bool                // whether to trust the certs provided by the organization on the exchange or not.
+	TrustDockerAuthFromOrg           bool                // whether to turst the docker auths provided by the organization on the exchange or not.
+	ServiceUpgradeCheckIntervalS     int64               // service upgrade check interval in seconds. The default is 300 seconds.
+	MultipleAnaxInstances            bool                // multiple anax instances running on the same machine
+	DefaultServiceRetryCount         int                 // the default service retry count if retries are not specified by the policy file. The default value is 2.
+	DefaultServiceRetryDuration      uint64              // the default retry duration in seconds. The next retry cycle occurs after the duration. The default value is 600
+	DefaultNodePolicyFile            string              // the default node policy file name.
+	NodeCheckIntervalS               int                 // the node check interval. The default is 15 seconds.
+	NodePolicyCheckIntervalS         int                 // the node policy check interval. The default is 15 seconds.
+	FileSyncService                  FSSConfig           // The config for the embedded ESS sync service.
+	EventLog                         EventLogConfig      // The config for the event log retention, compaction and export.
+	APIAuth                          APIAuthConfig       // The config for the authentication of the local agent API.
+	NodeDiscovery                    NodeDiscoveryConfig // The config for the node property discovery providers.
+	WorkloadAPI                      WorkloadAPIConfig   // The config for the API where services report their health and status.
+	SurfaceErrorTimeoutS             int                 // How long surfaced errors will remain active after they're created. Default is no timeout
+	SurfaceErrorCheckIntervalS       int                 // Deprecated. Used to be how often the node will check for errors that are no longer active and update the exchange. Default is 15 seconds
+	SurfaceErrorAgreementPersistentS int                 // How long an agreement needs to persist before it is considered persistent and the related errors are dismisse. Default is 90 seconds
+	InitialPollingBuffer             int                 // the number of seconds to wait before increasing the polling interval while there is no agreement on the node.
+	MaxAgreementPrelaunchTimeM       int64               // The maximum numbers of minutes to wait for workload to start in an agreement
+	PersistExchangeCache             bool                // Keep a copy of the exchange resource cache in the local DB so that it survives agent restarts and exchange outages. Default is false.
+	ExchangeCacheTTLS                uint64              // The number of seconds a persisted exchange cache entry is trusted after an agent restart or when the exchange is unreachable. The default is 86400 seconds.
 
 	// these Ids could be provided in config or discovered after startup by the system
 	BlockchainAccountId        string
@@ -119,6 +129,7 @@ type AGConfig struct {
 	MaxExchangeChanges            int              // The maximum number of exchange changes to request on a given call the exchange /changes API.
 	RetryLookBackWindow           uint64           // The time window (in seconds) used by the agbot to look backward in time for node changes when node agreements are retried.
 	PolicySearchOrder             bool             // When true, search policies from most recently changed to least recently changed.
+	SearchShards                  int              // The number of shards that the served policies and patterns are split into, so that clustered agbots each search for nodes for a part of them. Zero turns sharding off.
 }
 
 func (c *HorizonConfig) UserPublicKeyPath() string {
@@ -177,6 +188,13 @@ func (c *HorizonConfig) GetAgbotPolicyOrder() bool {
 	return c.AgreementBot.PolicySearchOrder
 }
 
+func (c *HorizonConfig) GetAgbotSearchShards() int {
+	if c.AgreementBot.SearchShards < 0 {
+		return 0
+	}
+	return c.AgreementBot.SearchShards
+}
+
 func (a *AGConfig) GetProtocolTimeout(maxHeartbeatInterval int) uint64 {
 	if a.ProtocolTimeoutS != 0 {
 		return a.ProtocolTimeoutS
@@ -310,6 +328,7 @@ func Read(file string) (*HorizonConfig, error) {
 				MaxExchangeChanges:  AgbotMaxChanges_DEFAULT,
 				RetryLookBackWindow: AgbotRetryLookBackWindow_DEFAULT,
 				PolicySearchOrder:   AgbotPolicySearchOrder_DEFAULT,
+				SearchShards:        AgbotSearchShards_DEFAULT,
 			},
 		}
 
@@ -360,6 +379,53 @@ func Read(file string) (*HorizonConfig, error) {
 			config.Edge.InitialPollingBuffer = 120
 		}
 
+		// set the event log defaults
+		if config.Edge.EventLog.CompactIntervalS == 0 {
+			config.Edge.EventLog.CompactIntervalS = EventLogCompactIntervalS_DEFAULT
+		}
+		if config.Edge.EventLog.ExportFileMaxSizeMB == 0 {
+			config.Edge.EventLog.ExportFileMaxSizeMB = EventLogExportFileMaxSizeMB_DEFAULT
+		}
+		if config.Edge.EventLog.ExportFileBackups == 0 {
+			config.Edge.EventLog.ExportFileBackups = EventLogExportFileBackups_DEFAULT
+		}
+		if config.Edge.EventLog.ExportSyslogTag == "" {
+			config.Edge.EventLog.ExportSyslogTag = EventLogExportSyslogTag_DEFAULT
+		}
+
+		// set the node property discovery defaults
+		if config.Edge.NodeDiscovery.IntervalS == 0 {
+			config.Edge.NodeDiscovery.IntervalS = NodeDiscoveryIntervalS_DEFAULT
+		}
+		if config.Edge.NodeDiscovery.DiskPath == "" {
+			config.Edge.NodeDiscovery.DiskPath = NodeDiscoveryDiskPath_DEFAULT
+		}
+		if config.Edge.NodeDiscovery.ExecDir == "" {
+			config.Edge.NodeDiscovery.ExecDir = NodeDiscoveryExecDir_DEFAULT
+		}
+		if config.Edge.NodeDiscovery.ExecTimeoutS == 0 {
+			config.Edge.NodeDiscovery.ExecTimeoutS = NodeDiscoveryExecTimeoutS_DEFAULT
+		}
+
+		// set the local API authentication defaults
+		if config.Edge.APIAuth.PeerRole == "" {
+			config.Edge.APIAuth.PeerRole = APIRoleReadOnly
+		}
+		if config.Edge.APIAuth.AnonymousRole == "" {
+			config.Edge.APIAuth.AnonymousRole = APIRoleNone
+		}
+		if config.Edge.APIAuth.TokenDir == "" {
+			config.Edge.APIAuth.TokenDir = filepath.Join(getDefaultBase(), APIAuthTokenPath_DEFAULT)
+		}
+		if !IsValidAPIRole(config.Edge.APIAuth.PeerRole) || !IsValidAPIRole(config.Edge.APIAuth.AnonymousRole) {
+			return nil, fmt.Errorf("APIAuth PeerRole %v and AnonymousRole %v must be %v, %v or %v", config.Edge.APIAuth.PeerRole, config.Edge.APIAuth.AnonymousRole, APIRoleNone, APIRoleReadOnly, APIRoleAdmin)
+		}
+
+		// default ExchangeCacheTTLS
+		if config.Edge.ExchangeCacheTTLS == 0 {
+			config.Edge.ExchangeCacheTTLS = ExchangeCacheTTLS_DEFAULT
+		}
+
 		// add a slash at the back of the ExchangeUrl
 		if config.Edge.ExchangeURL != "" {
 			config.Edge.ExchangeURL = strings.TrimRight(config.Edge.ExchangeURL, "/") + "/"
@@ -393,13 +459,60 @@ func Read(file string) (*HorizonConfig, error) {
 			config.AgreementBot.MMSGarbageCollectionInterval = 300
 		}
 
+		// set the tracing defaults
+		if config.Tracing.SampleRatio <= 0 || config.Tracing.SampleRatio > 1 {
+			config.Tracing.SampleRatio = TracingSampleRatio_DEFAULT
+		}
+		if config.Tracing.BatchIntervalS == 0 {
+			config.Tracing.BatchIntervalS = TracingBatchIntervalS_DEFAULT
+		}
+
+		// set the webhook defaults
+		if config.Webhooks.FailureThreshold <= 0 {
+			config.Webhooks.FailureThreshold = WebhookFailureThreshold_DEFAULT
+		}
+		if config.Webhooks.MaxAttempts <= 0 {
+			config.Webhooks.MaxAttempts = WebhookMaxAttempts_DEFAULT
+		}
+		if config.Webhooks.RetryBackoffS <= 0 {
+			config.Webhooks.RetryBackoffS = WebhookRetryBackoffS_DEFAULT
+		}
+		if config.Webhooks.MaxRetryBackoffS <= 0 {
+			config.Webhooks.MaxRetryBackoffS = WebhookMaxRetryBackoffS_DEFAULT
+		}
+		if config.Webhooks.TimeoutS <= 0 {
+			config.Webhooks.TimeoutS = WebhookTimeoutS_DEFAULT
+		}
+		if config.Webhooks.DeliveryIntervalS <= 0 {
+			config.Webhooks.DeliveryIntervalS = WebhookDeliveryIntervalS_DEFAULT
+		}
+
+		// set the MQTT defaults
+		if config.MQTT.TopicPrefix == "" {
+			config.MQTT.TopicPrefix = MQTTTopicPrefix_DEFAULT
+		}
+		if config.MQTT.KeepAliveS <= 0 {
+			config.MQTT.KeepAliveS = MQTTKeepAliveS_DEFAULT
+		}
+		if config.MQTT.ConnectTimeoutS <= 0 {
+			config.MQTT.ConnectTimeoutS = MQTTConnectTimeoutS_DEFAULT
+		}
+		if config.MQTT.PublishTimeoutS <= 0 {
+			config.MQTT.PublishTimeoutS = MQTTPublishTimeoutS_DEFAULT
+		}
+
+		// set the messaging key rotation defaults
+		if config.MessageKeyRotation.GraceS <= 0 {
+			config.MessageKeyRotation.GraceS = MessageKeyGraceS_DEFAULT
+		}
+
 		// success at last!
 		return &config, nil
 	}
 }
 
 func (c *HorizonConfig) String() string {
-	return fmt.Sprintf("Edge: {%v}, AgreementBot: {%v}, Collaborators: {%v}, ArchSynonyms: {%v}", c.Edge.String(), c.AgreementBot.String(), c.Collaborators.String(), c.ArchSynonyms)
+	return fmt.Sprintf("Edge: {%v}, AgreementBot: {%v}, Collaborators: {%v}, ArchSynonyms: {%v}, Tracing: {%v}, Webhooks: {%v}, MQTT: {%v}, MessageKeyRotation: {%v}", c.Edge.String(), c.AgreementBot.String(), c.Collaborators.String(), c.ArchSynonyms, c.Tracing.String(), c.Webhooks.String(), c.MQTT.String(), c.MessageKeyRotation.String())
 }
 
 func (con *Config) String() string {
@@ -436,7 +549,13 @@ func (con *Config) String() string {
 		", DefaultServiceRetryDuration: %v"+
 		", NodeCheckIntervalS: %v"+
 		", FileSyncService: {%v}"+
+		", EventLog: {%v}"+
+		", APIAuth: {%v}"+
+		", NodeDiscovery: {%v}"+
+		", WorkloadAPI: {%v}"+
 		", InitialPollingBuffer: {%v}"+
+		", PersistExchangeCache: %v"+
+		", ExchangeCacheTTLS: %v"+
 		", BlockchainAccountId: %v"+
 		", BlockchainDirectoryAddress %v",
 		con.ServiceStorage, con.APIListen, con.DBPath, con.DockerEndpoint, con.DockerCredFilePath, con.DefaultCPUSet,
@@ -445,8 +564,8 @@ func (con *Config) String() string {
 		con.DVPrefix, con.RegistrationDelayS, con.ExchangeMessageTTL, con.ExchangeMessageDynamicPoll, con.ExchangeMessagePollInterval,
 		con.ExchangeMessagePollMaxInterval, con.ExchangeMessagePollIncrement, con.UserPublicKeyPath, con.ReportDeviceStatus,
 		con.TrustCertUpdatesFromOrg, con.TrustDockerAuthFromOrg, con.ServiceUpgradeCheckIntervalS, con.MultipleAnaxInstances,
-		con.DefaultServiceRetryCount, con.DefaultServiceRetryDuration, con.NodeCheckIntervalS, con.FileSyncService.String(),
-		con.InitialPollingBuffer, con.BlockchainAccountId, con.BlockchainDirectoryAddress)
+		con.DefaultServiceRetryCount, con.DefaultServiceRetryDuration, con.NodeCheckIntervalS, con.FileSyncService.String(), con.EventLog.String(), con.APIAuth.String(), con.NodeDiscovery.String(), con.WorkloadAPI.String(),
+		con.InitialPollingBuffer, con.PersistExchangeCache, con.ExchangeCacheTTLS, con.BlockchainAccountId, con.BlockchainDirectoryAddress)
 }
 
 func (agc *AGConfig) String() string {
@@ -484,12 +603,13 @@ func (agc *AGConfig) String() string {
 		", CheckUpdatedPolicyS: %v"+
 		", CSSURL: %v"+
 		", CSSSSLCert: %v"+
-		", AgreementBatchSize: %v",
+		", AgreementBatchSize: %v"+
+		", SearchShards: %v",
 		agc.TxLostDelayTolerationSeconds, agc.AgreementWorkers, agc.DBPath, agc.Postgresql.String(),
 		agc.PartitionStale, agc.ProtocolTimeoutS, agc.AgreementTimeoutS, agc.NoDataIntervalS, agc.ActiveAgreementsURL,
 		agc.ActiveAgreementsUser, mask, agc.PolicyPath, agc.NewContractIntervalS, agc.ProcessGovernanceIntervalS,
 		agc.IgnoreContractWithAttribs, agc.ExchangeURL, agc.ExchangeHeartbeat, agc.ExchangeId,
 		mask, agc.DVPrefix, agc.ActiveDeviceTimeoutS, agc.ExchangeMessageTTL, agc.MessageKeyPath, mask, agc.APIListen,
 		agc.SecureAPIListenHost, agc.SecureAPIListenPort, agc.SecureAPIServerCert, agc.SecureAPIServerKey,
-		agc.PurgeArchivedAgreementHours, agc.CheckUpdatedPolicyS, agc.CSSURL, agc.CSSSSLCert, agc.AgreementBatchSize)
+		agc.PurgeArchivedAgreementHours, agc.CheckUpdatedPolicyS, agc.CSSURL, agc.CSSSSLCert, agc.AgreementBatchSize, agc.SearchShards)
 }
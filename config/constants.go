@@ -57,6 +57,23 @@ const HZN_FSS_CERT_KEY_FILE = "key.pem"
 // The number of seconds between polls to the CSS for updates.
 const HZN_FSS_POLLING_RATE = 60
 
+// The name of the workload API unix domain socket. It is in the same directory as the FSS unix domain socket.
+const HZN_WORKLOAD_API_DOMAIN_SOCKET = "workloadapi.sock"
+
+// The default port of the workload API when the FSS is listening over https.
+const HZN_WORKLOAD_API_LISTEN_PORT_DEFAULT = 8444
+
+// The default maximum size of a workload status report.
+const HZN_WORKLOAD_API_MAX_REPORT_BYTES_DEFAULT = 16384
+
+// The default quotas of the key-value store of each service in the workload API.
+const HZN_WORKLOAD_API_KV_MAX_KEYS_DEFAULT = 256
+const HZN_WORKLOAD_API_KV_MAX_VALUE_BYTES_DEFAULT = 65536
+const HZN_WORKLOAD_API_KV_MAX_SERVICE_BYTES_DEFAULT = 1048576
+
+// The maximum length of a key in the key-value store of the workload API.
+const HZN_WORKLOAD_API_KV_MAX_KEY_LENGTH = 256
+
 // The Default starting exchange message polling interval.
 const ExchangeMessagePollInterval_DEFAULT = 20
 
@@ -69,6 +86,75 @@ const ExchangeMessagePollIncrement_DEFAULT = 20
 // The maximum numbers of minutes to wait for workload to start in an agreement
 const EdgeMaxAgreementPrelaunchTimeM_DEFAULT = 10
 
+// The default number of seconds a persisted exchange cache entry is trusted after an agent restart.
+const ExchangeCacheTTLS_DEFAULT = 86400
+
+// The default ratio of the agreements and searches that are traced.
+const TracingSampleRatio_DEFAULT = 1.0
+
+// The default number of seconds between span exports.
+const TracingBatchIntervalS_DEFAULT = 5
+
+// The default number of agreements in a row that fail before the agbot notifies the webhooks.
+const WebhookFailureThreshold_DEFAULT = 3
+
+// The default number of times a webhook notification is attempted.
+const WebhookMaxAttempts_DEFAULT = 10
+
+// The default number of seconds before the first retry of a webhook notification.
+const WebhookRetryBackoffS_DEFAULT = 5
+
+// The default longest time between retries of a webhook notification.
+const WebhookMaxRetryBackoffS_DEFAULT = 600
+
+// The default number of seconds a webhook endpoint has to respond.
+const WebhookTimeoutS_DEFAULT = 10
+
+// The default number of seconds between deliveries of webhook notifications.
+const WebhookDeliveryIntervalS_DEFAULT = 5
+
+// The default prefix of the MQTT topics of the nodes and agbots.
+const MQTTTopicPrefix_DEFAULT = "horizon"
+
+// The default number of seconds between keep alive pings to the MQTT broker.
+const MQTTKeepAliveS_DEFAULT = 30
+
+// The default number of seconds to wait for the MQTT broker to accept a connection.
+const MQTTConnectTimeoutS_DEFAULT = 10
+
+// The default number of seconds to wait for the MQTT broker to acknowledge a message.
+const MQTTPublishTimeoutS_DEFAULT = 5
+
+// The default number of seconds that the old messaging key can still decrypt messages after a rotation.
+const MessageKeyGraceS_DEFAULT = 3600
+
+// The default number of seconds between node property discoveries.
+const NodeDiscoveryIntervalS_DEFAULT = 300
+
+// The default file system whose capacity is discovered.
+const NodeDiscoveryDiskPath_DEFAULT = "/"
+
+// The default directory of the node property discovery executables.
+const NodeDiscoveryExecDir_DEFAULT = "/etc/horizon/discovery.d"
+
+// The default number of seconds that a node property discovery executable can run.
+const NodeDiscoveryExecTimeoutS_DEFAULT = 10
+
+// The default relative path of the local agent API token files. This path should be combined with the HZN_VAR_BASE_DEFAULT.
+const APIAuthTokenPath_DEFAULT = "api"
+
+// The default number of seconds between event log compactions.
+const EventLogCompactIntervalS_DEFAULT = 3600
+
+// The default size of the event log export file at which it is rotated.
+const EventLogExportFileMaxSizeMB_DEFAULT = 10
+
+// The default number of rotated event log export files that are kept.
+const EventLogExportFileBackups_DEFAULT = 3
+
+// The default tag of the exported event log syslog records.
+const EventLogExportSyslogTag_DEFAULT = "anax"
+
 // The Default interval at which the agbot verifies that its message key is present in the exchange.
 const AgbotMessageKeyCheck_DEFAULT = 60
 
@@ -93,6 +179,10 @@ const AgbotRetryLookBackWindow_DEFAULT = 3600
 // Policy search order
 const AgbotPolicySearchOrder_DEFAULT = true
 
+// The default number of node search shards that clustered agbots split the served policies and patterns into. Sharding
+// is off unless it is configured, so that existing agbot clusters keep searching for all the policies.
+const AgbotSearchShards_DEFAULT = 0
+
 // Scale factor of node max hb interval to wait before declaring an a agreement for that node did not finalize
 const AgreementTimeoutScaleFactor_DEFAULT = 2
 
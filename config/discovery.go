@@ -0,0 +1,33 @@
+package config
+
+import (
+	"fmt"
+)
+
+// The names of the built-in node property discovery providers.
+const (
+	DiscoveryProviderUSB              = "usb"              // USB and serial devices from sysfs.
+	DiscoveryProviderDisk             = "disk"             // The capacity of the disk.
+	DiscoveryProviderOS               = "os"               // The OS name, version and kernel version.
+	DiscoveryProviderContainerRuntime = "containerRuntime" // The container runtime name and version.
+	DiscoveryProviderExec             = "exec"             // The executables in the exec directory.
+)
+
+// Configuration for the node property discovery providers. The discovered properties are added to the read-only
+// built-in properties of the node policy.
+type NodeDiscoveryConfig struct {
+	Providers    []string // The names of the providers to run. Discovery is off when there are none.
+	IntervalS    int      // The number of seconds between discoveries. The default is 300 seconds.
+	DiskPath     string   // The file system whose capacity the disk provider discovers. The default is "/".
+	ExecDir      string   // The directory of the executables that the exec provider runs. The default is /etc/horizon/discovery.d.
+	ExecTimeoutS int      // The number of seconds that an executable can run. The default is 10 seconds.
+}
+
+func (d *NodeDiscoveryConfig) String() string {
+	return fmt.Sprintf("Providers: %v, IntervalS: %v, DiskPath: %v, ExecDir: %v, ExecTimeoutS: %v", d.Providers, d.IntervalS, d.DiskPath, d.ExecDir, d.ExecTimeoutS)
+}
+
+// Returns true if at least one provider is configured.
+func (d *NodeDiscoveryConfig) IsEnabled() bool {
+	return len(d.Providers) != 0
+}
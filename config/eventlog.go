@@ -0,0 +1,44 @@
+package config
+
+import (
+	"fmt"
+)
+
+// The retention rule for the event logs of one severity.
+type EventLogRetention struct {
+	MaxAgeS  uint64 // Event logs older than this number of seconds are removed. Zero means there is no age limit.
+	MaxCount int    // Only this number of the most recent event logs are kept. Zero means there is no count limit.
+}
+
+// Configuration for the retention, compaction and export of the node's event logs.
+type EventLogConfig struct {
+	Retention           map[string]EventLogRetention // The retention rules keyed by severity (info, warning, error or fatal). The "*" rule applies to the severities without a rule. Event logs without a rule are kept forever.
+	CompactIntervalS    int                          // The number of seconds between event log compactions. The default is 3600 seconds.
+	ExportFile          string                       // When set, every new event log is appended to this file as a json line.
+	ExportFileMaxSizeMB int                          // The size of the export file at which it is rotated. The default is 10 MB.
+	ExportFileBackups   int                          // The number of rotated export files that are kept. The default is 3.
+	ExportSyslog        bool                         // When true, every new event log is also written to the local syslog.
+	ExportSyslogTag     string                       // The tag of the syslog records. The default is "anax".
+}
+
+// The wildcard severity for the event log retention rules.
+const EventLogRetentionAnySeverity = "*"
+
+func (e *EventLogConfig) String() string {
+	return fmt.Sprintf("Retention: %v, CompactIntervalS: %v, ExportFile: %v, ExportFileMaxSizeMB: %v, ExportFileBackups: %v, ExportSyslog: %v, ExportSyslogTag: %v", e.Retention, e.CompactIntervalS, e.ExportFile, e.ExportFileMaxSizeMB, e.ExportFileBackups, e.ExportSyslog, e.ExportSyslogTag)
+}
+
+// Returns the retention rule for the given severity, or nil if event logs of that severity are kept forever.
+func (e *EventLogConfig) GetRetention(severity string) *EventLogRetention {
+	if r, ok := e.Retention[severity]; ok {
+		return &r
+	} else if r, ok := e.Retention[EventLogRetentionAnySeverity]; ok {
+		return &r
+	}
+	return nil
+}
+
+// Returns true if event logs are exported as they are saved.
+func (e *EventLogConfig) IsExportEnabled() bool {
+	return e.ExportFile != "" || e.ExportSyslog
+}
@@ -0,0 +1,23 @@
+package config
+
+import (
+	"fmt"
+)
+
+// Configuration for the rotation of the messaging key of a node or agbot. The messaging key is the RSA key pair that the
+// agreement protocol messages to the node or agbot are encrypted with. When the key is rotated, the new public key is
+// published to the exchange, and the old private key is kept for a grace period so that the messages that were already
+// encrypted with the old key can still be decrypted.
+type MessageKeyRotationConfig struct {
+	IntervalS int // The number of seconds between rotations of the messaging key. Zero turns scheduled rotation off, which is the default.
+	GraceS    int // The number of seconds that the old key can still decrypt messages after a rotation. It should be longer than the message TTL. The default is 3600 seconds.
+}
+
+func (m *MessageKeyRotationConfig) String() string {
+	return fmt.Sprintf("IntervalS: %v, GraceS: %v", m.IntervalS, m.GraceS)
+}
+
+// Returns true if the messaging key is rotated on a schedule.
+func (m *MessageKeyRotationConfig) IsScheduled() bool {
+	return m.IntervalS > 0
+}
@@ -0,0 +1,27 @@
+package config
+
+import (
+	"fmt"
+)
+
+// Configuration for the delivery of the agreement protocol messages through an MQTT broker. When it is configured, the
+// nodes and agbots subscribe to their own topic on the broker and advertise it in the msgEndPoint of their exchange
+// resource, so that the messages sent to them are pushed instead of waiting in their exchange mailbox until they poll
+// it. The exchange mailboxes are still used when the broker is unreachable, or when the receiver does not use the
+// broker. The broker is trusted with the same certificates as the exchange.
+type MQTTConfig struct {
+	BrokerURL       string // The URL of the MQTT broker, e.g. ssl://mqtt.example.com:8883. Leave it empty to send all the messages through the exchange.
+	TopicPrefix     string // The prefix of the topics of the nodes and agbots. The default is horizon.
+	KeepAliveS      int    // The number of seconds between keep alive pings to the broker. The default is 30 seconds.
+	ConnectTimeoutS int    // The number of seconds to wait for the broker to accept a connection. The default is 10 seconds.
+	PublishTimeoutS int    // The number of seconds to wait for the broker to acknowledge a message before it is sent through the exchange. The default is 5 seconds.
+}
+
+func (m *MQTTConfig) String() string {
+	return fmt.Sprintf("BrokerURL: %v, TopicPrefix: %v, KeepAliveS: %v, ConnectTimeoutS: %v, PublishTimeoutS: %v", m.BrokerURL, m.TopicPrefix, m.KeepAliveS, m.ConnectTimeoutS, m.PublishTimeoutS)
+}
+
+// Returns true if the messages are pushed through an MQTT broker.
+func (m *MQTTConfig) IsEnabled() bool {
+	return m.BrokerURL != ""
+}
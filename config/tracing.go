@@ -0,0 +1,32 @@
+package config
+
+import (
+	"fmt"
+)
+
+// The supported span exporters.
+const (
+	TracingExporterOTLP   = "otlp"   // send the spans to an OTLP/HTTP collector
+	TracingExporterFile   = "file"   // write the spans to a file, one OTLP json batch per line
+	TracingExporterStdout = "stdout" // write the spans to stdout, one OTLP json batch per line
+)
+
+// Configuration for distributed tracing of the agreement protocol. Tracing is off when there is no exporter.
+type TracingConfig struct {
+	Exporter       string            // The span exporter, otlp, file or stdout. Leave it empty to turn tracing off.
+	OTLPEndpoint   string            // The base URL of the OTLP/HTTP collector, e.g. http://localhost:4318. The spans are posted to <OTLPEndpoint>/v1/traces.
+	OTLPHeaders    map[string]string // Additional http headers for the OTLP collector, e.g. for authentication.
+	File           string            // The file the spans are written to when the exporter is file.
+	ServiceName    string            // The service name reported with the spans. The default is anax for an agent and anax-agbot for an agbot.
+	SampleRatio    float64           // The ratio of the agreements and searches that are traced, greater than 0 and at most 1. The default is 1.
+	BatchIntervalS int               // The number of seconds between span exports. The default is 5 seconds.
+}
+
+func (t *TracingConfig) String() string {
+	return fmt.Sprintf("Exporter: %v, OTLPEndpoint: %v, File: %v, ServiceName: %v, SampleRatio: %v, BatchIntervalS: %v", t.Exporter, t.OTLPEndpoint, t.File, t.ServiceName, t.SampleRatio, t.BatchIntervalS)
+}
+
+// Returns true if tracing is turned on.
+func (t *TracingConfig) IsEnabled() bool {
+	return t.Exporter != ""
+}
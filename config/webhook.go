@@ -0,0 +1,63 @@
+package config
+
+import (
+	"fmt"
+)
+
+// The agbot events that can be notified to webhook endpoints.
+const (
+	WebhookEventAgreementFailures = "agreement_failures" // agreements with a node for a deployment policy or pattern failed repeatedly
+	WebhookEventNodeOutOfPolicy   = "node_out_of_policy" // the agbot cancelled an agreement because the node stopped heartbeating
+	WebhookEventWorkloadRollback  = "workload_rollback"  // a node was given a lower priority workload version after the higher priority one failed
+)
+
+// Returns true if the event type is one of the webhook events.
+func IsWebhookEvent(event string) bool {
+	return event == WebhookEventAgreementFailures || event == WebhookEventNodeOutOfPolicy || event == WebhookEventWorkloadRollback
+}
+
+// A webhook endpoint that is sent the agbot events that pass its filters. An empty filter passes every event.
+type WebhookEndpoint struct {
+	URL      string            // The URL that the notifications are posted to.
+	Secret   string            // When set, the payload is signed with HMAC-SHA256 using this secret, in the X-Horizon-Signature header.
+	Events   []string          // The event types that are notified.
+	Orgs     []string          // The orgs whose events are notified, an event matches the org of its deployment policy or pattern and the org of its node.
+	Policies []string          // The fully qualified (org/name) deployment policies and patterns whose events are notified.
+	Headers  map[string]string // Additional http headers for the endpoint, e.g. for authentication.
+}
+
+func (w WebhookEndpoint) String() string {
+	return fmt.Sprintf("URL: %v, Secret: %v, Events: %v, Orgs: %v, Policies: %v", w.URL, "******", w.Events, w.Orgs, w.Policies)
+}
+
+// Configuration for the webhook notifications of the agbot. Notifications are off when there are no endpoints. Pending
+// notifications are kept in the agbot database until they are delivered, so they survive restarts of the agbot.
+type WebhookConfig struct {
+	Endpoints         []WebhookEndpoint
+	FailureThreshold  int // The number of agreements in a row that fail with a node for a deployment policy or pattern before agreement_failures is notified. The default is 3.
+	MaxAttempts       int // The number of times a notification is attempted before it is dropped. The default is 10.
+	RetryBackoffS     int // The number of seconds before the first retry of a failed notification. It doubles with every retry. The default is 5 seconds.
+	MaxRetryBackoffS  int // The longest time between retries, in seconds. The default is 600 seconds.
+	TimeoutS          int // The number of seconds an endpoint has to respond. The default is 10 seconds.
+	DeliveryIntervalS int // The number of seconds between checks for notifications to deliver. The default is 5 seconds.
+}
+
+func (w *WebhookConfig) String() string {
+	return fmt.Sprintf("Endpoints: %v, FailureThreshold: %v, MaxAttempts: %v, RetryBackoffS: %v, MaxRetryBackoffS: %v, TimeoutS: %v, DeliveryIntervalS: %v",
+		w.Endpoints, w.FailureThreshold, w.MaxAttempts, w.RetryBackoffS, w.MaxRetryBackoffS, w.TimeoutS, w.DeliveryIntervalS)
+}
+
+// Returns true if webhook notifications are turned on.
+func (w *WebhookConfig) IsEnabled() bool {
+	return len(w.Endpoints) != 0
+}
+
+// Returns the endpoint with the URL, or nil if there is none.
+func (w *WebhookConfig) GetEndpoint(url string) *WebhookEndpoint {
+	for i := range w.Endpoints {
+		if w.Endpoints[i].URL == url {
+			return &w.Endpoints[i]
+		}
+	}
+	return nil
+}
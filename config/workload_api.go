@@ -0,0 +1,69 @@
+package config
+
+import (
+	"fmt"
+	"path"
+)
+
+// Configuration for the workload API, where service containers report their health and status to the agent. The
+// workload API is served next to the embedded ESS, over the same protocol, and services authenticate to it with their
+// FSS (ESS) API credentials.
+type WorkloadAPIConfig struct {
+	Disabled          bool   // Turns the workload API off.
+	APIPort           uint16 // The port on which the workload API will listen when the FSS protocol is https. For a unix domain socket, this will always be "0".
+	MaxReportBytes    int    // The maximum size of a status report that a service can post. The default is 16384.
+	KVMaxKeys         int    // The maximum number of keys that a service can store, across all its scopes. The default is 256.
+	KVMaxValueBytes   int    // The maximum size of a value that a service can store. The default is 65536.
+	KVMaxServiceBytes int    // The maximum size of the keys and values that a service can store, across all its scopes. The default is 1048576.
+}
+
+func (w *WorkloadAPIConfig) String() string {
+	return fmt.Sprintf("Disabled: %v, APIPort: %v, MaxReportBytes: %v, KVMaxKeys: %v, KVMaxValueBytes: %v, KVMaxServiceBytes: %v", w.Disabled, w.APIPort, w.MaxReportBytes, w.KVMaxKeys, w.KVMaxValueBytes, w.KVMaxServiceBytes)
+}
+
+// The workload API uses the same protocol as the FSS. With a unix domain socket, the workload API socket is in the same
+// directory as the FSS socket, so that it is reachable from every service container that can reach the FSS.
+func (c *HorizonConfig) GetWorkloadAPIListen() string {
+	if c.FSSIsUnixProtocol() {
+		return path.Join(c.GetFileSyncServiceAPIUnixDomainSocketPath(), HZN_WORKLOAD_API_DOMAIN_SOCKET)
+	}
+	return c.GetFileSyncServiceAPIListen()
+}
+
+func (c *HorizonConfig) GetWorkloadAPIPort() uint16 {
+	if c.FSSIsUnixProtocol() {
+		return 0
+	} else if c.Edge.WorkloadAPI.APIPort == 0 {
+		return HZN_WORKLOAD_API_LISTEN_PORT_DEFAULT
+	} else {
+		return c.Edge.WorkloadAPI.APIPort
+	}
+}
+
+func (c *HorizonConfig) GetWorkloadAPIMaxReportBytes() int {
+	if c.Edge.WorkloadAPI.MaxReportBytes <= 0 {
+		return HZN_WORKLOAD_API_MAX_REPORT_BYTES_DEFAULT
+	}
+	return c.Edge.WorkloadAPI.MaxReportBytes
+}
+
+func (c *HorizonConfig) GetWorkloadAPIKVMaxKeys() int {
+	if c.Edge.WorkloadAPI.KVMaxKeys <= 0 {
+		return HZN_WORKLOAD_API_KV_MAX_KEYS_DEFAULT
+	}
+	return c.Edge.WorkloadAPI.KVMaxKeys
+}
+
+func (c *HorizonConfig) GetWorkloadAPIKVMaxValueBytes() int {
+	if c.Edge.WorkloadAPI.KVMaxValueBytes <= 0 {
+		return HZN_WORKLOAD_API_KV_MAX_VALUE_BYTES_DEFAULT
+	}
+	return c.Edge.WorkloadAPI.KVMaxValueBytes
+}
+
+func (c *HorizonConfig) GetWorkloadAPIKVMaxServiceBytes() int {
+	if c.Edge.WorkloadAPI.KVMaxServiceBytes <= 0 {
+		return HZN_WORKLOAD_API_KV_MAX_SERVICE_BYTES_DEFAULT
+	}
+	return c.Edge.WorkloadAPI.KVMaxServiceBytes
+}
@@ -21,6 +21,7 @@ import (
 	"github.com/open-horizon/anax/persistence"
 	"github.com/open-horizon/anax/policy"
 	"github.com/open-horizon/anax/resource"
+	"github.com/open-horizon/anax/tracing"
 	"github.com/open-horizon/anax/worker"
 	"golang.org/x/sys/unix"
 	"io"
@@ -1474,7 +1475,12 @@ func (b *ContainerWorker) CommandHandler(command worker.Command) bool {
 			sVer := ags[0].RunningWorkload.Version
 
 			// Create the docker configuration and launch the containers.
-			if deploymentConfig, err := b.ResourcesCreate(agreementId, cmd.AgreementLaunchContext.AgreementProtocol, deploymentDesc, cmd.AgreementLaunchContext.ConfigureRaw, *cmd.AgreementLaunchContext.EnvironmentAdditions, ms_children_networks, serviceIdentity, sVer); err != nil {
+			span := tracing.StartAgreementSpan("node.container_start", agreementId)
+			deploymentConfig, err := b.ResourcesCreate(agreementId, cmd.AgreementLaunchContext.AgreementProtocol, deploymentDesc, cmd.AgreementLaunchContext.ConfigureRaw, *cmd.AgreementLaunchContext.EnvironmentAdditions, ms_children_networks, serviceIdentity, sVer)
+			span.SetError(err)
+			span.End()
+
+			if err != nil {
 				eventlog.LogAgreementEvent(b.db, persistence.SEVERITY_ERROR,
 					persistence.NewMessageMeta(EL_CONT_START_CONTAINER_ERROR, err.Error()),
 					persistence.EC_ERROR_START_CONTAINER,
@@ -2370,6 +2376,18 @@ func (b *ContainerWorker) ResourcesRemove(agreements []string) error {
 			glog.Errorf("Failed to remove FSS Authentication credential file for %v, error %v", agreementId, err)
 		}
 
+		// Remove the health and status that the service reported through the workload API, and the keys it stored in
+		// the scope of the agreement. The keys in the scope of the service are kept. The container worker of the hzn
+		// dev CLI does not have a database.
+		if b.db != nil {
+			if err := persistence.DeleteWorkloadReport(b.db, agreementId); err != nil {
+				glog.Errorf("Failed to remove the workload report of %v, error %v", agreementId, err)
+			}
+			if err := persistence.DeleteWorkloadKVAgreement(b.db, agreementId); err != nil {
+				glog.Errorf("Failed to remove the workload keys of %v, error %v", agreementId, err)
+			}
+		}
+
 	}
 
 	// gather agreement networks to free
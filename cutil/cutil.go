@@ -271,13 +271,26 @@ func SetPlatformEnvvars(envAdds map[string]string, prefix string, agreementId st
 
 }
 
-// Temporary function to remove ESS env vars for the edge cluster case.
+// Add the env vars that a service uses to form the URL of the workload API, where it reports its health and status. The
+// workload API uses the same protocol, credentials and SSL certificate as the FSS (ESS) API.
+func SetWorkloadAPIEnvvars(envAdds map[string]string, prefix string, address string, port string) {
+
+	// The address of the workload API.
+	envAdds[prefix+"WORKLOAD_API_ADDRESS"] = address
+
+	// The port of the workload API. Zero when using a unix domain socket.
+	envAdds[prefix+"WORKLOAD_API_PORT"] = port
+}
+
+// Temporary function to remove ESS and workload API env vars for the edge cluster case.
 func RemoveESSEnvVars(envAdds map[string]string, prefix string) map[string]string {
 	delete(envAdds, prefix+"ESS_API_PROTOCOL")
 	delete(envAdds, prefix+"ESS_API_ADDRESS")
 	delete(envAdds, prefix+"ESS_API_PORT")
 	delete(envAdds, prefix+"ESS_AUTH")
 	delete(envAdds, prefix+"ESS_CERT")
+	delete(envAdds, prefix+"WORKLOAD_API_ADDRESS")
+	delete(envAdds, prefix+"WORKLOAD_API_PORT")
 	return envAdds
 }
 
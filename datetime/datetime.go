@@ -0,0 +1,87 @@
+package datetime
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/open-horizon/anax/i18n"
+)
+
+// Dates and durations are written as strings so that they can be used as property values and in the text
+// constraint language.
+//
+// A date is either a day, e.g. "2026-01-01", which is midnight UTC, or a date and time in RFC3339 format,
+// e.g. "2026-01-01T08:30:00Z" or "2026-01-01T08:30:00+01:00". A date and time without a time zone is in UTC.
+//
+// A duration is a sequence of numbers with a unit of w (weeks), d (days), h, m, s, ms, us or ns, e.g. "90d" or
+// "1h30m". A day is always 24 hours.
+
+var dateFormats = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02"}
+
+// Parse a date.
+func ParseDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, format := range dateFormats {
+		if t, err := time.Parse(format, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.New(i18n.GetMessagePrinter().Sprintf("%v is not a date, it must be YYYY-MM-DD or a date and time in RFC3339 format, e.g. 2026-01-01T08:30:00Z", s))
+}
+
+// The duration units and their lengths, the longer units first so that m is not mistaken for ms.
+var durationUnits = []struct {
+	unit   string
+	length time.Duration
+}{
+	{"ms", time.Millisecond},
+	{"us", time.Microsecond},
+	{"µs", time.Microsecond},
+	{"ns", time.Nanosecond},
+	{"w", 7 * 24 * time.Hour},
+	{"d", 24 * time.Hour},
+	{"h", time.Hour},
+	{"m", time.Minute},
+	{"s", time.Second},
+}
+
+// Parse a duration.
+func ParseDuration(s string) (time.Duration, error) {
+	invalid := errors.New(i18n.GetMessagePrinter().Sprintf("%v is not a duration, it must be a sequence of numbers with a unit of w, d, h, m, s, ms, us or ns, e.g. 1h30m", s))
+
+	rest := strings.TrimSpace(s)
+	if rest == "" {
+		return 0, invalid
+	}
+
+	d := time.Duration(0)
+	for rest != "" {
+		// The number.
+		i := 0
+		for i < len(rest) && (rest[i] == '.' || (rest[i] >= '0' && rest[i] <= '9')) {
+			i++
+		}
+		n, err := strconv.ParseFloat(rest[:i], 64)
+		if i == 0 || err != nil {
+			return 0, invalid
+		}
+		rest = rest[i:]
+
+		// The unit.
+		found := false
+		for _, u := range durationUnits {
+			if strings.HasPrefix(rest, u.unit) {
+				d += time.Duration(n * float64(u.length))
+				rest = rest[len(u.unit):]
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, invalid
+		}
+	}
+	return d, nil
+}
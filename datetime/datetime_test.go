@@ -0,0 +1,63 @@
+// +build unit
+
+package datetime
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_ParseDate(t *testing.T) {
+	tests := []struct {
+		s    string
+		date time.Time
+	}{
+		{"2026-01-01", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{" 2026-01-01T08:30:00Z ", time.Date(2026, 1, 1, 8, 30, 0, 0, time.UTC)},
+		{"2026-01-01T08:30:00+01:00", time.Date(2026, 1, 1, 7, 30, 0, 0, time.UTC)},
+		{"2026-01-01T08:30:00.5Z", time.Date(2026, 1, 1, 8, 30, 0, 500000000, time.UTC)},
+		{"2026-01-01T08:30:00", time.Date(2026, 1, 1, 8, 30, 0, 0, time.UTC)},
+		{"2026-01-01T08:30", time.Date(2026, 1, 1, 8, 30, 0, 0, time.UTC)},
+	}
+	for _, tc := range tests {
+		if d, err := ParseDate(tc.s); err != nil {
+			t.Errorf("%v: unexpected error %v", tc.s, err)
+		} else if !d.Equal(tc.date) {
+			t.Errorf("%v: expected %v, got %v", tc.s, tc.date, d)
+		}
+	}
+
+	for _, s := range []string{"", "yesterday", "2026-13-01", "01/02/2026", "2026-01-01 08:30:00"} {
+		if _, err := ParseDate(s); err == nil {
+			t.Errorf("%v should not be a valid date", s)
+		}
+	}
+}
+
+func Test_ParseDuration(t *testing.T) {
+	tests := []struct {
+		s string
+		d time.Duration
+	}{
+		{"90d", 90 * 24 * time.Hour},
+		{"2w", 14 * 24 * time.Hour},
+		{"1h30m", 90 * time.Minute},
+		{"1.5h", 90 * time.Minute},
+		{"1d12h", 36 * time.Hour},
+		{"500ms", 500 * time.Millisecond},
+		{"10s", 10 * time.Second},
+	}
+	for _, tc := range tests {
+		if d, err := ParseDuration(tc.s); err != nil {
+			t.Errorf("%v: unexpected error %v", tc.s, err)
+		} else if d != tc.d {
+			t.Errorf("%v: expected %v, got %v", tc.s, tc.d, d)
+		}
+	}
+
+	for _, s := range []string{"", "10", "h", "10y", "-1h", "1h 30m", "1..5h"} {
+		if _, err := ParseDuration(s); err == nil {
+			t.Errorf("%v should not be a valid duration", s)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package diag
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// The name of the manifest in the bundle.
+const MANIFEST_FILE = "manifest.json"
+
+// The manifest of a diagnostics bundle describes each item that was collected, where it came from and whether it
+// was redacted. The items that could not be collected are in the manifest with the error, but not in the bundle.
+type Manifest struct {
+	Created   string          `json:"created"`
+	Collector string          `json:"collector"` // the hzn command or the agent API
+	Entries   []ManifestEntry `json:"entries"`
+}
+
+type ManifestEntry struct {
+	Name     string `json:"name"`   // the file name in the bundle
+	Source   string `json:"source"` // e.g. GET /agreement, docker inspect or /etc/horizon/anax.json
+	Size     int    `json:"size"`
+	Redacted int    `json:"redacted"` // the number of redacted values
+	Error    string `json:"error,omitempty"`
+}
+
+// A Bundle writes the collected diagnostics to a gzipped tar file. The content is redacted before it is written.
+// The first write error is saved and returned by Close, so that the collectors do not have to check each write.
+type Bundle struct {
+	Redactor *Redactor
+	gw       *gzip.Writer
+	tw       *tar.Writer
+	manifest Manifest
+	created  time.Time
+	err      error
+}
+
+func NewBundle(w io.Writer, collector string) *Bundle {
+	gw := gzip.NewWriter(w)
+	created := time.Now()
+	return &Bundle{
+		Redactor: NewRedactor(),
+		gw:       gw,
+		tw:       tar.NewWriter(gw),
+		manifest: Manifest{
+			Created:   created.UTC().Format(time.RFC3339),
+			Collector: collector,
+			Entries:   []ManifestEntry{},
+		},
+		created: created,
+	}
+}
+
+// Add the json representation of v to the bundle. The user inputs that the service definitions in v mark sensitive
+// are redacted in v and everything that is added after it, so the service definitions should be added first.
+func (b *Bundle) AddJson(name string, source string, v interface{}) {
+	jsonBytes, err := json.Marshal(v)
+	if err != nil {
+		b.AddError(name, source, err)
+		return
+	}
+	var data interface{}
+	if err := json.Unmarshal(jsonBytes, &data); err != nil {
+		b.AddError(name, source, err)
+		return
+	}
+
+	b.Redactor.FindSensitiveUserInputs(data)
+	data, count := b.Redactor.RedactJsonData(data)
+
+	if jsonBytes, err = json.MarshalIndent(data, "", "  "); err != nil {
+		b.AddError(name, source, err)
+		return
+	}
+	b.write(ManifestEntry{Name: name, Source: source, Redacted: count}, jsonBytes)
+}
+
+// Add text, e.g. a log, to the bundle.
+func (b *Bundle) AddText(name string, source string, text []byte) {
+	redacted, count := b.Redactor.RedactText(string(text))
+	b.write(ManifestEntry{Name: name, Source: source, Redacted: count}, []byte(redacted))
+}
+
+// Record in the manifest that an item could not be collected.
+func (b *Bundle) AddError(name string, source string, err error) {
+	msg := err.Error()
+	msg, _ = b.Redactor.RedactText(msg)
+	b.manifest.Entries = append(b.manifest.Entries, ManifestEntry{Name: name, Source: source, Error: msg})
+}
+
+// Return the manifest of the items that were added so far.
+func (b *Bundle) Manifest() Manifest {
+	return b.manifest
+}
+
+// Write the manifest and close the bundle. The writer that the bundle was created with is not closed.
+func (b *Bundle) Close() error {
+	if manifestBytes, err := json.MarshalIndent(b.manifest, "", "  "); err != nil {
+		b.saveErr(err)
+	} else {
+		b.writeFile(MANIFEST_FILE, manifestBytes)
+	}
+	b.saveErr(b.tw.Close())
+	b.saveErr(b.gw.Close())
+	return b.err
+}
+
+func (b *Bundle) write(entry ManifestEntry, content []byte) {
+	entry.Size = len(content)
+	b.manifest.Entries = append(b.manifest.Entries, entry)
+	b.writeFile(entry.Name, content)
+}
+
+func (b *Bundle) writeFile(name string, content []byte) {
+	if b.err != nil {
+		return
+	}
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0600,
+		Size:    int64(len(content)),
+		ModTime: b.created,
+	}
+	if err := b.tw.WriteHeader(hdr); err != nil {
+		b.saveErr(err)
+		return
+	}
+	_, err := b.tw.Write(content)
+	b.saveErr(err)
+}
+
+func (b *Bundle) saveErr(err error) {
+	if b.err == nil && err != nil {
+		b.err = err
+	}
+}
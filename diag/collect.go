@@ -0,0 +1,183 @@
+package diag
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/container"
+	"io/ioutil"
+	"net"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The names of the items in a bundle, the same whether it is collected by hzn node diag or the agent API.
+const (
+	NODE_FILE           = "node.json"
+	STATUS_FILE         = "status.json"
+	SERVICES_FILE       = "services.json"
+	SERVICE_CONFIG_FILE = "service_config.json"
+	AGREEMENTS_FILE     = "agreements.json"
+	EVENTLOGS_FILE      = "eventlogs.json"
+	SURFACE_ERRORS_FILE = "surface_errors.json"
+	NODE_POLICY_FILE    = "node_policy.json"
+	NODE_USERINPUT_FILE = "node_userinput.json"
+	ANAX_CONFIG_FILE    = "config/anax.json"
+	HORIZON_DEFAULTS    = "config/horizon"
+	ESS_STATUS_FILE     = "ess_status.json"
+)
+
+// The number of lines of the agent log that are collected.
+const AGENT_LOG_LINES = 5000
+
+// The name of the agent container when the agent runs in a container, e.g. on macOS.
+const AGENT_CONTAINER_NAME = "horizon1"
+
+// The status of the ESS that is embedded in the agent.
+type ESSStatus struct {
+	Protocol        string `json:"protocol"`
+	Listen          string `json:"listen"`
+	Port            uint16 `json:"port,omitempty"`
+	PersistencePath string `json:"persistencePath"`
+	CSSURL          string `json:"cssUrl"`
+	PollingRate     uint16 `json:"pollingRate"`
+	Reachable       bool   `json:"reachable"`
+	Error           string `json:"error,omitempty"`
+}
+
+// Return the ESS configuration of the agent and whether the ESS API is listening.
+func GetESSStatus(cfg *config.HorizonConfig) *ESSStatus {
+	status := &ESSStatus{
+		Protocol:        cfg.GetFileSyncServiceProtocol(),
+		Listen:          cfg.GetFileSyncServiceAPIListen(),
+		Port:            cfg.GetFileSyncServiceAPIPort(),
+		PersistencePath: cfg.GetFileSyncServiceStoragePath(),
+		CSSURL:          cfg.Edge.FileSyncService.CSSURL,
+		PollingRate:     cfg.Edge.FileSyncService.PollingRate,
+	}
+
+	network, address := "unix", status.Listen
+	if !cfg.FSSIsUnixProtocol() {
+		network, address = "tcp", net.JoinHostPort(status.Listen, strconv.Itoa(int(status.Port)))
+	}
+	if conn, err := net.DialTimeout(network, address, 5*time.Second); err != nil {
+		status.Error = err.Error()
+	} else {
+		status.Reachable = true
+		conn.Close()
+	}
+	return status
+}
+
+// Add a configuration file, e.g. /etc/horizon/anax.json or /etc/default/horizon, to the bundle. Json files are
+// redacted as json.
+func AddConfigFile(b *Bundle, name string, fileName string) {
+	content, err := ioutil.ReadFile(filepath.Clean(fileName))
+	if err != nil {
+		b.AddError(name, fileName, err)
+		return
+	}
+	if strings.HasSuffix(fileName, ".json") {
+		b.AddJson(name, fileName, rawJson(content))
+		return
+	}
+	b.AddText(name, fileName, content)
+}
+
+// json that is added to the bundle as is, before it is redacted.
+type rawJson []byte
+
+func (j rawJson) MarshalJSON() ([]byte, error) {
+	return j, nil
+}
+
+// Add the docker containers of the node to the bundle, i.e. the output of docker ps -a and docker inspect of the
+// agent container and the service containers.
+func AddDockerContainers(b *Bundle, dockerEndpoint string) {
+	client, err := docker.NewClient(dockerEndpoint)
+	if err != nil {
+		b.AddError("docker/ps.json", "docker ps -a", err)
+		return
+	}
+	containers, err := client.ListContainers(docker.ListContainersOptions{All: true})
+	if err != nil {
+		b.AddError("docker/ps.json", "docker ps -a", err)
+		return
+	}
+	b.AddJson("docker/ps.json", "docker ps -a", containers)
+
+	for _, c := range containers {
+		if !isHorizonContainer(c) {
+			continue
+		}
+		name := c.ID
+		if len(c.Names) > 0 {
+			name = strings.TrimPrefix(c.Names[0], "/")
+		}
+		fileName := fmt.Sprintf("docker/inspect/%v.json", name)
+		source := fmt.Sprintf("docker inspect %v", name)
+		if inspect, err := client.InspectContainer(c.ID); err != nil {
+			b.AddError(fileName, source, err)
+		} else {
+			b.AddJson(fileName, source, inspect)
+		}
+	}
+}
+
+// The agent container and the containers that the agent started for services.
+func isHorizonContainer(c docker.APIContainers) bool {
+	for _, n := range c.Names {
+		if strings.TrimPrefix(n, "/") == AGENT_CONTAINER_NAME {
+			return true
+		}
+	}
+	for label := range c.Labels {
+		if strings.HasPrefix(label, container.LABEL_PREFIX) {
+			return true
+		}
+	}
+	return false
+}
+
+// Add the log of the agent to the bundle. The log is read from the systemd journal of the horizon service, or from
+// the agent container when the agent runs in a container.
+func AddAgentLog(b *Bundle, dockerEndpoint string) {
+	name := "logs/anax.log"
+	source := fmt.Sprintf("journalctl -u horizon.service -n %v", AGENT_LOG_LINES)
+	journalErr := errors.New("journalctl not found")
+	if _, err := exec.LookPath("journalctl"); err == nil {
+		if out, err := exec.Command("journalctl", "-u", "horizon.service", "--no-pager", "-n", strconv.Itoa(AGENT_LOG_LINES)).Output(); err != nil {
+			journalErr = err
+		} else if len(bytes.TrimSpace(out)) != 0 && !bytes.Contains(out, []byte("-- No entries --")) {
+			b.AddText(name, source, out)
+			return
+		} else {
+			journalErr = errors.New("the journal has no entries for horizon.service")
+		}
+	}
+
+	containerSource := fmt.Sprintf("docker logs --tail %v %v", AGENT_LOG_LINES, AGENT_CONTAINER_NAME)
+	client, err := docker.NewClient(dockerEndpoint)
+	if err == nil {
+		out := new(bytes.Buffer)
+		err = client.Logs(docker.LogsOptions{
+			Container:    AGENT_CONTAINER_NAME,
+			OutputStream: out,
+			ErrorStream:  out,
+			Stdout:       true,
+			Stderr:       true,
+			Tail:         strconv.Itoa(AGENT_LOG_LINES),
+			Timestamps:   true,
+		})
+		if err == nil {
+			b.AddText(name, containerSource, out.Bytes())
+			return
+		}
+	}
+	b.AddError(name, source+", "+containerSource, fmt.Errorf("%v, %v", journalErr, err))
+}
@@ -0,0 +1,209 @@
+package diag
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// The text that replaces a redacted value.
+const REDACTED = "********"
+
+// Secret values shorter than this are not removed from the text of the bundle, because they would match too many
+// unrelated words and numbers.
+const MIN_SECRET_LEN = 6
+
+// The words in a key that mark its value as a secret, compared to the key in lower case without '_' and '-'.
+var secretKeyWords = []string{"token", "password", "passwd", "secret", "apikey", "privatekey", "credential", "auth"}
+
+// The words in a key that mark its value as something about a secret rather than the secret itself, e.g. a file
+// name or an expiration time.
+var notSecretKeyWords = []string{"time", "valid", "expir", "url", "path", "file", "type"}
+
+var textSecretPatterns = []*regexp.Regexp{
+	// key=value and key: value pairs where the key names a secret
+	regexp.MustCompile(`(?i)([a-z0-9_.-]*(?:token|password|passwd|secret|apikey|api_key|private_key|credential|user_auth|node_auth)[a-z0-9_.-]*["']?\s*[:=]\s*["']?)([^\s"',;&}]+)`),
+	// http authorization headers
+	regexp.MustCompile(`(?i)(authorization:\s*(?:basic|bearer)\s+)(\S+)`),
+	// the password of the user info in a url
+	regexp.MustCompile(`(://[^/\s:@]+:)([^@\s/]+)(@)`),
+}
+
+// Return true if the value of the key is a secret, e.g. exchangeToken, HZN_EXCHANGE_USER_AUTH or mqttPassword.
+func IsSecretKey(key string) bool {
+	k := strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(key))
+	for _, w := range notSecretKeyWords {
+		if strings.Contains(k, w) {
+			return false
+		}
+	}
+	for _, w := range secretKeyWords {
+		if strings.Contains(k, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// A Redactor removes secrets from the json and text content of a bundle. It knows the names of the user inputs
+// that are marked sensitive and remembers the secret values that it redacts from json, so that the same values are
+// also removed from the text content, like the logs, that is added after it.
+type Redactor struct {
+	sensitive map[string]bool
+	secrets   map[string]bool
+}
+
+func NewRedactor() *Redactor {
+	return &Redactor{
+		sensitive: make(map[string]bool),
+		secrets:   make(map[string]bool),
+	}
+}
+
+// Add the names of user input variables whose values are redacted.
+func (r *Redactor) AddSensitiveNames(names ...string) {
+	for _, n := range names {
+		if n != "" {
+			r.sensitive[n] = true
+		}
+	}
+}
+
+// Add secret values that are removed wherever they appear, e.g. the exchange token of the node.
+func (r *Redactor) AddSecrets(values ...string) {
+	for _, v := range values {
+		if len(v) >= MIN_SECRET_LEN && v != REDACTED {
+			r.secrets[v] = true
+		}
+	}
+}
+
+func (r *Redactor) isSecret(key string) bool {
+	return r.sensitive[key] || IsSecretKey(key)
+}
+
+// Find the user inputs of the service definitions in the json data that are marked sensitive and redact their
+// values from now on. The service definitions have a userInput array of objects with name, type and sensitive fields.
+func (r *Redactor) FindSensitiveUserInputs(data interface{}) {
+	switch d := data.(type) {
+	case map[string]interface{}:
+		if name, ok := d["name"].(string); ok {
+			if _, ok := d["type"]; ok {
+				if sensitive, ok := d["sensitive"].(bool); ok && sensitive {
+					r.AddSensitiveNames(name)
+				}
+			}
+		}
+		for _, v := range d {
+			r.FindSensitiveUserInputs(v)
+		}
+	case []interface{}:
+		for _, v := range d {
+			r.FindSensitiveUserInputs(v)
+		}
+	}
+}
+
+// Redact the secrets in json data, i.e. maps, arrays and values as unmarshaled by encoding/json. Return the
+// redacted data and the number of redacted values.
+func (r *Redactor) RedactJsonData(data interface{}) (interface{}, int) {
+	switch d := data.(type) {
+	case map[string]interface{}:
+		count := 0
+		// a {name, value} pair, e.g. a user input value of the node
+		if name, ok := d["name"].(string); ok && r.isSecret(name) {
+			if v, ok := d["value"]; ok && r.redactable(v) {
+				d["value"] = REDACTED
+				count++
+			}
+		}
+		// a user input definition that is marked sensitive has a sensitive default value
+		if sensitive, ok := d["sensitive"].(bool); ok && sensitive {
+			if v, ok := d["defaultValue"]; ok && r.redactable(v) {
+				d["defaultValue"] = REDACTED
+				count++
+			}
+		}
+		keys := make([]string, 0, len(d))
+		for k := range d {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if r.isSecret(k) && r.redactable(d[k]) {
+				d[k] = REDACTED
+				count++
+				continue
+			}
+			var c int
+			d[k], c = r.RedactJsonData(d[k])
+			count += c
+		}
+		return d, count
+	case []interface{}:
+		count := 0
+		for i, v := range d {
+			var c int
+			d[i], c = r.RedactJsonData(v)
+			count += c
+		}
+		return d, count
+	case string:
+		// a NAME=value environment variable, e.g. in the output of docker inspect
+		if i := strings.Index(d, "="); i > 0 && !strings.ContainsAny(d[:i], " \t\n") && r.isSecret(d[:i]) && d[i+1:] != "" {
+			r.AddSecrets(d[i+1:])
+			return d[:i+1] + REDACTED, 1
+		}
+		return r.RedactText(d)
+	}
+	return data, 0
+}
+
+// Only strings and numbers are redacted, booleans like requireAuth say nothing about a secret. The redacted strings
+// are remembered as secrets.
+func (r *Redactor) redactable(v interface{}) bool {
+	switch value := v.(type) {
+	case string:
+		r.AddSecrets(value)
+		return value != "" && value != REDACTED
+	case float64, int, int64:
+		return true
+	}
+	return false
+}
+
+// Redact the secrets in text, i.e. the secret values that are known and the values of key=value pairs, authorization
+// headers and urls that look like secrets. Return the redacted text and the number of redactions.
+func (r *Redactor) RedactText(text string) (string, int) {
+	count := 0
+
+	// replace the longest secrets first, in case one secret contains another
+	secrets := make([]string, 0, len(r.secrets))
+	for s := range r.secrets {
+		secrets = append(secrets, s)
+	}
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+	for _, s := range secrets {
+		if n := strings.Count(text, s); n > 0 {
+			text = strings.Replace(text, s, REDACTED, -1)
+			count += n
+		}
+	}
+
+	for _, re := range textSecretPatterns {
+		text = re.ReplaceAllStringFunc(text, func(match string) string {
+			groups := re.FindStringSubmatch(match)
+			switch strings.ToLower(groups[2]) {
+			case "true", "false", "null", strings.ToLower(REDACTED):
+				return match
+			}
+			count++
+			suffix := ""
+			if len(groups) > 3 {
+				suffix = groups[3]
+			}
+			return groups[1] + REDACTED + suffix
+		})
+	}
+	return text, count
+}
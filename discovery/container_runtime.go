@@ -0,0 +1,37 @@
+package discovery
+
+import (
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/open-horizon/anax/externalpolicy"
+)
+
+// The containerRuntime provider discovers the container runtime of the node. The containerRuntime.name property
+// is always "docker", and containerRuntime.version is the version of the docker engine, e.g. "19.03.8".
+type ContainerRuntimeProvider struct {
+	endpoint string
+}
+
+func NewContainerRuntimeProvider(endpoint string) *ContainerRuntimeProvider {
+	return &ContainerRuntimeProvider{endpoint: endpoint}
+}
+
+func (p *ContainerRuntimeProvider) Name() string {
+	return "containerRuntime"
+}
+
+func (p *ContainerRuntimeProvider) Discover() (externalpolicy.PropertyList, error) {
+	client, err := docker.NewClient(p.endpoint)
+	if err != nil {
+		return nil, err
+	}
+	env, err := client.Version()
+	if err != nil {
+		return nil, err
+	}
+
+	props := externalpolicy.PropertyList{*externalpolicy.Property_Factory("containerRuntime.name", "docker")}
+	if version := env.Get("Version"); version != "" {
+		props = append(props, *externalpolicy.Property_Factory("containerRuntime.version", version))
+	}
+	return props, nil
+}
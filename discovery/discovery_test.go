@@ -0,0 +1,154 @@
+// +build unit
+
+package discovery
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/open-horizon/anax/externalpolicy"
+)
+
+func writeTestFile(t *testing.T, file string, content string, perm os.FileMode) {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		t.Fatalf("unable to create dir for %v, error %v", file, err)
+	} else if err := ioutil.WriteFile(file, []byte(content), perm); err != nil {
+		t.Fatalf("unable to write %v, error %v", file, err)
+	}
+}
+
+func getTestDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "discovery-")
+	if err != nil {
+		t.Fatalf("unable to create temp dir, error %v", err)
+	}
+	return dir
+}
+
+func checkProperty(t *testing.T, props externalpolicy.PropertyList, name string, value interface{}) {
+	if prop, err := props.GetProperty(name); err != nil {
+		t.Errorf("property %v should be in %v", name, props)
+	} else if prop.Value != value {
+		t.Errorf("property %v should be %v, got %v", name, value, prop.Value)
+	}
+}
+
+func Test_USBProvider(t *testing.T) {
+	dir := getTestDir(t)
+	defer os.RemoveAll(dir)
+
+	usb := filepath.Join(dir, "bus", "usb", "devices")
+	writeTestFile(t, filepath.Join(usb, "usb1", "idVendor"), "1d6b\n", 0644)
+	writeTestFile(t, filepath.Join(usb, "usb1", "idProduct"), "0002\n", 0644)
+	writeTestFile(t, filepath.Join(usb, "1-1", "idVendor"), "0403\n", 0644)
+	writeTestFile(t, filepath.Join(usb, "1-1", "idProduct"), "6001\n", 0644)
+	writeTestFile(t, filepath.Join(usb, "1-1", "product"), "FT232R USB UART, rev 2\n", 0644)
+	writeTestFile(t, filepath.Join(usb, "1-2", "idVendor"), "046d\n", 0644)
+	writeTestFile(t, filepath.Join(usb, "1-2", "idProduct"), "0825\n", 0644)
+	writeTestFile(t, filepath.Join(usb, "1-1:1.0", "bInterfaceClass"), "ff\n", 0644)
+
+	tty := filepath.Join(dir, "class", "tty")
+	writeTestFile(t, filepath.Join(tty, "ttyUSB0", "device", "uevent"), "", 0644)
+	writeTestFile(t, filepath.Join(tty, "tty0", "uevent"), "", 0644)
+	writeTestFile(t, filepath.Join(dir, "bus", "platform", "drivers", "serial8250", "uevent"), "", 0644)
+	writeTestFile(t, filepath.Join(tty, "ttyS0", "device", "uevent"), "", 0644)
+	if err := os.Symlink(filepath.Join(dir, "bus", "platform", "drivers", "serial8250"), filepath.Join(tty, "ttyS0", "device", "driver")); err != nil {
+		t.Fatalf("unable to create symlink, error %v", err)
+	}
+
+	props, err := NewUSBProvider(dir).Discover()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	checkProperty(t, props, "usb.devices", "0403:6001,046d:0825")
+	checkProperty(t, props, "usb.products", "FT232R USB UART  rev 2")
+	checkProperty(t, props, "serial.devices", "ttyUSB0")
+	if err := props.Validate(); err != nil {
+		t.Errorf("discovered properties should be valid, error %v", err)
+	}
+
+	// Nothing is discovered without sysfs.
+	if props, err := NewUSBProvider(filepath.Join(dir, "none")).Discover(); err != nil {
+		t.Errorf("unexpected error %v", err)
+	} else if len(props) != 0 {
+		t.Errorf("no properties should be discovered, got %v", props)
+	}
+}
+
+func Test_OSProvider(t *testing.T) {
+	dir := getTestDir(t)
+	defer os.RemoveAll(dir)
+
+	writeTestFile(t, filepath.Join(dir, "etc", "os-release"), "# comment\nNAME=\"Ubuntu\"\nID=ubuntu\nVERSION_ID=\"20.04\"\n", 0644)
+	writeTestFile(t, filepath.Join(dir, "proc", "sys", "kernel", "osrelease"), "5.4.0-42-generic\n", 0644)
+
+	props, err := NewOSProvider(dir).Discover()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	checkProperty(t, props, "os.name", "ubuntu")
+	checkProperty(t, props, "os.version", "20.04")
+	checkProperty(t, props, "os.kernelVersion", "5.4.0-42-generic")
+}
+
+func Test_ExecProvider(t *testing.T) {
+	dir := getTestDir(t)
+	defer os.RemoveAll(dir)
+
+	writeTestFile(t, filepath.Join(dir, "gpu.sh"), "#!/bin/sh\necho '[{\"name\": \"model\", \"value\": \"jetson-nano\"}, {\"name\": \"count\", \"value\": 2}]'\n", 0755)
+	writeTestFile(t, filepath.Join(dir, "bad.sh"), "#!/bin/sh\necho 'not json'\n", 0755)
+	writeTestFile(t, filepath.Join(dir, "fail.sh"), "#!/bin/sh\nexit 1\n", 0755)
+	writeTestFile(t, filepath.Join(dir, "slow.sh"), "#!/bin/sh\nexec sleep 5\n", 0755)
+	writeTestFile(t, filepath.Join(dir, "README"), "[{\"name\": \"x\", \"value\": \"y\"}]", 0644)
+
+	props, err := NewExecProvider(dir, 1).Discover()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	} else if len(props) != 2 {
+		t.Errorf("only the properties from gpu.sh should be discovered, got %v", props)
+	}
+	checkProperty(t, props, "gpu.model", "jetson-nano")
+	checkProperty(t, props, "gpu.count", float64(2))
+}
+
+type testProvider struct {
+	props externalpolicy.PropertyList
+	err   error
+}
+
+func (p *testProvider) Name() string {
+	return "test"
+}
+
+func (p *testProvider) Discover() (externalpolicy.PropertyList, error) {
+	return p.props, p.err
+}
+
+func Test_Discover(t *testing.T) {
+	p := &testProvider{props: externalpolicy.PropertyList{
+		*externalpolicy.Property_Factory("b", "2"),
+		*externalpolicy.Property_Factory("a", "1"),
+	}}
+	previous := make(map[string]externalpolicy.PropertyList)
+
+	props := Discover([]Provider{p}, previous)
+	if len(props) != 2 || props[0].Name != externalpolicy.PROP_NODE_DISCOVERED_PREFIX+"a" || props[1].Name != externalpolicy.PROP_NODE_DISCOVERED_PREFIX+"b" {
+		t.Errorf("properties should be qualified and sorted, got %v", props)
+	}
+
+	// A provider that fails keeps its previous properties.
+	p.props = nil
+	p.err = errors.New("unavailable")
+	if failed := Discover([]Provider{p}, previous); !failed.IsSame(props) {
+		t.Errorf("expected the previous properties %v, got %v", props, failed)
+	}
+
+	// A provider that succeeds with nothing removes its properties.
+	p.err = nil
+	if none := Discover([]Provider{p}, previous); len(none) != 0 {
+		t.Errorf("expected no properties, got %v", none)
+	}
+}
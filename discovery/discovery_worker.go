@@ -0,0 +1,132 @@
+package discovery
+
+import (
+	"fmt"
+
+	"github.com/boltdb/bolt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/eventlog"
+	"github.com/open-horizon/anax/events"
+	"github.com/open-horizon/anax/externalpolicy"
+	"github.com/open-horizon/anax/i18n"
+	"github.com/open-horizon/anax/persistence"
+	"github.com/open-horizon/anax/worker"
+)
+
+// The discovery worker periodically runs the node property discovery providers. When the discovered properties
+// change, the built-in properties of the node policy are updated through the agreement worker, which pushes
+// the node policy to the exchange so that the agbots re-evaluate the node.
+type DiscoveryWorker struct {
+	worker.BaseWorker // embedded field
+	db                *bolt.DB
+	providers         []Provider
+	previous          map[string]externalpolicy.PropertyList // The last successful result of each provider.
+}
+
+// Returns nil if there are no discovery providers configured.
+func NewDiscoveryWorker(name string, cfg *config.HorizonConfig, db *bolt.DB) *DiscoveryWorker {
+
+	if !cfg.Edge.NodeDiscovery.IsEnabled() {
+		glog.Info(dlog("no node property discovery providers are configured, not starting the worker"))
+		return nil
+	}
+
+	providers, err := NewProviders(cfg)
+	if err != nil {
+		glog.Errorf(dlog(fmt.Sprintf("unable to create the node property discovery providers, not starting the worker, error: %v", err)))
+		return nil
+	}
+
+	worker := &DiscoveryWorker{
+		BaseWorker: worker.NewBaseWorker(name, cfg, nil),
+		db:         db,
+		providers:  providers,
+		previous:   make(map[string]externalpolicy.PropertyList),
+	}
+
+	glog.Info(dlog(fmt.Sprintf("Starting Node Discovery worker with providers %v", cfg.Edge.NodeDiscovery.Providers)))
+	worker.Start(worker, cfg.Edge.NodeDiscovery.IntervalS)
+	return worker
+}
+
+func (w *DiscoveryWorker) Messages() chan events.Message {
+	return w.BaseWorker.Manager.Messages
+}
+
+func (w *DiscoveryWorker) Initialize() bool {
+	w.discover()
+	return true
+}
+
+func (w *DiscoveryWorker) NewEvent(incoming events.Message) {
+
+	switch incoming.(type) {
+	case *events.NodeShutdownCompleteMessage:
+		msg, _ := incoming.(*events.NodeShutdownCompleteMessage)
+		switch msg.Event().Id {
+		case events.UNCONFIGURE_COMPLETE:
+			w.Commands <- worker.NewTerminateCommand("shutdown")
+		}
+
+	default: //nothing
+	}
+
+	return
+}
+
+func (w *DiscoveryWorker) CommandHandler(command worker.Command) bool {
+	return false
+}
+
+func (w *DiscoveryWorker) NoWorkHandler() {
+	w.discover()
+}
+
+// Run the providers and update the node policy if the discovered properties have changed.
+func (w *DiscoveryWorker) discover() {
+
+	dev, err := persistence.FindExchangeDevice(w.db)
+	if err != nil {
+		glog.Errorf(dlog(fmt.Sprintf("unable to read the node from the local database, error: %v", err)))
+		return
+	} else if dev != nil && dev.IsEdgeCluster() {
+		// The providers discover the host that they run on, which is not the cluster.
+		return
+	}
+
+	props := Discover(w.providers, w.previous)
+	if !externalpolicy.SetDiscoveredProperties(props) {
+		glog.V(5).Infof(dlog("discovered node properties have not changed"))
+		return
+	}
+
+	glog.V(3).Infof(dlog(fmt.Sprintf("discovered node properties changed to %v", props)))
+
+	// Before the node is registered the discovered properties are simply added to the node policy when it is created.
+	if dev == nil || !dev.IsState(persistence.CONFIGSTATE_CONFIGURED) {
+		return
+	}
+
+	eventlog.LogNodeEvent(w.db, persistence.SEVERITY_INFO,
+		persistence.NewMessageMeta(EL_DISC_NODE_PROPERTIES_CHANGED, props.ShortString()),
+		persistence.EC_NODE_PROPERTIES_DISCOVERED, dev.Id, dev.Org, dev.Pattern, dev.Config.State)
+
+	w.Messages() <- events.NewNodeDiscoveryMessage(events.NODE_PROPERTIES_DISCOVERED)
+}
+
+// messages for eventlog
+const (
+	EL_DISC_NODE_PROPERTIES_CHANGED = "Discovered node properties changed to %v."
+)
+
+// This is does nothing useful at run time.
+// This code is only used at compile time to make the eventlog messages get into the catalog so that
+// they can be translated.
+// The event log messages will be saved in English. But the CLI can request them in different languages.
+func MarkI18nMessages() {
+	// get message printer. anax default language is English
+	msgPrinter := i18n.GetMessagePrinter()
+
+	msgPrinter.Sprintf(EL_DISC_NODE_PROPERTIES_CHANGED)
+}
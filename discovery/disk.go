@@ -0,0 +1,31 @@
+package discovery
+
+import (
+	"math"
+
+	"github.com/open-horizon/anax/externalpolicy"
+	"golang.org/x/sys/unix"
+)
+
+// The disk provider discovers the size of a file system in MB, in the disk.totalMB property. The free space is
+// not discovered because it changes all the time.
+type DiskProvider struct {
+	path string
+}
+
+func NewDiskProvider(path string) *DiskProvider {
+	return &DiskProvider{path: path}
+}
+
+func (p *DiskProvider) Name() string {
+	return "disk"
+}
+
+func (p *DiskProvider) Discover() (externalpolicy.PropertyList, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(p.path, &st); err != nil {
+		return nil, err
+	}
+	totalMB := math.Floor(float64(st.Blocks) * float64(st.Bsize) / (1024 * 1024))
+	return externalpolicy.PropertyList{*externalpolicy.Property_Factory("disk.totalMB", totalMB)}, nil
+}
@@ -0,0 +1,93 @@
+package discovery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/externalpolicy"
+)
+
+// The exec provider runs each executable in a directory. An executable writes a JSON property list to stdout,
+// e.g. [{"name": "model", "value": "jetson-nano"}]. The property names are qualified with the name of the
+// executable without its extension, so the executable gpu.sh that discovers the property model adds the
+// property openhorizon.discovered.gpu.model to the node policy. An executable that fails, times out or writes
+// something other than a valid property list is skipped, and the whole provider is not failed because of it.
+type ExecProvider struct {
+	dir     string
+	timeout time.Duration
+}
+
+func NewExecProvider(dir string, timeoutS int) *ExecProvider {
+	return &ExecProvider{dir: dir, timeout: time.Duration(timeoutS) * time.Second}
+}
+
+func (p *ExecProvider) Name() string {
+	return "exec"
+}
+
+func (p *ExecProvider) Discover() (externalpolicy.PropertyList, error) {
+	entries, err := ioutil.ReadDir(p.dir)
+	if os.IsNotExist(err) {
+		return externalpolicy.PropertyList{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	props := externalpolicy.PropertyList{}
+	for _, e := range entries {
+		if !e.Mode().IsRegular() || e.Mode().Perm()&0111 == 0 {
+			continue
+		}
+		file := filepath.Join(p.dir, e.Name())
+		execProps, err := p.run(file)
+		if err != nil {
+			glog.Errorf(dlog(fmt.Sprintf("unable to discover properties with %v, error: %v", file, err)))
+			continue
+		}
+
+		prefix := strings.TrimSuffix(e.Name(), filepath.Ext(e.Name())) + "."
+		for _, prop := range execProps {
+			prop.Name = prefix + prop.Name
+			props = append(props, prop)
+		}
+	}
+	return props, nil
+}
+
+// Run an executable and return the properties that it writes to stdout.
+func (p *ExecProvider) run(file string) (externalpolicy.PropertyList, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, file)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, errors.New(fmt.Sprintf("timed out after %v", p.timeout))
+		}
+		return nil, errors.New(fmt.Sprintf("%v, stderr: %v", err, strings.TrimSpace(stderr.String())))
+	}
+
+	props := externalpolicy.PropertyList{}
+	if err := json.Unmarshal(stdout.Bytes(), &props); err != nil {
+		return nil, errors.New(fmt.Sprintf("output is not a property list, error: %v", err))
+	} else if err := props.Validate(); err != nil {
+		return nil, errors.New(fmt.Sprintf("output is not a valid property list, error: %v", err))
+	}
+	return props, nil
+}
@@ -0,0 +1,67 @@
+package discovery
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/open-horizon/anax/externalpolicy"
+)
+
+// The os provider discovers the operating system of the node. The os.name and os.version properties are the ID
+// and VERSION_ID in /etc/os-release, e.g. "ubuntu" and "20.04", and os.kernelVersion is the kernel release,
+// e.g. "5.4.0-42-generic".
+type OSProvider struct {
+	root string
+}
+
+func NewOSProvider(root string) *OSProvider {
+	return &OSProvider{root: root}
+}
+
+func (p *OSProvider) Name() string {
+	return "os"
+}
+
+func (p *OSProvider) Discover() (externalpolicy.PropertyList, error) {
+	props := externalpolicy.PropertyList{}
+
+	release, err := readOSRelease(filepath.Join(p.root, "etc", "os-release"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if release["ID"] != "" {
+		props = append(props, *externalpolicy.Property_Factory("os.name", release["ID"]))
+	}
+	if release["VERSION_ID"] != "" {
+		props = append(props, *externalpolicy.Property_Factory("os.version", release["VERSION_ID"]))
+	}
+	if kernel := readSysfsAttr(filepath.Join(p.root, "proc", "sys", "kernel", "osrelease")); kernel != "" {
+		props = append(props, *externalpolicy.Property_Factory("os.kernelVersion", kernel))
+	}
+
+	return props, nil
+}
+
+// Parse the KEY=value lines of an os-release file.
+func readOSRelease(file string) (map[string]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	release := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if kv := strings.SplitN(line, "=", 2); len(kv) == 2 {
+			release[kv[0]] = strings.Trim(kv[1], `"'`)
+		}
+	}
+	return release, scanner.Err()
+}
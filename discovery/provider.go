@@ -0,0 +1,88 @@
+package discovery
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/externalpolicy"
+)
+
+// A provider discovers some of the node's properties, e.g. the attached USB devices. The property names are
+// relative to the discovered property name space, e.g. "usb.devices" becomes "openhorizon.discovered.usb.devices".
+// A provider should only return properties whose values change when the node changes, otherwise every discovery
+// would update the node policy.
+type Provider interface {
+	Name() string
+	Discover() (externalpolicy.PropertyList, error)
+}
+
+// Create the providers that are named in the config.
+func NewProviders(cfg *config.HorizonConfig) ([]Provider, error) {
+	providers := make([]Provider, 0, len(cfg.Edge.NodeDiscovery.Providers))
+	for _, name := range cfg.Edge.NodeDiscovery.Providers {
+		switch name {
+		case config.DiscoveryProviderUSB:
+			providers = append(providers, NewUSBProvider(SYSFS_ROOT))
+		case config.DiscoveryProviderDisk:
+			providers = append(providers, NewDiskProvider(cfg.Edge.NodeDiscovery.DiskPath))
+		case config.DiscoveryProviderOS:
+			providers = append(providers, NewOSProvider("/"))
+		case config.DiscoveryProviderContainerRuntime:
+			providers = append(providers, NewContainerRuntimeProvider(cfg.Edge.DockerEndpoint))
+		case config.DiscoveryProviderExec:
+			providers = append(providers, NewExecProvider(cfg.Edge.NodeDiscovery.ExecDir, cfg.Edge.NodeDiscovery.ExecTimeoutS))
+		default:
+			return nil, errors.New(fmt.Sprintf("node property discovery provider %v is not supported, it must be one of %v, %v, %v, %v or %v", name,
+				config.DiscoveryProviderUSB, config.DiscoveryProviderDisk, config.DiscoveryProviderOS, config.DiscoveryProviderContainerRuntime, config.DiscoveryProviderExec))
+		}
+	}
+	return providers, nil
+}
+
+// Run the providers and return all of the discovered properties, with their full names and sorted by name. The
+// previous results are used for the providers that fail so that a transient error does not remove properties
+// from the node policy. The results of the providers that succeed are saved in previous.
+func Discover(providers []Provider, previous map[string]externalpolicy.PropertyList) externalpolicy.PropertyList {
+	all := externalpolicy.PropertyList{}
+	for _, p := range providers {
+		props, err := p.Discover()
+		if err != nil {
+			glog.Errorf(dlog(fmt.Sprintf("provider %v failed, keeping its previous properties %v, error: %v", p.Name(), previous[p.Name()], err)))
+			props = previous[p.Name()]
+		} else {
+			props = qualifyProperties(p.Name(), props)
+			previous[p.Name()] = props
+		}
+		glog.V(5).Infof(dlog(fmt.Sprintf("provider %v discovered %v", p.Name(), props)))
+
+		for _, prop := range props {
+			np := prop
+			if err := all.Add_Property(&np, false); err != nil {
+				glog.Errorf(dlog(fmt.Sprintf("provider %v discovered property %v more than once, error: %v", p.Name(), prop.Name, err)))
+			}
+		}
+	}
+
+	sort.Slice(all, func(i, j int) bool { return all[i].Name < all[j].Name })
+	return all
+}
+
+// Put the provider's properties in the discovered property name space and drop the ones that are not valid.
+func qualifyProperties(provider string, props externalpolicy.PropertyList) externalpolicy.PropertyList {
+	qualified := externalpolicy.PropertyList{}
+	for _, prop := range props {
+		prop.Name = externalpolicy.PROP_NODE_DISCOVERED_PREFIX + prop.Name
+		if err := qualified.Add_Property(&prop, true); err != nil {
+			glog.Errorf(dlog(fmt.Sprintf("provider %v discovered a property that is not valid: %v", provider, err)))
+		}
+	}
+	return qualified
+}
+
+// Utility logging function
+var dlog = func(v interface{}) string {
+	return fmt.Sprintf("Node Discovery: %v", v)
+}
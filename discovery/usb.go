@@ -0,0 +1,126 @@
+package discovery
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/open-horizon/anax/externalpolicy"
+)
+
+// The root of the sysfs file system.
+const SYSFS_ROOT = "/sys"
+
+// The vendor id of the linux root hubs, which are not attached devices.
+const usbLinuxFoundationVendor = "1d6b"
+
+// The usb provider discovers the USB devices and the serial ports that are attached to the node. The usb.devices
+// property is a list of the vendor:product ids of the USB devices, e.g. "0403:6001,1a86:7523", usb.products is
+// a list of their product names, and serial.devices is a list of the serial ports, e.g. "ttyACM0,ttyUSB0".
+type USBProvider struct {
+	sysfs string
+}
+
+func NewUSBProvider(sysfs string) *USBProvider {
+	return &USBProvider{sysfs: sysfs}
+}
+
+func (p *USBProvider) Name() string {
+	return "usb"
+}
+
+func (p *USBProvider) Discover() (externalpolicy.PropertyList, error) {
+	props := externalpolicy.PropertyList{}
+
+	devices, products, err := p.usbDevices()
+	if err != nil {
+		return nil, err
+	}
+	addListProperty(&props, "usb.devices", devices)
+	addListProperty(&props, "usb.products", products)
+
+	serial, err := p.serialDevices()
+	if err != nil {
+		return nil, err
+	}
+	addListProperty(&props, "serial.devices", serial)
+
+	return props, nil
+}
+
+func (p *USBProvider) usbDevices() ([]string, []string, error) {
+	dir := filepath.Join(p.sysfs, "bus", "usb", "devices")
+	entries, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil, nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	devices := []string{}
+	products := []string{}
+	for _, e := range entries {
+		// Interfaces, e.g. 1-1:1.0, do not have their own ids.
+		vendor := readSysfsAttr(filepath.Join(dir, e.Name(), "idVendor"))
+		product := readSysfsAttr(filepath.Join(dir, e.Name(), "idProduct"))
+		if vendor == "" || product == "" || vendor == usbLinuxFoundationVendor {
+			continue
+		}
+		devices = appendUnique(devices, vendor+":"+product)
+		if name := strings.Replace(readSysfsAttr(filepath.Join(dir, e.Name(), "product")), ",", " ", -1); name != "" {
+			products = appendUnique(products, name)
+		}
+	}
+	return devices, products, nil
+}
+
+func (p *USBProvider) serialDevices() ([]string, error) {
+	dir := filepath.Join(p.sysfs, "class", "tty")
+	entries, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	serial := []string{}
+	for _, e := range entries {
+		// Virtual terminals do not have a device, and the legacy 8250 ports are there whether or not anything is attached.
+		if _, err := os.Stat(filepath.Join(dir, e.Name(), "device")); err != nil {
+			continue
+		}
+		if driver, err := os.Readlink(filepath.Join(dir, e.Name(), "device", "driver")); err == nil && filepath.Base(driver) == "serial8250" {
+			continue
+		}
+		serial = appendUnique(serial, e.Name())
+	}
+	return serial, nil
+}
+
+// Returns the trimmed content of a sysfs attribute file, or an empty string if it cannot be read.
+func readSysfsAttr(file string) string {
+	if b, err := ioutil.ReadFile(file); err == nil {
+		return strings.TrimSpace(string(b))
+	}
+	return ""
+}
+
+func appendUnique(list []string, s string) []string {
+	for _, e := range list {
+		if e == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
+
+// Add a sorted list property, unless the list is empty.
+func addListProperty(props *externalpolicy.PropertyList, name string, values []string) {
+	if len(values) == 0 {
+		return
+	}
+	sort.Strings(values)
+	*props = append(*props, externalpolicy.Property{Name: name, Value: strings.Join(values, ","), Type: externalpolicy.LIST_TYPE})
+}
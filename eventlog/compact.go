@@ -0,0 +1,57 @@
+package eventlog
+
+import (
+	"fmt"
+	"github.com/boltdb/bolt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/persistence"
+	"time"
+)
+
+// Remove the event logs that are beyond the retention rules of their severity. The event logs that are referenced by
+// a surfaced error that is not hidden are always kept, so that the node's surfaced errors can still be looked up.
+// It returns the number of event logs that were removed.
+func CompactEventLogs(db *bolt.DB, elConfig *config.EventLogConfig) (int, error) {
+	if len(elConfig.Retention) == 0 {
+		return 0, nil
+	}
+
+	surfaced := make(map[string]bool)
+	if surfaceErrors, err := persistence.FindSurfaceErrors(db); err != nil {
+		return 0, err
+	} else {
+		for _, se := range surfaceErrors {
+			if !se.Hidden {
+				surfaced[se.Record_id] = true
+			}
+		}
+	}
+
+	now := uint64(time.Now().Unix())
+
+	return persistence.PruneEventLogs(db, func(el persistence.EventLogBase, keptNewer int) bool {
+		if surfaced[el.Id] {
+			return true
+		}
+
+		r := elConfig.GetRetention(el.Severity)
+		if r == nil {
+			return true
+		} else if r.MaxCount > 0 && keptNewer >= r.MaxCount {
+			return false
+		} else if r.MaxAgeS > 0 && el.Timestamp+r.MaxAgeS < now {
+			return false
+		}
+		return true
+	})
+}
+
+// Compact the event logs and log the result.
+func compactEventLogs(db *bolt.DB, elConfig *config.EventLogConfig) {
+	if removed, err := CompactEventLogs(db, elConfig); err != nil {
+		glog.Errorf(elwlog(fmt.Sprintf("Error compacting the event logs: %v", err)))
+	} else if removed > 0 {
+		glog.V(3).Infof(elwlog(fmt.Sprintf("Removed %v event logs that were beyond the retention rules.", removed)))
+	}
+}
@@ -0,0 +1,61 @@
+// +build unit
+
+package eventlog
+
+import (
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/persistence"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_CompactEventLogs(t *testing.T) {
+
+	dir, db, err := utsetup()
+	if err != nil {
+		t.Error(err)
+	}
+	defer cleanTestDir(dir)
+
+	src := persistence.NewNodeEventSource("node1", "myorg", "", "configured")
+	old := uint64(time.Now().Unix()) - 7200
+
+	// two old info records, one of them surfaced, and three new warning records.
+	for i, severity := range []string{persistence.SEVERITY_INFO, persistence.SEVERITY_INFO, persistence.SEVERITY_WARN, persistence.SEVERITY_WARN, persistence.SEVERITY_WARN} {
+		el := persistence.NewEventLog(severity, persistence.NewMessageMeta("message %v", i), "test_event", persistence.SRC_TYPE_NODE, *src)
+		if i < 2 {
+			el.Timestamp = old
+		}
+		if err := persistence.SaveEventLog(db, el); err != nil {
+			t.Errorf("error saving event log: %v", err)
+		}
+	}
+	if err := persistence.SaveSurfaceErrors(db, []persistence.SurfaceError{{Record_id: "1"}}); err != nil {
+		t.Errorf("error saving surface errors: %v", err)
+	}
+
+	elConfig := &config.EventLogConfig{
+		Retention: map[string]config.EventLogRetention{
+			persistence.SEVERITY_INFO:           {MaxAgeS: 3600},
+			config.EventLogRetentionAnySeverity: {MaxCount: 2},
+		},
+	}
+
+	removed, err := CompactEventLogs(db, elConfig)
+	assert.Nil(t, err, "Compacting the event logs should not fail.")
+	assert.Equal(t, 2, removed, "The number of removed event logs is wrong.")
+
+	evlogs, err := persistence.FindAllEventLogs(db)
+	assert.Nil(t, err, "Finding the event logs should not fail.")
+	ids := []string{}
+	for _, el := range evlogs {
+		ids = append(ids, el.Id)
+	}
+	assert.ElementsMatch(t, []string{"1", "4", "5"}, ids, "The surfaced and the newest event logs should be kept.")
+
+	// without retention rules nothing is removed.
+	removed, err = CompactEventLogs(db, &config.EventLogConfig{})
+	assert.Nil(t, err, "Compacting the event logs should not fail.")
+	assert.Equal(t, 0, removed, "No event logs should be removed without retention rules.")
+}
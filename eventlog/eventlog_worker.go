@@ -0,0 +1,91 @@
+package eventlog
+
+import (
+	"fmt"
+	"github.com/boltdb/bolt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/events"
+	"github.com/open-horizon/anax/worker"
+)
+
+// The event log worker compacts the event logs according to the retention rules in the config and exports
+// the new event logs as they are saved.
+type EventLogWorker struct {
+	worker.BaseWorker // embedded field
+	db                *bolt.DB
+	exporter          *EventLogExporter
+}
+
+func NewEventLogWorker(name string, config *config.HorizonConfig, db *bolt.DB) *EventLogWorker {
+
+	worker := &EventLogWorker{
+		BaseWorker: worker.NewBaseWorker(name, config, nil),
+		db:         db,
+	}
+
+	glog.Info(elwlog(fmt.Sprintf("Starting EventLog worker")))
+	// Compact the event logs each time the compaction interval passes.
+	worker.Start(worker, config.Edge.EventLog.CompactIntervalS)
+	return worker
+}
+
+func (w *EventLogWorker) Messages() chan events.Message {
+	return w.BaseWorker.Manager.Messages
+}
+
+func (w *EventLogWorker) Initialize() bool {
+	if w.Config.Edge.EventLog.IsExportEnabled() {
+		if exporter, err := StartEventLogExporter(w.db, &w.Config.Edge.EventLog); err != nil {
+			glog.Errorf(elwlog(fmt.Sprintf("Error starting the event log exporter: %v", err)))
+		} else {
+			w.exporter = exporter
+		}
+	}
+
+	// Apply the retention rules right away, the agent might have been down for a while.
+	compactEventLogs(w.db, &w.Config.Edge.EventLog)
+	return true
+}
+
+// Handle events that are propogated to this worker from the internal event bus.
+func (w *EventLogWorker) NewEvent(incoming events.Message) {
+
+	switch incoming.(type) {
+
+	case *events.NodeShutdownCompleteMessage:
+		msg, _ := incoming.(*events.NodeShutdownCompleteMessage)
+		switch msg.Event().Id {
+		case events.UNCONFIGURE_COMPLETE:
+			w.stopExporter()
+			w.Commands <- worker.NewTerminateCommand("shutdown")
+		}
+
+	default: //nothing
+
+	}
+
+	return
+}
+
+// This worker does not have any commands of its own.
+func (w *EventLogWorker) CommandHandler(command worker.Command) bool {
+	return false
+}
+
+// This function gets called when the worker framework has found nothing to do for the compaction interval.
+func (w *EventLogWorker) NoWorkHandler() {
+	compactEventLogs(w.db, &w.Config.Edge.EventLog)
+}
+
+func (w *EventLogWorker) stopExporter() {
+	if w.exporter != nil {
+		w.exporter.Stop()
+		w.exporter = nil
+	}
+}
+
+// Utility logging function
+var elwlog = func(v interface{}) string {
+	return fmt.Sprintf("EventLog Worker: %v", v)
+}
@@ -0,0 +1,233 @@
+package eventlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/boltdb/bolt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/i18n"
+	"github.com/open-horizon/anax/persistence"
+	"golang.org/x/text/message"
+	"log/syslog"
+	"os"
+	"sync"
+)
+
+// The number of event log notifications that can be waiting for the exporter. The notifications only wake the exporter
+// up, the event logs themselves are read from the db, so a notification that does not fit is not a lost event log.
+const EXPORT_BUFFER_SIZE = 100
+
+// The maximum number of event logs that are read from the db at once.
+const EXPORT_BATCH_SIZE = 100
+
+// The exporter writes every new event log as a json line to a size rotated file and/or to the local syslog.
+// The id of the last exported event log is kept in the db, so the event logs that are saved while the exporter
+// is busy or stopped are exported when it gets to them.
+type EventLogExporter struct {
+	db       *bolt.DB
+	elConfig *config.EventLogConfig
+	sub      *persistence.EventLogSubscription
+	cursor   uint64
+	file     *os.File
+	fileSize int64
+	syslog   *syslog.Writer
+	done     sync.WaitGroup
+}
+
+// Create an exporter for the event log config and start exporting the event logs that were saved after the last
+// exported one. The first time the export is enabled, only the event logs saved from then on are exported.
+func StartEventLogExporter(db *bolt.DB, elConfig *config.EventLogConfig) (*EventLogExporter, error) {
+	e := &EventLogExporter{
+		db:       db,
+		elConfig: elConfig,
+	}
+
+	if cursor, found, err := persistence.GetEventLogExportCursor(db); err != nil {
+		return nil, fmt.Errorf("unable to read the event log export cursor, error: %v", err)
+	} else if found {
+		e.cursor = cursor
+	} else if last, err := persistence.GetLastEventLogId(db); err != nil {
+		return nil, fmt.Errorf("unable to read the last event log id, error: %v", err)
+	} else if err := persistence.SaveEventLogExportCursor(db, last); err != nil {
+		return nil, fmt.Errorf("unable to save the event log export cursor, error: %v", err)
+	} else {
+		e.cursor = last
+	}
+
+	if elConfig.ExportFile != "" {
+		if err := e.openFile(); err != nil {
+			return nil, err
+		}
+	}
+
+	if elConfig.ExportSyslog {
+		if w, err := syslog.New(syslog.LOG_INFO|syslog.LOG_DAEMON, elConfig.ExportSyslogTag); err != nil {
+			e.closeFile()
+			return nil, fmt.Errorf("unable to connect to the syslog, error: %v", err)
+		} else {
+			e.syslog = w
+		}
+	}
+
+	e.sub = persistence.SubscribeEventLogs(EXPORT_BUFFER_SIZE)
+	e.done.Add(1)
+	go e.run()
+	return e, nil
+}
+
+// Stop exporting the event logs. The event logs that were saved before it is called are exported before it returns.
+func (e *EventLogExporter) Stop() {
+	e.sub.Close()
+	e.done.Wait()
+	e.closeFile()
+	if e.syslog != nil {
+		e.syslog.Close()
+	}
+}
+
+func (e *EventLogExporter) run() {
+	defer e.done.Done()
+
+	e.exportPending()
+	for range e.sub.C {
+		// one pass over the db covers all the notifications that are waiting.
+		e.drainNotifications()
+		e.exportPending()
+	}
+	e.exportPending()
+}
+
+func (e *EventLogExporter) drainNotifications() {
+	for {
+		select {
+		case _, ok := <-e.sub.C:
+			if !ok {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+// Export the event logs saved after the cursor, moving the cursor forward after each batch.
+func (e *EventLogExporter) exportPending() {
+	msgPrinter := i18n.GetMessagePrinter()
+	for {
+		evlogs, last, err := persistence.FindEventLogsAfter(e.db, e.cursor, EXPORT_BATCH_SIZE)
+		if err != nil {
+			glog.Errorf(elwlog(fmt.Sprintf("Unable to read the event logs after %v for export, error: %v", e.cursor, err)))
+			return
+		} else if last == e.cursor {
+			return
+		}
+
+		for _, el := range evlogs {
+			e.export(el, msgPrinter)
+		}
+
+		e.cursor = last
+		if err := persistence.SaveEventLogExportCursor(e.db, e.cursor); err != nil {
+			glog.Errorf(elwlog(fmt.Sprintf("Unable to save the event log export cursor %v, error: %v", e.cursor, err)))
+		}
+	}
+}
+
+func (e *EventLogExporter) export(el persistence.EventLog, msgPrinter *message.Printer) {
+	// Export the message the same way as it is shown by the event log API.
+	if el.MessageMeta != nil && el.MessageMeta.MessageKey != "" {
+		el.Message = msgPrinter.Sprintf(el.MessageMeta.MessageKey, el.MessageMeta.MessageArgs...)
+		el.MessageMeta = nil
+	}
+
+	line, err := json.Marshal(el)
+	if err != nil {
+		glog.Errorf(elwlog(fmt.Sprintf("Unable to serialize event log %v for export, error: %v", el.Id, err)))
+		return
+	}
+
+	if e.file != nil {
+		if err := e.writeFile(append(line, '\n')); err != nil {
+			glog.Errorf(elwlog(fmt.Sprintf("Unable to export event log %v to %v, error: %v", el.Id, e.elConfig.ExportFile, err)))
+		}
+	}
+
+	if e.syslog != nil {
+		if err := e.writeSyslog(el.Severity, string(line)); err != nil {
+			glog.Errorf(elwlog(fmt.Sprintf("Unable to export event log %v to the syslog, error: %v", el.Id, err)))
+		}
+	}
+}
+
+func (e *EventLogExporter) writeSyslog(severity string, line string) error {
+	switch severity {
+	case persistence.SEVERITY_FATAL:
+		return e.syslog.Crit(line)
+	case persistence.SEVERITY_ERROR:
+		return e.syslog.Err(line)
+	case persistence.SEVERITY_WARN:
+		return e.syslog.Warning(line)
+	default:
+		return e.syslog.Info(line)
+	}
+}
+
+// Append the line to the export file, rotating the file first when the line would make it larger than the maximum size.
+func (e *EventLogExporter) writeFile(line []byte) error {
+	maxSize := int64(e.elConfig.ExportFileMaxSizeMB) * 1024 * 1024
+	if e.fileSize > 0 && e.fileSize+int64(len(line)) > maxSize {
+		if err := e.rotateFile(); err != nil {
+			return err
+		}
+	}
+
+	n, err := e.file.Write(line)
+	e.fileSize += int64(n)
+	return err
+}
+
+func (e *EventLogExporter) openFile() error {
+	f, err := os.OpenFile(e.elConfig.ExportFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("unable to open event log export file %v, error: %v", e.elConfig.ExportFile, err)
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("unable to get the size of event log export file %v, error: %v", e.elConfig.ExportFile, err)
+	}
+
+	e.file = f
+	e.fileSize = fi.Size()
+	return nil
+}
+
+func (e *EventLogExporter) closeFile() {
+	if e.file != nil {
+		e.file.Close()
+		e.file = nil
+	}
+}
+
+// Shift the backups so that the export file becomes file.1, file.1 becomes file.2 and so on. The oldest backup is removed.
+func (e *EventLogExporter) rotateFile() error {
+	e.closeFile()
+
+	name := e.elConfig.ExportFile
+	backups := e.elConfig.ExportFileBackups
+	if backups > 0 {
+		os.Remove(fmt.Sprintf("%v.%v", name, backups))
+		for i := backups - 1; i > 0; i-- {
+			os.Rename(fmt.Sprintf("%v.%v", name, i), fmt.Sprintf("%v.%v", name, i+1))
+		}
+		if err := os.Rename(name, fmt.Sprintf("%v.1", name)); err != nil {
+			return fmt.Errorf("unable to rotate event log export file %v, error: %v", name, err)
+		}
+	} else if err := os.Remove(name); err != nil {
+		return fmt.Errorf("unable to rotate event log export file %v, error: %v", name, err)
+	}
+
+	return e.openFile()
+}
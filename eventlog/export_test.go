@@ -0,0 +1,147 @@
+// +build unit
+
+package eventlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/persistence"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path"
+	"testing"
+)
+
+func Test_EventLogExporter(t *testing.T) {
+
+	dir, db, err := utsetup()
+	if err != nil {
+		t.Error(err)
+	}
+	defer cleanTestDir(dir)
+
+	exportFile := path.Join(dir, "eventlog.json")
+	elConfig := &config.EventLogConfig{
+		ExportFile:          exportFile,
+		ExportFileMaxSizeMB: 1,
+		ExportFileBackups:   1,
+	}
+
+	exporter, err := StartEventLogExporter(db, elConfig)
+	if err != nil {
+		t.Fatalf("error starting the exporter: %v", err)
+	}
+
+	src := persistence.NewNodeEventSource("node1", "myorg", "", "configured")
+	if err := LogNodeEvent(db, persistence.SEVERITY_INFO, persistence.NewMessageMeta("Node %v registered.", "node1"), "node_registered", "node1", "myorg", "", "configured"); err != nil {
+		t.Errorf("error saving event log: %v", err)
+	}
+	exporter.Stop()
+
+	lines := readLines(t, exportFile)
+	assert.Equal(t, 1, len(lines), "One event log should have been exported.")
+	var el persistence.EventLogRaw
+	if err := json.Unmarshal([]byte(lines[0]), &el); err != nil {
+		t.Errorf("error unmarshalling the exported event log: %v", err)
+	}
+	assert.Equal(t, "1", el.Id, "The exported event log id is wrong.")
+	assert.Equal(t, "Node node1 registered.", el.Message, "The exported message should be translated.")
+	assert.Nil(t, el.MessageMeta, "The exported event log should not have the message meta.")
+
+	// a large event log rotates the export file into the backup.
+	exporter, err = StartEventLogExporter(db, elConfig)
+	if err != nil {
+		t.Fatalf("error starting the exporter: %v", err)
+	}
+	big := make([]byte, 1024*1024)
+	for i := range big {
+		big[i] = 'a'
+	}
+	if err := persistence.SaveEventLog(db, persistence.NewEventLog(persistence.SEVERITY_INFO, persistence.NewMessageMeta(string(big)), "big_event", persistence.SRC_TYPE_NODE, *src)); err != nil {
+		t.Errorf("error saving event log: %v", err)
+	}
+	exporter.Stop()
+
+	assert.Equal(t, 1, len(readLines(t, exportFile+".1")), "The export file should have been rotated.")
+	assert.Equal(t, 1, len(readLines(t, exportFile)), "The big event log should be in the new export file.")
+}
+
+func Test_EventLogExporter_cursor(t *testing.T) {
+
+	dir, db, err := utsetup()
+	if err != nil {
+		t.Error(err)
+	}
+	defer cleanTestDir(dir)
+
+	exportFile := path.Join(dir, "eventlog.json")
+	elConfig := &config.EventLogConfig{
+		ExportFile:          exportFile,
+		ExportFileMaxSizeMB: 10,
+		ExportFileBackups:   1,
+	}
+
+	// the event logs saved before the export is enabled for the first time are not exported.
+	if err := LogNodeEvent(db, persistence.SEVERITY_INFO, persistence.NewMessageMeta("Node %v registered.", "node1"), "node_registered", "node1", "myorg", "", "configured"); err != nil {
+		t.Errorf("error saving event log: %v", err)
+	}
+
+	exporter, err := StartEventLogExporter(db, elConfig)
+	if err != nil {
+		t.Fatalf("error starting the exporter: %v", err)
+	}
+	// more event logs than the subscription can buffer.
+	for i := 0; i < 3*EXPORT_BUFFER_SIZE; i++ {
+		if err := LogNodeEvent(db, persistence.SEVERITY_INFO, persistence.NewMessageMeta("Node %v changed.", "node1"), "node_changed", "node1", "myorg", "", "configured"); err != nil {
+			t.Errorf("error saving event log: %v", err)
+		}
+	}
+	exporter.Stop()
+
+	lines := readLines(t, exportFile)
+	assert.Equal(t, 3*EXPORT_BUFFER_SIZE, len(lines), "All the new event logs should have been exported.")
+	var el persistence.EventLogRaw
+	if err := json.Unmarshal([]byte(lines[0]), &el); err != nil {
+		t.Errorf("error unmarshalling the exported event log: %v", err)
+	}
+	assert.Equal(t, "2", el.Id, "The first exported event log should be the first one saved after the export was enabled.")
+
+	// the event logs saved while the exporter is stopped are exported when it starts again.
+	if err := LogNodeEvent(db, persistence.SEVERITY_INFO, persistence.NewMessageMeta("Node %v unregistered.", "node1"), "node_unregistered", "node1", "myorg", "", "unconfigured"); err != nil {
+		t.Errorf("error saving event log: %v", err)
+	}
+	exporter, err = StartEventLogExporter(db, elConfig)
+	if err != nil {
+		t.Fatalf("error starting the exporter: %v", err)
+	}
+	exporter.Stop()
+
+	lines = readLines(t, exportFile)
+	assert.Equal(t, 3*EXPORT_BUFFER_SIZE+1, len(lines), "The event log saved while the exporter was stopped should have been exported.")
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &el); err != nil {
+		t.Errorf("error unmarshalling the exported event log: %v", err)
+	}
+	assert.Equal(t, "Node node1 unregistered.", el.Message, "The wrong event log was exported last.")
+
+	cursor, found, err := persistence.GetEventLogExportCursor(db)
+	assert.Nil(t, err)
+	assert.True(t, found, "The export cursor should have been saved.")
+	assert.Equal(t, uint64(3*EXPORT_BUFFER_SIZE+2), cursor, "The export cursor should be the id of the last event log.")
+}
+
+func readLines(t *testing.T, fileName string) []string {
+	f, err := os.Open(fileName)
+	if err != nil {
+		t.Fatalf("error opening %v: %v", fileName, err)
+	}
+	defer f.Close()
+
+	lines := []string{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines
+}
@@ -55,6 +55,7 @@ const (
 	CANCEL_MICROSERVICE_NETWORK EventId = "CANCEL_MICROSERVICE_NETWORK"
 	NEW_BC_CLIENT               EventId = "NEW_BC_CONTAINER"
 	IMAGE_LOAD_FAILED           EventId = "IMAGE_LOAD_FAILED"
+	WORKLOAD_REPORTED           EventId = "WORKLOAD_REPORTED"
 
 	// policy-related
 	NEW_POLICY             EventId = "NEW_POLICY"
@@ -65,6 +66,9 @@ const (
 	SERVICE_POLICY_CHANGED EventId = "SERVICE_POLICY_CHANGED"
 	SERVICE_POLICY_DELETED EventId = "SERVICE_POLICY_DELETED"
 
+	// node property discovery related
+	NODE_PROPERTIES_DISCOVERED EventId = "NODE_PROPERTIES_DISCOVERED"
+
 	// exchange-related
 	NEW_DEVICE_REG             EventId = "NEW_DEVICE_REG"
 	NEW_DEVICE_CONFIG_COMPLETE EventId = "NEW_DEVICE_CONFIG_COMPLETE"
@@ -75,6 +79,8 @@ const (
 	DEVICE_AGREEMENTS_SYNCED EventId = "DEVICE_AGREEMENTS_SYNCED"
 	DEVICE_CONTAINERS_SYNCED EventId = "DEVICE_CONTAINERS_SYNCED"
 	WORKLOAD_UPGRADE         EventId = "WORKLOAD_UPGRADE"
+	POLICY_RESUMED           EventId = "POLICY_RESUMED"
+	NODE_REEVALUATE          EventId = "NODE_REEVALUATE"
 	PROPOSAL_ACCEPTED        EventId = "PROPOSAL_ACCEPTED"
 
 	// Node related
@@ -89,6 +95,7 @@ const (
 	NODE_PATTERN_CHANGE_SHUTDOWN EventId = "NODE_PATTERN_CHANGE_SHUTDOWN"
 	NODE_PATTERN_CHANGE_REREG    EventId = "NODE_PATTERN_CHANGE_REREG"
 	MESSAGE_STOP                 EventId = "MESSAGE_STOP"
+	ROTATE_MESSAGE_KEY           EventId = "ROTATE_MESSAGE_KEY"
 
 	// Service related
 	SERVICE_SUSPENDED EventId = "SERVICE_SUSPENDED"
@@ -354,6 +361,32 @@ func NewNodePolicyMessage(id EventId) *NodePolicyMessage {
 	}
 }
 
+// This event indicates that the node property discovery providers found a change in the node's properties.
+type NodeDiscoveryMessage struct {
+	event Event
+}
+
+func (e NodeDiscoveryMessage) String() string {
+	return fmt.Sprintf("event: %v", e.event)
+}
+
+func (e NodeDiscoveryMessage) ShortString() string {
+	return e.String()
+}
+
+func (e NodeDiscoveryMessage) Event() Event {
+	return e.event
+}
+
+func NewNodeDiscoveryMessage(id EventId) *NodeDiscoveryMessage {
+
+	return &NodeDiscoveryMessage{
+		event: Event{
+			Id: id,
+		},
+	}
+}
+
 // This event indicates that something happened with node user input.
 type NodeUserInputMessage struct {
 	event        Event
@@ -893,7 +926,7 @@ func NewGovernanceWorkloadCancelationMessage(id EventId, cause EndContractCause,
 	}
 }
 
-//Workload messages
+// Workload messages
 type WorkloadMessage struct {
 	event             Event
 	AgreementProtocol string
@@ -929,7 +962,7 @@ func NewWorkloadMessage(id EventId, protocol string, agreementId string, deploym
 	}
 }
 
-//Container messages
+// Container messages
 type ContainerMessage struct {
 	event         Event
 	LaunchContext ContainerLaunchContext
@@ -961,7 +994,7 @@ func NewContainerMessage(id EventId, lc ContainerLaunchContext, serviceName stri
 	}
 }
 
-//Container stop message
+// Container stop message
 type ContainerStopMessage struct {
 	event         Event
 	ContainerName string
@@ -991,7 +1024,7 @@ func NewContainerStopMessage(id EventId, containerName string, org string) *Cont
 	}
 }
 
-//Container Shutdown message
+// Container Shutdown message
 type ContainerShutdownMessage struct {
 	event         Event
 	ContainerName string
@@ -1119,6 +1152,62 @@ func NewABApiWorkloadUpgradeMessage(id EventId, protocol string, agreementId str
 	}
 }
 
+type ABApiPolicyResumedMessage struct {
+	event      Event
+	PolicyType string
+	PolicyName string
+	PausedAt   uint64
+}
+
+func (m *ABApiPolicyResumedMessage) Event() Event {
+	return m.event
+}
+
+func (m ABApiPolicyResumedMessage) String() string {
+	return fmt.Sprintf("Event: %v, PolicyType: %v, PolicyName: %v, PausedAt: %v", m.event, m.PolicyType, m.PolicyName, m.PausedAt)
+}
+
+func (m ABApiPolicyResumedMessage) ShortString() string {
+	return m.String()
+}
+
+func NewABApiPolicyResumedMessage(id EventId, policyType string, policyName string, pausedAt uint64) *ABApiPolicyResumedMessage {
+	return &ABApiPolicyResumedMessage{
+		event: Event{
+			Id: id,
+		},
+		PolicyType: policyType,
+		PolicyName: policyName,
+		PausedAt:   pausedAt,
+	}
+}
+
+type ABApiNodeReevaluateMessage struct {
+	event  Event
+	NodeId string
+}
+
+func (m *ABApiNodeReevaluateMessage) Event() Event {
+	return m.event
+}
+
+func (m ABApiNodeReevaluateMessage) String() string {
+	return fmt.Sprintf("Event: %v, NodeId: %v", m.event, m.NodeId)
+}
+
+func (m ABApiNodeReevaluateMessage) ShortString() string {
+	return m.String()
+}
+
+func NewABApiNodeReevaluateMessage(id EventId, nodeId string) *ABApiNodeReevaluateMessage {
+	return &ABApiNodeReevaluateMessage{
+		event: Event{
+			Id: id,
+		},
+		NodeId: nodeId,
+	}
+}
+
 // Initialization and restart messages
 type InitAgreementCancelationMessage struct {
 	event             Event
@@ -1712,6 +1801,31 @@ func NewNodeShutdownMessage(id EventId, blocking bool, removeNode bool) *NodeShu
 	}
 }
 
+// Rotate the messaging key of the node or agbot.
+type MessageKeyRotateMessage struct {
+	event Event
+}
+
+func (m *MessageKeyRotateMessage) Event() Event {
+	return m.event
+}
+
+func (m MessageKeyRotateMessage) String() string {
+	return m.ShortString()
+}
+
+func (m MessageKeyRotateMessage) ShortString() string {
+	return fmt.Sprintf("Event: %v", m.event)
+}
+
+func NewMessageKeyRotateMessage(id EventId) *MessageKeyRotateMessage {
+	return &MessageKeyRotateMessage{
+		event: Event{
+			Id: id,
+		},
+	}
+}
+
 type NodeShutdownCompleteMessage struct {
 	event Event
 	err   string
@@ -2015,3 +2129,32 @@ func GetLaunchContext(launchContext interface{}) LaunchContext {
 	}
 	return nil
 }
+
+// A service reported a change in its health or status through the workload API.
+type WorkloadReportMessage struct {
+	event          Event
+	Report         persistence.WorkloadReport
+	PreviousHealth string // The health of the previous report, empty if this is the first report of the service.
+}
+
+func (w *WorkloadReportMessage) Event() Event {
+	return w.event
+}
+
+func (w *WorkloadReportMessage) String() string {
+	return w.ShortString()
+}
+
+func (w *WorkloadReportMessage) ShortString() string {
+	return fmt.Sprintf("Event: %v, Report: %v, PreviousHealth: %v", w.event, w.Report, w.PreviousHealth)
+}
+
+func NewWorkloadReportMessage(id EventId, report persistence.WorkloadReport, previousHealth string) *WorkloadReportMessage {
+	return &WorkloadReportMessage{
+		event: Event{
+			Id: id,
+		},
+		Report:         report,
+		PreviousHealth: previousHealth,
+	}
+}
@@ -177,3 +177,39 @@ func GetAgbotPatterns(ec ExchangeContext) (map[string]ServedPattern, error) {
 	}
 
 }
+
+// Get the agbot resource of another agbot, or of this agbot, from the exchange.
+func GetAgbot(ec ExchangeContext, agbotId string) (*Agbot, error) {
+
+	var resp interface{}
+	resp = new(GetAgbotsResponse)
+	targetURL := ec.GetExchangeURL() + "orgs/" + GetOrg(agbotId) + "/agbots/" + GetId(agbotId)
+
+	httpClientFactory := ec.GetHTTPFactory()
+	retryCount := httpClientFactory.RetryCount
+	retryInterval := httpClientFactory.GetRetryInterval()
+
+	for {
+		if err, tpErr := InvokeExchange(httpClientFactory.NewHTTPClient(nil), "GET", targetURL, ec.GetExchangeId(), ec.GetExchangeToken(), nil, &resp); err != nil {
+			glog.Errorf(rpclogString(err.Error()))
+			return nil, err
+		} else if tpErr != nil {
+			glog.Warningf(rpclogString(tpErr.Error()))
+			if httpClientFactory.RetryCount == 0 {
+				time.Sleep(time.Duration(retryInterval) * time.Second)
+				continue
+			} else if retryCount == 0 {
+				return nil, fmt.Errorf("exceeded %v retries trying to retrieve agbot %v for %v", httpClientFactory.RetryCount, agbotId, tpErr)
+			} else {
+				retryCount--
+				time.Sleep(time.Duration(retryInterval) * time.Second)
+				continue
+			}
+		} else if ag, there := resp.(*GetAgbotsResponse).Agbots[agbotId]; !there {
+			return nil, fmt.Errorf("agbot %v not in GET response %v as expected", agbotId, resp)
+		} else {
+			glog.V(5).Infof(rpclogString(fmt.Sprintf("retrieved agbot %v from exchange %v", agbotId, ag.ShortString())))
+			return &ag, nil
+		}
+	}
+}
@@ -214,7 +214,6 @@ func UpdateCache(resourceKey string, resourceType string, updatedResource interf
 	}
 
 	ExchangeResourceCache.Lock.Lock()
-	defer ExchangeResourceCache.Lock.Unlock()
 
 	resourceCache, ok := ExchangeResourceCache.allResources[resourceType]
 	if !ok {
@@ -238,10 +237,17 @@ func UpdateCache(resourceKey string, resourceType string, updatedResource interf
 	if existingRecord == nil || !bytes.Equal(existingRecordTyped.Hash, recordHash) {
 		newRecord := CacheEntry{Resource: updatedResource, LastUpdated: uint64(time.Now().Unix()), Hash: recordHash}
 		resourceCache.Put(resourceKey, newRecord)
+		ExchangeResourceCache.Lock.Unlock()
+
+		// Write the local database copy after releasing the cache lock so cache readers don't wait on disk I/O.
+		persistCacheEntry(resourceType, resourceKey, newRecord)
 		return
 	}
 	existingRecordTyped.LastUpdated = uint64(time.Now().Unix())
 	resourceCache.Put(resourceKey, existingRecordTyped)
+	ExchangeResourceCache.Lock.Unlock()
+
+	refreshPersistedCacheEntry(resourceType, resourceKey, existingRecordTyped)
 }
 
 // DeleteCache will delete the entire cache for this type of resource
@@ -253,11 +259,12 @@ func DeleteCache(resourceType string) {
 	}
 
 	ExchangeResourceCache.Lock.Lock()
-	defer ExchangeResourceCache.Lock.Unlock()
-
 	if _, ok := ExchangeResourceCache.allResources[resourceType]; ok {
 		delete(ExchangeResourceCache.allResources, resourceType)
 	}
+	ExchangeResourceCache.Lock.Unlock()
+
+	unpersistCacheType(resourceType)
 }
 
 // DeleteCacheResource will delete the cached resource specified if it is in the cache
@@ -272,13 +279,14 @@ func DeleteCacheResource(resourceType string, resourceKey string) interface{} {
 	retResource = nil
 
 	ExchangeResourceCache.Lock.Lock()
-	defer ExchangeResourceCache.Lock.Unlock()
-
 	if resourceCache, ok := ExchangeResourceCache.allResources[resourceType]; ok {
 		retResource = resourceCache.Get(resourceKey)
 
 		resourceCache.Delete(resourceKey)
 	}
+	ExchangeResourceCache.Lock.Unlock()
+
+	unpersistCacheEntry(resourceType, resourceKey)
 	return retResource
 }
 
@@ -290,8 +298,6 @@ func DeleteOrgCachedResources(org string) {
 	}
 
 	ExchangeResourceCache.Lock.Lock()
-	defer ExchangeResourceCache.Lock.Unlock()
-
 	for _, cache := range ExchangeResourceCache.allResources {
 		orgResourceKeys := cache.GetKeys()
 		for _, orgResourceKey := range orgResourceKeys {
@@ -300,6 +306,9 @@ func DeleteOrgCachedResources(org string) {
 			}
 		}
 	}
+	ExchangeResourceCache.Lock.Unlock()
+
+	unpersistCacheOrg(org)
 }
 
 // DeleteCacheNodeWriteThru will delete the given node and return the typed node resource
@@ -442,8 +451,8 @@ func hashResource(resource interface{}) ([]byte, error) {
 func ClearAllResourceCache() {
 	if ExchangeResourceCache != nil && ExchangeResourceCache.allResources != nil {
 		ExchangeResourceCache.Lock.Lock()
-		defer ExchangeResourceCache.Lock.Unlock()
-
 		ExchangeResourceCache.allResources = map[string]cache.Cache{}
+		ExchangeResourceCache.Lock.Unlock()
 	}
+	unpersistAllCache()
 }
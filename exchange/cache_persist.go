@@ -0,0 +1,416 @@
+package exchange
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/boltdb/bolt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/cache"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The persistent tier of the exchange resource cache. When it is enabled, every entry written to the in-memory
+// cache is also written to the node's local bolt DB. On agent restart, entries that are younger than the configured
+// TTL are restored into the in-memory cache so that the agent does not have to fetch them again. When the exchange
+// still cannot be reached after the configured retries, the persisted entries that are younger than the TTL are
+// returned, so that already agreed workloads can continue to run during an exchange outage. Older entries are never
+// used. Entries are removed from both tiers by the /changes processing in DeleteCacheResourceFromChange.
+//
+// An unchanged resource is not written again each time it is read from the exchange. Only its persisted last updated
+// time is moved forward, and only once it is older than half the TTL, so the persisted entry stays usable for at
+// least half the TTL after the last successful read from the exchange.
+
+const EXCHANGE_CACHE = "exchange_cache"
+
+// The separator between the resource type and resource key in the bolt key of a persisted cache entry.
+const persistKeySeparator = "|"
+
+type PersistentResourceCache struct {
+	db   *bolt.DB
+	ttlS uint64
+	lock sync.Mutex
+}
+
+// The persistent tier of the top-level cache, nil when the persistent tier is not enabled.
+var PersistentExchangeResourceCache *PersistentResourceCache
+
+// This is the on-disk form of a CacheEntry. The resource is kept in its json form until the resource type is known.
+type persistedCacheEntry struct {
+	Resource    json.RawMessage `json:"resource"`
+	LastUpdated uint64          `json:"lastupdated"`
+	Hash        []byte          `json:"hash"`
+}
+
+// InitPersistentResourceCache enables the persistent tier of the exchange cache and restores all persisted entries that
+// are younger than ttlS seconds into the in-memory cache. It returns the number of restored entries.
+func InitPersistentResourceCache(db *bolt.DB, ttlS uint64) (int, error) {
+	if db == nil {
+		return 0, fmt.Errorf("unable to initialize the persistent exchange cache, no database")
+	}
+
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(EXCHANGE_CACHE))
+		return err
+	}); err != nil {
+		return 0, fmt.Errorf("unable to create the persistent exchange cache bucket, error %v", err)
+	}
+
+	PersistentExchangeResourceCache = &PersistentResourceCache{db: db, ttlS: ttlS}
+
+	// Deletions from the persistent tier are driven through the in-memory cache, so it has to exist even if nothing is restored.
+	if ExchangeResourceCache == nil {
+		newExchangeResourceCache := NewResourceCache()
+		ExchangeResourceCache = &newExchangeResourceCache
+	}
+
+	restored := 0
+	now := uint64(time.Now().Unix())
+	err := PersistentExchangeResourceCache.forEach(func(resourceType string, resourceKey string, entry *CacheEntry) {
+		if ttlS != 0 && now-entry.LastUpdated > ttlS {
+			glog.V(5).Infof("Persisted exchange cache resource %s/%s is older than %v seconds, it will not be used", resourceType, resourceKey, ttlS)
+			return
+		}
+		restoreCacheEntry(resourceType, resourceKey, *entry)
+		restored++
+	})
+
+	glog.V(3).Infof("Restored %v exchange cache resources from the local database.", restored)
+	return restored, err
+}
+
+// Put the given entry into the in-memory cache, without touching the persistent tier.
+func restoreCacheEntry(resourceType string, resourceKey string, entry CacheEntry) {
+	if ExchangeResourceCache == nil {
+		newExchangeResourceCache := NewResourceCache()
+		ExchangeResourceCache = &newExchangeResourceCache
+	}
+
+	ExchangeResourceCache.Lock.Lock()
+	defer ExchangeResourceCache.Lock.Unlock()
+
+	resourceCache, ok := ExchangeResourceCache.allResources[resourceType]
+	if !ok {
+		resourceCache = cache.NewSimpleMapCache()
+		ExchangeResourceCache.allResources[resourceType] = resourceCache
+	}
+	resourceCache.Put(resourceKey, entry)
+}
+
+// GetStaleResourceFromCache returns the requested resource from the persistent tier of the cache, or nil if it is not
+// present or older than the TTL. This should only be used when the exchange cannot be reached after the retries.
+func GetStaleResourceFromCache(resourceKey string, resourceType string) interface{} {
+	if PersistentExchangeResourceCache == nil {
+		return nil
+	}
+
+	entry, err := PersistentExchangeResourceCache.get(resourceType, resourceKey)
+	if err != nil {
+		glog.Errorf("Error reading exchange cache resource %s/%s from the local database: %v", resourceType, resourceKey, err)
+		return nil
+	} else if entry == nil {
+		return nil
+	} else if ttlS := PersistentExchangeResourceCache.ttlS; ttlS != 0 && uint64(time.Now().Unix())-entry.LastUpdated > ttlS {
+		glog.V(3).Infof("Not using exchange cache resource %s/%s from the local database, it is older than %v seconds", resourceType, resourceKey, ttlS)
+		return nil
+	}
+	glog.V(3).Infof("Using exchange cache resource %s/%s last updated at %v from the local database.", resourceType, resourceKey, entry.LastUpdated)
+	return entry.Copy()
+}
+
+// Save the given entry to the persistent tier.
+func persistCacheEntry(resourceType string, resourceKey string, entry CacheEntry) {
+	if PersistentExchangeResourceCache == nil {
+		return
+	}
+	if err := PersistentExchangeResourceCache.put(resourceType, resourceKey, entry); err != nil {
+		glog.Errorf("Error saving exchange cache resource %s/%s to the local database: %v", resourceType, resourceKey, err)
+	}
+}
+
+// Save the given entry to the persistent tier when its resource has not changed in the in-memory cache. The entry is only
+// written if the persisted one is missing or different, and only the last updated time is written if the persisted one
+// is older than half the TTL. Without a TTL the persisted entry never expires, so its last updated time is not written.
+func refreshPersistedCacheEntry(resourceType string, resourceKey string, entry CacheEntry) {
+	p := PersistentExchangeResourceCache
+	if p == nil {
+		return
+	}
+	if persisted, err := p.getPersisted(resourceType, resourceKey); err != nil {
+		glog.Errorf("Error reading exchange cache resource %s/%s from the local database: %v", resourceType, resourceKey, err)
+	} else if persisted == nil || !bytes.Equal(persisted.Hash, entry.Hash) {
+		persistCacheEntry(resourceType, resourceKey, entry)
+	} else if p.ttlS != 0 && entry.LastUpdated > persisted.LastUpdated+p.ttlS/2 {
+		if err := p.touch(resourceType, resourceKey, entry.LastUpdated); err != nil {
+			glog.Errorf("Error saving exchange cache resource %s/%s to the local database: %v", resourceType, resourceKey, err)
+		}
+	}
+}
+
+// Remove the given entry from the persistent tier.
+func unpersistCacheEntry(resourceType string, resourceKey string) {
+	if PersistentExchangeResourceCache == nil {
+		return
+	}
+	if err := PersistentExchangeResourceCache.deleteKey(resourceType, resourceKey); err != nil {
+		glog.Errorf("Error deleting exchange cache resource %s/%s from the local database: %v", resourceType, resourceKey, err)
+	}
+}
+
+// Remove all entries of the given resource type from the persistent tier.
+func unpersistCacheType(resourceType string) {
+	if PersistentExchangeResourceCache == nil {
+		return
+	}
+	if err := PersistentExchangeResourceCache.delete(func(rType string, rKey string) bool {
+		return rType == resourceType
+	}); err != nil {
+		glog.Errorf("Error deleting exchange cache %s from the local database: %v", resourceType, err)
+	}
+}
+
+// Remove all entries from the given org from the persistent tier.
+func unpersistCacheOrg(org string) {
+	if PersistentExchangeResourceCache == nil {
+		return
+	}
+	if err := PersistentExchangeResourceCache.delete(func(rType string, rKey string) bool {
+		return strings.Index(rKey, fmt.Sprintf("%s/", org)) == 0
+	}); err != nil {
+		glog.Errorf("Error deleting exchange cache resources for org %s from the local database: %v", org, err)
+	}
+}
+
+// Remove everything from the persistent tier.
+func unpersistAllCache() {
+	if PersistentExchangeResourceCache == nil {
+		return
+	}
+	if err := PersistentExchangeResourceCache.delete(func(rType string, rKey string) bool { return true }); err != nil {
+		glog.Errorf("Error deleting the exchange cache from the local database: %v", err)
+	}
+}
+
+func persistKey(resourceType string, resourceKey string) []byte {
+	return []byte(resourceType + persistKeySeparator + resourceKey)
+}
+
+func splitPersistKey(key []byte) (string, string) {
+	pieces := strings.SplitN(string(key), persistKeySeparator, 2)
+	if len(pieces) != 2 {
+		return pieces[0], ""
+	}
+	return pieces[0], pieces[1]
+}
+
+func (p *PersistentResourceCache) get(resourceType string, resourceKey string) (*CacheEntry, error) {
+	var entry *CacheEntry
+	err := p.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(EXCHANGE_CACHE))
+		if b == nil {
+			return nil
+		}
+		v := b.Get(persistKey(resourceType, resourceKey))
+		if v == nil {
+			return nil
+		}
+		var err error
+		entry, err = decodePersistedCacheEntry(resourceType, v)
+		return err
+	})
+	return entry, err
+}
+
+// Returns the persisted form of an entry without decoding its resource, or nil if it is not persisted.
+func (p *PersistentResourceCache) getPersisted(resourceType string, resourceKey string) (*persistedCacheEntry, error) {
+	var persisted *persistedCacheEntry
+	err := p.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(EXCHANGE_CACHE))
+		if b == nil {
+			return nil
+		}
+		v := b.Get(persistKey(resourceType, resourceKey))
+		if v == nil {
+			return nil
+		}
+		persisted = new(persistedCacheEntry)
+		if err := json.Unmarshal(v, persisted); err != nil {
+			return fmt.Errorf("unable to demarshal cache entry, error %v", err)
+		}
+		return nil
+	})
+	return persisted, err
+}
+
+func (p *PersistentResourceCache) put(resourceType string, resourceKey string, entry CacheEntry) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(EXCHANGE_CACHE))
+		if err != nil {
+			return err
+		}
+
+		newEntry := persistedCacheEntry{LastUpdated: entry.LastUpdated, Hash: entry.Hash}
+		if newEntry.Resource, err = json.Marshal(entry.Resource); err != nil {
+			return fmt.Errorf("unable to serialize resource, error %v", err)
+		}
+
+		if serial, err := json.Marshal(newEntry); err != nil {
+			return fmt.Errorf("unable to serialize cache entry, error %v", err)
+		} else {
+			return b.Put(persistKey(resourceType, resourceKey), serial)
+		}
+	})
+}
+
+// Change the last updated time of a persisted entry, keeping its serialized resource.
+func (p *PersistentResourceCache) touch(resourceType string, resourceKey string, lastUpdated uint64) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(EXCHANGE_CACHE))
+		if b == nil {
+			return nil
+		}
+
+		key := persistKey(resourceType, resourceKey)
+		v := b.Get(key)
+		if v == nil {
+			return nil
+		}
+
+		var persisted persistedCacheEntry
+		if err := json.Unmarshal(v, &persisted); err != nil {
+			return fmt.Errorf("unable to demarshal cache entry, error %v", err)
+		}
+		persisted.LastUpdated = lastUpdated
+
+		if serial, err := json.Marshal(persisted); err != nil {
+			return fmt.Errorf("unable to serialize cache entry, error %v", err)
+		} else {
+			return b.Put(key, serial)
+		}
+	})
+}
+
+// Delete the persisted entry of the given resource, if there is one.
+func (p *PersistentResourceCache) deleteKey(resourceType string, resourceKey string) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(EXCHANGE_CACHE))
+		if b == nil {
+			return nil
+		}
+		return b.Delete(persistKey(resourceType, resourceKey))
+	})
+}
+
+// Delete all persisted entries selected by the given filter function.
+func (p *PersistentResourceCache) delete(selected func(resourceType string, resourceKey string) bool) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(EXCHANGE_CACHE))
+		if b == nil {
+			return nil
+		}
+		keys := [][]byte{}
+		if err := b.ForEach(func(k, v []byte) error {
+			if rType, rKey := splitPersistKey(k); selected(rType, rKey) {
+				keys = append(keys, append([]byte{}, k...))
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		for _, k := range keys {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Call the given function for every persisted entry that can be decoded. Entries that cannot be decoded are skipped.
+func (p *PersistentResourceCache) forEach(fn func(resourceType string, resourceKey string, entry *CacheEntry)) error {
+	return p.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(EXCHANGE_CACHE))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			rType, rKey := splitPersistKey(k)
+			if entry, err := decodePersistedCacheEntry(rType, v); err != nil {
+				glog.Errorf("Unable to restore exchange cache resource %s/%s, error %v", rType, rKey, err)
+			} else {
+				fn(rType, rKey, entry)
+			}
+			return nil
+		})
+	})
+}
+
+// Convert the persisted form of a cache entry back into the typed resource that the cache getters expect.
+func decodePersistedCacheEntry(resourceType string, serial []byte) (*CacheEntry, error) {
+	var persisted persistedCacheEntry
+	if err := json.Unmarshal(serial, &persisted); err != nil {
+		return nil, fmt.Errorf("unable to demarshal cache entry, error %v", err)
+	}
+
+	var resource interface{}
+	var err error
+	switch resourceType {
+	case SVC_DEF_TYPE_CACHE:
+		r := map[string]ServiceDefinition{}
+		err = json.Unmarshal(persisted.Resource, &r)
+		resource = r
+	case SVC_POL_TYPE_CACHE, NODE_POL_TYPE_CACHE:
+		r := ExchangePolicy{}
+		err = json.Unmarshal(persisted.Resource, &r)
+		resource = r
+	case SVC_KEY_TYPE_CACHE:
+		r := map[string]string{}
+		err = json.Unmarshal(persisted.Resource, &r)
+		resource = r
+	case SVC_DOCKAUTH_TYPE_CACHE:
+		r := []ImageDockerAuth{}
+		err = json.Unmarshal(persisted.Resource, &r)
+		resource = r
+	case NODE_DEF_TYPE_CACHE:
+		r := Device{}
+		err = json.Unmarshal(persisted.Resource, &r)
+		resource = r
+	case EXCH_VERS_TYPE_CACHE:
+		r := ""
+		err = json.Unmarshal(persisted.Resource, &r)
+		resource = r
+	case ORG_DEF_TYPE_CACHE:
+		r := Organization{}
+		err = json.Unmarshal(persisted.Resource, &r)
+		resource = r
+	default:
+		return nil, fmt.Errorf("unsupported resource type %v", resourceType)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to demarshal %v resource, error %v", resourceType, err)
+	}
+
+	// Verify that the resource is still the one that was hashed when it was cached.
+	if hash, err := hashResource(resource); err != nil {
+		return nil, err
+	} else if len(persisted.Hash) != 0 && !bytes.Equal(hash, persisted.Hash) {
+		return nil, fmt.Errorf("hash of restored %v resource does not match the cached hash", resourceType)
+	}
+
+	return &CacheEntry{Resource: resource, LastUpdated: persisted.LastUpdated, Hash: persisted.Hash}, nil
+}
@@ -0,0 +1,215 @@
+// +build unit
+
+package exchange
+
+import (
+	"github.com/boltdb/bolt"
+	"github.com/open-horizon/anax/config"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestPersistentCacheRestore(t *testing.T) {
+	dir, db := setupPersistentCacheDB(t)
+	defer cleanupPersistentCacheDB(dir, db)
+
+	if _, err := InitPersistentResourceCache(db, 3600); err != nil {
+		t.Fatalf("Error initializing the persistent cache: %v", err)
+	}
+
+	nodeDef := Device{Name: "test-node-2", Arch: "amd64", NodeType: "device", Pattern: "A Pattern"}
+	UpdateCache(NodeCacheMapKey("userdev", "test-node-2"), NODE_DEF_TYPE_CACHE, nodeDef)
+
+	svcDefs := map[string]ServiceDefinition{}
+	svcDefs["1.0.0"] = ServiceDefinition{Owner: "joe@somecomp.com", URL: "a-persisted-service", Arch: "amd64", Version: "1.0.0", Deployment: "abcdefg12345"}
+	UpdateCache(ServiceCacheMapKey("userdev", "a-persisted-service", "amd64"), SVC_DEF_TYPE_CACHE, svcDefs)
+
+	// Simulate an agent restart.
+	ExchangeResourceCache = nil
+	if restored, err := InitPersistentResourceCache(db, 3600); err != nil {
+		t.Fatalf("Error restoring the persistent cache: %v", err)
+	} else if restored != 2 {
+		t.Errorf("Expected 2 restored resources, got %v", restored)
+	}
+
+	if cachedNodeDef := GetNodeFromCache("userdev", "test-node-2"); cachedNodeDef == nil {
+		t.Errorf("Node was not restored from the persistent cache.")
+	} else if cachedNodeDef.Name != "test-node-2" || cachedNodeDef.Pattern != "A Pattern" {
+		t.Errorf("Restored node %v is different than what was stored.", cachedNodeDef)
+	}
+
+	if cachedSvcDefs := GetServiceFromCache("userdev", "a-persisted-service", "amd64"); cachedSvcDefs == nil {
+		t.Errorf("Service was not restored from the persistent cache.")
+	} else if cachedSvcDefs["1.0.0"].Deployment != "abcdefg12345" {
+		t.Errorf("Restored service %v is different than what was stored.", cachedSvcDefs)
+	}
+
+	// A change from the exchange removes the resource from both tiers.
+	DeleteCacheResourceFromChange(ExchangeChange{OrgID: "userdev", Resource: RESOURCE_NODE, ID: "test-node-2"}, "userdev/test-node-2")
+	if cachedNodeDef := GetNodeFromCache("userdev", "test-node-2"); cachedNodeDef != nil {
+		t.Errorf("Node was not deleted from the in-memory cache.")
+	}
+	if staleNodeDef := GetStaleResourceFromCache(NodeCacheMapKey("userdev", "test-node-2"), NODE_DEF_TYPE_CACHE); staleNodeDef != nil {
+		t.Errorf("Node was not deleted from the persistent cache.")
+	}
+}
+
+func TestPersistentCacheTTL(t *testing.T) {
+	dir, db := setupPersistentCacheDB(t)
+	defer cleanupPersistentCacheDB(dir, db)
+
+	if _, err := InitPersistentResourceCache(db, 1); err != nil {
+		t.Fatalf("Error initializing the persistent cache: %v", err)
+	}
+
+	nodePol := ExchangePolicy{LastUpdated: "yesterday"}
+	UpdateCache(NodeCacheMapKey("userdev", "test-node-3"), NODE_POL_TYPE_CACHE, nodePol)
+
+	// Within the TTL, the entry is available when the exchange is unreachable.
+	if stalePol, ok := GetStaleResourceFromCache(NodeCacheMapKey("userdev", "test-node-3"), NODE_POL_TYPE_CACHE).(ExchangePolicy); !ok {
+		t.Errorf("Node policy is not available as a stale resource.")
+	} else if stalePol.LastUpdated != "yesterday" {
+		t.Errorf("Stale node policy %v is different than what was stored.", stalePol)
+	}
+
+	// Restart after the TTL has expired, the entry is not used anymore.
+	time.Sleep(2 * time.Second)
+	ExchangeResourceCache = nil
+	if restored, err := InitPersistentResourceCache(db, 1); err != nil {
+		t.Fatalf("Error restoring the persistent cache: %v", err)
+	} else if restored != 0 {
+		t.Errorf("Expected no restored resources, got %v", restored)
+	}
+
+	if cachedPol := GetNodePolicyFromCache("userdev", "test-node-3"); cachedPol != nil {
+		t.Errorf("Expired node policy was restored from the persistent cache.")
+	}
+	if stalePol := GetStaleResourceFromCache(NodeCacheMapKey("userdev", "test-node-3"), NODE_POL_TYPE_CACHE); stalePol != nil {
+		t.Errorf("Expired node policy is available as a stale resource.")
+	}
+
+	// Clearing the cache also clears the persistent tier.
+	UpdateCache(NodeCacheMapKey("userdev", "test-node-3"), NODE_POL_TYPE_CACHE, nodePol)
+	ClearAllResourceCache()
+	if stalePol := GetStaleResourceFromCache(NodeCacheMapKey("userdev", "test-node-3"), NODE_POL_TYPE_CACHE); stalePol != nil {
+		t.Errorf("Node policy was not deleted from the persistent cache.")
+	}
+}
+
+// An unchanged resource only moves the persisted last updated time once it is older than half the TTL.
+func TestPersistentCacheUnchangedResource(t *testing.T) {
+	dir, db := setupPersistentCacheDB(t)
+	defer cleanupPersistentCacheDB(dir, db)
+
+	if _, err := InitPersistentResourceCache(db, 3600); err != nil {
+		t.Fatalf("Error initializing the persistent cache: %v", err)
+	}
+
+	key := NodeCacheMapKey("userdev", "test-node-5")
+	nodeDef := Device{Name: "test-node-5", Arch: "amd64", NodeType: "device"}
+	UpdateCache(key, NODE_DEF_TYPE_CACHE, nodeDef)
+
+	persisted, err := PersistentExchangeResourceCache.getPersisted(NODE_DEF_TYPE_CACHE, key)
+	if err != nil || persisted == nil {
+		t.Fatalf("Node was not persisted, error %v", err)
+	}
+
+	// Within half the TTL, the persisted entry is not written again.
+	recent := persisted.LastUpdated - 100
+	if err := PersistentExchangeResourceCache.touch(NODE_DEF_TYPE_CACHE, key, recent); err != nil {
+		t.Fatalf("Error changing the persisted last updated time: %v", err)
+	}
+	UpdateCache(key, NODE_DEF_TYPE_CACHE, nodeDef)
+	if persisted, err := PersistentExchangeResourceCache.getPersisted(NODE_DEF_TYPE_CACHE, key); err != nil || persisted == nil {
+		t.Errorf("Node is not persisted anymore, error %v", err)
+	} else if persisted.LastUpdated != recent {
+		t.Errorf("Expected persisted last updated time %v, got %v", recent, persisted.LastUpdated)
+	}
+
+	// Older than half the TTL, the persisted last updated time is moved forward.
+	old := persisted.LastUpdated - 2000
+	if err := PersistentExchangeResourceCache.touch(NODE_DEF_TYPE_CACHE, key, old); err != nil {
+		t.Fatalf("Error changing the persisted last updated time: %v", err)
+	}
+	UpdateCache(key, NODE_DEF_TYPE_CACHE, nodeDef)
+	if persisted, err := PersistentExchangeResourceCache.getPersisted(NODE_DEF_TYPE_CACHE, key); err != nil || persisted == nil {
+		t.Errorf("Node is not persisted anymore, error %v", err)
+	} else if persisted.LastUpdated <= old {
+		t.Errorf("Expected the persisted last updated time to move forward from %v, got %v", old, persisted.LastUpdated)
+	} else if staleNodeDef, ok := GetStaleResourceFromCache(key, NODE_DEF_TYPE_CACHE).(Device); !ok || staleNodeDef.Name != "test-node-5" {
+		t.Errorf("Refreshed node %v is different than what was stored.", staleNodeDef)
+	}
+
+	// A persisted entry that is missing is written again.
+	unpersistCacheEntry(NODE_DEF_TYPE_CACHE, key)
+	UpdateCache(key, NODE_DEF_TYPE_CACHE, nodeDef)
+	if staleNodeDef, ok := GetStaleResourceFromCache(key, NODE_DEF_TYPE_CACHE).(Device); !ok || staleNodeDef.Name != "test-node-5" {
+		t.Errorf("Node was not persisted again, got %v", staleNodeDef)
+	}
+}
+
+// The persisted entries are only used once the retries for an unreachable exchange are used up.
+func TestPersistentCacheExchangeUnreachable(t *testing.T) {
+	dir, db := setupPersistentCacheDB(t)
+	defer cleanupPersistentCacheDB(dir, db)
+
+	if _, err := InitPersistentResourceCache(db, 3600); err != nil {
+		t.Fatalf("Error initializing the persistent cache: %v", err)
+	}
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	httpClientFactory := &config.HTTPClientFactory{
+		NewHTTPClient: func(overrideTimeoutS *uint) *http.Client { return server.Client() },
+		RetryCount:    1,
+		RetryInterval: 1,
+	}
+
+	nodeDef := Device{Name: "test-node-4", Arch: "amd64", NodeType: "device"}
+	UpdateCache(NodeCacheMapKey("userdev", "test-node-4"), NODE_DEF_TYPE_CACHE, nodeDef)
+	dropInMemoryCache(NODE_DEF_TYPE_CACHE)
+
+	if dev, err := GetExchangeDevice(httpClientFactory, "userdev/test-node-4", "userdev/test-node-4", "token", server.URL+"/"); err != nil {
+		t.Errorf("Expected the persisted node, got error %v", err)
+	} else if dev.Name != "test-node-4" {
+		t.Errorf("Persisted node %v is different than what was stored.", dev)
+	} else if requests != 2 {
+		t.Errorf("Expected the persisted node after 2 requests, got %v requests", requests)
+	}
+}
+
+// Remove the resource type from the in-memory cache only, the persistent tier keeps it.
+func dropInMemoryCache(resourceType string) {
+	ExchangeResourceCache.Lock.Lock()
+	defer ExchangeResourceCache.Lock.Unlock()
+	delete(ExchangeResourceCache.allResources, resourceType)
+}
+
+func setupPersistentCacheDB(t *testing.T) (string, *bolt.DB) {
+	dir, err := ioutil.TempDir("", "utdb-")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %v", err)
+	}
+
+	db, err := bolt.Open(path.Join(dir, "anax-ut.db"), 0600, &bolt.Options{Timeout: 10 * time.Second})
+	if err != nil {
+		t.Fatalf("Error opening db: %v", err)
+	}
+	return dir, db
+}
+
+func cleanupPersistentCacheDB(dir string, db *bolt.DB) {
+	PersistentExchangeResourceCache = nil
+	db.Close()
+	os.RemoveAll(dir)
+}
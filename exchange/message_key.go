@@ -0,0 +1,92 @@
+package exchange
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/golang/glog"
+	"time"
+)
+
+// The reasons for a rotation of the messaging key.
+const MESSAGE_KEY_ROTATION_SCHEDULED = "scheduled"
+const MESSAGE_KEY_ROTATION_REQUESTED = "requested"
+
+// PublishMessagingKey sets the current public messaging key of the node or agbot of the exchange context in its exchange
+// resource, so that the messages sent to it from then on are encrypted with that key.
+func PublishMessagingKey(ec ExchangeContext, mailbox string, keyPath string) error {
+	pr := CreateAgbotPublicKeyPatch(keyPath)
+	if bytes.Equal(pr.PublicKey, []byte(`none`)) {
+		return errors.New(fmt.Sprintf("unable to get the public messaging key of %v", ec.GetExchangeId()))
+	}
+
+	var resp interface{}
+	resp = new(PostDeviceResponse)
+	targetURL := ec.GetExchangeURL() + "orgs/" + GetOrg(ec.GetExchangeId()) + "/" + mailbox + "/" + GetId(ec.GetExchangeId())
+
+	httpClientFactory := ec.GetHTTPFactory()
+	retryCount := httpClientFactory.RetryCount
+	retryInterval := httpClientFactory.GetRetryInterval()
+
+	for {
+		if err, tpErr := InvokeExchange(httpClientFactory.NewHTTPClient(nil), "PATCH", targetURL, ec.GetExchangeId(), ec.GetExchangeToken(), pr, &resp); err != nil {
+			glog.Errorf(rpclogString(err.Error()))
+			return err
+		} else if tpErr != nil {
+			glog.Warningf(rpclogString(tpErr.Error()))
+			if httpClientFactory.RetryCount == 0 {
+				time.Sleep(time.Duration(retryInterval) * time.Second)
+				continue
+			} else if retryCount == 0 {
+				return errors.New(fmt.Sprintf("exceeded %v retries trying to publish the messaging key of %v for %v", httpClientFactory.RetryCount, ec.GetExchangeId(), tpErr))
+			} else {
+				retryCount--
+				time.Sleep(time.Duration(retryInterval) * time.Second)
+				continue
+			}
+		} else {
+			glog.V(3).Infof(rpclogString(fmt.Sprintf("published messaging key %v of %v", pr.ShortString(), ec.GetExchangeId())))
+			if mailbox == NODE_MAILBOX {
+				// The cached node has the key from before the patch.
+				DeleteCacheNodeWriteThru(GetOrg(ec.GetExchangeId()), GetId(ec.GetExchangeId()))
+			}
+			return nil
+		}
+	}
+}
+
+// RotateMessagingKey replaces the messaging key of the node or agbot of the exchange context and publishes the new public
+// key to the exchange. The old key can still decrypt messages for graceS seconds. When the key was rotated but could not
+// be published, the error says so and the caller should publish it again later (see IsPreviousPublicKey).
+func RotateMessagingKey(ec ExchangeContext, mailbox string, keyPath string, graceS int) error {
+	if _, err := RotateKeys(keyPath, graceS); err != nil {
+		return errors.New(fmt.Sprintf("unable to rotate the messaging key of %v, error: %v", ec.GetExchangeId(), err))
+	} else if err := PublishMessagingKey(ec, mailbox, keyPath); err != nil {
+		return errors.New(fmt.Sprintf("rotated the messaging key of %v but unable to publish it, error: %v", ec.GetExchangeId(), err))
+	}
+	return nil
+}
+
+// Returns true if the rotation of the messaging key is due. A key that was never rotated is as old as its files.
+func MessagingKeyRotationDue(keyPath string, intervalS int) bool {
+	if intervalS <= 0 {
+		return false
+	} else if created, err := GetKeysCreated(keyPath); err != nil {
+		glog.Errorf(rpclogString(fmt.Sprintf("unable to get the age of the messaging key, error: %v", err)))
+		return false
+	} else {
+		return time.Since(created) >= time.Duration(intervalS)*time.Second
+	}
+}
+
+// Returns true if the serialized public key is the key from before the last rotation of the messaging key, i.e. the
+// exchange still has the old key because the new key could not be published.
+func IsPreviousPublicKey(pubKey []byte) bool {
+	if previousKey := GetPreviousPrivateKey(); previousKey == nil {
+		return false
+	} else if b, err := MarshalPublicKey(&previousKey.PublicKey); err != nil {
+		return false
+	} else {
+		return bytes.Equal(b, pubKey)
+	}
+}
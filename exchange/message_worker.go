@@ -15,9 +15,12 @@ import (
 )
 
 type ExchangeMessageWorker struct {
-	worker.BaseWorker // embedded field
-	db                *bolt.DB
-	config            *config.HorizonConfig
+	worker.BaseWorker  // embedded field
+	db                 *bolt.DB
+	config             *config.HorizonConfig
+	push               PushTransport // The transport that pushes messages to and from the agbots, nil when no broker is configured.
+	endPoint           string        // The msgEndPoint of the node in the exchange.
+	endPointAdvertised bool          // True when endPoint is known to be the msgEndPoint of the node in the exchange.
 }
 
 func NewExchangeMessageWorker(name string, cfg *config.HorizonConfig, db *bolt.DB) *ExchangeMessageWorker {
@@ -59,11 +62,13 @@ func (w *ExchangeMessageWorker) NewEvent(incoming events.Message) {
 	case *events.EdgeRegisteredExchangeMessage:
 		msg, _ := incoming.(*events.EdgeRegisteredExchangeMessage)
 		w.EC = worker.NewExchangeContext(fmt.Sprintf("%v/%v", msg.Org(), msg.DeviceId()), msg.Token(), w.Config.Edge.ExchangeURL, w.Config.GetCSSURL(), newLimitedRetryHTTPFactory(w.Config.Collaborators.HTTPClientFactory))
+		w.Commands <- NewPushTransportCommand(true)
 
 	case *events.NodeShutdownCompleteMessage:
 		msg, _ := incoming.(*events.NodeShutdownCompleteMessage)
 		switch msg.Event().Id {
 		case events.UNCONFIGURE_COMPLETE:
+			w.Commands <- NewPushTransportCommand(false)
 			w.Commands <- worker.NewTerminateCommand("shutdown")
 		}
 
@@ -91,6 +96,9 @@ func (w *ExchangeMessageWorker) NewEvent(incoming events.Message) {
 }
 
 func (w *ExchangeMessageWorker) Initialize() bool {
+	if w.EC != nil {
+		w.startPushTransport()
+	}
 	return true
 }
 
@@ -102,6 +110,17 @@ func (w *ExchangeMessageWorker) CommandHandler(command worker.Command) bool {
 			w.AddDeferredCommand(command)
 		}
 
+	case *PushedMessageCommand:
+		cmd, _ := command.(*PushedMessageCommand)
+		w.handlePushedMessage(&cmd.Msg)
+
+	case *PushTransportCommand:
+		cmd, _ := command.(*PushTransportCommand)
+		w.stopPushTransport()
+		if cmd.Start {
+			w.startPushTransport()
+		}
+
 	default:
 		return false
 	}
@@ -109,6 +128,65 @@ func (w *ExchangeMessageWorker) CommandHandler(command worker.Command) bool {
 }
 
 func (w *ExchangeMessageWorker) NoWorkHandler() {
+	w.advertiseEndPoint()
+}
+
+// Start receiving messages pushed through the MQTT broker, when there is one.
+func (w *ExchangeMessageWorker) startPushTransport() {
+	w.endPointAdvertised = false
+	if w.config.MQTT.IsEnabled() {
+		w.push = NewMQTTTransport(w.config, w, NODE_MAILBOX, func(msg *PushedMessage) {
+			w.Commands <- NewPushedMessageCommand(msg)
+		})
+		SetPushTransport(w.push)
+	}
+	w.advertiseEndPoint()
+}
+
+// Stop receiving pushed messages. The msgEndPoint is removed from the exchange before the transport is closed, so that
+// the agbots send their messages through the exchange instead of to a topic that nobody reads.
+func (w *ExchangeMessageWorker) stopPushTransport() {
+	if push := w.push; push != nil {
+		SetPushTransport(nil)
+		w.push = nil
+		w.advertiseEndPoint()
+		push.Close()
+	}
+}
+
+// Advertise the topic of the push transport as the msgEndPoint of the node while the transport is connected, so that the
+// agbots do not push messages to the node before it has subscribed to its topic, or while it cannot receive them.
+// Otherwise the msgEndPoint is removed, so that the agbots send their messages through the exchange.
+func (w *ExchangeMessageWorker) advertiseEndPoint() {
+	if w.GetExchangeToken() == "" {
+		return
+	}
+
+	endPoint := ""
+	if w.push != nil && w.push.Connected() {
+		endPoint = w.push.EndPoint()
+	}
+
+	if w.endPointAdvertised && endPoint == w.endPoint {
+		return
+	} else if !w.endPointAdvertised && endPoint == "" {
+		// Only remove a msgEndPoint that is in the exchange.
+		if dev, err := GetExchangeDevice(w.GetHTTPFactory(), w.GetExchangeId(), w.GetExchangeId(), w.GetExchangeToken(), w.GetExchangeURL()); err != nil {
+			glog.Errorf(logString(fmt.Sprintf("unable to get the msgEndPoint of the node from the exchange, error: %v", err)))
+			return
+		} else if dev.MsgEndPoint == "" {
+			w.endPoint = ""
+			w.endPointAdvertised = true
+			return
+		}
+	}
+
+	if err := PatchMessageEndPoint(w, NODE_MAILBOX, endPoint); err != nil {
+		glog.Errorf(logString(fmt.Sprintf("unable to advertise msgEndPoint %v, error: %v", endPoint, err)))
+	} else {
+		w.endPoint = endPoint
+		w.endPointAdvertised = true
+	}
 }
 
 func (w *ExchangeMessageWorker) handleMessages() bool {
@@ -127,40 +205,79 @@ func (w *ExchangeMessageWorker) handleMessages() bool {
 
 	// Loop through all the returned messages and process them
 	for _, msg := range msgs {
-
 		glog.V(3).Infof(logString(fmt.Sprintf("reading message %v from the exchange", msg.MsgId)))
+		w.processMessage(&msg)
+	}
+	return true
 
-		// First get my own keys
-		_, myPrivKey, _ := GetKeys("")
-
-		// Deconstruct and decrypt the message. If there is a problem with the message, it will be deleted.
-		deleteMessage := true
-		if protocolMessage, receivedPubKey, err := DeconstructExchangeMessage(msg.Message, myPrivKey); err != nil {
-			glog.Errorf(logString(fmt.Sprintf("unable to deconstruct exchange message %v, error %v", msg, err)))
-		} else if serializedPubKey, err := MarshalPublicKey(receivedPubKey); err != nil {
-			glog.Errorf(logString(fmt.Sprintf("unable to marshal the key from the encrypted message %v, error %v", receivedPubKey, err)))
-		} else if bytes.Compare(msg.AgbotPubKey, serializedPubKey) != 0 {
-			glog.Errorf(logString(fmt.Sprintf("sender public key from exchange %v is not the same as the sender public key in the encrypted message %v", msg.AgbotPubKey, serializedPubKey)))
-		} else if mBytes, err := json.Marshal(msg); err != nil {
-			glog.Errorf(logString(fmt.Sprintf("error marshalling message %v, error: %v", msg, err)))
-		} else {
-			// The message seems to be good, so don't delete it yet, the worker that handles the message will delete it.
-			deleteMessage = false
+}
 
-			// Send the message to all workers.
-			em := events.NewExchangeDeviceMessage(events.RECEIVED_EXCHANGE_DEV_MSG, msg.AgbotId, mBytes, string(protocolMessage))
-			w.Messages() <- em
-		}
+// Decrypt a message from an agbot and send it out as an event. It returns false when the public key of the sender in the
+// message is not the one in the DeviceMessage.
+func (w *ExchangeMessageWorker) processMessage(msg *DeviceMessage) bool {
+
+	// First get my own keys
+	_, myPrivKey, _ := GetKeys("")
+
+	// Deconstruct and decrypt the message. If there is a problem with the message, it will be deleted.
+	deleteMessage := true
+	senderKeyMatches := true
+	if protocolMessage, receivedPubKey, err := DeconstructExchangeMessage(msg.Message, myPrivKey); err != nil {
+		glog.Errorf(logString(fmt.Sprintf("unable to deconstruct exchange message %v, error %v", msg, err)))
+	} else if serializedPubKey, err := MarshalPublicKey(receivedPubKey); err != nil {
+		glog.Errorf(logString(fmt.Sprintf("unable to marshal the key from the encrypted message %v, error %v", receivedPubKey, err)))
+	} else if bytes.Compare(msg.AgbotPubKey, serializedPubKey) != 0 {
+		glog.Errorf(logString(fmt.Sprintf("sender public key from exchange %v is not the same as the sender public key in the encrypted message %v", msg.AgbotPubKey, serializedPubKey)))
+		senderKeyMatches = false
+	} else if mBytes, err := json.Marshal(msg); err != nil {
+		glog.Errorf(logString(fmt.Sprintf("error marshalling message %v, error: %v", msg, err)))
+	} else {
+		// The message seems to be good, so don't delete it yet, the worker that handles the message will delete it.
+		deleteMessage = false
+
+		// Send the message to all workers.
+		em := events.NewExchangeDeviceMessage(events.RECEIVED_EXCHANGE_DEV_MSG, msg.AgbotId, mBytes, string(protocolMessage))
+		w.Messages() <- em
+	}
 
-		// If anything went wrong trying to decrypt the message or verify its origin, etc, just delete it. These errors aren't
-		// expected to be retryable.
-		if deleteMessage {
-			w.deleteMessage(&msg)
+	// If anything went wrong trying to decrypt the message or verify its origin, etc, just delete it. These errors aren't
+	// expected to be retryable.
+	if deleteMessage {
+		w.deleteMessage(msg)
+	}
+	return senderKeyMatches
+}
+
+// A message pushed by an agbot is checked with the public key that the exchange has for the agbot, like the messages in
+// the exchange mailbox of the node, to which the exchange adds the key of the sender. The key of the agbot is kept for
+// its next messages, it is read from the exchange again when it expires or when the agbot signed a message with
+// another key, e.g. after it rotated its key.
+func (w *ExchangeMessageWorker) handlePushedMessage(pushed *PushedMessage) {
+	glog.V(3).Infof(logString(fmt.Sprintf("reading message pushed by %v", pushed.SenderId)))
+
+	pushedMessage := func(pubKey []byte) *DeviceMessage {
+		return &DeviceMessage{
+			MsgId:       PUSHED_MESSAGE_ID,
+			AgbotId:     pushed.SenderId,
+			AgbotPubKey: pubKey,
+			Message:     pushed.Message,
+			TimeSent:    pushed.TimeSent,
 		}
+	}
 
+	if pubKey := GetMessageSenderKey(pushed.SenderId); pubKey != nil {
+		if w.processMessage(pushedMessage(pubKey)) {
+			return
+		}
+		glog.V(3).Infof(logString(fmt.Sprintf("public key of agbot %v changed, reading it from the exchange", pushed.SenderId)))
 	}
-	return true
 
+	if ag, err := GetAgbot(w, pushed.SenderId); err != nil {
+		glog.Errorf(logString(fmt.Sprintf("unable to get agbot %v of pushed message from the exchange, error: %v", pushed.SenderId, err)))
+	} else {
+		SetMessageSender(pushed.SenderId, ag.MsgEndPoint, ag.PublicKey)
+		w.processMessage(pushedMessage(ag.PublicKey))
+	}
 }
 
 func (w *ExchangeMessageWorker) getMessages() ([]DeviceMessage, error) {
@@ -196,6 +313,10 @@ func (w *ExchangeMessageWorker) getMessages() ([]DeviceMessage, error) {
 }
 
 func (w *ExchangeMessageWorker) deleteMessage(msg *DeviceMessage) error {
+	if msg.MsgId == PUSHED_MESSAGE_ID {
+		return nil
+	}
+
 	var resp interface{}
 	resp = new(PostDeviceResponse)
 
@@ -238,6 +359,36 @@ func NewMessageCommand() *MessageCommand {
 	return &MessageCommand{}
 }
 
+// Indicates that a message was pushed to this node.
+type PushedMessageCommand struct {
+	Msg PushedMessage
+}
+
+func (c PushedMessageCommand) ShortString() string {
+	return fmt.Sprintf("PushedMessageCommand %v", c.Msg.String())
+}
+
+func NewPushedMessageCommand(msg *PushedMessage) *PushedMessageCommand {
+	return &PushedMessageCommand{
+		Msg: *msg,
+	}
+}
+
+// Indicates that the push transport has to be stopped, and started again when the node is registered.
+type PushTransportCommand struct {
+	Start bool
+}
+
+func (c PushTransportCommand) ShortString() string {
+	return fmt.Sprintf("PushTransportCommand Start: %v", c.Start)
+}
+
+func NewPushTransportCommand(start bool) *PushTransportCommand {
+	return &PushTransportCommand{
+		Start: start,
+	}
+}
+
 var logString = func(v interface{}) string {
 	return fmt.Sprintf("ExchangeMessageWorker %v", v)
 }
@@ -18,6 +18,7 @@ import (
 	"os"
 	"path"
 	"sync"
+	"time"
 )
 
 // This module is used to construct a message that can be sent over an insecure transport
@@ -228,7 +229,14 @@ func DeconstructExchangeMessage(encryptedMessage []byte, receiverPrivateKey *rsa
 	label := []byte("")
 	var receivedSymValues []byte
 	if receivedSymValues, err = rsa.DecryptOAEP(sha3.New256(), rand.Reader, receiverPrivateKey, em.SymmetricValues, label); err != nil {
-		return nil, nil, errors.New(fmt.Sprintf("Error decrypting Symmetric values from message, error %v", err))
+		// The message might have been encrypted with the receiver's key from before the last key rotation.
+		if previousKey := GetPreviousPrivateKey(); previousKey == nil || previousKey == receiverPrivateKey {
+			return nil, nil, errors.New(fmt.Sprintf("Error decrypting Symmetric values from message, error %v", err))
+		} else if receivedSymValues, err = rsa.DecryptOAEP(sha3.New256(), rand.Reader, previousKey, em.SymmetricValues, label); err != nil {
+			return nil, nil, errors.New(fmt.Sprintf("Error decrypting Symmetric values from message with the current or previous key, error %v", err))
+		} else {
+			glog.V(3).Infof("Decrypted message with the previous messaging key")
+		}
 	}
 
 	sv := new(SymmetricValues)
@@ -376,6 +384,11 @@ func symmetricallyDecrypt(data []byte, key []byte, nonce []byte) ([]byte, error)
 var gPublicKey *rsa.PublicKey
 var gPrivateKey *rsa.PrivateKey
 
+// The private key from before the last rotation of the messaging key, and the time until which it can still decrypt
+// messages.
+var gPreviousPrivateKey *rsa.PrivateKey
+var gPreviousKeyExpires time.Time
+
 func HasKeys() bool {
 	if gPublicKey != nil {
 		return true
@@ -385,6 +398,10 @@ func HasKeys() bool {
 
 var privFileName = "privateMessagingKey.pem"
 var pubFileName = "publicMessagingKey.pem"
+var previousPrivFileName = "previousPrivateMessagingKey.pem"
+
+// The pem header of the previous private key that holds the time (RFC3339) when the key expires.
+const previousKeyExpiresHeader = "Expires"
 
 var KeyLock sync.Mutex
 
@@ -392,111 +409,283 @@ func GetKeys(keyPath string) (*rsa.PublicKey, *rsa.PrivateKey, error) {
 	KeyLock.Lock()
 	defer KeyLock.Unlock()
 
+	return getKeys(keyPath)
+}
+
+// The caller must hold the KeyLock.
+func getKeys(keyPath string) (*rsa.PublicKey, *rsa.PrivateKey, error) {
+
 	if gPublicKey != nil {
 		return gPublicKey, gPrivateKey, nil
 	}
 
-	snap_common := os.Getenv("HZN_VAR_BASE")
-	if len(snap_common) == 0 {
-		snap_common = config.HZN_VAR_BASE_DEFAULT
-	}
-
-	privFilepath := path.Join(snap_common, keyPath, privFileName)
-	pubFilepath := path.Join(snap_common, keyPath, pubFileName)
+	privFilepath, pubFilepath, previousPrivFilepath := keyFilePaths(keyPath)
 	if _, ferr := os.Stat(privFilepath); os.IsNotExist(ferr) {
 
 		if privateKey, err := rsa.GenerateKey(rand.Reader, 2048); err != nil {
 			return nil, nil, errors.New(fmt.Sprintf("Could not generate private key, error %v", err))
-		} else if privFile, err := os.Create(privFilepath); err != nil {
-			return nil, nil, errors.New(fmt.Sprintf("Could not create private key file %v, error %v", privFilepath, err))
-		} else if err := privFile.Chmod(0600); err != nil {
-			return nil, nil, errors.New(fmt.Sprintf("Could not chmod private key file %v, error %v", privFilepath, err))
-		} else if pubFile, err := os.Create(pubFilepath); err != nil {
-			return nil, nil, errors.New(fmt.Sprintf("Could not create public key file %v, error %v", pubFilepath, err))
-		} else if err := pubFile.Chmod(0600); err != nil {
-			return nil, nil, errors.New(fmt.Sprintf("Could not chmod public key file %v, error %v", pubFilepath, err))
+		} else if err := writeKeys(privFilepath, pubFilepath, privateKey); err != nil {
+			return nil, nil, err
 		} else {
-			publicKey := &privateKey.PublicKey
-
-			if pubKeyBytes, err := x509.MarshalPKIXPublicKey(publicKey); err != nil {
-				return nil, nil, errors.New(fmt.Sprintf("Could not marshal public key, error %v", err))
-			} else {
-				pubEnc := &pem.Block{
-					Type:    "PUBLIC KEY",
-					Headers: nil,
-					Bytes:   pubKeyBytes}
-				if err := pem.Encode(pubFile, pubEnc); err != nil {
-					return nil, nil, errors.New(fmt.Sprintf("Could not encode public key to file, error %v", err))
-				} else if err := pubFile.Close(); err != nil {
-					return nil, nil, errors.New(fmt.Sprintf("Could not close public key file %v, error %v", pubFilepath, err))
-				}
-			}
-
-			privEnc := &pem.Block{
-				Type:    "RSA PRIVATE KEY",
-				Headers: nil,
-				Bytes:   x509.MarshalPKCS1PrivateKey(privateKey)}
-			if err := pem.Encode(privFile, privEnc); err != nil {
-				return nil, nil, errors.New(fmt.Sprintf("Could not encode private key to file, error %v", err))
-			} else if err := privFile.Close(); err != nil {
-				return nil, nil, errors.New(fmt.Sprintf("Could not close private key file %v, error %v", privFilepath, err))
-			}
-
-			gPublicKey = publicKey
+			gPublicKey = &privateKey.PublicKey
 			gPrivateKey = privateKey
 		}
 	} else {
-		if _, ferr := os.Stat(pubFilepath); os.IsNotExist(ferr) {
-			return nil, nil, errors.New(fmt.Sprintf("Could not find public key file %v, error %v", privFilepath, ferr))
-		} else if privFile, err := os.Open(privFilepath); err != nil {
-			return nil, nil, errors.New(fmt.Sprintf("Unable to open private key file %v, error: %v", privFilepath, err))
-		} else if privBytes, err := ioutil.ReadAll(privFile); err != nil {
-			return nil, nil, errors.New(fmt.Sprintf("Unable to read private key file %v, error: %v", privFilepath, err))
-		} else if privBlock, _ := pem.Decode(privBytes); privBlock == nil {
-			return nil, nil, errors.New(fmt.Sprintf("Unable to extract pem block from private key file %v, error: %v", privFilepath, err))
-		} else if privateKey, err := x509.ParsePKCS1PrivateKey(privBlock.Bytes); err != nil {
-			return nil, nil, errors.New(fmt.Sprintf("Unable to parse private key %x, error: %v", privBytes, err))
-		} else if pubFile, err := os.Open(pubFilepath); err != nil {
-			return nil, nil, errors.New(fmt.Sprintf("Unable to open public key file %v, error: %v", pubFilepath, err))
-		} else if pubBytes, err := ioutil.ReadAll(pubFile); err != nil {
-			return nil, nil, errors.New(fmt.Sprintf("Unable to read public key file %v, error: %v", pubFilepath, err))
-		} else if pubBlock, _ := pem.Decode(pubBytes); pubBlock == nil {
-			return nil, nil, errors.New(fmt.Sprintf("Unable to extract pem block from public key file %v, error: %v", pubFilepath, err))
-		} else if publicKey, err := x509.ParsePKIXPublicKey(pubBlock.Bytes); err != nil {
-			return nil, nil, errors.New(fmt.Sprintf("Unable to parse public key %x, error: %v", pubBytes, err))
-		} else {
-			gPublicKey = publicKey.(*rsa.PublicKey)
-			gPrivateKey = privateKey
+		publicKey, privateKey, err := readKeys(privFilepath, pubFilepath)
+		if mismatch, ok := err.(*keyMismatchError); ok {
+			// The private key is written first, so it is the key of a rotation that stopped before writing the public key.
+			glog.Warningf(fmt.Sprintf("%v, writing the public key of the private key", err))
+			if err = writePublicKey(pubFilepath, mismatch.privateKey); err == nil {
+				publicKey, privateKey = &mismatch.privateKey.PublicKey, mismatch.privateKey
+			}
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		gPublicKey = publicKey
+		gPrivateKey = privateKey
+
+		// A restart during the grace period of a rotation keeps the previous key.
+		if err := readPreviousKey(previousPrivFilepath); err != nil {
+			glog.Errorf(fmt.Sprintf("Unable to read the previous messaging key, messages encrypted with it cannot be decrypted, error: %v", err))
 		}
 	}
 
 	return gPublicKey, gPrivateKey, nil
 }
 
-func DeleteKeys(keyPath string) error {
-	// Construct the full file path name
+// Returns the paths of the private key, public key and previous private key files.
+func keyFilePaths(keyPath string) (string, string, string) {
 	snap_common := os.Getenv("HZN_VAR_BASE")
 	if len(snap_common) == 0 {
 		snap_common = config.HZN_VAR_BASE_DEFAULT
 	}
 
-	privFilepath := path.Join(snap_common, keyPath, privFileName)
-	pubFilepath := path.Join(snap_common, keyPath, pubFileName)
+	return path.Join(snap_common, keyPath, privFileName), path.Join(snap_common, keyPath, pubFileName), path.Join(snap_common, keyPath, previousPrivFileName)
+}
 
-	glog.V(5).Infof("Removing private key path %v, and public key path %v", privFilepath, pubFilepath)
+// Write the key pair to its files. Each file is written to a temporary file first and then renamed, so that a rotation
+// that fails part way does not leave a damaged key file behind. The private key is written first, a public key file that
+// does not match it is then known to be the one that is out of date.
+func writeKeys(privFilepath string, pubFilepath string, privateKey *rsa.PrivateKey) error {
 
-	// Delete both the private and public key files
-	if _, ferr := os.Stat(privFilepath); !os.IsNotExist(ferr) {
-		if err := os.Remove(privFilepath); err != nil {
-			return err
-		}
+	privEnc := &pem.Block{
+		Type:    "RSA PRIVATE KEY",
+		Headers: nil,
+		Bytes:   x509.MarshalPKCS1PrivateKey(privateKey)}
+
+	if err := writeKeyFile(privFilepath, privEnc); err != nil {
+		return err
+	}
+	return writePublicKey(pubFilepath, privateKey)
+}
+
+func writePublicKey(pubFilepath string, privateKey *rsa.PrivateKey) error {
+
+	pubKeyBytes, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Could not marshal public key, error %v", err))
+	}
+
+	pubEnc := &pem.Block{
+		Type:    "PUBLIC KEY",
+		Headers: nil,
+		Bytes:   pubKeyBytes}
+
+	return writeKeyFile(pubFilepath, pubEnc)
+}
+
+func writeKeyFile(filepath string, block *pem.Block) error {
+	tmpFilepath := filepath + ".tmp"
+	if keyFile, err := os.OpenFile(tmpFilepath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600); err != nil {
+		return errors.New(fmt.Sprintf("Could not create key file %v, error %v", tmpFilepath, err))
+	} else if err := keyFile.Chmod(0600); err != nil {
+		keyFile.Close()
+		return errors.New(fmt.Sprintf("Could not chmod key file %v, error %v", tmpFilepath, err))
+	} else if err := pem.Encode(keyFile, block); err != nil {
+		keyFile.Close()
+		return errors.New(fmt.Sprintf("Could not encode key to file %v, error %v", tmpFilepath, err))
+	} else if err := keyFile.Close(); err != nil {
+		return errors.New(fmt.Sprintf("Could not close key file %v, error %v", tmpFilepath, err))
+	} else if err := os.Rename(tmpFilepath, filepath); err != nil {
+		return errors.New(fmt.Sprintf("Could not rename key file %v to %v, error %v", tmpFilepath, filepath, err))
+	}
+	return nil
+}
+
+func readKeys(privFilepath string, pubFilepath string) (*rsa.PublicKey, *rsa.PrivateKey, error) {
+	if _, ferr := os.Stat(pubFilepath); os.IsNotExist(ferr) {
+		return nil, nil, errors.New(fmt.Sprintf("Could not find public key file %v, error %v", pubFilepath, ferr))
+	} else if privBytes, err := ioutil.ReadFile(privFilepath); err != nil {
+		return nil, nil, errors.New(fmt.Sprintf("Unable to read private key file %v, error: %v", privFilepath, err))
+	} else if privBlock, _ := pem.Decode(privBytes); privBlock == nil {
+		return nil, nil, errors.New(fmt.Sprintf("Unable to extract pem block from private key file %v", privFilepath))
+	} else if privateKey, err := x509.ParsePKCS1PrivateKey(privBlock.Bytes); err != nil {
+		return nil, nil, errors.New(fmt.Sprintf("Unable to parse private key from file %v, error: %v", privFilepath, err))
+	} else if pubBytes, err := ioutil.ReadFile(pubFilepath); err != nil {
+		return nil, nil, errors.New(fmt.Sprintf("Unable to read public key file %v, error: %v", pubFilepath, err))
+	} else if pubBlock, _ := pem.Decode(pubBytes); pubBlock == nil {
+		return nil, nil, errors.New(fmt.Sprintf("Unable to extract pem block from public key file %v", pubFilepath))
+	} else if publicKey, err := x509.ParsePKIXPublicKey(pubBlock.Bytes); err != nil {
+		return nil, nil, errors.New(fmt.Sprintf("Unable to parse public key %x, error: %v", pubBytes, err))
+	} else if rsaPublicKey, ok := publicKey.(*rsa.PublicKey); !ok {
+		return nil, nil, errors.New(fmt.Sprintf("Public key in file %v is not an RSA key", pubFilepath))
+	} else if rsaPublicKey.N.Cmp(privateKey.PublicKey.N) != 0 || rsaPublicKey.E != privateKey.PublicKey.E {
+		return nil, nil, &keyMismatchError{pubFilepath: pubFilepath, privateKey: privateKey}
+	} else {
+		return rsaPublicKey, privateKey, nil
+	}
+}
+
+// The public key file does not hold the public key of the private key file, e.g. while another runtime that shares the
+// key files is rotating the key, or after a rotation that stopped part way.
+type keyMismatchError struct {
+	pubFilepath string
+	privateKey  *rsa.PrivateKey
+}
+
+func (e *keyMismatchError) Error() string {
+	return fmt.Sprintf("Public key in file %v does not match the private key", e.pubFilepath)
+}
+
+// Read the private key from before the last rotation, unless it has expired. An expired key file is removed. The caller
+// must hold the KeyLock.
+func readPreviousKey(previousPrivFilepath string) error {
+	gPreviousPrivateKey = nil
+	gPreviousKeyExpires = time.Time{}
+
+	if _, ferr := os.Stat(previousPrivFilepath); os.IsNotExist(ferr) {
+		return nil
+	} else if prevBytes, err := ioutil.ReadFile(previousPrivFilepath); err != nil {
+		return errors.New(fmt.Sprintf("Unable to read previous private key file %v, error: %v", previousPrivFilepath, err))
+	} else if prevBlock, _ := pem.Decode(prevBytes); prevBlock == nil {
+		return errors.New(fmt.Sprintf("Unable to extract pem block from previous private key file %v", previousPrivFilepath))
+	} else if expires, err := time.Parse(time.RFC3339, prevBlock.Headers[previousKeyExpiresHeader]); err != nil {
+		return errors.New(fmt.Sprintf("Unable to parse the expiry of the previous private key in file %v, error: %v", previousPrivFilepath, err))
+	} else if time.Now().After(expires) {
+		glog.V(3).Infof(fmt.Sprintf("Removing the previous messaging key, it expired at %v", expires))
+		return os.Remove(previousPrivFilepath)
+	} else if privateKey, err := x509.ParsePKCS1PrivateKey(prevBlock.Bytes); err != nil {
+		return errors.New(fmt.Sprintf("Unable to parse previous private key from file %v, error: %v", previousPrivFilepath, err))
+	} else {
+		gPreviousPrivateKey = privateKey
+		gPreviousKeyExpires = expires
+	}
+	return nil
+}
+
+// Keep the current private key as the previous key until the grace period (in seconds) expires. It is written to a file
+// so that an agent or agbot that restarts during the grace period can still decrypt the messages encrypted with it. The
+// caller must hold the KeyLock.
+func keepPreviousKey(previousPrivFilepath string, graceS int) error {
+	expires := time.Now().Add(time.Duration(graceS) * time.Second).UTC()
+	prevEnc := &pem.Block{
+		Type:    "RSA PRIVATE KEY",
+		Headers: map[string]string{previousKeyExpiresHeader: expires.Format(time.RFC3339)},
+		Bytes:   x509.MarshalPKCS1PrivateKey(gPrivateKey)}
+
+	if err := writeKeyFile(previousPrivFilepath, prevEnc); err != nil {
+		return err
+	}
+
+	gPreviousPrivateKey = gPrivateKey
+	gPreviousKeyExpires = expires
+	return nil
+}
+
+// RotateKeys replaces the messaging key pair of this runtime with a new one. The old private key can still decrypt
+// messages for graceS seconds, so that the messages that were encrypted with the old public key before the new one was
+// published to the exchange are not lost. The caller is responsible for publishing the new public key.
+func RotateKeys(keyPath string, graceS int) (*rsa.PublicKey, error) {
+	KeyLock.Lock()
+	defer KeyLock.Unlock()
+
+	if _, _, err := getKeys(keyPath); err != nil {
+		return nil, err
+	}
+
+	privFilepath, pubFilepath, previousPrivFilepath := keyFilePaths(keyPath)
+	if privateKey, err := rsa.GenerateKey(rand.Reader, 2048); err != nil {
+		return nil, errors.New(fmt.Sprintf("Could not generate private key, error %v", err))
+	} else if err := keepPreviousKey(previousPrivFilepath, graceS); err != nil {
+		return nil, err
+	} else if err := writeKeys(privFilepath, pubFilepath, privateKey); err != nil {
+		return nil, err
+	} else {
+		gPublicKey = &privateKey.PublicKey
+		gPrivateKey = privateKey
+		glog.V(3).Infof(fmt.Sprintf("Rotated the messaging key, the previous key expires at %v", gPreviousKeyExpires))
+	}
+
+	return gPublicKey, nil
+}
+
+// ReloadKeys reads the messaging key pair from its files again, for when another runtime that shares the key files
+// rotated the key. If the key in the files is different, the key of this runtime is kept as the previous key for graceS
+// seconds, and true is returned.
+func ReloadKeys(keyPath string, graceS int) (bool, error) {
+	KeyLock.Lock()
+	defer KeyLock.Unlock()
+
+	if _, _, err := getKeys(keyPath); err != nil {
+		return false, err
 	}
 
-	if _, ferr := os.Stat(pubFilepath); !os.IsNotExist(ferr) {
-		if err := os.Remove(pubFilepath); err != nil {
-			return err
+	privFilepath, pubFilepath, _ := keyFilePaths(keyPath)
+	if publicKey, privateKey, err := readKeys(privFilepath, pubFilepath); err != nil {
+		return false, err
+	} else if publicKey.N.Cmp(gPublicKey.N) == 0 && publicKey.E == gPublicKey.E {
+		return false, nil
+	} else {
+		gPreviousPrivateKey = gPrivateKey
+		gPreviousKeyExpires = time.Now().Add(time.Duration(graceS) * time.Second).UTC()
+		gPublicKey = publicKey
+		gPrivateKey = privateKey
+		glog.V(3).Infof(fmt.Sprintf("Reloaded the rotated messaging key, the previous key expires at %v", gPreviousKeyExpires))
+		return true, nil
+	}
+}
+
+// Returns the private key from before the last rotation of the messaging key, or nil if there is none or it expired.
+func GetPreviousPrivateKey() *rsa.PrivateKey {
+	KeyLock.Lock()
+	defer KeyLock.Unlock()
+
+	if gPreviousPrivateKey != nil && time.Now().After(gPreviousKeyExpires) {
+		gPreviousPrivateKey = nil
+	}
+	return gPreviousPrivateKey
+}
+
+// Returns the time when the messaging key pair in the files was created.
+func GetKeysCreated(keyPath string) (time.Time, error) {
+	_, pubFilepath, _ := keyFilePaths(keyPath)
+	if info, err := os.Stat(pubFilepath); err != nil {
+		return time.Time{}, err
+	} else {
+		return info.ModTime(), nil
+	}
+}
+
+func DeleteKeys(keyPath string) error {
+	// Construct the full file path name
+	privFilepath, pubFilepath, previousPrivFilepath := keyFilePaths(keyPath)
+
+	glog.V(5).Infof("Removing private key path %v, and public key path %v", privFilepath, pubFilepath)
+
+	// Delete the private, public and previous private key files
+	for _, filepath := range []string{privFilepath, pubFilepath, previousPrivFilepath} {
+		if _, ferr := os.Stat(filepath); !os.IsNotExist(ferr) {
+			if err := os.Remove(filepath); err != nil {
+				return err
+			}
 		}
 	}
 
+	KeyLock.Lock()
+	defer KeyLock.Unlock()
+	gPreviousPrivateKey = nil
+
 	return nil
 }
@@ -13,9 +13,12 @@ import (
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
 	"golang.org/x/crypto/sha3"
+	"io/ioutil"
 	"os"
+	"path"
 	"testing"
 )
 
@@ -1004,3 +1007,144 @@ func TestKeySaving_success1(t *testing.T) {
 	}
 
 }
+
+func TestKeyRotation_success1(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "keyrotation")
+	if err != nil {
+		t.Fatalf("Could not create temp dir, error %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	_ = os.Setenv("HZN_VAR_BASE", dir)
+	gPublicKey = nil
+	gPrivateKey = nil
+	defer func() {
+		gPublicKey = nil
+		gPrivateKey = nil
+		gPreviousPrivateKey = nil
+	}()
+
+	message := []byte(`{"type":"proposal","protocol":"Basic","version":2}`)
+
+	senderPrivateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Could not generate sender private key, error %v", err)
+	}
+
+	oldPub, oldPriv, err := GetKeys("")
+	if err != nil {
+		t.Fatalf("Could not generate key, error %v", err)
+	}
+
+	// A message in flight, encrypted with the key from before the rotation.
+	inflight, err := ConstructExchangeMessage(message, &senderPrivateKey.PublicKey, senderPrivateKey, oldPub)
+	if err != nil {
+		t.Fatalf("Could not construct message, %v", err)
+	}
+	inflightBody, _ := json.Marshal(inflight)
+
+	newPub, err := RotateKeys("", 3600)
+	if err != nil {
+		t.Fatalf("Could not rotate key, error %v", err)
+	} else if newPub.N.Cmp(oldPub.N) == 0 {
+		t.Errorf("The rotated key is the same as the old key")
+	} else if GetPreviousPrivateKey() != oldPriv {
+		t.Errorf("The previous key is not the old key")
+	} else if b, _ := MarshalPublicKey(oldPub); !IsPreviousPublicKey(b) {
+		t.Errorf("The old public key is not the previous public key")
+	}
+
+	_, newPriv, _ := GetKeys("")
+	if receivedMessage, _, err := DeconstructExchangeMessage(inflightBody, newPriv); err != nil {
+		t.Errorf("Could not deconstruct the in flight message with the previous key, %v", err)
+	} else if !bytes.Equal(message, receivedMessage) {
+		t.Errorf("Received message %s is not the same as the original message %s.", receivedMessage, message)
+	}
+
+	// The previous key survives a restart during the grace period.
+	gPublicKey = nil
+	gPrivateKey = nil
+	if pub, _, err := GetKeys(""); err != nil {
+		t.Errorf("Could not read key, error %v", err)
+	} else if pub.N.Cmp(newPub.N) != 0 {
+		t.Errorf("The key read after the restart is not the rotated key")
+	} else if prev := GetPreviousPrivateKey(); prev == nil || prev.N.Cmp(oldPriv.N) != 0 {
+		t.Errorf("The previous key was not kept across the restart")
+	}
+
+	// The previous key cannot decrypt messages once it expired.
+	if _, err := RotateKeys("", -1); err != nil {
+		t.Fatalf("Could not rotate key, error %v", err)
+	} else if GetPreviousPrivateKey() != nil {
+		t.Errorf("The previous key did not expire")
+	}
+
+	_, newestPriv, _ := GetKeys("")
+	if _, _, err := DeconstructExchangeMessage(inflightBody, newestPriv); err == nil {
+		t.Errorf("The in flight message should not be decrypted with an expired key")
+	}
+
+	gPublicKey = nil
+	gPrivateKey = nil
+	if _, _, err := GetKeys(""); err != nil {
+		t.Errorf("Could not read key, error %v", err)
+	} else if _, err := os.Stat(path.Join(dir, previousPrivFileName)); !os.IsNotExist(err) {
+		t.Errorf("The expired previous key file was not removed")
+	}
+
+}
+
+func TestKeyRotation_mismatch(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "keymismatch")
+	if err != nil {
+		t.Fatalf("Could not create temp dir, error %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	_ = os.Setenv("HZN_VAR_BASE", dir)
+	gPublicKey = nil
+	gPrivateKey = nil
+	defer func() {
+		gPublicKey = nil
+		gPrivateKey = nil
+		gPreviousPrivateKey = nil
+	}()
+
+	oldPub, _, err := GetKeys("")
+	if err != nil {
+		t.Fatalf("Could not generate key, error %v", err)
+	}
+
+	// A rotation that stopped after writing the private key.
+	privFilepath, pubFilepath, _ := keyFilePaths("")
+	newPriv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Could not generate private key, error %v", err)
+	} else if err := writeKeyFile(privFilepath, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(newPriv)}); err != nil {
+		t.Fatalf("Could not write private key, error %v", err)
+	}
+
+	if _, _, err := readKeys(privFilepath, pubFilepath); err == nil {
+		t.Errorf("A public key that does not match the private key should be an error")
+	} else if reloaded, err := ReloadKeys("", 3600); err == nil || reloaded {
+		t.Errorf("The key should not be reloaded from key files that do not match")
+	} else if pub, _, _ := GetKeys(""); pub.N.Cmp(oldPub.N) != 0 {
+		t.Errorf("The key in use should not change when the key files do not match")
+	}
+
+	// A restart writes the public key of the private key.
+	gPublicKey = nil
+	gPrivateKey = nil
+	if pub, priv, err := GetKeys(""); err != nil {
+		t.Errorf("Could not read key, error %v", err)
+	} else if pub.N.Cmp(newPriv.N) != 0 || priv.N.Cmp(newPriv.N) != 0 {
+		t.Errorf("The key read after the restart is not the key in the private key file")
+	} else if filePub, _, err := readKeys(privFilepath, pubFilepath); err != nil {
+		t.Errorf("The public key file was not repaired, error %v", err)
+	} else if filePub.N.Cmp(newPriv.N) != 0 {
+		t.Errorf("The public key file does not hold the public key of the private key")
+	}
+
+}
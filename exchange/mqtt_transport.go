@@ -0,0 +1,196 @@
+package exchange
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/config"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The time between attempts to connect to the broker, until the first connection succeeds. After that, the client
+// reconnects by itself.
+var mqttConnectRetryInterval = 30 * time.Second
+
+// The messages are delivered at least once. A message can be delivered twice, e.g. when the broker acknowledges it too
+// late and it is also sent through the exchange, the agreement protocol ignores the duplicates.
+const mqttQoS = 1
+
+// The group of the shared subscription of the agbots. Agbots that share an exchange id share its topic, and each message
+// is given to one of them, like the messages in the exchange mailbox of the agbot.
+const mqttAgbotGroup = "horizon-agbots"
+
+// The transport that pushes the messages through an MQTT broker. The messages to a node or agbot are published to its own
+// topic, <prefix>/orgs/<org>/<nodes|agbots>/<id>/msgs, which it advertises as its msgEndPoint in the exchange. The node
+// or agbot connects to the broker with its exchange credentials, so that the broker can authenticate it with the
+// exchange and only let it subscribe to its own topic.
+type MQTTTransport struct {
+	client         paho.Client
+	senderId       string
+	topic          string
+	publishTimeout time.Duration
+	handler        func(*PushedMessage)
+	closed         chan bool
+	closeOnce      sync.Once
+	subscribed     bool // True once the transport is subscribed to its topic on the current connection.
+	lock           sync.Mutex
+}
+
+// Returns the topic of a node or agbot.
+func MQTTTopic(prefix string, mailbox string, id string) string {
+	return fmt.Sprintf("%v/orgs/%v/%v/%v/msgs", prefix, GetOrg(id), mailbox, GetId(id))
+}
+
+// NewMQTTTransport creates the MQTT transport of the node or agbot of the exchange context, its messages are given to the
+// handler. It connects to the broker in the background, and keeps trying until the transport is closed, so the transport
+// is returned even when the broker is unreachable. Messages go through the exchange until it is connected.
+func NewMQTTTransport(cfg *config.HorizonConfig, ec ExchangeContext, mailbox string, handler func(*PushedMessage)) *MQTTTransport {
+	t := &MQTTTransport{
+		senderId:       ec.GetExchangeId(),
+		topic:          MQTTTopic(cfg.MQTT.TopicPrefix, mailbox, ec.GetExchangeId()),
+		publishTimeout: time.Duration(cfg.MQTT.PublishTimeoutS) * time.Second,
+		handler:        handler,
+		closed:         make(chan bool),
+	}
+
+	// The session is kept by the broker while the node or agbot is disconnected, so that the messages sent in the
+	// meantime are delivered when it reconnects.
+	clientId := ec.GetExchangeId()
+	subscription := t.topic
+	if mailbox == AGBOT_MAILBOX {
+		if host, err := os.Hostname(); err == nil {
+			clientId = clientId + "/" + host
+		}
+		subscription = "$share/" + mqttAgbotGroup + "/" + t.topic
+	}
+
+	opts := paho.NewClientOptions()
+	opts.AddBroker(cfg.MQTT.BrokerURL)
+	opts.SetClientID(clientId)
+	opts.SetUsername(ec.GetExchangeId())
+	opts.SetPassword(ec.GetExchangeToken())
+	opts.SetCleanSession(false)
+	opts.SetAutoReconnect(true)
+	opts.SetKeepAlive(time.Duration(cfg.MQTT.KeepAliveS) * time.Second)
+	opts.SetConnectTimeout(time.Duration(cfg.MQTT.ConnectTimeoutS) * time.Second)
+	if cfg.Collaborators.HTTPClientFactory != nil && cfg.Collaborators.HTTPClientFactory.TLSConfig != nil {
+		opts.SetTLSConfig(cfg.Collaborators.HTTPClientFactory.TLSConfig)
+	}
+
+	// Subscribe on every connection, in case the broker did not keep the session.
+	opts.SetOnConnectHandler(func(c paho.Client) {
+		glog.V(3).Infof(mqttlogString(fmt.Sprintf("connected to %v", cfg.MQTT.BrokerURL)))
+		if token := c.Subscribe(subscription, mqttQoS, t.receive); !token.WaitTimeout(t.publishTimeout) {
+			glog.Errorf(mqttlogString(fmt.Sprintf("timed out subscribing to %v", subscription)))
+		} else if token.Error() != nil {
+			glog.Errorf(mqttlogString(fmt.Sprintf("unable to subscribe to %v, error: %v", subscription, token.Error())))
+		} else {
+			glog.V(3).Infof(mqttlogString(fmt.Sprintf("subscribed to %v", subscription)))
+			t.setSubscribed(true)
+		}
+	})
+	opts.SetConnectionLostHandler(func(c paho.Client, err error) {
+		t.setSubscribed(false)
+		glog.Warningf(mqttlogString(fmt.Sprintf("lost connection to %v, messages are sent through the exchange until it is restored, error: %v", cfg.MQTT.BrokerURL, err)))
+	})
+
+	t.client = paho.NewClient(opts)
+	go t.connect(cfg.MQTT.BrokerURL)
+	return t
+}
+
+func (t *MQTTTransport) connect(brokerURL string) {
+	for {
+		if token := t.client.Connect(); token.Wait() && token.Error() != nil {
+			glog.Warningf(mqttlogString(fmt.Sprintf("unable to connect to %v, messages are sent through the exchange, retrying in %v, error: %v", brokerURL, mqttConnectRetryInterval, token.Error())))
+		} else {
+			// The transport might have been closed while it was connecting.
+			select {
+			case <-t.closed:
+				t.client.Disconnect(250)
+			default:
+			}
+			return
+		}
+
+		select {
+		case <-t.closed:
+			return
+		case <-time.After(mqttConnectRetryInterval):
+		}
+	}
+}
+
+func (t *MQTTTransport) receive(c paho.Client, m paho.Message) {
+	msg := new(PushedMessage)
+	if err := json.Unmarshal(m.Payload(), msg); err != nil {
+		glog.Errorf(mqttlogString(fmt.Sprintf("ignoring message on %v that is not a protocol message, error: %v", m.Topic(), err)))
+	} else {
+		glog.V(3).Infof(mqttlogString(fmt.Sprintf("received message from %v", msg.SenderId)))
+		t.handler(msg)
+	}
+}
+
+func (t *MQTTTransport) Name() string {
+	return "mqtt"
+}
+
+// Publish the message to the topic in the msgEndPoint of the receiver. There is no expiry in MQTT 3.1.1, so the ttl is
+// not used, the receiver of an old message rejects it like it would reject an old message from its exchange mailbox.
+func (t *MQTTTransport) SendMessage(mailbox string, receiverId string, endPoint string, msg []byte, ttl int) error {
+	if !t.Accepts(endPoint) {
+		return errors.New(fmt.Sprintf("msgEndPoint %v of %v is not an MQTT topic", endPoint, receiverId))
+	}
+
+	payload, err := json.Marshal(PushedMessage{SenderId: t.senderId, Message: msg, TimeSent: time.Now().UTC().Format(time.RFC3339)})
+	if err != nil {
+		return errors.New(fmt.Sprintf("unable to marshal message for %v, error %v", receiverId, err))
+	}
+
+	topic := strings.TrimPrefix(endPoint, MQTT_ENDPOINT_PREFIX)
+	if token := t.client.Publish(topic, mqttQoS, false, payload); !token.WaitTimeout(t.publishTimeout) {
+		return errors.New(fmt.Sprintf("timed out publishing message to %v", topic))
+	} else if token.Error() != nil {
+		return errors.New(fmt.Sprintf("unable to publish message to %v, error %v", topic, token.Error()))
+	}
+	return nil
+}
+
+func (t *MQTTTransport) EndPoint() string {
+	return MQTT_ENDPOINT_PREFIX + t.topic
+}
+
+func (t *MQTTTransport) Accepts(endPoint string) bool {
+	return IsMQTTEndPoint(endPoint)
+}
+
+// The transport is connected when it can publish to the broker and receive the messages published to its topic.
+func (t *MQTTTransport) Connected() bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.subscribed && t.client.IsConnectionOpen()
+}
+
+func (t *MQTTTransport) setSubscribed(subscribed bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.subscribed = subscribed
+}
+
+func (t *MQTTTransport) Close() {
+	t.closeOnce.Do(func() {
+		close(t.closed)
+		if t.client.IsConnected() {
+			t.client.Disconnect(250)
+		}
+	})
+}
+
+var mqttlogString = func(v interface{}) string {
+	return fmt.Sprintf("MQTTTransport %v", v)
+}
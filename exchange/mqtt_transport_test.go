@@ -0,0 +1,375 @@
+// +build unit
+
+package exchange
+
+import (
+	"github.com/eclipse/paho.mqtt.golang/packets"
+	"github.com/open-horizon/anax/config"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// A minimal MQTT 3.1.1 broker for the tests. It accepts every client, keeps the subscriptions of the connected clients
+// only, and gives each message to a shared subscription to one member of the group.
+type testBroker struct {
+	listener net.Listener
+	lock     sync.Mutex
+	conns    map[net.Conn]*testBrokerClient
+	msgId    uint16
+}
+
+type testBrokerClient struct {
+	writeLock sync.Mutex
+	conn      net.Conn
+	username  string
+	filters   []string
+}
+
+func startTestBroker(t *testing.T, addr string) *testBroker {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatalf("unable to start the test broker: %v", err)
+	}
+	b := &testBroker{listener: l, conns: make(map[net.Conn]*testBrokerClient)}
+	go b.serve()
+	return b
+}
+
+func (b *testBroker) URL() string {
+	return "tcp://" + b.listener.Addr().String()
+}
+
+// Stop the broker and drop its clients, like a broker that becomes unreachable.
+func (b *testBroker) Stop() {
+	b.listener.Close()
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for conn := range b.conns {
+		conn.Close()
+	}
+}
+
+func (b *testBroker) serve() {
+	for {
+		conn, err := b.listener.Accept()
+		if err != nil {
+			return
+		}
+		go b.handle(conn)
+	}
+}
+
+func (b *testBroker) handle(conn net.Conn) {
+	c := &testBrokerClient{conn: conn}
+	defer func() {
+		b.lock.Lock()
+		delete(b.conns, conn)
+		b.lock.Unlock()
+		conn.Close()
+	}()
+
+	for {
+		cp, err := packets.ReadPacket(conn)
+		if err != nil {
+			return
+		}
+
+		switch p := cp.(type) {
+		case *packets.ConnectPacket:
+			c.username = p.Username
+			b.lock.Lock()
+			b.conns[conn] = c
+			b.lock.Unlock()
+			ack := packets.NewControlPacket(packets.Connack).(*packets.ConnackPacket)
+			ack.ReturnCode = packets.Accepted
+			c.write(ack)
+		case *packets.SubscribePacket:
+			b.lock.Lock()
+			c.filters = append(c.filters, p.Topics...)
+			b.lock.Unlock()
+			ack := packets.NewControlPacket(packets.Suback).(*packets.SubackPacket)
+			ack.MessageID = p.MessageID
+			ack.ReturnCodes = p.Qoss
+			c.write(ack)
+		case *packets.PublishPacket:
+			if p.Qos > 0 {
+				ack := packets.NewControlPacket(packets.Puback).(*packets.PubackPacket)
+				ack.MessageID = p.MessageID
+				c.write(ack)
+			}
+			b.publish(p.TopicName, p.Payload)
+		case *packets.PingreqPacket:
+			c.write(packets.NewControlPacket(packets.Pingresp))
+		case *packets.DisconnectPacket:
+			return
+		}
+	}
+}
+
+func (b *testBroker) publish(topic string, payload []byte) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	groups := make(map[string]bool)
+	for _, c := range b.conns {
+		for _, filter := range c.filters {
+			group := ""
+			if strings.HasPrefix(filter, "$share/") {
+				parts := strings.SplitN(filter, "/", 3)
+				group, filter = parts[1], parts[2]
+			}
+			if filter != topic || (group != "" && groups[group]) {
+				continue
+			}
+			if group != "" {
+				groups[group] = true
+			}
+
+			b.msgId++
+			pub := packets.NewControlPacket(packets.Publish).(*packets.PublishPacket)
+			pub.Qos = 1
+			pub.MessageID = b.msgId
+			pub.TopicName = topic
+			pub.Payload = payload
+			go c.write(pub)
+		}
+	}
+}
+
+func (c *testBrokerClient) write(p packets.ControlPacket) {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	p.Write(c.conn)
+}
+
+// An exchange transport that records the messages instead of posting them to the exchange.
+type testExchangeTransport struct {
+	lock     sync.Mutex
+	messages []string
+}
+
+func (t *testExchangeTransport) Name() string {
+	return "exchange"
+}
+
+func (t *testExchangeTransport) SendMessage(mailbox string, receiverId string, endPoint string, msg []byte, ttl int) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.messages = append(t.messages, mailbox+"/"+receiverId+":"+string(msg))
+	return nil
+}
+
+func (t *testExchangeTransport) sent() []string {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return append([]string{}, t.messages...)
+}
+
+func getMQTTTestConfig(brokerURL string) *config.HorizonConfig {
+	return &config.HorizonConfig{
+		MQTT: config.MQTTConfig{
+			BrokerURL:       brokerURL,
+			TopicPrefix:     "test",
+			KeepAliveS:      30,
+			ConnectTimeoutS: 2,
+			PublishTimeoutS: 2,
+		},
+	}
+}
+
+func getMQTTTestContext(id string) ExchangeContext {
+	return NewCustomExchangeContext(id, "token", "http://localhost/", "", &config.HTTPClientFactory{})
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	for i := 0; i < 100; i++ {
+		if cond() {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %v", what)
+}
+
+func Test_MQTTTransport_push(t *testing.T) {
+	broker := startTestBroker(t, "127.0.0.1:0")
+	defer broker.Stop()
+
+	received := make(chan *PushedMessage, 10)
+	node := NewMQTTTransport(getMQTTTestConfig(broker.URL()), getMQTTTestContext("myorg/node1"), NODE_MAILBOX, func(m *PushedMessage) { received <- m })
+	defer node.Close()
+	agbot := NewMQTTTransport(getMQTTTestConfig(broker.URL()), getMQTTTestContext("agorg/ag1"), AGBOT_MAILBOX, func(m *PushedMessage) { received <- m })
+	defer agbot.Close()
+	waitFor(t, "the transports to connect", func() bool { return node.Connected() && agbot.Connected() })
+
+	if ep := node.EndPoint(); ep != "mqtt:test/orgs/myorg/nodes/node1/msgs" {
+		t.Errorf("unexpected node endpoint %v", ep)
+	} else if ep := agbot.EndPoint(); ep != "mqtt:test/orgs/agorg/agbots/ag1/msgs" {
+		t.Errorf("unexpected agbot endpoint %v", ep)
+	}
+
+	// The subscriptions are made after the connection, wait for both of them.
+	waitFor(t, "the subscriptions", func() bool {
+		broker.lock.Lock()
+		defer broker.lock.Unlock()
+		subs := 0
+		for _, c := range broker.conns {
+			subs += len(c.filters)
+		}
+		return subs == 2
+	})
+
+	SetPushTransport(agbot)
+	defer SetPushTransport(nil)
+	et := &testExchangeTransport{}
+
+	target, _ := CreateMessageTarget("myorg/node1", nil, []byte("key"), node.EndPoint())
+	if err := DeliverMessage(et, NODE_MAILBOX, target, []byte("proposal"), 300); err != nil {
+		t.Fatalf("unexpected error delivering message: %v", err)
+	}
+
+	select {
+	case m := <-received:
+		if m.SenderId != "agorg/ag1" || string(m.Message) != "proposal" || m.TimeSent == "" {
+			t.Errorf("unexpected pushed message %v", m)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("the message was not pushed to the node")
+	}
+	if sent := et.sent(); len(sent) != 0 {
+		t.Errorf("the message should not have been sent through the exchange: %v", sent)
+	}
+
+	// The reply of the node goes to the shared subscription of the agbot.
+	SetPushTransport(node)
+	target, _ = CreateMessageTarget("agorg/ag1", nil, []byte("key"), agbot.EndPoint())
+	if err := DeliverMessage(et, AGBOT_MAILBOX, target, []byte("reply"), 300); err != nil {
+		t.Fatalf("unexpected error delivering message: %v", err)
+	}
+
+	select {
+	case m := <-received:
+		if m.SenderId != "myorg/node1" || string(m.Message) != "reply" {
+			t.Errorf("unexpected pushed message %v", m)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("the message was not pushed to the agbot")
+	}
+}
+
+func Test_MQTTTransport_fallback(t *testing.T) {
+	broker := startTestBroker(t, "127.0.0.1:0")
+	addr := broker.listener.Addr().String()
+
+	received := make(chan *PushedMessage, 10)
+	agbot := NewMQTTTransport(getMQTTTestConfig(broker.URL()), getMQTTTestContext("agorg/ag1"), AGBOT_MAILBOX, func(m *PushedMessage) { received <- m })
+	defer agbot.Close()
+	waitFor(t, "the transport to connect", agbot.Connected)
+
+	SetPushTransport(agbot)
+	defer SetPushTransport(nil)
+	et := &testExchangeTransport{}
+
+	// A node that does not advertise an MQTT endpoint gets its messages in its exchange mailbox.
+	target, _ := CreateMessageTarget("myorg/node2", nil, []byte("key"), "")
+	if err := DeliverMessage(et, NODE_MAILBOX, target, []byte("one"), 300); err != nil {
+		t.Errorf("unexpected error delivering message: %v", err)
+	}
+
+	// A node whose endpoint was seen in the exchange gets its messages pushed, even when the target has no endpoint.
+	SetMessageEndPoint("myorg/node2", "mqtt:test/orgs/myorg/nodes/node2/msgs")
+	defer SetMessageEndPoint("myorg/node2", "")
+	if err := DeliverMessage(et, NODE_MAILBOX, target, []byte("two"), 300); err != nil {
+		t.Errorf("unexpected error delivering message: %v", err)
+	}
+
+	// When the broker is unreachable, the messages go through the exchange.
+	broker.Stop()
+	waitFor(t, "the transport to disconnect", func() bool { return !agbot.Connected() })
+	if err := DeliverMessage(et, NODE_MAILBOX, target, []byte("three"), 300); err != nil {
+		t.Errorf("unexpected error delivering message: %v", err)
+	}
+
+	if sent := et.sent(); len(sent) != 2 || sent[0] != "nodes/myorg/node2:one" || sent[1] != "nodes/myorg/node2:three" {
+		t.Errorf("unexpected messages sent through the exchange: %v", sent)
+	}
+
+	// The transport reconnects when the broker is back.
+	broker = startTestBroker(t, addr)
+	defer broker.Stop()
+	waitFor(t, "the transport to reconnect", agbot.Connected)
+}
+
+func Test_MQTTTransport_unreachable(t *testing.T) {
+	// Find a free port for a broker that is not running yet.
+	broker := startTestBroker(t, "127.0.0.1:0")
+	addr := broker.listener.Addr().String()
+	broker.Stop()
+
+	retry := mqttConnectRetryInterval
+	mqttConnectRetryInterval = 100 * time.Millisecond
+	defer func() { mqttConnectRetryInterval = retry }()
+
+	node := NewMQTTTransport(getMQTTTestConfig("tcp://"+addr), getMQTTTestContext("myorg/node1"), NODE_MAILBOX, func(m *PushedMessage) {})
+	defer node.Close()
+
+	time.Sleep(300 * time.Millisecond)
+	if node.Connected() {
+		t.Errorf("the transport should not be connected")
+	} else if err := node.SendMessage(AGBOT_MAILBOX, "agorg/ag1", "mqtt:test/orgs/agorg/agbots/ag1/msgs", []byte("m"), 300); err == nil {
+		t.Errorf("publishing without a broker should fail")
+	}
+
+	broker = startTestBroker(t, addr)
+	defer broker.Stop()
+	waitFor(t, "the transport to connect", node.Connected)
+
+	if err := node.SendMessage(AGBOT_MAILBOX, "agorg/ag1", "exchange", []byte("m"), 300); err == nil {
+		t.Errorf("publishing to an endpoint that is not an MQTT topic should fail")
+	}
+}
+
+func Test_MessageSenderKey(t *testing.T) {
+	defer SetMessageEndPoint("agorg/ag1", "")
+
+	if key := GetMessageSenderKey("agorg/ag1"); key != nil {
+		t.Errorf("an unknown sender should not have a key, it has %v", key)
+	}
+
+	// The key and the msgEndPoint of a sender are kept together.
+	SetMessageSender("agorg/ag1", "mqtt:test/orgs/agorg/agbots/ag1/msgs", []byte("key1"))
+	target, _ := CreateMessageTarget("agorg/ag1", nil, []byte("key1"), "")
+	if key := GetMessageSenderKey("agorg/ag1"); string(key) != "key1" {
+		t.Errorf("the key of the sender should be key1, it is %v", key)
+	} else if ep := GetMessageEndPoint(target); ep != "mqtt:test/orgs/agorg/agbots/ag1/msgs" {
+		t.Errorf("the msgEndPoint of the sender should be kept with its key, it is %v", ep)
+	}
+
+	// A sender without a msgEndPoint still has its key.
+	SetMessageSender("agorg/ag1", "", []byte("key2"))
+	if key := GetMessageSenderKey("agorg/ag1"); string(key) != "key2" {
+		t.Errorf("the key of the sender should be key2, it is %v", key)
+	} else if ep := GetMessageEndPoint(target); ep != "" {
+		t.Errorf("the sender should not have a msgEndPoint, it has %v", ep)
+	}
+
+	// The key expires with the msgEndPoint.
+	messageEndPointsLock.Lock()
+	ep := messageEndPoints["agorg/ag1"]
+	ep.seen = time.Now().Add(-(MESSAGE_ENDPOINT_TTL_S + 1) * time.Second)
+	messageEndPoints["agorg/ag1"] = ep
+	messageEndPointsLock.Unlock()
+	if key := GetMessageSenderKey("agorg/ag1"); key != nil {
+		t.Errorf("the key of the sender should have expired, it is %v", key)
+	}
+
+	// A msgEndPoint seen without the key forgets the key.
+	SetMessageSender("agorg/ag1", "", []byte("key3"))
+	SetMessageEndPoint("agorg/ag1", "mqtt:test/orgs/agorg/agbots/ag1/msgs")
+	if key := GetMessageSenderKey("agorg/ag1"); key != nil {
+		t.Errorf("the key of the sender should have been forgotten, it is %v", key)
+	}
+}
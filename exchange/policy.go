@@ -127,6 +127,10 @@ func GetNodePolicy(ec ExchangeContext, deviceId string) (*ExchangePolicy, error)
 				time.Sleep(time.Duration(retryInterval) * time.Second)
 				continue
 			} else if retryCount == 0 {
+				if stalePol, ok := GetStaleResourceFromCache(NodeCacheMapKey(GetOrg(deviceId), GetId(deviceId)), NODE_POL_TYPE_CACHE).(ExchangePolicy); ok {
+					glog.Warningf(rpclogString(fmt.Sprintf("exchange is unreachable, using persisted node policy for %v", deviceId)))
+					return &stalePol, nil
+				}
 				return nil, fmt.Errorf("Exceeded %v retries for error: %v", ec.GetHTTPFactory().RetryCount, tpErr)
 			} else {
 				retryCount--
@@ -95,9 +95,10 @@ func (a SearchExchangeMSRequest) String() string {
 }
 
 type SearchResultDevice struct {
-	Id        string `json:"id"`
-	NodeType  string `json:"nodeType"`
-	PublicKey string `json:"publicKey"`
+	Id          string `json:"id"`
+	NodeType    string `json:"nodeType"`
+	PublicKey   string `json:"publicKey"`
+	MsgEndPoint string `json:"msgEndPoint"`
 }
 
 func (d SearchResultDevice) String() string {
@@ -224,6 +225,10 @@ func GetExchangeDevice(httpClientFactory *config.HTTPClientFactory, deviceId str
 				time.Sleep(time.Duration(retryInterval) * time.Second)
 				continue
 			} else if retryCount == 0 {
+				if staleDev, ok := GetStaleResourceFromCache(NodeCacheMapKey(GetOrg(deviceId), GetId(deviceId)), NODE_DEF_TYPE_CACHE).(Device); ok {
+					glog.Warningf(rpclogString(fmt.Sprintf("exchange is unreachable, using persisted device %v", deviceId)))
+					return &staleDev, nil
+				}
 				return nil, fmt.Errorf("Exceeded %v retries for error: %v", httpClientFactory.RetryCount, tpErr)
 			} else {
 				retryCount--
@@ -522,14 +527,14 @@ type ExchangeMessageTarget struct {
 	ReceiverExchangeId     string // in the form org/id
 	ReceiverPublicKeyObj   *rsa.PublicKey
 	ReceiverPublicKeyBytes []byte
-	ReceiverMsgEndPoint    string
+	ReceiverMsgEndPoint    string // The msgEndPoint of the receiver, see DeliverMessage. It can be empty.
 }
 
+// The messages are always encrypted with the public key of the receiver, so one of the public key inputs is needed. The
+// message endpoint is optional, it is used to push the messages to a receiver that advertises one.
 func CreateMessageTarget(receiverId string, receiverPubKey *rsa.PublicKey, receiverPubKeySerialized []byte, receiverMessageEndpoint string) (*ExchangeMessageTarget, error) {
-	if len(receiverMessageEndpoint) == 0 && receiverPubKey == nil && len(receiverPubKeySerialized) == 0 {
-		return nil, errors.New(fmt.Sprintf("Must specify either one of the public key inputs OR the message endpoint input for the message receiver %v", receiverId))
-	} else if len(receiverMessageEndpoint) != 0 && (receiverPubKey != nil || len(receiverPubKeySerialized) != 0) {
-		return nil, errors.New(fmt.Sprintf("Specified message endpoint and at least one of the public key inputs for the message receiver %v, %v or %v", receiverId, receiverPubKey, receiverPubKeySerialized))
+	if receiverPubKey == nil && len(receiverPubKeySerialized) == 0 {
+		return nil, errors.New(fmt.Sprintf("Must specify one of the public key inputs for the message receiver %v", receiverId))
 	} else {
 		return &ExchangeMessageTarget{
 			ReceiverExchangeId:     receiverId,
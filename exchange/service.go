@@ -67,6 +67,7 @@ type UserInput struct {
 	Label        string `json:"label"`
 	Type         string `json:"type"` // Valid values are "string", "int", "float", "boolean", "list of strings"
 	DefaultValue string `json:"defaultValue"`
+	Sensitive    bool   `json:"sensitive,omitempty"` // The value is a secret, it is redacted from the node diagnostics.
 }
 
 func (ui UserInput) String() string {
@@ -339,24 +340,44 @@ func getSearchVersion(version string) (string, error) {
 // This function finds the specific version that we want
 func getServiceFromCache(url string, org string, version string, searchVersion string, arch string) (*ServiceDefinition, string, map[string]ServiceDefinition, error) {
 	svcDefMap := GetServiceFromCache(org, cutil.FormExchangeIdWithSpecRef(url), arch)
+	svc, sId, err := selectServiceFromCachedMap(svcDefMap, version, searchVersion)
+	return svc, sId, svcDefMap, err
+}
+
+// When the exchange cannot be reached, look for the service in the persistent tier of the exchange cache.
+func getStaleServiceFromCache(url string, org string, version string, searchVersion string, arch string) (*ServiceDefinition, string) {
+	svcDefMap, ok := GetStaleResourceFromCache(ServiceCacheMapKey(org, cutil.FormExchangeIdWithSpecRef(url), arch), SVC_DEF_TYPE_CACHE).(map[string]ServiceDefinition)
+	if !ok {
+		return nil, ""
+	}
+	svc, sId, err := selectServiceFromCachedMap(svcDefMap, version, searchVersion)
+	if err != nil {
+		glog.Errorf("Error getting service from the persisted cache: %v", err)
+		return nil, ""
+	}
+	return svc, sId
+}
+
+// Find the specific version or the highest version in the version range in the map of cached service versions.
+func selectServiceFromCachedMap(svcDefMap map[string]ServiceDefinition, version string, searchVersion string) (*ServiceDefinition, string, error) {
 	if svcDefMap == nil {
-		return nil, "", nil, nil
+		return nil, "", nil
 	}
 	if searchVersion != "" {
 		for sId, svc := range svcDefMap {
 			if svc.Version == searchVersion {
-				return &svc, sId, svcDefMap, nil
+				return &svc, sId, nil
 			}
 		}
-		return nil, "", svcDefMap, nil
+		return nil, "", nil
 	}
 
 	vRange, err := semanticversion.Version_Expression_Factory(version)
 	if err != nil {
-		return nil, "", svcDefMap, errors.New(fmt.Sprintf("version range %v in error: %v", version, err))
+		return nil, "", errors.New(fmt.Sprintf("version range %v in error: %v", version, err))
 	}
 	_, highestDef, highestSId, err := GetHighestVersion(svcDefMap, vRange)
-	return &highestDef, highestSId, svcDefMap, nil
+	return &highestDef, highestSId, nil
 }
 
 // Update the service definition cache with a changed service definition
@@ -412,6 +433,10 @@ func GetService(ec ExchangeContext, mURL string, mOrg string, mVersion string, m
 				time.Sleep(time.Duration(retryInterval) * time.Second)
 				continue
 			} else if retryCount == 0 {
+				if staleSvc, staleSvcId := getStaleServiceFromCache(mURL, mOrg, mVersion, searchVersion, mArch); staleSvc != nil {
+					glog.Warningf(rpclogString(fmt.Sprintf("exchange is unreachable, using persisted service definition %v", staleSvcId)))
+					return staleSvc, staleSvcId, nil
+				}
 				return nil, "", fmt.Errorf("Exceeded %v retries for error: %v", ec.GetHTTPFactory().RetryCount, tpErr)
 			} else {
 				retryCount--
@@ -946,6 +971,13 @@ func GetServicePolicyWithId(ec ExchangeContext, service_id string) (*ExchangePol
 				time.Sleep(time.Duration(retryInterval) * time.Second)
 				continue
 			} else if retryCount == 0 {
+				if stalePol, ok := GetStaleResourceFromCache(service_id, SVC_POL_TYPE_CACHE).(ExchangePolicy); ok {
+					glog.Warningf(rpclogString(fmt.Sprintf("exchange is unreachable, using persisted service policy for %v", service_id)))
+					if stalePol.LastUpdated == "" {
+						return nil, nil
+					}
+					return &stalePol, nil
+				}
 				return nil, fmt.Errorf("Exceeded %v retries for error: %v", ec.GetHTTPFactory().RetryCount, tpErr)
 			} else {
 				retryCount--
@@ -0,0 +1,272 @@
+package exchange
+
+import (
+	"errors"
+	"fmt"
+	"github.com/golang/glog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The mailboxes that the protocol messages are sent to, named by the exchange resource of the receiver.
+const NODE_MAILBOX = "nodes"
+const AGBOT_MAILBOX = "agbots"
+
+// The prefix of the msgEndPoint of the nodes and agbots that receive their messages from an MQTT broker. The rest of the
+// msgEndPoint is the topic that the messages are published to.
+const MQTT_ENDPOINT_PREFIX = "mqtt:"
+
+// The number of seconds that a msgEndPoint seen in the exchange is used for a receiver, when the message target does
+// not have one.
+const MESSAGE_ENDPOINT_TTL_S = 3600
+
+// A MessageTransport delivers the agreement protocol messages between the agbots and the nodes. The messages are
+// already encrypted and signed (see ConstructExchangeMessage) when they are given to a transport, so a transport only
+// moves opaque bytes. Whatever transport delivers a message, the receiver checks the signature of the message with the
+// public key that the exchange has for the sender.
+type MessageTransport interface {
+	Name() string
+	SendMessage(mailbox string, receiverId string, endPoint string, msg []byte, ttl int) error
+}
+
+// A PushTransport delivers the messages to the receivers as soon as they are sent, instead of leaving them in the
+// exchange mailbox of the receiver until it polls for them. Messages are only pushed to the receivers that advertise a
+// msgEndPoint that the transport accepts.
+type PushTransport interface {
+	MessageTransport
+	EndPoint() string             // The msgEndPoint that this node or agbot advertises in the exchange.
+	Accepts(endPoint string) bool // Returns true if messages can be pushed to a receiver with this msgEndPoint.
+	Connected() bool              // Returns false while the transport cannot reach its broker.
+	Close()
+}
+
+// The message id of the messages that were pushed to a node or agbot. Message ids in the exchange start at 1, and a
+// pushed message is not in the exchange mailbox, so there is nothing to delete from the exchange when it is handled.
+const PUSHED_MESSAGE_ID = 0
+
+// A message that was pushed to this node or agbot. The sender id is what the sender claims, it is verified when the
+// message is decrypted, with the public key that the exchange has for the sender.
+type PushedMessage struct {
+	SenderId string `json:"senderId"`
+	Message  []byte `json:"message"`
+	TimeSent string `json:"timeSent"`
+}
+
+func (p PushedMessage) String() string {
+	return fmt.Sprintf("SenderId: %v, TimeSent: %v, Message: %v bytes", p.SenderId, p.TimeSent, len(p.Message))
+}
+
+// The transport through the exchange mailboxes, which the receivers poll. This is the transport that every node and
+// agbot can use.
+type ExchangeTransport struct {
+	ec         ExchangeContext
+	httpClient *http.Client
+}
+
+// The transport uses the http client of the caller when there is one, otherwise it creates its own.
+func NewExchangeTransport(ec ExchangeContext, httpClient *http.Client) *ExchangeTransport {
+	if httpClient == nil {
+		httpClient = ec.GetHTTPFactory().NewHTTPClient(nil)
+	}
+	return &ExchangeTransport{
+		ec:         ec,
+		httpClient: httpClient,
+	}
+}
+
+func (t *ExchangeTransport) Name() string {
+	return "exchange"
+}
+
+// Post the message to the mailbox of the receiver. The exchange deletes the message when the ttl (in seconds) expires.
+func (t *ExchangeTransport) SendMessage(mailbox string, receiverId string, endPoint string, msg []byte, ttl int) error {
+	pm := CreatePostMessage(msg, ttl)
+	var resp interface{}
+	resp = new(PostDeviceResponse)
+	targetURL := t.ec.GetExchangeURL() + "orgs/" + GetOrg(receiverId) + "/" + mailbox + "/" + GetId(receiverId) + "/msgs"
+
+	httpClientFactory := t.ec.GetHTTPFactory()
+	retryCount := httpClientFactory.RetryCount
+	retryInterval := httpClientFactory.GetRetryInterval()
+
+	for {
+		if err, tpErr := InvokeExchange(t.httpClient, "POST", targetURL, t.ec.GetExchangeId(), t.ec.GetExchangeToken(), pm, &resp); err != nil {
+			return err
+		} else if tpErr != nil {
+			glog.Warningf(tpErr.Error())
+			if httpClientFactory.RetryCount == 0 {
+				time.Sleep(time.Duration(retryInterval) * time.Second)
+				continue
+			} else if retryCount == 0 {
+				return errors.New(fmt.Sprintf("exceeded %v retries trying to send message to %v for %v", httpClientFactory.RetryCount, receiverId, tpErr))
+			} else {
+				retryCount--
+				time.Sleep(time.Duration(retryInterval) * time.Second)
+				continue
+			}
+		} else {
+			glog.V(5).Infof(rpclogString(fmt.Sprintf("sent message for %v to exchange.", receiverId)))
+			return nil
+		}
+	}
+}
+
+// The push transport of this node or agbot, there is none when no broker is configured.
+var pushTransport PushTransport
+var pushTransportLock sync.RWMutex
+
+func SetPushTransport(t PushTransport) {
+	pushTransportLock.Lock()
+	defer pushTransportLock.Unlock()
+	pushTransport = t
+}
+
+func GetPushTransport() PushTransport {
+	pushTransportLock.RLock()
+	defer pushTransportLock.RUnlock()
+	return pushTransport
+}
+
+// Returns the msgEndPoint that this node or agbot should advertise in the exchange, empty when its messages are only
+// delivered through its exchange mailbox.
+func GetPushEndPoint() string {
+	if t := GetPushTransport(); t != nil {
+		return t.EndPoint()
+	}
+	return ""
+}
+
+// The msgEndPoints of the receivers as last seen in the exchange, so that the messages to a receiver can be pushed even
+// when the message target was created with only the public key of the receiver. For the senders of pushed messages, the
+// public key that the exchange has for the sender is kept too.
+type messageEndPoint struct {
+	endPoint  string
+	publicKey []byte
+	seen      time.Time
+}
+
+var messageEndPoints = make(map[string]messageEndPoint)
+var messageEndPointsLock sync.Mutex
+
+// Remember the msgEndPoint that the exchange has for a node or agbot. An empty endpoint forgets it.
+func SetMessageEndPoint(id string, endPoint string) {
+	messageEndPointsLock.Lock()
+	defer messageEndPointsLock.Unlock()
+	if endPoint == "" {
+		delete(messageEndPoints, id)
+	} else {
+		messageEndPoints[id] = messageEndPoint{endPoint: endPoint, seen: time.Now()}
+	}
+}
+
+// Remember the msgEndPoint and the public key that the exchange has for the sender of a pushed message, so that the
+// exchange is not asked for them again for each message that the sender pushes.
+func SetMessageSender(id string, endPoint string, publicKey []byte) {
+	messageEndPointsLock.Lock()
+	defer messageEndPointsLock.Unlock()
+	messageEndPoints[id] = messageEndPoint{endPoint: endPoint, publicKey: publicKey, seen: time.Now()}
+}
+
+// Returns the public key last seen in the exchange for the sender of a pushed message, nil when it is not known or it
+// was seen more than MESSAGE_ENDPOINT_TTL_S seconds ago.
+func GetMessageSenderKey(id string) []byte {
+	messageEndPointsLock.Lock()
+	defer messageEndPointsLock.Unlock()
+	if ep, ok := messageEndPoints[id]; !ok || ep.publicKey == nil {
+		return nil
+	} else if time.Since(ep.seen) > MESSAGE_ENDPOINT_TTL_S*time.Second {
+		delete(messageEndPoints, id)
+		return nil
+	} else {
+		return ep.publicKey
+	}
+}
+
+// Returns the msgEndPoint of the message target, or the one last seen in the exchange for the receiver.
+func GetMessageEndPoint(target *ExchangeMessageTarget) string {
+	if target.ReceiverMsgEndPoint != "" {
+		return target.ReceiverMsgEndPoint
+	}
+
+	messageEndPointsLock.Lock()
+	defer messageEndPointsLock.Unlock()
+	if ep, ok := messageEndPoints[target.ReceiverExchangeId]; !ok {
+		return ""
+	} else if time.Since(ep.seen) > MESSAGE_ENDPOINT_TTL_S*time.Second {
+		delete(messageEndPoints, target.ReceiverExchangeId)
+		return ""
+	} else {
+		return ep.endPoint
+	}
+}
+
+// DeliverMessage sends an encrypted protocol message to the mailbox of the target. The message is pushed when the
+// receiver advertises an endpoint of the push transport and the push transport is connected. Otherwise, or when the push
+// fails, the message is sent through the exchange transport.
+func DeliverMessage(exchangeTransport MessageTransport, mailbox string, target *ExchangeMessageTarget, msg []byte, ttl int) error {
+	endPoint := GetMessageEndPoint(target)
+	if pt := GetPushTransport(); pt != nil && endPoint != "" && pt.Accepts(endPoint) {
+		if !pt.Connected() {
+			glog.V(3).Infof(rpclogString(fmt.Sprintf("%v transport is not connected, sending message for %v through the %v", pt.Name(), target.ReceiverExchangeId, exchangeTransport.Name())))
+		} else if err := pt.SendMessage(mailbox, target.ReceiverExchangeId, endPoint, msg, ttl); err != nil {
+			glog.Warningf(rpclogString(fmt.Sprintf("unable to send message for %v through the %v transport, sending it through the %v, error: %v", target.ReceiverExchangeId, pt.Name(), exchangeTransport.Name(), err)))
+		} else {
+			glog.V(5).Infof(rpclogString(fmt.Sprintf("sent message for %v through the %v transport.", target.ReceiverExchangeId, pt.Name())))
+			return nil
+		}
+	}
+	return exchangeTransport.SendMessage(mailbox, target.ReceiverExchangeId, endPoint, msg, ttl)
+}
+
+// Returns true if the msgEndPoint is an MQTT topic.
+func IsMQTTEndPoint(endPoint string) bool {
+	return strings.HasPrefix(endPoint, MQTT_ENDPOINT_PREFIX)
+}
+
+type PatchMessageEndPointRequest struct {
+	MsgEndPoint string `json:"msgEndPoint"`
+}
+
+func (p PatchMessageEndPointRequest) String() string {
+	return fmt.Sprintf("MsgEndPoint: %v", p.MsgEndPoint)
+}
+
+// PatchMessageEndPoint advertises the msgEndPoint of the node or agbot of the exchange context, in its exchange resource.
+func PatchMessageEndPoint(ec ExchangeContext, mailbox string, endPoint string) error {
+	pr := PatchMessageEndPointRequest{MsgEndPoint: endPoint}
+	var resp interface{}
+	resp = new(PostDeviceResponse)
+	targetURL := ec.GetExchangeURL() + "orgs/" + GetOrg(ec.GetExchangeId()) + "/" + mailbox + "/" + GetId(ec.GetExchangeId())
+
+	httpClientFactory := ec.GetHTTPFactory()
+	retryCount := httpClientFactory.RetryCount
+	retryInterval := httpClientFactory.GetRetryInterval()
+
+	for {
+		if err, tpErr := InvokeExchange(httpClientFactory.NewHTTPClient(nil), "PATCH", targetURL, ec.GetExchangeId(), ec.GetExchangeToken(), &pr, &resp); err != nil {
+			glog.Errorf(rpclogString(err.Error()))
+			return err
+		} else if tpErr != nil {
+			glog.Warningf(rpclogString(tpErr.Error()))
+			if httpClientFactory.RetryCount == 0 {
+				time.Sleep(time.Duration(retryInterval) * time.Second)
+				continue
+			} else if retryCount == 0 {
+				return errors.New(fmt.Sprintf("exceeded %v retries trying to patch the msgEndPoint of %v for %v", httpClientFactory.RetryCount, ec.GetExchangeId(), tpErr))
+			} else {
+				retryCount--
+				time.Sleep(time.Duration(retryInterval) * time.Second)
+				continue
+			}
+		} else {
+			glog.V(3).Infof(rpclogString(fmt.Sprintf("patched msgEndPoint of %v to %v", ec.GetExchangeId(), endPoint)))
+			if mailbox == NODE_MAILBOX {
+				// The cached node has the msgEndPoint from before the patch.
+				DeleteCacheNodeWriteThru(GetOrg(ec.GetExchangeId()), GetId(ec.GetExchangeId()))
+			}
+			return nil
+		}
+	}
+}
@@ -141,8 +141,13 @@ func GetProcessedExchangeNodePolicy(pDevice *persistence.ExchangeDevice, getExch
 			}
 		}
 
+		// the discovered properties that are gone have to be removed from the exchange copy
+		polTemp := exchangeNodePolicy.GetExternalPolicy()
+		if externalpolicy.RemoveUndiscoveredProperties(&polTemp.Properties) {
+			needsBuiltIns = true
+		}
+
 		if needsBuiltIns {
-			polTemp := exchangeNodePolicy.GetExternalPolicy()
 			mergedPol = &polTemp
 			mergedPol.MergeWith(builtinPolicyReadOnly, true)
 			mergedPol.MergeWith(builtinPolicyReadWrite, false)
@@ -214,6 +219,7 @@ func SetDefaultNodePolicy(config *config.HorizonConfig, pDevice *persistence.Exc
 			}
 
 			// add the built-in properties if they are not in the default policy file
+			externalpolicy.RemoveUndiscoveredProperties(&nodePolicy.Properties)
 			if builtinNodePol != nil {
 				nodePolicy.MergeWith(builtinNodePol, true)
 			}
@@ -369,6 +375,8 @@ func UpdateNodePolicy(pDevice *persistence.ExchangeDevice, db *bolt.DB, nodePoli
 	}
 	builtinNodePol, builtinNodePolReadWrite := externalpolicy.CreateNodeBuiltInPolicy(false, false, existingPol, pDevice.IsEdgeCluster())
 
+	// the discovered properties are read-only, only the ones that are currently discovered are kept
+	externalpolicy.RemoveUndiscoveredProperties(&nodePolicy.Properties)
 	if builtinNodePol != nil {
 		nodePolicy.MergeWith(builtinNodePol, true)
 	}
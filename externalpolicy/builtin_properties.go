@@ -4,6 +4,8 @@ import (
 	"github.com/golang/glog"
 	"github.com/open-horizon/anax/cutil"
 	"runtime"
+	"strings"
+	"sync"
 )
 
 // These are built-in property names that can be used in the policies.
@@ -18,6 +20,10 @@ const (
 	PROP_NODE_PRIVILEGED  = "openhorizon.allowPrivileged"   // Property set to determine if privileged services may be run on this device. Can be set by user, default is false.
 	PROP_NODE_K8S_VERSION = "openhorizon.kubernetesVersion" // Server version of the cluster the agent is running in
 
+	// The prefix of the read-only node properties that are found by the node property discovery providers,
+	// e.g. openhorizon.discovered.usb.devices.
+	PROP_NODE_DISCOVERED_PREFIX = "openhorizon.discovered."
+
 	// for service policy
 	PROP_SVC_URL        = "openhorizon.service.url"     // The unique name of the service.
 	PROP_SVC_NAME       = "openhorizon.service.name"    // The unique name of the service.
@@ -33,6 +39,82 @@ func ListReadOnlyProperties() []string {
 	return []string{PROP_NODE_CPU, PROP_NODE_ARCH, PROP_NODE_MEMORY, PROP_NODE_HARDWAREID, PROP_NODE_K8S_VERSION}
 }
 
+// Returns true if the property is one of the read-only built-in properties, including the discovered properties.
+func IsReadOnlyProperty(name string) bool {
+	if IsDiscoveredProperty(name) {
+		return true
+	}
+	for _, prop := range ListReadOnlyProperties() {
+		if prop == name {
+			return true
+		}
+	}
+	return false
+}
+
+// The node properties that were most recently found by the node property discovery providers. They are
+// added to the read-only built-in properties of a device node. discoveryDone is false until the providers
+// have run once, which never happens when node property discovery is disabled.
+var discoveredProperties PropertyList
+var discoveryDone bool
+var discoveredPropertiesLock sync.RWMutex
+
+// Replace the discovered node properties. It returns true if the properties changed, which is always the
+// case the first time, so that the node policy is brought up to date after the agent starts.
+func SetDiscoveredProperties(props PropertyList) bool {
+	discoveredPropertiesLock.Lock()
+	defer discoveredPropertiesLock.Unlock()
+
+	changed := !discoveryDone || len(props) != len(discoveredProperties) || !props.IsSame(discoveredProperties)
+	discoveredProperties = make(PropertyList, len(props))
+	copy(discoveredProperties, props)
+	discoveryDone = true
+	return changed
+}
+
+// Returns a copy of the discovered node properties.
+func GetDiscoveredProperties() PropertyList {
+	discoveredPropertiesLock.RLock()
+	defer discoveredPropertiesLock.RUnlock()
+
+	props := make(PropertyList, len(discoveredProperties))
+	copy(props, discoveredProperties)
+	return props
+}
+
+// Returns true if the property name is in the discovered property name space.
+func IsDiscoveredProperty(name string) bool {
+	return strings.HasPrefix(name, PROP_NODE_DISCOVERED_PREFIX)
+}
+
+// Remove the properties in the discovered property name space that are not currently discovered, e.g. the
+// property of a USB device that was unplugged. It returns true if a property was removed. Nothing is removed
+// until the discovery providers have run once, so that the discovered properties in the node policy survive
+// a restart of the agent, and a node that does not run discovery keeps them.
+func RemoveUndiscoveredProperties(props *PropertyList) bool {
+	discoveredPropertiesLock.RLock()
+	done := discoveryDone
+	discoveredPropertiesLock.RUnlock()
+	if !done {
+		return false
+	}
+
+	current := GetDiscoveredProperties()
+
+	kept := PropertyList{}
+	for _, prop := range *props {
+		if IsDiscoveredProperty(prop.Name) && !current.HasProperty(prop.Name) {
+			glog.V(3).Infof("Removing node property %v, it is no longer discovered.", prop.Name)
+			continue
+		}
+		kept = append(kept, prop)
+	}
+
+	removed := len(kept) != len(*props)
+	*props = kept
+	return removed
+}
+
 // CreateNodeBuiltInPolicy returns 2 externalpolicies.
 // The first contains read-only built-in properties. The second has read/write properties.
 // get the node's built-in ptoperties to be used in the node policy
@@ -140,6 +222,11 @@ func createDeviceNodeBuiltInPolicy(availableMem bool, omitGenHwId bool, existing
 
 	nodeBuiltInReadWriteProps.Add_Property(Property_Factory(PROP_NODE_PRIVILEGED, privileged), false)
 
+	for _, prop := range GetDiscoveredProperties() {
+		p := prop
+		nodeBuiltInReadOnlyProps.Add_Property(&p, false)
+	}
+
 	if availableMem {
 		nodeBuiltInReadOnlyProps.Add_Property(Property_Factory(PROP_NODE_MEMORY, float64(avail_mem)), false)
 	} else {
@@ -0,0 +1,70 @@
+// +build unit
+
+package externalpolicy
+
+import (
+	"testing"
+)
+
+// Forget the discovered properties, as if the agent was restarted.
+func resetDiscoveredProperties() {
+	discoveredProperties = nil
+	discoveryDone = false
+}
+
+func Test_DiscoveredProperties(t *testing.T) {
+	resetDiscoveredProperties()
+	defer resetDiscoveredProperties()
+
+	usb := *Property_Factory(PROP_NODE_DISCOVERED_PREFIX+"usb.devices", "0403:6001")
+	disk := *Property_Factory(PROP_NODE_DISCOVERED_PREFIX+"disk.totalMB", float64(1024))
+
+	if !SetDiscoveredProperties(PropertyList{usb, disk}) {
+		t.Errorf("setting new discovered properties should be a change")
+	} else if SetDiscoveredProperties(PropertyList{usb, disk}) {
+		t.Errorf("setting the same discovered properties should not be a change")
+	}
+
+	if !IsReadOnlyProperty(usb.Name) || !IsReadOnlyProperty(PROP_NODE_CPU) || IsReadOnlyProperty("color") {
+		t.Errorf("only the built-in and discovered properties should be read-only")
+	}
+
+	// The USB device is unplugged.
+	SetDiscoveredProperties(PropertyList{disk})
+	props := PropertyList{*Property_Factory("color", "red"), usb, disk}
+	if !RemoveUndiscoveredProperties(&props) {
+		t.Errorf("the USB property should have been removed")
+	} else if len(props) != 2 || props.HasProperty(usb.Name) || !props.HasProperty("color") || !props.HasProperty(disk.Name) {
+		t.Errorf("only the USB property should have been removed, got %v", props)
+	}
+
+	if RemoveUndiscoveredProperties(&props) {
+		t.Errorf("nothing should be removed the second time, got %v", props)
+	}
+}
+
+// The discovered properties in the node policy are kept until discovery has run after a restart of the agent,
+// or forever when discovery is disabled.
+func Test_RemoveUndiscoveredProperties_restart(t *testing.T) {
+	resetDiscoveredProperties()
+	defer resetDiscoveredProperties()
+
+	usb := *Property_Factory(PROP_NODE_DISCOVERED_PREFIX+"usb.devices", "0403:6001")
+	disk := *Property_Factory(PROP_NODE_DISCOVERED_PREFIX+"disk.totalMB", float64(1024))
+
+	props := PropertyList{*Property_Factory("color", "red"), usb, disk}
+	if RemoveUndiscoveredProperties(&props) {
+		t.Errorf("nothing should be removed before discovery has run, got %v", props)
+	} else if len(props) != 3 {
+		t.Errorf("the discovered properties should have been kept, got %v", props)
+	}
+
+	// The first discovery is a change even when nothing is found, so that the node policy is updated.
+	if !SetDiscoveredProperties(PropertyList{}) {
+		t.Errorf("the first discovery should be a change")
+	} else if !RemoveUndiscoveredProperties(&props) {
+		t.Errorf("the discovered properties should have been removed")
+	} else if len(props) != 1 || !props.HasProperty("color") {
+		t.Errorf("only the discovered properties should have been removed, got %v", props)
+	}
+}
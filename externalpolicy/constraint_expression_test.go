@@ -337,3 +337,86 @@ func Test_MergeWith(t *testing.T) {
 		t.Errorf("Error: constraints %v should have 4 elements but got %v", ce1, len(*ce1))
 	}
 }
+
+// Verify the geo constraint operators.
+func Test_geo_IsSatisfiedBy(t *testing.T) {
+	// Berlin Mitte, Potsdam (about 27km away) and Munich (about 500km away).
+	prop_list := `[{"name":"mitte","value":"52.52,13.405","type":"geo"},{"name":"potsdam","value":"52.3906,13.0645","type":"geo"},{"name":"munich","value":"48.1351,11.582"},{"name":"city","value":"52.52,13.405"}]`
+	props := create_property_list(prop_list, t)
+
+	satisfied := []string{
+		"mitte withinRadius \"52.52,13.405,1km\"",
+		"potsdam withinRadius \"52.52,13.405,30km\"",
+		"potsdam withinRadius \"52.52,13.405,30000m\" && munich withinRadius \"52.52,13.405,400mi\"",
+		"mitte withinPolygon \"52.45,13.30,52.60,13.30,52.60,13.50,52.45,13.50\"",
+		"munich withinPolygon \"52.45,13.30,52.60,13.30,52.60,13.50\" || mitte withinPolygon \"52.45,13.30,52.60,13.50,52.45,13.50\"",
+		"city withinRadius \"52.52,13.405,1km\"",
+	}
+	for _, c := range satisfied {
+		ce := ConstraintExpression{c}
+		if err := ce.IsSatisfiedBy(*props); err != nil {
+			t.Errorf("Error: %v should be satisfied, error: %v", c, err)
+		}
+	}
+
+	unsatisfied := []string{
+		"potsdam withinRadius \"52.52,13.405,20km\"",
+		"munich withinRadius \"52.52,13.405,400km\"",
+		"potsdam withinPolygon \"52.45,13.30,52.60,13.30,52.60,13.50,52.45,13.50\"",
+		"nowhere withinRadius \"52.52,13.405,20000km\"",
+	}
+	for _, c := range unsatisfied {
+		ce := ConstraintExpression{c}
+		if err := ce.IsSatisfiedBy(*props); err == nil {
+			t.Errorf("Error: %v should not be satisfied", c)
+		}
+	}
+
+	// Only geo properties can be within a region.
+	notGeo := `[{"name":"mitte","value":"52.52,13.405","type":"string"},{"name":"potsdam","value":52.39}]`
+	props = create_property_list(notGeo, t)
+	for _, c := range []string{"mitte withinRadius \"52.52,13.405,1km\"", "potsdam withinRadius \"52.52,13.405,100km\""} {
+		ce := ConstraintExpression{c}
+		if err := ce.IsSatisfiedBy(*props); err == nil {
+			t.Errorf("Error: %v should not be satisfied by %v", c, notGeo)
+		}
+	}
+}
+
+// Verify the comparison of date and duration properties.
+func Test_date_duration_IsSatisfiedBy(t *testing.T) {
+	prop_list := `[{"name":"calibrationDate","value":"2026-03-15","type":"date"},{"name":"serviced","value":"2026-03-15T10:00:00+02:00","type":"date"},{"name":"retention","value":"2w","type":"duration"},{"name":"notADate","value":"2026-03-15"}]`
+	props := create_property_list(prop_list, t)
+
+	satisfied := []string{
+		"calibrationDate > 2026-01-01",
+		"calibrationDate >= 2026-03-15 && calibrationDate <= 2026-03-15T00:00:00Z",
+		"calibrationDate == 2026-03-15T01:00:00+01:00",
+		"calibrationDate != 2026-03-16",
+		"serviced < 2026-03-15T09:00:00Z",
+		"retention >= 14d && retention < 15d",
+		"retention == 336h",
+		"calibrationDate < 2020-01-01 || retention > 1w",
+	}
+	for _, c := range satisfied {
+		ce := ConstraintExpression{c}
+		if err := ce.IsSatisfiedBy(*props); err != nil {
+			t.Errorf("Error: %v should be satisfied, error: %v", c, err)
+		}
+	}
+
+	unsatisfied := []string{
+		"calibrationDate > 2026-03-15",
+		"calibrationDate < 2026-01-01T00:00:00Z",
+		"serviced > 2026-03-15T09:00:00Z",
+		"retention > 2w",
+		"retention < 1h30m",
+		"notADate > 2026-01-01",
+	}
+	for _, c := range unsatisfied {
+		ce := ConstraintExpression{c}
+		if err := ce.IsSatisfiedBy(*props); err == nil {
+			t.Errorf("Error: %v should not be satisfied", c)
+		}
+	}
+}
@@ -3,6 +3,8 @@ package externalpolicy
 import (
 	"errors"
 	"fmt"
+	"github.com/open-horizon/anax/datetime"
+	"github.com/open-horizon/anax/geo"
 	"github.com/open-horizon/anax/semanticversion"
 	"strconv"
 	"strings"
@@ -23,8 +25,11 @@ import (
 // _control_operator_    = {"and", "or", "not"}
 // _expression_          = _control_operator_: [_expression_] || property
 // _property_            = "name": _property_name_, "value": _property_value, "op": _comparison_operator_
-// _comparison_operator_ = {"<", "=", ">", "<=", ">=", "!="}
+// _comparison_operator_ = {"<", "=", ">", "<=", ">=", "!=", "in", "withinRadius", "withinPolygon"}
 // The "=" and "!=" comparison operators can be applied to strings and integers.
+// The "withinRadius" and "withinPolygon" operators can only be applied to geo properties. Their values are a
+// circle, e.g. "52.52,13.405,50km", and a polygon, e.g. "52.50,13.30,52.55,13.30,52.55,13.45".
+// The numerical comparison operators can also be applied to date and duration properties.
 // If the "op" key is missing, then equal is assumed.
 //
 // See the unit tests for examples of valid and invalid syntax
@@ -65,6 +70,8 @@ const lessthaneq = "<="
 const greaterthaneq = ">="
 const notequalto = "!="
 const isin = "in"
+const withinradius = "withinRadius"
+const withinpolygon = "withinPolygon"
 
 // This struct represents property value expressions to be satisfied
 type PropertyExpression struct {
@@ -282,7 +289,7 @@ func controlOperators() map[string]int {
 // of the supported comparison operators.
 func comparisonOperators() map[string]int {
 	// return map[string]int {and:0, or:0, not:0}
-	return map[string]int{lessthan: 0, greaterthan: 0, doubleequalto: 0, equalto: 0, lessthaneq: 0, greaterthaneq: 0, notequalto: 0, isin: 0}
+	return map[string]int{lessthan: 0, greaterthan: 0, doubleequalto: 0, equalto: 0, lessthaneq: 0, greaterthaneq: 0, notequalto: 0, isin: 0, withinradius: 0, withinpolygon: 0}
 }
 
 // Return a map of comparison operators that only work on strings
@@ -417,7 +424,11 @@ func propertyInArray(propexp *PropertyExpression, props *[]Property) bool {
 			// These are not the droids we're looking for
 			continue
 		} else {
-			if isFloat64(p.Value) {
+			if propexp.Op == withinradius || propexp.Op == withinpolygon {
+				return geoPropertyWithin(p, propexp)
+			} else if p.Type == DATE_TYPE || p.Type == DURATION_TYPE {
+				return timePropertyCompare(p, propexp)
+			} else if isFloat64(p.Value) {
 				var propexpFloat float64
 				if isFloat64(propexp.Value) {
 					propexpFloat = propexp.Value.(float64)
@@ -489,6 +500,67 @@ func propertyInArray(propexp *PropertyExpression, props *[]Property) bool {
 	return false
 }
 
+// This function checks if a geo property is inside the circle or polygon of a geo property expression.
+func geoPropertyWithin(p Property, propexp *PropertyExpression) bool {
+	if (p.Type != GEO_TYPE && p.Type != UNDECLARED_TYPE) || !isString(p.Value) || !isString(propexp.Value) {
+		return false
+	}
+	point, err := geo.ParsePoint(removeQuotes(p.Value.(string)))
+	if err != nil {
+		return false
+	}
+
+	region := removeSpaces(removeQuotes(propexp.Value.(string)))
+	if propexp.Op == withinradius {
+		circle, err := geo.ParseCircle(region)
+		return err == nil && circle.Contains(*point)
+	}
+	poly, err := geo.ParsePolygon(region)
+	return err == nil && poly.Contains(*point)
+}
+
+// This function compares a date or duration property with the value of a property expression.
+func timePropertyCompare(p Property, propexp *PropertyExpression) bool {
+	if !isString(p.Value) || !isString(propexp.Value) {
+		return false
+	}
+	pValue := removeSpaces(removeQuotes(p.Value.(string)))
+	propexpValue := removeSpaces(removeQuotes(propexp.Value.(string)))
+
+	var pTime, propexpTime int64
+	if p.Type == DATE_TYPE {
+		pDate, err1 := datetime.ParseDate(pValue)
+		propexpDate, err2 := datetime.ParseDate(propexpValue)
+		if err1 != nil || err2 != nil {
+			return false
+		}
+		pTime, propexpTime = pDate.UnixNano(), propexpDate.UnixNano()
+	} else {
+		pDuration, err1 := datetime.ParseDuration(pValue)
+		propexpDuration, err2 := datetime.ParseDuration(propexpValue)
+		if err1 != nil || err2 != nil {
+			return false
+		}
+		pTime, propexpTime = int64(pDuration), int64(propexpDuration)
+	}
+
+	switch propexp.Op {
+	case lessthan:
+		return pTime < propexpTime
+	case greaterthan:
+		return pTime > propexpTime
+	case lessthaneq:
+		return pTime <= propexpTime
+	case greaterthaneq:
+		return pTime >= propexpTime
+	case notequalto:
+		return pTime != propexpTime
+	case equalto, doubleequalto:
+		return pTime == propexpTime
+	}
+	return false
+}
+
 func removeSpaces(value string) string {
 	return strings.Trim(value, " ")
 }
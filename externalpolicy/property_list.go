@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/open-horizon/anax/datetime"
+	"github.com/open-horizon/anax/geo"
 	"github.com/open-horizon/anax/i18n"
 	"strings"
 )
@@ -17,6 +19,9 @@ const (
 	INTEGER_TYPE    = "int"
 	FLOAT_TYPE      = "float"
 	LIST_TYPE       = "list of strings"
+	GEO_TYPE        = "geo"      // A point in decimal degrees, e.g. "52.52,13.405"
+	DATE_TYPE       = "date"     // A date, or a date and time in RFC3339 format, e.g. "2026-01-01"
+	DURATION_TYPE   = "duration" // A duration, e.g. "90d" or "1h30m"
 	UNDECLARED_TYPE = ""
 )
 
@@ -211,7 +216,7 @@ func (self *PropertyList) Validate() error {
 		declaredType := property.Type
 
 		if !isValidPropertyType(declaredType) {
-			return fmt.Errorf(msgPrinter.Sprintf("Property %s has invalid property type %s. Allowed property types are: version, string, int, boolean, float, and list of strings.", property.Name, declaredType))
+			return fmt.Errorf(msgPrinter.Sprintf("Property %s has invalid property type %s. Allowed property types are: version, string, int, boolean, float, list of strings, geo, date, and duration.", property.Name, declaredType))
 		}
 
 		switch actualType := property.Value.(type) {
@@ -242,6 +247,18 @@ func (self *PropertyList) Validate() error {
 				if !IsVersionString(stringVal) {
 					return fmt.Errorf(msgPrinter.Sprintf("Property %s with value %v is not a valid verion string", property.Name, property.Value))
 				}
+			} else if declaredType == GEO_TYPE {
+				if _, err := geo.ParsePoint(stringVal); err != nil {
+					return fmt.Errorf(msgPrinter.Sprintf("Property %s with value %v is not a valid geo point: %v", property.Name, property.Value, err))
+				}
+			} else if declaredType == DATE_TYPE {
+				if _, err := datetime.ParseDate(stringVal); err != nil {
+					return fmt.Errorf(msgPrinter.Sprintf("Property %s with value %v is not a valid date: %v", property.Name, property.Value, err))
+				}
+			} else if declaredType == DURATION_TYPE {
+				if _, err := datetime.ParseDuration(stringVal); err != nil {
+					return fmt.Errorf(msgPrinter.Sprintf("Property %s with value %v is not a valid duration: %v", property.Name, property.Value, err))
+				}
 			} else if declaredType != STRING_TYPE && declaredType != UNDECLARED_TYPE && declaredType != LIST_TYPE {
 				return fmt.Errorf(msgPrinter.Sprintf("Property value is of type %T, expected type %s", actualType, declaredType))
 			}
@@ -281,7 +298,7 @@ func IsVersionString(expr string) bool {
 }
 
 func isValidPropertyType(typeInput string) bool {
-	validTypes := []string{STRING_TYPE, VERSION_TYPE, BOOLEAN_TYPE, INTEGER_TYPE, FLOAT_TYPE, LIST_TYPE, UNDECLARED_TYPE}
+	validTypes := []string{STRING_TYPE, VERSION_TYPE, BOOLEAN_TYPE, INTEGER_TYPE, FLOAT_TYPE, LIST_TYPE, GEO_TYPE, DATE_TYPE, DURATION_TYPE, UNDECLARED_TYPE}
 	for _, validType := range validTypes {
 		if validType == typeInput {
 			return true
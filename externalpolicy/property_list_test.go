@@ -176,6 +176,19 @@ func Test_Validate_valid(t *testing.T) {
 			t.Errorf("Error: %v has only valid properties but gave error: %v\n", p1, err)
 		}
 	}
+	p1 = `[{"name":"prop1","value":"2026-01-01","type":"date"},{"name":"prop2","value":"2026-01-01T08:30:00Z","type":"date"},{"name":"prop3","value":"1h30m","type":"duration"}]`
+	if pl1 := create_PropertyList(p1, t); pl1 != nil {
+		if err := pl1.Validate(); err != nil {
+			t.Errorf("Error: %v has only valid properties but gave error: %v\n", p1, err)
+		}
+	}
+
+	p1 = `[{"name":"prop1","value":"52.52,13.405","type":"geo"},{"name":"prop2","value":"-33.8688, 151.2093","type":"geo"}]`
+	if pl1 := create_PropertyList(p1, t); pl1 != nil {
+		if err := pl1.Validate(); err != nil {
+			t.Errorf("Error: %v has only valid properties but gave error: %v\n", p1, err)
+		}
+	}
 	p1 = `[{"name":"prop1","value":[1,2]}]`
 	if pl1 := create_PropertyList(p1, t); pl1 != nil {
 		if err := pl1.Validate(); err == nil {
@@ -238,6 +251,21 @@ func Test_Validate_invalid(t *testing.T) {
 			t.Errorf("Error: %v has invalid properties but gave no error\n", p1)
 		}
 	}
+	for _, p1 = range []string{`[{"name":"prop1","value":"tomorrow","type":"date"}]`, `[{"name":"prop1","value":20260101,"type":"date"}]`, `[{"name":"prop1","value":"10y","type":"duration"}]`} {
+		if pl1 := create_PropertyList(p1, t); pl1 != nil {
+			if err := pl1.Validate(); err == nil {
+				t.Errorf("Error: %v has invalid properties but gave no error\n", p1)
+			}
+		}
+	}
+	for _, v := range []string{`"berlin"`, `"52.52"`, `"91.0,13.405"`, `"52.52,181.0"`, `[52.52,13.405]`} {
+		p1 = `[{"name":"prop1","value":` + v + `,"type":"geo"}]`
+		if pl1 := create_PropertyList(p1, t); pl1 != nil {
+			if err := pl1.Validate(); err == nil {
+				t.Errorf("Error: %v has invalid properties but gave no error\n", p1)
+			}
+		}
+	}
 }
 
 func Test_add_property(t *testing.T) {
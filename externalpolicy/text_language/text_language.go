@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"github.com/alecthomas/participle/lexer"
 	"github.com/alecthomas/participle/lexer/ebnf"
+	"github.com/open-horizon/anax/datetime"
 	"github.com/open-horizon/anax/externalpolicy/plugin_registry"
+	"github.com/open-horizon/anax/geo"
 	"github.com/open-horizon/anax/i18n"
 	"github.com/open-horizon/anax/semanticversion"
 	"strconv"
@@ -114,7 +116,7 @@ func (p *TextConstraintLanguagePlugin) GetNextExpression(expression string) (str
 		}
 
 		nextRune = nextToken.Type
-		if nextRune != def["OpEq"] && nextRune != def["OpComp"] && nextRune != def["OpIn"] {
+		if nextRune != def["OpEq"] && nextRune != def["OpComp"] && nextRune != def["OpIn"] && nextRune != def["OpGeo"] {
 			if len(name) > 3 && name[len(name)-2:] == "in" {
 				op = "in"
 				opType = def["in"]
@@ -135,7 +137,7 @@ func (p *TextConstraintLanguagePlugin) GetNextExpression(expression string) (str
 			nextRune = nextToken.Type
 		}
 
-		if nextRune != def["Str"] && nextRune != def["InStr"] && nextRune != def["QuoteStr"] && nextRune != def["ListStr"] && nextRune != def["Vers"] && nextRune != def["VersRange"] && nextRune != def["Num"] {
+		if nextRune != def["Str"] && nextRune != def["InStr"] && nextRune != def["QuoteStr"] && nextRune != def["ListStr"] && nextRune != def["Vers"] && nextRune != def["VersRange"] && nextRune != def["Num"] && nextRune != def["Date"] && nextRune != def["Dur"] {
 			return "", expression, fmt.Errorf("Invalid property value. %v%v%v", name, op, nextToken.Value)
 		}
 		if val == "" {
@@ -193,6 +195,8 @@ func (p *TextConstraintLanguagePlugin) GetNextOperator(expression string) (strin
 // 4. for string types, a quoted string, inside which is a list of comma separated strings provide acceptable values
 // 5. string values that contain spaces must be quoted
 // 6. for the version type, supported values are a single version or a range of versions in the semantic version format (the same as used for service verions). The == operator implies that the value is a single version. The 'in' operator treats the value as a version range. As with service versions, the version 1.0.0 when treated as a version range is equivalent to the explicit range [1.0.0,INFINITY).
+// 7. for the date and duration types, the operators ==, <, >, <=, >=, != are supported. A date is YYYY-MM-DD or a date and time in RFC3339 format, e.g. 2026-01-01T08:30:00Z. A duration is a sequence of numbers with a unit of w, d, h, m or s, e.g. 90d or 1h30m.
+// 8. for the geo type, the withinRadius operator takes a quoted circle, e.g. "52.52,13.405,50km", and the withinPolygon operator takes a quoted list of at least 3 latitude,longitude vertices.
 
 // This function checks that the operator is valid for the specified value and validates version ranges with the semanticversion Factory function
 // Returns a property expression struct with numerical values as float64
@@ -208,11 +212,33 @@ func validOpValuePair(name string, op string, opType rune, val interface{}, valT
 			return fmt.Errorf("Property type list of strings can only use operator 'in'.")
 		}
 	}
-	if lexMap["OpComp"] == opType {
+	if lexMap["Date"] == valType {
+		if _, err := datetime.ParseDate(val.(string)); err != nil {
+			return err
+		}
+	} else if lexMap["Dur"] == valType {
+		if _, err := datetime.ParseDuration(val.(string)); err != nil {
+			return err
+		}
+	} else if lexMap["OpComp"] == opType {
 		if _, err := strconv.ParseFloat(val.(string), 64); err != nil {
 			return fmt.Errorf("Cannot use numerical comparison operator %s with value %v.", op, val)
 		}
 	}
+	if lexMap["OpGeo"] == opType {
+		if lexMap["ListStr"] != valType {
+			return fmt.Errorf("The %s operator can only be used with a quoted list of coordinates.", strings.TrimSpace(op))
+		}
+		region := strings.Trim(strings.TrimSpace(val.(string)), "\x22")
+		if strings.TrimSpace(op) == "withinRadius" {
+			_, err = geo.ParseCircle(region)
+		} else {
+			_, err = geo.ParsePolygon(region)
+		}
+		if err != nil {
+			return err
+		}
+	}
 	if lexMap["OpIn"] == opType {
 		if lexMap["ListStr"] != valType && lexMap["QuoteStr"] != valType && lexMap["VersRange"] != valType && lexMap["Vers"] != valType {
 			return fmt.Errorf("The 'in' operator can only be used for types version and list of strings")
@@ -242,12 +268,16 @@ func getLexer() lexer.Definition {
 	  AndOp = whitespace {whitespace} ("AND" | "&&") whitespace {whitespace} .
 	  OrOp = whitespace {whitespace} ("OR" | "||") whitespace {whitespace} .
 
+		OpGeo = whitespace {whitespace} "within" ("Radius" | "Polygon") whitespace {whitespace} .
 		OpComp =  {whitespace} ( ["="] (">" | "<") ["="] ) {whitespace} .
 		OpIn =  {whitespace} "in" {whitespace} .
 	  OpEq =  {whitespace}  ( "!=" | "="["="] )  {whitespace} .
 
 	  VersRange = {whitespace}  ( "(" | "[" )  vers {whitespace}  "," {whitespace}  (vers | "INFINITY")  ("]" | ")").
 		Vers = {whitespace}  vers .
+	  Date = {whitespace} digit digit digit digit "-" digit digit "-" digit digit ["T" digit digit ":" digit digit [":" digit digit ["." digit {digit}]] ["Z" | ("+" | "-") digit digit ":" digit digit]] .
+	  Dur = {whitespace} digit {digit} durunit {digit {digit} durunit} .
+	  durunit = "w" | "d" | "h" | "m" | "s" .
 	  Num = {whitespace} ["-"] digit {digit} ["." {digit}] .
 	  whitespace = "\n" | "\r" | "\t" | " " .
 	  OpenParen = {whitespace} "(" {whitespace} .
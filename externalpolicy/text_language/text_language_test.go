@@ -340,3 +340,78 @@ func Test_GetNextExpression_Succeed(t *testing.T) {
 	}
 
 }
+
+func Test_Validate_Geo(t *testing.T) {
+	textConstraintLanguagePlugin := NewTextConstraintLanguagePlugin()
+
+	valid := []string{
+		"location withinRadius \"52.52,13.405,50km\"",
+		"location withinRadius \"52.52, 13.405, 500m\" AND purpose == depot",
+		"location withinPolygon \"52.50,13.30,52.55,13.30,52.55,13.45,52.50,13.45\" || location withinRadius \"48.13,11.58,10mi\"",
+	}
+	for _, c := range valid {
+		if validated, _, err := textConstraintLanguagePlugin.Validate(interface{}([]string{c})); !validated || err != nil {
+			t.Errorf("%v should validate, err: %v", c, err)
+		}
+	}
+
+	invalid := []string{
+		"location withinRadius \"52.52,13.405\"",
+		"location withinRadius \"52.52,13.405,far\"",
+		"location withinRadius \"95.0,13.405,50km\"",
+		"location withinPolygon \"52.50,13.30,52.55,13.30\"",
+		"location withinPolygon 52.50",
+		"location withinRadius berlin",
+	}
+	for _, c := range invalid {
+		if validated, _, err := textConstraintLanguagePlugin.Validate(interface{}([]string{c})); validated && err == nil {
+			t.Errorf("%v should not validate", c)
+		}
+	}
+
+	// The property name is separated from the operator.
+	if exp, rem, err := textConstraintLanguagePlugin.GetNextExpression("location withinRadius \"52.52,13.405,50km\" && a == b"); err != nil {
+		t.Errorf("unexpected error %v", err)
+	} else if exp != "location\awithinRadius\a\"52.52,13.405,50km\"" {
+		t.Errorf("unexpected expression %q", exp)
+	} else if rem != " && a == b" {
+		t.Errorf("unexpected remainder %q", rem)
+	}
+}
+
+func Test_Validate_DateDuration(t *testing.T) {
+	textConstraintLanguagePlugin := NewTextConstraintLanguagePlugin()
+
+	valid := []string{
+		"calibrationDate > 2026-01-01",
+		"calibrationDate >= 2026-01-01T08:30:00Z AND calibrationDate < 2027-01-01T08:30:00+01:00",
+		"calibrationDate == 2026-01-01 || uptime <= 90d",
+		"maxAge < 1h30m && cpu > 2",
+		"(retention >= 2w) OR retention == 0s",
+	}
+	for _, c := range valid {
+		if validated, _, err := textConstraintLanguagePlugin.Validate(interface{}([]string{c})); !validated || err != nil {
+			t.Errorf("%v should validate, err: %v", c, err)
+		}
+	}
+
+	invalid := []string{
+		"calibrationDate > 2026-13-01",
+		"calibrationDate in 2026-01-01",
+		"calibrationDate > 2026-01",
+		"maxAge < 10y",
+	}
+	for _, c := range invalid {
+		if validated, _, err := textConstraintLanguagePlugin.Validate(interface{}([]string{c})); validated && err == nil {
+			t.Errorf("%v should not validate", c)
+		}
+	}
+
+	if exp, rem, err := textConstraintLanguagePlugin.GetNextExpression("calibrationDate > 2026-01-01T08:30:00Z AND maxAge < 1h30m"); err != nil {
+		t.Errorf("unexpected error %v", err)
+	} else if exp != "calibrationDate\a>\a2026-01-01T08:30:00Z" {
+		t.Errorf("unexpected expression %q", exp)
+	} else if rem != " AND maxAge < 1h30m" {
+		t.Errorf("unexpected remainder %q", rem)
+	}
+}
@@ -0,0 +1,164 @@
+package geo
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/open-horizon/anax/i18n"
+)
+
+// Geospatial values are written as comma separated decimal degrees so that they can be used as property
+// values and in the text constraint language.
+//
+// A point is latitude,longitude, e.g. "52.52,13.405".
+//
+// A circle is latitude,longitude,radius where the radius is a number with an optional unit of km (the
+// default), m or mi, e.g. "52.52,13.405,50km".
+//
+// A polygon is a list of at least 3 latitude,longitude vertices, e.g. "52.50,13.30,52.55,13.30,52.55,13.45".
+// The polygon is closed automatically. The edges of a polygon are straight lines in latitude and longitude,
+// which is accurate enough for polygons the size of a city, and a polygon cannot cross the 180th meridian.
+
+// The mean radius of the earth in km.
+const EARTH_RADIUS_KM = 6371.0
+
+type Point struct {
+	Lat float64
+	Lon float64
+}
+
+func (p Point) String() string {
+	return fmt.Sprintf("%v,%v", p.Lat, p.Lon)
+}
+
+type Circle struct {
+	Center   Point
+	RadiusKm float64
+}
+
+func (c Circle) String() string {
+	return fmt.Sprintf("%v,%vkm", c.Center, c.RadiusKm)
+}
+
+type Polygon []Point
+
+// Parse a point, e.g. "52.52,13.405".
+func ParsePoint(s string) (*Point, error) {
+	nums, err := parseNumbers(s)
+	if err != nil {
+		return nil, err
+	} else if len(nums) != 2 {
+		return nil, errors.New(i18n.GetMessagePrinter().Sprintf("%v is not a geo point, it must be latitude,longitude", s))
+	}
+	return newPoint(nums[0], nums[1])
+}
+
+// Parse a circle, e.g. "52.52,13.405,50km".
+func ParseCircle(s string) (*Circle, error) {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	parts := strings.Split(s, ",")
+	if len(parts) != 3 {
+		return nil, errors.New(msgPrinter.Sprintf("%v is not a geo circle, it must be latitude,longitude,radius", s))
+	}
+
+	center, err := ParsePoint(strings.Join(parts[:2], ","))
+	if err != nil {
+		return nil, err
+	}
+
+	radius := strings.TrimSpace(parts[2])
+	factor := 1.0
+	switch {
+	case strings.HasSuffix(radius, "km"):
+		radius = strings.TrimSuffix(radius, "km")
+	case strings.HasSuffix(radius, "mi"):
+		radius = strings.TrimSuffix(radius, "mi")
+		factor = 1.609344
+	case strings.HasSuffix(radius, "m"):
+		radius = strings.TrimSuffix(radius, "m")
+		factor = 0.001
+	}
+	r, err := strconv.ParseFloat(strings.TrimSpace(radius), 64)
+	if err != nil || r < 0 || math.IsInf(r, 0) || math.IsNaN(r) {
+		return nil, errors.New(msgPrinter.Sprintf("the radius of geo circle %v must be a positive number with an optional unit of km, m or mi", s))
+	}
+
+	return &Circle{Center: *center, RadiusKm: r * factor}, nil
+}
+
+// Parse a polygon, e.g. "52.50,13.30,52.55,13.30,52.55,13.45".
+func ParsePolygon(s string) (Polygon, error) {
+	nums, err := parseNumbers(s)
+	if err != nil {
+		return nil, err
+	} else if len(nums) < 6 || len(nums)%2 != 0 {
+		return nil, errors.New(i18n.GetMessagePrinter().Sprintf("%v is not a geo polygon, it must be a list of at least 3 latitude,longitude pairs", s))
+	}
+
+	poly := make(Polygon, 0, len(nums)/2)
+	for i := 0; i < len(nums); i += 2 {
+		p, err := newPoint(nums[i], nums[i+1])
+		if err != nil {
+			return nil, err
+		}
+		poly = append(poly, *p)
+	}
+	return poly, nil
+}
+
+// Returns the great circle distance in km between 2 points.
+func Distance(a, b Point) float64 {
+	lat1 := toRadians(a.Lat)
+	lat2 := toRadians(b.Lat)
+	dLat := lat2 - lat1
+	dLon := toRadians(b.Lon - a.Lon)
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * EARTH_RADIUS_KM * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// Returns true if the point is inside the circle or on its edge.
+func (c Circle) Contains(p Point) bool {
+	return Distance(c.Center, p) <= c.RadiusKm
+}
+
+// Returns true if the point is inside the polygon. A point on an edge may be inside or outside.
+func (poly Polygon) Contains(p Point) bool {
+	inside := false
+	for i, j := 0, len(poly)-1; i < len(poly); j, i = i, i+1 {
+		a, b := poly[i], poly[j]
+		if (a.Lat > p.Lat) != (b.Lat > p.Lat) && p.Lon < (b.Lon-a.Lon)*(p.Lat-a.Lat)/(b.Lat-a.Lat)+a.Lon {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+func newPoint(lat float64, lon float64) (*Point, error) {
+	if lat < -90 || lat > 90 {
+		return nil, errors.New(i18n.GetMessagePrinter().Sprintf("latitude %v must be between -90 and 90", lat))
+	} else if lon < -180 || lon > 180 {
+		return nil, errors.New(i18n.GetMessagePrinter().Sprintf("longitude %v must be between -180 and 180", lon))
+	}
+	return &Point{Lat: lat, Lon: lon}, nil
+}
+
+func parseNumbers(s string) ([]float64, error) {
+	nums := []float64{}
+	for _, n := range strings.Split(s, ",") {
+		f, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+			return nil, errors.New(i18n.GetMessagePrinter().Sprintf("%v is not a number of degrees in %v", strings.TrimSpace(n), s))
+		}
+		nums = append(nums, f)
+	}
+	return nums, nil
+}
+
+func toRadians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
@@ -0,0 +1,92 @@
+// +build unit
+
+package geo
+
+import (
+	"math"
+	"testing"
+)
+
+func Test_ParsePoint(t *testing.T) {
+	if p, err := ParsePoint(" 52.52, 13.405 "); err != nil {
+		t.Errorf("unexpected error %v", err)
+	} else if p.Lat != 52.52 || p.Lon != 13.405 {
+		t.Errorf("unexpected point %v", p)
+	}
+
+	for _, s := range []string{"", "52.52", "52.52,13.405,1", "north,east", "-90.1,0", "0,180.5", "NaN,0"} {
+		if _, err := ParsePoint(s); err == nil {
+			t.Errorf("%v should not be a valid point", s)
+		}
+	}
+}
+
+func Test_ParseCircle(t *testing.T) {
+	tests := []struct {
+		s      string
+		radius float64
+	}{
+		{"52.52,13.405,50", 50},
+		{"52.52,13.405,50km", 50},
+		{"52.52,13.405, 500m", 0.5},
+		{"52.52,13.405,10mi", 16.09344},
+	}
+	for _, tc := range tests {
+		if c, err := ParseCircle(tc.s); err != nil {
+			t.Errorf("%v: unexpected error %v", tc.s, err)
+		} else if math.Abs(c.RadiusKm-tc.radius) > 1e-9 || c.Center.Lat != 52.52 || c.Center.Lon != 13.405 {
+			t.Errorf("%v: unexpected circle %v", tc.s, c)
+		}
+	}
+
+	for _, s := range []string{"52.52,13.405", "52.52,13.405,-1km", "52.52,13.405,far", "52.52,13.405,5ft", "100,13.405,5"} {
+		if _, err := ParseCircle(s); err == nil {
+			t.Errorf("%v should not be a valid circle", s)
+		}
+	}
+}
+
+func Test_Distance(t *testing.T) {
+	berlin := Point{52.52, 13.405}
+	munich := Point{48.1351, 11.582}
+	if d := Distance(berlin, munich); math.Abs(d-504) > 2 {
+		t.Errorf("the distance from Berlin to Munich should be about 504km, got %v", d)
+	}
+	if d := Distance(berlin, berlin); d != 0 {
+		t.Errorf("the distance from a point to itself should be 0, got %v", d)
+	}
+	if d := Distance(Point{0, 179.9}, Point{0, -179.9}); math.Abs(d-22.2) > 0.1 {
+		t.Errorf("the distance across the 180th meridian should be about 22.2km, got %v", d)
+	}
+
+	c := Circle{Center: berlin, RadiusKm: 505}
+	if !c.Contains(munich) || (Circle{Center: berlin, RadiusKm: 500}).Contains(munich) {
+		t.Errorf("only the larger circle should contain Munich")
+	}
+}
+
+func Test_Polygon(t *testing.T) {
+	if _, err := ParsePolygon("52.50,13.30,52.55,13.30"); err == nil {
+		t.Errorf("a polygon needs at least 3 vertices")
+	} else if _, err := ParsePolygon("52.50,13.30,52.55,13.30,52.55"); err == nil {
+		t.Errorf("a polygon needs pairs of coordinates")
+	}
+
+	// An L shaped polygon.
+	poly, err := ParsePolygon("0,0, 0,10, 5,10, 5,5, 10,5, 10,0")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	inside := []Point{{1, 1}, {4, 9}, {9, 4}}
+	outside := []Point{{7, 7}, {-1, 1}, {11, 1}, {1, 11}}
+	for _, p := range inside {
+		if !poly.Contains(p) {
+			t.Errorf("%v should be inside the polygon", p)
+		}
+	}
+	for _, p := range outside {
+		if poly.Contains(p) {
+			t.Errorf("%v should be outside the polygon", p)
+		}
+	}
+}
@@ -15,7 +15,7 @@ require (
 	github.com/davecgh/go-spew v1.1.2-0.20180830191138-d8f796af33cc // indirect
 	github.com/docker/docker v1.4.2-0.20200227192531-bc1c0c7a8a9c // indirect
 	github.com/docker/go-connections v0.4.1-0.20180821093606-97c2040d34df // indirect
-	github.com/eclipse/paho.mqtt.golang v1.2.0 // indirect
+	github.com/eclipse/paho.mqtt.golang v1.2.0
 	github.com/etcd-io/bbolt v1.3.3-0.20190528202153-2eb7227adea1 // indirect
 	github.com/fsouza/go-dockerclient v1.6.4
 	github.com/golang/glog v0.0.0-20160126235308-23def4e6c14b
@@ -303,3 +303,35 @@ func (c ServiceChangeCommand) ShortString() string {
 func NewServiceChangeCommand() *ServiceChangeCommand {
 	return &ServiceChangeCommand{}
 }
+
+// ==============================================================================================================
+// A service reported a change in its health through the workload API.
+type WorkloadReportedCommand struct {
+	Report persistence.WorkloadReport
+}
+
+func (c WorkloadReportedCommand) ShortString() string {
+	return fmt.Sprintf("WorkloadReportedCommand: Report %v", c.Report)
+}
+
+func NewWorkloadReportedCommand(report persistence.WorkloadReport) *WorkloadReportedCommand {
+	return &WorkloadReportedCommand{
+		Report: report,
+	}
+}
+
+// ==============================================================================================================
+// Rotate the messaging key of the node.
+type MessageKeyRotateCommand struct {
+	Reason string
+}
+
+func (c MessageKeyRotateCommand) ShortString() string {
+	return fmt.Sprintf("MessageKeyRotateCommand: Reason %v", c.Reason)
+}
+
+func NewMessageKeyRotateCommand(reason string) *MessageKeyRotateCommand {
+	return &MessageKeyRotateCommand{
+		Reason: reason,
+	}
+}
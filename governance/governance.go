@@ -18,6 +18,7 @@ import (
 	"github.com/open-horizon/anax/persistence"
 	"github.com/open-horizon/anax/policy"
 	"github.com/open-horizon/anax/producer"
+	"github.com/open-horizon/anax/tracing"
 	"github.com/open-horizon/anax/worker"
 	"net/http"
 	"strconv"
@@ -37,6 +38,7 @@ const MICROSERVICE_GOVERNOR = "MicroserviceGovernor"
 const BC_GOVERNOR = "BlockchainGovernor"
 const SURFACEERRORS = "SurfaceExchErrors"
 const NODESTATUS = "NodeStatus"
+const MESSAGE_KEY_ROTATION = "MessageKeyRotation"
 
 // Keys for the exchange errors cache in the worker
 const EXCHANGE_ERRORS = "ExchangeErrors"
@@ -54,6 +56,7 @@ type GovernanceWorker struct {
 	limitedRetryEC    exchange.ExchangeContext
 	exchErrors        cache.Cache
 	noworkDispatch    int64 // The last time the NoWorkHandler was dispatched.
+	messageKey        MessageKeyState
 }
 
 func NewGovernanceWorker(name string, cfg *config.HorizonConfig, db *bolt.DB, pm *policy.PolicyManager) *GovernanceWorker {
@@ -284,6 +287,17 @@ func (w *GovernanceWorker) NewEvent(incoming events.Message) {
 			cmd := w.NewReportDeviceStatusCommand()
 			w.Commands <- cmd
 		}
+	case *events.WorkloadReportMessage:
+		msg, _ := incoming.(*events.WorkloadReportMessage)
+		switch msg.Event().Id {
+		case events.WORKLOAD_REPORTED:
+			// The service decides how often it reports, the node status in the exchange is only updated when its health changes.
+			if msg.Report.Health != msg.PreviousHealth {
+				w.Commands <- NewWorkloadReportedCommand(msg.Report)
+				w.Commands <- w.NewReportDeviceStatusCommand()
+			}
+		}
+
 	case *events.MicroserviceContainersDestroyedMessage:
 		msg, _ := incoming.(*events.MicroserviceContainersDestroyedMessage)
 
@@ -309,6 +323,13 @@ func (w *GovernanceWorker) NewEvent(incoming events.Message) {
 			w.Commands <- worker.NewTerminateCommand("shutdown")
 		}
 
+	case *events.MessageKeyRotateMessage:
+		msg, _ := incoming.(*events.MessageKeyRotateMessage)
+		switch msg.Event().Id {
+		case events.ROTATE_MESSAGE_KEY:
+			w.Commands <- NewMessageKeyRotateCommand(exchange.MESSAGE_KEY_ROTATION_REQUESTED)
+		}
+
 	case *events.NodeHeartbeatStateChangeMessage:
 		msg, _ := incoming.(*events.NodeHeartbeatStateChangeMessage)
 		switch msg.Event().Id {
@@ -631,6 +652,10 @@ func (w *GovernanceWorker) cancelAgreement(agreementId string, agreementProtocol
 	} else {
 		ag = &agreements[0]
 
+		span := tracing.StartAgreementSpan("node.cancel", agreementId)
+		span.SetAttribute("cancel.reason", desc)
+		defer span.End()
+
 		if !ag.Archived && ag.AgreementTerminatedTime == 0 {
 			// Update the database
 			if _, err := persistence.AgreementStateTerminated(w.db, agreementId, uint64(reason), desc, agreementProtocol); err != nil {
@@ -662,6 +687,7 @@ func (w *GovernanceWorker) cancelAgreement(agreementId string, agreementProtocol
 
 		// If we can do the termination now, do it. Otherwise we will queue a command to do it later.
 		w.externalTermination(ag, agreementId, agreementProtocol, reason)
+		defer tracing.ForgetAgreement(agreementId)
 		if !w.producerPH[agreementProtocol].IsBlockchainWritable(ag) {
 			// create deferred external termination command
 			w.Commands <- NewAsyncTerminationCommand(agreementId, agreementProtocol, reason)
@@ -753,6 +779,14 @@ func (w *GovernanceWorker) Initialize() bool {
 	// Fire up the microservice governor
 	w.DispatchSubworker(MICROSERVICE_GOVERNOR, w.governMicroservices, 60, false)
 
+	// A rotated key might not have been published before the agent restarted, publishing it again does no harm.
+	w.messageKey.Pending = exchange.GetPreviousPrivateKey() != nil
+
+	// rotate the messaging key on its schedule
+	if w.Config.MessageKeyRotation.IsScheduled() {
+		w.DispatchSubworker(MESSAGE_KEY_ROTATION, w.messageKeyRotationCheck, MESSAGE_KEY_ROTATION_CHECK_S, false)
+	}
+
 	// for the policy case update the exchange with the latest registeredServices
 	if w.devicePattern == "" {
 		w.UpdateRegisteredServicesWithAgreement()
@@ -851,6 +885,8 @@ func (w *GovernanceWorker) CommandHandler(command worker.Command) bool {
 
 			// ReplyAck messages could indicate that the agbot has decided not to pursue the agreement any longer.
 			if replyAck, err := protocolHandler.ValidateReplyAck(protocolMsg); err == nil {
+				span := tracing.StartRemoteAgreementSpan("node.replyack", replyAck.AgreementId(), replyAck.TraceContext())
+				span.SetAttribute("replyack.valid", replyAck.ReplyAgreementStillValid())
 				err_log_msg := ""
 				ags := []persistence.EstablishedAgreement{}
 				var err error
@@ -907,7 +943,9 @@ func (w *GovernanceWorker) CommandHandler(command worker.Command) bool {
 							persistence.NewMessageMeta(EL_GOV_ERR_HANDLE_REPLYACK_MSG, err_log_msg),
 							persistence.EC_ERROR_PROCESSING_REPLYACT_MESSAGE, replyAck.AgreementId(), persistence.WorkloadInfo{}, []persistence.ServiceSpec{}, "", replyAck.Protocol())
 					}
+					span.SetError(errors.New(err_log_msg))
 				}
+				span.End()
 
 			} else if dataReceived, err := protocolHandler.ValidateDataReceived(protocolMsg); err == nil {
 
@@ -997,6 +1035,7 @@ func (w *GovernanceWorker) CommandHandler(command worker.Command) bool {
 
 			} else if canReceived, err := protocolHandler.ValidateCancel(protocolMsg); err == nil {
 				// Cancel messages indicate that the agbot wants to get rid of the agreement.
+				tracing.StartRemoteAgreementSpan("node.cancel_received", canReceived.AgreementId(), canReceived.TraceContext()).End()
 
 				err_log_msg := ""
 				ags := []persistence.EstablishedAgreement{}
@@ -1376,6 +1415,14 @@ func (w *GovernanceWorker) CommandHandler(command worker.Command) bool {
 	case *ServiceChangeCommand:
 		w.governMicroserviceVersions()
 
+	case *WorkloadReportedCommand:
+		cmd, _ := command.(*WorkloadReportedCommand)
+		w.handleWorkloadReport(&cmd.Report)
+
+	case *MessageKeyRotateCommand:
+		cmd, _ := command.(*MessageKeyRotateCommand)
+		w.rotateMessageKey(cmd.Reason)
+
 	default:
 		return false
 	}
@@ -1390,6 +1437,7 @@ func (w *GovernanceWorker) NoWorkHandler() {
 	// Make sure that all known agreements are maintained, if we're not shutting down.
 	if !w.IsWorkerShuttingDown() {
 		w.governAgreements()
+		w.retryMessageKeyPublish()
 	}
 
 	// When all subworkers are down, start the shutdown process.
@@ -1444,7 +1492,13 @@ func (w *GovernanceWorker) finalizeAgreement(agreement persistence.EstablishedAg
 	return nil
 }
 
-func (w *GovernanceWorker) RecordReply(proposal abstractprotocol.Proposal, protocol string) error {
+func (w *GovernanceWorker) RecordReply(proposal abstractprotocol.Proposal, protocol string) (err error) {
+
+	span := tracing.StartAgreementSpan("node.finalize", proposal.AgreementId())
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
 
 	// Update the agreement state in the database and in the exchange.
 	if ag, err := persistence.AgreementStateAccepted(w.db, proposal.AgreementId(), protocol); err != nil {
@@ -1526,6 +1580,13 @@ func (w *GovernanceWorker) RecordReply(proposal abstractprotocol.Proposal, proto
 			w.BaseWorker.Manager.Config.GetFileSyncServiceAPIListen(),
 			strconv.Itoa(int(w.BaseWorker.Manager.Config.GetFileSyncServiceAPIPort())))
 
+		if !w.Config.Edge.WorkloadAPI.Disabled {
+			cutil.SetWorkloadAPIEnvvars(envAdds,
+				config.ENVVAR_PREFIX,
+				w.Config.GetWorkloadAPIListen(),
+				strconv.Itoa(int(w.Config.GetWorkloadAPIPort())))
+		}
+
 		lc.EnvironmentAdditions = &envAdds
 
 		if w.deviceType == persistence.DEVICE_TYPE_DEVICE {
@@ -1832,6 +1893,10 @@ func (w *GovernanceWorker) deleteProducerAgreement(url string, deviceId string,
 }
 
 func (w *GovernanceWorker) deleteMessage(msg *exchange.DeviceMessage) error {
+	if msg.MsgId == exchange.PUSHED_MESSAGE_ID {
+		return nil
+	}
+
 	var resp interface{}
 	resp = new(exchange.PostDeviceResponse)
 	targetURL := w.GetExchangeURL() + "orgs/" + exchange.GetOrg(w.GetExchangeId()) + "/nodes/" + exchange.GetId(w.GetExchangeId()) + "/msgs/" + strconv.Itoa(msg.MsgId)
@@ -1851,6 +1916,11 @@ func (w *GovernanceWorker) deleteMessage(msg *exchange.DeviceMessage) error {
 }
 
 func (w *GovernanceWorker) messageInExchange(msgId int) (bool, error) {
+	// A pushed message is handled once, there is no copy in the exchange that another worker could have handled.
+	if msgId == exchange.PUSHED_MESSAGE_ID {
+		return true, nil
+	}
+
 	var resp interface{}
 	resp = new(exchange.GetDeviceMessageResponse)
 	targetURL := w.GetExchangeURL() + "orgs/" + exchange.GetOrg(w.GetExchangeId()) + "/nodes/" + exchange.GetId(w.GetExchangeId()) + "/msgs/" + strconv.Itoa(msgId)
@@ -0,0 +1,37 @@
+// +build unit
+
+package governance
+
+import (
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/events"
+	"github.com/open-horizon/anax/persistence"
+	"github.com/open-horizon/anax/worker"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_NewEvent_workload_reported(t *testing.T) {
+	w := &GovernanceWorker{BaseWorker: worker.NewBaseWorker("governance", &config.HorizonConfig{}, nil)}
+
+	report := persistence.WorkloadReport{Key: "aaaa", Service: "mycompany/netspeed5", Health: persistence.WORKLOAD_HEALTH_OK, ReportedAt: 100}
+
+	// the first report of the service is a change of its health
+	w.NewEvent(events.NewWorkloadReportMessage(events.WORKLOAD_REPORTED, report, ""))
+	assert.Len(t, w.Commands, 2)
+	assert.IsType(t, &WorkloadReportedCommand{}, <-w.Commands)
+	assert.IsType(t, &ReportDeviceStatusCommand{}, <-w.Commands)
+
+	// reports with the same health do not update the node status
+	for i := 0; i < 5; i++ {
+		report.ReportedAt++
+		w.NewEvent(events.NewWorkloadReportMessage(events.WORKLOAD_REPORTED, report, persistence.WORKLOAD_HEALTH_OK))
+	}
+	assert.Len(t, w.Commands, 0)
+
+	report.Health = persistence.WORKLOAD_HEALTH_DEGRADED
+	w.NewEvent(events.NewWorkloadReportMessage(events.WORKLOAD_REPORTED, report, persistence.WORKLOAD_HEALTH_OK))
+	assert.Len(t, w.Commands, 2)
+	assert.IsType(t, &WorkloadReportedCommand{}, <-w.Commands)
+	assert.IsType(t, &ReportDeviceStatusCommand{}, <-w.Commands)
+}
@@ -0,0 +1,95 @@
+package governance
+
+import (
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/eventlog"
+	"github.com/open-horizon/anax/exchange"
+	"github.com/open-horizon/anax/persistence"
+	"time"
+)
+
+// The number of seconds between checks for a scheduled rotation of the messaging key, and between attempts to publish a
+// rotated key that could not be published.
+const MESSAGE_KEY_ROTATION_CHECK_S = 60
+
+// The state of the rotation of the messaging key of the node.
+type MessageKeyState struct {
+	Pending     bool  // The rotated key is not in the exchange yet.
+	LastPublish int64 // The last time the rotated key was published.
+}
+
+// The subworker that asks for a rotation of the messaging key when it is older than the rotation interval. The rotation
+// itself happens on the command thread, like a rotation that is requested through the API.
+func (w *GovernanceWorker) messageKeyRotationCheck() int {
+	if exchange.MessagingKeyRotationDue("", w.Config.MessageKeyRotation.IntervalS) {
+		w.Commands <- NewMessageKeyRotateCommand(exchange.MESSAGE_KEY_ROTATION_SCHEDULED)
+	}
+	return 0
+}
+
+// Rotate the messaging key of the node and publish the new public key to the exchange. Until the agbots see the new key,
+// they encrypt their messages with the old key, which can still decrypt messages during the grace period.
+func (w *GovernanceWorker) rotateMessageKey(reason string) {
+
+	// A scheduled rotation might have been queued twice.
+	if reason == exchange.MESSAGE_KEY_ROTATION_SCHEDULED && !exchange.MessagingKeyRotationDue("", w.Config.MessageKeyRotation.IntervalS) {
+		return
+	}
+
+	pDevice, err := persistence.FindExchangeDevice(w.db)
+	if err != nil || pDevice == nil {
+		glog.Errorf(logString(fmt.Sprintf("unable to rotate the messaging key, the node is not registered, error: %v", err)))
+		return
+	}
+
+	graceS := w.Config.MessageKeyRotation.GraceS
+	if _, err := exchange.RotateKeys("", graceS); err != nil {
+		glog.Errorf(logString(fmt.Sprintf("unable to rotate the messaging key, error: %v", err)))
+		eventlog.LogNodeEvent(w.db, persistence.SEVERITY_ERROR,
+			persistence.NewMessageMeta(EL_GOV_ERR_MESSAGE_KEY_ROTATION, reason, err.Error()),
+			persistence.EC_ERROR_MESSAGE_KEY_ROTATION,
+			pDevice.Id, pDevice.Org, pDevice.Pattern, pDevice.Config.State)
+		return
+	}
+
+	glog.Infof(logString(fmt.Sprintf("rotated the messaging key (%v)", reason)))
+	eventlog.LogNodeEvent(w.db, persistence.SEVERITY_INFO,
+		persistence.NewMessageMeta(EL_GOV_MESSAGE_KEY_ROTATED, reason, graceS),
+		persistence.EC_MESSAGE_KEY_ROTATED,
+		pDevice.Id, pDevice.Org, pDevice.Pattern, pDevice.Config.State)
+
+	w.messageKey.Pending = true
+	if err := w.publishMessageKey(); err != nil {
+		eventlog.LogNodeEvent(w.db, persistence.SEVERITY_ERROR,
+			persistence.NewMessageMeta(EL_GOV_ERR_MESSAGE_KEY_PUBLISH, err.Error()),
+			persistence.EC_ERROR_MESSAGE_KEY_ROTATION,
+			pDevice.Id, pDevice.Org, pDevice.Pattern, pDevice.Config.State)
+	}
+}
+
+// Publish the rotated messaging key, the node might be offline so the publication is retried later if it fails.
+func (w *GovernanceWorker) publishMessageKey() error {
+	w.messageKey.LastPublish = time.Now().Unix()
+	if err := exchange.PublishMessagingKey(w.limitedRetryEC, exchange.NODE_MAILBOX, ""); err != nil {
+		glog.Errorf(logString(fmt.Sprintf("unable to publish the rotated messaging key, error: %v", err)))
+		return err
+	}
+
+	w.messageKey.Pending = false
+	glog.V(3).Infof(logString(fmt.Sprintf("published the rotated messaging key")))
+	if pDevice, err := persistence.FindExchangeDevice(w.db); err == nil && pDevice != nil {
+		eventlog.LogNodeEvent(w.db, persistence.SEVERITY_INFO,
+			persistence.NewMessageMeta(EL_GOV_MESSAGE_KEY_PUBLISHED),
+			persistence.EC_MESSAGE_KEY_ROTATED,
+			pDevice.Id, pDevice.Org, pDevice.Pattern, pDevice.Config.State)
+	}
+	return nil
+}
+
+// Publish a rotated messaging key that could not be published when it was rotated.
+func (w *GovernanceWorker) retryMessageKeyPublish() {
+	if w.messageKey.Pending && time.Now().Unix()-w.messageKey.LastPublish >= MESSAGE_KEY_ROTATION_CHECK_S {
+		w.publishMessageKey()
+	}
+}
@@ -106,6 +106,17 @@ const (
 	EL_GOV_ERR_VALIDATE_NEW_PATTERN        = "Error validating new node pattern %v: %v"
 	EL_GOV_NODE_KEEP_OLD_PATTERN           = "The node will keep using the old pattern %v"
 	EL_GOV_NEW_PATTERN_VERIFIED            = "New pattern %v is verified. Will cancel agreements and re-register the node with the new pattern."
+
+	// workload API
+	EL_GOV_WORKLOAD_REPORTED_OK       = "Service %v reported that it is healthy. %v"
+	EL_GOV_WORKLOAD_REPORTED_DEGRADED = "Service %v reported that it is degraded. %v"
+	EL_GOV_WORKLOAD_REPORTED_FAILED   = "Service %v reported that it failed. %v"
+
+	// messaging key
+	EL_GOV_MESSAGE_KEY_ROTATED      = "Messaging key rotated (%v). The previous key can decrypt messages for %v seconds."
+	EL_GOV_ERR_MESSAGE_KEY_ROTATION = "Error rotating the messaging key (%v): %v"
+	EL_GOV_MESSAGE_KEY_PUBLISHED    = "Rotated messaging key published to the Exchange."
+	EL_GOV_ERR_MESSAGE_KEY_PUBLISH  = "Error publishing the rotated messaging key to the Exchange, will retry: %v"
 )
 
 // This is does nothing useful at run time.
@@ -216,4 +227,15 @@ func MarkI18nMessages() {
 	msgPrinter.Sprintf(EL_GOV_ERR_VALIDATE_NEW_PATTERN)
 	msgPrinter.Sprintf(EL_GOV_NODE_KEEP_OLD_PATTERN)
 	msgPrinter.Sprintf(EL_GOV_NEW_PATTERN_VERIFIED)
+
+	// workload API
+	msgPrinter.Sprintf(EL_GOV_WORKLOAD_REPORTED_OK)
+	msgPrinter.Sprintf(EL_GOV_WORKLOAD_REPORTED_DEGRADED)
+	msgPrinter.Sprintf(EL_GOV_WORKLOAD_REPORTED_FAILED)
+
+	// messaging key
+	msgPrinter.Sprintf(EL_GOV_MESSAGE_KEY_ROTATED)
+	msgPrinter.Sprintf(EL_GOV_ERR_MESSAGE_KEY_ROTATION)
+	msgPrinter.Sprintf(EL_GOV_MESSAGE_KEY_PUBLISHED)
+	msgPrinter.Sprintf(EL_GOV_ERR_MESSAGE_KEY_PUBLISH)
 }
@@ -14,6 +14,7 @@ import (
 	"github.com/open-horizon/anax/policy"
 	"github.com/open-horizon/anax/producer"
 	"math"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -260,6 +261,9 @@ func (w *GovernanceWorker) GetEnvVarsForServiceDepolyment(msdef *persistence.Mic
 	envAdds[config.ENVVAR_PREFIX+"ORGANIZATION"] = exchange.GetOrg(w.GetExchangeId())
 	envAdds[config.ENVVAR_PREFIX+"PATTERN"] = w.devicePattern
 	envAdds[config.ENVVAR_PREFIX+"EXCHANGE_URL"] = w.Config.Edge.ExchangeURL
+	if !w.Config.Edge.WorkloadAPI.Disabled {
+		cutil.SetWorkloadAPIEnvvars(envAdds, config.ENVVAR_PREFIX, w.Config.GetWorkloadAPIListen(), strconv.Itoa(int(w.Config.GetWorkloadAPIPort())))
+	}
 
 	// Add in any default variables from the microservice userInputs that havent been overridden
 	for _, ui := range msdef.UserInputs {
@@ -744,13 +748,13 @@ func (w *GovernanceWorker) handleMicroserviceExecFailure(msdef *persistence.Micr
 		current_retry := msi.CurrentRetryCount + 1
 		// start the retry
 		eventlog.LogServiceEvent2(w.db, persistence.SEVERITY_INFO,
-			persistence.NewMessageMeta(EL_GOV_START_SVC_RETRY, string(current_retry), msdef.SpecRef, msdef.Version),
+			persistence.NewMessageMeta(EL_GOV_START_SVC_RETRY, fmt.Sprintf("%v", current_retry), msdef.SpecRef, msdef.Version),
 			persistence.EC_START_RETRY_DEPENDENT_SERVICE,
 			msinst_key, msdef.SpecRef, msdef.Org, msdef.Version, msdef.Arch, []string{})
 
 		if err := w.RetryMicroservice(msi); err != nil {
 			eventlog.LogServiceEvent2(w.db, persistence.SEVERITY_ERROR,
-				persistence.NewMessageMeta(EL_GOV_FAILED_SVC_RETRY, string(current_retry), msdef.SpecRef, msdef.Version),
+				persistence.NewMessageMeta(EL_GOV_FAILED_SVC_RETRY, fmt.Sprintf("%v", current_retry), msdef.SpecRef, msdef.Version),
 				persistence.EC_ERROR_START_RETRY_DEPENDENT_SERVICE,
 				msinst_key, msdef.SpecRef, msdef.Org, msdef.Version, msdef.Arch, []string{})
 			glog.Errorf(logString(fmt.Sprintf("error retrying number %v for failed dependent service %v.", msinst_key, err)))
@@ -33,13 +33,14 @@ func (w ContainerStatus) String() string {
 }
 
 type WorkloadStatus struct {
-	AgreementId    string            `json:"agreementId"`
-	ServiceURL     string            `json:"serviceUrl,omitempty"`
-	Org            string            `json:"orgid,omitempty"`
-	Version        string            `json:"version,omitempty"`
-	Arch           string            `json:"arch,omitempty"`
-	Containers     []ContainerStatus `json:"containerStatus"`
-	OperatorStatus interface{}       `json:"operatorStatus,omitempty"`
+	AgreementId    string                      `json:"agreementId"`
+	ServiceURL     string                      `json:"serviceUrl,omitempty"`
+	Org            string                      `json:"orgid,omitempty"`
+	Version        string                      `json:"version,omitempty"`
+	Arch           string                      `json:"arch,omitempty"`
+	Containers     []ContainerStatus           `json:"containerStatus"`
+	OperatorStatus interface{}                 `json:"operatorStatus,omitempty"`
+	Reported       *persistence.WorkloadReport `json:"reportedStatus,omitempty"` // The health and status that the service reported through the workload API.
 }
 
 func (w WorkloadStatus) String() string {
@@ -49,8 +50,9 @@ func (w WorkloadStatus) String() string {
 		"Version: %v, "+
 		"Arch: %v, "+
 		"Containers: %v"+
-		"OperatorStatus: %v",
-		w.AgreementId, w.ServiceURL, w.Org, w.Version, w.Arch, w.Containers, w.OperatorStatus)
+		"OperatorStatus: %v, "+
+		"Reported: %v",
+		w.AgreementId, w.ServiceURL, w.Org, w.Version, w.Arch, w.Containers, w.OperatorStatus, w.Reported)
 }
 
 type DeviceStatus struct {
@@ -215,6 +217,12 @@ func (w *GovernanceWorker) getMicroserviceStatus(containers []docker.APIContaine
 							msdef_status.Containers = append(msdef_status.Containers, cstatus...)
 						}
 					}
+					// When there are several instances of the service, report the most recent of their reports.
+					if report, err := persistence.FindWorkloadReport(w.db, msi.GetKey()); err != nil {
+						glog.Errorf(logString(fmt.Sprintf("Error retrieving the workload report of %v from database, error: %v", msi.GetKey(), err)))
+					} else if report != nil && (msdef_status.Reported == nil || report.ReportedAt > msdef_status.Reported.ReportedAt) {
+						msdef_status.Reported = report
+					}
 				}
 			}
 			if len(msdef_status.Containers) > 0 {
@@ -254,6 +262,12 @@ func (w *GovernanceWorker) getWorkloadStatus(containers []docker.APIContainers)
 						wl_status.Version = wl.Version
 						wl_status.Arch = wl.Arch
 
+						if report, err := persistence.FindWorkloadReport(w.db, ag.CurrentAgreementId); err != nil {
+							glog.Errorf(logString(fmt.Sprintf("Error retrieving the workload report of agreement %v from database, error: %v", ag.CurrentAgreementId, err)))
+						} else {
+							wl_status.Reported = report
+						}
+
 						if wl.ClusterDeployment != "" {
 							opStatus, opErr := GetOperatorStatus(wl.ClusterDeployment)
 							if opErr != nil {
@@ -435,6 +449,9 @@ func changeInWorkloadStatuses(newStatuses []WorkloadStatus, oldStatuses []persis
 				if !reflect.DeepEqual(newStatus.OperatorStatus, oldStatus.OperatorStatus) {
 					return true
 				}
+				if !newStatus.Reported.SameContent(oldStatus.Reported) {
+					return true
+				}
 				if changeInContainerStatuses(newStatus.Containers, oldStatus.Containers) {
 					return true
 				}
@@ -479,7 +496,7 @@ func convertToPersistenceType(workload []WorkloadStatus) []persistence.WorkloadS
 	for _, wlStatus := range workload {
 		newPersistentWlStatus := persistence.WorkloadStatus{AgreementId: wlStatus.AgreementId,
 			ServiceURL: wlStatus.ServiceURL, Org: wlStatus.Org, Version: wlStatus.Version,
-			Arch: wlStatus.Arch, OperatorStatus: wlStatus.OperatorStatus}
+			Arch: wlStatus.Arch, OperatorStatus: wlStatus.OperatorStatus, Reported: wlStatus.Reported}
 		newPersistentWlStatus.Containers = converContainerStatusToPersistenceType(wlStatus.Containers)
 		persistentWls = append(persistentWls, newPersistentWlStatus)
 	}
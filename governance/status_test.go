@@ -4,6 +4,7 @@ package governance
 
 import (
 	docker "github.com/fsouza/go-dockerclient"
+	"github.com/open-horizon/anax/persistence"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
@@ -132,3 +133,21 @@ func statusArrayIsSame(a1 []ContainerStatus, a2 []ContainerStatus) bool {
 
 	return true
 }
+
+func Test_changeInWorkloadStatuses_reported(t *testing.T) {
+	containers := []ContainerStatus{{Name: "/aaaa-netspeed5", Image: "mycompany/x86/netspeed5:v2.5", Created: 1507728202, State: "running"}}
+	report := &persistence.WorkloadReport{Key: "aaaa", Service: "mycompany/netspeed5", Health: persistence.WORKLOAD_HEALTH_OK, ReportedAt: 100}
+	newStatuses := []WorkloadStatus{{AgreementId: "aaaa", ServiceURL: "netspeed5", Org: "mycompany", Containers: containers, Reported: report}}
+	oldStatuses := convertToPersistenceType(newStatuses)
+
+	assert.False(t, changeInWorkloadStatuses(newStatuses, oldStatuses))
+
+	newStatuses[0].Reported = &persistence.WorkloadReport{Key: "aaaa", Service: "mycompany/netspeed5", Health: persistence.WORKLOAD_HEALTH_OK, ReportedAt: 200}
+	assert.False(t, changeInWorkloadStatuses(newStatuses, oldStatuses), "a new report with the same content is not a change")
+
+	newStatuses[0].Reported = &persistence.WorkloadReport{Key: "aaaa", Service: "mycompany/netspeed5", Health: persistence.WORKLOAD_HEALTH_DEGRADED, ReportedAt: 200}
+	assert.True(t, changeInWorkloadStatuses(newStatuses, oldStatuses))
+
+	newStatuses[0].Reported = nil
+	assert.True(t, changeInWorkloadStatuses(newStatuses, oldStatuses))
+}
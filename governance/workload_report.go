@@ -0,0 +1,52 @@
+package governance
+
+import (
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/eventlog"
+	"github.com/open-horizon/anax/microservice"
+	"github.com/open-horizon/anax/persistence"
+	"github.com/open-horizon/anax/policy"
+	"github.com/open-horizon/anax/producer"
+)
+
+// Handle a change in the health that a service reported through the workload API. The report is keyed by the agreement
+// of a top level service, or by the instance of a dependent service. A service that reports that it failed is treated
+// the same way as a service whose containers stopped running.
+func (w *GovernanceWorker) handleWorkloadReport(report *persistence.WorkloadReport) {
+
+	severity := persistence.SEVERITY_INFO
+	message := EL_GOV_WORKLOAD_REPORTED_OK
+	if report.Health == persistence.WORKLOAD_HEALTH_DEGRADED {
+		severity = persistence.SEVERITY_WARN
+		message = EL_GOV_WORKLOAD_REPORTED_DEGRADED
+	} else if report.Health == persistence.WORKLOAD_HEALTH_FAILED {
+		severity = persistence.SEVERITY_ERROR
+		message = EL_GOV_WORKLOAD_REPORTED_FAILED
+	}
+	meta := persistence.NewMessageMeta(message, report.Service, report.Message)
+
+	if ags, err := persistence.FindEstablishedAgreementsAllProtocols(w.db, policy.AllAgreementProtocols(), []persistence.EAFilter{persistence.UnarchivedEAFilter(), persistence.IdEAFilter(report.Key)}); err != nil {
+		glog.Errorf(logString(fmt.Sprintf("unable to retrieve agreement %v from database, error %v", report.Key, err)))
+	} else if len(ags) == 1 {
+		ag := ags[0]
+		eventlog.LogAgreementEvent(w.db, severity, meta, persistence.EC_WORKLOAD_REPORTED_HEALTH, ag)
+		if report.Health == persistence.WORKLOAD_HEALTH_FAILED && ag.AgreementTerminatedTime == 0 {
+			glog.Warningf(logString(fmt.Sprintf("service %v in agreement %v reported that it failed, cleaning up the agreement.", report.Service, ag.CurrentAgreementId)))
+			w.Commands <- w.NewCleanupExecutionCommand(ag.AgreementProtocol, ag.CurrentAgreementId, w.producerPH[ag.AgreementProtocol].GetTerminationCode(producer.TERM_REASON_CONTAINER_FAILURE), ag.GetDeploymentConfig())
+		}
+		return
+	}
+
+	if msinst, err := persistence.FindMicroserviceInstanceWithKey(w.db, report.Key); err != nil {
+		glog.Errorf(logString(fmt.Sprintf("unable to retrieve service instance %v from database, error %v", report.Key, err)))
+	} else if msinst == nil || msinst.Archived {
+		glog.V(3).Infof(logString(fmt.Sprintf("ignoring the report of %v, there is no agreement or service instance %v.", report.Service, report.Key)))
+	} else {
+		eventlog.LogServiceEvent(w.db, severity, meta, persistence.EC_WORKLOAD_REPORTED_HEALTH, *msinst)
+		if report.Health == persistence.WORKLOAD_HEALTH_FAILED {
+			glog.Warningf(logString(fmt.Sprintf("service instance %v reported that it failed.", report.Key)))
+			w.Commands <- w.NewUpdateMicroserviceCommand(report.Key, false, microservice.MS_EXEC_FAILED, microservice.DecodeReasonCode(microservice.MS_EXEC_FAILED))
+		}
+	}
+}
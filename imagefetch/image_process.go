@@ -10,6 +10,7 @@ import (
 	"github.com/open-horizon/anax/containermessage"
 	"github.com/open-horizon/anax/events"
 	"github.com/open-horizon/anax/persistence"
+	"github.com/open-horizon/anax/tracing"
 	"github.com/open-horizon/anax/worker"
 	"strings"
 )
@@ -294,7 +295,17 @@ func (b *ImageFetchWorker) CommandHandler(command worker.Command) bool {
 				return true
 			}
 
-			if fetchErr := processFetch(b.Config, b.client, b.db, deploymentDesc, lc.ContainerConfig().ImageDockerAuths); fetchErr != nil {
+			// Image fetching for an agreement is part of the agreement's trace.
+			var span *tracing.Span
+			if alc, ok := cmd.LaunchContext.(*events.AgreementLaunchContext); ok {
+				span = tracing.StartAgreementSpan("node.image_fetch", alc.AgreementId)
+			}
+
+			fetchErr := processFetch(b.Config, b.client, b.db, deploymentDesc, lc.ContainerConfig().ImageDockerAuths)
+			span.SetError(fetchErr)
+			span.End()
+
+			if fetchErr != nil {
 				var id events.EventId
 				if strings.Contains(fetchErr.Error(), "Auth error") {
 					id = events.IMAGE_FETCH_AUTH_ERROR
@@ -282,7 +282,7 @@ type DeploymentAppsV1 struct {
 func (d DeploymentAppsV1) Install(c KubeClient, namespace string) error {
 	glog.V(3).Infof(kwlog(fmt.Sprintf("creating deployment %v", d)))
 
-	// The ESS is not supported in edge cluster services, so for now, remove the ESS env vars.
+	// The ESS and the workload API are not supported in edge cluster services, so for now, remove their env vars.
 	envAdds := cutil.RemoveESSEnvVars(d.EnvVarMap, config.ENVVAR_PREFIX)
 
 	// Create the config map.
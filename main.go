@@ -14,6 +14,8 @@ import (
 	"github.com/open-horizon/anax/changes"
 	"github.com/open-horizon/anax/config"
 	"github.com/open-horizon/anax/container"
+	"github.com/open-horizon/anax/discovery"
+	"github.com/open-horizon/anax/eventlog"
 	"github.com/open-horizon/anax/exchange"
 	_ "github.com/open-horizon/anax/externalpolicy/text_language"
 	"github.com/open-horizon/anax/governance"
@@ -24,6 +26,7 @@ import (
 	"github.com/open-horizon/anax/persistence"
 	"github.com/open-horizon/anax/policy"
 	"github.com/open-horizon/anax/resource"
+	"github.com/open-horizon/anax/tracing"
 	"github.com/open-horizon/anax/worker"
 	"os"
 	"os/signal"
@@ -92,6 +95,15 @@ func main() {
 		glog.Warningf("Unable to initialize Agreement Bot database on this node: %v", dberr)
 	}
 
+	// start the agreement protocol tracer, spans from the agbot and the node are reported as different services.
+	serviceName := "anax"
+	if db == nil {
+		serviceName = "anax-agbot"
+	}
+	if err := tracing.Init(&cfg.Tracing, serviceName); err != nil {
+		glog.Errorf("Unable to initialize tracing, error %v", err)
+	}
+
 	// start control signal handler
 	control := make(chan os.Signal, 1)
 	signal.Notify(control, os.Interrupt)
@@ -118,6 +130,7 @@ func main() {
 		if agbotDB != nil {
 			agbotDB.Close()
 		}
+		tracing.Shutdown()
 
 		os.Exit(0)
 	}()
@@ -129,6 +142,14 @@ func main() {
 		panic(err)
 	}
 
+	// Restore the exchange resource cache from the local DB so that the agent does not have to fetch everything again
+	// and can keep running its workloads if the exchange is unreachable.
+	if db != nil && cfg.Edge.PersistExchangeCache {
+		if _, err := exchange.InitPersistentResourceCache(db, cfg.Edge.ExchangeCacheTTLS); err != nil {
+			glog.Errorf("Unable to restore the exchange resource cache, error %v", err)
+		}
+	}
+
 	// Get the device side policy manager started early so that all the workers can use it.
 	// Make sure the policy directory is in place.
 	var pm *policy.PolicyManager
@@ -175,6 +196,10 @@ func main() {
 		workers.Add(kube_operator.NewKubeWorker("Kube", cfg, db))
 		workers.Add(resource.NewResourceWorker("Resource", cfg, db, authm))
 		workers.Add(changes.NewChangesWorker("ExchangeChanges", cfg, db))
+		workers.Add(eventlog.NewEventLogWorker("EventLog", cfg, db))
+		if discoveryWorker := discovery.NewDiscoveryWorker("NodeDiscovery", cfg, db); discoveryWorker != nil {
+			workers.Add(discoveryWorker)
+		}
 	}
 
 	// Get into the event processing loop until anax shuts itself down.
@@ -196,6 +221,7 @@ func main() {
 	if agbotDB != nil {
 		agbotDB.Close()
 	}
+	tracing.Shutdown()
 
 	glog.Info("Main process terminating")
 }
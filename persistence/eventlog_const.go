@@ -108,6 +108,11 @@ const (
 	EC_ERROR_NODE_USERINPUT_UPDATE = "error_userinput_update"
 	EC_ERROR_NODE_USERINPUT_PATCH  = "error_userinput_patch"
 
+	EC_NODE_PROPERTIES_DISCOVERED = "discover_node_properties"
+
+	EC_MESSAGE_KEY_ROTATED        = "message_key_rotated"
+	EC_ERROR_MESSAGE_KEY_ROTATION = "error_message_key_rotation"
+
 	EC_AGREEMENT_REACHED                  = "agreement_reached"
 	EC_CANCEL_AGREEMENT                   = "cancel_agreement"
 	EC_AGREEMENT_CANCELED                 = "agreement_canceled"
@@ -121,6 +126,7 @@ const (
 	EC_CONTAINER_STOPPED          = "container_stopped"
 	EC_ERROR_IN_DEPLOYMENT_CONFIG = "error_in_deployment_configuration"
 	EC_ERROR_START_CONTAINER      = "error_start_container"
+	EC_WORKLOAD_REPORTED_HEALTH   = "workload_reported_health"
 
 	EC_IMAGE_LOADED                       = "image_loaded"
 	EC_ERROR_IMAGE_LOADE                  = "error_image_load"
@@ -0,0 +1,58 @@
+package persistence
+
+import (
+	"github.com/golang/glog"
+	"sync"
+)
+
+// Event log subscriptions allow a caller (e.g. the event log streaming API) to be told about every event log
+// as soon as SaveEventLog has written it to the db. Delivery is best effort; a subscriber that does not keep up
+// will miss records instead of blocking the writer.
+
+type EventLogSubscription struct {
+	C  chan EventLog // the new event logs are delivered on this channel
+	id uint64
+}
+
+var eventLogSubscribersLock sync.Mutex
+var eventLogSubscribers = make(map[uint64]*EventLogSubscription)
+var nextEventLogSubscriberId uint64
+
+// Create a new subscription for event logs. The channel holds up to bufferSize records that have not been consumed.
+func SubscribeEventLogs(bufferSize int) *EventLogSubscription {
+	eventLogSubscribersLock.Lock()
+	defer eventLogSubscribersLock.Unlock()
+
+	nextEventLogSubscriberId++
+	sub := &EventLogSubscription{
+		C:  make(chan EventLog, bufferSize),
+		id: nextEventLogSubscriberId,
+	}
+	eventLogSubscribers[sub.id] = sub
+	return sub
+}
+
+// Stop receiving event logs. The subscription channel is closed.
+func (s *EventLogSubscription) Close() {
+	eventLogSubscribersLock.Lock()
+	defer eventLogSubscribersLock.Unlock()
+
+	if _, ok := eventLogSubscribers[s.id]; ok {
+		delete(eventLogSubscribers, s.id)
+		close(s.C)
+	}
+}
+
+// Send the given event log to all the subscribers.
+func publishEventLog(event_log EventLog) {
+	eventLogSubscribersLock.Lock()
+	defer eventLogSubscribersLock.Unlock()
+
+	for _, sub := range eventLogSubscribers {
+		select {
+		case sub.C <- event_log:
+		default:
+			glog.Warningf("Event log subscriber %v is not keeping up, dropping event log %v", sub.id, event_log.Id)
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/open-horizon/anax/i18n"
 	"golang.org/x/text/message"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -19,6 +20,9 @@ const EVENT_LOGS = "event_logs"
 // table stores the timestamp of last unregistration
 const LAST_UNREG = "last_unreg"
 
+// table stores the id of the last event log that was exported
+const EVENT_LOG_EXPORT = "event_log_export"
+
 const BASE_SELECTORS = "source_type,severity,message,event_code,record_id,timestamp" // only support these 2 for now
 
 // Each event source implements this interface.
@@ -250,6 +254,10 @@ func SaveEventLog(db *bolt.DB, event_log *EventLog) error {
 	})
 
 	NewErrorLog(db, *event_log)
+
+	if writeErr == nil {
+		publishEventLog(*event_log)
+	}
 	return writeErr
 }
 
@@ -717,3 +725,146 @@ func GetEventLogObject(db *bolt.DB, msgPrinter *message.Printer, recordID string
 	}
 	return logs[0]
 }
+
+// Remove the event logs that the keep function rejects. The event logs are handed to the keep function from the
+// newest to the oldest, together with the number of newer event logs of the same severity that have been kept so far.
+// It returns the number of event logs that were removed.
+func PruneEventLogs(db *bolt.DB, keep func(el EventLogBase, keptNewer int) bool) (int, error) {
+	removed := 0
+
+	writeErr := db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(EVENT_LOGS))
+		if b == nil {
+			return nil
+		}
+
+		// the keys are decimal record ids, so the bolt key order is not the order in which the records were saved.
+		type keyedEventLog struct {
+			id  uint64
+			key []byte
+			el  EventLogBase
+		}
+		evlogs := make([]keyedEventLog, 0)
+		if err := b.ForEach(func(k, v []byte) error {
+			var el EventLogBase
+			if err := json.Unmarshal(v, &el); err != nil {
+				glog.Errorf("Unable to deserialize event log db record: %v. Error: %v", v, err)
+				return nil
+			}
+			id, err := strconv.ParseUint(string(k), 10, 64)
+			if err != nil {
+				glog.Errorf("Unable to convert event log db key %v into uint64. Error: %v", string(k), err)
+				return nil
+			}
+			evlogs = append(evlogs, keyedEventLog{id: id, key: append([]byte{}, k...), el: el})
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		sort.Slice(evlogs, func(i, j int) bool { return evlogs[i].id > evlogs[j].id })
+
+		kept := make(map[string]int)
+		for _, kel := range evlogs {
+			if keep(kel.el, kept[kel.el.Severity]) {
+				kept[kel.el.Severity]++
+			} else if err := b.Delete(kel.key); err != nil {
+				return fmt.Errorf("Unable to delete event log %v. Error: %v", kel.el.Id, err)
+			} else {
+				removed++
+			}
+		}
+		return nil
+	})
+
+	return removed, writeErr
+}
+
+// Get the id of the last event log that was exported. The second return value is false when the cursor has never been saved.
+func GetEventLogExportCursor(db *bolt.DB) (uint64, bool, error) {
+	var cursor uint64
+	found := false
+
+	readErr := db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(EVENT_LOG_EXPORT)); b != nil {
+			if v := b.Get([]byte("cursor")); v != nil {
+				if s, err := strconv.ParseUint(string(v), 10, 64); err != nil {
+					return fmt.Errorf("Failed to convert the event log export cursor %v into uint64, error: %v", string(v), err)
+				} else {
+					cursor = s
+					found = true
+				}
+			}
+		}
+		return nil
+	})
+
+	return cursor, found, readErr
+}
+
+// Save the id of the last event log that was exported.
+func SaveEventLogExportCursor(db *bolt.DB, cursor uint64) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		if bucket, err := tx.CreateBucketIfNotExists([]byte(EVENT_LOG_EXPORT)); err != nil {
+			return err
+		} else {
+			return bucket.Put([]byte("cursor"), []byte(strconv.FormatUint(cursor, 10)))
+		}
+	})
+}
+
+// Get the id of the last event log that was saved, 0 if no event log was ever saved.
+func GetLastEventLogId(db *bolt.DB) (uint64, error) {
+	var last uint64
+
+	readErr := db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(EVENT_LOGS)); b != nil {
+			last = b.Sequence()
+		}
+		return nil
+	})
+
+	return last, readErr
+}
+
+// Find up to max event logs that were saved after the event log with the given id, in the order in which they were saved.
+// It also returns the id up to which the event logs were read, the ids of the records that were removed in the meantime
+// are skipped.
+func FindEventLogsAfter(db *bolt.DB, after uint64, max int) ([]EventLog, uint64, error) {
+	evlogs := make([]EventLog, 0)
+	last := after
+
+	readErr := db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(EVENT_LOGS))
+		if b == nil {
+			return nil
+		}
+
+		// the record ids come from the bucket sequence, so they can be read in order without scanning the bucket.
+		for id := after + 1; id <= b.Sequence() && len(evlogs) < max; id++ {
+			last = id
+			v := b.Get([]byte(strconv.FormatUint(id, 10)))
+			if v == nil {
+				continue
+			}
+
+			var el EventLogRaw
+			if err := json.Unmarshal(v, &el); err != nil {
+				glog.Errorf("Unable to deserialize event log db record: %v. Error: %v", v, err)
+			} else if esrc, err := GetRealEventSource(el.SourceType, el.Source); err != nil {
+				glog.Errorf("Unable to convert event source: %v. Error: %v", el.Source, err)
+			} else {
+				pel := newEventLog1(el.Severity, el.Message, el.MessageMeta, el.EventCode, el.SourceType, *esrc)
+				pel.Id = el.Id
+				pel.Timestamp = el.Timestamp
+				evlogs = append(evlogs, *pel)
+			}
+		}
+		return nil
+	})
+
+	if readErr != nil {
+		return nil, after, readErr
+	}
+	return evlogs, last, nil
+}
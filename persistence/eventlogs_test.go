@@ -3,6 +3,7 @@
 package persistence
 
 import (
+	"fmt"
 	"github.com/stretchr/testify/assert"
 	"strings"
 	"testing"
@@ -475,3 +476,87 @@ func Test_SaveLastUnregistrationTime(t *testing.T) {
 	assert.False(t, e8.Matches(selectors), "Test eventlog Matches.")
 
 }
+
+func Test_SubscribeEventLogs(t *testing.T) {
+
+	dir, db, err := utsetup()
+	if err != nil {
+		t.Error(err)
+	}
+	defer cleanTestDir(dir)
+
+	sub := SubscribeEventLogs(2)
+
+	source1 := NewAgreementEventSource("agreement id 1", WorkloadInfo{"http://top1.com", "mycomp", "1.0.0", "amd64"}, []ServiceSpec{}, "agbot1", "basic")
+	e1 := newEventLog1(SEVERITY_INFO, "message 1", nil, EC_START_NODE_CONFIG_REG, SRC_TYPE_AG, *source1)
+	e2 := newEventLog1(SEVERITY_INFO, "message 2", nil, EC_START_NODE_CONFIG_REG, SRC_TYPE_AG, *source1)
+	e3 := newEventLog1(SEVERITY_INFO, "message 3", nil, EC_START_NODE_CONFIG_REG, SRC_TYPE_AG, *source1)
+	for _, e := range []*EventLog{e1, e2, e3} {
+		if err := SaveEventLog(db, e); err != nil {
+			t.Errorf("Erorr saving eventlog into db. %v", err)
+		}
+	}
+
+	// The subscriber did not read, so the third record is dropped instead of blocking the writer.
+	received := <-sub.C
+	assert.Equal(t, "1", received.Id, "The first event log should be received first.")
+	assert.Equal(t, "message 1", received.Message, "The received event log should be the saved one.")
+	received = <-sub.C
+	assert.Equal(t, "2", received.Id, "The second event log should be received second.")
+	select {
+	case received = <-sub.C:
+		t.Errorf("The event log %v should have been dropped.", received.Id)
+	default:
+	}
+
+	sub.Close()
+	if _, ok := <-sub.C; ok {
+		t.Errorf("The subscription channel should be closed.")
+	}
+
+	// Saving after the subscription is closed must not panic.
+	e4 := newEventLog1(SEVERITY_INFO, "message 4", nil, EC_START_NODE_CONFIG_REG, SRC_TYPE_AG, *source1)
+	if err := SaveEventLog(db, e4); err != nil {
+		t.Errorf("Erorr saving eventlog into db. %v", err)
+	}
+}
+
+func Test_PruneEventLogs(t *testing.T) {
+
+	dir, db, err := utsetup()
+	if err != nil {
+		t.Error(err)
+	}
+	defer cleanTestDir(dir)
+
+	// save more than 10 records so that the numeric order of the ids differs from the bolt key order.
+	source1 := NewAgreementEventSource("agreement id 1", WorkloadInfo{"http://top1.com", "mycomp", "1.0.0", "amd64"}, []ServiceSpec{}, "agbot1", "basic")
+	for i := 1; i <= 12; i++ {
+		severity := SEVERITY_INFO
+		if i%4 == 0 {
+			severity = SEVERITY_ERROR
+		}
+		e := newEventLog1(severity, fmt.Sprintf("message %v", i), nil, EC_START_NODE_CONFIG_REG, SRC_TYPE_AG, *source1)
+		if err := SaveEventLog(db, e); err != nil {
+			t.Errorf("Erorr saving eventlog into db. %v", err)
+		}
+	}
+
+	// keep the 2 newest info records and the 1 newest error record.
+	removed, err := PruneEventLogs(db, func(el EventLogBase, keptNewer int) bool {
+		if el.Severity == SEVERITY_ERROR {
+			return keptNewer < 1
+		}
+		return keptNewer < 2
+	})
+	assert.Nil(t, err, "Pruning the event logs should not fail.")
+	assert.Equal(t, 9, removed, "The number of removed event logs is wrong.")
+
+	evlogs, err := FindAllEventLogs(db)
+	assert.Nil(t, err, "Finding the event logs should not fail.")
+	ids := []string{}
+	for _, el := range evlogs {
+		ids = append(ids, el.Id)
+	}
+	assert.ElementsMatch(t, []string{"10", "11", "12"}, ids, "The newest event logs should be kept.")
+}
@@ -21,14 +21,16 @@ type UserInput struct {
 	Label        string `json:"label"`
 	Type         string `json:"type"`
 	DefaultValue string `json:"defaultValue"`
+	Sensitive    bool   `json:"sensitive,omitempty"`
 }
 
-func NewUserInput(name string, label string, stype string, default_value string) *UserInput {
+func NewUserInput(name string, label string, stype string, default_value string, sensitive bool) *UserInput {
 	return &UserInput{
 		Name:         name,
 		Label:        label,
 		Type:         stype,
 		DefaultValue: default_value,
+		Sensitive:    sensitive,
 	}
 }
 
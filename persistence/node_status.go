@@ -16,6 +16,7 @@ type WorkloadStatus struct {
 	Arch           string            `json:"arch,omitempty"`
 	Containers     []ContainerStatus `json:"containerStatus"`
 	OperatorStatus interface{}       `json:"operatorStatus,omitempty"`
+	Reported       *WorkloadReport   `json:"reportedStatus,omitempty"`
 }
 
 type ContainerStatus struct {
@@ -0,0 +1,244 @@
+package persistence
+
+import (
+	"fmt"
+	"github.com/boltdb/bolt"
+	"strings"
+)
+
+// The key-value store of the workload API. The store of each service is a bucket named by the org qualified URL of the
+// service, so that it is shared by all the versions of the service. Within it, the keys are either in the service scope,
+// which is kept when the service is upgraded or its agreements are cancelled, or in the scope of an agreement, which
+// is removed with the containers of the agreement.
+const WORKLOAD_KV = "workload_kv"
+
+const workloadKVServiceScope = "service"
+const workloadKVAgreementScopePrefix = "agreement/"
+
+// The limits of the key-value store of a service. The keys and bytes are counted across all the scopes of the service.
+type WorkloadKVQuota struct {
+	MaxKeys         int `json:"maxKeys"`
+	MaxValueBytes   int `json:"maxValueBytes"`
+	MaxServiceBytes int `json:"maxServiceBytes"`
+}
+
+// The usage of the key-value store of a service, across all its scopes.
+type WorkloadKVUsage struct {
+	Keys  int `json:"keys"`
+	Bytes int `json:"bytes"` // The size of the keys and values.
+}
+
+// Returned when a value does not fit in the quota of the service.
+type WorkloadKVQuotaError struct {
+	msg string
+}
+
+func (e *WorkloadKVQuotaError) Error() string {
+	return e.msg
+}
+
+// The scope of the keys of an agreement, or the service scope when there is no agreement. The agreement is the key of
+// the FSS (ESS) API credential of the service, so for a dependent service it is the service instance.
+func workloadKVScope(agreementId string) []byte {
+	if agreementId == "" {
+		return []byte(workloadKVServiceScope)
+	}
+	return []byte(workloadKVAgreementScopePrefix + agreementId)
+}
+
+func workloadKVUsage(sb *bolt.Bucket) (WorkloadKVUsage, error) {
+	usage := WorkloadKVUsage{}
+	err := sb.ForEach(func(scope, v []byte) error {
+		if b := sb.Bucket(scope); b != nil {
+			return b.ForEach(func(k, v []byte) error {
+				usage.Keys++
+				usage.Bytes += len(k) + len(v)
+				return nil
+			})
+		}
+		return nil
+	})
+	return usage, err
+}
+
+// PutWorkloadKV sets the value of the key in the scope, unless it would exceed the quota of the service.
+func PutWorkloadKV(db *bolt.DB, service string, agreementId string, key string, value []byte, quota WorkloadKVQuota) error {
+	if len(value) > quota.MaxValueBytes {
+		return &WorkloadKVQuotaError{fmt.Sprintf("the value of %v is %v bytes, the maximum is %v", key, len(value), quota.MaxValueBytes)}
+	}
+
+	return db.Update(func(tx *bolt.Tx) error {
+		kvb, err := tx.CreateBucketIfNotExists([]byte(WORKLOAD_KV))
+		if err != nil {
+			return err
+		}
+		sb, err := kvb.CreateBucketIfNotExists([]byte(service))
+		if err != nil {
+			return err
+		}
+		b, err := sb.CreateBucketIfNotExists(workloadKVScope(agreementId))
+		if err != nil {
+			return err
+		}
+
+		usage, err := workloadKVUsage(sb)
+		if err != nil {
+			return err
+		}
+		if old := b.Get([]byte(key)); old != nil {
+			usage.Keys--
+			usage.Bytes -= len(key) + len(old)
+		}
+
+		if usage.Keys+1 > quota.MaxKeys {
+			return &WorkloadKVQuotaError{fmt.Sprintf("service %v already has the maximum of %v keys", service, quota.MaxKeys)}
+		} else if bytes := usage.Bytes + len(key) + len(value); bytes > quota.MaxServiceBytes {
+			return &WorkloadKVQuotaError{fmt.Sprintf("service %v would use %v bytes, the maximum is %v", service, bytes, quota.MaxServiceBytes)}
+		}
+
+		return b.Put([]byte(key), value)
+	})
+}
+
+// GetWorkloadKV returns the value of the key in the scope, or nil if it is not set.
+func GetWorkloadKV(db *bolt.DB, service string, agreementId string, key string) ([]byte, error) {
+	var value []byte
+
+	readErr := db.View(func(tx *bolt.Tx) error {
+		if kvb := tx.Bucket([]byte(WORKLOAD_KV)); kvb == nil {
+			return nil
+		} else if sb := kvb.Bucket([]byte(service)); sb == nil {
+			return nil
+		} else if b := sb.Bucket(workloadKVScope(agreementId)); b == nil {
+			return nil
+		} else if v := b.Get([]byte(key)); v != nil {
+			// The value is only valid during the transaction.
+			value = append([]byte{}, v...)
+		}
+		return nil
+	})
+
+	if readErr != nil {
+		return nil, readErr
+	}
+	return value, nil
+}
+
+// DeleteWorkloadKV deletes the key from the scope. It returns false if the key was not set.
+func DeleteWorkloadKV(db *bolt.DB, service string, agreementId string, key string) (bool, error) {
+	deleted := false
+
+	writeErr := db.Update(func(tx *bolt.Tx) error {
+		if kvb := tx.Bucket([]byte(WORKLOAD_KV)); kvb == nil {
+			return nil
+		} else if sb := kvb.Bucket([]byte(service)); sb == nil {
+			return nil
+		} else if b := sb.Bucket(workloadKVScope(agreementId)); b == nil {
+			return nil
+		} else if b.Get([]byte(key)) == nil {
+			return nil
+		} else {
+			deleted = true
+			return b.Delete([]byte(key))
+		}
+	})
+
+	return deleted, writeErr
+}
+
+// ListWorkloadKV returns the sorted keys of the scope, and the usage of the service across all its scopes.
+func ListWorkloadKV(db *bolt.DB, service string, agreementId string) ([]string, WorkloadKVUsage, error) {
+	keys := make([]string, 0)
+	usage := WorkloadKVUsage{}
+
+	readErr := db.View(func(tx *bolt.Tx) error {
+		kvb := tx.Bucket([]byte(WORKLOAD_KV))
+		if kvb == nil {
+			return nil
+		}
+		sb := kvb.Bucket([]byte(service))
+		if sb == nil {
+			return nil
+		}
+
+		var err error
+		if usage, err = workloadKVUsage(sb); err != nil {
+			return err
+		} else if b := sb.Bucket(workloadKVScope(agreementId)); b != nil {
+			return b.ForEach(func(k, v []byte) error {
+				keys = append(keys, string(k))
+				return nil
+			})
+		}
+		return nil
+	})
+
+	if readErr != nil {
+		return nil, usage, readErr
+	}
+	return keys, usage, nil
+}
+
+// DeleteWorkloadKVAgreement deletes the keys in the scope of the agreement, for every service.
+func DeleteWorkloadKVAgreement(db *bolt.DB, agreementId string) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		kvb := tx.Bucket([]byte(WORKLOAD_KV))
+		if kvb == nil {
+			return nil
+		}
+
+		services := make([][]byte, 0)
+		kvb.ForEach(func(service, v []byte) error {
+			services = append(services, append([]byte{}, service...))
+			return nil
+		})
+
+		for _, service := range services {
+			if sb := kvb.Bucket(service); sb != nil && sb.Bucket(workloadKVScope(agreementId)) != nil {
+				if err := sb.DeleteBucket(workloadKVScope(agreementId)); err != nil {
+					return fmt.Errorf("Unable to delete the keys of agreement %v for service %v: %v", agreementId, string(service), err)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteAllWorkloadKVAgreements deletes the keys in the scope of every agreement, for every service. The keys in the
+// scope of the services are kept.
+func DeleteAllWorkloadKVAgreements(db *bolt.DB) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		kvb := tx.Bucket([]byte(WORKLOAD_KV))
+		if kvb == nil {
+			return nil
+		}
+
+		services := make([][]byte, 0)
+		kvb.ForEach(func(service, v []byte) error {
+			services = append(services, append([]byte{}, service...))
+			return nil
+		})
+
+		for _, service := range services {
+			sb := kvb.Bucket(service)
+			if sb == nil {
+				continue
+			}
+
+			scopes := make([][]byte, 0)
+			sb.ForEach(func(scope, v []byte) error {
+				if strings.HasPrefix(string(scope), workloadKVAgreementScopePrefix) {
+					scopes = append(scopes, append([]byte{}, scope...))
+				}
+				return nil
+			})
+
+			for _, scope := range scopes {
+				if err := sb.DeleteBucket(scope); err != nil {
+					return fmt.Errorf("Unable to delete the keys of %v for service %v: %v", string(scope), string(service), err)
+				}
+			}
+		}
+		return nil
+	})
+}
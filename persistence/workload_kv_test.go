@@ -0,0 +1,108 @@
+// +build unit
+
+package persistence
+
+import (
+	"testing"
+)
+
+func Test_WorkloadKV_scopes(t *testing.T) {
+
+	dir, db, err := utsetup()
+	if err != nil {
+		t.Errorf("Error setting up UT DB: %v", err)
+	}
+	defer cleanTestDir(dir)
+
+	quota := WorkloadKVQuota{MaxKeys: 10, MaxValueBytes: 100, MaxServiceBytes: 1000}
+
+	if v, err := GetWorkloadKV(db, "myorg/svc1", "", "cursor"); err != nil || v != nil {
+		t.Errorf("there should be no value before any were put, found %v, error %v", v, err)
+	}
+
+	if err := PutWorkloadKV(db, "myorg/svc1", "", "cursor", []byte("10"), quota); err != nil {
+		t.Errorf("error putting a service key: %v", err)
+	} else if err := PutWorkloadKV(db, "myorg/svc1", "ag1", "cursor", []byte("20"), quota); err != nil {
+		t.Errorf("error putting an agreement key: %v", err)
+	} else if err := PutWorkloadKV(db, "myorg/svc2", "ag1", "cursor", []byte("30"), quota); err != nil {
+		t.Errorf("error putting an agreement key of another service: %v", err)
+	}
+
+	if v, err := GetWorkloadKV(db, "myorg/svc1", "", "cursor"); err != nil || string(v) != "10" {
+		t.Errorf("the service key should be 10, found %v, error %v", string(v), err)
+	} else if v, err := GetWorkloadKV(db, "myorg/svc1", "ag1", "cursor"); err != nil || string(v) != "20" {
+		t.Errorf("the agreement key should be 20, found %v, error %v", string(v), err)
+	} else if v, err := GetWorkloadKV(db, "myorg/svc1", "ag2", "cursor"); err != nil || v != nil {
+		t.Errorf("the key should not be set for another agreement, found %v, error %v", string(v), err)
+	}
+
+	if keys, usage, err := ListWorkloadKV(db, "myorg/svc1", ""); err != nil {
+		t.Errorf("error listing the service keys: %v", err)
+	} else if len(keys) != 1 || keys[0] != "cursor" {
+		t.Errorf("the service keys should be [cursor], found %v", keys)
+	} else if usage.Keys != 2 || usage.Bytes != 16 {
+		t.Errorf("the usage should count both scopes, found %v", usage)
+	}
+
+	if err := DeleteWorkloadKVAgreement(db, "ag1"); err != nil {
+		t.Errorf("error deleting the keys of agreement ag1: %v", err)
+	} else if v, err := GetWorkloadKV(db, "myorg/svc1", "ag1", "cursor"); err != nil || v != nil {
+		t.Errorf("the agreement key should be deleted, found %v, error %v", string(v), err)
+	} else if v, err := GetWorkloadKV(db, "myorg/svc2", "ag1", "cursor"); err != nil || v != nil {
+		t.Errorf("the agreement key of the other service should be deleted, found %v, error %v", string(v), err)
+	} else if v, err := GetWorkloadKV(db, "myorg/svc1", "", "cursor"); err != nil || string(v) != "10" {
+		t.Errorf("the service key should be kept, found %v, error %v", string(v), err)
+	}
+
+	if err := PutWorkloadKV(db, "myorg/svc1", "ag2", "cursor", []byte("40"), quota); err != nil {
+		t.Errorf("error putting an agreement key: %v", err)
+	} else if err := DeleteAllWorkloadKVAgreements(db); err != nil {
+		t.Errorf("error deleting the keys of all agreements: %v", err)
+	} else if v, err := GetWorkloadKV(db, "myorg/svc1", "ag2", "cursor"); err != nil || v != nil {
+		t.Errorf("the agreement key should be deleted, found %v, error %v", string(v), err)
+	} else if v, err := GetWorkloadKV(db, "myorg/svc1", "", "cursor"); err != nil || string(v) != "10" {
+		t.Errorf("the service key should be kept, found %v, error %v", string(v), err)
+	}
+
+	if deleted, err := DeleteWorkloadKV(db, "myorg/svc1", "", "cursor"); err != nil || !deleted {
+		t.Errorf("the service key should be deleted, deleted %v, error %v", deleted, err)
+	} else if deleted, err := DeleteWorkloadKV(db, "myorg/svc1", "", "cursor"); err != nil || deleted {
+		t.Errorf("the service key should already be deleted, deleted %v, error %v", deleted, err)
+	}
+}
+
+func Test_WorkloadKV_quota(t *testing.T) {
+
+	dir, db, err := utsetup()
+	if err != nil {
+		t.Errorf("Error setting up UT DB: %v", err)
+	}
+	defer cleanTestDir(dir)
+
+	quota := WorkloadKVQuota{MaxKeys: 2, MaxValueBytes: 10, MaxServiceBytes: 19}
+
+	if err := PutWorkloadKV(db, "myorg/svc1", "", "a", []byte("12345678901"), quota); err == nil {
+		t.Errorf("a value larger than the maximum should be rejected")
+	} else if _, ok := err.(*WorkloadKVQuotaError); !ok {
+		t.Errorf("the error should be a quota error, found %T %v", err, err)
+	}
+
+	if err := PutWorkloadKV(db, "myorg/svc1", "", "a", []byte("123456789"), quota); err != nil {
+		t.Errorf("error putting key a: %v", err)
+	} else if err := PutWorkloadKV(db, "myorg/svc1", "ag1", "b", []byte("123456789"), quota); err == nil {
+		t.Errorf("the service bytes across scopes should be limited")
+	} else if _, ok := err.(*WorkloadKVQuotaError); !ok {
+		t.Errorf("the error should be a quota error, found %T %v", err, err)
+	}
+
+	// Replacing a value only counts the new value.
+	if err := PutWorkloadKV(db, "myorg/svc1", "", "a", []byte("1234567890"), quota); err != nil {
+		t.Errorf("error replacing key a: %v", err)
+	} else if err := PutWorkloadKV(db, "myorg/svc1", "", "b", []byte("1"), quota); err != nil {
+		t.Errorf("error putting key b: %v", err)
+	} else if err := PutWorkloadKV(db, "myorg/svc1", "", "c", []byte("1"), quota); err == nil {
+		t.Errorf("the number of keys should be limited")
+	} else if err := PutWorkloadKV(db, "myorg/svc2", "", "c", []byte("1"), quota); err != nil {
+		t.Errorf("the quota of another service should not be used, error %v", err)
+	}
+}
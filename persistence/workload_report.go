@@ -0,0 +1,95 @@
+package persistence
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/boltdb/bolt"
+	"reflect"
+)
+
+const WORKLOAD_REPORTS = "workload_reports"
+
+// The health that a service can report through the workload API.
+const (
+	WORKLOAD_HEALTH_OK       = "ok"
+	WORKLOAD_HEALTH_DEGRADED = "degraded"
+	WORKLOAD_HEALTH_FAILED   = "failed"
+)
+
+func IsWorkloadHealth(health string) bool {
+	return health == WORKLOAD_HEALTH_OK || health == WORKLOAD_HEALTH_DEGRADED || health == WORKLOAD_HEALTH_FAILED
+}
+
+// The health and status that a service reported about itself through the workload API. There is one report per
+// running service instance, keyed by the agreement id of a top level service or the instance key of a dependent
+// service, which is the key of the FSS (ESS) API credential that the service used.
+type WorkloadReport struct {
+	Key        string                 `json:"key"`
+	Service    string                 `json:"service"`           // The org qualified URL of the service that made the report.
+	Health     string                 `json:"health"`            // One of the WORKLOAD_HEALTH constants.
+	Message    string                 `json:"message,omitempty"` // A human readable explanation of the health.
+	Status     map[string]interface{} `json:"status,omitempty"`  // Arbitrary status key/values of the service.
+	ReportedAt uint64                 `json:"reportedAt"`        // The time of the report, in seconds since the epoch.
+}
+
+func (w WorkloadReport) String() string {
+	return fmt.Sprintf("Key: %v, Service: %v, Health: %v, Message: %v, Status: %v, ReportedAt: %v", w.Key, w.Service, w.Health, w.Message, w.Status, w.ReportedAt)
+}
+
+// Returns true if the reports have the same content, regardless of when they were made.
+func (w *WorkloadReport) SameContent(other *WorkloadReport) bool {
+	if w == nil || other == nil {
+		return w == other
+	}
+	return w.Service == other.Service && w.Health == other.Health && w.Message == other.Message && reflect.DeepEqual(w.Status, other.Status)
+}
+
+// SaveWorkloadReport saves the report, replacing the previous report with the same key.
+func SaveWorkloadReport(db *bolt.DB, report *WorkloadReport) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(WORKLOAD_REPORTS))
+		if err != nil {
+			return err
+		}
+
+		if serial, err := json.Marshal(report); err != nil {
+			return fmt.Errorf("Failed to serialize workload report: %v. Error: %v", report, err)
+		} else {
+			return b.Put([]byte(report.Key), serial)
+		}
+	})
+}
+
+// FindWorkloadReport returns the report with the key, or nil if there is none.
+func FindWorkloadReport(db *bolt.DB, key string) (*WorkloadReport, error) {
+	var report *WorkloadReport
+
+	readErr := db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(WORKLOAD_REPORTS)); b != nil {
+			if v := b.Get([]byte(key)); v != nil {
+				report = new(WorkloadReport)
+				if err := json.Unmarshal(v, report); err != nil {
+					return fmt.Errorf("Unable to deserialize workload report record: %v", v)
+				}
+			}
+		}
+		return nil
+	})
+
+	if readErr != nil {
+		return nil, readErr
+	}
+	return report, nil
+}
+
+// DeleteWorkloadReport deletes the report with the key, if there is one.
+func DeleteWorkloadReport(db *bolt.DB, key string) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(WORKLOAD_REPORTS)); b != nil {
+			if err := b.Delete([]byte(key)); err != nil {
+				return fmt.Errorf("Unable to delete workload report %v: %v", key, err)
+			}
+		}
+		return nil
+	})
+}
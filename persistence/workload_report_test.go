@@ -0,0 +1,75 @@
+// +build unit
+
+package persistence
+
+import (
+	"testing"
+)
+
+func Test_WorkloadReport_SaveFindDelete(t *testing.T) {
+
+	dir, db, err := utsetup()
+	if err != nil {
+		t.Errorf("Error setting up UT DB: %v", err)
+	}
+	defer cleanTestDir(dir)
+
+	if report, err := FindWorkloadReport(db, "ag1"); err != nil {
+		t.Errorf("error finding a report before any were saved: %v", err)
+	} else if report != nil {
+		t.Errorf("there should be no report, found %v", report)
+	}
+
+	r1 := &WorkloadReport{Key: "ag1", Service: "myorg/svc1", Health: WORKLOAD_HEALTH_DEGRADED, Message: "no camera", Status: map[string]interface{}{"fps": 0}, ReportedAt: 100}
+	r2 := &WorkloadReport{Key: "ag2", Service: "myorg/svc2", Health: WORKLOAD_HEALTH_OK, ReportedAt: 100}
+	for _, r := range []*WorkloadReport{r1, r2} {
+		if err := SaveWorkloadReport(db, r); err != nil {
+			t.Errorf("error saving report %v: %v", r, err)
+		}
+	}
+
+	r1.Health = WORKLOAD_HEALTH_OK
+	if err := SaveWorkloadReport(db, r1); err != nil {
+		t.Errorf("error replacing report %v: %v", r1, err)
+	}
+
+	if report, err := FindWorkloadReport(db, "ag1"); err != nil {
+		t.Errorf("error finding report ag1: %v", err)
+	} else if report == nil || report.Health != WORKLOAD_HEALTH_OK || report.Message != "no camera" || report.Status["fps"] != float64(0) {
+		t.Errorf("report ag1 should have been replaced, found %v", report)
+	}
+
+	if err := DeleteWorkloadReport(db, "ag1"); err != nil {
+		t.Errorf("error deleting report ag1: %v", err)
+	} else if report, err := FindWorkloadReport(db, "ag1"); err != nil || report != nil {
+		t.Errorf("report ag1 should be deleted, found %v, error %v", report, err)
+	} else if report, err := FindWorkloadReport(db, "ag2"); err != nil || report == nil {
+		t.Errorf("report ag2 should not be deleted, found %v, error %v", report, err)
+	}
+}
+
+func Test_WorkloadReport_SameContent(t *testing.T) {
+
+	r1 := &WorkloadReport{Key: "ag1", Service: "myorg/svc1", Health: WORKLOAD_HEALTH_OK, Status: map[string]interface{}{"fps": 30.0}, ReportedAt: 100}
+	r2 := &WorkloadReport{Key: "ag1", Service: "myorg/svc1", Health: WORKLOAD_HEALTH_OK, Status: map[string]interface{}{"fps": 30.0}, ReportedAt: 200}
+	var none *WorkloadReport
+
+	if !r1.SameContent(r2) {
+		t.Errorf("reports that only differ in time should have the same content")
+	} else if !none.SameContent(nil) {
+		t.Errorf("two missing reports should have the same content")
+	} else if r1.SameContent(nil) || none.SameContent(r1) {
+		t.Errorf("a report and a missing report should not have the same content")
+	}
+
+	r2.Status["fps"] = 25.0
+	if r1.SameContent(r2) {
+		t.Errorf("reports with a different status should not have the same content")
+	}
+
+	r2.Status["fps"] = 30.0
+	r2.Health = WORKLOAD_HEALTH_DEGRADED
+	if r1.SameContent(r2) {
+		t.Errorf("reports with a different health should not have the same content")
+	}
+}
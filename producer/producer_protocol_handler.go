@@ -15,9 +15,9 @@ import (
 	"github.com/open-horizon/anax/i18n"
 	"github.com/open-horizon/anax/persistence"
 	"github.com/open-horizon/anax/policy"
+	"github.com/open-horizon/anax/tracing"
 	"github.com/open-horizon/anax/worker"
 	"strings"
-	"time"
 )
 
 const (
@@ -125,37 +125,9 @@ func (w *BaseProducerProtocolHandler) sendMessage(mt interface{}, pay []byte) er
 		// Marshal it into a byte array
 	} else if msgBody, err := json.Marshal(encryptedMsg); err != nil {
 		return errors.New(fmt.Sprintf("Unable to marshal exchange message %v, error %v", encryptedMsg, err))
-		// Send it to the device's message queue
+		// Send it to the agbot, through the exchange or pushed to the agbot when it advertises a msgEndPoint
 	} else {
-		pm := exchange.CreatePostMessage(msgBody, w.config.Edge.ExchangeMessageTTL)
-		var resp interface{}
-		resp = new(exchange.PostDeviceResponse)
-		targetURL := w.config.Edge.ExchangeURL + "orgs/" + exchange.GetOrg(messageTarget.ReceiverExchangeId) + "/agbots/" + exchange.GetId(messageTarget.ReceiverExchangeId) + "/msgs"
-
-		httpClientFactory := w.ec.GetHTTPFactory()
-		retryCount := httpClientFactory.RetryCount
-		retryInterval := httpClientFactory.GetRetryInterval()
-
-		for {
-			if err, tpErr := exchange.InvokeExchange(httpClientFactory.NewHTTPClient(nil), "POST", targetURL, w.ec.GetExchangeId(), w.ec.GetExchangeToken(), pm, &resp); err != nil {
-				return err
-			} else if tpErr != nil {
-				glog.Warningf(tpErr.Error())
-				if httpClientFactory.RetryCount == 0 {
-					time.Sleep(time.Duration(retryInterval) * time.Second)
-					continue
-				} else if retryCount == 0 {
-					return errors.New(fmt.Sprintf("exceeded %v retries trying to retrieve agbot for %v", httpClientFactory.RetryCount, tpErr))
-				} else {
-					retryCount--
-					time.Sleep(time.Duration(retryInterval) * time.Second)
-					continue
-				}
-			} else {
-				glog.V(5).Infof(BPPHlogString(w.Name(), fmt.Sprintf("Sent message for %v to exchange.", messageTarget.ReceiverExchangeId)))
-				return nil
-			}
-		}
+		return exchange.DeliverMessage(exchange.NewExchangeTransport(w.ec, nil), exchange.AGBOT_MAILBOX, messageTarget, msgBody, w.config.Edge.ExchangeMessageTTL)
 	}
 }
 
@@ -176,6 +148,11 @@ func (w *BaseProducerProtocolHandler) HandleProposal(ph abstractprotocol.Protoco
 
 	handled := false
 
+	// The span covers the node's evaluation of the proposal against its policy and the reply to the agbot.
+	span := tracing.StartRemoteAgreementSpan("node.policy_evaluation", proposal.AgreementId(), proposal.TraceContext())
+	span.SetAttribute("agbot.id", proposal.ConsumerId())
+	defer span.End()
+
 	if agAlreadyExists, err := persistence.FindEstablishedAgreements(w.db, w.Name(), []persistence.EAFilter{persistence.UnarchivedEAFilter(), persistence.IdEAFilter(proposal.AgreementId())}); err != nil {
 		glog.Errorf(BPPHlogString(w.Name(), fmt.Sprintf("unable to retrieve agreements from database, error %v", err)))
 	} else if len(agAlreadyExists) != 0 {
@@ -292,6 +269,7 @@ func (w *BaseProducerProtocolHandler) HandleProposal(ph abstractprotocol.Protoco
 				glog.Errorf(BPPHlogString(w.Name(), fmt.Sprintf("respond to proposal with error: %v", err)))
 				err_log_event = fmt.Sprintf("Respond to proposal with error: %v", err)
 			} else {
+				span.SetAttribute("proposal.accepted", r.ProposalAccepted())
 				if !r.ProposalAccepted() {
 					eventlog.LogAgreementEvent2(
 						w.db,
@@ -309,6 +287,7 @@ func (w *BaseProducerProtocolHandler) HandleProposal(ph abstractprotocol.Protoco
 		}
 
 		if err_log_event != "" {
+			span.SetError(errors.New(err_log_event))
 			eventlog.LogAgreementEvent2(
 				w.db,
 				persistence.SEVERITY_ERROR,
@@ -505,56 +484,16 @@ func (w *BaseProducerProtocolHandler) GetAgbotMessageEndpoint(agbotId string) (s
 
 	glog.V(5).Infof(BPPHlogString(w.Name(), fmt.Sprintf("retrieving agbot %v msg endpoint from exchange", agbotId)))
 
-	if ag, err := w.getAgbot(agbotId, w.ec.GetExchangeURL(), w.ec.GetExchangeId(), w.ec.GetExchangeToken()); err != nil {
+	if ag, err := exchange.GetAgbot(w.ec, agbotId); err != nil {
 		return "", nil, err
 	} else {
 		glog.V(5).Infof(BPPHlogString(w.Name(), fmt.Sprintf("retrieved agbot %v msg endpoint from exchange %v", agbotId, ag.MsgEndPoint)))
+		exchange.SetMessageEndPoint(agbotId, ag.MsgEndPoint)
 		return ag.MsgEndPoint, ag.PublicKey, nil
 	}
 
 }
 
-func (w *BaseProducerProtocolHandler) getAgbot(agbotId string, url string, deviceId string, token string) (*exchange.Agbot, error) {
-
-	glog.V(5).Infof(BPPHlogString(w.Name(), fmt.Sprintf("retrieving agbot %v from exchange", agbotId)))
-
-	var resp interface{}
-	resp = new(exchange.GetAgbotsResponse)
-	targetURL := url + "orgs/" + exchange.GetOrg(agbotId) + "/agbots/" + exchange.GetId(agbotId)
-
-	httpClientFactory := w.ec.GetHTTPFactory()
-	retryCount := httpClientFactory.RetryCount
-	retryInterval := httpClientFactory.GetRetryInterval()
-
-	for {
-		if err, tpErr := exchange.InvokeExchange(httpClientFactory.NewHTTPClient(nil), "GET", targetURL, deviceId, token, nil, &resp); err != nil {
-			glog.Errorf(BPPHlogString(w.Name(), fmt.Sprintf(err.Error())))
-			return nil, err
-		} else if tpErr != nil {
-			glog.Warningf(BPPHlogString(w.Name(), tpErr.Error()))
-			if httpClientFactory.RetryCount == 0 {
-				time.Sleep(time.Duration(retryInterval) * time.Second)
-				continue
-			} else if retryCount == 0 {
-				return nil, errors.New(fmt.Sprintf("exceeded %v retries trying to retrieve agbot for %v", httpClientFactory.RetryCount, tpErr))
-			} else {
-				retryCount--
-				time.Sleep(time.Duration(retryInterval) * time.Second)
-				continue
-			}
-		} else {
-			ags := resp.(*exchange.GetAgbotsResponse).Agbots
-			if ag, there := ags[agbotId]; !there {
-				return nil, errors.New(fmt.Sprintf("agbot %v not in GET response %v as expected", agbotId, ags))
-			} else {
-				glog.V(5).Infof(BPPHlogString(w.Name(), fmt.Sprintf("retrieved agbot %v from exchange %v", agbotId, ag)))
-				return &ag, nil
-			}
-		}
-	}
-
-}
-
 func (b *BaseProducerProtocolHandler) HandleExtensionMessages(msg *events.ExchangeDeviceMessage, exchangeMsg *exchange.DeviceMessage) (bool, bool, string, error) {
 	return false, false, "", nil
 }
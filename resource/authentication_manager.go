@@ -107,11 +107,29 @@ func (a *AuthenticationManager) CreateCredential(key string, id string, ver stri
 
 // Verify that the input credentials are in the auth manager.
 func (a *AuthenticationManager) Authenticate(authId string, appSecret string) (bool, string, error) {
+	if _, cred, err := a.findCredential(authId, appSecret); err != nil {
+		return false, "", err
+	} else if cred == nil {
+		return false, "", nil
+	} else {
+		return true, cred.Version, nil
+	}
+}
+
+// Verify that the input credentials are in the auth manager, and return the key that the credential was created with. The
+// key is empty when the credentials are not valid.
+func (a *AuthenticationManager) AuthenticateKey(authId string, appSecret string) (string, error) {
+	key, _, err := a.findCredential(authId, appSecret)
+	return key, err
+}
+
+// Find the credential that matches the input credentials and the key that it was created with.
+func (a *AuthenticationManager) findCredential(authId string, appSecret string) (string, *AuthenticationCredential, error) {
 
 	// Iterate through the list of all directories in the auth manager. Each directory represents a running service
 	// that has been assigned FSS (ESS) API credentials.
 	if dirs, err := ioutil.ReadDir(a.AuthPath); err != nil {
-		return false, "", errors.New(fmt.Sprintf("unable to read authentication credential file directories in %v, error: %v", a.AuthPath, err))
+		return "", nil, errors.New(fmt.Sprintf("unable to read authentication credential file directories in %v, error: %v", a.AuthPath, err))
 	} else {
 		for _, d := range dirs {
 
@@ -123,22 +141,24 @@ func (a *AuthenticationManager) Authenticate(authId string, appSecret string) (b
 			// Demarshal the auth.json file and check to see if the id and pw contained within it matches the input authId and appSecret.
 			authFileName := path.Join(a.GetCredentialPath(d.Name()), config.HZN_FSS_AUTH_FILE)
 			if authFile, err := os.Open(authFileName); err != nil {
-				return false, "", errors.New(fmt.Sprintf("unable to open auth file %v, error: %v", authFileName, err))
+				return "", nil, errors.New(fmt.Sprintf("unable to open auth file %v, error: %v", authFileName, err))
 			} else if bytes, err := ioutil.ReadAll(authFile); err != nil {
-				return false, "", errors.New(fmt.Sprintf("unable to read auth file %v, error: %v", authFileName, err))
+				authFile.Close()
+				return "", nil, errors.New(fmt.Sprintf("unable to read auth file %v, error: %v", authFileName, err))
 			} else {
+				authFile.Close()
 				authObj := new(AuthenticationCredential)
 				if err := json.Unmarshal(bytes, authObj); err != nil {
-					return false, "", errors.New(fmt.Sprintf("unable to demarshal auth file %v, error: %v", authFileName, err))
+					return "", nil, errors.New(fmt.Sprintf("unable to demarshal auth file %v, error: %v", authFileName, err))
 				} else if authObj.Id == authId && authObj.Token == appSecret {
 					glog.V(5).Infof(authLogString(fmt.Sprintf("Found valid credential for %v.", authId)))
-					return true, authObj.Version, nil
+					return d.Name(), authObj, nil
 				}
 			}
 		}
 	}
 
-	return false, "", nil
+	return "", nil, nil
 }
 
 // Remove a container authentication credential from the Agent's host file system.
@@ -16,6 +16,7 @@ type ResourceWorker struct {
 	db                *bolt.DB
 	rm                *ResourceManager
 	am                *AuthenticationManager
+	wa                *WorkloadAPI
 }
 
 func NewResourceWorker(name string, config *config.HorizonConfig, db *bolt.DB, am *AuthenticationManager) *ResourceWorker {
@@ -47,6 +48,10 @@ func NewResourceWorker(name string, config *config.HorizonConfig, db *bolt.DB, a
 		am:         am,
 	}
 
+	if config != nil && !config.Edge.WorkloadAPI.Disabled {
+		worker.wa = NewWorkloadAPI(config, db, am, worker.Messages())
+	}
+
 	glog.Info(reslog(fmt.Sprintf("Starting Resource worker")))
 	// Establish the no work interval at 1 hour for garbage collection of resources.
 	worker.Start(worker, 3600)
@@ -63,10 +68,21 @@ func (w *ResourceWorker) Initialize() bool {
 			glog.Errorf(reslog(fmt.Sprintf("Error starting ESS: %v", err)))
 			return false
 		}
+		w.startWorkloadAPI()
 	}
 	return true
 }
 
+// Start the workload API, which uses the SSL certificate that was created for the ESS. Services can still run without
+// it, so an error is only logged.
+func (w *ResourceWorker) startWorkloadAPI() {
+	if w.wa == nil {
+		return
+	} else if err := w.wa.Start(); err != nil {
+		glog.Errorf(reslog(fmt.Sprintf("Error starting the workload API: %v", err)))
+	}
+}
+
 // Handle events that are propogated to this worker from the internal event bus.
 func (w *ResourceWorker) NewEvent(incoming events.Message) {
 
@@ -151,11 +167,18 @@ func (w *ResourceWorker) handleNodeConfigCommand(cmd *NodeConfigCommand) error {
 		destinationType = "openhorizon/openhorizon.edgenode"
 	}
 	w.rm.NodeConfigUpdate(cmd.msg.Org(), destinationType, cmd.msg.DeviceId(), cmd.msg.Token())
-	return w.rm.StartFileSyncService(w.am)
+	if err := w.rm.StartFileSyncService(w.am); err != nil {
+		return err
+	}
+	w.startWorkloadAPI()
+	return nil
 }
 
 // The node has just been unconfigured so we can stop the file sync service.
 func (w *ResourceWorker) handleNodeUnconfigCommand(cmd *NodeUnconfigCommand) error {
+	if w.wa != nil {
+		w.wa.Stop()
+	}
 	w.rm.StopFileSyncService()
 	w.Commands <- worker.NewTerminateCommand("shutdown")
 	return nil
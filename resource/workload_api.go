@@ -0,0 +1,383 @@
+package resource
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/boltdb/bolt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/events"
+	"github.com/open-horizon/anax/persistence"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The path of the workload API resource where a service reports its health and status.
+const WORKLOAD_STATUS_PATH = "/workload/status"
+
+// The path of the workload API resource where a service stores its keys and values.
+const WORKLOAD_KV_PATH = "/workload/kv"
+
+// The scopes of the keys of a service. The service scope is shared by all the instances and versions of the service,
+// the agreement scope belongs to the agreement (or dependent service instance) that the calling container is part of.
+const (
+	WORKLOAD_KV_SCOPE_SERVICE   = "service"
+	WORKLOAD_KV_SCOPE_AGREEMENT = "agreement"
+)
+
+// The response to a listing of the keys of a service.
+type WorkloadKVList struct {
+	Scope string                      `json:"scope"`
+	Keys  []string                    `json:"keys"`
+	Usage persistence.WorkloadKVUsage `json:"usage"` // The usage of the service, across all its scopes.
+	Quota persistence.WorkloadKVQuota `json:"quota"`
+}
+
+// The body of a status report posted by a service.
+type WorkloadStatusInput struct {
+	Health  string                 `json:"health"`
+	Message string                 `json:"message,omitempty"`
+	Status  map[string]interface{} `json:"status,omitempty"`
+}
+
+// The workload API is where service containers report their health and status to the agent. It is served next to the
+// embedded ESS, over the same protocol and with the same SSL certificate, and a service authenticates with the FSS (ESS)
+// API credentials that were mounted into its containers. A report that changes the health or status of a service is
+// sent to the other workers, so that the status is reported to the exchange and a failed service is cleaned up. Services
+// can also keep small amounts of durable state in a key-value store in the agent's database, within the quotas of the
+// configuration.
+type WorkloadAPI struct {
+	config   *config.HorizonConfig
+	db       *bolt.DB
+	am       *AuthenticationManager
+	messages chan events.Message
+	server   *http.Server
+	lock     sync.Mutex // serializes the reports, so that every change is sent exactly once
+}
+
+func NewWorkloadAPI(cfg *config.HorizonConfig, db *bolt.DB, am *AuthenticationManager, messages chan events.Message) *WorkloadAPI {
+	return &WorkloadAPI{
+		config:   cfg,
+		db:       db,
+		am:       am,
+		messages: messages,
+	}
+}
+
+// Start listening on the workload API address. The SSL certificate of the ESS must already exist.
+func (a *WorkloadAPI) Start() error {
+	if a.server != nil {
+		return nil
+	}
+
+	certFile := path.Join(a.config.GetESSSSLClientCertPath(), config.HZN_FSS_CERT_FILE)
+	certKeyFile := path.Join(a.config.GetESSSSLCertKeyPath(), config.HZN_FSS_CERT_KEY_FILE)
+	cert, err := tls.LoadX509KeyPair(certFile, certKeyFile)
+	if err != nil {
+		return errors.New(fmt.Sprintf("unable to load the SSL certificate %v and key %v for the workload API, error %v", certFile, certKeyFile, err))
+	}
+
+	var listener net.Listener
+	if a.config.FSSIsUnixProtocol() {
+		socket := a.config.GetWorkloadAPIListen()
+		if err := os.RemoveAll(socket); err != nil {
+			return errors.New(fmt.Sprintf("unable to remove the old workload API socket %v, error %v", socket, err))
+		} else if listener, err = net.Listen("unix", socket); err != nil {
+			return errors.New(fmt.Sprintf("unable to listen on the workload API socket %v, error %v", socket, err))
+		} else if err := os.Chmod(socket, 0666); err != nil {
+			listener.Close()
+			return errors.New(fmt.Sprintf("unable to set the permissions of the workload API socket %v, error %v", socket, err))
+		}
+	} else {
+		address := fmt.Sprintf("%v:%v", a.config.GetWorkloadAPIListen(), a.config.GetWorkloadAPIPort())
+		if listener, err = net.Listen("tcp", address); err != nil {
+			return errors.New(fmt.Sprintf("unable to listen on the workload API address %v, error %v", address, err))
+		}
+	}
+
+	a.server = &http.Server{
+		Handler:      a.Router(),
+		TLSConfig:    &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12},
+		ReadTimeout:  time.Duration(config.HTTPRequestTimeoutS) * time.Second,
+		WriteTimeout: time.Duration(config.HTTPRequestTimeoutS) * time.Second,
+	}
+
+	go func(server *http.Server, listener net.Listener) {
+		if err := server.Serve(tls.NewListener(listener, server.TLSConfig)); err != nil && err != http.ErrServerClosed {
+			glog.Errorf(walogString(fmt.Sprintf("stopped serving, error: %v", err)))
+		}
+	}(a.server, listener)
+
+	glog.V(3).Infof(walogString(fmt.Sprintf("listening on %v", listener.Addr())))
+	return nil
+}
+
+func (a *WorkloadAPI) Stop() {
+	if a.server == nil {
+		return
+	}
+	if err := a.server.Close(); err != nil {
+		glog.Errorf(walogString(fmt.Sprintf("error stopping, error: %v", err)))
+	}
+	a.server = nil
+	if a.config.FSSIsUnixProtocol() {
+		os.RemoveAll(a.config.GetWorkloadAPIListen())
+	}
+	glog.V(3).Infof(walogString("stopped"))
+}
+
+func (a *WorkloadAPI) Router() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(WORKLOAD_STATUS_PATH, a.status)
+	mux.HandleFunc(WORKLOAD_KV_PATH, a.kvList)
+	mux.HandleFunc(WORKLOAD_KV_PATH+"/", a.kv)
+	return mux
+}
+
+// Verify the FSS (ESS) API credentials of the calling service. Returns the org qualified URL of the service and the
+// key of its credential. When the credentials are not valid, the error has been written to the response.
+func (a *WorkloadAPI) authenticate(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+
+	authId, secret, ok := r.BasicAuth()
+	if !ok {
+		writeWorkloadAPIError(w, http.StatusUnauthorized, "the FSS (ESS) API credentials of the service must be provided with basic authentication")
+		return "", "", false
+	}
+
+	key, err := a.am.AuthenticateKey(authId, secret)
+	if err != nil {
+		glog.Errorf(walogString(fmt.Sprintf("unable to verify %v, error %v", authId, err)))
+		writeWorkloadAPIError(w, http.StatusInternalServerError, "unable to verify the credentials")
+		return "", "", false
+	} else if key == "" {
+		glog.Errorf(walogString(fmt.Sprintf("credentials for %v are not valid", authId)))
+		writeWorkloadAPIError(w, http.StatusUnauthorized, "the credentials are not valid")
+		return "", "", false
+	}
+	return authId, key, true
+}
+
+// GET returns the last report of the calling service, POST replaces it.
+func (a *WorkloadAPI) status(w http.ResponseWriter, r *http.Request) {
+
+	authId, key, ok := a.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if report, err := persistence.FindWorkloadReport(a.db, key); err != nil {
+			glog.Errorf(walogString(fmt.Sprintf("unable to read the report of %v, error %v", key, err)))
+			writeWorkloadAPIError(w, http.StatusInternalServerError, "unable to read the report")
+		} else if report == nil {
+			writeWorkloadAPIError(w, http.StatusNotFound, "the service has not reported its status")
+		} else {
+			writeWorkloadAPIResponse(w, http.StatusOK, report)
+		}
+
+	case http.MethodPost:
+		var input WorkloadStatusInput
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, int64(a.config.GetWorkloadAPIMaxReportBytes()))).Decode(&input); err != nil {
+			writeWorkloadAPIError(w, http.StatusBadRequest, fmt.Sprintf("the report must be a JSON object of at most %v bytes, error: %v", a.config.GetWorkloadAPIMaxReportBytes(), err))
+			return
+		} else if !persistence.IsWorkloadHealth(input.Health) {
+			writeWorkloadAPIError(w, http.StatusBadRequest, fmt.Sprintf("health must be %v, %v or %v", persistence.WORKLOAD_HEALTH_OK, persistence.WORKLOAD_HEALTH_DEGRADED, persistence.WORKLOAD_HEALTH_FAILED))
+			return
+		}
+
+		report := persistence.WorkloadReport{
+			Key:        key,
+			Service:    authId,
+			Health:     input.Health,
+			Message:    input.Message,
+			Status:     input.Status,
+			ReportedAt: uint64(time.Now().Unix()),
+		}
+		if err := a.saveReport(&report); err != nil {
+			glog.Errorf(walogString(fmt.Sprintf("unable to save the report %v, error %v", report, err)))
+			writeWorkloadAPIError(w, http.StatusInternalServerError, "unable to save the report")
+		} else {
+			writeWorkloadAPIResponse(w, http.StatusCreated, report)
+		}
+
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		writeWorkloadAPIError(w, http.StatusMethodNotAllowed, fmt.Sprintf("method %v is not supported", r.Method))
+	}
+}
+
+// GET lists the keys of the calling service in the scope.
+func (a *WorkloadAPI) kvList(w http.ResponseWriter, r *http.Request) {
+
+	_, key, ok := a.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	service, agreementId, ok := a.kvNamespace(w, r, key)
+	if !ok {
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		writeWorkloadAPIError(w, http.StatusMethodNotAllowed, fmt.Sprintf("method %v is not supported", r.Method))
+		return
+	}
+
+	if keys, usage, err := persistence.ListWorkloadKV(a.db, service, agreementId); err != nil {
+		glog.Errorf(walogString(fmt.Sprintf("unable to list the keys of %v, error %v", service, err)))
+		writeWorkloadAPIError(w, http.StatusInternalServerError, "unable to list the keys")
+	} else {
+		scope := WORKLOAD_KV_SCOPE_SERVICE
+		if agreementId != "" {
+			scope = WORKLOAD_KV_SCOPE_AGREEMENT
+		}
+		writeWorkloadAPIResponse(w, http.StatusOK, WorkloadKVList{Scope: scope, Keys: keys, Usage: usage, Quota: a.kvQuota()})
+	}
+}
+
+// GET returns the value of a key of the calling service, PUT sets it and DELETE deletes it. Values are opaque bytes.
+func (a *WorkloadAPI) kv(w http.ResponseWriter, r *http.Request) {
+
+	_, key, ok := a.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	service, agreementId, ok := a.kvNamespace(w, r, key)
+	if !ok {
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, WORKLOAD_KV_PATH+"/")
+	if name == "" || strings.Contains(name, "/") || len(name) > config.HZN_WORKLOAD_API_KV_MAX_KEY_LENGTH {
+		writeWorkloadAPIError(w, http.StatusBadRequest, fmt.Sprintf("the key must be 1 to %v characters, without /", config.HZN_WORKLOAD_API_KV_MAX_KEY_LENGTH))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if value, err := persistence.GetWorkloadKV(a.db, service, agreementId, name); err != nil {
+			glog.Errorf(walogString(fmt.Sprintf("unable to read key %v of %v, error %v", name, service, err)))
+			writeWorkloadAPIError(w, http.StatusInternalServerError, "unable to read the key")
+		} else if value == nil {
+			writeWorkloadAPIError(w, http.StatusNotFound, fmt.Sprintf("key %v is not set", name))
+		} else {
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.WriteHeader(http.StatusOK)
+			w.Write(value)
+		}
+
+	case http.MethodPut:
+		maxValueBytes := a.config.GetWorkloadAPIKVMaxValueBytes()
+		value, err := ioutil.ReadAll(io.LimitReader(r.Body, int64(maxValueBytes)+1))
+		if err != nil {
+			writeWorkloadAPIError(w, http.StatusBadRequest, fmt.Sprintf("unable to read the value, error: %v", err))
+			return
+		} else if len(value) > maxValueBytes {
+			writeWorkloadAPIError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("the value must be at most %v bytes", maxValueBytes))
+			return
+		}
+
+		if err := persistence.PutWorkloadKV(a.db, service, agreementId, name, value, a.kvQuota()); err != nil {
+			if _, ok := err.(*persistence.WorkloadKVQuotaError); ok {
+				writeWorkloadAPIError(w, http.StatusInsufficientStorage, err.Error())
+			} else {
+				glog.Errorf(walogString(fmt.Sprintf("unable to save key %v of %v, error %v", name, service, err)))
+				writeWorkloadAPIError(w, http.StatusInternalServerError, "unable to save the key")
+			}
+		} else {
+			w.WriteHeader(http.StatusNoContent)
+		}
+
+	case http.MethodDelete:
+		if deleted, err := persistence.DeleteWorkloadKV(a.db, service, agreementId, name); err != nil {
+			glog.Errorf(walogString(fmt.Sprintf("unable to delete key %v of %v, error %v", name, service, err)))
+			writeWorkloadAPIError(w, http.StatusInternalServerError, "unable to delete the key")
+		} else if !deleted {
+			writeWorkloadAPIError(w, http.StatusNotFound, fmt.Sprintf("key %v is not set", name))
+		} else {
+			w.WriteHeader(http.StatusNoContent)
+		}
+
+	default:
+		w.Header().Set("Allow", "GET, PUT, DELETE")
+		writeWorkloadAPIError(w, http.StatusMethodNotAllowed, fmt.Sprintf("method %v is not supported", r.Method))
+	}
+}
+
+// Returns the service and agreement that the keys of the request belong to. The store of a service is named by the
+// org qualified URL that it authenticated with, so that it is kept across upgrades of the service. The scope query
+// parameter selects the keys of the calling agreement instead of the keys of the service.
+func (a *WorkloadAPI) kvNamespace(w http.ResponseWriter, r *http.Request, key string) (string, string, bool) {
+	service, _, _ := r.BasicAuth()
+
+	switch scope := r.URL.Query().Get("scope"); scope {
+	case "", WORKLOAD_KV_SCOPE_SERVICE:
+		return service, "", true
+	case WORKLOAD_KV_SCOPE_AGREEMENT:
+		return service, key, true
+	default:
+		writeWorkloadAPIError(w, http.StatusBadRequest, fmt.Sprintf("scope must be %v or %v", WORKLOAD_KV_SCOPE_SERVICE, WORKLOAD_KV_SCOPE_AGREEMENT))
+		return "", "", false
+	}
+}
+
+func (a *WorkloadAPI) kvQuota() persistence.WorkloadKVQuota {
+	return persistence.WorkloadKVQuota{
+		MaxKeys:         a.config.GetWorkloadAPIKVMaxKeys(),
+		MaxValueBytes:   a.config.GetWorkloadAPIKVMaxValueBytes(),
+		MaxServiceBytes: a.config.GetWorkloadAPIKVMaxServiceBytes(),
+	}
+}
+
+// Save the report and tell the other workers about it when it changed the health or status of the service.
+func (a *WorkloadAPI) saveReport(report *persistence.WorkloadReport) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	previous, err := persistence.FindWorkloadReport(a.db, report.Key)
+	if err != nil {
+		return err
+	} else if err := persistence.SaveWorkloadReport(a.db, report); err != nil {
+		return err
+	}
+
+	if previous == nil || !previous.SameContent(report) {
+		previousHealth := ""
+		if previous != nil {
+			previousHealth = previous.Health
+		}
+		glog.V(3).Infof(walogString(fmt.Sprintf("%v reported a change, %v", report.Service, report)))
+		a.messages <- events.NewWorkloadReportMessage(events.WORKLOAD_REPORTED, *report, previousHealth)
+	}
+	return nil
+}
+
+func writeWorkloadAPIResponse(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		glog.Errorf(walogString(fmt.Sprintf("unable to write the response, error: %v", err)))
+	}
+}
+
+func writeWorkloadAPIError(w http.ResponseWriter, code int, msg string) {
+	writeWorkloadAPIResponse(w, code, map[string]string{"error": msg})
+}
+
+// Logging function
+var walogString = func(v interface{}) string {
+	return fmt.Sprintf("Workload API: %v", v)
+}
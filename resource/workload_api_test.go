@@ -0,0 +1,170 @@
+// +build unit
+
+package resource
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/boltdb/bolt"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/events"
+	"github.com/open-horizon/anax/persistence"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+// Write a credential the way CreateCredential does, without the group that it needs root to create.
+func writeTestCredential(t *testing.T, am *AuthenticationManager, key string, cred *AuthenticationCredential) {
+	credBytes, err := json.Marshal(cred)
+	assert.Nil(t, err)
+	assert.Nil(t, os.MkdirAll(am.GetCredentialPath(key), 0750))
+	assert.Nil(t, ioutil.WriteFile(path.Join(am.GetCredentialPath(key), config.HZN_FSS_AUTH_FILE), credBytes, 0750))
+}
+
+func newTestWorkloadAPI(t *testing.T) (*WorkloadAPI, *bolt.DB, chan events.Message, string) {
+	dir, err := ioutil.TempDir("", "workloadapi-")
+	assert.Nil(t, err)
+
+	db, err := bolt.Open(path.Join(dir, "anax.db"), 0600, &bolt.Options{Timeout: 10 * time.Second})
+	assert.Nil(t, err)
+
+	am := NewAuthenticationManager(path.Join(dir, "auth"))
+	writeTestCredential(t, am, "ag1", &AuthenticationCredential{Id: "myorg/svc1", Token: "token1"})
+	writeTestCredential(t, am, "myorg_svc2_1.0.0_key", &AuthenticationCredential{Id: "myorg/svc2", Token: "token2", Version: "1.0.0"})
+
+	cfg := &config.HorizonConfig{Edge: config.Config{WorkloadAPI: config.WorkloadAPIConfig{MaxReportBytes: 256, KVMaxKeys: 4, KVMaxValueBytes: 16, KVMaxServiceBytes: 40}}}
+	messages := make(chan events.Message, 10)
+	return NewWorkloadAPI(cfg, db, am, messages), db, messages, dir
+}
+
+func workloadAPIRequest(a *WorkloadAPI, method string, user string, pw string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, WORKLOAD_STATUS_PATH, bytes.NewBufferString(body))
+	if user != "" {
+		req.SetBasicAuth(user, pw)
+	}
+	rr := httptest.NewRecorder()
+	a.Router().ServeHTTP(rr, req)
+	return rr
+}
+
+func Test_WorkloadAPI_authentication(t *testing.T) {
+	a, db, _, dir := newTestWorkloadAPI(t)
+	defer os.RemoveAll(dir)
+	defer db.Close()
+
+	assert.Equal(t, http.StatusUnauthorized, workloadAPIRequest(a, http.MethodGet, "", "", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, workloadAPIRequest(a, http.MethodGet, "myorg/svc1", "token2", "").Code)
+	assert.Equal(t, http.StatusNotFound, workloadAPIRequest(a, http.MethodGet, "myorg/svc1", "token1", "").Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, workloadAPIRequest(a, http.MethodDelete, "myorg/svc1", "token1", "").Code)
+}
+
+func Test_WorkloadAPI_report(t *testing.T) {
+	a, db, messages, dir := newTestWorkloadAPI(t)
+	defer os.RemoveAll(dir)
+	defer db.Close()
+
+	// Invalid reports are rejected.
+	assert.Equal(t, http.StatusBadRequest, workloadAPIRequest(a, http.MethodPost, "myorg/svc1", "token1", `{"health":"sick"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, workloadAPIRequest(a, http.MethodPost, "myorg/svc1", "token1", `{"health":`).Code)
+	big, _ := json.Marshal(map[string]interface{}{"health": "ok", "message": string(make([]byte, 300))})
+	assert.Equal(t, http.StatusBadRequest, workloadAPIRequest(a, http.MethodPost, "myorg/svc1", "token1", string(big)).Code, "the report is larger than MaxReportBytes")
+	assert.Len(t, messages, 0)
+
+	// The first report is sent to the other workers.
+	rr := workloadAPIRequest(a, http.MethodPost, "myorg/svc1", "token1", `{"health":"degraded","message":"no camera","status":{"fps":0}}`)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	if assert.Len(t, messages, 1) {
+		msg := (<-messages).(*events.WorkloadReportMessage)
+		assert.Equal(t, events.WORKLOAD_REPORTED, msg.Event().Id)
+		assert.Equal(t, "", msg.PreviousHealth)
+		assert.Equal(t, "ag1", msg.Report.Key)
+		assert.Equal(t, "myorg/svc1", msg.Report.Service)
+		assert.Equal(t, persistence.WORKLOAD_HEALTH_DEGRADED, msg.Report.Health)
+	}
+
+	// The same report again is saved, but nothing changed.
+	assert.Equal(t, http.StatusCreated, workloadAPIRequest(a, http.MethodPost, "myorg/svc1", "token1", `{"health":"degraded","message":"no camera","status":{"fps":0}}`).Code)
+	assert.Len(t, messages, 0)
+
+	// A change of status is sent.
+	assert.Equal(t, http.StatusCreated, workloadAPIRequest(a, http.MethodPost, "myorg/svc1", "token1", `{"health":"degraded","message":"no camera","status":{"fps":1}}`).Code)
+	if assert.Len(t, messages, 1) {
+		msg := (<-messages).(*events.WorkloadReportMessage)
+		assert.Equal(t, persistence.WORKLOAD_HEALTH_DEGRADED, msg.PreviousHealth)
+	}
+
+	// Each service only sees its own report.
+	rr = workloadAPIRequest(a, http.MethodGet, "myorg/svc1", "token1", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var report persistence.WorkloadReport
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &report))
+	assert.Equal(t, "no camera", report.Message)
+	assert.Equal(t, float64(1), report.Status["fps"])
+	assert.Equal(t, http.StatusNotFound, workloadAPIRequest(a, http.MethodGet, "myorg/svc2", "token2", "").Code)
+
+	// A dependent service reports with the key of its instance.
+	assert.Equal(t, http.StatusCreated, workloadAPIRequest(a, http.MethodPost, "myorg/svc2", "token2", `{"health":"failed"}`).Code)
+	if assert.Len(t, messages, 1) {
+		msg := (<-messages).(*events.WorkloadReportMessage)
+		assert.Equal(t, "myorg_svc2_1.0.0_key", msg.Report.Key)
+		assert.Equal(t, persistence.WORKLOAD_HEALTH_FAILED, msg.Report.Health)
+	}
+}
+
+func workloadKVRequest(a *WorkloadAPI, method string, user string, pw string, target string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+	req.SetBasicAuth(user, pw)
+	rr := httptest.NewRecorder()
+	a.Router().ServeHTTP(rr, req)
+	return rr
+}
+
+func Test_WorkloadAPI_kv(t *testing.T) {
+	a, db, _, dir := newTestWorkloadAPI(t)
+	defer os.RemoveAll(dir)
+	defer db.Close()
+
+	assert.Equal(t, http.StatusUnauthorized, workloadKVRequest(a, http.MethodGet, "myorg/svc1", "token2", "/workload/kv/cursor", "").Code)
+	assert.Equal(t, http.StatusNotFound, workloadKVRequest(a, http.MethodGet, "myorg/svc1", "token1", "/workload/kv/cursor", "").Code)
+	assert.Equal(t, http.StatusBadRequest, workloadKVRequest(a, http.MethodGet, "myorg/svc1", "token1", "/workload/kv/cursor?scope=node", "").Code)
+	assert.Equal(t, http.StatusBadRequest, workloadKVRequest(a, http.MethodPut, "myorg/svc1", "token1", "/workload/kv/a/b", "1").Code)
+
+	// The service and agreement scopes are separate.
+	assert.Equal(t, http.StatusNoContent, workloadKVRequest(a, http.MethodPut, "myorg/svc1", "token1", "/workload/kv/cursor", "10").Code)
+	assert.Equal(t, http.StatusNoContent, workloadKVRequest(a, http.MethodPut, "myorg/svc1", "token1", "/workload/kv/cursor?scope=agreement", "20").Code)
+	rr := workloadKVRequest(a, http.MethodGet, "myorg/svc1", "token1", "/workload/kv/cursor", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "10", rr.Body.String())
+	rr = workloadKVRequest(a, http.MethodGet, "myorg/svc1", "token1", "/workload/kv/cursor?scope=agreement", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "20", rr.Body.String())
+
+	// Another service does not see the keys.
+	assert.Equal(t, http.StatusNotFound, workloadKVRequest(a, http.MethodGet, "myorg/svc2", "token2", "/workload/kv/cursor", "").Code)
+
+	rr = workloadKVRequest(a, http.MethodGet, "myorg/svc1", "token1", "/workload/kv", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var list WorkloadKVList
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &list))
+	assert.Equal(t, WORKLOAD_KV_SCOPE_SERVICE, list.Scope)
+	assert.Equal(t, []string{"cursor"}, list.Keys)
+	assert.Equal(t, persistence.WorkloadKVUsage{Keys: 2, Bytes: 16}, list.Usage)
+	assert.Equal(t, 4, list.Quota.MaxKeys)
+
+	// Quotas.
+	assert.Equal(t, http.StatusRequestEntityTooLarge, workloadKVRequest(a, http.MethodPut, "myorg/svc1", "token1", "/workload/kv/big", string(make([]byte, 17))).Code)
+	assert.Equal(t, http.StatusNoContent, workloadKVRequest(a, http.MethodPut, "myorg/svc1", "token1", "/workload/kv/k3", "1234567890").Code)
+	assert.Equal(t, http.StatusInsufficientStorage, workloadKVRequest(a, http.MethodPut, "myorg/svc1", "token1", "/workload/kv/k4", "12345678901").Code, "the service bytes are exceeded")
+	assert.Equal(t, http.StatusNoContent, workloadKVRequest(a, http.MethodPut, "myorg/svc1", "token1", "/workload/kv/k4", "1").Code)
+	assert.Equal(t, http.StatusInsufficientStorage, workloadKVRequest(a, http.MethodPut, "myorg/svc1", "token1", "/workload/kv/k5", "1").Code, "the service keys are exceeded")
+
+	assert.Equal(t, http.StatusNoContent, workloadKVRequest(a, http.MethodDelete, "myorg/svc1", "token1", "/workload/kv/cursor", "").Code)
+	assert.Equal(t, http.StatusNotFound, workloadKVRequest(a, http.MethodDelete, "myorg/svc1", "token1", "/workload/kv/cursor", "").Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, workloadKVRequest(a, http.MethodPost, "myorg/svc1", "token1", "/workload/kv/cursor", "").Code)
+}
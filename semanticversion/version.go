@@ -34,6 +34,18 @@ import (
 // specifying [x.y.z, INFINITY) which is also expressed as:
 // x.y.z <= a
 //
+// A version can also have a prerelease and build metadata, as defined by SemVer 2.0, e.g. 1.2.3-rc.1+build.5.
+// A prerelease version is lower than the version without the prerelease, and the build metadata is ignored
+// when versions are compared. A prerelease version is only within a range when the start or the end of the
+// range is a prerelease of the same x.y.z version, so that prereleases are never picked up by accident.
+//
+// The following shorthand ranges, as used by npm, are also supported. They are converted to the syntax above.
+// ^x.y.z allows changes that do not modify the left-most non-zero number, e.g. ^1.2.3 is [1.2.3,2.0.0)
+// and ^0.2.3 is [0.2.3,0.3.0).
+// ~x.y.z allows patch changes when a minor version is specified, e.g. ~1.2.3 is [1.2.3,1.3.0) and ~1 is [1.0.0,2.0.0).
+// >=x.y.z, >x.y.z, <x.y.z and <=x.y.z are the obvious comparisons. A lower and an upper comparison can be combined
+// with a comma, e.g. >=1.2.0,<2.0.0 is [1.2.0,2.0.0).
+//
 
 const leftEx = "("
 const leftInc = "["
@@ -42,6 +54,15 @@ const rightInc = "]"
 const INF = "INFINITY"
 const versionSeperator = ","
 const numberSeperator = "."
+const prereleaseSeperator = "-"
+const buildSeperator = "+"
+const caret = "^"
+const tilde = "~"
+const greaterEq = ">="
+const greater = ">"
+const lessEq = "<="
+const less = "<"
+const minVersion = "0.0.0"
 
 type Version_Expression struct {
 	full_expression string
@@ -73,7 +94,14 @@ func Version_Expression_Factory(ver_string string) (*Version_Expression, error)
 		return nil, errors.New(errorString)
 	}
 
-	if singleVersion(ver_string) {
+	if isShorthandRange(ver_string) {
+		if r, err := expandShorthandRange(ver_string); err != nil {
+			return nil, err
+		} else {
+			expr = r
+			glog.V(6).Infof("Version_Expression: Detected shorthand range input, converted to %v", expr)
+		}
+	} else if singleVersion(ver_string) {
 		if !IsVersionString(ver_string) {
 			errorString := msgPrinter.Sprintf("Version_Expression: %v is not a valid version string.", ver_string)
 			return nil, errors.New(errorString)
@@ -183,52 +211,43 @@ func (self *Version_Expression) Is_within_range(expr string) (bool, error) {
 		return false, errors.New(errorString)
 	}
 
-	normalizedExpr := normalize(expr)
-
-	// Exit early in the easy cases
-	if (normalizedExpr == self.start && self.start_inclusive) || (normalizedExpr == self.end && self.end_inclusive) {
-		return true, nil
-	} else if (normalizedExpr == self.start && !self.start_inclusive) || (normalizedExpr == self.end && !self.end_inclusive) {
+	// A prerelease is only within the range when the range asks for prereleases of the same version.
+	if isPrerelease(expr) && !self.allowsPrerelease(expr) {
 		return false, nil
 	}
 
 	// Compare the start version to see if the input is in this object's range
-	exprNums := strings.Split(normalizedExpr, numberSeperator)
-	startNums := strings.Split(self.start, numberSeperator)
-	for idx, startVal := range startNums {
-		startInt, _ := strconv.Atoi(startVal)
-		exprInt, _ := strconv.Atoi(exprNums[idx])
-		if startInt == exprInt {
-			continue
-		} else if startInt < exprInt {
-			break
-		} else {
-			return false, nil
-		}
+	if c, err := CompareVersions(expr, self.start); err != nil {
+		return false, err
+	} else if c < 0 || (c == 0 && !self.start_inclusive) {
+		return false, nil
 	}
 
 	// Compare the end version to see if the input is in this object's range. An end range of
 	// "INFINITY" will always be in range.
 	if self.end == INF {
-		return true, nil
+		return expr != INF, nil
 	}
 
-	endNums := strings.Split(self.end, numberSeperator)
-	for idx, endVal := range endNums {
-		endInt, _ := strconv.Atoi(endVal)
-		exprInt, _ := strconv.Atoi(exprNums[idx])
-		if endInt == exprInt {
-			continue
-		} else if endInt > exprInt {
-			return true, nil
-		} else {
-			return false, nil
-		}
+	if c, err := CompareVersions(expr, self.end); err != nil {
+		return false, err
+	} else {
+		return c < 0 || (c == 0 && self.end_inclusive), nil
 	}
+}
 
-	// Should never get here
-	errorString := fmt.Sprintf("Version_Expression: Unable to compare versions %v %v.", expr, self)
-	return false, errors.New(errorString)
+// Return true if the start or the end of the range is a prerelease of the same x.y.z version as the input
+// prerelease version.
+func (self *Version_Expression) allowsPrerelease(expr string) bool {
+	core, _, _ := splitVersion(expr)
+	for _, bound := range []string{self.start, self.end} {
+		if bound != INF && isPrerelease(bound) {
+			if boundCore, _, _ := splitVersion(bound); boundCore == normalize(core) {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 // make this version equals to the intersection of self and the given version
@@ -347,7 +366,8 @@ func multipleVersions(expr string) bool {
 }
 
 // Return true if the input version string is a valid version according to the version string schema above.
-// A number with leading 0's, for example 1.02.1, is not a valid version string.
+// A number with leading 0's, for example 1.02.1, is not a valid version string. A version with a prerelease
+// or build metadata must have all 3 numbers, for example 1.2.0-rc.1.
 func IsVersionString(expr string) bool {
 
 	if len(expr) == 0 {
@@ -358,6 +378,26 @@ func IsVersionString(expr string) bool {
 		return true
 	}
 
+	core, pre, build := splitVersion(expr)
+	if !isVersionCore(core) {
+		return false
+	} else if core == expr {
+		return true
+	} else if len(strings.Split(core, numberSeperator)) != 3 {
+		return false
+	}
+
+	noBuild := strings.SplitN(expr, buildSeperator, 2)[0]
+	if strings.Contains(noBuild, prereleaseSeperator) && !isIdentifierList(pre, true) {
+		return false
+	} else if strings.Contains(expr, buildSeperator) && !isIdentifierList(build, false) {
+		return false
+	}
+	return true
+}
+
+// Return true if the input string is 1 to 3 dot separated numbers without leading 0's.
+func isVersionCore(expr string) bool {
 	nums := strings.Split(expr, numberSeperator)
 	if len(nums) == 0 || len(nums) > 3 {
 		return false
@@ -382,6 +422,157 @@ func IsVersionString(expr string) bool {
 	}
 }
 
+// Return true if the input string is a list of dot separated identifiers made of ASCII letters, digits and
+// hyphens. Numeric prerelease identifiers must not have leading 0's.
+func isIdentifierList(expr string, prerelease bool) bool {
+	for _, id := range strings.Split(expr, numberSeperator) {
+		if id == "" {
+			return false
+		}
+		for _, c := range id {
+			if !(c >= '0' && c <= '9') && !(c >= 'a' && c <= 'z') && !(c >= 'A' && c <= 'Z') && c != '-' {
+				return false
+			}
+		}
+		if prerelease && isNumeric(id) && len(id) > 1 && id[0] == '0' {
+			return false
+		}
+	}
+	return true
+}
+
+// Return true if the input string only has digits.
+func isNumeric(expr string) bool {
+	for _, c := range expr {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return len(expr) != 0
+}
+
+// Split a version string into its numbers, its prerelease and its build metadata, e.g. 1.2.3-rc.1+build.5
+// is split into 1.2.3, rc.1 and build.5. The prerelease and build metadata are empty when they are not specified.
+func splitVersion(expr string) (string, string, string) {
+	build := ""
+	if i := strings.Index(expr, buildSeperator); i != -1 {
+		build = expr[i+1:]
+		expr = expr[:i]
+	}
+	pre := ""
+	if i := strings.Index(expr, prereleaseSeperator); i != -1 {
+		pre = expr[i+1:]
+		expr = expr[:i]
+	}
+	return expr, pre, build
+}
+
+// Return true if the input version string has a prerelease.
+func isPrerelease(expr string) bool {
+	_, pre, _ := splitVersion(expr)
+	return pre != ""
+}
+
+// Return true if the input string starts with one of the shorthand range operators.
+func isShorthandRange(expr string) bool {
+	for _, op := range []string{caret, tilde, greater, less} {
+		if strings.HasPrefix(expr, op) {
+			return true
+		}
+	}
+	return false
+}
+
+// Convert a shorthand range, e.g. ^1.2.3, ~1.2 or >=1.0.0,<2.0.0, into the equivalent full version expression.
+func expandShorthandRange(expr string) (string, error) {
+
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	if strings.HasPrefix(expr, caret) || strings.HasPrefix(expr, tilde) {
+		ver := expr[1:]
+		if !IsVersionString(ver) || ver == INF {
+			return "", errors.New(msgPrinter.Sprintf("Version_Expression: %v is not a valid version string.", ver))
+		}
+
+		// The position of the number that is incremented to get the end of the range.
+		core, _, _ := splitVersion(ver)
+		nums := strings.Split(core, numberSeperator)
+		pos := 0
+		if strings.HasPrefix(expr, caret) {
+			pos = len(nums) - 1
+			for i, n := range nums {
+				if n != "0" {
+					pos = i
+					break
+				}
+			}
+		} else if len(nums) > 1 {
+			pos = 1
+		}
+
+		endNums := []string{"0", "0", "0"}
+		for i := 0; i < pos; i++ {
+			endNums[i] = nums[i]
+		}
+		n, _ := strconv.Atoi(nums[pos])
+		endNums[pos] = strconv.Itoa(n + 1)
+
+		return leftInc + normalize(ver) + versionSeperator + strings.Join(endNums, numberSeperator) + rightEx, nil
+	}
+
+	start, startInc := minVersion, true
+	end, endInc := INF, false
+	haveStart, haveEnd := false, false
+	for _, comp := range strings.Split(expr, versionSeperator) {
+		var op string
+		for _, o := range []string{greaterEq, greater, lessEq, less} {
+			if strings.HasPrefix(comp, o) {
+				op = o
+				break
+			}
+		}
+		ver := strings.TrimPrefix(comp, op)
+		if op == "" {
+			return "", errors.New(msgPrinter.Sprintf("Version_Expression: %v does not begin with a comparison operator.", comp))
+		} else if !IsVersionString(ver) || ver == INF {
+			return "", errors.New(msgPrinter.Sprintf("Version_Expression: %v is not a valid version string.", ver))
+		}
+
+		if op == greaterEq || op == greater {
+			if haveStart {
+				return "", errors.New(msgPrinter.Sprintf("Version_Expression: %v has more than one lower bound.", expr))
+			}
+			start, startInc, haveStart = ver, op == greaterEq, true
+		} else {
+			if haveEnd {
+				return "", errors.New(msgPrinter.Sprintf("Version_Expression: %v has more than one upper bound.", expr))
+			}
+			end, endInc, haveEnd = ver, op == lessEq, true
+		}
+	}
+
+	if haveEnd {
+		if c, err := CompareVersions(start, end); err != nil {
+			return "", err
+		} else if c > 0 || (c == 0 && !(startInc && endInc)) {
+			return "", errors.New(msgPrinter.Sprintf("Version_Expression: %v is an empty range.", expr))
+		}
+	}
+
+	full := leftEx
+	if startInc {
+		full = leftInc
+	}
+	full += normalize(start) + versionSeperator + normalize(end)
+	if endInc {
+		full += rightInc
+	} else {
+		full += rightEx
+	}
+	return full, nil
+}
+
 // Return true if the input version string is a full version expression
 func IsVersionExpression(expr string) bool {
 
@@ -389,6 +580,11 @@ func IsVersionExpression(expr string) bool {
 		return false
 	}
 
+	if isShorthandRange(expr) {
+		_, err := expandShorthandRange(expr)
+		return err == nil
+	}
+
 	if !(leftIncluded(expr) || leftExcluded(expr)) && !(rightIncluded(expr) || rightExcluded(expr)) {
 		return false
 	}
@@ -412,16 +608,21 @@ func IsVersionExpression(expr string) bool {
 }
 
 // Return a normalized version string containing all 3 version numbers. The input version string is ASSUMED to
-// be a valid version string. For example, an input version string of 1 will be returned as 1.0.0
+// be a valid version string. For example, an input version string of 1 will be returned as 1.0.0. The prerelease
+// is kept and the build metadata is dropped because it has no meaning in a version range.
 func normalize(expr string) string {
 	if expr == INF {
 		return expr
 	}
-	result := expr
-	nums := strings.Split(expr, numberSeperator)
+	core, pre, _ := splitVersion(expr)
+	result := core
+	nums := strings.Split(core, numberSeperator)
 	if len(nums) < 3 {
 		result += strings.Repeat(".0", 3-len(nums))
 	}
+	if pre != "" {
+		result += prereleaseSeperator + pre
+	}
 	return result
 }
 
@@ -450,8 +651,8 @@ func CompareVersions(v1 string, v2 string) (int, error) {
 	}
 
 	// make each has 3 fields
-	v1n := normalize(v1)
-	v2n := normalize(v2)
+	v1n, v1pre, _ := splitVersion(normalize(v1))
+	v2n, v2pre, _ := splitVersion(normalize(v2))
 
 	// convert each field into integer and then compare
 	v1s := strings.Split(v1n, numberSeperator)
@@ -472,5 +673,53 @@ func CompareVersions(v1 string, v2 string) (int, error) {
 		}
 	}
 
-	return 0, nil
+	return comparePrereleases(v1pre, v2pre), nil
+}
+
+// Compare the prereleases of 2 versions that have the same numbers, as defined by SemVer 2.0. A version without
+// a prerelease is higher than the same version with a prerelease. Otherwise the dot separated identifiers are
+// compared in turn, numerically when both are numbers and in ASCII order when they are not, and a number is lower
+// than a string. When all of the identifiers are equal, the longer prerelease is higher, e.g. 1.0.0-alpha is lower
+// than 1.0.0-alpha.1 which is lower than 1.0.0-beta.
+func comparePrereleases(pre1 string, pre2 string) int {
+	if pre1 == pre2 {
+		return 0
+	} else if pre1 == "" {
+		return 1
+	} else if pre2 == "" {
+		return -1
+	}
+
+	ids1 := strings.Split(pre1, numberSeperator)
+	ids2 := strings.Split(pre2, numberSeperator)
+	for i := 0; i < len(ids1) && i < len(ids2); i++ {
+		id1, id2 := ids1[i], ids2[i]
+		if id1 == id2 {
+			continue
+		}
+		num1, num2 := isNumeric(id1), isNumeric(id2)
+		if num1 && num2 {
+			if len(id1) != len(id2) {
+				if len(id1) < len(id2) {
+					return -1
+				}
+				return 1
+			}
+		} else if num1 {
+			return -1
+		} else if num2 {
+			return 1
+		}
+		if id1 < id2 {
+			return -1
+		}
+		return 1
+	}
+
+	if len(ids1) < len(ids2) {
+		return -1
+	} else if len(ids1) > len(ids2) {
+		return 1
+	}
+	return 0
 }
@@ -320,14 +320,14 @@ func TestVersionExpressionFailure(t *testing.T) {
 
 // This test tests if the version string is a valide string.
 func TestIsVersionString(t *testing.T) {
-	v_good := []string{"1.0", "1.2", "1.234.567", "3.0.0", "234"}
+	v_good := []string{"1.0", "1.2", "1.234.567", "3.0.0", "234", "1.2.3-abc", "1.0.0-rc.1", "1.0.0-0.3.7", "1.0.0-x-y.z", "1.0.0+20260101", "1.0.0-beta+exp.sha.5114f85"}
 	for _, v := range v_good {
 		if !IsVersionString(v) {
 			t.Errorf("Version string %v is valid, however the IsVersionString function returned false.\n", v)
 		}
 	}
 
-	v_bad := []string{"1.0.0.1", "1.2.3a", "[1.2, 1.3]", "1.2.3-abc", "1.2.03"}
+	v_bad := []string{"1.0.0.1", "1.2.3a", "[1.2, 1.3]", "1.2.03", "1.2-abc", "1.2.3-", "1.2.3-01", "1.2.3-a..b", "1.2.3+", "1.2.3-rc_1", "1.2.3+a+b"}
 	for _, v := range v_bad {
 		if IsVersionString(v) {
 			t.Errorf("Version string %v is invalid, however the IsVersionString function returned true.\n", v)
@@ -521,3 +521,104 @@ func TestCompareVersions(t *testing.T) {
 	c, err = CompareVersions(v1, v2)
 	assert.NotNil(t, err, fmt.Sprintf("Should get error, but did not. \n"))
 }
+
+// This series of tests verifies that the shorthand ranges are converted to the full version expression.
+func TestShorthandRanges(t *testing.T) {
+	good := map[string]string{
+		"^1.2.3":          "[1.2.3,2.0.0)",
+		"^1.2":            "[1.2.0,2.0.0)",
+		"^1":              "[1.0.0,2.0.0)",
+		"^0.2.3":          "[0.2.3,0.3.0)",
+		"^0.0.3":          "[0.0.3,0.0.4)",
+		"^0.0":            "[0.0.0,0.1.0)",
+		"^0":              "[0.0.0,1.0.0)",
+		"^1.2.3-beta.2":   "[1.2.3-beta.2,2.0.0)",
+		"~1.2.3":          "[1.2.3,1.3.0)",
+		"~1.2":            "[1.2.0,1.3.0)",
+		"~1":              "[1.0.0,2.0.0)",
+		"~0.2.3":          "[0.2.3,0.3.0)",
+		">=1.2":           "[1.2.0,INFINITY)",
+		">1.2.3":          "(1.2.3,INFINITY)",
+		"<2.0.0":          "[0.0.0,2.0.0)",
+		"<=2":             "[0.0.0,2.0.0]",
+		">=1.2.0,<2.0.0":  "[1.2.0,2.0.0)",
+		"<=2.0.0,>1.0.0":  "(1.0.0,2.0.0]",
+		">=1.0.0,<=1.0.0": "[1.0.0,1.0.0]",
+	}
+	for in, out := range good {
+		if c, err := Version_Expression_Factory(in); err != nil {
+			t.Errorf("Factory returned error for %v: %v", in, err)
+		} else if c.Get_expression() != out {
+			t.Errorf("Factory converted %v to %v, expected %v", in, c.Get_expression(), out)
+		} else if !IsVersionExpression(in) {
+			t.Errorf("%v is a version expression", in)
+		}
+	}
+
+	bad := []string{"^", "~", "^a", "~1.2.3.4", "^INFINITY", ">=", ">=1.0.0,>=2.0.0", "<1.0.0,<2.0.0", ">=2.0.0,<1.0.0", ">1.0.0,<1.0.0", ">=1.0.0,2.0.0", "=>1.0.0", ">=1.0.0,<2.0.0,<3.0.0"}
+	for _, in := range bad {
+		if c, err := Version_Expression_Factory(in); err == nil {
+			t.Errorf("Factory did not return an error for %v, it returned %v", in, c)
+		} else if IsVersionExpression(in) {
+			t.Errorf("%v is NOT a version expression", in)
+		}
+	}
+}
+
+// This series of tests verifies that prerelease versions are only within a range that asks for them.
+func TestPrereleaseRanges(t *testing.T) {
+	tests := []struct {
+		expr    string
+		version string
+		within  bool
+	}{
+		{"^1.2.3", "1.5.0", true},
+		{"^1.2.3", "2.0.0", false},
+		{"^1.2.3", "1.5.0-rc.1", false},
+		{"^1.2.3", "2.0.0-rc.1", false},
+		{"^1.2.3", "1.2.3+build.1", true},
+		{"^1.2.3-beta.2", "1.2.3-beta.3", true},
+		{"^1.2.3-beta.2", "1.2.3-beta.1", false},
+		{"^1.2.3-beta.2", "1.2.3", true},
+		{"^1.2.3-beta.2", "1.2.4-beta.3", false},
+		{"1.2.0", "1.2.0-rc.1", false},
+		{"1.2.0-rc.1", "1.2.0-rc.2", true},
+		{"1.2.0-rc.1", "1.3.0", true},
+		{"[1.0.0,2.0.0-rc.1]", "2.0.0-beta", true},
+		{"[1.0.0,2.0.0-rc.1]", "2.0.0-rc.2", false},
+		{">1.0.0,<=1.5.0", "1.5.0", true},
+		{">1.0.0,<=1.5.0", "1.0.0", false},
+		{"~1.2", "1.2.9", true},
+		{"~1.2", "1.3.0", false},
+	}
+	for _, test := range tests {
+		if c, err := Version_Expression_Factory(test.expr); err != nil {
+			t.Errorf("Factory returned error for %v: %v", test.expr, err)
+		} else if within, err := c.Is_within_range(test.version); err != nil {
+			t.Errorf("Is_within_range returned error for %v in %v: %v", test.version, test.expr, err)
+		} else if within != test.within {
+			t.Errorf("Is_within_range returned %v for %v in %v, expected %v", within, test.version, test.expr, test.within)
+		}
+	}
+}
+
+// This test verifies the SemVer 2.0 ordering of prerelease versions.
+func TestComparePrereleaseVersions(t *testing.T) {
+	ordered := []string{"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta", "1.0.0-beta.2", "1.0.0-beta.11", "1.0.0-rc.1", "1.0.0", "1.0.1-0", "1.0.1"}
+	for i := 0; i < len(ordered)-1; i++ {
+		c, err := CompareVersions(ordered[i], ordered[i+1])
+		assert.Nil(t, err, fmt.Sprintf("Error should be nil, but got:%v \n", err))
+		assert.Equal(t, -1, c, fmt.Sprintf("%v should be lower than %v.", ordered[i], ordered[i+1]))
+		c, err = CompareVersions(ordered[i+1], ordered[i])
+		assert.Nil(t, err, fmt.Sprintf("Error should be nil, but got:%v \n", err))
+		assert.Equal(t, 1, c, fmt.Sprintf("%v should be higher than %v.", ordered[i+1], ordered[i]))
+	}
+
+	c, err := CompareVersions("1.0.0+build.1", "1.0.0+build.2")
+	assert.Nil(t, err, fmt.Sprintf("Error should be nil, but got:%v \n", err))
+	assert.Equal(t, 0, c, "build metadata should be ignored.")
+
+	c, err = CompareVersions("1.0.0-rc.1", "INFINITY")
+	assert.Nil(t, err, fmt.Sprintf("Error should be nil, but got:%v \n", err))
+	assert.Equal(t, -1, c, "1.0.0-rc.1 should be lower than INFINITY.")
+}
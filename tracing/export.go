@@ -0,0 +1,309 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/config"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The number of ended spans that can be waiting to be exported. Spans are dropped when the queue is full.
+const SPAN_QUEUE_SIZE = 2048
+
+// The maximum number of spans in one export.
+const SPAN_BATCH_SIZE = 512
+
+// The maximum number of agreements whose most recent span is remembered.
+const AGREEMENT_SPAN_MAP_SIZE = 10000
+
+// The timeout for sending spans to the OTLP collector.
+const OTLP_HTTP_TIMEOUT_S = 10
+
+// The instrumentation scope of the spans.
+const SCOPE_NAME = "github.com/open-horizon/anax"
+
+// An exporter sends a batch of spans, encoded as an OTLP json export request, to its destination.
+type exporter interface {
+	Export(request []byte) error
+	Shutdown()
+}
+
+func newExporter(cfg *config.TracingConfig) (exporter, error) {
+	switch cfg.Exporter {
+	case config.TracingExporterOTLP:
+		if cfg.OTLPEndpoint == "" {
+			return nil, errors.New("the OTLPEndpoint must be set for the otlp tracing exporter")
+		}
+		return &otlpHTTPExporter{
+			url:     strings.TrimRight(cfg.OTLPEndpoint, "/") + "/v1/traces",
+			headers: cfg.OTLPHeaders,
+			client:  &http.Client{Timeout: OTLP_HTTP_TIMEOUT_S * time.Second},
+		}, nil
+	case config.TracingExporterFile:
+		if cfg.File == "" {
+			return nil, errors.New("the File must be set for the file tracing exporter")
+		}
+		if f, err := os.OpenFile(cfg.File, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600); err != nil {
+			return nil, errors.New(fmt.Sprintf("unable to open the tracing file %v, error: %v", cfg.File, err))
+		} else {
+			return &writerExporter{w: f, closer: f}, nil
+		}
+	case config.TracingExporterStdout:
+		return &writerExporter{w: os.Stdout}, nil
+	default:
+		return nil, errors.New(fmt.Sprintf("tracing exporter %v is not supported, it must be %v, %v or %v", cfg.Exporter, config.TracingExporterOTLP, config.TracingExporterFile, config.TracingExporterStdout))
+	}
+}
+
+// Post the spans to an OTLP/HTTP collector using the json encoding.
+type otlpHTTPExporter struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+func (e *otlpHTTPExporter) Export(request []byte) error {
+	req, err := http.NewRequest(http.MethodPost, e.url, bytes.NewReader(request))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return errors.New(fmt.Sprintf("OTLP collector %v returned %v: %v", e.url, resp.Status, string(body)))
+	}
+	return nil
+}
+
+func (e *otlpHTTPExporter) Shutdown() {}
+
+// Write each batch of spans as one line, which is the same format as the OTLP json file exporter of the
+// OpenTelemetry collector. The file can later be replayed into a collector.
+type writerExporter struct {
+	w      io.Writer
+	closer io.Closer
+}
+
+func (e *writerExporter) Export(request []byte) error {
+	_, err := e.w.Write(append(request, '\n'))
+	return err
+}
+
+func (e *writerExporter) Shutdown() {
+	if e.closer != nil {
+		e.closer.Close()
+	}
+}
+
+// Collect the ended spans and export them in batches, on the batch interval or when a batch is full.
+func (t *tracer) run() {
+	defer t.stopped.Done()
+
+	ticker := time.NewTicker(time.Duration(t.cfg.BatchIntervalS) * time.Second)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, SPAN_BATCH_SIZE)
+	for {
+		select {
+		case s := <-t.spans:
+			batch = append(batch, s)
+			if len(batch) >= SPAN_BATCH_SIZE {
+				batch = t.export(batch)
+			}
+		case <-ticker.C:
+			batch = t.export(batch)
+		case <-t.done:
+			for {
+				select {
+				case s := <-t.spans:
+					batch = append(batch, s)
+				default:
+					t.export(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+// Export the batch and return an empty batch. Failed exports are logged and the spans are dropped.
+func (t *tracer) export(batch []*Span) []*Span {
+	if len(batch) == 0 {
+		return batch
+	}
+
+	if request, err := json.Marshal(newExportRequest(t.serviceName, batch)); err != nil {
+		glog.Errorf(tlogString(fmt.Sprintf("unable to encode %v spans, error: %v", len(batch), err)))
+	} else if err := t.exporter.Export(request); err != nil {
+		glog.Errorf(tlogString(fmt.Sprintf("unable to export %v spans, error: %v", len(batch), err)))
+	} else {
+		glog.V(5).Infof(tlogString(fmt.Sprintf("exported %v spans", len(batch))))
+	}
+	return make([]*Span, 0, SPAN_BATCH_SIZE)
+}
+
+// The OTLP json encoding of an export request, see
+// https://github.com/open-telemetry/opentelemetry-proto/blob/main/opentelemetry/proto/trace/v1/trace.proto.
+// The trace and span ids are hex strings and the 64 bit integers are decimal strings.
+
+type otlpExportRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceId           string         `json:"traceId"`
+	SpanId            string         `json:"spanId"`
+	ParentSpanId      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Links             []otlpLink     `json:"links,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpLink struct {
+	TraceId string `json:"traceId"`
+	SpanId  string `json:"spanId"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+// OTLP span kind and status codes. The status of a successful span is left unset.
+const (
+	otlpSpanKindInternal = 1
+	otlpStatusCodeError  = 2
+)
+
+func newExportRequest(serviceName string, batch []*Span) *otlpExportRequest {
+	spans := make([]otlpSpan, 0, len(batch))
+	for _, s := range batch {
+		spans = append(spans, s.toOTLP())
+	}
+
+	return &otlpExportRequest{
+		ResourceSpans: []otlpResourceSpans{
+			{
+				Resource: otlpResource{
+					Attributes: []otlpKeyValue{newOTLPKeyValue("service.name", serviceName)},
+				},
+				ScopeSpans: []otlpScopeSpans{
+					{
+						Scope: otlpScope{Name: SCOPE_NAME},
+						Spans: spans,
+					},
+				},
+			},
+		},
+	}
+}
+
+func (s *Span) toOTLP() otlpSpan {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	o := otlpSpan{
+		TraceId:           s.sc.TraceID.String(),
+		SpanId:            s.sc.SpanID.String(),
+		Name:              s.name,
+		Kind:              otlpSpanKindInternal,
+		StartTimeUnixNano: fmt.Sprintf("%d", s.start.UnixNano()),
+		EndTimeUnixNano:   fmt.Sprintf("%d", s.end.UnixNano()),
+	}
+	if s.parent.IsValid() {
+		o.ParentSpanId = s.parent.String()
+	}
+
+	// sort the attributes so that the output is stable.
+	keys := make([]string, 0, len(s.attributes))
+	for k := range s.attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		o.Attributes = append(o.Attributes, newOTLPKeyValue(k, s.attributes[k]))
+	}
+
+	for _, l := range s.links {
+		o.Links = append(o.Links, otlpLink{TraceId: l.TraceID.String(), SpanId: l.SpanID.String()})
+	}
+
+	if s.err != nil {
+		o.Status = otlpStatus{Code: otlpStatusCodeError, Message: s.err.Error()}
+	}
+	return o
+}
+
+func newOTLPKeyValue(key string, value interface{}) otlpKeyValue {
+	kv := otlpKeyValue{Key: key}
+	switch v := value.(type) {
+	case string:
+		kv.Value.StringValue = &v
+	case bool:
+		kv.Value.BoolValue = &v
+	case int, int32, int64, uint, uint32, uint64:
+		i := fmt.Sprintf("%d", v)
+		kv.Value.IntValue = &i
+	case float32:
+		f := float64(v)
+		kv.Value.DoubleValue = &f
+	case float64:
+		kv.Value.DoubleValue = &v
+	default:
+		str := fmt.Sprintf("%v", v)
+		kv.Value.StringValue = &str
+	}
+	return kv
+}
@@ -0,0 +1,438 @@
+package tracing
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/config"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+// This package traces the agreement protocol across the agbot and the node. The trace context is carried in the
+// agreement protocol messages as a W3C traceparent (https://www.w3.org/TR/trace-context/) and the spans are exported
+// in the OpenTelemetry (OTLP) json format.
+//
+// All the spans for an agreement belong to the same trace. When the counter party did not send a trace context (e.g.
+// it is downlevel or has tracing turned off), the trace id is derived from the agreement id so that the spans of both
+// parties can still be correlated by agreement id.
+
+type TraceID [16]byte
+type SpanID [8]byte
+
+func (t TraceID) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+func (t TraceID) IsValid() bool {
+	return t != TraceID{}
+}
+
+func (s SpanID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+func (s SpanID) IsValid() bool {
+	return s != SpanID{}
+}
+
+// The part of a span that is propagated to other spans and to the counter party.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+func (sc SpanContext) String() string {
+	return fmt.Sprintf("TraceID: %v, SpanID: %v, Sampled: %v", sc.TraceID, sc.SpanID, sc.Sampled)
+}
+
+// Returns the span context as a W3C traceparent header value, or an empty string if the span context is not valid.
+func (sc SpanContext) TraceParent() string {
+	if !sc.IsValid() {
+		return ""
+	}
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%v-%v-%v", sc.TraceID, sc.SpanID, flags)
+}
+
+// Parse a W3C traceparent header value.
+func ParseTraceParent(tp string) (SpanContext, error) {
+	sc := SpanContext{}
+
+	parts := strings.Split(strings.TrimSpace(tp), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return sc, errors.New(fmt.Sprintf("traceparent %v is not valid", tp))
+	} else if parts[0] == "00" && len(parts) != 4 {
+		return sc, errors.New(fmt.Sprintf("traceparent %v is not valid", tp))
+	}
+
+	if tid, err := hex.DecodeString(parts[1]); err != nil || len(tid) != len(sc.TraceID) {
+		return sc, errors.New(fmt.Sprintf("traceparent %v has an invalid trace id", tp))
+	} else {
+		copy(sc.TraceID[:], tid)
+	}
+
+	if sid, err := hex.DecodeString(parts[2]); err != nil || len(sid) != len(sc.SpanID) {
+		return sc, errors.New(fmt.Sprintf("traceparent %v has an invalid span id", tp))
+	} else {
+		copy(sc.SpanID[:], sid)
+	}
+
+	if flags, err := hex.DecodeString(parts[3]); err != nil || len(flags) != 1 {
+		return sc, errors.New(fmt.Sprintf("traceparent %v has invalid flags", tp))
+	} else {
+		sc.Sampled = flags[0]&0x01 == 0x01
+	}
+
+	if !sc.IsValid() {
+		return sc, errors.New(fmt.Sprintf("traceparent %v has an all zero trace id or span id", tp))
+	}
+	return sc, nil
+}
+
+// Returns the trace id that is used for an agreement when the counter party did not send a trace context.
+func AgreementTraceID(agreementId string) TraceID {
+	var tid TraceID
+	sum := sha256.Sum256([]byte(agreementId))
+	copy(tid[:], sum[:len(tid)])
+	return tid
+}
+
+// A span is a timed operation, e.g. sending a proposal or starting a container. The methods of a nil span do
+// nothing, which is what StartSpan returns when tracing is turned off.
+type Span struct {
+	name       string
+	sc         SpanContext
+	parent     SpanID
+	start      time.Time
+	end        time.Time
+	attributes map[string]interface{}
+	links      []SpanContext
+	err        error
+	lock       sync.Mutex
+}
+
+// Start a new span. If the parent is not valid, the span starts a new trace.
+func StartSpan(name string, parent SpanContext) *Span {
+	t := getTracer()
+	if t == nil {
+		return nil
+	}
+
+	sc := SpanContext{SpanID: newSpanID()}
+	if parent.IsValid() {
+		sc.TraceID = parent.TraceID
+		sc.Sampled = parent.Sampled
+	} else {
+		sc.TraceID = newTraceID()
+		sc.Sampled = t.sample(sc.TraceID)
+	}
+
+	return newSpan(name, sc, parent.SpanID)
+}
+
+// Start a span for an agreement. The span is a child of the most recent span of the agreement in this process, or
+// the first span in the agreement's trace if there is none.
+func StartAgreementSpan(name string, agreementId string) *Span {
+	return StartRemoteAgreementSpan(name, agreementId, "")
+}
+
+// Start a span for an agreement protocol message that was received from the counter party. The span is a child of
+// the counter party's span in the traceparent. If the traceparent is missing or not valid, it falls back to
+// StartAgreementSpan.
+func StartRemoteAgreementSpan(name string, agreementId string, traceParent string) *Span {
+	t := getTracer()
+	if t == nil {
+		return nil
+	}
+
+	var parent SpanContext
+	if traceParent != "" {
+		if sc, err := ParseTraceParent(traceParent); err != nil {
+			glog.V(3).Infof(tlogString(fmt.Sprintf("ignoring trace context for agreement %v, error: %v", agreementId, err)))
+		} else {
+			parent = sc
+		}
+	}
+	if !parent.IsValid() {
+		parent = t.agreementSpans.get(agreementId)
+	}
+
+	sc := SpanContext{SpanID: newSpanID()}
+	if parent.IsValid() {
+		sc.TraceID = parent.TraceID
+		sc.Sampled = parent.Sampled
+	} else {
+		sc.TraceID = AgreementTraceID(agreementId)
+		sc.Sampled = t.sample(sc.TraceID)
+	}
+
+	span := newSpan(name, sc, parent.SpanID)
+	span.SetAttribute("agreement.id", agreementId)
+	t.agreementSpans.put(agreementId, sc)
+	return span
+}
+
+// Returns the traceparent of the most recent span of the agreement in this process. The agreement protocol messages
+// carry it to the counter party. It is empty when tracing is off or the agreement has no span.
+func AgreementTraceParent(agreementId string) string {
+	t := getTracer()
+	if t == nil {
+		return ""
+	}
+	return t.agreementSpans.get(agreementId).TraceParent()
+}
+
+// Forget the spans of an agreement that has ended.
+func ForgetAgreement(agreementId string) {
+	if t := getTracer(); t != nil {
+		t.agreementSpans.remove(agreementId)
+	}
+}
+
+func newSpan(name string, sc SpanContext, parent SpanID) *Span {
+	return &Span{
+		name:       name,
+		sc:         sc,
+		parent:     parent,
+		start:      time.Now(),
+		attributes: make(map[string]interface{}),
+	}
+}
+
+// Returns the span context, which is not valid for a nil span.
+func (s *Span) Context() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.sc
+}
+
+// Returns the span's W3C traceparent, which is empty for a nil span.
+func (s *Span) TraceParent() string {
+	return s.Context().TraceParent()
+}
+
+// Set an attribute on the span. The value should be a string, bool or number.
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.attributes[key] = value
+}
+
+// Link the span to a span in another trace, e.g. an agreement to the search that found the node.
+func (s *Span) AddLink(sc SpanContext) {
+	if s == nil || !sc.IsValid() {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.links = append(s.links, sc)
+}
+
+// Mark the span as failed. A nil error is ignored.
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.err = err
+}
+
+// End the span and queue it for export. Ending a span more than once has no effect.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	if !s.end.IsZero() {
+		s.lock.Unlock()
+		return
+	}
+	s.end = time.Now()
+	s.lock.Unlock()
+
+	if t := getTracer(); t != nil && s.sc.Sampled {
+		t.queue(s)
+	}
+}
+
+// The tracer holds the state of the tracing package when tracing is on.
+type tracer struct {
+	cfg            config.TracingConfig
+	serviceName    string
+	exporter       exporter
+	spans          chan *Span
+	done           chan bool
+	stopped        sync.WaitGroup
+	agreementSpans *agreementSpanMap
+}
+
+var tracerLock sync.RWMutex
+var theTracer *tracer
+
+func getTracer() *tracer {
+	tracerLock.RLock()
+	defer tracerLock.RUnlock()
+	return theTracer
+}
+
+// Turn on tracing according to the config. It does nothing if the config does not have an exporter. The default
+// service name is used if the config does not have one.
+func Init(cfg *config.TracingConfig, defaultServiceName string) error {
+	if !cfg.IsEnabled() {
+		return nil
+	}
+
+	serviceName := cfg.ServiceName
+	if serviceName == "" {
+		serviceName = defaultServiceName
+	}
+
+	exp, err := newExporter(cfg)
+	if err != nil {
+		return err
+	}
+
+	t := &tracer{
+		cfg:            *cfg,
+		serviceName:    serviceName,
+		exporter:       exp,
+		spans:          make(chan *Span, SPAN_QUEUE_SIZE),
+		done:           make(chan bool),
+		agreementSpans: newAgreementSpanMap(AGREEMENT_SPAN_MAP_SIZE),
+	}
+
+	tracerLock.Lock()
+	defer tracerLock.Unlock()
+	if theTracer != nil {
+		return errors.New("tracing is already initialized")
+	}
+	theTracer = t
+
+	t.stopped.Add(1)
+	go t.run()
+
+	glog.V(3).Infof(tlogString(fmt.Sprintf("tracing is on, service name %v, config %v", serviceName, cfg)))
+	return nil
+}
+
+// Turn off tracing. The spans that have ended are exported before it returns.
+func Shutdown() {
+	tracerLock.Lock()
+	t := theTracer
+	theTracer = nil
+	tracerLock.Unlock()
+
+	if t != nil {
+		close(t.done)
+		t.stopped.Wait()
+		t.exporter.Shutdown()
+	}
+}
+
+// A trace is sampled if the low 8 bytes of its id are below the sample ratio. Both parties of an agreement make the
+// same decision for a trace id that is derived from the agreement id.
+func (t *tracer) sample(tid TraceID) bool {
+	if t.cfg.SampleRatio >= 1 {
+		return true
+	}
+	bound := uint64(t.cfg.SampleRatio * math.MaxUint64)
+	return binary.BigEndian.Uint64(tid[8:]) < bound
+}
+
+func (t *tracer) queue(s *Span) {
+	select {
+	case t.spans <- s:
+	default:
+		glog.Warningf(tlogString(fmt.Sprintf("span queue is full, dropping span %v %v", s.name, s.sc)))
+	}
+}
+
+func newTraceID() TraceID {
+	var tid TraceID
+	for !tid.IsValid() {
+		rand.Read(tid[:])
+	}
+	return tid
+}
+
+func newSpanID() SpanID {
+	var sid SpanID
+	for !sid.IsValid() {
+		rand.Read(sid[:])
+	}
+	return sid
+}
+
+// The most recent span context of each agreement. It is bounded so that agreements which are never forgotten
+// (e.g. because the process restarted) do not grow it forever; when it is full the oldest agreement is dropped.
+type agreementSpanMap struct {
+	lock    sync.Mutex
+	maxSize int
+	spans   map[string]SpanContext
+	order   []string
+}
+
+func newAgreementSpanMap(maxSize int) *agreementSpanMap {
+	return &agreementSpanMap{
+		maxSize: maxSize,
+		spans:   make(map[string]SpanContext),
+		order:   make([]string, 0),
+	}
+}
+
+func (m *agreementSpanMap) get(agreementId string) SpanContext {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.spans[agreementId]
+}
+
+func (m *agreementSpanMap) put(agreementId string, sc SpanContext) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if _, ok := m.spans[agreementId]; !ok {
+		if len(m.order) >= m.maxSize {
+			delete(m.spans, m.order[0])
+			m.order = m.order[1:]
+		}
+		m.order = append(m.order, agreementId)
+	}
+	m.spans[agreementId] = sc
+}
+
+func (m *agreementSpanMap) remove(agreementId string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if _, ok := m.spans[agreementId]; ok {
+		delete(m.spans, agreementId)
+		for i, id := range m.order {
+			if id == agreementId {
+				m.order = append(m.order[:i], m.order[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+var tlogString = func(v interface{}) string {
+	return fmt.Sprintf("Tracing: %v", v)
+}
@@ -0,0 +1,169 @@
+// +build unit
+
+package tracing
+
+import (
+	"bufio"
+	"encoding/json"
+	"github.com/open-horizon/anax/config"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func Test_TraceParent(t *testing.T) {
+
+	tp := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	sc, err := ParseTraceParent(tp)
+	assert.Nil(t, err, "should parse a valid traceparent")
+	assert.True(t, sc.Sampled, "traceparent should be sampled")
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID.String())
+	assert.Equal(t, "00f067aa0ba902b7", sc.SpanID.String())
+	assert.Equal(t, tp, sc.TraceParent(), "traceparent should round trip")
+
+	sc, err = ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	assert.Nil(t, err)
+	assert.False(t, sc.Sampled, "traceparent should not be sampled")
+
+	invalid := []string{
+		"",
+		"garbage",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-zzf067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+	}
+	for _, tp := range invalid {
+		_, err := ParseTraceParent(tp)
+		assert.NotNil(t, err, "traceparent %v should not be valid", tp)
+	}
+
+	assert.Equal(t, "", SpanContext{}.TraceParent(), "an invalid span context has no traceparent")
+}
+
+func Test_AgreementTraceID(t *testing.T) {
+	assert.Equal(t, AgreementTraceID("ag1"), AgreementTraceID("ag1"), "trace id should be derived from the agreement id")
+	assert.NotEqual(t, AgreementTraceID("ag1"), AgreementTraceID("ag2"))
+	assert.True(t, AgreementTraceID("ag1").IsValid())
+}
+
+func Test_TracingOff(t *testing.T) {
+
+	// Nothing happens when tracing is not configured.
+	assert.Nil(t, Init(&config.TracingConfig{}, "anax"))
+
+	span := StartAgreementSpan("test", "ag1")
+	assert.Nil(t, span, "spans should be nil when tracing is off")
+	span.SetAttribute("a", "b")
+	span.SetError(os.ErrNotExist)
+	span.End()
+	assert.Equal(t, "", span.TraceParent())
+	assert.Equal(t, "", AgreementTraceParent("ag1"))
+	ForgetAgreement("ag1")
+}
+
+func Test_AgreementSpans(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "tracing-")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	file := path.Join(dir, "spans.json")
+	cfg := &config.TracingConfig{
+		Exporter:       config.TracingExporterFile,
+		File:           file,
+		SampleRatio:    1.0,
+		BatchIntervalS: 60,
+	}
+	assert.Nil(t, Init(cfg, "anax-test"))
+	assert.NotNil(t, Init(cfg, "anax-test"), "tracing should only be initialized once")
+
+	// The first span of an agreement is in the trace derived from the agreement id.
+	first := StartAgreementSpan("first", "ag1")
+	assert.Equal(t, AgreementTraceID("ag1"), first.Context().TraceID)
+	assert.Equal(t, first.TraceParent(), AgreementTraceParent("ag1"))
+
+	// The next local span is a child of the first one.
+	second := StartAgreementSpan("second", "ag1")
+	second.SetAttribute("count", 3)
+	second.SetError(os.ErrNotExist)
+	assert.Equal(t, first.Context().TraceID, second.Context().TraceID)
+	assert.Equal(t, first.Context().SpanID, second.parent)
+
+	// A span for a remote message is a child of the sender's span.
+	remote := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	third := StartRemoteAgreementSpan("third", "ag1", remote)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", third.Context().TraceID.String())
+	assert.Equal(t, "00f067aa0ba902b7", third.parent.String())
+
+	// A bad traceparent falls back to the local parent.
+	fourth := StartRemoteAgreementSpan("fourth", "ag1", "garbage")
+	assert.Equal(t, third.Context().SpanID, fourth.parent)
+
+	// Links are kept on the span.
+	search := StartSpan("search", SpanContext{})
+	fourth.AddLink(search.Context())
+
+	for _, s := range []*Span{first, second, third, fourth, search} {
+		s.End()
+	}
+	second.End()
+
+	ForgetAgreement("ag1")
+	assert.Equal(t, "", AgreementTraceParent("ag1"), "agreement should be forgotten")
+
+	// Shutdown flushes the spans to the file.
+	Shutdown()
+	assert.Nil(t, StartAgreementSpan("after", "ag1"), "spans should be nil after shutdown")
+
+	f, err := os.Open(file)
+	assert.Nil(t, err)
+	defer f.Close()
+
+	spans := map[string]otlpSpan{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		req := otlpExportRequest{}
+		assert.Nil(t, json.Unmarshal(scanner.Bytes(), &req))
+		assert.Equal(t, 1, len(req.ResourceSpans))
+		assert.Equal(t, "service.name", req.ResourceSpans[0].Resource.Attributes[0].Key)
+		assert.Equal(t, "anax-test", *req.ResourceSpans[0].Resource.Attributes[0].Value.StringValue)
+		for _, s := range req.ResourceSpans[0].ScopeSpans[0].Spans {
+			spans[s.Name] = s
+		}
+	}
+	assert.Equal(t, 5, len(spans), "each span should be exported once")
+
+	assert.Equal(t, first.Context().SpanID.String(), spans["second"].ParentSpanId)
+	assert.Equal(t, otlpStatusCodeError, spans["second"].Status.Code)
+	assert.Equal(t, os.ErrNotExist.Error(), spans["second"].Status.Message)
+	assert.Equal(t, "agreement.id", spans["second"].Attributes[0].Key)
+	assert.Equal(t, "count", spans["second"].Attributes[1].Key)
+	assert.Equal(t, "3", *spans["second"].Attributes[1].Value.IntValue)
+	assert.Equal(t, "", spans["first"].ParentSpanId)
+	assert.Equal(t, 0, spans["first"].Status.Code)
+	assert.Equal(t, 1, len(spans["fourth"].Links))
+	assert.Equal(t, search.Context().SpanID.String(), spans["fourth"].Links[0].SpanId)
+}
+
+func Test_Sampling(t *testing.T) {
+
+	tr := &tracer{cfg: config.TracingConfig{SampleRatio: 0}}
+	assert.False(t, tr.sample(AgreementTraceID("ag1")), "nothing should be sampled")
+
+	tr.cfg.SampleRatio = 1.0
+	assert.True(t, tr.sample(AgreementTraceID("ag1")), "everything should be sampled")
+
+	tr.cfg.SampleRatio = 0.5
+	sampled := 0
+	for i := 0; i < 1000; i++ {
+		if tr.sample(newTraceID()) {
+			sampled++
+		}
+	}
+	assert.True(t, sampled > 400 && sampled < 600, "about half the traces should be sampled, got %v", sampled)
+}